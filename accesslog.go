@@ -0,0 +1,109 @@
+package jsonrpc2
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+type (
+	//One record of the access log. A record is produced per call, so a batch produces one record per entry
+	AccessRecord struct {
+		Time       time.Time     //When the call started
+		Method     string        //Method called
+		RequestId  string        //Id of the request. Empty for notifications
+		Duration   time.Duration //Time taken to handle the call
+		BytesIn    int           //Size of the encoded request
+		BytesOut   int           //Size of the encoded response. Zero for notifications
+		Code       RpcErrorCode  //Error code of the response. Zero on success
+		RemoteAddr string        //Address of the client
+	}
+
+	//AccessLogger receives access log records. Implement it to write records to slog, zap or any other sink
+	AccessLogger interface {
+		LogAccess(ctx context.Context, record AccessRecord)
+	}
+
+	//AccessLoggerFunc adapts a function to an AccessLogger
+	AccessLoggerFunc func(ctx context.Context, record AccessRecord)
+
+	accessLogConfig struct {
+		logger      AccessLogger
+		sampleRate  float64
+		methodRates map[string]float64
+	}
+)
+
+func (f AccessLoggerFunc) LogAccess(ctx context.Context, record AccessRecord) {
+	f(ctx, record)
+}
+
+// WithAccessLog writes a record for every call to logger.
+// sampleRate is the fraction of successful calls that are logged, between 0 and 1. Failed calls are always logged.
+func WithAccessLog(logger AccessLogger, sampleRate float64) Option {
+	return func(c *config) {
+		if c.accessLog == nil {
+			c.accessLog = &accessLogConfig{methodRates: make(map[string]float64)}
+		}
+		c.accessLog.logger = logger
+		c.accessLog.sampleRate = sampleRate
+	}
+}
+
+// WithAccessLogSampleRate overrides the sample rate of successful calls for a single method.
+// eg. log every call of a rare admin method while sampling hot methods.
+func WithAccessLogSampleRate(method string, sampleRate float64) Option {
+	return func(c *config) {
+		if c.accessLog == nil {
+			c.accessLog = &accessLogConfig{methodRates: make(map[string]float64)}
+		}
+		c.accessLog.methodRates[method] = sampleRate
+	}
+}
+
+func (l *accessLogConfig) sampled(method string, res Response) bool {
+	if res.Error != nil {
+		return true
+	}
+
+	rate, ok := l.methodRates[method]
+	if !ok {
+		rate = l.sampleRate
+	}
+
+	return rate >= 1 || rand.Float64() < rate
+}
+
+func (rpc *jsonRpcImpl) logAccess(ctx context.Context, cfg *config, start time.Time, req Request, res Response) {
+	l := cfg.accessLog
+	if l == nil || l.logger == nil || !l.sampled(req.Method, res) {
+		return
+	}
+
+	record := AccessRecord{
+		Time:       start,
+		Method:     req.Method,
+		Duration:   time.Since(start),
+		RemoteAddr: RemoteAddrFromContext(ctx),
+	}
+
+	if in, err := cfg.marshal(&req, false); err == nil {
+		record.BytesIn = len(in)
+	}
+
+	if req.Id != nil {
+		record.RequestId = *req.Id
+	}
+	if _, ok := attachmentOf(res); req.Id != nil && !ok {
+		//Attachments are not encoded here since that would consume their content
+		if out, err := cfg.marshal(&res, false); err == nil {
+			record.BytesOut = len(out)
+		}
+	}
+
+	if res.Error != nil {
+		record.Code = res.Error.Code
+	}
+
+	l.logger.LogAccess(ctx, record)
+}
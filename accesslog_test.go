@@ -0,0 +1,62 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAccessLog struct {
+	mu      sync.Mutex
+	records []AccessRecord
+}
+
+func (l *testAccessLog) LogAccess(ctx context.Context, record AccessRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, record)
+}
+
+func TestAccessLog(t *testing.T) {
+	log := &testAccessLog{}
+	rpc := NewJsonRpc(WithAccessLog(log, 1))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)
+
+	assert.Len(t, log.records, 1)
+	record := log.records[0]
+	assert.Equal(t, "Arith.Add", record.Method)
+	assert.Equal(t, "1", record.RequestId)
+	assert.Equal(t, RpcErrorCode(0), record.Code)
+	assert.Equal(t, "192.0.2.1:1234", record.RemoteAddr)
+	assert.Greater(t, record.BytesIn, 0)
+	assert.Greater(t, record.BytesOut, 0)
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	log := &testAccessLog{}
+	rpc := NewJsonRpc(WithAccessLog(log, 0), WithAccessLogSampleRate("Arith.Add", 1))
+	rpc.RegisterWithName(arith{}, "Arith")
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	ids := []string{"1", "2", "3"}
+	_, err := makeRpcBatchTestRequest(rpc, []Request{
+		{Id: &ids[0], Method: "Enc.Echo", Params: []any{"not sampled"}, Jsonrpc: RPC_VERSION},
+		{Id: &ids[1], Method: "Arith.ErrorMethod", Params: []any{}, Jsonrpc: RPC_VERSION},
+		{Id: &ids[2], Method: "Arith.Add", Params: []any{1, 2}, Jsonrpc: RPC_VERSION},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	methods := []string{}
+	for _, record := range log.records {
+		methods = append(methods, record.Method)
+	}
+
+	assert.ElementsMatch(t, []string{"Arith.ErrorMethod", "Arith.Add"}, methods)
+}
@@ -0,0 +1,95 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// First file descriptor passed by the service manager, after stdin, stdout and stderr
+const LISTEN_FDS_START = 3
+
+// ActivatedListener is a listening socket passed by the service manager, eg. systemd socket activation
+type ActivatedListener struct {
+	net.Listener
+	Name string //Name from LISTEN_FDNAMES, eg. the FileDescriptorName of the socket unit. "unknown" when not set
+}
+
+// ActivationListeners returns the sockets passed by systemd, or any service manager following the LISTEN_FDS
+// protocol, so the server can be socket activated. The service manager keeps the sockets open while the process
+// restarts, so clients connecting meanwhile wait instead of being refused. Nil is returned when the process was not
+// socket activated. The environment variables are unset, so child processes do not inherit the sockets.
+func ActivationListeners() ([]ActivatedListener, error) {
+	return activationListeners(LISTEN_FDS_START)
+}
+
+// ListenActivated returns the first socket passed by the service manager, else listens on the address, so the same
+// binary runs with and without socket activation.
+func ListenActivated(network, addr string) (net.Listener, error) {
+	listeners, err := ActivationListeners()
+	if err != nil {
+		return nil, err
+	}
+	if len(listeners) == 0 {
+		return net.Listen(network, addr)
+	}
+
+	//Sockets that are not served are closed
+	for _, l := range listeners[1:] {
+		l.Close()
+	}
+
+	return listeners[0], nil
+}
+
+// Listeners of the file descriptors passed from start on
+func activationListeners(start int) ([]ActivatedListener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	//The sockets are meant for another process, eg. the parent of a process that forked
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	var names []string
+	if value := os.Getenv("LISTEN_FDNAMES"); value != "" {
+		names = strings.Split(value, ":")
+	}
+
+	listeners := make([]ActivatedListener, 0, count)
+	for i := 0; i < count; i++ {
+		name := "unknown"
+		if i < len(names) {
+			name = names[i]
+		}
+
+		fd := start + i
+		f := os.NewFile(uintptr(fd), name)
+		//The listener holds a duplicate of the descriptor, so the passed one is closed
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, activated := range listeners {
+				activated.Close()
+			}
+			return nil, errors.New(fmt.Sprintf("Socket %d (%s) is not a listener: %v", fd, name, err))
+		}
+
+		listeners = append(listeners, ActivatedListener{Listener: l, Name: name})
+	}
+
+	return listeners, nil
+}
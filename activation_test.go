@@ -0,0 +1,101 @@
+//go:build unix
+
+package jsonrpc2
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Descriptor of a listening socket, as the service manager passes it
+func newTestActivationFd(t *testing.T) (int, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fd, l.Addr().String()
+}
+
+func TestActivationListeners(t *testing.T) {
+	fd, addr := newTestActivationFd(t)
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "rpc")
+
+	listeners, err := activationListeners(fd)
+	assert.Nil(t, err)
+	assert.Len(t, listeners, 1)
+	assert.Equal(t, "rpc", listeners[0].Name)
+	assert.Equal(t, "", os.Getenv("LISTEN_FDS"))
+
+	rpc := newTestArithRpc()
+	go rpc.Serve(listeners[0])
+	defer listeners[0].Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewConnClient(conn)
+	defer client.Close()
+
+	var sum int
+	assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, 3, sum)
+}
+
+func TestActivationListenersOfAnotherProcess(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := ActivationListeners()
+	assert.Nil(t, err)
+	assert.Nil(t, listeners)
+	assert.Equal(t, "", os.Getenv("LISTEN_PID"))
+}
+
+func TestListenActivatedFallsBack(t *testing.T) {
+	l, err := ListenActivated("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+
+	_, ok := l.(*net.TCPListener)
+	assert.True(t, ok)
+}
+
+func TestActivationListenerNotASocket(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "activation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+
+	_, err = activationListeners(fd)
+	assert.Contains(t, err.Error(), "is not a listener")
+}
@@ -0,0 +1,198 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type (
+	//ProtocolAdapter translates the messages of another protocol to JSON-RPC 2.0 and back, so older or foreign
+	//clients call the same services. WithProtocolAdapter serves it on its own path
+	ProtocolAdapter interface {
+		//Translate the body of a request to a JSON-RPC 2.0 request or batch
+		DecodeRequest(body []byte) ([]byte, error)
+
+		//Translate the JSON-RPC 2.0 response to the request body back to the protocol. The response is nil when
+		//nothing is answered, eg. for notifications, and an error response when the request could not be decoded.
+		//A nil result is answered with 204 No Content
+		EncodeResponse(request, response []byte) ([]byte, error)
+
+		//Content type of the encoded responses
+		ContentType() string
+	}
+
+	//JSON-RPC 1.0: no jsonrpc member, ids of any type, notifications with a null id and responses carrying both
+	//result and error, one of them null
+	jsonRPC1Adapter struct{}
+
+	jsonRPC1Request struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+
+	jsonRPC1Response struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+		Id     json.RawMessage `json:"id"`
+	}
+)
+
+var (
+	//JSONRPC1 adapts JSON-RPC 1.0 requests. Batches are not part of 1.0 and are refused
+	JSONRPC1 ProtocolAdapter = jsonRPC1Adapter{}
+
+	//XMLRPC adapts XML-RPC method calls. Params are passed by position and faults carry the code of the error
+	XMLRPC ProtocolAdapter = xmlRPCAdapter{}
+)
+
+// WithProtocolAdapter serves requests sent to path, eg. /v1, through the adapter, so clients of another protocol
+// call the registered services there while other paths keep serving JSON-RPC 2.0.
+//
+//	rpc := jsonrpc2.NewJsonRpc(jsonrpc2.WithProtocolAdapter("/v1", jsonrpc2.JSONRPC1), jsonrpc2.WithProtocolAdapter("/xmlrpc", jsonrpc2.XMLRPC))
+func WithProtocolAdapter(path string, adapter ProtocolAdapter) Option {
+	return func(c *config) {
+		if c.adapters == nil {
+			c.adapters = make(map[string]ProtocolAdapter)
+		}
+		c.adapters[path] = adapter
+	}
+}
+
+// Serve the request through the adapter of its path. False is returned when the path has none
+func (s *jsonRpcImpl) serveAdapted(w http.ResponseWriter, r *http.Request) bool {
+	cfg := s.cfg()
+	adapter, ok := cfg.adapters[r.URL.Path]
+	if !ok {
+		return false
+	}
+
+	ctx := withInFlightRequests(r.Context(), s.inFlight)
+	ctx = withRemoteAddr(ctx, cfg.remoteAddr(r))
+	ctx = withHTTPRequest(ctx, r)
+	ctx = cfg.requestMetadata(ctx, r.Header)
+
+	ctx, cancel, timeoutErr := withTimeoutHeader(ctx, r.Header)
+	defer cancel()
+
+	body, err := s.readBody(r)
+	var translated []byte
+	if err == nil {
+		if translated, err = adapter.DecodeRequest(body); err != nil {
+			err = errors.New(fmt.Sprintf("Unable to decode request: %v", err))
+		}
+	}
+
+	var response []byte
+	switch {
+	case timeoutErr != nil:
+		response = encodeErrorResponse(timeoutErr, INVALID_REQUEST)
+	case err != nil:
+		response = encodeErrorResponse(err, decodeErrorCode(err))
+	default:
+		response = s.HandleMessage(ctx, translated)
+	}
+
+	encoded, err := adapter.EncodeResponse(body, response)
+	if err != nil {
+		s.cfg().logger.Printf("Response of %s could not be encoded: %v", r.URL.Path, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+	if encoded == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+
+	w.Header().Set("Content-Type", adapter.ContentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write(encoded)
+
+	return true
+}
+
+// Error response to a request that could not be decoded, as received by adapters
+func encodeErrorResponse(err error, code RpcErrorCode) []byte {
+	res := makeErrorResponse(err, code, nil, nil)
+	encoded, _ := json.Marshal(res)
+
+	return encoded
+}
+
+// Read the body of the request within the size limit of the server
+func (s *jsonRpcImpl) readBody(r *http.Request) ([]byte, error) {
+	var reader io.Reader = r.Body
+	maxSize := s.cfg().maxRequestSize
+	if maxSize > 0 {
+		reader = io.LimitReader(r.Body, maxSize+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && int64(len(body)) > maxSize {
+		return nil, errRequestTooLarge
+	}
+
+	return body, nil
+}
+
+func (jsonRPC1Adapter) DecodeRequest(body []byte) ([]byte, error) {
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		return nil, errors.New("Batches are not supported by JSON-RPC 1.0")
+	}
+
+	var req jsonRPC1Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	translated := Request{Jsonrpc: RPC_VERSION, Method: req.Method, Params: req.Params}
+	if req.Params == nil {
+		translated.Params = []any{}
+	}
+	//A null id marks a notification. Other ids are restored from the request in the response
+	if id := bytes.TrimSpace(req.Id); len(id) > 0 && !bytes.Equal(id, []byte("null")) {
+		var text string
+		if json.Unmarshal(id, &text) != nil {
+			text = string(id)
+		}
+		translated.Id = &text
+	}
+
+	return json.Marshal(translated)
+}
+
+func (jsonRPC1Adapter) EncodeResponse(request, response []byte) ([]byte, error) {
+	if response == nil {
+		return nil, nil
+	}
+
+	var res jsonRPC1Response
+	if err := json.Unmarshal(response, &res); err != nil {
+		return nil, err
+	}
+
+	//Ids keep the type the client sent them with
+	var req jsonRPC1Request
+	json.Unmarshal(request, &req)
+	res.Id = req.Id
+
+	null := json.RawMessage("null")
+	for _, member := range []*json.RawMessage{&res.Result, &res.Error, &res.Id} {
+		if len(*member) == 0 {
+			*member = null
+		}
+	}
+
+	return json.Marshal(res)
+}
+
+func (jsonRPC1Adapter) ContentType() string {
+	return "application/json"
+}
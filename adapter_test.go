@@ -0,0 +1,46 @@
+package jsonrpc2
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postAdapted(t *testing.T, url, contentType, body string) (int, string) {
+	res, err := http.Post(url, contentType, strings.NewReader(body))
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+
+	return res.StatusCode, string(data)
+}
+
+func TestJSONRPC1Adapter(t *testing.T) {
+	rpc := NewJsonRpc(WithProtocolAdapter("/v1", JSONRPC1))
+	rpc.RegisterWithName(arith{}, "Arith")
+	url := newTestHTTPServer(t, rpc)
+
+	status, body := postAdapted(t, url+"/v1", "application/json", `{"method":"Arith.Add","params":[1,2],"id":7}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"result":3,"error":null,"id":7}`, body)
+
+	_, body = postAdapted(t, url+"/v1", "application/json", `{"method":"Arith.ErrorMethod","params":[],"id":"a"}`)
+	assert.JSONEq(t, `{"result":null,"error":{"code":32603,"message":"Some error here","data":null},"id":"a"}`, body)
+
+	//A null id is a notification
+	status, body = postAdapted(t, url+"/v1", "application/json", `{"method":"Arith.Add","params":[1,2],"id":null}`)
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Empty(t, body)
+
+	_, body = postAdapted(t, url+"/v1", "application/json", `[{"method":"Arith.Add","params":[1,2],"id":1}]`)
+	assert.JSONEq(t, `{"result":null,"error":{"code":32700,"message":"Unable to decode request: Batches are not supported by JSON-RPC 1.0","data":null},"id":null}`, body)
+
+	//Other paths keep serving JSON-RPC 2.0
+	_, body = postAdapted(t, url, "application/json", `{"method":"Arith.Add","params":[1,2],"id":"1"}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","error":{"code":32600,"message":"Invalid RPC version. jsonrpc must be 2.0","data":null}}`, body)
+}
@@ -0,0 +1,220 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// Pseudo queue of RabbitMQ delivering replies to the channel that published the request, without declaring a queue
+const AMQP_DIRECT_REPLY_TO = "amq.rabbitmq.reply-to"
+
+type (
+	//AMQPChannel is the part of an AMQP channel used by the AMQP transport. Adapt *amqp.Channel to it, see the README.
+	//The package does not depend on an AMQP client so applications pick its version.
+	AMQPChannel interface {
+		//Call handler with the messages delivered from the queue until cancel is called. Deliveries are acknowledged
+		//by the adapter, eg. by consuming with auto-ack
+		Consume(queue string, handler func(msg AMQPMessage)) (cancel func() error, err error)
+
+		//Publish msg to the exchange with the routing key. The default exchange "" routes to the queue of that name
+		Publish(ctx context.Context, exchange, routingKey string, msg AMQPMessage) error
+	}
+
+	//AMQPMessage is a message published or delivered over AMQP
+	AMQPMessage struct {
+		Body          []byte
+		ContentType   string
+		CorrelationId string //Matches a reply to its request
+		ReplyTo       string //Queue the reply is published to. Empty for notifications
+	}
+
+	//AMQPOptions configure how a server is exposed over AMQP
+	AMQPOptions struct {
+		Queue string //Queue the requests are consumed from. Servers consuming the same queue split the requests
+
+		//Exchange published notifications go to, with their topic as routing key. Not forwarded when empty
+		NotificationExchange string
+	}
+
+	//AMQPTransport publishes each message to a queue and receives the replies on ReplyQueue, matched by correlation id
+	AMQPTransport struct {
+		Channel    AMQPChannel
+		Exchange   string //Exchange the requests are published to. Defaults to the default exchange
+		Queue      string //Routing key of the requests, the queue of the server with the default exchange
+		ReplyQueue string //Queue the replies are consumed from. Defaults to AMQP_DIRECT_REPLY_TO
+
+		mu          sync.Mutex
+		pending     map[string]chan []byte
+		lastId      uint64
+		stopReplies func() error //Nil until replies are consumed
+	}
+)
+
+// ServeAMQP handles the requests consumed from the queue of opts and publishes the responses to their reply queue,
+// with the correlation id of the request. Requests are handled concurrently. Stop cancels the consumer and the calls
+// in flight.
+func (rpc *jsonRpcImpl) ServeAMQP(channel AMQPChannel, opts AMQPOptions) (stop func() error, err error) {
+	if opts.Queue == "" {
+		return nil, errors.New("AMQP queue is required")
+	}
+	if err := rpc.Start(context.Background()); err != nil {
+		return nil, err
+	}
+
+	ctx := withInFlightRequests(context.Background(), newInFlightRequests())
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	cancelConsumer, err := channel.Consume(opts.Queue, func(msg AMQPMessage) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res := rpc.HandleMessage(ctx, msg.Body)
+			if res == nil || msg.ReplyTo == "" {
+				return
+			}
+			reply := AMQPMessage{Body: res, ContentType: JSON_MEDIA_TYPE, CorrelationId: msg.CorrelationId}
+			if err := channel.Publish(ctx, "", msg.ReplyTo, reply); err != nil {
+				rpc.cfg().logger.Printf("Unable to publish AMQP response to %s: %v", msg.ReplyTo, err)
+			}
+		}()
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	stopNotifications := func() {}
+	if opts.NotificationExchange != "" {
+		stopNotifications = rpc.Subscribe(func(n Notification) {
+			rpc.publishAMQP(channel, opts.NotificationExchange, n)
+		})
+	}
+
+	return func() error {
+		stopNotifications()
+		err := cancelConsumer()
+		cancel()
+		wg.Wait()
+
+		return err
+	}, nil
+}
+
+// Publish a notification to the exchange, routed by its topic
+func (rpc *jsonRpcImpl) publishAMQP(channel AMQPChannel, exchange string, n Notification) {
+	cfg := rpc.cfg()
+
+	data, err := cfg.encodeNotification(n)
+	if err != nil {
+		cfg.logger.Printf("Unable to encode notification %s: %v", n.Method, err)
+		return
+	}
+
+	msg := AMQPMessage{Body: data, ContentType: JSON_MEDIA_TYPE}
+	if err := channel.Publish(context.Background(), exchange, n.Topic, msg); err != nil {
+		cfg.logger.Printf("Unable to publish notification %s to %s: %v", n.Method, exchange, err)
+	}
+}
+
+// NewAMQPClient creates a client calling the server consuming queue over AMQP. Replies are consumed from
+// AMQP_DIRECT_REPLY_TO on the channel, so the channel must not be shared with another client.
+func NewAMQPClient(channel AMQPChannel, queue string, opts ...ClientOption) (*Client, error) {
+	transport := &AMQPTransport{Channel: channel, Queue: queue}
+	if err := transport.consumeReplies(); err != nil {
+		return nil, err
+	}
+
+	return NewClient(transport, opts...), nil
+}
+
+func (t *AMQPTransport) RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error) {
+	if notification {
+		return nil, t.Channel.Publish(ctx, t.Exchange, t.Queue, AMQPMessage{Body: msg, ContentType: JSON_MEDIA_TYPE})
+	}
+	if err := t.consumeReplies(); err != nil {
+		return nil, err
+	}
+
+	replies := make(chan []byte, 1)
+	t.mu.Lock()
+	t.lastId++
+	correlationId := strconv.FormatUint(t.lastId, 10)
+	t.pending[correlationId] = replies
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, correlationId)
+		t.mu.Unlock()
+	}()
+
+	request := AMQPMessage{Body: msg, ContentType: JSON_MEDIA_TYPE, CorrelationId: correlationId, ReplyTo: t.replyQueue()}
+	if err := t.Channel.Publish(ctx, t.Exchange, t.Queue, request); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Consume the replies once, before the first request is published, as direct reply-to requires
+func (t *AMQPTransport) consumeReplies() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopReplies != nil {
+		return nil
+	}
+
+	t.pending = make(map[string]chan []byte)
+	stop, err := t.Channel.Consume(t.replyQueue(), t.receiveReply)
+	if err != nil {
+		return err
+	}
+	t.stopReplies = stop
+
+	return nil
+}
+
+// Hand a reply to the call of its correlation id. Replies of calls given up on are dropped
+func (t *AMQPTransport) receiveReply(msg AMQPMessage) {
+	t.mu.Lock()
+	replies, ok := t.pending[msg.CorrelationId]
+	t.mu.Unlock()
+
+	if ok {
+		select {
+		case replies <- msg.Body:
+		default:
+			//Duplicate reply for the correlation id
+		}
+	}
+}
+
+func (t *AMQPTransport) replyQueue() string {
+	if t.ReplyQueue == "" {
+		return AMQP_DIRECT_REPLY_TO
+	}
+
+	return t.ReplyQueue
+}
+
+// Close stops consuming replies. It does not close the AMQP channel, which is owned by the application
+func (t *AMQPTransport) Close() error {
+	t.mu.Lock()
+	stop := t.stopReplies
+	t.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+	return stop()
+}
@@ -0,0 +1,138 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// In memory AMQP broker. Exchanges other than the default route to the queues bound with the exact routing key
+type memoryAMQP struct {
+	mu        sync.Mutex
+	consumers map[string]func(msg AMQPMessage)
+	bindings  map[string]string //Queue by exchange and routing key
+}
+
+func newMemoryAMQP() *memoryAMQP {
+	return &memoryAMQP{consumers: make(map[string]func(msg AMQPMessage)), bindings: make(map[string]string)}
+}
+
+func (b *memoryAMQP) Consume(queue string, handler func(msg AMQPMessage)) (func() error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consumers[queue] = handler
+	return func() error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.consumers, queue)
+		return nil
+	}, nil
+}
+
+func (b *memoryAMQP) Publish(ctx context.Context, exchange, routingKey string, msg AMQPMessage) error {
+	b.mu.Lock()
+	queue := routingKey
+	if exchange != "" {
+		queue = b.bindings[exchange+"/"+routingKey]
+	}
+	handler, ok := b.consumers[queue]
+	b.mu.Unlock()
+
+	if ok {
+		handler(msg)
+	}
+	return nil
+}
+
+// Bind a queue to the exchange and consume it
+func (b *memoryAMQP) bind(exchange, routingKey, queue string) chan AMQPMessage {
+	b.mu.Lock()
+	b.bindings[exchange+"/"+routingKey] = queue
+	b.mu.Unlock()
+
+	messages := make(chan AMQPMessage, 10)
+	b.Consume(queue, func(msg AMQPMessage) { messages <- msg })
+
+	return messages
+}
+
+func TestAMQP(t *testing.T) {
+	broker := newMemoryAMQP()
+	stop, err := newTestArithRpc().ServeAMQP(broker, AMQPOptions{Queue: "rpc.arith"})
+	assert.Nil(t, err)
+	defer stop()
+
+	client, err := NewAMQPClient(broker, "rpc.arith")
+	assert.Nil(t, err)
+	defer client.Close()
+
+	sum, err := Call[int](context.Background(), client, "Arith.Add", []any{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, sum)
+
+	err = client.Call(context.Background(), "Arith.ErrorMethod", nil, nil)
+	assert.Equal(t, "Some error here", err.Error())
+
+	//Notifications are not answered
+	assert.Nil(t, client.Notify(context.Background(), "Arith.Add", []any{1, 2}))
+}
+
+func TestAMQPReplies(t *testing.T) {
+	broker := newMemoryAMQP()
+	requests := make(chan AMQPMessage, 1)
+	broker.Consume("rpc", func(msg AMQPMessage) { requests <- msg })
+
+	transport := &AMQPTransport{Channel: broker, Queue: "rpc", ReplyQueue: "replies"}
+	defer transport.Close()
+
+	replied := make(chan []byte, 1)
+	go func() {
+		reply, _ := transport.RoundTrip(context.Background(), []byte(`{"id":"1"}`), false)
+		replied <- reply
+	}()
+
+	request := <-requests
+	assert.Equal(t, "replies", request.ReplyTo)
+	assert.Equal(t, JSON_MEDIA_TYPE, request.ContentType)
+
+	//Replies of other correlation ids are dropped
+	broker.Publish(context.Background(), "", "replies", AMQPMessage{Body: []byte("other"), CorrelationId: "unknown"})
+	broker.Publish(context.Background(), "", "replies", AMQPMessage{Body: []byte("reply"), CorrelationId: request.CorrelationId})
+	assert.Equal(t, []byte("reply"), <-replied)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := transport.RoundTrip(ctx, []byte(`{"id":"2"}`), false)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestAMQPNotifications(t *testing.T) {
+	broker := newMemoryAMQP()
+	rpc := NewJsonRpc()
+	stop, _ := rpc.ServeAMQP(broker, AMQPOptions{Queue: "rpc", NotificationExchange: "events"})
+	orders := broker.bind("events", "orders", "billing")
+
+	rpc.Publish("orders", "Orders.Created", []any{"order-1"})
+
+	select {
+	case msg := <-orders:
+		assert.JSONEq(t, `{"jsonrpc":"2.0","method":"Orders.Created","params":["order-1"]}`, string(msg.Body))
+	case <-time.After(time.Second):
+		t.Fatal("Notification not published")
+	}
+
+	stop()
+	rpc.Publish("orders", "Orders.Created", nil)
+	assert.Len(t, orders, 0)
+}
+
+func TestAMQPRequiresQueue(t *testing.T) {
+	_, err := NewJsonRpc().ServeAMQP(newMemoryAMQP(), AMQPOptions{})
+
+	assert.NotNil(t, err)
+}
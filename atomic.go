@@ -0,0 +1,173 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Method of the notification marking a batch atomic when it is the first entry of the batch
+const ATOMIC_BATCH_METHOD = BUILTIN_SERVICE_NAME + ".atomic"
+
+type (
+	//TransactionFailure is the data of the TRANSACTION_FAILED errors answering the entries of a failed atomic batch
+	TransactionFailure struct {
+		Index              int       `json:"index"`                        //Position of the failed entry in the batch, the marker being 0
+		Id                 *string   `json:"id,omitempty"`                 //Id of the failed entry. Nil for notifications
+		Error              *RpcError `json:"error"`                        //Error the entry failed with
+		CompensationErrors []string  `json:"compensationErrors,omitempty"` //Errors of the compensations that failed
+	}
+
+	//Compensations registered by the handler of an entry of an atomic batch
+	compensations struct {
+		mu    sync.Mutex
+		funcs []func(ctx context.Context) error
+		done  bool //The entry was answered, so later compensations are ignored
+	}
+
+	compensationsKey struct{}
+
+	//Context keeping the values of its parent without its deadline and cancellation, so compensations run after the
+	//batch timed out or its client went away
+	detachedContext struct {
+		context.Context
+	}
+)
+
+// Compensate registers a function undoing the effects of the handler, eg. deleting the order it created. When the
+// handler is called for an entry of an atomic batch and a later entry fails, the compensations of the entries that
+// ran are called in reverse order. Outside of atomic batches it does nothing.
+func Compensate(ctx context.Context, compensation func(ctx context.Context) error) {
+	c, ok := ctx.Value(compensationsKey{}).(*compensations)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.done {
+		c.funcs = append(c.funcs, compensation)
+	}
+}
+
+// Whether the batch starts with the rpc.atomic marker
+func isAtomicBatch(requests []Request) bool {
+	return len(requests) > 0 && requests[0].Method == ATOMIC_BATCH_METHOD
+}
+
+// The marker is a notification without params. Batches with another marker are refused before any entry runs
+func checkAtomicMarker(marker Request) error {
+	if marker.Id != nil {
+		return errors.New(fmt.Sprintf("%s must be a notification", ATOMIC_BATCH_METHOD))
+	}
+
+	switch params := marker.Params.(type) {
+	case nil:
+	case []any:
+		if len(params) > 0 {
+			return errors.New(fmt.Sprintf("%s takes no params", ATOMIC_BATCH_METHOD))
+		}
+	case map[string]any:
+		if len(params) > 0 {
+			return errors.New(fmt.Sprintf("%s takes no params", ATOMIC_BATCH_METHOD))
+		}
+	default:
+		return errors.New(fmt.Sprintf("%s takes no params", ATOMIC_BATCH_METHOD))
+	}
+
+	return nil
+}
+
+// Handle the entries after the marker one after the other. Once an entry fails, the compensations of the entries
+// that ran are called and every entry is answered with TRANSACTION_FAILED. Every entry is answered with
+// INVALID_REQUEST when the marker is not a notification without params
+func (s *jsonRpcImpl) handleAtomicBatch(ctx context.Context, requests []Request) []Response {
+	responses := make([]Response, len(requests))
+	if err := checkAtomicMarker(requests[0]); err != nil {
+		for i, req := range requests {
+			responses[i] = makeErrorResponse(err, INVALID_REQUEST, nil, req.Id)
+			responses[i].numericId = req.numericId && req.Id != nil
+		}
+		return responses
+	}
+	responses[0] = makeSuccessResponse(nil, nil)
+
+	ran := make([]*compensations, 0, len(requests)-1)
+	for i := 1; i < len(requests); i++ {
+		entry := &compensations{}
+		res := s.handleSingleRequest(context.WithValue(ctx, compensationsKey{}, entry), requests[i])
+		entry.close()
+
+		if res.Error == nil {
+			responses[i] = res
+			ran = append(ran, entry)
+			continue
+		}
+
+		failure := TransactionFailure{Index: i, Id: requests[i].Id, Error: res.Error}
+		for j := len(ran) - 1; j >= 0; j-- {
+			failure.CompensationErrors = append(failure.CompensationErrors, ran[j].run(detachedContext{ctx})...)
+		}
+		return transactionFailed(requests, failure)
+	}
+
+	return responses
+}
+
+func transactionFailed(requests []Request, failure TransactionFailure) []Response {
+	err := errors.New(fmt.Sprintf("Transaction failed: %s", failure.Error.Message))
+	var data any = failure
+
+	responses := make([]Response, len(requests))
+	for i, req := range requests {
+		responses[i] = makeErrorResponse(err, TRANSACTION_FAILED, &data, req.Id)
+		responses[i].numericId = req.numericId && req.Id != nil
+	}
+
+	return responses
+}
+
+// Ignore the compensations registered once the entry is answered, eg. by a handler still running after a timeout
+func (c *compensations) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.done = true
+}
+
+// Call the compensations in reverse order and return the messages of their errors. Panics are reported as errors
+func (c *compensations) run(ctx context.Context) []string {
+	var failed []string
+	for i := len(c.funcs) - 1; i >= 0; i-- {
+		if err := runCompensation(ctx, c.funcs[i]); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	return failed
+}
+
+func runCompensation(ctx context.Context, compensation func(ctx context.Context) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = errors.New(fmt.Sprintf("Compensation panicked: %v", recovered))
+		}
+	}()
+
+	return compensation(ctx)
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,181 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Reserves stock and releases it when compensated
+type stockroom struct {
+	mu       sync.Mutex
+	reserved []string
+	released []string
+}
+
+func (s *stockroom) Reserve(ctx context.Context, sku string) (string, error, *RpcErrorCode) {
+	if sku == "" {
+		code := INVALID_PARAMS
+		return "", errors.New("Sku is required"), &code
+	}
+
+	s.mu.Lock()
+	s.reserved = append(s.reserved, sku)
+	s.mu.Unlock()
+
+	Compensate(ctx, func(ctx context.Context) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if sku == "broken" {
+			return errors.New("Stock of broken can not be released")
+		}
+		if sku == "panicking" {
+			panic("released twice")
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.released = append(s.released, sku)
+		return nil
+	})
+	return sku, nil, nil
+}
+
+func atomicTestBatch(entries ...Request) []Request {
+	return append([]Request{{Method: ATOMIC_BATCH_METHOD}}, entries...)
+}
+
+func reserveTestRequest(id string, sku string) Request {
+	return Request{Id: &id, Method: "Stock.Reserve", Params: []any{sku}}
+}
+
+func TestAtomicBatch(t *testing.T) {
+	stock := &stockroom{}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(stock, "Stock")
+
+	body := serveTestBody(rpc, `[
+		{"jsonrpc":"2.0","method":"rpc.atomic"},
+		{"jsonrpc":"2.0","id":"1","method":"Stock.Reserve","params":["a"]},
+		{"jsonrpc":"2.0","id":"2","method":"Stock.Reserve","params":["b"]}
+	]`)
+
+	assert.JSONEq(t, `[{"jsonrpc":"2.0","id":"1","result":"a"},{"jsonrpc":"2.0","id":"2","result":"b"}]`, body)
+	assert.Equal(t, []string{"a", "b"}, stock.reserved)
+	assert.Empty(t, stock.released)
+}
+
+func TestAtomicBatchFailure(t *testing.T) {
+	stock := &stockroom{}
+	rpc := NewJsonRpc(WithBatchTimeout(time.Minute))
+	rpc.RegisterWithName(stock, "Stock")
+
+	responses := rpc.DispatchBatch(context.Background(), atomicTestBatch(
+		reserveTestRequest("1", "a"),
+		reserveTestRequest("2", "b"),
+		reserveTestRequest("3", ""),
+		reserveTestRequest("4", "c"),
+	))
+
+	//Entries after the failed one do not run, and the ones before are compensated in reverse order
+	assert.Equal(t, []string{"a", "b"}, stock.reserved)
+	assert.Equal(t, []string{"b", "a"}, stock.released)
+
+	assert.Len(t, responses, 5)
+	for i, res := range responses[1:] {
+		assert.Equal(t, TRANSACTION_FAILED, res.Error.Code)
+		assert.Equal(t, "Transaction failed: Sku is required", res.Error.Message)
+		assert.Equal(t, []string{"1", "2", "3", "4"}[i], *res.Id)
+	}
+
+	failure := (*responses[1].Error.Data.(*any)).(TransactionFailure)
+	assert.Equal(t, 3, failure.Index)
+	assert.Equal(t, "3", *failure.Id)
+	assert.Equal(t, INVALID_PARAMS, failure.Error.Code)
+	assert.Empty(t, failure.CompensationErrors)
+}
+
+func TestAtomicBatchCompensationErrors(t *testing.T) {
+	stock := &stockroom{}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(stock, "Stock")
+
+	body := serveTestBody(rpc, `[
+		{"jsonrpc":"2.0","method":"rpc.atomic"},
+		{"jsonrpc":"2.0","id":"1","method":"Stock.Reserve","params":["panicking"]},
+		{"jsonrpc":"2.0","id":"2","method":"Stock.Reserve","params":["broken"]},
+		{"jsonrpc":"2.0","id":"3","method":"Stock.Reserve","params":["a"]},
+		{"jsonrpc":"2.0","id":"4","method":"Stock.Missing"}
+	]`)
+
+	var responses []Response
+	assert.Nil(t, json.Unmarshal([]byte(body), &responses))
+	assert.Len(t, responses, 4)
+	assert.Equal(t, TRANSACTION_FAILED, responses[0].Error.Code)
+	assert.Equal(t, map[string]any{
+		"index": float64(4),
+		"id":    "4",
+		"error": map[string]any{"code": float64(METHOD_NOT_FOUND), "data": nil, "message": responses[3].Error.Message[len("Transaction failed: "):]},
+		"compensationErrors": []any{
+			"Stock of broken can not be released",
+			"Compensation panicked: released twice",
+		},
+	}, responses[3].Error.Data)
+	assert.Equal(t, []string{"a"}, stock.released)
+}
+
+func TestAtomicBatchInvalidMarker(t *testing.T) {
+	stock := &stockroom{}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(stock, "Stock")
+
+	//A marker with an id would get an empty response
+	body := serveTestBody(rpc, `[
+		{"jsonrpc":"2.0","id":"0","method":"rpc.atomic"},
+		{"jsonrpc":"2.0","id":"1","method":"Stock.Reserve","params":["a"]}
+	]`)
+	var responses []Response
+	assert.Nil(t, json.Unmarshal([]byte(body), &responses))
+	assert.Len(t, responses, 2)
+	for _, res := range responses {
+		assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+		assert.Equal(t, "rpc.atomic must be a notification", res.Error.Message)
+	}
+
+	//Params of the marker are refused before any entry runs
+	for _, params := range []any{[]any{"isolation"}, map[string]any{"isolation": "serializable"}, "serializable"} {
+		batch := atomicTestBatch(reserveTestRequest("1", "a"))
+		batch[0].Params = params
+		res := rpc.DispatchBatch(context.Background(), batch)[1]
+		assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+		assert.Equal(t, "rpc.atomic takes no params", res.Error.Message)
+	}
+	assert.Empty(t, stock.reserved)
+
+	//Empty params are fine
+	batch := atomicTestBatch(reserveTestRequest("1", "a"))
+	batch[0].Params = []any{}
+	assert.Equal(t, "a", *rpc.DispatchBatch(context.Background(), batch)[1].Result)
+}
+
+func TestCompensateOutsideAtomicBatch(t *testing.T) {
+	stock := &stockroom{}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(stock, "Stock")
+
+	//Entries of other batches run on their own, whether their siblings fail or not
+	responses := rpc.DispatchBatch(context.Background(), []Request{
+		reserveTestRequest("1", "a"),
+		reserveTestRequest("2", ""),
+	})
+
+	assert.Equal(t, "a", *responses[0].Result)
+	assert.Equal(t, INVALID_PARAMS, responses[1].Error.Code)
+	assert.Empty(t, stock.released)
+}
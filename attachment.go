@@ -0,0 +1,188 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// Media type a client accepts to receive attachments as a separate binary part
+const MULTIPART_RELATED = "multipart/related"
+
+// Content-ID of the binary part of a multipart response
+const ATTACHMENT_CONTENT_ID = "attachment"
+
+type (
+	//Attachment is a binary result, eg. a file, returned by a handler.
+	//For single requests over HTTP the content is streamed from Reader, either as a separate part of a
+	//multipart/related response when the client accepts it, or base64 encoded in the result otherwise.
+	//Batches and raw sockets read the whole content into memory to encode it.
+	Attachment struct {
+		ContentType string    //Media type of the content. eg. image/png
+		Name        string    //File name of the content, if any
+		Reader      io.Reader //Content. Closed after it is sent when it is an io.Closer
+	}
+
+	//Result sent in place of an attachment. Data holds the base64 content, Href the part holding the content
+	attachmentResult struct {
+		ContentType string `json:"contentType,omitempty"`
+		Name        string `json:"name,omitempty"`
+		Data        []byte `json:"data,omitempty"`
+		Href        string `json:"href,omitempty"`
+	}
+)
+
+// MarshalJSON reads the whole content and encodes it as base64
+func (a *Attachment) MarshalJSON() ([]byte, error) {
+	data, err := a.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(attachmentResult{ContentType: a.ContentType, Name: a.Name, Data: data})
+}
+
+// UnmarshalJSON decodes an attachment received as base64, so clients can decode results into *Attachment
+func (a *Attachment) UnmarshalJSON(data []byte) error {
+	var res attachmentResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+
+	a.ContentType = res.ContentType
+	a.Name = res.Name
+	a.Reader = bytes.NewReader(res.Data)
+
+	return nil
+}
+
+func (a *Attachment) readAll() ([]byte, error) {
+	if closer, ok := a.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	return io.ReadAll(a.Reader)
+}
+
+// Attachment returned as the result of the response, if any
+func attachmentOf(res Response) (*Attachment, bool) {
+	if res.Result == nil {
+		return nil, false
+	}
+
+	a, ok := (*res.Result).(*Attachment)
+	return a, ok && a != nil && a.Reader != nil
+}
+
+// Stream the attachment of the response in the format accepted by the client
+func (s *jsonRpcImpl) writeAttachmentResponse(w http.ResponseWriter, r *http.Request, res Response, a *Attachment) {
+	if closer, ok := a.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	var err error
+	if strings.Contains(r.Header.Get("Accept"), MULTIPART_RELATED) {
+		err = s.writeMultipartAttachment(w, res, a)
+	} else {
+		err = s.writeBase64Attachment(w, res, a)
+	}
+
+	if err != nil {
+		//Headers are sent already, the client sees a truncated response
+		s.cfg().logger.Printf("Unable to send attachment: %v", err)
+	}
+}
+
+// Write the response with the result as a reference to a second part holding the raw content
+func (s *jsonRpcImpl) writeMultipartAttachment(w http.ResponseWriter, res Response, a *Attachment) error {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", MULTIPART_RELATED+`; type="application/json"; boundary=`+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+
+	var result any = attachmentResult{ContentType: a.ContentType, Name: a.Name, Href: "cid:" + ATTACHMENT_CONTENT_ID}
+	res.Result = &result
+	body, err := s.cfg().marshal(&res, false)
+	if err != nil {
+		return err
+	}
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(body); err != nil {
+		return err
+	}
+
+	header := textproto.MIMEHeader{
+		"Content-Type": {a.ContentType},
+		"Content-Id":   {"<" + ATTACHMENT_CONTENT_ID + ">"},
+	}
+	if a.Name != "" {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	}
+
+	part, err = mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, a.Reader); err != nil {
+		return err
+	}
+
+	return mw.Close()
+}
+
+// Write the response with the content base64 encoded in the result, encoding it while it is read
+func (s *jsonRpcImpl) writeBase64Attachment(w http.ResponseWriter, res Response, a *Attachment) error {
+	//Encode the envelope with a placeholder result and splice the streamed result into it
+	placeholder := []byte(`{"$attachment":true}`)
+	var result any = json.RawMessage(placeholder)
+	res.Result = &result
+	envelope, err := s.cfg().marshal(&res, false)
+	if err != nil {
+		return err
+	}
+
+	i := bytes.LastIndex(envelope, placeholder)
+	if i < 0 {
+		return errors.New("Unable to encode attachment response")
+	}
+	head, tail := envelope[:i], envelope[i+len(placeholder):]
+
+	meta, err := json.Marshal(attachmentResult{ContentType: a.ContentType, Name: a.Name})
+	if err != nil {
+		return err
+	}
+	//Leave the object open for the data field
+	meta = bytes.TrimSuffix(meta, []byte(`}`))
+	if len(meta) > 1 {
+		meta = append(meta, ',')
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	w.Write(head)
+	w.Write(meta)
+	w.Write([]byte(`"data":"`))
+
+	encoder := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(encoder, a.Reader); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	w.Write([]byte(`"}`))
+	_, err = w.Write(tail)
+	return err
+}
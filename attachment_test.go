@@ -0,0 +1,82 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type files struct{}
+
+func (files) Get(ctx context.Context, name string) (*Attachment, error, *RpcErrorCode) {
+	return &Attachment{ContentType: "text/plain", Name: name, Reader: bytes.NewReader([]byte("hello " + name))}, nil, nil
+}
+
+func newTestFilesRpc() JsonRPC {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(files{}, "Files")
+
+	return rpc
+}
+
+func TestBase64Attachment(t *testing.T) {
+	body := serveTestBody(newTestFilesRpc(), `{"jsonrpc":"2.0","id":"1","method":"Files.Get","params":["a.txt"]}`)
+
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":{"contentType":"text/plain","name":"a.txt","data":"aGVsbG8gYS50eHQ="}}`, body)
+}
+
+func TestClientDecodesAttachment(t *testing.T) {
+	client := NewHTTPClient(newTestHTTPServer(t, newTestFilesRpc()))
+
+	var a Attachment
+	assert.NoError(t, client.Call(context.Background(), "Files.Get", []any{"a.txt"}, &a))
+
+	content, _ := io.ReadAll(a.Reader)
+	assert.Equal(t, "text/plain", a.ContentType)
+	assert.Equal(t, "hello a.txt", string(content))
+}
+
+func TestMultipartAttachment(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":"1","method":"Files.Get","params":["a.txt"]}`))
+	r.Header.Set("Accept", MULTIPART_RELATED)
+	newTestFilesRpc().ServeHTTP(recorder, r)
+
+	mediaType, params, err := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, MULTIPART_RELATED, mediaType)
+
+	reader := multipart.NewReader(recorder.Body, params["boundary"])
+
+	part, err := reader.NextPart()
+	assert.NoError(t, err)
+	envelope, _ := io.ReadAll(part)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":{"contentType":"text/plain","name":"a.txt","href":"cid:attachment"}}`, string(envelope))
+
+	part, err = reader.NextPart()
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(part)
+	assert.Equal(t, "<attachment>", part.Header.Get("Content-Id"))
+	assert.Equal(t, "a.txt", part.FileName())
+	assert.Equal(t, "hello a.txt", string(content))
+}
+
+func TestAttachmentInBatch(t *testing.T) {
+	body := serveTestBody(newTestFilesRpc(), `[{"jsonrpc":"2.0","id":"1","method":"Files.Get","params":["a.txt"]}]`)
+
+	var responses []struct {
+		Result Attachment `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &responses))
+
+	content, _ := io.ReadAll(responses[0].Result.Reader)
+	assert.Equal(t, "hello a.txt", string(content))
+}
@@ -0,0 +1,312 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Value that replaces redacted params in audit records
+const REDACTED = "[REDACTED]"
+
+type (
+	//AuditRecord is the audit trail entry of a call
+	AuditRecord struct {
+		Time      time.Time     `json:"time"`
+		Method    string        `json:"method"`
+		RequestId string        `json:"requestId,omitempty"` //Empty for notifications
+		Params    any           `json:"params"`              //Params with the redacted fields replaced
+		Caller    string        `json:"caller"`              //Identity of the caller. Defaults to the remote address
+		Duration  time.Duration `json:"duration"`
+		Code      RpcErrorCode  `json:"code,omitempty"`  //Zero for successful calls
+		Error     string        `json:"error,omitempty"` //Message of the error, if any
+	}
+
+	//AuditSink stores audit records, eg. in a file or database
+	AuditSink interface {
+		Audit(ctx context.Context, records []AuditRecord) error
+	}
+
+	//AuditSinkFunc is a function used as an AuditSink
+	AuditSinkFunc func(ctx context.Context, records []AuditRecord) error
+
+	//Audit settings of the server
+	auditConfig struct {
+		sink     AuditSink
+		redact   map[string]bool //Lower case field names redacted at any depth
+		paths    [][]string      //Dotted field paths redacted from the root of each param
+		identity func(ctx context.Context) string
+	}
+
+	//BatchingAuditSink buffers records and writes them to another sink in batches from a go routine
+	BatchingAuditSink struct {
+		sink     AuditSink
+		size     int
+		records  chan AuditRecord
+		done     chan struct{}
+		mu       sync.Mutex //Guards closed so records are never sent after the channel is closed
+		closed   bool
+		errorLog func(error)
+	}
+
+	//Writes records as JSON lines
+	writerAuditSink struct {
+		mu sync.Mutex
+		w  io.Writer
+	}
+)
+
+func (f AuditSinkFunc) Audit(ctx context.Context, records []AuditRecord) error {
+	return f(ctx, records)
+}
+
+// WithAudit records every call, including each entry of a batch, in the sink.
+// The sink is called before the response is sent, so slow sinks should be wrapped with NewBatchingAuditSink.
+func WithAudit(sink AuditSink) Option {
+	return func(c *config) {
+		c.auditConfig().sink = sink
+	}
+}
+
+// WithAuditRedaction replaces fields of the params with REDACTED in audit records.
+// A plain name, eg. password, is redacted at any depth regardless of case. A dotted path, eg. card.number,
+// is redacted from the root of each param.
+func WithAuditRedaction(fields ...string) Option {
+	return func(c *config) {
+		a := c.auditConfig()
+		for _, field := range fields {
+			if strings.Contains(field, ".") {
+				a.paths = append(a.paths, strings.Split(field, "."))
+				continue
+			}
+			a.redact[strings.ToLower(field)] = true
+		}
+	}
+}
+
+// WithAuditIdentity sets how the identity of the caller is read from the context, eg. the authenticated user
+func WithAuditIdentity(identity func(ctx context.Context) string) Option {
+	return func(c *config) {
+		c.auditConfig().identity = identity
+	}
+}
+
+func (c *config) auditConfig() *auditConfig {
+	if c.audit == nil {
+		c.audit = &auditConfig{redact: make(map[string]bool), identity: RemoteAddrFromContext}
+	}
+
+	return c.audit
+}
+
+func (a *auditConfig) clone() *auditConfig {
+	clone := *a
+	clone.redact = make(map[string]bool, len(a.redact))
+	for field := range a.redact {
+		clone.redact[field] = true
+	}
+	clone.paths = append([][]string(nil), a.paths...)
+
+	return &clone
+}
+
+func (rpc *jsonRpcImpl) audit(ctx context.Context, cfg *config, start time.Time, req Request, res Response) {
+	a := cfg.audit
+	if a == nil || a.sink == nil {
+		return
+	}
+
+	record := AuditRecord{
+		Time:     start,
+		Method:   req.Method,
+		Params:   a.redactParams(req.Params),
+		Caller:   a.identity(ctx),
+		Duration: time.Since(start),
+	}
+	if req.Id != nil {
+		record.RequestId = *req.Id
+	}
+	if res.Error != nil {
+		record.Code = res.Error.Code
+		record.Error = res.Error.Message
+	}
+
+	if err := a.sink.Audit(ctx, []AuditRecord{record}); err != nil {
+		cfg.logger.Printf("Unable to write audit record of %s: %v", req.Method, err)
+	}
+}
+
+// Copy of the params with the redacted fields replaced. The params of the request are not modified
+func (a *auditConfig) redactParams(params any) any {
+	if len(a.redact) == 0 && len(a.paths) == 0 {
+		return params
+	}
+
+	list, ok := params.([]any)
+	if !ok {
+		return a.redactValue(params, a.paths)
+	}
+
+	redacted := make([]any, len(list))
+	for i, param := range list {
+		redacted[i] = a.redactValue(param, a.paths)
+	}
+
+	return redacted
+}
+
+// Redact the value. paths are the dotted paths still to match below the value
+func (a *auditConfig) redactValue(value any, paths [][]string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, field := range v {
+			children := make([][]string, 0)
+			matched := a.redact[strings.ToLower(key)]
+			for _, path := range paths {
+				if path[0] != key {
+					continue
+				}
+				if len(path) == 1 {
+					matched = true
+				} else {
+					children = append(children, path[1:])
+				}
+			}
+
+			if matched {
+				redacted[key] = REDACTED
+			} else {
+				redacted[key] = a.redactValue(field, children)
+			}
+		}
+		return redacted
+
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = a.redactValue(item, paths)
+		}
+		return redacted
+
+	default:
+		return value
+	}
+}
+
+// NewWriterAuditSink writes each record as a line of JSON, eg. to an append only file
+func NewWriterAuditSink(w io.Writer) AuditSink {
+	return &writerAuditSink{w: w}
+}
+
+func (s *writerAuditSink) Audit(ctx context.Context, records []AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoder := json.NewEncoder(s.w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewBatchingAuditSink writes records to sink in batches of up to size records, or every interval when fewer are
+// buffered. Records are dropped with an error when more than 10 batches are waiting to be written.
+// Errors of sink are passed to errorLog, which can be nil. Close flushes the buffered records.
+func NewBatchingAuditSink(sink AuditSink, size int, interval time.Duration, errorLog func(error)) *BatchingAuditSink {
+	if size < 1 {
+		size = 1
+	}
+
+	s := &BatchingAuditSink{
+		sink:     sink,
+		size:     size,
+		records:  make(chan AuditRecord, size*10),
+		done:     make(chan struct{}),
+		errorLog: errorLog,
+	}
+	go s.run(interval)
+
+	return s
+}
+
+var (
+	errAuditBufferFull = errors.New("Audit buffer full. Record dropped")
+	errAuditSinkClosed = errors.New("Audit sink closed. Record dropped")
+)
+
+func (s *BatchingAuditSink) Audit(ctx context.Context, records []AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errAuditSinkClosed
+	}
+
+	for _, record := range records {
+		select {
+		case s.records <- record:
+		default:
+			return errAuditBufferFull
+		}
+	}
+
+	return nil
+}
+
+// Close writes the buffered records and stops the batching go routine. Records added after Close are dropped
+// with an error
+func (s *BatchingAuditSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.records)
+	}
+	s.mu.Unlock()
+
+	<-s.done
+
+	return nil
+}
+
+func (s *BatchingAuditSink) run(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]AuditRecord, 0, s.size)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.sink.Audit(context.Background(), batch); err != nil && s.errorLog != nil {
+			s.errorLog(err)
+		}
+		batch = make([]AuditRecord, 0, s.size)
+	}
+
+	for {
+		select {
+		case record, ok := <-s.records:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= s.size {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	batches [][]AuditRecord
+}
+
+func (s *memoryAuditSink) Audit(ctx context.Context, records []AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func (s *memoryAuditSink) records() []AuditRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]AuditRecord, 0)
+	for _, batch := range s.batches {
+		records = append(records, batch...)
+	}
+	return records
+}
+
+func TestAudit(t *testing.T) {
+	sink := &memoryAuditSink{}
+	rpc := NewJsonRpc(WithAudit(sink))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	serveTestBody(rpc, `[{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]},{"jsonrpc":"2.0","id":"2","method":"Arith.ErrorMethod","params":[]}]`)
+
+	records := sink.records()
+	assert.Len(t, records, 2)
+	for _, record := range records {
+		assert.Equal(t, "192.0.2.1:1234", record.Caller)
+		if record.Method == "Arith.ErrorMethod" {
+			assert.Equal(t, INTERNAL_ERROR, record.Code)
+			assert.Equal(t, "Some error here", record.Error)
+		} else {
+			assert.Equal(t, []any{float64(1), float64(2)}, record.Params)
+			assert.Zero(t, record.Code)
+		}
+	}
+}
+
+func TestAuditRedaction(t *testing.T) {
+	sink := &memoryAuditSink{}
+	rpc := NewJsonRpc(WithAudit(sink), WithAuditRedaction("Password", "card.number"))
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":[{"user":{"password":"secret"},"card":{"number":"4111","expiry":"12/30"},"number":7}]}`)
+
+	assert.Equal(t, []any{map[string]any{
+		"user":   map[string]any{"password": REDACTED},
+		"card":   map[string]any{"number": REDACTED, "expiry": "12/30"},
+		"number": float64(7),
+	}}, sink.records()[0].Params)
+}
+
+func TestAuditIdentity(t *testing.T) {
+	sink := &memoryAuditSink{}
+	rpc := NewJsonRpc(WithAudit(sink), WithAuditIdentity(func(ctx context.Context) string { return "alice" }))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)
+
+	assert.Equal(t, "alice", sink.records()[0].Caller)
+}
+
+func TestBatchingAuditSink(t *testing.T) {
+	sink := &memoryAuditSink{}
+	batching := NewBatchingAuditSink(sink, 2, time.Hour, nil)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, batching.Audit(context.Background(), []AuditRecord{{Method: "Arith.Add"}}))
+	}
+	batching.Close()
+
+	assert.Len(t, sink.records(), 5)
+	assert.Len(t, sink.batches, 3)
+}
+
+func TestBatchingAuditSinkAfterClose(t *testing.T) {
+	sink := &memoryAuditSink{}
+	batching := NewBatchingAuditSink(sink, 2, time.Hour, nil)
+	batching.Close()
+
+	assert.Equal(t, errAuditSinkClosed, batching.Audit(context.Background(), []AuditRecord{{Method: "Arith.Add"}}))
+	assert.NoError(t, batching.Close())
+	assert.Empty(t, sink.records())
+}
+
+func TestWriterAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterAuditSink(&buf)
+
+	sink.Audit(context.Background(), []AuditRecord{{Method: "Arith.Add"}, {Method: "Arith.ErrorMethod"}})
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+
+	var record AuditRecord
+	assert.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.Equal(t, "Arith.ErrorMethod", record.Method)
+}
@@ -0,0 +1,115 @@
+// Package auth provides middlewares authenticating the callers of a jsonrpc2 server with API keys, JWTs or signed
+// requests. The authenticated principal is available to handlers through PrincipalFromContext.
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+)
+
+// Header carrying the API key of a request
+const API_KEY_HEADER = "X-API-Key"
+
+type (
+	//Principal is the authenticated caller of a request
+	Principal struct {
+		ID     string //Owner of the API key, subject of the JWT or key id of the signature
+		Claims Claims //Claims of the JWT. Nil for API keys
+	}
+
+	//APIKeyLookup returns the principal owning the key, or an error when the key is unknown or revoked
+	APIKeyLookup func(ctx context.Context, key string) (*Principal, error)
+
+	principalKey struct{}
+)
+
+var (
+	errMissingAPIKey = errors.New("Missing API key")
+	errMissingToken  = errors.New("Missing bearer token")
+)
+
+// PrincipalFromContext returns the principal authenticated by the APIKey or JWT middleware
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// ContextWithPrincipal returns a context carrying the principal, eg. to call handlers in tests
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// QuotaKey counts the calls of jsonrpc2.WithQuotas per authenticated principal. Calls without a principal are not
+// counted
+func QuotaKey(ctx context.Context, req *jsonrpc2.Request) string {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		return principal.ID
+	}
+
+	return ""
+}
+
+// APIKey authenticates requests with the key in the X-API-Key header, resolved to a principal by lookup.
+// Requests without a known key fail with UNAUTHORIZED.
+func APIKey(lookup APIKeyLookup) jsonrpc2.Middleware {
+	return func(next jsonrpc2.Handler) jsonrpc2.Handler {
+		return func(ctx context.Context, req *jsonrpc2.Request) jsonrpc2.Response {
+			key := header(ctx, API_KEY_HEADER)
+			if key == "" {
+				return unauthorized(errMissingAPIKey, req)
+			}
+
+			principal, err := lookup(ctx, key)
+			if err != nil {
+				return unauthorized(err, req)
+			}
+
+			return next(ContextWithPrincipal(ctx, principal), req)
+		}
+	}
+}
+
+// JWT authenticates requests with the bearer token of the Authorization header. keyfunc returns the key verifying
+// the token, eg. by its kid header. withClaims, which can be nil, adds values from the claims to the context or
+// rejects the token with an error. Requests without a valid token fail with UNAUTHORIZED.
+func JWT(keyfunc Keyfunc, withClaims func(ctx context.Context, claims Claims) (context.Context, error)) jsonrpc2.Middleware {
+	return func(next jsonrpc2.Handler) jsonrpc2.Handler {
+		return func(ctx context.Context, req *jsonrpc2.Request) jsonrpc2.Response {
+			raw, ok := strings.CutPrefix(header(ctx, "Authorization"), "Bearer ")
+			if !ok || raw == "" {
+				return unauthorized(errMissingToken, req)
+			}
+
+			token, err := Parse(raw, keyfunc)
+			if err != nil {
+				return unauthorized(err, req)
+			}
+
+			ctx = ContextWithPrincipal(ctx, &Principal{ID: token.Claims.Subject(), Claims: token.Claims})
+			if withClaims != nil {
+				if ctx, err = withClaims(ctx, token.Claims); err != nil {
+					return unauthorized(err, req)
+				}
+			}
+
+			return next(ctx, req)
+		}
+	}
+}
+
+// Header of the HTTP request. Empty for other transports
+func header(ctx context.Context, name string) string {
+	r := jsonrpc2.HTTPRequestFromContext(ctx)
+	if r == nil {
+		return ""
+	}
+
+	return r.Header.Get(name)
+}
+
+func unauthorized(err error, req *jsonrpc2.Request) jsonrpc2.Response {
+	return jsonrpc2.NewErrorResponse(err, jsonrpc2.UNAUTHORIZED, req.Id)
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantKey struct{}
+
+type whoami struct{}
+
+func (whoami) Get(ctx context.Context) (string, error, *jsonrpc2.RpcErrorCode) {
+	principal, _ := PrincipalFromContext(ctx)
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+
+	return principal.ID + tenant, nil, nil
+}
+
+var secret = []byte("secret")
+
+func signHS256(claims Claims) string {
+	signed := encodeSegment(map[string]any{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+	mac := hmac.New(crypto.SHA256.New, secret)
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeSegment(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hsKey(token *Token) (any, error) {
+	return secret, nil
+}
+
+// Call whoami.Get through a server using the middleware with the headers and return the response
+func callWhoami(t *testing.T, middleware jsonrpc2.Middleware, header http.Header) jsonrpc2.Response {
+	rpc := jsonrpc2.NewJsonRpc(jsonrpc2.WithMiddleware(middleware))
+	rpc.Register(whoami{})
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":"1","method":"whoami.Get"}`))
+	for key, values := range header {
+		r.Header.Set(key, values[0])
+	}
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, r)
+
+	var res jsonrpc2.Response
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &res))
+	return res
+}
+
+func lookupKey(ctx context.Context, key string) (*Principal, error) {
+	if key != "key-1" {
+		return nil, errors.New("Unknown API key")
+	}
+
+	return &Principal{ID: "service-a"}, nil
+}
+
+func TestAPIKey(t *testing.T) {
+	res := callWhoami(t, APIKey(lookupKey), http.Header{API_KEY_HEADER: {"key-1"}})
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, any("service-a"), *res.Result)
+}
+
+func TestAPIKeyRejected(t *testing.T) {
+	res := callWhoami(t, APIKey(lookupKey), http.Header{API_KEY_HEADER: {"key-2"}})
+	assert.Equal(t, jsonrpc2.UNAUTHORIZED, res.Error.Code)
+	assert.Equal(t, "Unknown API key", res.Error.Message)
+
+	res = callWhoami(t, APIKey(lookupKey), http.Header{})
+	assert.Equal(t, jsonrpc2.UNAUTHORIZED, res.Error.Code)
+	assert.Equal(t, "Missing API key", res.Error.Message)
+}
+
+func TestJWT(t *testing.T) {
+	token := signHS256(Claims{"sub": "ada", "tenant": "acme", "exp": time.Now().Add(time.Hour).Unix()})
+	withTenant := func(ctx context.Context, claims Claims) (context.Context, error) {
+		return context.WithValue(ctx, tenantKey{}, "@"+claims["tenant"].(string)), nil
+	}
+
+	res := callWhoami(t, JWT(hsKey, withTenant), http.Header{"Authorization": {"Bearer " + token}})
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, any("ada@acme"), *res.Result)
+}
+
+func TestJWTRejected(t *testing.T) {
+	valid := signHS256(Claims{"sub": "ada"})
+	tests := map[string]string{
+		"":                       "Missing bearer token",
+		"Basic YWRhOnNlY3JldA==": "Missing bearer token",
+		"Bearer not-a-token":     "Malformed token",
+		"Bearer " + valid + "x":  "Invalid token signature",
+		"Bearer " + signHS256(Claims{"sub": "ada", "exp": time.Now().Add(-time.Minute).Unix()}): "Token is expired",
+		"Bearer " + signHS256(Claims{"sub": "ada", "nbf": time.Now().Add(time.Hour).Unix()}):    "Token is not valid yet",
+	}
+
+	for authorization, message := range tests {
+		res := callWhoami(t, JWT(hsKey, nil), http.Header{"Authorization": {authorization}})
+
+		assert.Equal(t, jsonrpc2.UNAUTHORIZED, res.Error.Code, authorization)
+		assert.Equal(t, message, res.Error.Message, authorization)
+	}
+}
+
+func TestJWTRejectedByClaims(t *testing.T) {
+	token := signHS256(Claims{"sub": "ada"})
+	requireAdmin := func(ctx context.Context, claims Claims) (context.Context, error) {
+		return ctx, errors.New("Admin role required")
+	}
+
+	res := callWhoami(t, JWT(hsKey, requireAdmin), http.Header{"Authorization": {"Bearer " + token}})
+
+	assert.Equal(t, jsonrpc2.UNAUTHORIZED, res.Error.Code)
+	assert.Equal(t, "Admin role required", res.Error.Message)
+}
+
+func TestParseES256(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signed := encodeSegment(map[string]any{"alg": "ES256", "kid": "k1"}) + "." + encodeSegment(Claims{"sub": "ada"})
+	digest := crypto.SHA256.New()
+	digest.Write([]byte(signed))
+	r, s, _ := ecdsa.Sign(rand.Reader, key, digest.Sum(nil))
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	raw := signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+
+	token, err := Parse(raw, func(token *Token) (any, error) {
+		assert.Equal(t, "k1", token.Header["kid"])
+		return &key.PublicKey, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "ada", token.Claims.Subject())
+
+	//A public key is never accepted as a HMAC secret
+	_, err = Parse(signHS256(Claims{"sub": "ada"}), func(token *Token) (any, error) { return &key.PublicKey, nil })
+	assert.Equal(t, "Key of HS256 must be []byte", err.Error())
+}
+
+func TestParseRejectsNone(t *testing.T) {
+	raw := encodeSegment(map[string]any{"alg": "none"}) + "." + encodeSegment(Claims{"sub": "ada"}) + "."
+
+	_, err := Parse(raw, hsKey)
+
+	assert.Equal(t, "Unsupported signing algorithm none", err.Error())
+}
+
+func TestQuotaKey(t *testing.T) {
+	ctx := ContextWithPrincipal(context.Background(), &Principal{ID: "service-a"})
+	assert.Equal(t, "service-a", QuotaKey(ctx, &jsonrpc2.Request{}))
+	assert.Equal(t, "", QuotaKey(context.Background(), &jsonrpc2.Request{}))
+}
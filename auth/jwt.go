@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+type (
+	//Claims of a JWT
+	Claims map[string]any
+
+	//Token is a parsed JWT
+	Token struct {
+		Header map[string]any
+		Claims Claims
+		Alg    string //Signing algorithm from the header. eg. HS256
+	}
+
+	//Keyfunc returns the key verifying a token: []byte for HS*, *rsa.PublicKey for RS* and PS*,
+	//*ecdsa.PublicKey for ES*. The header of the token is parsed but not verified yet.
+	Keyfunc func(token *Token) (any, error)
+)
+
+var (
+	errMalformedToken = errors.New("Malformed token")
+	errInvalidSig     = errors.New("Invalid token signature")
+	errExpiredToken   = errors.New("Token is expired")
+	errEarlyToken     = errors.New("Token is not valid yet")
+)
+
+// Hash of each supported algorithm
+var algHashes = map[string]crypto.Hash{
+	"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// Parse verifies the signature of a compact JWT with the key of keyfunc and checks its exp and nbf claims
+func Parse(raw string, keyfunc Keyfunc) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+
+	token := &Token{}
+	if err := decodeSegment(parts[0], &token.Header); err != nil {
+		return nil, err
+	}
+	if err := decodeSegment(parts[1], &token.Claims); err != nil {
+		return nil, err
+	}
+	token.Alg, _ = token.Header["alg"].(string)
+
+	hash, ok := algHashes[token.Alg]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Unsupported signing algorithm %s", token.Alg))
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+
+	key, err := keyfunc(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(token.Alg, hash, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	if err := token.Claims.validate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errMalformedToken
+	}
+
+	return nil
+}
+
+// Verify the signature with a key of the type the algorithm expects, so a public key is never used as a HMAC secret
+func verify(alg string, hash crypto.Hash, key any, signed string, sig []byte) error {
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return errors.New(fmt.Sprintf("Key of %s must be []byte", alg))
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errInvalidSig
+		}
+
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New(fmt.Sprintf("Key of %s must be *rsa.PublicKey", alg))
+		}
+		var err error
+		if alg[:2] == "RS" {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+		} else {
+			err = rsa.VerifyPSS(pub, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		if err != nil {
+			return errInvalidSig
+		}
+
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New(fmt.Sprintf("Key of %s must be *ecdsa.PublicKey", alg))
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errInvalidSig
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errInvalidSig
+		}
+	}
+
+	return nil
+}
+
+// Check the time claims. Tokens without them do not expire
+func (c Claims) validate(now time.Time) error {
+	if exp, ok := c["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return errExpiredToken
+	}
+	if nbf, ok := c["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return errEarlyToken
+	}
+
+	return nil
+}
+
+// Subject returns the sub claim
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
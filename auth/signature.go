@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+)
+
+// Headers of signed requests
+const (
+	SIGNATURE_HEADER = "X-RPC-Signature" //HMAC-SHA256 of the signed payload, base64url encoded
+	KEY_ID_HEADER    = "X-RPC-Key-Id"    //Identifies the key, and the caller
+	TIMESTAMP_HEADER = "X-RPC-Timestamp" //Unix time the request was signed at, in seconds
+	NONCE_HEADER     = "X-RPC-Nonce"     //Random value making every signed request unique
+)
+
+// Time a signed request is accepted for, either side of the clock of the server, unless SignatureOptions sets it
+const DEFAULT_SIGNATURE_MAX_SKEW = 5 * time.Minute
+
+type (
+	//SigningKeyLookup returns the secret of the key id, or an error when the key is unknown or revoked
+	SigningKeyLookup func(ctx context.Context, keyID string) ([]byte, error)
+
+	//SignatureOptions tunes the verification of signed requests
+	SignatureOptions struct {
+		MaxSkew time.Duration //Age of the requests accepted. DEFAULT_SIGNATURE_MAX_SKEW when zero
+		Nonces  NonceStore    //Remembers the nonces of accepted requests. In memory when nil
+	}
+
+	//NonceStore remembers nonces until they expire, so a request can not be replayed. Share one store, eg. backed
+	//by Redis, between the instances of a deployment. Implementations must be safe for concurrent use
+	NonceStore interface {
+		//Remember the nonce until expires. False when it is already remembered
+		Remember(ctx context.Context, nonce string, expires time.Time) (bool, error)
+	}
+
+	//NonceStore keeping the nonces in memory
+	memoryNonceStore struct {
+		mu     sync.Mutex
+		nonces map[string]time.Time
+		pruned time.Time
+	}
+)
+
+var (
+	errMissingSignature = errors.New("Missing request signature")
+	errInvalidSignature = errors.New("Invalid request signature")
+	errStaleSignature   = errors.New("Request signature is expired")
+	errReplayedRequest  = errors.New("Request was already received")
+)
+
+// SignRequests signs every call and notification of a client with the secret of the key id. The signature covers
+// the method, params and id of the request with a timestamp and a nonce, and is sent in headers, so it needs the
+// HTTP transport. The server checks it with VerifySignatures.
+func SignRequests(keyID string, secret []byte) jsonrpc2.ClientMiddleware {
+	return func(next jsonrpc2.Invoker) jsonrpc2.Invoker {
+		return func(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+			nonce := make([]byte, 16)
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			encodedNonce := base64.RawURLEncoding.EncodeToString(nonce)
+			signature, err := signRequest(secret, req, timestamp, encodedNonce)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx = jsonrpc2.ContextWithHeader(ctx, KEY_ID_HEADER, keyID)
+			ctx = jsonrpc2.ContextWithHeader(ctx, TIMESTAMP_HEADER, timestamp)
+			ctx = jsonrpc2.ContextWithHeader(ctx, NONCE_HEADER, encodedNonce)
+			ctx = jsonrpc2.ContextWithHeader(ctx, SIGNATURE_HEADER, signature)
+
+			return next(ctx, req)
+		}
+	}
+}
+
+// VerifySignatures authenticates requests signed by SignRequests with the secret lookup returns for their key id.
+// Requests signed longer ago than the max skew, or whose nonce was already received, are rejected so they can not be
+// replayed. The key id becomes the ID of the principal. Requests without a valid signature fail with UNAUTHORIZED.
+// Add it before middleware modifying requests, since the signature covers the request as sent. The entries of a
+// batch share the headers of the HTTP request, so only single requests can be verified.
+func VerifySignatures(lookup SigningKeyLookup, opts SignatureOptions) jsonrpc2.Middleware {
+	maxSkew := opts.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = DEFAULT_SIGNATURE_MAX_SKEW
+	}
+	nonces := opts.Nonces
+	if nonces == nil {
+		nonces = NewMemoryNonceStore()
+	}
+
+	return func(next jsonrpc2.Handler) jsonrpc2.Handler {
+		return func(ctx context.Context, req *jsonrpc2.Request) jsonrpc2.Response {
+			keyID, signature := header(ctx, KEY_ID_HEADER), header(ctx, SIGNATURE_HEADER)
+			timestamp, nonce := header(ctx, TIMESTAMP_HEADER), header(ctx, NONCE_HEADER)
+			if keyID == "" || signature == "" || timestamp == "" || nonce == "" {
+				return unauthorized(errMissingSignature, req)
+			}
+
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return unauthorized(errInvalidSignature, req)
+			}
+			signedAt := time.Unix(unix, 0)
+			if skew := time.Since(signedAt); skew > maxSkew || skew < -maxSkew {
+				return unauthorized(errStaleSignature, req)
+			}
+
+			secret, err := lookup(ctx, keyID)
+			if err != nil {
+				return unauthorized(err, req)
+			}
+			expected, err := signRequest(secret, req, timestamp, nonce)
+			if err != nil || !hmac.Equal([]byte(expected), []byte(signature)) {
+				return unauthorized(errInvalidSignature, req)
+			}
+
+			//Checked once the signature is valid, so forged requests do not fill the store
+			fresh, err := nonces.Remember(ctx, keyID+":"+nonce, signedAt.Add(maxSkew))
+			if err != nil {
+				return jsonrpc2.NewErrorResponse(errors.New(fmt.Sprintf("Unable to check nonce: %v", err)), jsonrpc2.INTERNAL_ERROR, req.Id)
+			}
+			if !fresh {
+				return unauthorized(errReplayedRequest, req)
+			}
+
+			return next(ContextWithPrincipal(ctx, &Principal{ID: keyID}), req)
+		}
+	}
+}
+
+// NewMemoryNonceStore returns a NonceStore keeping the nonces in memory. Expired nonces are pruned as new ones are
+// remembered
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *memoryNonceStore) Remember(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.pruned) > time.Minute {
+		for n, exp := range s.nonces {
+			if now.After(exp) {
+				delete(s.nonces, n)
+			}
+		}
+		s.pruned = now
+	}
+
+	if exp, ok := s.nonces[nonce]; ok && !now.After(exp) {
+		return false, nil
+	}
+	s.nonces[nonce] = expires
+
+	return true, nil
+}
+
+// HMAC-SHA256 of the timestamp, nonce and canonical request, base64url encoded
+func signRequest(secret []byte, req *jsonrpc2.Request, timestamp, nonce string) (string, error) {
+	canonical, err := canonicalRequest(req)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n"))
+	mac.Write(canonical)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Encoding of the method, params and id of a request that is the same for the client and the server. Params are
+// decoded like the server does, with numbers as float64, then encoded with the keys of objects sorted
+func canonicalRequest(req *jsonrpc2.Request) ([]byte, error) {
+	params, err := json.Marshal(req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+	if err := json.Unmarshal(params, &decoded); err != nil {
+		return nil, err
+	}
+	//Calls without params are sent with an empty array
+	if decoded == nil {
+		decoded = []any{}
+	}
+
+	return json.Marshal(map[string]any{"method": req.Method, "params": decoded, "id": req.Id})
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+)
+
+type arith struct{}
+
+func (arith) Add(ctx context.Context, a, b float64) (float64, error, *jsonrpc2.RpcErrorCode) {
+	return a + b, nil, nil
+}
+
+func lookupSigningKey(ctx context.Context, keyID string) ([]byte, error) {
+	if keyID != "service-a" {
+		return nil, errors.New("Unknown signing key")
+	}
+
+	return secret, nil
+}
+
+// Server verifying signatures, and the requests it received as sent on the wire
+func newSignedServer(t *testing.T, opts SignatureOptions) (string, *[]*http.Request) {
+	rpc := jsonrpc2.NewJsonRpc(jsonrpc2.WithMiddleware(VerifySignatures(lookupSigningKey, opts)))
+	rpc.Register(arith{})
+	rpc.Register(whoami{})
+
+	var received []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		copied := r.Clone(context.Background())
+		copied.Body = io.NopCloser(strings.NewReader(string(body)))
+		received = append(received, copied)
+
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		rpc.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL, &received
+}
+
+func rpcErrorOf(err error) *jsonrpc2.RpcError {
+	var rpcErr *jsonrpc2.RpcError
+	errors.As(err, &rpcErr)
+	return rpcErr
+}
+
+func TestSignedRequests(t *testing.T) {
+	url, _ := newSignedServer(t, SignatureOptions{})
+	client := jsonrpc2.NewHTTPClient(url)
+	client.Use(SignRequests("service-a", secret))
+
+	var sum float64
+	assert.Nil(t, client.Call(context.Background(), "arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, float64(3), sum)
+
+	var id string
+	assert.Nil(t, client.Call(context.Background(), "whoami.Get", nil, &id))
+	assert.Equal(t, "service-a", id)
+}
+
+func TestUnsignedRequestsRejected(t *testing.T) {
+	url, _ := newSignedServer(t, SignatureOptions{})
+
+	err := jsonrpc2.NewHTTPClient(url).Call(context.Background(), "arith.Add", []any{1, 2}, nil)
+	assert.Equal(t, jsonrpc2.UNAUTHORIZED, rpcErrorOf(err).Code)
+	assert.Equal(t, "Missing request signature", rpcErrorOf(err).Message)
+
+	unknown := jsonrpc2.NewHTTPClient(url)
+	unknown.Use(SignRequests("service-b", secret))
+	err = unknown.Call(context.Background(), "arith.Add", []any{1, 2}, nil)
+	assert.Equal(t, "Unknown signing key", rpcErrorOf(err).Message)
+
+	wrongSecret := jsonrpc2.NewHTTPClient(url)
+	wrongSecret.Use(SignRequests("service-a", []byte("other")))
+	err = wrongSecret.Call(context.Background(), "arith.Add", []any{1, 2}, nil)
+	assert.Equal(t, "Invalid request signature", rpcErrorOf(err).Message)
+
+	//Params changed after signing
+	tampered := jsonrpc2.NewHTTPClient(url)
+	tampered.Use(SignRequests("service-a", secret), func(next jsonrpc2.Invoker) jsonrpc2.Invoker {
+		return func(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+			changed := *req
+			changed.Params = []any{1, 1000}
+			return next(ctx, &changed)
+		}
+	})
+	err = tampered.Call(context.Background(), "arith.Add", []any{1, 2}, nil)
+	assert.Equal(t, "Invalid request signature", rpcErrorOf(err).Message)
+}
+
+func TestReplayedRequestsRejected(t *testing.T) {
+	url, received := newSignedServer(t, SignatureOptions{})
+	client := jsonrpc2.NewHTTPClient(url)
+	client.Use(SignRequests("service-a", secret))
+	assert.Nil(t, client.Call(context.Background(), "arith.Add", []any{1, 2}, nil))
+
+	//Send the captured request again
+	replay := (*received)[0]
+	r, _ := http.NewRequest(http.MethodPost, url, replay.Body)
+	r.Header = replay.Header
+	res, err := http.DefaultClient.Do(r)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	assert.Contains(t, string(body), "Request was already received")
+}
+
+func TestStaleSignatureRejected(t *testing.T) {
+	url, _ := newSignedServer(t, SignatureOptions{MaxSkew: time.Minute})
+
+	client := jsonrpc2.NewHTTPClient(url)
+	client.Use(func(next jsonrpc2.Invoker) jsonrpc2.Invoker {
+		return func(ctx context.Context, req *jsonrpc2.Request) (*jsonrpc2.Response, error) {
+			timestamp := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+			signature, _ := signRequest(secret, req, timestamp, "nonce")
+
+			ctx = jsonrpc2.ContextWithHeader(ctx, KEY_ID_HEADER, "service-a")
+			ctx = jsonrpc2.ContextWithHeader(ctx, TIMESTAMP_HEADER, timestamp)
+			ctx = jsonrpc2.ContextWithHeader(ctx, NONCE_HEADER, "nonce")
+			ctx = jsonrpc2.ContextWithHeader(ctx, SIGNATURE_HEADER, signature)
+			return next(ctx, req)
+		}
+	})
+
+	err := client.Call(context.Background(), "arith.Add", []any{1, 2}, nil)
+	assert.Equal(t, "Request signature is expired", rpcErrorOf(err).Message)
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	ctx := context.Background()
+
+	fresh, _ := store.Remember(ctx, "a", time.Now().Add(time.Minute))
+	assert.True(t, fresh)
+	fresh, _ = store.Remember(ctx, "a", time.Now().Add(time.Minute))
+	assert.False(t, fresh)
+
+	//Expired nonces can be used again, since their requests are rejected as stale anyway
+	store.Remember(ctx, "b", time.Now().Add(-time.Second))
+	fresh, _ = store.Remember(ctx, "b", time.Now().Add(time.Minute))
+	assert.True(t, fresh)
+}
@@ -0,0 +1,194 @@
+package jsonrpc2
+
+import (
+	"sync"
+	"time"
+)
+
+// BackpressurePolicy decides what happens to messages for a persistent connection whose write buffer is full,
+// eg. a client that reads subscription events slower than they are published
+type BackpressurePolicy int
+
+const (
+	BACKPRESSURE_BLOCK       BackpressurePolicy = iota //Wait for room in the buffer, then disconnect the client after the timeout. Zero timeout waits forever
+	BACKPRESSURE_DROP_OLDEST                           //Drop the oldest buffered event to make room. Responses are never dropped
+	BACKPRESSURE_DISCONNECT                            //Disconnect the client
+)
+
+const (
+	DEFAULT_WRITE_BUFFER_SIZE = 64              //Messages buffered per connection when WithWriteBuffer is not used
+	DEFAULT_WRITE_TIMEOUT     = 5 * time.Second //Wait for room in the buffer of a raw socket before disconnecting it, when WithWriteBuffer is not used
+)
+
+type (
+	//Write buffer settings of persistent connections
+	writeBufferConfig struct {
+		size    int
+		policy  BackpressurePolicy
+		timeout time.Duration
+	}
+
+	//Messages waiting to be written to a connection. The transport owns the loop writing them. Events and responses
+	//have their own buffers, so the policy dropping events never drops a response
+	writeQueue struct {
+		messages   chan []byte   //Subscription events, which the policy may drop
+		responses  chan []byte   //Responses and other messages the client waits for. Written before the events
+		done       chan struct{} //Closed when the client is disconnected
+		config     writeBufferConfig
+		dropMu     sync.Mutex //Serializes the drops of BACKPRESSURE_DROP_OLDEST
+		disconnect sync.Once
+		onDrop     func()
+		onClose    func() //Closes the connection of the client
+	}
+)
+
+// WithWriteBuffer buffers up to size messages per raw socket and SSE connection, so a slow client does not hold up
+// the handlers and publishers of the server. policy decides what happens to subscription events when the buffer is
+// full, timeout is used by BACKPRESSURE_BLOCK. Responses are never dropped: with BACKPRESSURE_DROP_OLDEST they wait
+// for room like BACKPRESSURE_BLOCK, for DEFAULT_WRITE_TIMEOUT when timeout is zero. By default raw sockets block
+// for DEFAULT_WRITE_TIMEOUT and SSE connections drop the oldest events.
+func WithWriteBuffer(size int, policy BackpressurePolicy, timeout time.Duration) Option {
+	return func(c *config) {
+		if size < 1 {
+			size = 1
+		}
+		c.writeBuffer = &writeBufferConfig{size: size, policy: policy, timeout: timeout}
+	}
+}
+
+// Write buffer of a transport. defaultPolicy applies when WithWriteBuffer is not used
+func (c *config) writeBufferFor(defaultPolicy BackpressurePolicy) writeBufferConfig {
+	if c.writeBuffer != nil {
+		return *c.writeBuffer
+	}
+
+	config := writeBufferConfig{size: DEFAULT_WRITE_BUFFER_SIZE, policy: defaultPolicy}
+	if defaultPolicy == BACKPRESSURE_BLOCK {
+		config.timeout = DEFAULT_WRITE_TIMEOUT
+	}
+	return config
+}
+
+// onDrop is called for each dropped message and onClose when the client is disconnected. Both can be nil
+func newWriteQueue(config writeBufferConfig, onDrop func(), onClose func()) *writeQueue {
+	return &writeQueue{
+		messages:  make(chan []byte, config.size),
+		responses: make(chan []byte, config.size),
+		done:      make(chan struct{}),
+		config:    config,
+		onDrop:    onDrop,
+		onClose:   onClose,
+	}
+}
+
+// Queue the event following the policy. False is returned when the client is disconnected
+func (q *writeQueue) push(msg []byte) bool {
+	if q.closed() {
+		return false
+	}
+
+	select {
+	case q.messages <- msg:
+		return true
+	default:
+	}
+
+	switch q.config.policy {
+	case BACKPRESSURE_DROP_OLDEST:
+		q.dropMu.Lock()
+		defer q.dropMu.Unlock()
+
+		for {
+			select {
+			case q.messages <- msg:
+				return true
+			default:
+			}
+
+			select {
+			case <-q.messages:
+				if q.onDrop != nil {
+					q.onDrop()
+				}
+			default:
+			}
+		}
+
+	case BACKPRESSURE_DISCONNECT:
+		q.close()
+		return false
+
+	default:
+		return q.wait(q.messages, msg, q.config.timeout)
+	}
+}
+
+// Queue a response, or another message the client waits for. It is never dropped: the client is disconnected when
+// there is no room for it in time, or at once with BACKPRESSURE_DISCONNECT
+func (q *writeQueue) pushResponse(msg []byte) bool {
+	if q.closed() {
+		return false
+	}
+
+	select {
+	case q.responses <- msg:
+		return true
+	default:
+	}
+
+	switch q.config.policy {
+	case BACKPRESSURE_DISCONNECT:
+		q.close()
+		return false
+
+	case BACKPRESSURE_DROP_OLDEST:
+		timeout := q.config.timeout
+		if timeout == 0 {
+			timeout = DEFAULT_WRITE_TIMEOUT
+		}
+		return q.wait(q.responses, msg, timeout)
+
+	default:
+		return q.wait(q.responses, msg, q.config.timeout)
+	}
+}
+
+// Wait for room for the message, then disconnect the client after the timeout. Zero timeout waits forever
+func (q *writeQueue) wait(buffer chan []byte, msg []byte, timeout time.Duration) bool {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case buffer <- msg:
+		return true
+	case <-q.done:
+		return false
+	case <-expired:
+		q.close()
+		return false
+	}
+}
+
+// Whether the client is disconnected. Checked before queueing since a select picks randomly among ready cases
+func (q *writeQueue) closed() bool {
+	select {
+	case <-q.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Disconnect the client. Pending pushes return false
+func (q *writeQueue) close() {
+	q.disconnect.Do(func() {
+		close(q.done)
+		if q.onClose != nil {
+			q.onClose()
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package jsonrpc2
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteQueueDropOldest(t *testing.T) {
+	var dropped atomic.Int32
+	queue := newWriteQueue(writeBufferConfig{size: 2, policy: BACKPRESSURE_DROP_OLDEST}, func() { dropped.Add(1) }, nil)
+
+	for _, msg := range []string{"1", "2", "3"} {
+		assert.True(t, queue.push([]byte(msg)))
+	}
+
+	assert.Equal(t, int32(1), dropped.Load())
+	assert.Equal(t, "2", string(<-queue.messages))
+	assert.Equal(t, "3", string(<-queue.messages))
+}
+
+func TestWriteQueueDisconnect(t *testing.T) {
+	closed := false
+	queue := newWriteQueue(writeBufferConfig{size: 1, policy: BACKPRESSURE_DISCONNECT}, nil, func() { closed = true })
+
+	assert.True(t, queue.push([]byte("1")))
+	assert.False(t, queue.push([]byte("2")))
+	assert.True(t, closed)
+	assert.False(t, queue.push([]byte("3")))
+}
+
+func TestWriteQueueBlock(t *testing.T) {
+	queue := newWriteQueue(writeBufferConfig{size: 1, policy: BACKPRESSURE_BLOCK, timeout: time.Second}, nil, nil)
+	queue.push([]byte("1"))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-queue.messages
+	}()
+
+	assert.True(t, queue.push([]byte("2")))
+	assert.Equal(t, "2", string(<-queue.messages))
+}
+
+func TestWriteQueueBlockTimeout(t *testing.T) {
+	queue := newWriteQueue(writeBufferConfig{size: 1, policy: BACKPRESSURE_BLOCK, timeout: 10 * time.Millisecond}, nil, nil)
+	queue.push([]byte("1"))
+
+	assert.False(t, queue.push([]byte("2")))
+	assert.False(t, queue.push([]byte("3")))
+}
+
+func TestWriteQueueDropOldestKeepsResponses(t *testing.T) {
+	var dropped atomic.Int32
+	queue := newWriteQueue(writeBufferConfig{size: 1, policy: BACKPRESSURE_DROP_OLDEST, timeout: 10 * time.Millisecond}, func() { dropped.Add(1) }, nil)
+
+	assert.True(t, queue.pushResponse([]byte("response")))
+	assert.True(t, queue.push([]byte("1")))
+	assert.True(t, queue.push([]byte("2")))
+	assert.Equal(t, int32(1), dropped.Load())
+
+	//Responses wait for room instead of being dropped, and the client is disconnected once they can not
+	assert.False(t, queue.pushResponse([]byte("late")))
+	assert.Equal(t, "response", string(<-queue.responses))
+	assert.Equal(t, "2", string(<-queue.messages))
+	assert.False(t, queue.push([]byte("3")))
+}
+
+func TestWriteBufferDefaults(t *testing.T) {
+	cfg := newConfig(nil)
+
+	//Raw sockets disconnect clients that stop reading instead of waiting forever
+	assert.Equal(t, writeBufferConfig{size: DEFAULT_WRITE_BUFFER_SIZE, policy: BACKPRESSURE_BLOCK, timeout: DEFAULT_WRITE_TIMEOUT}, cfg.writeBufferFor(BACKPRESSURE_BLOCK))
+	assert.Equal(t, writeBufferConfig{size: DEFAULT_WRITE_BUFFER_SIZE, policy: BACKPRESSURE_DROP_OLDEST}, cfg.writeBufferFor(BACKPRESSURE_DROP_OLDEST))
+}
+
+func TestPublishOutsideHubLock(t *testing.T) {
+	rpc := NewJsonRpc()
+	blocked, release := make(chan struct{}), make(chan struct{})
+	rpc.Subscribe(func(n Notification) {
+		close(blocked)
+		<-release
+	})
+
+	go rpc.Publish("", "Slow.Event", nil)
+	<-blocked
+
+	//A subscriber waiting on its client holds up neither subscriptions nor unsubscriptions
+	subscribed := make(chan struct{})
+	go func() {
+		rpc.Subscribe(func(n Notification) {})()
+		close(subscribed)
+	}()
+	select {
+	case <-subscribed:
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe blocked by a slow subscriber")
+	}
+	close(release)
+}
+
+func TestServeConnDisconnectsSlowClient(t *testing.T) {
+	rpc := NewJsonRpc(WithWriteBuffer(1, BACKPRESSURE_DISCONNECT, 0))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go rpc.ServeConn(server)
+
+	//The client never reads, so responses pile up in the buffer until the server disconnects it
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		client.SetWriteDeadline(time.Now().Add(time.Second))
+		_, err = client.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}` + "\n"))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
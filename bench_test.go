@@ -0,0 +1,52 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Serve body b.N times, reusing the body bytes so only the server is measured
+func benchmarkServe(b *testing.B, rpc JsonRPC, body []byte) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		recorder := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		rpc.ServeHTTP(recorder, r)
+
+		if recorder.Code != http.StatusOK {
+			b.Fatalf("Unexpected status %d", recorder.Code)
+		}
+	}
+}
+
+func BenchmarkSingleRequest(b *testing.B) {
+	body := []byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)
+
+	benchmarkServe(b, newTestArithRpc(), body)
+}
+
+func BenchmarkBatch100(b *testing.B) {
+	entries := make([]string, 100)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":"%d","method":"Arith.Add","params":[%d,2]}`, i, i)
+	}
+	body := []byte("[" + strings.Join(entries, ",") + "]")
+
+	benchmarkServe(b, newTestArithRpc(), body)
+}
+
+func BenchmarkLargePayload(b *testing.B) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	body := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["%s"]}`, strings.Repeat("x", 256<<10)))
+
+	benchmarkServe(b, rpc, body)
+}
@@ -0,0 +1,253 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of the circuit breaker of a method
+type BreakerState int
+
+const (
+	BREAKER_CLOSED    BreakerState = iota //Calls go through and their outcome is counted
+	BREAKER_OPEN                          //Calls fail at once with UPSTREAM_UNAVAILABLE
+	BREAKER_HALF_OPEN                     //A few probe calls go through to find out whether the upstream recovered
+)
+
+type (
+	//BreakerOptions set when the circuit breaker of a method opens. Zero values take the defaults
+	BreakerOptions struct {
+		//Share of failed calls in the window opening the breaker. Defaults to 0.5
+		ErrorRate float64
+
+		//Calls slower than this count as slow. Latency is not checked when zero
+		SlowCall time.Duration
+
+		//Share of slow calls in the window opening the breaker. Defaults to 0.5
+		SlowRate float64
+
+		//Calls in the window before the rates are checked, so a single failure does not open it. Defaults to 10
+		MinCalls int
+
+		//Period the calls are counted over. Counts start over after it. Defaults to 10s
+		Window time.Duration
+
+		//How long the breaker stays open before it probes the upstream. Defaults to 30s
+		OpenFor time.Duration
+
+		//Successful probes closing a half-open breaker. Only as many calls go through at once. Defaults to 1
+		Probes int
+
+		//Called when the breaker of a method changes state, eg. for metrics or alerts. It is called with the breaker
+		//locked, so it must not call the breaker
+		OnStateChange func(method string, from, to BreakerState)
+	}
+
+	//CircuitBreaker stops calling methods of an upstream that keeps failing or is too slow, so callers fail fast
+	//instead of piling up on it. Each method has its own breaker. Use it on a client with WithCircuitBreaker and on
+	//a proxy by setting the Breaker of an Upstream.
+	CircuitBreaker struct {
+		opts    BreakerOptions
+		mu      sync.Mutex
+		methods map[string]*methodBreaker
+	}
+
+	//Breaker of a single method
+	methodBreaker struct {
+		state    BreakerState
+		since    time.Time //When the state or the window started
+		calls    int
+		failures int
+		slow     int
+		probes   int //Probes in flight while half-open
+		passed   int //Probes that succeeded while half-open
+	}
+)
+
+// NewCircuitBreaker creates a circuit breaker whose methods are all closed
+func NewCircuitBreaker(opts BreakerOptions) *CircuitBreaker {
+	if opts.ErrorRate <= 0 {
+		opts.ErrorRate = 0.5
+	}
+	if opts.SlowRate <= 0 {
+		opts.SlowRate = 0.5
+	}
+	if opts.MinCalls <= 0 {
+		opts.MinCalls = 10
+	}
+	if opts.Window <= 0 {
+		opts.Window = 10 * time.Second
+	}
+	if opts.OpenFor <= 0 {
+		opts.OpenFor = 30 * time.Second
+	}
+	if opts.Probes <= 0 {
+		opts.Probes = 1
+	}
+
+	return &CircuitBreaker{opts: opts, methods: make(map[string]*methodBreaker)}
+}
+
+// WithCircuitBreaker fails the calls and notifications of the methods whose breaker is open with an UPSTREAM_UNAVAILABLE
+// *RpcError, without sending them. Transport errors and INTERNAL_ERROR, SERVER_BUSY and UPSTREAM_UNAVAILABLE
+// responses count as failures. Other error responses show the server is up and count as successes.
+func WithCircuitBreaker(b *CircuitBreaker) ClientOption {
+	return func(c *Client) {
+		c.Use(func(next Invoker) Invoker {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				done, ok := b.allow(req.Method)
+				if !ok {
+					return nil, &RpcError{Code: UPSTREAM_UNAVAILABLE, Message: fmt.Sprintf("Upstream unavailable. Circuit breaker of method %s is open", req.Method)}
+				}
+
+				start := time.Now()
+				res, err := next(ctx, req)
+				if err == nil && res != nil && res.Error != nil {
+					done(ctx, start, failureCode(res.Error.Code))
+				} else {
+					done(ctx, start, err != nil)
+				}
+
+				return res, err
+			}
+		})
+	}
+}
+
+// State returns the state of the breaker of the method
+func (b *CircuitBreaker) State(method string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m, ok := b.methods[method]
+	if !ok {
+		return BREAKER_CLOSED
+	}
+	b.halfOpen(method, m, time.Now())
+
+	return m.state
+}
+
+// Reset closes the breakers of every method
+func (b *CircuitBreaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.methods = make(map[string]*methodBreaker)
+}
+
+// Whether a call of the method may go through. The returned func records the outcome of the call. Calls ended by
+// their own context are not counted, since the upstream is not to blame. A nil breaker lets every call through
+func (b *CircuitBreaker) allow(method string) (func(ctx context.Context, start time.Time, failed bool), bool) {
+	if b == nil {
+		return func(context.Context, time.Time, bool) {}, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	m, ok := b.methods[method]
+	if !ok {
+		m = &methodBreaker{since: now}
+		b.methods[method] = m
+	}
+	b.halfOpen(method, m, now)
+
+	switch {
+	case m.state == BREAKER_OPEN, m.state == BREAKER_HALF_OPEN && m.probes+m.passed >= b.opts.Probes:
+		return nil, false
+	case m.state == BREAKER_HALF_OPEN:
+		m.probes++
+	}
+	probe := m.state == BREAKER_HALF_OPEN
+
+	return func(ctx context.Context, start time.Time, failed bool) {
+		b.record(method, m, probe, ctx.Err() != nil, failed, time.Since(start))
+	}, true
+}
+
+// Count the outcome of a call
+func (b *CircuitBreaker) record(method string, m *methodBreaker, probe, ignored, failed bool, latency time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slow := b.opts.SlowCall > 0 && latency >= b.opts.SlowCall
+	now := time.Now()
+
+	if probe {
+		m.probes--
+		switch {
+		case m.state != BREAKER_HALF_OPEN || ignored:
+		case failed || slow:
+			b.transition(method, m, BREAKER_OPEN, now)
+		default:
+			m.passed++
+			if m.passed >= b.opts.Probes {
+				b.transition(method, m, BREAKER_CLOSED, now)
+			}
+		}
+		return
+	}
+	if m.state != BREAKER_CLOSED || ignored {
+		return
+	}
+
+	if now.Sub(m.since) >= b.opts.Window {
+		m.since, m.calls, m.failures, m.slow = now, 0, 0, 0
+	}
+	m.calls++
+	if failed {
+		m.failures++
+	}
+	if slow {
+		m.slow++
+	}
+
+	if m.calls < b.opts.MinCalls {
+		return
+	}
+	calls := float64(m.calls)
+	if float64(m.failures)/calls >= b.opts.ErrorRate || b.opts.SlowCall > 0 && float64(m.slow)/calls >= b.opts.SlowRate {
+		b.transition(method, m, BREAKER_OPEN, now)
+	}
+}
+
+// Move an open breaker to half-open once it was open long enough
+func (b *CircuitBreaker) halfOpen(method string, m *methodBreaker, now time.Time) {
+	if m.state == BREAKER_OPEN && now.Sub(m.since) >= b.opts.OpenFor {
+		b.transition(method, m, BREAKER_HALF_OPEN, now)
+	}
+}
+
+func (b *CircuitBreaker) transition(method string, m *methodBreaker, state BreakerState, now time.Time) {
+	from := m.state
+	m.state, m.since = state, now
+	m.calls, m.failures, m.slow, m.passed = 0, 0, 0, 0
+
+	if b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(method, from, state)
+	}
+}
+
+// Whether an error response shows the upstream is failing rather than refusing a bad call
+func failureCode(code RpcErrorCode) bool {
+	return code == INTERNAL_ERROR || code == SERVER_BUSY || code == UPSTREAM_UNAVAILABLE
+}
+
+// Whether the encoded response forwarded by the proxy is a failure of the upstream
+func failedResponse(body []byte) bool {
+	var res struct {
+		Error *struct {
+			Code RpcErrorCode `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return true
+	}
+
+	return res.Error != nil && failureCode(res.Error.Code)
+}
@@ -0,0 +1,93 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerOpensOnErrors(t *testing.T) {
+	var changes []string
+	breaker := NewCircuitBreaker(BreakerOptions{MinCalls: 2, OpenFor: 20 * time.Millisecond, OnStateChange: func(method string, from, to BreakerState) {
+		changes = append(changes, method)
+	}})
+	transport := &delayedTransport{err: errors.New("connection refused")}
+	client := NewClient(transport, WithCircuitBreaker(breaker))
+
+	for i := 0; i < 2; i++ {
+		assert.EqualError(t, client.Call(context.Background(), "Arith.Add", nil, nil), "connection refused")
+	}
+	assert.Equal(t, BREAKER_OPEN, breaker.State("Arith.Add"))
+	assert.Equal(t, BREAKER_CLOSED, breaker.State("Arith.Sub"))
+
+	err := client.Call(context.Background(), "Arith.Add", nil, nil)
+	var rpcErr *RpcError
+	assert.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, UPSTREAM_UNAVAILABLE, rpcErr.Code)
+	assert.Equal(t, "Upstream unavailable. Circuit breaker of method Arith.Add is open", rpcErr.Message)
+	assert.Equal(t, int32(2), transport.calls.Load())
+
+	//A successful probe closes the breaker
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, BREAKER_HALF_OPEN, breaker.State("Arith.Add"))
+	transport.err = nil
+	assert.NoError(t, client.Call(context.Background(), "Arith.Add", nil, nil))
+	assert.Equal(t, BREAKER_CLOSED, breaker.State("Arith.Add"))
+	assert.Equal(t, []string{"Arith.Add", "Arith.Add", "Arith.Add"}, changes)
+}
+
+func TestCircuitBreakerFailedProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerOptions{MinCalls: 1, OpenFor: 10 * time.Millisecond})
+	client := NewClient(&delayedTransport{err: errors.New("connection refused")}, WithCircuitBreaker(breaker))
+
+	assert.Error(t, client.Call(context.Background(), "Arith.Add", nil, nil))
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualError(t, client.Call(context.Background(), "Arith.Add", nil, nil), "connection refused")
+	assert.Equal(t, BREAKER_OPEN, breaker.State("Arith.Add"))
+}
+
+func TestCircuitBreakerSlowCalls(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerOptions{MinCalls: 2, SlowCall: 5 * time.Millisecond})
+	client := NewClient(&delayedTransport{delay: 10 * time.Millisecond}, WithCircuitBreaker(breaker))
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, client.Call(context.Background(), "Arith.Add", nil, nil))
+	}
+	assert.Equal(t, BREAKER_OPEN, breaker.State("Arith.Add"))
+
+	breaker.Reset()
+	assert.Equal(t, BREAKER_CLOSED, breaker.State("Arith.Add"))
+}
+
+func TestCircuitBreakerIgnoresCallerErrors(t *testing.T) {
+	breaker := NewCircuitBreaker(BreakerOptions{MinCalls: 1})
+	client := NewInProcessClient(newTestArithRpc(), WithCircuitBreaker(breaker))
+
+	//Unknown methods and cancelled calls do not show the server is failing
+	assert.Error(t, client.Call(context.Background(), "Arith.Sub", []any{1, 2}, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.Call(ctx, "Arith.ErrorMethod", nil, nil)
+	assert.Equal(t, BREAKER_CLOSED, breaker.State("Arith.Sub"))
+	assert.Equal(t, BREAKER_CLOSED, breaker.State("Arith.ErrorMethod"))
+
+	assert.Error(t, client.Call(context.Background(), "Arith.ErrorMethod", nil, nil))
+	assert.Equal(t, BREAKER_OPEN, breaker.State("Arith.ErrorMethod"))
+}
+
+func TestProxyCircuitBreaker(t *testing.T) {
+	failing := &delayedTransport{err: errors.New("connection refused")}
+	upstream := NewUpstream("failing", failing)
+	upstream.Breaker = NewCircuitBreaker(BreakerOptions{MinCalls: 1})
+
+	proxy := NewProxy()
+	proxy.Route("*", upstream)
+	client := NewHTTPClient(newTestHTTPServer(t, proxy))
+
+	assert.EqualError(t, client.Call(context.Background(), "Arith.Add", nil, nil), "Upstream unavailable. failing: connection refused")
+	assert.EqualError(t, client.Call(context.Background(), "Arith.Add", nil, nil), "Upstream unavailable. failing: circuit breaker open")
+	assert.Equal(t, int32(1), failing.calls.Load())
+}
@@ -0,0 +1,152 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Notifications waiting to be sent to the broker. Notifications are dropped when the broker falls further behind
+const BRIDGE_BUFFER_SIZE = 256
+
+type (
+	//NotificationBroker is a pub/sub system shared by several servers, eg. Redis pub/sub or MQTT.
+	//The bridge/redis and bridge/mqtt modules adapt Redis and MQTT clients. Adapt the clients of other brokers to it, see the README.
+	NotificationBroker interface {
+		//Publish data on channel
+		Publish(ctx context.Context, channel string, data []byte) error
+
+		//Call handler with the data of the messages published on channel, including those of this server
+		Subscribe(ctx context.Context, channel string, handler func(data []byte)) (unsubscribe func() error, err error)
+	}
+
+	//NotificationBridge shares the notifications published on a server with the servers bridged to the same channel,
+	//so clients connected to any server receive them, eg. on the SSE endpoint
+	NotificationBridge struct {
+		rpc         *jsonRpcImpl
+		broker      NotificationBroker
+		channel     string
+		origin      string //Id of the bridge, so it ignores its own messages
+		outgoing    chan Notification
+		unsubscribe func() error
+		stopLocal   func()
+		done        chan struct{}
+		closed      sync.Once
+	}
+
+	//Notification as sent through the broker
+	bridgeMessage struct {
+		Origin string          `json:"origin"`
+		Topic  string          `json:"topic,omitempty"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params,omitempty"`
+	}
+)
+
+// NewNotificationBridge forwards the notifications published on rpc to channel of the broker, and publishes the
+// notifications of the other servers on the channel on rpc. Close stops the bridge.
+func NewNotificationBridge(rpc JsonRPC, broker NotificationBroker, channel string) (*NotificationBridge, error) {
+	if server, ok := rpc.(*OpenRPCServer); ok {
+		rpc = server.JsonRPC
+	}
+
+	impl, ok := rpc.(*jsonRpcImpl)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Bridge is not supported on %T", rpc))
+	}
+
+	b := &NotificationBridge{
+		rpc:      impl,
+		broker:   broker,
+		channel:  channel,
+		origin:   string(UUIDs()()),
+		outgoing: make(chan Notification, BRIDGE_BUFFER_SIZE),
+		done:     make(chan struct{}),
+	}
+
+	unsubscribe, err := broker.Subscribe(context.Background(), channel, b.receive)
+	if err != nil {
+		return nil, err
+	}
+	b.unsubscribe = unsubscribe
+
+	b.stopLocal = impl.Subscribe(b.forward)
+	go b.run()
+
+	return b, nil
+}
+
+// Queue local notifications for the broker. Publish must not block, so the broker is called from run
+func (b *NotificationBridge) forward(n Notification) {
+	if n.remote {
+		return
+	}
+
+	select {
+	case b.outgoing <- n:
+	default:
+		b.rpc.cfg().logger.Printf("Notification bridge buffer full. Notification %s dropped", n.Method)
+	}
+}
+
+func (b *NotificationBridge) run() {
+	defer close(b.done)
+
+	for n := range b.outgoing {
+		if err := b.send(n); err != nil {
+			b.rpc.cfg().logger.Printf("Unable to forward notification %s to %s: %v", n.Method, b.channel, err)
+		}
+	}
+}
+
+func (b *NotificationBridge) send(n Notification) error {
+	msg := bridgeMessage{Origin: b.origin, Topic: n.Topic, Method: n.Method}
+	if n.Params != nil {
+		params, err := b.rpc.cfg().marshal(n.Params, false)
+		if err != nil {
+			return err
+		}
+		msg.Params = params
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return b.broker.Publish(context.Background(), b.channel, data)
+}
+
+// Publish the notifications of the other servers to the local subscribers
+func (b *NotificationBridge) receive(data []byte) {
+	var msg bridgeMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		b.rpc.cfg().logger.Printf("Invalid message on notification channel %s: %v", b.channel, err)
+		return
+	}
+	if msg.Origin == b.origin {
+		return
+	}
+
+	var params any
+	if len(msg.Params) > 0 {
+		params = msg.Params
+	}
+
+	b.rpc.notifications.publish(Notification{Topic: msg.Topic, Method: msg.Method, Params: params, remote: true})
+}
+
+// Close unsubscribes from the broker and sends the queued notifications
+func (b *NotificationBridge) Close() error {
+	var err error
+	b.closed.Do(func() {
+		b.stopLocal()
+		err = b.unsubscribe()
+		close(b.outgoing)
+	})
+	<-b.done
+
+	return err
+}
@@ -0,0 +1,56 @@
+// Package mqtt shares the notifications of jsonrpc2 servers through an MQTT broker with
+// github.com/eclipse/paho.mqtt.golang. It is a module of its own, so applications not using it do not depend on the
+// MQTT client.
+package mqtt
+
+import (
+	"context"
+
+	"github.com/developertom01/jsonrpc2"
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Broker is MQTT as a jsonrpc2.NotificationBroker. Channels are MQTT topics without wildcards
+type Broker struct {
+	client paho.Client
+	qos    byte
+}
+
+// NewBroker publishes and subscribes with the connected client at the quality of service, eg. 0 for at most once
+// delivery like Redis pub/sub. A client holds one subscription per topic, so bridge a channel once per client.
+func NewBroker(client paho.Client, qos byte) *Broker {
+	return &Broker{client: client, qos: qos}
+}
+
+// NewNotificationBridge bridges rpc to the servers sharing the topic on the broker of the client, at most once
+func NewNotificationBridge(rpc jsonrpc2.JsonRPC, client paho.Client, topic string) (*jsonrpc2.NotificationBridge, error) {
+	return jsonrpc2.NewNotificationBridge(rpc, NewBroker(client, 0), topic)
+}
+
+func (b *Broker) Publish(ctx context.Context, channel string, data []byte) error {
+	return wait(ctx, b.client.Publish(channel, b.qos, false, data))
+}
+
+// Subscribe returns once the broker acknowledged the subscription, so the messages published afterwards are received
+func (b *Broker) Subscribe(ctx context.Context, channel string, handler func(data []byte)) (func() error, error) {
+	token := b.client.Subscribe(channel, b.qos, func(_ paho.Client, msg paho.Message) {
+		handler(msg.Payload())
+	})
+	if err := wait(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return wait(context.Background(), b.client.Unsubscribe(channel))
+	}, nil
+}
+
+// Wait for the broker to acknowledge the operation of the token, or for the context to end
+func wait(ctx context.Context, token paho.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,92 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/developertom01/jsonrpc2"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/stretchr/testify/assert"
+)
+
+// Start an in-process MQTT broker and return its address
+func runTestBroker(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	server := mochi.New(nil)
+	server.AddHook(new(auth.AllowHook), nil)
+	if err := server.AddListener(listeners.NewTCP("tcp", addr, nil)); err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	return addr
+}
+
+func newTestClient(t *testing.T, addr string, id string) paho.Client {
+	client := paho.NewClient(paho.NewClientOptions().AddBroker("tcp://" + addr).SetClientID(id).SetConnectRetry(true))
+	if token := client.Connect(); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatal(fmt.Sprintf("Unable to connect to the broker: %v", token.Error()))
+	}
+	t.Cleanup(func() { client.Disconnect(0) })
+
+	return client
+}
+
+func TestNotificationBridge(t *testing.T) {
+	addr := runTestBroker(t)
+	first, second := jsonrpc2.NewJsonRpc(), jsonrpc2.NewJsonRpc()
+
+	//Each server has its own connection to the broker
+	firstBridge, err := NewNotificationBridge(first, newTestClient(t, addr, "first"), "jsonrpc2/notifications")
+	assert.Nil(t, err)
+	defer firstBridge.Close()
+	secondBridge, err := NewNotificationBridge(second, newTestClient(t, addr, "second"), "jsonrpc2/notifications")
+	assert.Nil(t, err)
+	defer secondBridge.Close()
+
+	received := make(chan jsonrpc2.Notification, 10)
+	second.Subscribe(func(n jsonrpc2.Notification) { received <- n })
+
+	first.Publish("orders", "Orders.Created", []any{"order-1"})
+
+	select {
+	case n := <-received:
+		assert.Equal(t, "orders", n.Topic)
+		assert.Equal(t, "Orders.Created", n.Method)
+		assert.Equal(t, json.RawMessage(`["order-1"]`), n.Params)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notification not bridged")
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	addr := runTestBroker(t)
+	broker := NewBroker(newTestClient(t, addr, "subscriber"), 1)
+	publisher := NewBroker(newTestClient(t, addr, "publisher"), 1)
+
+	received := make(chan []byte, 10)
+	unsubscribe, err := broker.Subscribe(context.Background(), "jsonrpc2/notifications", func(data []byte) { received <- data })
+	assert.Nil(t, err)
+
+	assert.Nil(t, publisher.Publish(context.Background(), "jsonrpc2/notifications", []byte("a")))
+	assert.Equal(t, []byte("a"), <-received)
+
+	assert.Nil(t, unsubscribe())
+	assert.Nil(t, publisher.Publish(context.Background(), "jsonrpc2/notifications", []byte("b")))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, received, 0)
+}
@@ -0,0 +1,47 @@
+// Package redis shares the notifications of jsonrpc2 servers through Redis pub/sub with github.com/redis/go-redis/v9.
+// It is a module of its own, so applications not using it do not depend on the Redis client.
+package redis
+
+import (
+	"context"
+
+	"github.com/developertom01/jsonrpc2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Broker is Redis pub/sub as a jsonrpc2.NotificationBroker
+type Broker struct {
+	client goredis.UniversalClient
+}
+
+// NewBroker publishes and subscribes with the client, eg. a *redis.Client or a *redis.ClusterClient
+func NewBroker(client goredis.UniversalClient) *Broker {
+	return &Broker{client: client}
+}
+
+// NewNotificationBridge bridges rpc to the servers sharing the channel of the Redis server of the client
+func NewNotificationBridge(rpc jsonrpc2.JsonRPC, client goredis.UniversalClient, channel string) (*jsonrpc2.NotificationBridge, error) {
+	return jsonrpc2.NewNotificationBridge(rpc, NewBroker(client), channel)
+}
+
+func (b *Broker) Publish(ctx context.Context, channel string, data []byte) error {
+	return b.client.Publish(ctx, channel, data).Err()
+}
+
+// Subscribe returns once Redis confirmed the subscription, so the messages published afterwards are received
+func (b *Broker) Subscribe(ctx context.Context, channel string, handler func(data []byte)) (func() error, error) {
+	sub := b.client.Subscribe(ctx, channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	messages := sub.Channel()
+	go func() {
+		for msg := range messages {
+			handler([]byte(msg.Payload))
+		}
+	}()
+
+	return sub.Close, nil
+}
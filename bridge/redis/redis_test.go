@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/developertom01/jsonrpc2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T, server *miniredis.Miniredis) *goredis.Client {
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestNotificationBridge(t *testing.T) {
+	server := miniredis.RunT(t)
+	first, second := jsonrpc2.NewJsonRpc(), jsonrpc2.NewJsonRpc()
+
+	//Each server has its own connection to Redis
+	firstBridge, err := NewNotificationBridge(first, newTestClient(t, server), "notifications")
+	assert.Nil(t, err)
+	defer firstBridge.Close()
+	secondBridge, err := NewNotificationBridge(second, newTestClient(t, server), "notifications")
+	assert.Nil(t, err)
+	defer secondBridge.Close()
+
+	received := make(chan jsonrpc2.Notification, 10)
+	second.Subscribe(func(n jsonrpc2.Notification) { received <- n })
+
+	first.Publish("orders", "Orders.Created", []any{"order-1"})
+
+	select {
+	case n := <-received:
+		assert.Equal(t, "orders", n.Topic)
+		assert.Equal(t, "Orders.Created", n.Method)
+		assert.Equal(t, json.RawMessage(`["order-1"]`), n.Params)
+	case <-time.After(time.Second):
+		t.Fatal("Notification not bridged")
+	}
+}
+
+func TestSubscribeUnavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := newTestClient(t, server)
+	server.Close()
+
+	_, err := jsonrpc2.NewNotificationBridge(jsonrpc2.NewJsonRpc(), NewBroker(client), "notifications")
+	assert.NotNil(t, err)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	broker := NewBroker(newTestClient(t, server))
+
+	received := make(chan []byte, 10)
+	unsubscribe, err := broker.Subscribe(context.Background(), "notifications", func(data []byte) { received <- data })
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"notifications"}, server.PubSubChannels(""))
+
+	assert.Nil(t, broker.Publish(context.Background(), "notifications", []byte("a")))
+	assert.Equal(t, []byte("a"), <-received)
+
+	assert.Nil(t, unsubscribe())
+	assert.Eventually(t, func() bool { return len(server.PubSubChannels("")) == 0 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,127 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// In memory broker delivering every message to every subscriber of the channel, like Redis pub/sub
+type memoryBroker struct {
+	mu          sync.Mutex
+	subscribers map[int]func(data []byte)
+	lastId      int
+	published   int
+}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{subscribers: make(map[int]func(data []byte))}
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, channel string, data []byte) error {
+	b.mu.Lock()
+	b.published++
+	handlers := make([]func(data []byte), 0, len(b.subscribers))
+	for _, handler := range b.subscribers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(data)
+	}
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(ctx context.Context, channel string, handler func(data []byte)) (func() error, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastId++
+	id := b.lastId
+	b.subscribers[id] = handler
+
+	return func() error {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers, id)
+		return nil
+	}, nil
+}
+
+// Collect the notifications published on the server
+func collectNotifications(rpc JsonRPC) chan Notification {
+	received := make(chan Notification, 10)
+	rpc.Subscribe(func(n Notification) { received <- n })
+
+	return received
+}
+
+func TestNotificationBridge(t *testing.T) {
+	broker := newMemoryBroker()
+	first, second := NewJsonRpc(), NewJsonRpc()
+
+	firstBridge, err := NewNotificationBridge(first, broker, "notifications")
+	assert.Nil(t, err)
+	defer firstBridge.Close()
+	secondBridge, _ := NewNotificationBridge(second, broker, "notifications")
+	defer secondBridge.Close()
+
+	onFirst, onSecond := collectNotifications(first), collectNotifications(second)
+
+	first.Publish("orders", "Orders.Created", []any{"order-1"})
+
+	select {
+	case n := <-onSecond:
+		assert.Equal(t, "orders", n.Topic)
+		assert.Equal(t, "Orders.Created", n.Method)
+		assert.Equal(t, json.RawMessage(`["order-1"]`), n.Params)
+	case <-time.After(time.Second):
+		t.Fatal("Notification not bridged")
+	}
+
+	//Delivered once locally, and not echoed back or forwarded again by the second server
+	assert.Equal(t, "Orders.Created", (<-onFirst).Method)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, onFirst, 0)
+	assert.Len(t, onSecond, 0)
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Equal(t, 1, broker.published)
+}
+
+func TestNotificationBridgeToSSE(t *testing.T) {
+	broker := newMemoryBroker()
+	first, second := NewJsonRpc(), NewJsonRpc(WithSSE("/events"))
+
+	firstBridge, _ := NewNotificationBridge(first, broker, "notifications")
+	defer firstBridge.Close()
+	secondBridge, _ := NewNotificationBridge(second, broker, "notifications")
+	defer secondBridge.Close()
+
+	events := openTestSSE(t, newTestHTTPServer(t, second)+"/events")
+	first.Publish("", "Orders.Created", nil)
+
+	assert.Equal(t, `data: {"method":"Orders.Created","params":[],"jsonrpc":"2.0"}`, readTestEvent(t, events))
+}
+
+func TestNotificationBridgeClose(t *testing.T) {
+	broker := newMemoryBroker()
+	rpc := NewJsonRpc()
+	bridge, _ := NewNotificationBridge(rpc, broker, "notifications")
+
+	rpc.Publish("", "Orders.Created", nil)
+	assert.Nil(t, bridge.Close())
+	rpc.Publish("", "Orders.Created", nil)
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Equal(t, 1, broker.published)
+	assert.Len(t, broker.subscribers, 0)
+}
@@ -0,0 +1,162 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Method name used by the Language Server Protocol to cancel a request. It is handled like rpc.cancel
+const CANCEL_REQUEST_METHOD = "$/cancelRequest"
+
+// Cause of the context cancellation when a request is cancelled by the client
+var errRequestCancelled = errors.New("Request cancelled")
+
+type (
+	//Requests in flight that can be cancelled by id. Stream connections have their own registry,
+	//HTTP requests share the registry of the server, where ids are scoped to the identity of their caller
+	inFlightRequests struct {
+		mu     sync.Mutex
+		calls  map[string]*inFlightCall
+		caller func(ctx context.Context) string //Identity of the caller of shared registries. Nil on connections
+	}
+
+	inFlightCall struct {
+		cancel context.CancelCauseFunc
+	}
+
+	inFlightKey struct{}
+)
+
+func newInFlightRequests() *inFlightRequests {
+	return &inFlightRequests{calls: make(map[string]*inFlightCall)}
+}
+
+func withInFlightRequests(ctx context.Context, requests *inFlightRequests) context.Context {
+	return context.WithValue(ctx, inFlightKey{}, requests)
+}
+
+// Make the request cancellable by id. The returned function must be called once the request is done.
+func trackRequest(ctx context.Context, id string) (context.Context, func()) {
+	requests, ok := ctx.Value(inFlightKey{}).(*inFlightRequests)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	call := &inFlightCall{cancel: cancel}
+	id = requests.key(ctx, id)
+
+	requests.mu.Lock()
+	requests.calls[id] = call
+	requests.mu.Unlock()
+
+	return ctx, func() {
+		requests.mu.Lock()
+		if requests.calls[id] == call {
+			delete(requests.calls, id)
+		}
+		requests.mu.Unlock()
+
+		cancel(nil)
+	}
+}
+
+// Key of the id in the registry. Ids of shared registries are prefixed with the identity of the caller, so clients
+// can only cancel their own requests
+func (r *inFlightRequests) key(ctx context.Context, id string) string {
+	if r.caller == nil {
+		return id
+	}
+	return r.caller(ctx) + "\x00" + id
+}
+
+func (r *inFlightRequests) cancel(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	call, ok := r.calls[id]
+	if ok {
+		call.cancel(errRequestCancelled)
+		delete(r.calls, id)
+	}
+
+	return ok
+}
+
+// Check if the request of the context was cancelled by the client
+func cancelledByClient(ctx context.Context) bool {
+	return ctx != nil && errors.Is(context.Cause(ctx), errRequestCancelled)
+}
+
+// WithCancellationIdentity sets how the caller of an HTTP request is identified, eg. by the authenticated user or a
+// session header, so it can only cancel its own requests with rpc.cancel. Requests ids only need to be unique per
+// caller. Defaults to the IP address of the client. Raw sockets cancel the requests of the connection.
+func WithCancellationIdentity(identity func(ctx context.Context) string) Option {
+	return func(c *config) {
+		c.cancelIdentity = identity
+	}
+}
+
+// Caller of a request in the shared registry of the server
+func (rpc *jsonRpcImpl) cancellationCaller(ctx context.Context) string {
+	if identity := rpc.cfg().cancelIdentity; identity != nil {
+		return identity(ctx)
+	}
+
+	addr := RemoteAddrFromContext(ctx)
+	if ip, err := parseRemoteAddr(addr); err == nil {
+		return ip.String()
+	}
+	return addr
+}
+
+func makeCancelledResponse(id *string) Response {
+	return makeErrorResponse(errRequestCancelled, REQUEST_CANCELLED, nil, id)
+}
+
+// Rewrite $/cancelRequest calls, which pass the id by name, to rpc.cancel
+func normalizeCancelRequest(req Request) Request {
+	if req.Method != CANCEL_REQUEST_METHOD {
+		return req
+	}
+
+	req.Method = BUILTIN_SERVICE_NAME + ".cancel"
+	if params, ok := req.Params.(map[string]any); ok {
+		req.Params = []any{params["id"]}
+	}
+
+	return req
+}
+
+func (rpc *jsonRpcImpl) registerCancelMethod(builtins *service) {
+	builtins.methods["cancel"] = reflect.ValueOf(cancelRequest)
+}
+
+// Cancel the in flight request with the id. It returns false if no such request is running
+func cancelRequest(ctx context.Context, id any) (bool, error, *RpcErrorCode) {
+	requests, ok := ctx.Value(inFlightKey{}).(*inFlightRequests)
+	if !ok {
+		return false, nil, nil
+	}
+
+	switch id.(type) {
+	case string, float64, json.Number:
+	default:
+		code := INVALID_PARAMS
+		return false, errors.New("Request id must be a string or a number"), &code
+	}
+
+	return requests.cancel(requests.key(ctx, fmt.Sprint(id))), nil, nil
+}
+
+// Number of requests in flight
+func (r *inFlightRequests) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.calls)
+}
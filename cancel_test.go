@@ -0,0 +1,164 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingService struct {
+	started chan struct{}
+}
+
+func (s blockingService) Block(ctx context.Context) (string, error, *RpcErrorCode) {
+	s.started <- struct{}{}
+	<-ctx.Done()
+
+	code := INTERNAL_ERROR
+	return "", ctx.Err(), &code
+}
+
+func readTestResponses(t *testing.T, reader *bufio.Reader, n int) map[string]Response {
+	responses := make(map[string]Response)
+	for i := 0; i < n; i++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := Response{}
+		if err := json.Unmarshal(line, &res); err != nil {
+			t.Fatal(err)
+		}
+		responses[*res.Id] = res
+	}
+
+	return responses
+}
+
+func testCancel(t *testing.T, cancelMessage string) {
+	srv := blockingService{started: make(chan struct{}, 1)}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(srv, "Blocking")
+
+	client, server := net.Pipe()
+	go rpc.ServeConn(server)
+	defer client.Close()
+
+	reader := bufio.NewReader(client)
+	go client.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Blocking.Block","params":[]}` + "\n"))
+	<-srv.started
+	go client.Write([]byte(cancelMessage + "\n"))
+
+	responses := readTestResponses(t, reader, 2)
+
+	assert.Equal(t, true, *responses["2"].Result)
+	assert.Equal(t, REQUEST_CANCELLED, responses["1"].Error.Code)
+	assert.Equal(t, "Request cancelled", responses["1"].Error.Message)
+}
+
+func TestCancelRequest(t *testing.T) {
+	testCancel(t, `{"jsonrpc":"2.0","id":"2","method":"rpc.cancel","params":["1"]}`)
+}
+
+func TestCancelRequestLSP(t *testing.T) {
+	testCancel(t, `{"jsonrpc":"2.0","id":"2","method":"$/cancelRequest","params":{"id":"1"}}`)
+}
+
+// Serve the body over HTTP as sent by a client at the address
+func serveTestBodyFrom(rpc JsonRPC, remoteAddr string, body string) string {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+
+	return w.Body.String()
+}
+
+func TestHTTPCancelScopedToCaller(t *testing.T) {
+	srv := blockingService{started: make(chan struct{}, 2)}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(srv, "Blocking")
+
+	//Both clients call with the same id
+	first, second := make(chan string), make(chan string)
+	go func() {
+		first <- serveTestBodyFrom(rpc, "10.0.0.1:4000", `{"jsonrpc":"2.0","id":"1","method":"Blocking.Block","params":[]}`)
+	}()
+	<-srv.started
+	go func() {
+		second <- serveTestBodyFrom(rpc, "10.0.0.2:4000", `{"jsonrpc":"2.0","id":"1","method":"Blocking.Block","params":[]}`)
+	}()
+	<-srv.started
+
+	//Clients only cancel their own requests, from any of their connections
+	cancel := `{"jsonrpc":"2.0","id":"2","method":"rpc.cancel","params":["1"]}`
+	assert.Equal(t, false, *decodeTestResponse(t, serveTestBodyFrom(rpc, "10.0.0.3:4000", cancel)).Result)
+	assert.Equal(t, true, *decodeTestResponse(t, serveTestBodyFrom(rpc, "10.0.0.2:5000", cancel)).Result)
+	assert.Equal(t, REQUEST_CANCELLED, decodeTestResponse(t, <-second).Error.Code)
+
+	select {
+	case <-first:
+		t.Fatal("Request of the other client was cancelled")
+	default:
+	}
+	assert.Equal(t, true, *decodeTestResponse(t, serveTestBodyFrom(rpc, "10.0.0.1:5000", cancel)).Result)
+	assert.Equal(t, REQUEST_CANCELLED, decodeTestResponse(t, <-first).Error.Code)
+}
+
+func TestWithCancellationIdentity(t *testing.T) {
+	srv := blockingService{started: make(chan struct{}, 1)}
+	rpc := NewJsonRpc(WithCancellationIdentity(func(ctx context.Context) string {
+		return HTTPRequestFromContext(ctx).Header.Get("X-Session")
+	}))
+	rpc.RegisterWithName(srv, "Blocking")
+
+	serve := func(session string, body string) string {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("X-Session", session)
+		w := httptest.NewRecorder()
+		rpc.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	done := make(chan string)
+	go func() { done <- serve("a", `{"jsonrpc":"2.0","id":"1","method":"Blocking.Block","params":[]}`) }()
+	<-srv.started
+
+	cancel := `{"jsonrpc":"2.0","id":"2","method":"rpc.cancel","params":["1"]}`
+	assert.Equal(t, false, *decodeTestResponse(t, serve("b", cancel)).Result)
+	assert.Equal(t, true, *decodeTestResponse(t, serve("a", cancel)).Result)
+	assert.Equal(t, REQUEST_CANCELLED, decodeTestResponse(t, <-done).Error.Code)
+}
+
+func TestCancelUnknownRequest(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "rpc.cancel", Params: []any{"missing"}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, false, *res.Result)
+}
+
+func TestNamedParamsRejected(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Arith.Add", Params: map[string]any{"a": 1}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+}
@@ -0,0 +1,118 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"time"
+)
+
+type (
+	//ChaosRule is a fault Chaos injects in a fraction of the calls
+	ChaosRule struct {
+		Methods []string //Methods the rule applies to. Every method when empty
+		Rate    float64  //Fraction of the matching calls the faults are injected in, between 0 and 1
+
+		Latency           time.Duration //Delay added before the call
+		Jitter            time.Duration //Random delay, up to it, added to Latency
+		Error             bool          //Answer with INTERNAL_ERROR without calling the method
+		DropNotifications bool          //Notifications are not handled
+		Truncate          bool          //The encoded response is cut in half so the client receives invalid JSON
+	}
+
+	//Writes half of each message, for responses Chaos truncated
+	truncatingWriter struct {
+		io.Writer
+	}
+)
+
+// Chaos injects faults in the calls matching its rules, so clients can be tested against a slow or failing server.
+// Each rule is drawn independently for every call. Latency is added first and ends early when the request is
+// cancelled. Truncated responses apply to HTTP and raw socket transports, and cut the whole batch.
+// Never use it in production.
+func Chaos(rules ...ChaosRule) Middleware {
+	methods := make([]map[string]bool, len(rules))
+	for i, rule := range rules {
+		methods[i] = make(map[string]bool, len(rule.Methods))
+		for _, method := range rule.Methods {
+			methods[i][method] = true
+		}
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			var delay time.Duration
+			var fail, drop, truncate bool
+			for i, rule := range rules {
+				if len(methods[i]) > 0 && !methods[i][req.Method] {
+					continue
+				}
+				if rule.Rate <= 0 || (rule.Rate < 1 && rand.Float64() >= rule.Rate) {
+					continue
+				}
+
+				delay += rule.Latency
+				if rule.Jitter > 0 {
+					delay += time.Duration(rand.Int63n(int64(rule.Jitter)))
+				}
+				fail = fail || rule.Error
+				drop = drop || (rule.DropNotifications && req.isNotification())
+				truncate = truncate || rule.Truncate
+			}
+
+			if delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return makeErrorResponse(callContextError(ctx), INTERNAL_ERROR, nil, req.Id)
+				}
+			}
+
+			var res Response
+			switch {
+			case drop:
+				res = makeSuccessResponse(nil, req.Id)
+			case fail:
+				res = makeErrorResponse(errors.New("Injected fault"), INTERNAL_ERROR, nil, req.Id)
+			default:
+				res = next(ctx, req)
+			}
+			res.truncated = truncate
+
+			return res
+		}
+	}
+}
+
+// Cut the encoded message in half when one of its responses was truncated
+func truncateMessage(msg []byte, responses ...Response) []byte {
+	for _, res := range responses {
+		if res.truncated {
+			return msg[:len(msg)/2]
+		}
+	}
+
+	return msg
+}
+
+// Writer cutting the message in half when one of the responses was truncated
+func truncatingWriterFor(w io.Writer, responses ...Response) io.Writer {
+	for _, res := range responses {
+		if res.truncated {
+			return truncatingWriter{w}
+		}
+	}
+
+	return w
+}
+
+func (w truncatingWriter) Write(p []byte) (int, error) {
+	if _, err := w.Writer.Write(p[:len(p)/2]); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,83 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Counts the notifications it receives
+type testCounter struct {
+	n *atomic.Int32
+}
+
+func (c testCounter) Inc(ctx context.Context) (int, error, *RpcErrorCode) {
+	return int(c.n.Add(1)), nil, nil
+}
+
+func TestChaosError(t *testing.T) {
+	rpc := NewJsonRpc(WithMiddleware(Chaos(ChaosRule{Methods: []string{"Arith.Add"}, Rate: 1, Error: true})))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Equal(t, INTERNAL_ERROR, res.Error.Code)
+	assert.Equal(t, "Injected fault", res.Error.Message)
+
+	//Other methods are not affected
+	res = callMethod(t, rpc, "Arith.ErrorMethod", nil)
+	assert.Equal(t, "Some error here", res.Error.Message)
+}
+
+func TestChaosLatency(t *testing.T) {
+	rpc := NewJsonRpc(WithMiddleware(Chaos(ChaosRule{Rate: 1, Latency: 30 * time.Millisecond, Jitter: 10 * time.Millisecond})))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	start := time.Now()
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Nil(t, res.Error)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+}
+
+func TestChaosRate(t *testing.T) {
+	rpc := NewJsonRpc(WithMiddleware(Chaos(ChaosRule{Rate: 0, Error: true})))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	assert.Nil(t, callMethod(t, rpc, "Arith.Add", []any{1, 2}).Error)
+}
+
+func TestChaosDropNotifications(t *testing.T) {
+	counter := testCounter{n: &atomic.Int32{}}
+	rpc := NewJsonRpc(WithMiddleware(Chaos(ChaosRule{Rate: 1, DropNotifications: true})))
+	rpc.RegisterWithName(counter, "Counter")
+
+	rpc.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","method":"Counter.Inc"}`))
+	assert.Equal(t, int32(0), counter.n.Load())
+
+	//Calls are not dropped
+	callMethod(t, rpc, "Counter.Inc", nil)
+	assert.Equal(t, int32(1), counter.n.Load())
+}
+
+func TestChaosTruncate(t *testing.T) {
+	rpc := NewJsonRpc(WithMiddleware(Chaos(ChaosRule{Methods: []string{"Arith.Add"}, Rate: 1, Truncate: true})))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	body := `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	assert.False(t, json.Valid(w.Body.Bytes()))
+	assert.True(t, strings.HasPrefix(`{"jsonrpc":"2.0","id":"1","result":3}`, w.Body.String()))
+
+	msg := rpc.HandleMessage(context.Background(), []byte(`[`+body+`,{"jsonrpc":"2.0","id":"2","method":"Arith.ErrorMethod"}]`))
+	assert.False(t, json.Valid(msg))
+
+	msg = rpc.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":"2","method":"Arith.ErrorMethod"}`))
+	assert.True(t, json.Valid(msg))
+}
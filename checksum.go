@@ -0,0 +1,123 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// HTTP header carrying the hex encoded SHA-256 of the body of a request or a response, as sent over the wire
+const CHECKSUM_HEADER = "X-Content-SHA256"
+
+type (
+	//Verification of the checksums of requests and emission of the checksums of responses
+	checksumConfig struct {
+		required bool //Requests without a checksum are rejected
+	}
+
+	//Buffers the response to write its checksum before its body
+	checksumResponseWriter struct {
+		http.ResponseWriter
+		status int
+		body   bytes.Buffer
+	}
+)
+
+// WithChecksums verifies the X-Content-SHA256 header of HTTP requests against their body and sets it on responses,
+// so clients behind proxies and other intermediaries detect corrupted or tampered payloads. The checksum covers the
+// body as sent, after compression. Requests whose checksum does not match are answered with INVALID_REQUEST. When
+// required, requests without the header are rejected too. Responses are buffered to checksum them, so results are no
+// longer streamed.
+func WithChecksums(required bool) Option {
+	return func(c *config) {
+		c.checksums = &checksumConfig{required: required}
+	}
+}
+
+// Handle the request, verifying its checksum and writing the checksum of the response, when checksums are enabled
+func (s *jsonRpcImpl) handleChecksummed(w http.ResponseWriter, r *http.Request) {
+	checksums := s.cfg().checksums
+	if checksums == nil {
+		s.handleNegotiated(w, r)
+		return
+	}
+
+	cw := &checksumResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	defer cw.finish()
+
+	//Calls made with GET have no body to checksum
+	if s.cfg().isGETCall(r) {
+		s.handleNegotiated(cw, r)
+		return
+	}
+
+	var reader io.Reader = r.Body
+	if maxSize := s.cfg().maxRequestSize; maxSize > 0 {
+		//Larger bodies are rejected once decoded, so the remainder need not be read
+		reader = io.LimitReader(r.Body, maxSize+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		s.writeErrorResponse(cw, errors.New("Unable to read request"), PARSE_ERROR, nil, nil)
+		return
+	}
+
+	if err := checksums.verify(r.Header.Get(CHECKSUM_HEADER), body); err != nil {
+		s.writeErrorResponse(cw, err, INVALID_REQUEST, nil, nil)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	s.handleNegotiated(cw, r)
+}
+
+// Check the body matches the checksum of its header
+func (c *checksumConfig) verify(header string, body []byte) error {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		if c.required {
+			return errors.New(fmt.Sprintf("%s header is required", CHECKSUM_HEADER))
+		}
+		return nil
+	}
+
+	expected, err := hex.DecodeString(header)
+	if err != nil || len(expected) != sha256.Size {
+		return errors.New(fmt.Sprintf("%s header is not a hex encoded SHA-256", CHECKSUM_HEADER))
+	}
+
+	sum := sha256.Sum256(body)
+	if !bytes.Equal(sum[:], expected) {
+		return errors.New(fmt.Sprintf("Request body does not match its %s header", CHECKSUM_HEADER))
+	}
+
+	return nil
+}
+
+// Hex encoded SHA-256 of the body, the value of the checksum header
+func checksumOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func (w *checksumResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *checksumResponseWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+// Write the buffered response with its checksum
+func (w *checksumResponseWriter) finish() {
+	if w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		w.Header().Set(CHECKSUM_HEADER, checksumOf(w.body.Bytes()))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
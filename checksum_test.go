@@ -0,0 +1,84 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveChecksummed(rpc JsonRPC, body, checksum string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	if checksum != "" {
+		r.Header.Set(CHECKSUM_HEADER, checksum)
+	}
+	rpc.ServeHTTP(recorder, r)
+
+	return recorder
+}
+
+func TestWithChecksums(t *testing.T) {
+	rpc := NewJsonRpc(WithChecksums(false))
+	rpc.RegisterWithName(arith{}, "Arith")
+	body := `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`
+
+	recorder := serveChecksummed(rpc, body, checksumOf([]byte(body)))
+	assert.Contains(t, recorder.Body.String(), `"result":3`)
+	assert.Equal(t, checksumOf(recorder.Body.Bytes()), recorder.Header().Get(CHECKSUM_HEADER))
+
+	//Upper case digits match too
+	recorder = serveChecksummed(rpc, body, strings.ToUpper(checksumOf([]byte(body))))
+	assert.Contains(t, recorder.Body.String(), `"result":3`)
+
+	//Requests without a checksum are let through
+	recorder = serveChecksummed(rpc, body, "")
+	assert.Contains(t, recorder.Body.String(), `"result":3`)
+	assert.NotEmpty(t, recorder.Header().Get(CHECKSUM_HEADER))
+
+	res := decodeTestResponse(t, serveChecksummed(rpc, body, checksumOf([]byte(body+" "))).Body.String())
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, "Request body does not match its X-Content-SHA256 header", res.Error.Message)
+
+	res = decodeTestResponse(t, serveChecksummed(rpc, body, "not hex").Body.String())
+	assert.Equal(t, "X-Content-SHA256 header is not a hex encoded SHA-256", res.Error.Message)
+}
+
+func TestRequiredChecksums(t *testing.T) {
+	rpc := NewJsonRpc(WithChecksums(true))
+	rpc.RegisterWithName(arith{}, "Arith")
+	body := `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`
+
+	res := decodeTestResponse(t, serveChecksummed(rpc, body, "").Body.String())
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, "X-Content-SHA256 header is required", res.Error.Message)
+
+	//Notifications are answered without a body to checksum
+	recorder := serveChecksummed(rpc, `{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]}`, checksumOf([]byte(`{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]}`)))
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.Header().Get(CHECKSUM_HEADER))
+}
+
+func TestClientChecksums(t *testing.T) {
+	rpc := NewJsonRpc(WithChecksums(true))
+	rpc.RegisterWithName(arith{}, "Arith")
+	url := newTestHTTPServer(t, rpc)
+
+	var sum int
+	client := NewClient(&HTTPTransport{URL: url, Checksums: true})
+	assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, 3, sum)
+
+	//Responses altered on the way are rejected
+	tampering := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(CHECKSUM_HEADER, checksumOf([]byte(`{"jsonrpc":"2.0","id":"1","result":3}`)))
+		w.Write([]byte(`{"jsonrpc":"2.0","id":"1","result":4}`))
+	}))
+	client = NewClient(&HTTPTransport{URL: tampering, Checksums: true})
+	err := client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum)
+	assert.EqualError(t, err, "Response body does not match its X-Content-SHA256 header")
+}
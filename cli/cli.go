@@ -0,0 +1,444 @@
+// Package cli implements the jsonrpc command, which calls methods of any JSON-RPC endpoint from the command line,
+// sends batches read from files and prints the events of subscriptions. Programs embed it as a subcommand of their
+// own binary with a Command, eg. to add the auth middleware of their servers.
+//
+//	jsonrpc call http://localhost:8000/rpc Arith.Add 1 2
+//	jsonrpc batch http://localhost:8000/rpc requests.json
+//	jsonrpc subscribe tcp://localhost:9000 prices
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+)
+
+// Exit statuses of Run
+const (
+	EXIT_OK    = 0 //The command succeeded
+	EXIT_ERROR = 1 //The server answered with an error, or could not be reached
+	EXIT_USAGE = 2 //The command line is invalid
+)
+
+type (
+	//Command is the jsonrpc command. The zero value uses the standard streams
+	Command struct {
+		Name   string    //Name shown in the usage. Defaults to jsonrpc
+		Stdin  io.Reader //Batches are read from it when no file is given
+		Stdout io.Writer //Results and events are written to it
+		Stderr io.Writer //Errors and the usage are written to it
+
+		//Options of the clients the command makes, eg. to sign requests
+		ClientOptions []jsonrpc2.ClientOption
+		//Middleware of the clients the command makes, eg. to add auth headers. Batches are sent as they are read,
+		//without middleware
+		Middleware []jsonrpc2.ClientMiddleware
+	}
+
+	//Settings shared by the subcommands
+	settings struct {
+		headers headerFlags
+		timeout time.Duration
+		params  string
+		compact bool
+	}
+
+	//Repeatable -H flag
+	headerFlags []string
+
+	//Error of an invalid command line
+	usageError struct {
+		err error
+	}
+)
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return errors.New("Header must be Name: value")
+	}
+	*h = append(*h, value)
+
+	return nil
+}
+
+// Run runs the command with args, excluding the program name, and returns the exit status
+func Run(ctx context.Context, args []string) int {
+	return (&Command{}).Run(ctx, args)
+}
+
+// Run runs the command with args, excluding the program name, and returns the exit status
+func (c *Command) Run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return EXIT_USAGE
+	}
+
+	var run func(ctx context.Context, s settings, args []string) error
+	var minArgs int
+	timeout := 30 * time.Second
+	switch args[0] {
+	case "call":
+		run, minArgs = c.call, 2
+	case "notify":
+		run, minArgs = c.notify, 2
+	case "batch":
+		run, minArgs = c.batch, 1
+	case "subscribe":
+		//Events are printed until interrupted
+		run, minArgs, timeout = c.subscribe, 1, 0
+	case "help", "-h", "-help", "--help":
+		c.usage()
+		return EXIT_OK
+	default:
+		fmt.Fprintf(c.stderr(), "Unknown command %s\n", args[0])
+		c.usage()
+		return EXIT_USAGE
+	}
+
+	var s settings
+	flags := flag.NewFlagSet(c.name()+" "+args[0], flag.ContinueOnError)
+	flags.SetOutput(c.stderr())
+	flags.Var(&s.headers, "H", "HTTP header sent with the requests, eg. -H 'Authorization: Bearer token'. Repeatable")
+	flags.DurationVar(&s.timeout, "timeout", timeout, "Time the command has to complete. 0 waits forever")
+	flags.StringVar(&s.params, "params", "", "Params as a JSON array or object, instead of the positional arguments")
+	flags.BoolVar(&s.compact, "compact", false, "Print JSON on a single line instead of indented")
+	if err := flags.Parse(args[1:]); err != nil {
+		return EXIT_USAGE
+	}
+	if flags.NArg() < minArgs {
+		fmt.Fprintf(c.stderr(), "Missing arguments of %s\n", args[0])
+		c.usage()
+		return EXIT_USAGE
+	}
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	for _, header := range s.headers {
+		name, value, _ := strings.Cut(header, ":")
+		ctx = jsonrpc2.ContextWithHeader(ctx, strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if err := run(ctx, s, flags.Args()); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintln(c.stderr(), err)
+			return EXIT_USAGE
+		}
+
+		c.printError(err)
+		return EXIT_ERROR
+	}
+
+	return EXIT_OK
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (c *Command) usage() {
+	fmt.Fprintf(c.stderr(), `Usage:
+  %[1]s call [flags] URL METHOD [PARAM...]    Call the method and print its result
+  %[1]s notify [flags] URL METHOD [PARAM...]  Send a notification
+  %[1]s batch [flags] URL [FILE]              Send the requests of the file, or of the standard input, as a batch
+  %[1]s subscribe [flags] URL [TOPIC...]      Print the notifications published on the topics until interrupted
+
+URL is http://, https://, tcp://host:port, tls://host:port, ws:// or wss://. Batches need http:// or https://,
+subscriptions a persistent connection. Each PARAM is sent as JSON when it is valid JSON, else as a string.
+
+Flags:
+  -H 'Name: value'  HTTP header sent with the requests. Repeatable
+  -params JSON      Params as a JSON array or object, instead of the positional arguments
+  -timeout 30s      Time the command has to complete. 0 waits forever, the default of subscribe
+  -compact          Print JSON on a single line instead of indented
+`, c.name())
+}
+
+func (c *Command) call(ctx context.Context, s settings, args []string) error {
+	params, err := parseParams(s.params, args[2:])
+	if err != nil {
+		return err
+	}
+
+	client, err := c.dial(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var result json.RawMessage
+	if err := client.Call(ctx, args[1], params, &result); err != nil {
+		return err
+	}
+
+	return c.printJSON(s, result)
+}
+
+func (c *Command) notify(ctx context.Context, s settings, args []string) error {
+	params, err := parseParams(s.params, args[2:])
+	if err != nil {
+		return err
+	}
+
+	client, err := c.dial(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Notify(ctx, args[1], params)
+}
+
+// Send the requests of the file as they are, and print the responses. Fails when any response is an error
+func (c *Command) batch(ctx context.Context, s settings, args []string) error {
+	if !isHTTP(args[0]) {
+		return usageError{errors.New("Batches need an http:// or https:// URL")}
+	}
+
+	var body []byte
+	var err error
+	if len(args) < 2 || args[1] == "-" {
+		body, err = io.ReadAll(c.stdin())
+	} else {
+		body, err = os.ReadFile(args[1])
+	}
+	if err != nil {
+		return err
+	}
+	if !json.Valid(body) {
+		return usageError{errors.New("Requests are not valid JSON")}
+	}
+
+	transport := &jsonrpc2.HTTPTransport{URL: args[0]}
+	res, err := transport.RoundTrip(ctx, body, false)
+	if err != nil {
+		return err
+	}
+	//Only notifications were sent
+	if len(bytes.TrimSpace(res)) == 0 {
+		return nil
+	}
+
+	if err := c.printJSON(s, res); err != nil {
+		return err
+	}
+
+	type response struct {
+		Error *jsonrpc2.RpcError `json:"error"`
+	}
+	var responses []response
+	if trimmed := bytes.TrimSpace(res); trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &responses)
+	} else {
+		//A single error answers the whole batch, eg. when it can not be parsed
+		responses = make([]response, 1)
+		err = json.Unmarshal(trimmed, &responses[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, res := range responses {
+		if res.Error != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.New(fmt.Sprintf("%d of %d requests failed", failed, len(responses)))
+	}
+
+	return nil
+}
+
+// Print the events of rpc.subscribe until the context ends, eg. on interrupt
+func (c *Command) subscribe(ctx context.Context, s settings, args []string) error {
+	if isHTTP(args[0]) {
+		return usageError{errors.New("Subscriptions need a persistent connection, eg. a tcp://, tls:// or ws:// URL")}
+	}
+
+	client, err := c.dial(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	topics := make([]any, 0, len(args)-1)
+	for _, topic := range args[1:] {
+		topics = append(topics, topic)
+	}
+
+	events, unsubscribe, err := client.Subscribe(ctx, jsonrpc2.BUILTIN_SERVICE_NAME+".subscribe", topics)
+	if err != nil {
+		return err
+	}
+	defer unsubscribe()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return errors.New("Subscription ended")
+			}
+			if err := c.printJSON(s, event); err != nil {
+				return err
+			}
+
+		case <-ctx.Done():
+			//Interrupting is how the command is meant to stop
+			return nil
+		}
+	}
+}
+
+// Client of the endpoint, over the transport its scheme names
+func (c *Command) dial(ctx context.Context, endpoint string) (*jsonrpc2.Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, usageError{errors.New(fmt.Sprintf("Invalid URL %s", endpoint))}
+	}
+
+	var client *jsonrpc2.Client
+	switch u.Scheme {
+	case "http", "https":
+		client = jsonrpc2.NewHTTPClient(endpoint, c.ClientOptions...)
+
+	case "tcp", "tls", "ws", "wss":
+		var conn net.Conn
+		switch u.Scheme {
+		case "tcp":
+			var dialer net.Dialer
+			conn, err = dialer.DialContext(ctx, "tcp", u.Host)
+		case "tls":
+			conn, err = jsonrpc2.DialTLS(ctx, u.Host)
+		default:
+			conn, err = jsonrpc2.DialWebSocket(ctx, endpoint)
+		}
+		if err != nil {
+			return nil, err
+		}
+		client = jsonrpc2.NewConnClient(conn, c.ClientOptions...)
+
+	default:
+		return nil, usageError{errors.New(fmt.Sprintf("Unsupported URL scheme %q", u.Scheme))}
+	}
+
+	client.Use(c.Middleware...)
+
+	return client, nil
+}
+
+// Params of the -params flag, else of the positional arguments. Arguments that are valid JSON are sent as is, the
+// others as strings, so 1 is a number and Ada a string
+func parseParams(raw string, args []string) (any, error) {
+	if raw != "" {
+		var params any
+		if err := json.Unmarshal([]byte(raw), &params); err != nil {
+			return nil, usageError{errors.New(fmt.Sprintf("Invalid -params: %v", err))}
+		}
+		switch params.(type) {
+		case []any, map[string]any:
+			return params, nil
+		default:
+			return nil, usageError{errors.New("-params must be a JSON array or object")}
+		}
+	}
+
+	params := make([]any, len(args))
+	for i, arg := range args {
+		if json.Valid([]byte(arg)) {
+			params[i] = json.RawMessage(arg)
+		} else {
+			params[i] = arg
+		}
+	}
+
+	return params, nil
+}
+
+func isHTTP(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://")
+}
+
+// Print the JSON value indented, unless the command is compact
+func (c *Command) printJSON(s settings, value json.RawMessage) error {
+	var buf bytes.Buffer
+	var err error
+	if s.compact {
+		err = json.Compact(&buf, value)
+	} else {
+		err = json.Indent(&buf, value, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+
+	_, err = c.stdout().Write(buf.Bytes())
+	return err
+}
+
+// Print the error, with the code and data of errors of the server
+func (c *Command) printError(err error) {
+	var rpcErr *jsonrpc2.RpcError
+	if !errors.As(err, &rpcErr) {
+		fmt.Fprintf(c.stderr(), "Error: %v\n", err)
+		return
+	}
+
+	fmt.Fprintf(c.stderr(), "Error %d: %s\n", rpcErr.Code, rpcErr.Message)
+	if rpcErr.Data != nil {
+		data, _ := json.MarshalIndent(rpcErr.Data, "", "  ")
+		fmt.Fprintf(c.stderr(), "%s\n", data)
+	}
+}
+
+func (c *Command) name() string {
+	if c.Name == "" {
+		return "jsonrpc"
+	}
+
+	return c.Name
+}
+
+func (c *Command) stdin() io.Reader {
+	if c.Stdin == nil {
+		return os.Stdin
+	}
+
+	return c.Stdin
+}
+
+func (c *Command) stdout() io.Writer {
+	if c.Stdout == nil {
+		return os.Stdout
+	}
+
+	return c.Stdout
+}
+
+func (c *Command) stderr() io.Writer {
+	if c.Stderr == nil {
+		return os.Stderr
+	}
+
+	return c.Stderr
+}
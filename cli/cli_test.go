@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	greeter struct{}
+
+	person struct {
+		Name  string `json:"name"`
+		Title string `json:"title"`
+	}
+
+	//Buffer written by the command while the test reads it
+	syncBuffer struct {
+		mu  sync.Mutex
+		buf bytes.Buffer
+	}
+)
+
+func (greeter) Hello(ctx context.Context, name string, times int) (string, error, *jsonrpc2.RpcErrorCode) {
+	if header := jsonrpc2.HTTPRequestFromContext(ctx); header != nil && header.Header.Get("X-Lang") == "fr" {
+		return strings.Repeat("Bonjour "+name+"! ", times), nil, nil
+	}
+
+	return strings.Repeat("Hello "+name+"! ", times), nil, nil
+}
+
+func (greeter) Introduce(ctx context.Context, p person) (map[string]string, error, *jsonrpc2.RpcErrorCode) {
+	return map[string]string{"greeting": "Dear " + p.Title + " " + p.Name}, nil, nil
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func newTestServer(t *testing.T) (jsonrpc2.JsonRPC, string) {
+	rpc := jsonrpc2.NewJsonRpc()
+	rpc.RegisterWithName(greeter{}, "Greeter")
+
+	server := httptest.NewServer(rpc)
+	t.Cleanup(server.Close)
+
+	return rpc, server.URL
+}
+
+// Run the command and return its status, output and errors
+func runTestCommand(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	cmd := &Command{Stdin: strings.NewReader(stdin), Stdout: &stdout, Stderr: &stderr}
+	status := cmd.Run(context.Background(), args)
+
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCall(t *testing.T) {
+	_, url := newTestServer(t)
+
+	status, stdout, _ := runTestCommand([]string{"call", url, "Greeter.Hello", "Ada", "2"}, "")
+	assert.Equal(t, EXIT_OK, status)
+	assert.Equal(t, "\"Hello Ada! Hello Ada! \"\n", stdout)
+
+	status, stdout, _ = runTestCommand([]string{"call", "-H", "X-Lang: fr", url, "Greeter.Hello", "Ada", "1"}, "")
+	assert.Equal(t, EXIT_OK, status)
+	assert.Equal(t, "\"Bonjour Ada! \"\n", stdout)
+
+	//Results are indented unless compact
+	status, stdout, _ = runTestCommand([]string{"call", "-params", `{"name":"Lovelace","title":"Countess"}`, url, "Greeter.Introduce"}, "")
+	assert.Equal(t, EXIT_OK, status)
+	assert.Equal(t, "{\n  \"greeting\": \"Dear Countess Lovelace\"\n}\n", stdout)
+
+	_, stdout, _ = runTestCommand([]string{"call", "-compact", "-params", `{"name":"Ada"}`, url, "Greeter.Introduce"}, "")
+	assert.Equal(t, "{\"greeting\":\"Dear  Ada\"}\n", stdout)
+}
+
+func TestCallError(t *testing.T) {
+	_, url := newTestServer(t)
+
+	status, _, stderr := runTestCommand([]string{"call", url, "Greeter.Missing"}, "")
+	assert.Equal(t, EXIT_ERROR, status)
+	assert.Equal(t, "Error 32601: Method Missing does not exist on service Greeter\n", stderr)
+}
+
+func TestNotify(t *testing.T) {
+	_, url := newTestServer(t)
+
+	status, stdout, _ := runTestCommand([]string{"notify", url, "Greeter.Hello", "Ada", "1"}, "")
+	assert.Equal(t, EXIT_OK, status)
+	assert.Equal(t, "", stdout)
+}
+
+func TestBatch(t *testing.T) {
+	_, url := newTestServer(t)
+
+	requests := `[
+		{"jsonrpc":"2.0","id":"1","method":"Greeter.Hello","params":["Ada",1]},
+		{"jsonrpc":"2.0","id":"2","method":"Greeter.Hello","params":["Ada"]}
+	]`
+	status, stdout, stderr := runTestCommand([]string{"batch", "-compact", url}, requests)
+	assert.Equal(t, EXIT_ERROR, status)
+	assert.Contains(t, stdout, `"result":"Hello Ada! "`)
+	assert.Contains(t, stdout, `"id":"2"`)
+	assert.Equal(t, "Error: 1 of 2 requests failed\n", stderr)
+
+	status, stdout, _ = runTestCommand([]string{"batch", url, "-"}, `[{"jsonrpc":"2.0","method":"Greeter.Hello","params":["Ada",1]}]`)
+	assert.Equal(t, EXIT_OK, status)
+	assert.Equal(t, "", stdout)
+}
+
+func TestSubscribe(t *testing.T) {
+	rpc, _ := newTestServer(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	defer l.Close()
+
+	var stdout syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	status := make(chan int, 1)
+	go func() {
+		cmd := &Command{Stdout: &stdout, Stderr: &stdout}
+		status <- cmd.Run(ctx, []string{"subscribe", "-compact", "tcp://" + l.Addr().String(), "prices"})
+	}()
+
+	//Published until the subscription is made
+	for i := 0; i < 100 && stdout.String() == ""; i++ {
+		rpc.Publish("news", "headline", []any{"ignored"})
+		rpc.Publish("prices", "tick", []any{42})
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	assert.Equal(t, EXIT_OK, <-status)
+	assert.True(t, strings.HasPrefix(stdout.String(), `{"topic":"prices","method":"tick","params":[42]}`+"\n"))
+	assert.NotContains(t, stdout.String(), "headline")
+}
+
+func TestUsage(t *testing.T) {
+	status, _, stderr := runTestCommand([]string{"fetch"}, "")
+	assert.Equal(t, EXIT_USAGE, status)
+	assert.Contains(t, stderr, "Unknown command fetch")
+
+	status, _, stderr = runTestCommand([]string{"call", "http://localhost"}, "")
+	assert.Equal(t, EXIT_USAGE, status)
+	assert.Contains(t, stderr, "Missing arguments of call")
+
+	status, _, stderr = runTestCommand([]string{"batch", "tcp://localhost:9000"}, "[]")
+	assert.Equal(t, EXIT_USAGE, status)
+	assert.Equal(t, "Batches need an http:// or https:// URL\n", stderr)
+
+	status, _, stderr = runTestCommand([]string{"subscribe", "http://localhost"}, "")
+	assert.Equal(t, EXIT_USAGE, status)
+
+	status, _, stderr = runTestCommand([]string{"call", "-params", "1", "http://localhost", "Greeter.Hello"}, "")
+	assert.Equal(t, EXIT_USAGE, status)
+	assert.Equal(t, "-params must be a JSON array or object\n", stderr)
+
+	status, _, stderr = runTestCommand([]string{"call", "ftp://localhost", "Greeter.Hello"}, "")
+	assert.Equal(t, EXIT_USAGE, status)
+	assert.Equal(t, "Unsupported URL scheme \"ftp\"\n", stderr)
+}
@@ -0,0 +1,505 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type (
+	//ClientTransport carries encoded messages from a Client to a server
+	ClientTransport interface {
+		//Send the message and return the encoded response. Notifications have no response and return nil
+		RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error)
+
+		//Release the resources of the transport
+		Close() error
+	}
+
+	//Invoker sends a request and returns its response. The response is nil for notifications.
+	//On the client the Result of the response holds the undecoded json.RawMessage.
+	Invoker func(ctx context.Context, req *Request) (*Response, error)
+
+	//ClientMiddleware wraps every outgoing call and notification of a client.
+	//Use it for auth headers, logging, retries or metrics.
+	ClientMiddleware func(next Invoker) Invoker
+
+	//ClientOption configures a client. Options are passed to NewClient and the other client constructors
+	ClientOption func(*Client)
+
+	//Client calls methods on a JSON-RPC server
+	Client struct {
+		transport  ClientTransport
+		mu         sync.RWMutex
+		middleware []ClientMiddleware
+		invoker    Invoker
+		generateID func() ID
+		hedging    *hedging
+
+		subscriptions *clientSubscriptions //Created by the first call to Subscribe
+		streams       *clientStreams       //Created by the first call to Stream
+		handlers      *clientHandlers      //Created by the first call to Handle
+		watching      bool                 //Whether the notifications of the transport are received
+		strictNumbers bool                 //Decode numbers of results as json.Number, with WithStrictNumberResults
+	}
+
+	//HTTPTransport sends each message as a POST request
+	HTTPTransport struct {
+		URL    string       //Endpoint of the server
+		Client *http.Client //Defaults to http.DefaultClient
+
+		//Send the X-Content-SHA256 checksum of requests and verify the checksum of the responses carrying one, see
+		//WithChecksums
+		Checksums bool
+
+		pooled bool //Client was built by WithHTTPPool, so closing the transport closes its idle connections
+	}
+
+	//Transport over a persistent connection. Responses are matched to calls by request id
+	connTransport struct {
+		dial      func(ctx context.Context) (net.Conn, error) //Reconnects once the connection fails. Nil to stay disconnected
+		writeMu   sync.Mutex
+		mu        sync.Mutex
+		conn      net.Conn
+		pending   map[string]chan []byte
+		done      chan struct{} //Closed when conn fails
+		err       error
+		closed    chan struct{}
+		closeOnce sync.Once
+
+		onNotification func(msg []byte) //Receives the notifications and requests of the server
+		onReconnect    func()
+		offline        *offlineQueue //Messages held while disconnected, with WithOfflineQueue. Nil when they fail
+	}
+
+	//Response as received by the client. The result is decoded by the caller
+	clientResponse struct {
+		Jsonrpc string          `json:"jsonrpc"`
+		Id      *string         `json:"id"`
+		Meta    map[string]any  `json:"meta"`
+		Result  json.RawMessage `json:"result"`
+		Error   *RpcError       `json:"error"`
+	}
+
+	headerKey struct{}
+)
+
+// NewClient creates a client sending its messages over the transport
+func NewClient(transport ClientTransport, opts ...ClientOption) *Client {
+	c := &Client{transport: transport, generateID: SequentialIDs()}
+	c.invoker = c.send
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// NewHTTPClient creates a client calling the server at url over HTTP
+func NewHTTPClient(url string, opts ...ClientOption) *Client {
+	return NewClient(&HTTPTransport{URL: url}, opts...)
+}
+
+// NewConnClient creates a client calling the server over a persistent connection, eg. from net.Dial or DialTLS
+func NewConnClient(conn net.Conn, opts ...ClientOption) *Client {
+	return NewClient(newConnTransport(conn), opts...)
+}
+
+// Use adds middleware around every call and notification. The first middleware added is the outermost.
+func (c *Client) Use(middleware ...ClientMiddleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.middleware = append(c.middleware, middleware...)
+
+	invoker := Invoker(c.send)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		invoker = c.middleware[i](invoker)
+	}
+	c.invoker = invoker
+}
+
+// Call the method with params and decode its result into result.
+// Errors returned by the server are of type *RpcError.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	return c.call(ctx, string(c.generateID()), method, params, result)
+}
+
+// Call the method with the request id
+func (c *Client) call(ctx context.Context, id string, method string, params any, result any) error {
+	res, err := c.invoke(ctx, &Request{
+		Jsonrpc: RPC_VERSION,
+		Id:      &id,
+		Method:  method,
+		Params:  clientParams(params),
+	})
+	if err != nil {
+		return err
+	}
+
+	if res == nil {
+		return errors.New("No response received")
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if res.Result == nil {
+		return nil
+	}
+
+	raw, ok := (*res.Result).(json.RawMessage)
+	if !ok {
+		//A middleware replaced the result
+		raw, err = json.Marshal(*res.Result)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := checkExpectedResult(ctx, method, raw); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if c.strictNumbers {
+		return decodeStrictNumbers(raw, result)
+	}
+
+	return json.Unmarshal(raw, result)
+}
+
+// Notify calls the method without waiting for a result
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	_, err := c.invoke(ctx, &Request{
+		Jsonrpc: RPC_VERSION,
+		Method:  method,
+		Params:  clientParams(params),
+	})
+
+	return err
+}
+
+// Close the transport of the client, and the secondary transport of hedged calls
+func (c *Client) Close() error {
+	if c.hedging != nil {
+		c.hedging.secondary.Close()
+	}
+
+	c.mu.RLock()
+	if c.subscriptions != nil {
+		c.subscriptions.close()
+	}
+	if c.handlers != nil {
+		c.handlers.close()
+	}
+	c.mu.RUnlock()
+
+	return c.transport.Close()
+}
+
+func (c *Client) invoke(ctx context.Context, req *Request) (*Response, error) {
+	c.mu.RLock()
+	invoker := c.invoker
+	c.mu.RUnlock()
+
+	return invoker(ctx, req)
+}
+
+// Innermost invoker. It encodes the request and decodes the response
+func (c *Client) send(ctx context.Context, req *Request) (*Response, error) {
+	msg, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	notification := req.Id == nil
+	transport := c.transportFor(ctx)
+	var body []byte
+	if c.hedging.hedges(req) {
+		body, err = c.hedging.roundTrip(ctx, transport, msg)
+	} else {
+		body, err = transport.RoundTrip(ctx, msg, notification)
+	}
+	if err != nil || notification {
+		return nil, err
+	}
+
+	res := &clientResponse{}
+	if err := json.Unmarshal(body, res); err != nil {
+		return nil, err
+	}
+
+	response := &Response{
+		Jsonrpc: res.Jsonrpc,
+		Id:      res.Id,
+		Meta:    res.Meta,
+		Error:   res.Error,
+	}
+	if res.Error == nil {
+		var result any = res.Result
+		response.Result = &result
+	}
+
+	return response, nil
+}
+
+// Params are always sent as a structured value
+func clientParams(params any) any {
+	if params == nil {
+		return []any{}
+	}
+
+	return params
+}
+
+// ContextWithHeader returns a context that makes the HTTP transport send the header with the call.
+// Client middleware use it to attach auth headers.
+func ContextWithHeader(ctx context.Context, key, value string) context.Context {
+	header := http.Header{}
+	if parent, ok := ctx.Value(headerKey{}).(http.Header); ok {
+		header = parent.Clone()
+	}
+	header.Add(key, value)
+
+	return context.WithValue(ctx, headerKey{}, header)
+}
+
+func (t *HTTPTransport) RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+	if t.Checksums {
+		r.Header.Set(CHECKSUM_HEADER, checksumOf(msg))
+	}
+	if timeout, ok := timeoutHeader(ctx); ok {
+		r.Header.Set(TIMEOUT_HEADER, timeout)
+	}
+	header, _ := ctx.Value(headerKey{}).(http.Header)
+	setMetadataHeaders(ctx, r.Header, header)
+	if header != nil {
+		for key, values := range header {
+			for _, value := range values {
+				r.Header.Add(key, value)
+			}
+		}
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNoContent || notification {
+		return nil, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("Unexpected HTTP status %s", res.Status))
+	}
+	//Bodies net/http decompressed no longer match the checksum of the body sent
+	if sum := res.Header.Get(CHECKSUM_HEADER); t.Checksums && sum != "" && !res.Uncompressed && !strings.EqualFold(sum, checksumOf(body)) {
+		return nil, errors.New(fmt.Sprintf("Response body does not match its %s header", CHECKSUM_HEADER))
+	}
+
+	return body, nil
+}
+
+func (t *HTTPTransport) Close() error {
+	if t.pooled {
+		t.Client.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+func newConnTransport(conn net.Conn) *connTransport {
+	t := &connTransport{
+		conn:    conn,
+		pending: make(map[string]chan []byte),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	go t.readLoop(conn, t.done)
+
+	return t
+}
+
+func (t *connTransport) RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error) {
+	if notification {
+		_, err := t.send(ctx, msg, true)
+		return nil, err
+	}
+
+	var req struct {
+		Id *string `json:"id"`
+	}
+	if err := json.Unmarshal(msg, &req); err != nil || req.Id == nil {
+		return nil, errors.New("Only single requests with an id can be sent on a connection")
+	}
+
+	resChan := make(chan []byte, 1)
+	t.mu.Lock()
+	t.pending[*req.Id] = resChan
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, *req.Id)
+		t.mu.Unlock()
+	}()
+
+	done, err := t.send(ctx, msg, false)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-resChan:
+		return res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, t.err
+	}
+}
+
+func (t *connTransport) write(msg []byte) error {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	_, err := conn.Write(append(msg, '\n'))
+	return err
+}
+
+// Read responses and hand them to the pending calls until the connection fails.
+// Notifications and requests of the server go to onNotification.
+func (t *connTransport) readLoop(conn net.Conn, done chan struct{}) {
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg json.RawMessage
+		if err := decoder.Decode(&msg); err != nil {
+			t.mu.Lock()
+			t.err = err
+			if errors.Is(err, io.EOF) {
+				t.err = errors.New("Connection closed")
+			}
+			close(done)
+			t.mu.Unlock()
+
+			if t.dial != nil {
+				t.reconnect()
+			}
+			return
+		}
+
+		//Requests of the server may have ids of any type
+		var res struct {
+			Id     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Error  json.RawMessage `json:"error"`
+		}
+		if err := json.Unmarshal(msg, &res); err != nil {
+			continue
+		}
+		isNotification := res.Id == nil || bytes.Equal(res.Id, []byte("null"))
+		if isNotification && res.Method == "" && res.Error != nil {
+			//The server could not read the id of a request, eg. a parse error. It fails the pending calls
+			t.failPending(msg)
+			continue
+		}
+		if isNotification || res.Method != "" {
+			if isNotification && res.Method == HEARTBEAT_PING_METHOD {
+				//Answered aside so reading goes on while the write waits
+				go t.write(pongNotification)
+				continue
+			}
+
+			t.mu.Lock()
+			onNotification := t.onNotification
+			t.mu.Unlock()
+
+			if res.Method != "" && onNotification != nil {
+				onNotification(msg)
+			}
+			continue
+		}
+
+		var id string
+		if err := json.Unmarshal(res.Id, &id); err != nil {
+			continue
+		}
+
+		t.mu.Lock()
+		resChan, ok := t.pending[id]
+		t.mu.Unlock()
+
+		if ok {
+			select {
+			case resChan <- msg:
+			default:
+				//Duplicate response for the id
+			}
+		}
+	}
+}
+
+// Hand an error response without an id to every pending call, since it can not tell which call it answers
+func (t *connTransport) failPending(msg []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, resChan := range t.pending {
+		select {
+		case resChan <- msg:
+		default:
+		}
+	}
+}
+
+// Call the handlers with the notifications received and once the transport reconnected
+func (t *connTransport) watch(notification func(msg []byte), reconnected func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onNotification = notification
+	t.onReconnect = reconnected
+}
+
+func (t *connTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.conn.Close()
+}
@@ -0,0 +1,128 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHTTPServer(t *testing.T, handler http.Handler) string {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func newTestArithRpc() JsonRPC {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	return rpc
+}
+
+func TestClientCall(t *testing.T) {
+	client := NewHTTPClient(newTestHTTPServer(t, newTestArithRpc()))
+
+	var sum int
+	err := client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, sum)
+}
+
+func TestClientCallError(t *testing.T) {
+	client := NewHTTPClient(newTestHTTPServer(t, newTestArithRpc()))
+
+	err := client.Call(context.Background(), "Arith.ErrorMethod", nil, nil)
+
+	var rpcErr *RpcError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, INTERNAL_ERROR, rpcErr.Code)
+	assert.Equal(t, "Some error here", rpcErr.Message)
+}
+
+func TestClientNotify(t *testing.T) {
+	client := NewHTTPClient(newTestHTTPServer(t, newTestArithRpc()))
+
+	assert.NoError(t, client.Notify(context.Background(), "Arith.Add", []any{1, 2}))
+}
+
+func TestClientMiddleware(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	var authorization string
+	url := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		rpc.ServeHTTP(w, r)
+	}))
+
+	var calls []string
+	client := NewHTTPClient(url)
+	client.Use(
+		func(next Invoker) Invoker {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				calls = append(calls, "outer:"+req.Method)
+				return next(ContextWithHeader(ctx, "Authorization", "Bearer token"), req)
+			}
+		},
+		func(next Invoker) Invoker {
+			return func(ctx context.Context, req *Request) (*Response, error) {
+				calls = append(calls, "inner:"+req.Method)
+				return next(ctx, req)
+			}
+		},
+	)
+
+	var sum int
+	assert.NoError(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.NoError(t, client.Notify(context.Background(), "Arith.Add", []any{1, 2}))
+
+	assert.Equal(t, "Bearer token", authorization)
+	assert.Equal(t, []string{"outer:Arith.Add", "inner:Arith.Add", "outer:Arith.Add", "inner:Arith.Add"}, calls)
+}
+
+func TestConnClient(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	go newTestArithRpc().ServeConn(serverConn)
+
+	client := NewConnClient(clientConn)
+	defer client.Close()
+
+	results := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(n int) {
+			var sum int
+			assert.NoError(t, client.Call(context.Background(), "Arith.Add", []any{n, n}, &sum))
+			results <- sum
+		}(i + 1)
+	}
+
+	assert.ElementsMatch(t, []int{2, 4}, []int{<-results, <-results})
+}
+
+func TestConnClientErrorWithoutId(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	go func() {
+		//A server that could not read the id of the request answers with a null id
+		bufio.NewReader(serverConn).ReadBytes('\n')
+		serverConn.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":32600,"message":"Invalid request"}}` + "\n"))
+	}()
+
+	client := NewConnClient(clientConn)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := client.Call(ctx, "Arith.Add", []any{1, 2}, nil)
+
+	var rpcErr *RpcError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, INVALID_REQUEST, rpcErr.Code)
+}
@@ -0,0 +1,158 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type (
+	//ClientHandler handles a request or a notification the server sends to a client. The result answers requests
+	//and is dropped for notifications. Return an *RpcError to answer with its code, other errors answer INTERNAL_ERROR
+	ClientHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+	//Handlers of the messages of the server, by method
+	clientHandlers struct {
+		mu            sync.RWMutex
+		byMethod      map[string]ClientHandler
+		notifications chan func() //Notifications waiting for their handler, in the order they were received
+		ctx           context.Context
+		cancel        context.CancelFunc
+	}
+
+	//Request or notification of the server. The id is kept raw since servers may use numbers
+	serverMessage struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+
+	//Response whose id is kept raw, eg. the answer of the client to a request of the server
+	handledResponse struct {
+		Jsonrpc string          `json:"jsonrpc"`
+		Id      json.RawMessage `json:"id"`
+		Meta    map[string]any  `json:"meta,omitempty"`
+		Result  *any            `json:"result,omitempty"`
+		Error   *RpcError       `json:"error,omitempty"`
+	}
+)
+
+// Handle calls handler with the requests and notifications of the server for the method, so both ends of a
+// persistent connection serve calls, as in the Language Server Protocol. Requests are handled concurrently and
+// answered with the result of the handler. Requests of methods without a handler are answered with
+// METHOD_NOT_FOUND. Notifications are handled one at a time in the order they were received. Up to
+// SUBSCRIPTION_BUFFER_SIZE of them wait for their handler before reading the connection waits too.
+// Frames of streams still go to them, while a handler of the method of subscription events, eg. rpc.subscription,
+// receives them in place of the subscriptions. A nil handler removes the handler of the method.
+// Handlers need a persistent connection, eg. NewConnClient or NewReconnectingClient. Their context is cancelled
+// when the client is closed.
+func (c *Client) Handle(method string, handler ClientHandler) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.watchTransport() {
+		return errors.New("Handlers need a persistent connection")
+	}
+	if c.handlers == nil {
+		c.handlers = newClientHandlers()
+	}
+
+	c.handlers.mu.Lock()
+	defer c.handlers.mu.Unlock()
+
+	if handler == nil {
+		delete(c.handlers.byMethod, method)
+	} else {
+		c.handlers.byMethod[method] = handler
+	}
+
+	return nil
+}
+
+func newClientHandlers() *clientHandlers {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &clientHandlers{
+		byMethod:      make(map[string]ClientHandler),
+		notifications: make(chan func(), SUBSCRIPTION_BUFFER_SIZE),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	go h.handleNotifications()
+
+	return h
+}
+
+// Handle a message of the server. Requests are answered with reply. False when the message is a notification
+// without a handler
+func (h *clientHandlers) receive(msg serverMessage, reply func(ctx context.Context, msg []byte) error) bool {
+	h.mu.RLock()
+	handler, ok := h.byMethod[msg.Method]
+	h.mu.RUnlock()
+
+	if msg.Id == nil {
+		if !ok {
+			return false
+		}
+
+		select {
+		case h.notifications <- func() { h.call(handler, msg) }:
+		case <-h.ctx.Done():
+		}
+		return true
+	}
+
+	go func() {
+		res := handledResponse{Jsonrpc: RPC_VERSION, Id: msg.Id}
+		if ok {
+			res.Result, res.Error = h.call(handler, msg)
+		} else {
+			res.Error = &RpcError{Code: METHOD_NOT_FOUND, Message: fmt.Sprintf("Method %s does not exist", msg.Method)}
+		}
+
+		encoded, err := json.Marshal(&res)
+		if err != nil {
+			encoded, _ = json.Marshal(&handledResponse{Jsonrpc: RPC_VERSION, Id: msg.Id, Error: &RpcError{Code: INTERNAL_ERROR, Message: err.Error()}})
+		}
+		reply(h.ctx, encoded)
+	}()
+
+	return true
+}
+
+// Call the handler with the message and return its result or error. Panics are recovered as INTERNAL_ERROR
+func (h *clientHandlers) call(handler ClientHandler, msg serverMessage) (result *any, rpcErr *RpcError) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result, rpcErr = nil, &RpcError{Code: INTERNAL_ERROR, Message: fmt.Sprintf("Handler of %s panicked: %v", msg.Method, recovered)}
+		}
+	}()
+
+	value, err := handler(h.ctx, msg.Params)
+	if err != nil {
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		return nil, &RpcError{Code: INTERNAL_ERROR, Message: err.Error(), err: err}
+	}
+
+	return &value, nil
+}
+
+// Call the handlers of the notifications in order until the client is closed
+func (h *clientHandlers) handleNotifications() {
+	for {
+		select {
+		case handle := <-h.notifications:
+			handle()
+		case <-h.ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop handling the messages of the server
+func (h *clientHandlers) close() {
+	h.cancel()
+}
@@ -0,0 +1,106 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Connected client and the reader of what it writes, standing for the server end
+func newHandlingClient(t *testing.T) (*Client, net.Conn, *bufio.Reader) {
+	clientConn, serverConn := net.Pipe()
+	client := NewConnClient(clientConn)
+	t.Cleanup(func() {
+		client.Close()
+		serverConn.Close()
+	})
+
+	return client, serverConn, bufio.NewReader(serverConn)
+}
+
+func TestClientHandleRequests(t *testing.T) {
+	client, server, replies := newHandlingClient(t)
+
+	assert.Nil(t, client.Handle("workspace/configuration", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var items []string
+		if err := json.Unmarshal(params, &items); err != nil {
+			return nil, &RpcError{Code: INVALID_PARAMS, Message: "Params must be a list of sections"}
+		}
+		return map[string]int{items[0]: len(items)}, nil
+	}))
+	assert.Nil(t, client.Handle("fail", func(ctx context.Context, params json.RawMessage) (any, error) {
+		return nil, errors.New("Not now")
+	}))
+
+	//Numeric ids are answered as they are
+	server.Write([]byte(`{"jsonrpc":"2.0","id":7,"method":"workspace/configuration","params":["editor","files"]}` + "\n"))
+	line, _ := replies.ReadBytes('\n')
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":7,"result":{"editor":2}}`, string(line))
+
+	server.Write([]byte(`{"jsonrpc":"2.0","id":"a","method":"workspace/configuration","params":{}}` + "\n"))
+	line, _ = replies.ReadBytes('\n')
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"a","error":{"code":32602,"message":"Params must be a list of sections","data":null}}`, string(line))
+
+	server.Write([]byte(`{"jsonrpc":"2.0","id":"b","method":"fail"}` + "\n"))
+	line, _ = replies.ReadBytes('\n')
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"b","error":{"code":32603,"message":"Not now","data":null}}`, string(line))
+
+	assert.Nil(t, client.Handle("fail", nil))
+	server.Write([]byte(`{"jsonrpc":"2.0","id":"c","method":"fail"}` + "\n"))
+	line, _ = replies.ReadBytes('\n')
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"c","error":{"code":32601,"message":"Method fail does not exist","data":null}}`, string(line))
+}
+
+func TestClientHandleNotifications(t *testing.T) {
+	client, server, _ := newHandlingClient(t)
+
+	logs := make(chan string, 3)
+	assert.Nil(t, client.Handle("event.log", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var args []string
+		json.Unmarshal(params, &args)
+		logs <- args[0]
+		return nil, nil
+	}))
+
+	for _, msg := range []string{"first", "second", "third"} {
+		server.Write([]byte(`{"jsonrpc":"2.0","method":"event.log","params":["` + msg + `"]}` + "\n"))
+	}
+	//Notifications without a handler are dropped
+	server.Write([]byte(`{"jsonrpc":"2.0","method":"event.other","params":[]}` + "\n"))
+
+	assert.Equal(t, []string{"first", "second", "third"}, []string{<-logs, <-logs, <-logs})
+}
+
+func TestClientHandleCallsBack(t *testing.T) {
+	rpc := newTestArithRpc()
+	clientConn, serverConn := net.Pipe()
+	go rpc.ServeConn(serverConn)
+
+	client := NewConnClient(clientConn)
+	defer client.Close()
+
+	//Handlers can call the server on the same connection
+	sums := make(chan int, 1)
+	assert.Nil(t, client.Handle(SUBSCRIPTION_METHOD, func(ctx context.Context, params json.RawMessage) (any, error) {
+		var sum int
+		err := client.Call(ctx, "Arith.Add", []any{1, 2}, &sum)
+		sums <- sum
+		return nil, err
+	}))
+
+	var id string
+	assert.Nil(t, client.Call(context.Background(), "rpc.subscribe", []any{"math"}, &id))
+	rpc.Publish("math", "sum", []any{})
+	assert.Equal(t, 3, <-sums)
+}
+
+func TestClientHandleNeedsConnection(t *testing.T) {
+	client := NewHTTPClient("http://localhost:0")
+	err := client.Handle("event.log", func(ctx context.Context, params json.RawMessage) (any, error) { return nil, nil })
+	assert.EqualError(t, err, "Handlers need a persistent connection")
+}
@@ -0,0 +1,87 @@
+// Command jsonrpc-replay sends recorded requests to a JSON-RPC server over HTTP and prints the responses that differ.
+//
+//	jsonrpc-replay -target http://localhost:8000 [-baseline http://prod:8000] [-speed 1] [-ignore updatedAt,id] audit.log
+//
+// Records are read from the files given, or from the standard input. It exits with status 1 when responses differ.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+	"github.com/developertom01/jsonrpc2/replay"
+)
+
+func main() {
+	target := flag.String("target", "", "URL of the server the requests are replayed on")
+	baseline := flag.String("baseline", "", "URL of a server whose responses are expected instead of the recorded ones")
+	speed := flag.Float64("speed", 0, "Pace relative to the recorded times. 0 replays as fast as possible")
+	ignore := flag.String("ignore", "", "Comma separated dotted paths of result fields left out of the comparison")
+	flag.Parse()
+
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "Missing -target")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	records, err := readRecords(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	opts := replay.Options{Speed: *speed}
+	if *baseline != "" {
+		opts.Baseline = jsonrpc2.NewHTTPClient(*baseline)
+	}
+	if *ignore != "" {
+		opts.Ignore = strings.Split(*ignore, ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := replay.Replay(ctx, records, jsonrpc2.NewHTTPClient(*target), opts)
+	for _, diff := range report.Diffs {
+		fmt.Printf("%s %v\n  expected: %s\n  actual:   %s\n", diff.Record.Method, diff.Record.Params, diff.Expected, diff.Actual)
+	}
+	fmt.Printf("%d sent, %d matched, %d differ\n", report.Sent, report.Matched, len(report.Diffs))
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if len(report.Diffs) > 0 {
+		os.Exit(1)
+	}
+}
+
+func readRecords(files []string) ([]replay.Record, error) {
+	if len(files) == 0 {
+		return replay.ReadRecords(os.Stdin)
+	}
+
+	records := make([]replay.Record, 0)
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+
+		read, err := replay.ReadRecords(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("%s: %s", name, err))
+		}
+		records = append(records, read...)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,25 @@
+// Command jsonrpc calls methods of a JSON-RPC server, sends batches and prints the events of subscriptions.
+//
+//	jsonrpc call [-H 'Name: value'] [-params JSON] [-timeout 30s] [-compact] URL METHOD [PARAM...]
+//	jsonrpc notify URL METHOD [PARAM...]
+//	jsonrpc batch URL [FILE]
+//	jsonrpc subscribe URL [TOPIC...]
+//
+// It exits with status 1 when the server answers with an error, and 2 when the command line is invalid.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/developertom01/jsonrpc2/cli"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	status := cli.Run(ctx, os.Args[1:])
+	stop()
+
+	os.Exit(status)
+}
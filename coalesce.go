@@ -0,0 +1,99 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+type (
+	//Calls being coalesced, keyed by method and params
+	coalescer struct {
+		mu    sync.Mutex
+		calls map[string]*coalescedCall
+	}
+
+	//Call whose response is shared by identical calls arriving while it runs
+	coalescedCall struct {
+		done      chan struct{}
+		res       Response
+		abandoned bool //The call failed as its own context ended, eg. cancelled by its client or past its deadline
+	}
+)
+
+func newCoalescer() *coalescer {
+	return &coalescer{calls: make(map[string]*coalescedCall)}
+}
+
+// WithCoalescing runs the handler once for identical concurrent calls of the methods, ie. calls with the same
+// method and params, and answers all of them with its response, like singleflight. Calls arriving after the
+// response are handled again, nothing is cached. Use it for read-only methods whose result does not depend on the
+// caller, since middleware runs for each call but the handler only for the first one. method is the full method
+// name. eg. Prices.Get
+func WithCoalescing(methods ...string) Option {
+	return func(c *config) {
+		if c.coalescedMethods == nil {
+			c.coalescedMethods = make(map[string]bool, len(methods))
+		}
+		for _, method := range methods {
+			c.coalescedMethods[method] = true
+		}
+	}
+}
+
+// Key identifying the calls the request can share a response with. False when the method is not coalesced
+func (c *config) coalescingKey(req *Request) (string, bool) {
+	if !c.coalescedMethods[req.Method] || req.Id == nil {
+		return "", false
+	}
+
+	//Keys of objects are sorted, so named params in any order match
+	params, err := json.Marshal(req.Params)
+	if err != nil {
+		return "", false
+	}
+
+	return req.Method + "\n" + string(params), true
+}
+
+// Call the method of the service, sharing the response of an identical call in flight when the method is coalesced
+func (s *jsonRpcImpl) invokeCoalesced(ctx context.Context, cfg *config, service *service, methodName string, req *Request) Response {
+	key, ok := cfg.coalescingKey(req)
+	if !ok {
+		return s.invoke(ctx, cfg, service, methodName, req)
+	}
+
+	s.coalesced.mu.Lock()
+	if shared, ok := s.coalesced.calls[key]; ok {
+		s.coalesced.mu.Unlock()
+
+		select {
+		case <-shared.done:
+		case <-ctx.Done():
+			return cfg.makeMappedErrorResponse(callContextError(ctx), req.Id)
+		}
+
+		//The call was cancelled or timed out on its own context, which says nothing of this one
+		if shared.abandoned {
+			return s.invokeCoalesced(ctx, cfg, service, methodName, req)
+		}
+
+		res := shared.res
+		res.Id = req.Id
+		return res
+	}
+
+	shared := &coalescedCall{done: make(chan struct{})}
+	s.coalesced.calls[key] = shared
+	s.coalesced.mu.Unlock()
+
+	shared.res = s.invoke(ctx, cfg, service, methodName, req)
+	shared.abandoned = shared.res.Error != nil && ctx.Err() != nil
+
+	s.coalesced.mu.Lock()
+	delete(s.coalesced.calls, key)
+	s.coalesced.mu.Unlock()
+	close(shared.done)
+
+	return shared.res
+}
@@ -0,0 +1,116 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type prices struct {
+	calls   *atomic.Int32
+	release chan struct{}
+}
+
+func (p prices) Get(ctx context.Context, symbol string) (string, error, *RpcErrorCode) {
+	p.calls.Add(1)
+	<-p.release
+
+	return symbol + ":42", nil, nil
+}
+
+func TestCoalescing(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	rpc := NewJsonRpc(WithCoalescing("Prices.Get"))
+	rpc.RegisterWithName(prices{calls: &calls, release: release}, "Prices")
+
+	var wg sync.WaitGroup
+	responses := make([]Response, 6)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, symbol := string(rune('a'+i)), "ACME"
+			if i == 5 {
+				symbol = "INIT"
+			}
+			responses[i] = rpc.Dispatch(context.Background(), Request{Jsonrpc: RPC_VERSION, Id: &id, Method: "Prices.Get", Params: []any{symbol}})
+		}(i)
+	}
+
+	//Let the calls arrive while the first ones run
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), calls.Load())
+	for i, res := range responses[:5] {
+		assert.Equal(t, string(rune('a'+i)), *res.Id)
+		assert.Equal(t, any("ACME:42"), *res.Result)
+	}
+	assert.Equal(t, any("INIT:42"), *responses[5].Result)
+
+	//Nothing is cached once the calls returned
+	callMethod(t, rpc, "Prices.Get", []any{"ACME"})
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestCoalescingOtherMethods(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	close(release)
+	rpc := NewJsonRpc(WithCoalescing("Prices.List"))
+	rpc.RegisterWithName(prices{calls: &calls, release: release}, "Prices")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callMethod(t, rpc, "Prices.Get", []any{"ACME"})
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestCoalescingLeaderDeadline(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	rpc := NewJsonRpc(WithCoalescing("Prices.Get"))
+	rpc.RegisterWithName(prices{calls: &calls, release: release}, "Prices")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	leader := make(chan Response)
+	go func() {
+		id := "a"
+		leader <- rpc.Dispatch(ctx, Request{Jsonrpc: RPC_VERSION, Id: &id, Method: "Prices.Get", Params: []any{"ACME"}})
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	follower := make(chan Response)
+	go func() {
+		id := "b"
+		follower <- rpc.Dispatch(context.Background(), Request{Jsonrpc: RPC_VERSION, Id: &id, Method: "Prices.Get", Params: []any{"ACME"}})
+	}()
+
+	//The follower calls the method again once the leader ran out of time
+	assert.NotNil(t, (<-leader).Error)
+	for calls.Load() == 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	res := <-follower
+	assert.Equal(t, "b", *res.Id)
+	assert.Equal(t, any("ACME:42"), *res.Result)
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,121 @@
+package jsonrpc2
+
+import (
+	"reflect"
+)
+
+// Tag value of an embedded field whose methods stay under the outer service when embedded services are namespaced
+const EMBED_INLINE = "inline"
+
+// Exported field embedded in a struct service that is hidden or exposed as a service of its own
+type embeddedField struct {
+	index     int
+	typ       reflect.Type
+	hidden    bool
+	namespace string
+}
+
+// Embedded fields of a struct service whose promoted methods are not exposed under the service itself. A field tagged
+// rpc:"-" is hidden, one tagged rpc:"name" is exposed under the namespace name and one tagged rpc:"inline" stays under
+// the service. Untagged fields are namespaced after the field name when the options namespace embedded services.
+func embeddedFields(srv any, opts ServiceOptions) []embeddedField {
+	t := reflect.TypeOf(srv)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []embeddedField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("rpc")
+		switch {
+		case tag == "-":
+			fields = append(fields, embeddedField{index: i, typ: field.Type, hidden: true})
+		case tag == EMBED_INLINE || !field.IsExported():
+			//The value of an unexported field cannot be registered on its own
+		case tag != "":
+			fields = append(fields, embeddedField{index: i, typ: field.Type, namespace: tag})
+		case opts.NamespaceEmbedded:
+			fields = append(fields, embeddedField{index: i, typ: field.Type, namespace: field.Name})
+		}
+	}
+
+	return fields
+}
+
+// Services of the values embedded in srv under their own namespace, nested under the name of srv. Embedded pointers
+// and interfaces that are nil and embedded structs without any valid method are skipped.
+func (rpc *jsonRpcImpl) embeddedServices(srv any, name string, opts ServiceOptions) ([]*service, error) {
+	nested := ServiceOptions{
+		Docs:              opts.Docs,
+		Middleware:        opts.Middleware,
+		MethodNamer:       opts.MethodNamer,
+		NamespaceEmbedded: opts.NamespaceEmbedded,
+	}
+
+	var services []*service
+	v := reflect.ValueOf(srv)
+	for _, field := range embeddedFields(srv, opts) {
+		if field.hidden {
+			continue
+		}
+
+		embedded, ok := embeddedValue(v, field.index)
+		if !ok {
+			continue
+		}
+
+		serviceName := field.namespace
+		if name != ROOT_SERVICE_NAME {
+			serviceName = name + "." + field.namespace
+		}
+
+		service, err := rpc.newService(embedded, serviceName, nested)
+		if err != nil {
+			return nil, err
+		}
+		if len(service.methods) > 0 {
+			services = append(services, service)
+		}
+
+		inner, err := rpc.embeddedServices(embedded, serviceName, nested)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, inner...)
+	}
+
+	return services, nil
+}
+
+// Value of the embedded field to register. Fields of a service registered by pointer are taken by address so their
+// methods with a pointer receiver are exposed as they are when promoted.
+func embeddedValue(v reflect.Value, index int) (any, bool) {
+	addressable := v.Kind() == reflect.Pointer
+	if addressable {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	field := v.Field(index)
+	switch {
+	case field.Kind() == reflect.Pointer || field.Kind() == reflect.Interface:
+		if field.IsNil() {
+			return nil, false
+		}
+		return field.Interface(), true
+	case addressable:
+		return field.Addr().Interface(), true
+	default:
+		return field.Interface(), true
+	}
+}
@@ -0,0 +1,89 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	Catalog struct {
+		items []string
+	}
+
+	Orders struct{}
+
+	Carts struct{}
+
+	//Service composed of the services it embeds
+	storefront struct {
+		*Catalog
+		Orders
+		Carts `rpc:"inline"`
+	}
+)
+
+func (c *Catalog) Add(ctx context.Context, item string) (int, error, *RpcErrorCode) {
+	c.items = append(c.items, item)
+	return len(c.items), nil, nil
+}
+
+func (Orders) Place(ctx context.Context, item string) (string, error, *RpcErrorCode) {
+	return "placed " + item, nil, nil
+}
+
+func (Carts) Empty(ctx context.Context) (bool, error, *RpcErrorCode) {
+	return true, nil, nil
+}
+
+func (storefront) Open(ctx context.Context) (bool, error, *RpcErrorCode) {
+	return true, nil, nil
+}
+
+func TestEmbeddedServicesInline(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithName(&storefront{Catalog: &Catalog{}}, "Store"))
+
+	assert.Equal(t, any(float64(1)), *callMethod(t, rpc, "Store.Add", []any{"book"}).Result)
+	assert.Equal(t, any("placed book"), *callMethod(t, rpc, "Store.Place", []any{"book"}).Result)
+	assert.Nil(t, callMethod(t, rpc, "Store.Empty", nil).Error)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Store.Catalog.Add", []any{"book"}).Error.Code)
+}
+
+func TestEmbeddedServicesNamespaced(t *testing.T) {
+	rpc := NewJsonRpc()
+	catalog := &Catalog{}
+	opts := ServiceOptions{Name: "Store", NamespaceEmbedded: true}
+	assert.Nil(t, rpc.RegisterWithOptions(&storefront{Catalog: catalog}, opts))
+
+	assert.Equal(t, any(float64(1)), *callMethod(t, rpc, "Store.Catalog.Add", []any{"book"}).Result)
+	assert.Equal(t, []string{"book"}, catalog.items)
+	assert.Equal(t, any("placed pen"), *callMethod(t, rpc, "Store.Orders.Place", []any{"pen"}).Result)
+	assert.Nil(t, callMethod(t, rpc, "Store.Open", nil).Error)
+
+	//Namespaced methods leave the outer service, inline ones stay
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Store.Add", []any{"book"}).Error.Code)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Store.Place", []any{"book"}).Error.Code)
+	assert.Nil(t, callMethod(t, rpc, "Store.Empty", nil).Error)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Store.Carts.Empty", nil).Error.Code)
+}
+
+func TestEmbeddedServicesTagged(t *testing.T) {
+	type shop struct {
+		Orders `rpc:"purchases"`
+		*Catalog
+	}
+
+	//Nil embedded pointers are skipped, embedded values of a pointer service are addressable
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithOptions(&shop{}, ServiceOptions{Name: "Shop", NamespaceEmbedded: true}))
+	assert.Nil(t, callMethod(t, rpc, "Shop.purchases.Place", []any{"pen"}).Error)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Shop.Catalog.Add", []any{"pen"}).Error.Code)
+
+	//At the root, namespaces are the top level services
+	rpc = NewJsonRpc()
+	assert.Nil(t, rpc.RegisterRoot(&shop{Catalog: &Catalog{}}))
+	assert.Nil(t, callMethod(t, rpc, "purchases.Place", []any{"pen"}).Error)
+	assert.Nil(t, callMethod(t, rpc, "Add", []any{"pen"}).Error)
+}
@@ -0,0 +1,144 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	//Compression is a content coding of messages, eg. zstd or brotli from the compression/zstd and compression/brotli
+	//modules. The package does not depend on a compression library so applications pick the implementation and its version
+	Compression interface {
+		//Writer compressing what is written to w. Close flushes the end of the compressed data
+		NewWriter(w io.Writer) (io.WriteCloser, error)
+
+		//Reader decompressing what is read from r
+		NewReader(r io.Reader) (io.ReadCloser, error)
+	}
+
+	//Compression offered under a content coding name
+	namedCompression struct {
+		name        string
+		compression Compression
+	}
+
+	//Compress/gzip as a Compression, always offered
+	gzipCompression struct{}
+)
+
+// WithCompression offers the compression under the content coding name, eg. COMPRESSION_ZSTD or COMPRESSION_BROTLI,
+// to the clients of WithCodecNegotiation, along with gzip. HTTP responses are compressed with the coding of the
+// Accept-Encoding header with the highest weight. On ties the compressions are preferred in the order they were
+// added, then gzip.
+func WithCompression(name string, compression Compression) Option {
+	return func(c *config) {
+		compressions := make([]namedCompression, 0, len(c.compressions)+1)
+		for _, existing := range c.compressions {
+			if !strings.EqualFold(existing.name, name) {
+				compressions = append(compressions, existing)
+			}
+		}
+		c.compressions = append(compressions, namedCompression{name: strings.ToLower(name), compression: compression})
+	}
+}
+
+// Compression of the content coding. Nil for the empty coding, which leaves messages uncompressed
+func (c *config) compression(name string) (Compression, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	for _, offered := range c.offeredCompressions() {
+		if strings.EqualFold(offered.name, name) {
+			return offered.compression, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("Compression %s is not supported", name))
+}
+
+// Compressions of the server in the order they are preferred
+func (c *config) offeredCompressions() []namedCompression {
+	return append(c.compressions[:len(c.compressions):len(c.compressions)], namedCompression{name: COMPRESSION_GZIP, compression: gzipCompression{}})
+}
+
+// Content coding of the response allowed by the Accept-Encoding header. Empty when the response is not compressed
+func (c *config) acceptedCompression(header string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, encoding := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		weight := 1.0
+		//q=0 refuses the encoding
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if weight, err = strconv.ParseFloat(q, 64); err != nil {
+				weight = 0
+			}
+		}
+
+		if name == "*" {
+			wildcard = weight
+		} else {
+			weights[name] = weight
+		}
+	}
+
+	accepted, best := "", 0.0
+	for _, offered := range c.offeredCompressions() {
+		weight, ok := weights[offered.name]
+		if !ok {
+			weight = wildcard
+		}
+		if weight > best {
+			accepted, best = offered.name, weight
+		}
+	}
+
+	return accepted
+}
+
+func compress(compression Compression, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := compression.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress at most MAX_FRAME_SIZE bytes, so small messages can not expand without bounds
+func decompress(compression Compression, data []byte) ([]byte, error) {
+	r, err := compression.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(io.LimitReader(r, MAX_FRAME_SIZE))
+}
+
+func (gzipCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
@@ -0,0 +1,31 @@
+// Package brotli offers Brotli compression to jsonrpc2 servers and clients with github.com/andybalholm/brotli.
+// It is a module of its own, so applications not using it do not depend on the compression library.
+package brotli
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/developertom01/jsonrpc2"
+)
+
+// Compression is Brotli as a jsonrpc2.Compression. The zero value compresses with brotli.DefaultCompression
+type Compression struct {
+	Quality int //From 1 to brotli.BestCompression. Zero defaults to brotli.DefaultCompression
+}
+
+// WithCompression offers Brotli to the clients of a server with codec negotiation
+func WithCompression() jsonrpc2.Option {
+	return jsonrpc2.WithCompression(jsonrpc2.COMPRESSION_BROTLI, Compression{})
+}
+
+func (c Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.Quality == 0 {
+		return brotli.NewWriter(w), nil
+	}
+	return brotli.NewWriterLevel(w, c.Quality), nil
+}
+
+func (Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
@@ -0,0 +1,17 @@
+module github.com/developertom01/jsonrpc2/compression/zstd
+
+go 1.20
+
+replace github.com/developertom01/jsonrpc2 => ../..
+
+require (
+	github.com/developertom01/jsonrpc2 v0.0.0-00010101000000-000000000000
+	github.com/klauspost/compress v1.17.9
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
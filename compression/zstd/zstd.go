@@ -0,0 +1,35 @@
+// Package zstd offers Zstandard compression to jsonrpc2 servers and clients with github.com/klauspost/compress/zstd.
+// It is a module of its own, so applications not using it do not depend on the compression library.
+package zstd
+
+import (
+	"io"
+
+	"github.com/developertom01/jsonrpc2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is Zstandard as a jsonrpc2.Compression. The zero value compresses with zstd.SpeedDefault
+type Compression struct {
+	Level zstd.EncoderLevel //Defaults to zstd.SpeedDefault
+}
+
+// WithCompression offers Zstandard to the clients of a server with codec negotiation
+func WithCompression() jsonrpc2.Option {
+	return jsonrpc2.WithCompression(jsonrpc2.COMPRESSION_ZSTD, Compression{})
+}
+
+func (c Compression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.Level == 0 {
+		return zstd.NewWriter(w)
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(c.Level))
+}
+
+func (Compression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
@@ -0,0 +1,77 @@
+package zstd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/developertom01/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+)
+
+type arith struct{}
+
+func (arith) Add(ctx context.Context, a int, b int) (int, error, *jsonrpc2.RpcErrorCode) {
+	return a + b, nil, nil
+}
+
+func compress(t *testing.T, c Compression, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	w, err := c.NewWriter(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	assert.Nil(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func decompress(t *testing.T, c Compression, data []byte) []byte {
+	r, err := c.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	decoded, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	return decoded
+}
+
+func TestRoundTrip(t *testing.T) {
+	msg := []byte(strings.Repeat(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`, 20))
+
+	compressed := compress(t, Compression{}, msg)
+	assert.Less(t, len(compressed), len(msg))
+	assert.Equal(t, msg, decompress(t, Compression{}, compressed))
+}
+
+func TestAcceptEncoding(t *testing.T) {
+	rpc := jsonrpc2.NewJsonRpc(jsonrpc2.WithCodecNegotiation(nil), WithCompression())
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	body := compress(t, Compression{}, []byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`))
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Content-Encoding", jsonrpc2.COMPRESSION_ZSTD)
+	r.Header.Set("Accept-Encoding", "gzip, "+jsonrpc2.COMPRESSION_ZSTD)
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+
+	//Zstandard is preferred over gzip on ties
+	assert.Equal(t, jsonrpc2.COMPRESSION_ZSTD, w.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":3}`, string(decompress(t, Compression{}, w.Body.Bytes())))
+
+	//Clients preferring gzip get it
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept-Encoding", "gzip, "+jsonrpc2.COMPRESSION_ZSTD+";q=0.5")
+	w = httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+	assert.Equal(t, jsonrpc2.COMPRESSION_GZIP, w.Header().Get("Content-Encoding"))
+}
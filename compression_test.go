@@ -0,0 +1,93 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Compress/flate standing for a third party compression
+type flateCompression struct{}
+
+func (flateCompression) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.BestSpeed)
+}
+
+func (flateCompression) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+func newTestCompressionRpc() JsonRPC {
+	rpc := NewJsonRpc(WithCodecNegotiation(nil), WithCompression("deflate", flateCompression{}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	return rpc
+}
+
+func TestAcceptedCompression(t *testing.T) {
+	cfg := newConfig([]Option{WithCompression("deflate", flateCompression{})})
+
+	assert.Equal(t, COMPRESSION_GZIP, cfg.acceptedCompression("gzip"))
+	assert.Equal(t, COMPRESSION_GZIP, cfg.acceptedCompression("deflate;q=0.4, GZIP;q=0.5"))
+	assert.Equal(t, "", cfg.acceptedCompression("gzip;q=0"))
+	assert.Equal(t, "", cfg.acceptedCompression("br"))
+	//Ties go to the compressions in the order they were added
+	assert.Equal(t, "deflate", cfg.acceptedCompression("gzip, deflate"))
+	assert.Equal(t, "deflate", cfg.acceptedCompression("*"))
+	assert.Equal(t, COMPRESSION_GZIP, cfg.acceptedCompression("deflate;q=0, *"))
+}
+
+func TestHTTPCustomCompression(t *testing.T) {
+	rpc := newTestCompressionRpc()
+
+	body, _ := compress(flateCompression{}, []byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`))
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Encoding", "deflate")
+	r.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	decoded, err := decompress(flateCompression{}, w.Body.Bytes())
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":3}`, string(decoded))
+}
+
+func TestHTTPUnsupportedCompression(t *testing.T) {
+	rpc := newTestCompressionRpc()
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("(compressed)")))
+	r.Header.Set("Content-Encoding", COMPRESSION_ZSTD)
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+
+	res := decodeTestResponse(t, w.Body.String())
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, "Compression zstd is not supported", res.Error.Message)
+}
+
+func TestNegotiateCompressedCodec(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	go newTestCompressionRpc().ServeConn(serverConn)
+	defer clientConn.Close()
+
+	_, err := NegotiateCodec(context.Background(), clientConn, Negotiation{Compression: "deflate"}, nil)
+	assert.Equal(t, "No compression given for deflate", err.Error())
+
+	conn, err := NegotiateCompressedCodec(context.Background(), clientConn, Negotiation{Compression: "deflate"}, nil, flateCompression{})
+	assert.Nil(t, err)
+
+	client := NewConnClient(conn)
+	defer client.Close()
+	var sum int
+	assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, 3, sum)
+}
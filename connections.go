@@ -0,0 +1,171 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Reason of the closing notification sent to the clients of kicked connections
+const CLOSING_REASON_KICK = "kicked"
+
+type (
+	//ConnectionInfo describes a persistent connection being served, as returned by Connections and rpc.connections
+	ConnectionInfo struct {
+		Id            string        `json:"id"`        //Id to kick the connection with. Unique for the life of the server
+		Transport     string        `json:"transport"` //tls for TLS connections, otherwise the network of the listener, eg. tcp or unix
+		RemoteAddr    string        `json:"remoteAddr"`
+		ConnectedAt   time.Time     `json:"connectedAt"`
+		Uptime        time.Duration `json:"uptime"`
+		PendingCalls  int           `json:"pendingCalls"` //Calls with an id the server has not answered yet
+		Subscriptions int           `json:"subscriptions"`
+		Draining      bool          `json:"draining"` //The client was told to reconnect elsewhere
+	}
+
+	//ListenerStats counts the persistent connections of a transport
+	ListenerStats struct {
+		Transport string `json:"transport"`
+		Active    int    `json:"active"`   //Connections being served
+		Accepted  uint64 `json:"accepted"` //Connections served since the server was created, including active ones
+		Kicked    uint64 `json:"kicked"`   //Connections closed by Kick
+	}
+
+	//Counters of the connections of a transport
+	listenerCounters struct {
+		accepted uint64
+		kicked   uint64
+	}
+)
+
+// Connections returns the persistent connections being served, oldest first
+func (rpc *jsonRpcImpl) Connections() []ConnectionInfo {
+	rpc.conns.mu.Lock()
+	conns := make([]*servedConn, 0, len(rpc.conns.conns))
+	for conn := range rpc.conns.conns {
+		conns = append(conns, conn)
+	}
+	rpc.conns.mu.Unlock()
+
+	now := time.Now()
+	infos := make([]ConnectionInfo, 0, len(conns))
+	for _, conn := range conns {
+		infos = append(infos, conn.info(now))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ConnectedAt.Before(infos[j].ConnectedAt)
+	})
+
+	return infos
+}
+
+// ListenerStats returns the connection counts of every transport that served a connection, by transport name
+func (rpc *jsonRpcImpl) ListenerStats() []ListenerStats {
+	rpc.conns.mu.Lock()
+	defer rpc.conns.mu.Unlock()
+
+	stats := make([]ListenerStats, 0, len(rpc.conns.listeners))
+	for transport, counters := range rpc.conns.listeners {
+		stats = append(stats, ListenerStats{Transport: transport, Accepted: counters.accepted, Kicked: counters.kicked})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Transport < stats[j].Transport
+	})
+	for conn := range rpc.conns.conns {
+		for i := range stats {
+			if stats[i].Transport == conn.transport {
+				stats[i].Active++
+			}
+		}
+	}
+
+	return stats
+}
+
+// Kick closes the connection without a grace period, eg. to get rid of a misbehaving client. Its calls in flight are
+// cancelled and its client is sent a rpc.closing notification before the connection is closed
+func (rpc *jsonRpcImpl) Kick(connId string) error {
+	rpc.conns.mu.Lock()
+	var kicked *servedConn
+	for conn := range rpc.conns.conns {
+		if conn.id == connId {
+			kicked = conn
+			rpc.conns.listeners[conn.transport].kicked++
+			break
+		}
+	}
+	rpc.conns.mu.Unlock()
+
+	if kicked == nil {
+		return errors.New(fmt.Sprintf("Connection %s not found", connId))
+	}
+
+	kicked.kick()
+	return nil
+}
+
+// Name of the transport of the connection
+func transportName(conn net.Conn) string {
+	if _, ok := conn.(*tls.Conn); ok {
+		return "tls"
+	}
+
+	return conn.LocalAddr().Network()
+}
+
+// Give the connection its id and count it with its transport. Called with the lock of the connections held
+func (s *servedConns) identify(conn *servedConn) {
+	s.lastId++
+	conn.id = strconv.FormatUint(s.lastId, 10)
+
+	counters, ok := s.listeners[conn.transport]
+	if !ok {
+		counters = &listenerCounters{}
+		s.listeners[conn.transport] = counters
+	}
+	counters.accepted++
+}
+
+func (c *servedConn) info(now time.Time) ConnectionInfo {
+	c.mu.Lock()
+	draining := c.closing
+	c.mu.Unlock()
+
+	return ConnectionInfo{
+		Id:            c.id,
+		Transport:     c.transport,
+		RemoteAddr:    c.remoteAddr,
+		ConnectedAt:   c.connectedAt,
+		Uptime:        now.Sub(c.connectedAt),
+		PendingCalls:  c.inFlight.count(),
+		Subscriptions: c.subs.count(),
+		Draining:      draining,
+	}
+}
+
+// Notify the client and close the connection once the notification is written, without a grace period, even while
+// it is drained
+func (c *servedConn) kick() {
+	c.mu.Lock()
+	if c.released {
+		c.mu.Unlock()
+		return
+	}
+	c.closing = true
+	c.mu.Unlock()
+
+	c.refuse()
+	c.notify(closingNotification(CLOSING_REASON_KICK, 0))
+	c.stopReading()
+}
+
+func (rpc *jsonRpcImpl) registerConnectionsMethod(builtins *service) {
+	builtins.methods["connections"] = reflect.ValueOf(func(ctx context.Context) ([]ConnectionInfo, error, *RpcErrorCode) {
+		return rpc.Connections(), nil, nil
+	})
+}
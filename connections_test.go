@@ -0,0 +1,92 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnections(t *testing.T) {
+	srv := blockingService{started: make(chan struct{}, 1)}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(srv, "Blocking")
+
+	clientConn, serverConn := net.Pipe()
+	go rpc.ServeConn(serverConn)
+	defer clientConn.Close()
+	reader := bufio.NewReader(clientConn)
+
+	clientConn.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"rpc.subscribe","params":[]}` + "\n"))
+	readTestMessage(t, clientConn, reader)
+	go clientConn.Write([]byte(`{"jsonrpc":"2.0","id":"2","method":"Blocking.Block","params":[]}` + "\n"))
+	<-srv.started
+
+	conns := rpc.Connections()
+	assert.Len(t, conns, 1)
+	assert.Equal(t, "1", conns[0].Id)
+	assert.Equal(t, "pipe", conns[0].Transport)
+	assert.Equal(t, 1, conns[0].PendingCalls)
+	assert.Equal(t, 1, conns[0].Subscriptions)
+	assert.False(t, conns[0].Draining)
+	assert.True(t, conns[0].Uptime > 0)
+
+	res := callMethod(t, rpc, "rpc.connections", nil)
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "1", (*res.Result).([]any)[0].(map[string]any)["id"])
+
+	assert.EqualError(t, rpc.Kick("42"), "Connection 42 not found")
+	assert.Nil(t, rpc.Kick("1"))
+
+	//The call in flight is cancelled and the client is told why before the connection closes
+	var closing map[string]any
+	for closing == nil {
+		msg := readTestMessage(t, clientConn, reader)
+		if msg["method"] == CLOSING_METHOD {
+			closing = msg
+		}
+	}
+	assert.Equal(t, map[string]any{"reason": CLOSING_REASON_KICK, "gracePeriodMs": float64(0)}, closing["params"])
+
+	clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := io.ReadAll(reader)
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool { return len(rpc.Connections()) == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, []ListenerStats{{Transport: "pipe", Active: 0, Accepted: 1, Kicked: 1}}, rpc.ListenerStats())
+}
+
+func TestListenerStats(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	defer l.Close()
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+	}
+
+	assert.Eventually(t, func() bool { return len(rpc.Connections()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []ListenerStats{{Transport: "tcp", Active: 2, Accepted: 2}}, rpc.ListenerStats())
+
+	conns := rpc.Connections()
+	assert.NotEqual(t, conns[0].Id, conns[1].Id)
+	assert.False(t, conns[1].ConnectedAt.Before(conns[0].ConnectedAt))
+}
+
+func TestConnectionsDisabledIntrospection(t *testing.T) {
+	res := callMethod(t, NewJsonRpc(DisableIntrospection()), "rpc.connections", nil)
+
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+}
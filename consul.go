@@ -0,0 +1,122 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	//ConsulOptions configure the agent a ConsulBackend registers endpoints with
+	ConsulOptions struct {
+		Address string //URL of the agent. Defaults to http://127.0.0.1:8500
+		Token   string //ACL token, sent as X-Consul-Token when set
+
+		//How long the agent keeps an endpoint passing without a heartbeat. Defaults to 15s
+		TTL time.Duration
+
+		//How long a critical endpoint stays registered before the agent removes it. Defaults to 1m
+		DeregisterAfter time.Duration
+
+		Client *http.Client //Client of the agent API. Defaults to http.DefaultClient
+	}
+
+	//ConsulBackend registers endpoints as services of the local Consul agent, with a TTL health check kept passing by
+	//the heartbeats. The transport and methods of the endpoint are tags of the service, methods as method=<name>
+	ConsulBackend struct {
+		opts ConsulOptions
+	}
+
+	consulService struct {
+		ID      string            `json:"ID"`
+		Name    string            `json:"Name"`
+		Address string            `json:"Address"`
+		Port    int               `json:"Port,omitempty"`
+		Tags    []string          `json:"Tags"`
+		Meta    map[string]string `json:"Meta,omitempty"`
+		Check   consulCheck       `json:"Check"`
+	}
+
+	consulCheck struct {
+		CheckID                        string `json:"CheckID"`
+		TTL                            string `json:"TTL"`
+		DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter"`
+	}
+)
+
+// NewConsulBackend returns a backend registering endpoints with the Consul agent of the options
+func NewConsulBackend(opts ConsulOptions) *ConsulBackend {
+	if opts.Address == "" {
+		opts.Address = "http://127.0.0.1:8500"
+	}
+	opts.Address = strings.TrimSuffix(opts.Address, "/")
+	if opts.TTL <= 0 {
+		opts.TTL = 15 * time.Second
+	}
+	if opts.DeregisterAfter <= 0 {
+		opts.DeregisterAfter = time.Minute
+	}
+
+	return &ConsulBackend{opts: opts}
+}
+
+// Register the endpoint as a service whose check passes when the endpoint is healthy
+func (b *ConsulBackend) Register(ctx context.Context, endpoint Endpoint) error {
+	service := consulService{
+		ID:      endpoint.ID,
+		Name:    endpoint.Name,
+		Address: endpoint.Address,
+		Tags:    []string{endpoint.Transport},
+		Meta:    endpoint.Meta,
+		Check: consulCheck{
+			CheckID:                        b.checkID(endpoint),
+			TTL:                            b.opts.TTL.String(),
+			DeregisterCriticalServiceAfter: b.opts.DeregisterAfter.String(),
+		},
+	}
+	if host, port, err := net.SplitHostPort(endpoint.Address); err == nil {
+		service.Address = host
+		service.Port, _ = strconv.Atoi(port)
+	}
+	for _, method := range endpoint.Methods {
+		service.Tags = append(service.Tags, "method="+method)
+	}
+
+	if err := b.request(ctx, "/v1/agent/service/register", service); err != nil {
+		return err
+	}
+
+	return b.Heartbeat(ctx, endpoint)
+}
+
+// Heartbeat marks the check of the endpoint passing, or critical once it is unhealthy
+func (b *ConsulBackend) Heartbeat(ctx context.Context, endpoint Endpoint) error {
+	status := "passing"
+	if !endpoint.Healthy {
+		status = "critical"
+	}
+
+	return b.request(ctx, "/v1/agent/check/update/"+url.PathEscape(b.checkID(endpoint)), map[string]string{"Status": status})
+}
+
+// Deregister removes the service of the endpoint
+func (b *ConsulBackend) Deregister(ctx context.Context, endpoint Endpoint) error {
+	return b.request(ctx, "/v1/agent/service/deregister/"+url.PathEscape(endpoint.ID), nil)
+}
+
+func (b *ConsulBackend) checkID(endpoint Endpoint) string {
+	return "service:" + endpoint.ID
+}
+
+func (b *ConsulBackend) request(ctx context.Context, path string, body any) error {
+	header := http.Header{}
+	if b.opts.Token != "" {
+		header.Set("X-Consul-Token", b.opts.Token)
+	}
+
+	return discoveryRequest(ctx, b.opts.Client, http.MethodPut, b.opts.Address+path, header, body, nil)
+}
@@ -0,0 +1,53 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net/http"
+)
+
+// ContextHook returns the context passed to the handler of a request, eg. with the tenant or principal of the caller.
+// The HTTP request is nil for requests received over other transports, eg. raw sockets.
+type ContextHook func(ctx context.Context, r *http.Request, req Request) context.Context
+
+type (
+	remoteAddrKey  struct{}
+	httpRequestKey struct{}
+)
+
+func withRemoteAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, remoteAddrKey{}, addr)
+}
+
+// RemoteAddrFromContext returns the network address of the client that sent the request being handled
+func RemoteAddrFromContext(ctx context.Context) string {
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	return addr
+}
+
+// WithContextHook calls hook for every request, including each entry of a batch, before it is handled.
+// The returned context is seen by middleware, interceptors, the audit trail and the handler.
+func WithContextHook(hook ContextHook) Option {
+	return func(c *config) {
+		c.contextHook = hook
+	}
+}
+
+func withHTTPRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, httpRequestKey{}, r)
+}
+
+// HTTPRequestFromContext returns the HTTP request carrying the request being handled, eg. to read its headers in
+// a middleware. Nil is returned for requests received over other transports
+func HTTPRequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(httpRequestKey{}).(*http.Request)
+	return r
+}
+
+// Apply the context hook of the server, if any, for the request
+func (c *config) requestContext(ctx context.Context, req Request) context.Context {
+	if c.contextHook == nil {
+		return ctx
+	}
+
+	return c.contextHook(ctx, HTTPRequestFromContext(ctx), req)
+}
@@ -0,0 +1,67 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tenantHook(ctx context.Context, r *http.Request, req Request) context.Context {
+	tenant := "none"
+	if r != nil {
+		tenant = r.Header.Get("X-Tenant")
+	}
+
+	return context.WithValue(ctx, ctxKey{}, tenant+":"+req.Method)
+}
+
+func TestContextHook(t *testing.T) {
+	rpc := NewJsonRpc(WithContextHook(tenantHook))
+	rpc.RegisterWithName(contextService{}, "Ctx")
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[
+		{"jsonrpc":"2.0","id":"1","method":"Ctx.Value"},
+		{"jsonrpc":"2.0","id":"2","method":"Ctx.Value"}
+	]`))
+	r.Header.Set("X-Tenant", "acme")
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, r)
+
+	var responses []Response
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &responses))
+	assert.Len(t, responses, 2)
+	for _, res := range responses {
+		assert.Equal(t, any("acme:Ctx.Value"), *res.Result)
+	}
+}
+
+func TestContextHookWithoutHTTPRequest(t *testing.T) {
+	rpc := NewJsonRpc(WithContextHook(tenantHook))
+	rpc.RegisterWithName(contextService{}, "Ctx")
+
+	body := rpc.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":"1","method":"Ctx.Value"}`))
+
+	assert.Equal(t, any("none:Ctx.Value"), *decodeTestResponse(t, string(body)).Result)
+}
+
+func TestContextHookSeenByAudit(t *testing.T) {
+	sink := &memoryAuditSink{}
+	rpc := NewJsonRpc(
+		WithContextHook(tenantHook),
+		WithAudit(sink),
+		WithAuditIdentity(func(ctx context.Context) string {
+			value, _ := ctx.Value(ctxKey{}).(string)
+			return value
+		}),
+	)
+	rpc.RegisterWithName(contextService{}, "Ctx")
+
+	serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Ctx.Value"}`)
+
+	assert.Equal(t, ":Ctx.Value", sink.records()[0].Caller)
+}
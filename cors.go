@@ -0,0 +1,91 @@
+package jsonrpc2
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Methods the RPC endpoint accepts from browsers
+const corsAllowedMethods = "POST, OPTIONS"
+
+type corsConfig struct {
+	origins   map[string]bool
+	anyOrigin bool
+	headers   string
+	maxAge    time.Duration
+}
+
+// WithCORS enables Cross-Origin Resource Sharing on the RPC endpoint so browser based apps can call the server.
+// origins is the list of allowed origins. Use "*" to allow every origin.
+// headers lists request headers browsers may send in addition to Content-Type.
+// maxAge is how long browsers may cache a preflight response. A zero maxAge omits the header.
+func WithCORS(origins []string, headers []string, maxAge time.Duration) Option {
+	return func(c *config) {
+		cors := &corsConfig{
+			origins: make(map[string]bool, len(origins)),
+			maxAge:  maxAge,
+		}
+
+		for _, origin := range origins {
+			if origin == "*" {
+				cors.anyOrigin = true
+				continue
+			}
+			cors.origins[origin] = true
+		}
+
+		allowedHeaders := []string{"Content-Type"}
+		for _, h := range headers {
+			if !strings.EqualFold(h, "Content-Type") {
+				allowedHeaders = append(allowedHeaders, http.CanonicalHeaderKey(h))
+			}
+		}
+		cors.headers = strings.Join(allowedHeaders, ", ")
+
+		c.cors = cors
+	}
+}
+
+func (c *corsConfig) isAllowedOrigin(origin string) bool {
+	return c.anyOrigin || c.origins[origin]
+}
+
+// handleCORS writes CORS headers for the request. It returns true when the request was a preflight request
+// and has been fully answered.
+func (c *corsConfig) handleCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	w.Header().Add("Vary", "Origin")
+
+	if origin == "" || !c.isAllowedOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+		return false
+	}
+
+	if c.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if !preflight {
+		return false
+	}
+
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+	w.Header().Set("Access-Control-Allow-Headers", c.headers)
+	if c.maxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
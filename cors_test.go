@@ -0,0 +1,57 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	rpc := NewJsonRpc(WithCORS([]string{"https://app.example.com"}, []string{"authorization"}, 10*time.Minute))
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	recorder := httptest.NewRecorder()
+
+	rpc.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "POST, OPTIONS", recorder.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", recorder.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORSPreflightDisallowedOrigin(t *testing.T) {
+	rpc := NewJsonRpc(WithCORS([]string{"https://app.example.com"}, nil, 0))
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	recorder := httptest.NewRecorder()
+
+	rpc.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	rpc := NewJsonRpc(WithCORS([]string{"*"}, nil, 0))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	body := []byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBuffer(body))
+	r.Header.Set("Origin", "https://app.example.com")
+	recorder := httptest.NewRecorder()
+
+	rpc.ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+}
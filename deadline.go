@@ -0,0 +1,46 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header carrying the time a caller is willing to wait for a response, eg. 250ms or 2s
+const TIMEOUT_HEADER = "X-RPC-Timeout"
+
+// Set the deadline of the context from the timeout header of the request, if any
+func withTimeoutHeader(ctx context.Context, header http.Header) (context.Context, context.CancelFunc, error) {
+	value := header.Get(TIMEOUT_HEADER)
+	if value == "" {
+		return ctx, func() {}, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return ctx, func() {}, errors.New(fmt.Sprintf("Invalid %s header %q", TIMEOUT_HEADER, value))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// Format the time left until the deadline of the context for the timeout header.
+// False is returned when the context has no deadline
+func timeoutHeader(ctx context.Context) (string, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return "", false
+	}
+
+	//Round up so a deadline that is about to expire is not sent as no time at all
+	ms := (time.Until(deadline) + time.Millisecond - 1) / time.Millisecond
+	if ms < 1 {
+		ms = 1
+	}
+
+	return strconv.FormatInt(int64(ms), 10) + "ms", true
+}
@@ -0,0 +1,74 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type deadlineService struct{}
+
+func (deadlineService) Remaining(ctx context.Context) (bool, error, *RpcErrorCode) {
+	_, ok := ctx.Deadline()
+	return ok, nil, nil
+}
+
+func TestTimeoutHeaderSetsDeadline(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(deadlineService{}, "Deadline")
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":"1","method":"Deadline.Remaining","params":[]}`))
+	r.Header.Set(TIMEOUT_HEADER, "2s")
+	rpc.ServeHTTP(recorder, r)
+
+	assert.Contains(t, recorder.Body.String(), `"result":true`)
+}
+
+func TestTimeoutHeaderExpires(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(users{}, "Users")
+	rpc.MapError(context.DeadlineExceeded, SERVER_BUSY, "Timed out")
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":"1","method":"Users.Slow","params":[]}`))
+	r.Header.Set(TIMEOUT_HEADER, "10ms")
+	rpc.ServeHTTP(recorder, r)
+
+	assert.Contains(t, recorder.Body.String(), `"code":32001`)
+}
+
+func TestInvalidTimeoutHeader(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(deadlineService{}, "Deadline")
+
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":"1","method":"Deadline.Remaining","params":[]}`))
+	r.Header.Set(TIMEOUT_HEADER, "soon")
+	rpc.ServeHTTP(recorder, r)
+
+	assert.Contains(t, recorder.Body.String(), `"code":32600`)
+}
+
+func TestClientSendsTimeoutHeader(t *testing.T) {
+	var header string
+	url := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(TIMEOUT_HEADER)
+		newTestArithRpc().ServeHTTP(w, r)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var sum int
+	assert.NoError(t, NewHTTPClient(url).Call(ctx, "Arith.Add", []any{1, 2}, &sum))
+
+	timeout, err := time.ParseDuration(header)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Minute, timeout, float64(time.Second))
+}
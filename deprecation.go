@@ -0,0 +1,63 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// HTTP header warning about calls of deprecated methods. It is repeated for every deprecated method of a batch
+const DEPRECATION_HEADER = "X-RPC-Deprecation"
+
+type (
+	//Warnings about deprecated methods called by a request
+	deprecationWarnings struct {
+		mu       sync.Mutex
+		warnings []string
+	}
+
+	deprecationKey struct{}
+)
+
+func withDeprecationWarnings(ctx context.Context) (context.Context, *deprecationWarnings) {
+	warnings := &deprecationWarnings{}
+	return context.WithValue(ctx, deprecationKey{}, warnings), warnings
+}
+
+// Record a warning when the method is deprecated
+func warnDeprecated(ctx context.Context, srv *service, methodName string) {
+	doc, ok := srv.docs[methodName]
+	if !ok || !doc.Deprecated {
+		return
+	}
+
+	warnings, ok := ctx.Value(deprecationKey{}).(*deprecationWarnings)
+	if !ok {
+		return
+	}
+
+	warning := srv.fullName(methodName) + " is deprecated"
+	if doc.ReplacedBy != "" {
+		warning += ". Use " + doc.ReplacedBy + " instead"
+	}
+
+	warnings.mu.Lock()
+	defer warnings.mu.Unlock()
+
+	for _, w := range warnings.warnings {
+		if w == warning {
+			return
+		}
+	}
+	warnings.warnings = append(warnings.warnings, warning)
+}
+
+// Add the warnings to the headers of the response. Call before the response is written
+func (d *deprecationWarnings) writeHeaders(header http.Header) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, warning := range d.warnings {
+		header.Add(DEPRECATION_HEADER, warning)
+	}
+}
@@ -0,0 +1,60 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDeprecatedRpc() JsonRPC {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithOptions(arith{}, ServiceOptions{
+		Name: "Arith",
+		Docs: map[string]MethodDoc{
+			"Add":         {Deprecated: true, Since: "1.0", ReplacedBy: "Math.Sum"},
+			"ErrorMethod": {Since: "1.2"},
+		},
+	})
+
+	return rpc
+}
+
+func TestDeprecationHeader(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`[
+		{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]},
+		{"jsonrpc":"2.0","id":"2","method":"Arith.Add","params":[3,4]},
+		{"jsonrpc":"2.0","id":"3","method":"Arith.ErrorMethod","params":[]}
+	]`))
+	newTestDeprecatedRpc().ServeHTTP(recorder, r)
+
+	assert.Equal(t, []string{"Arith.Add is deprecated. Use Math.Sum instead"}, recorder.Header().Values(DEPRECATION_HEADER))
+	assert.Contains(t, recorder.Body.String(), `"result":3`)
+}
+
+func TestNoDeprecationHeader(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"jsonrpc":"2.0","id":"1","method":"Arith.ErrorMethod","params":[]}`))
+	newTestDeprecatedRpc().ServeHTTP(recorder, r)
+
+	assert.Empty(t, recorder.Header().Values(DEPRECATION_HEADER))
+}
+
+func TestDescribeDeprecation(t *testing.T) {
+	body := serveTestBody(newTestDeprecatedRpc(), `{"jsonrpc":"2.0","id":"1","method":"rpc.describe","params":["Arith"]}`)
+
+	var res struct {
+		Result []ServiceDescription `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &res))
+
+	add := res.Result[0].Methods[0]
+	assert.True(t, add.Deprecated)
+	assert.Equal(t, "1.0", add.Since)
+	assert.Equal(t, "Math.Sum", add.ReplacedBy)
+	assert.False(t, res.Result[0].Methods[1].Deprecated)
+}
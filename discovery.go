@@ -0,0 +1,186 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Interval of the heartbeats sent to the discovery backend when WithDiscovery sets none
+const DISCOVERY_HEARTBEAT_INTERVAL = 5 * time.Second
+
+type (
+	//DiscoveryBackend publishes the endpoints of servers so clients find them, eg. ConsulBackend or EtcdBackend
+	DiscoveryBackend interface {
+		//Publish the endpoint
+		Register(ctx context.Context, endpoint Endpoint) error
+
+		//Renew the endpoint and update its health. Called periodically until it is deregistered
+		Heartbeat(ctx context.Context, endpoint Endpoint) error
+
+		//Remove the endpoint
+		Deregister(ctx context.Context, endpoint Endpoint) error
+	}
+
+	//Endpoint of a server as published for discovery
+	Endpoint struct {
+		ID        string            `json:"id"`        //Unique per server. Defaults to <name>-<address>
+		Name      string            `json:"name"`      //Name clients look the servers up by
+		Address   string            `json:"address"`   //Host and port clients connect to
+		Transport string            `json:"transport"` //How clients connect: http, tcp or tls. Defaults to http
+		Methods   []string          `json:"methods"`   //Methods the server exposes. Filled by the server
+		Healthy   bool              `json:"healthy"`   //False once the server drains. Set by the server
+		Meta      map[string]string `json:"meta,omitempty"`
+	}
+
+	discoveryConfig struct {
+		backend  DiscoveryBackend
+		endpoint Endpoint
+		interval time.Duration
+	}
+
+	//Endpoint published while the server is started
+	announcement struct {
+		backend  DiscoveryBackend
+		endpoint Endpoint
+		stop     chan struct{}
+		done     chan struct{}
+	}
+)
+
+// WithDiscovery publishes the endpoint of the server to the backend when the server starts, with the methods it
+// exposes, and removes it when the server shuts down. Heartbeats are sent every interval, DISCOVERY_HEARTBEAT_INTERVAL
+// when zero, and report the server as unhealthy once it drains. The backend must keep endpoints alive longer than
+// the interval. A server whose endpoint can not be registered does not start.
+func WithDiscovery(backend DiscoveryBackend, endpoint Endpoint, interval time.Duration) Option {
+	if endpoint.ID == "" {
+		endpoint.ID = endpoint.Name + "-" + endpoint.Address
+	}
+	if endpoint.Transport == "" {
+		endpoint.Transport = "http"
+	}
+	if interval <= 0 {
+		interval = DISCOVERY_HEARTBEAT_INTERVAL
+	}
+
+	return func(c *config) {
+		c.discovery = &discoveryConfig{backend: backend, endpoint: endpoint, interval: interval}
+	}
+}
+
+// Register the endpoint and keep it alive until it is withdrawn
+func (rpc *jsonRpcImpl) announce(ctx context.Context, discovery *discoveryConfig) (*announcement, error) {
+	endpoint := discovery.endpoint
+	endpoint.Methods = rpc.exposedMethods()
+	endpoint.Healthy = !rpc.conns.isDraining()
+
+	if err := discovery.backend.Register(ctx, endpoint); err != nil {
+		return nil, errors.New(fmt.Sprintf("Registering endpoint %s failed: %v", endpoint.ID, err))
+	}
+
+	a := &announcement{
+		backend:  discovery.backend,
+		endpoint: endpoint,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(a.done)
+
+		ticker := time.NewTicker(discovery.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-a.stop:
+				return
+			}
+
+			endpoint.Healthy = !rpc.conns.isDraining()
+			if err := a.backend.Heartbeat(context.Background(), endpoint); err != nil {
+				rpc.cfg().logger.Printf("Heartbeat of endpoint %s failed: %v", endpoint.ID, err)
+			}
+		}
+	}()
+
+	return a, nil
+}
+
+// Stop the heartbeats and deregister the endpoint
+func (a *announcement) withdraw(ctx context.Context) error {
+	close(a.stop)
+	<-a.done
+
+	if err := a.backend.Deregister(ctx, a.endpoint); err != nil {
+		return errors.New(fmt.Sprintf("Deregistering endpoint %s failed: %v", a.endpoint.ID, err))
+	}
+
+	return nil
+}
+
+// Full names of the registered methods clients can call
+func (rpc *jsonRpcImpl) exposedMethods() []string {
+	cfg := rpc.cfg()
+	methods := make([]string, 0)
+	for _, srv := range rpc.registeredServices() {
+		for name := range srv.methods {
+			if method := srv.fullName(name); cfg.exposes(method) {
+				methods = append(methods, method)
+			}
+		}
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// Send a JSON request to the HTTP API of a discovery backend and decode its JSON answer into out, unless nil
+func discoveryRequest(ctx context.Context, client *http.Client, method, url string, header http.Header, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode/100 != 2 {
+		return errors.New(fmt.Sprintf("Unexpected HTTP status %s: %s", res.Status, bytes.TrimSpace(data)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
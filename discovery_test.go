@@ -0,0 +1,199 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Discovery backend recording the calls it receives
+type announcer struct {
+	mu        sync.Mutex
+	calls     []string
+	endpoints []Endpoint
+	failing   bool
+}
+
+func (a *announcer) record(call string, endpoint Endpoint) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.failing {
+		return errors.New("unreachable")
+	}
+	a.calls = append(a.calls, call)
+	a.endpoints = append(a.endpoints, endpoint)
+	return nil
+}
+
+func (a *announcer) Register(ctx context.Context, endpoint Endpoint) error {
+	return a.record("register", endpoint)
+}
+
+func (a *announcer) Heartbeat(ctx context.Context, endpoint Endpoint) error {
+	return a.record("heartbeat", endpoint)
+}
+
+func (a *announcer) Deregister(ctx context.Context, endpoint Endpoint) error {
+	return a.record("deregister", endpoint)
+}
+
+func (a *announcer) last() (string, Endpoint) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.calls[len(a.calls)-1], a.endpoints[len(a.endpoints)-1]
+}
+
+func TestWithDiscovery(t *testing.T) {
+	backend := &announcer{}
+	rpc := NewJsonRpc(
+		WithDiscovery(backend, Endpoint{Name: "arith", Address: "10.0.0.1:8080"}, 10*time.Millisecond),
+		WithExposedMethods("Arith.Add"),
+	)
+	assert.Nil(t, rpc.RegisterWithName(arith{}, "Arith"))
+
+	assert.Nil(t, rpc.Start(context.Background()))
+	call, endpoint := backend.last()
+	assert.Equal(t, "register", call)
+	assert.Equal(t, "arith-10.0.0.1:8080", endpoint.ID)
+	assert.Equal(t, "http", endpoint.Transport)
+	assert.Equal(t, []string{"Arith.Add"}, endpoint.Methods)
+	assert.True(t, endpoint.Healthy)
+
+	assert.Eventually(t, func() bool {
+		call, endpoint := backend.last()
+		return call == "heartbeat" && endpoint.Healthy
+	}, time.Second, 5*time.Millisecond)
+
+	rpc.Drain(0)
+	assert.Eventually(t, func() bool {
+		_, endpoint := backend.last()
+		return !endpoint.Healthy
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Nil(t, rpc.Shutdown(context.Background()))
+	call, _ = backend.last()
+	assert.Equal(t, "deregister", call)
+}
+
+func TestWithDiscoveryRegisterFails(t *testing.T) {
+	var log []string
+	backend := &announcer{failing: true}
+	rpc := NewJsonRpc(WithDiscovery(backend, Endpoint{Name: "stock", Address: "10.0.0.1:8080"}, 0))
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "a", log: &log}, "A"))
+
+	err := rpc.Start(context.Background())
+	assert.EqualError(t, err, "Registering endpoint stock-10.0.0.1:8080 failed: unreachable")
+	assert.Equal(t, []string{"start a", "stop a"}, log)
+}
+
+func TestConsulBackend(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	var service map[string]any
+	var status map[string]string
+	url := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Consul-Token"))
+		switch r.URL.Path {
+		case "/v1/agent/service/register":
+			json.NewDecoder(r.Body).Decode(&service)
+		case "/v1/agent/check/update/service:arith-1":
+			json.NewDecoder(r.Body).Decode(&status)
+		}
+	}))
+
+	backend := NewConsulBackend(ConsulOptions{Address: url, Token: "secret"})
+	endpoint := Endpoint{ID: "arith-1", Name: "arith", Address: "10.0.0.1:8080", Transport: "tcp", Methods: []string{"Arith.Add"}, Healthy: true}
+	assert.Nil(t, backend.Register(context.Background(), endpoint))
+	assert.Equal(t, "10.0.0.1", service["Address"])
+	assert.Equal(t, float64(8080), service["Port"])
+	assert.Equal(t, []any{"tcp", "method=Arith.Add"}, service["Tags"])
+	assert.Equal(t, "15s", service["Check"].(map[string]any)["TTL"])
+	assert.Equal(t, "passing", status["Status"])
+
+	endpoint.Healthy = false
+	assert.Nil(t, backend.Heartbeat(context.Background(), endpoint))
+	assert.Equal(t, "critical", status["Status"])
+
+	assert.Nil(t, backend.Deregister(context.Background(), endpoint))
+	assert.Equal(t, []string{
+		"PUT /v1/agent/service/register secret",
+		"PUT /v1/agent/check/update/service:arith-1 secret",
+		"PUT /v1/agent/check/update/service:arith-1 secret",
+		"PUT /v1/agent/service/deregister/arith-1 secret",
+	}, requests)
+}
+
+func TestConsulBackendError(t *testing.T) {
+	url := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+	}))
+
+	backend := NewConsulBackend(ConsulOptions{Address: url})
+	err := backend.Register(context.Background(), Endpoint{ID: "arith-1", Name: "arith"})
+	assert.EqualError(t, err, "Unexpected HTTP status 403 Forbidden: Permission denied")
+}
+
+func TestEtcdBackend(t *testing.T) {
+	var mu sync.Mutex
+	kv := map[string]string{}
+	ttl := "15"
+	url := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		switch r.URL.Path {
+		case "/v3/lease/grant":
+			io.WriteString(w, `{"ID":"42","TTL":"15"}`)
+		case "/v3/kv/put":
+			assert.Equal(t, "42", body["lease"])
+			key, _ := base64.StdEncoding.DecodeString(body["key"].(string))
+			value, _ := base64.StdEncoding.DecodeString(body["value"].(string))
+			kv[string(key)] = string(value)
+			io.WriteString(w, `{}`)
+		case "/v3/lease/keepalive":
+			io.WriteString(w, `{"result":{"ID":"42","TTL":"`+ttl+`"}}`)
+		case "/v3/lease/revoke":
+			assert.Equal(t, "42", body["ID"])
+			kv = map[string]string{}
+			io.WriteString(w, `{}`)
+		}
+	}))
+
+	backend := NewEtcdBackend(EtcdOptions{Address: url})
+	endpoint := Endpoint{ID: "arith-1", Name: "arith", Address: "10.0.0.1:8080", Transport: "http", Healthy: true}
+	assert.Nil(t, backend.Register(context.Background(), endpoint))
+
+	var stored Endpoint
+	assert.Nil(t, json.Unmarshal([]byte(kv["/services/arith/arith-1"]), &stored))
+	assert.Equal(t, endpoint, stored)
+
+	endpoint.Healthy = false
+	assert.Nil(t, backend.Heartbeat(context.Background(), endpoint))
+	assert.Nil(t, json.Unmarshal([]byte(kv["/services/arith/arith-1"]), &stored))
+	assert.False(t, stored.Healthy)
+
+	//An expired lease is granted again
+	ttl = "0"
+	kv = map[string]string{}
+	assert.Nil(t, backend.Heartbeat(context.Background(), endpoint))
+	assert.Contains(t, kv, "/services/arith/arith-1")
+
+	assert.Nil(t, backend.Deregister(context.Background(), endpoint))
+	assert.Empty(t, kv)
+}
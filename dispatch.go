@@ -0,0 +1,72 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+)
+
+// Dispatch handles a request without encoding it, eg. to embed the server in another transport or call it from
+// tests. It goes through the same path as HTTP requests, including middleware, interceptors and the audit trail.
+// Params may be any value that encodes to an array or an object. The version defaults to 2.0 when it is empty.
+// The response of a notification is returned as well, though transports must not send it.
+func (rpc *jsonRpcImpl) Dispatch(ctx context.Context, req Request) Response {
+	return rpc.handleSingleRequest(rpc.dispatchContext(ctx), rpc.prepareDispatch(req))
+}
+
+// DispatchBatch handles the requests as a batch, concurrently, like Dispatch. Responses are in the order of the
+// requests, including those of notifications. A batch that can not be handled, eg. an empty one or one with
+// duplicate ids rejected by WithDuplicateIDs, is answered with a single INVALID_REQUEST response.
+func (rpc *jsonRpcImpl) DispatchBatch(ctx context.Context, requests []Request) []Response {
+	cfg := rpc.cfg()
+	if len(requests) == 0 && !cfg.legacyBatchResponses {
+		return []Response{makeErrorResponse(errEmptyBatch, INVALID_REQUEST, nil, nil)}
+	}
+
+	prepared := make([]Request, len(requests))
+	for i, req := range requests {
+		prepared[i] = rpc.prepareDispatch(req)
+	}
+	if err := checkDuplicateIds(cfg.duplicateIds, prepared); err != nil {
+		return []Response{makeErrorResponse(err, INVALID_REQUEST, nil, nil)}
+	}
+
+	return rpc.handleBatchRequest(rpc.dispatchContext(ctx), prepared)
+}
+
+// Requests can be cancelled with rpc.cancel when the caller did not set up cancellation of its own
+func (rpc *jsonRpcImpl) dispatchContext(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(inFlightKey{}).(*inFlightRequests); ok {
+		return ctx
+	}
+
+	return withInFlightRequests(ctx, rpc.inFlight)
+}
+
+// Fill in the version and convert params to the types decoded from JSON. Requests whose params can not be
+// converted are invalid
+func (rpc *jsonRpcImpl) prepareDispatch(req Request) Request {
+	if req.Jsonrpc == "" {
+		req.Jsonrpc = RPC_VERSION
+	}
+
+	switch req.Params.(type) {
+	case nil, []any, map[string]any:
+		return req
+	}
+
+	//Params stay nil when they can not be encoded
+	cfg := rpc.cfg()
+	var params any
+	if data, err := cfg.marshal(req.Params, false); err == nil {
+		cfg.unmarshal(data, &params)
+	}
+
+	switch params.(type) {
+	case []any, map[string]any:
+		req.Params = params
+	default:
+		req.invalid = errors.New("Params must be an array or an object")
+	}
+
+	return req
+}
@@ -0,0 +1,57 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatch(t *testing.T) {
+	rpc := newTestArithRpc()
+	id := "1"
+
+	res := rpc.Dispatch(context.Background(), Request{Id: &id, Method: "Arith.Add", Params: []int{1, 2}})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, &id, res.Id)
+	assert.Equal(t, 3, *res.Result)
+
+	res = rpc.Dispatch(context.Background(), Request{Id: &id, Method: "Arith.Add", Params: 3})
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, "Params must be an array or an object", res.Error.Message)
+
+	res = rpc.Dispatch(context.Background(), Request{Id: &id, Jsonrpc: "1.0", Method: "Arith.Add", Params: []any{1, 2}})
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+}
+
+func TestDispatchBatch(t *testing.T) {
+	rpc := newTestArithRpc()
+	first, second := "1", "2"
+
+	responses := rpc.DispatchBatch(context.Background(), []Request{
+		{Id: &first, Method: "Arith.Add", Params: []any{1, 2}},
+		{Method: "Arith.Add", Params: []any{3, 4}},
+		{Id: &second, Method: "Arith.ErrorMethod"},
+	})
+	assert.Len(t, responses, 3)
+	assert.Equal(t, 3, *responses[0].Result)
+	assert.Nil(t, responses[1].Id)
+	assert.Equal(t, "Some error here", responses[2].Error.Message)
+
+	responses = rpc.DispatchBatch(context.Background(), nil)
+	assert.Len(t, responses, 1)
+	assert.Equal(t, INVALID_REQUEST, responses[0].Error.Code)
+}
+
+func TestDispatchDuplicateIds(t *testing.T) {
+	rpc := NewJsonRpc(WithDuplicateIDs(REJECT_DUPLICATE_IDS))
+	rpc.RegisterWithName(arith{}, "Arith")
+	id := "1"
+
+	responses := rpc.DispatchBatch(context.Background(), []Request{
+		{Id: &id, Method: "Arith.Add", Params: []any{1, 2}},
+		{Id: &id, Method: "Arith.Add", Params: []any{3, 4}},
+	})
+	assert.Len(t, responses, 1)
+	assert.Equal(t, INVALID_REQUEST, responses[0].Error.Code)
+}
@@ -0,0 +1,194 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	//Notification sent to the clients of a persistent connection before the server closes it
+	CLOSING_METHOD = "rpc.closing"
+	//Reason of the closing notifications sent by Drain
+	CLOSING_REASON_DRAIN = "draining"
+	//Reason of the closing notifications sent when a connection reaches the age of WithMaxConnectionAge
+	CLOSING_REASON_MAX_AGE = "max connection age"
+)
+
+type (
+	//Params of the rpc.closing notification
+	closingParams struct {
+		Reason      string `json:"reason"`
+		GracePeriod int64  `json:"gracePeriodMs"` //Time left before the connection is closed, in milliseconds
+	}
+
+	//Age after which connections are drained, set by WithMaxConnectionAge
+	connectionAge struct {
+		age   time.Duration
+		grace time.Duration
+	}
+
+	//Persistent connections being served, so they can be drained together
+	servedConns struct {
+		mu       sync.Mutex
+		conns    map[*servedConn]struct{}
+		draining bool
+		grace    time.Duration //Grace period of the connections accepted while draining
+		empty    *sync.Cond    //Signaled when the last connection is released
+
+		lastId    uint64                       //Id of the last connection accepted
+		listeners map[string]*listenerCounters //Counters of the connections, by transport
+	}
+
+	//Connection served by ServeConn
+	servedConn struct {
+		mu          sync.Mutex
+		closing     bool
+		released    bool
+		timers      []*time.Timer
+		notify      func(msg []byte) //Write a message to the connection
+		refuse      func()           //Stop new subscriptions
+		close       func()           //Close the connection, cancelling its calls in flight
+		stopReading func()           //Cancel the calls in flight and close the connection once the queued messages are written
+
+		id          string
+		transport   string
+		remoteAddr  string
+		connectedAt time.Time
+		inFlight    *inFlightRequests
+		subs        *connSubscriptions
+	}
+)
+
+// WithMaxConnectionAge drains persistent connections once they are open for about the age, so clients spread over
+// new servers during rolling deployments and behind load balancers. Ages vary by up to 10% so connections opened
+// together do not all close together. The connection is closed after the grace period, as with Drain.
+// Applies to the connections accepted afterwards.
+func WithMaxConnectionAge(age, grace time.Duration) Option {
+	return func(c *config) {
+		c.maxConnectionAge = &connectionAge{age: age, grace: grace}
+	}
+}
+
+// Drain closes the persistent connections of the server, eg. before it shuts down. Clients are sent a rpc.closing
+// notification and can no longer subscribe. Their connections are closed after the grace period, which lets the
+// calls in flight finish and the clients reconnect to another server. Connections accepted while draining are
+// drained at once. Drain returns once every connection is closed.
+func (rpc *jsonRpcImpl) Drain(grace time.Duration) {
+	rpc.conns.mu.Lock()
+	rpc.conns.draining = true
+	rpc.conns.grace = grace
+	conns := make([]*servedConn, 0, len(rpc.conns.conns))
+	for conn := range rpc.conns.conns {
+		conns = append(conns, conn)
+	}
+	rpc.conns.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.drain(CLOSING_REASON_DRAIN, grace)
+	}
+
+	rpc.conns.mu.Lock()
+	defer rpc.conns.mu.Unlock()
+	for len(rpc.conns.conns) > 0 {
+		rpc.conns.empty.Wait()
+	}
+}
+
+func newServedConns() *servedConns {
+	conns := &servedConns{conns: make(map[*servedConn]struct{}), listeners: make(map[string]*listenerCounters)}
+	conns.empty = sync.NewCond(&conns.mu)
+
+	return conns
+}
+
+// Whether Drain was called
+func (s *servedConns) isDraining() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.draining
+}
+
+// Track the connection until it is released. It is drained at once while the server is draining
+func (s *servedConns) add(conn *servedConn) {
+	s.mu.Lock()
+	s.identify(conn)
+	s.conns[conn] = struct{}{}
+	draining, grace := s.draining, s.grace
+	s.mu.Unlock()
+
+	if draining {
+		conn.drain(CLOSING_REASON_DRAIN, grace)
+	}
+}
+
+func (s *servedConns) remove(conn *servedConn) {
+	conn.release()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, conn)
+	if len(s.conns) == 0 {
+		s.empty.Broadcast()
+	}
+}
+
+// Drain the connection once it reaches the age
+func (c *servedConn) expireAfter(age connectionAge) {
+	jitter := time.Duration(rand.Int63n(int64(age.age)/10 + 1))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timers = append(c.timers, time.AfterFunc(age.age-jitter, func() {
+		c.drain(CLOSING_REASON_MAX_AGE, age.grace)
+	}))
+}
+
+// Notify the client, stop new subscriptions and close the connection after the grace period.
+// Only the first call has an effect
+func (c *servedConn) drain(reason string, grace time.Duration) {
+	c.mu.Lock()
+	if c.closing || c.released {
+		c.mu.Unlock()
+		return
+	}
+	c.closing = true
+	c.mu.Unlock()
+
+	//Writing may wait for a slow client, so it is done without holding the lock
+	c.refuse()
+	c.notify(closingNotification(reason, grace))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.released {
+		c.timers = append(c.timers, time.AfterFunc(grace, c.close))
+	}
+}
+
+// Encoded rpc.closing notification
+func closingNotification(reason string, grace time.Duration) []byte {
+	msg, _ := json.Marshal(Request{
+		Jsonrpc: RPC_VERSION,
+		Method:  CLOSING_METHOD,
+		Params:  closingParams{Reason: reason, GracePeriod: grace.Milliseconds()},
+	})
+
+	return msg
+}
+
+// Stop the timers once the connection is closed
+func (c *servedConn) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.released = true
+	for _, t := range c.timers {
+		t.Stop()
+	}
+}
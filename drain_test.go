@@ -0,0 +1,98 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Read the next message of the connection, failing after a second
+func readTestMessage(t *testing.T, conn net.Conn, reader *bufio.Reader) map[string]any {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg map[string]any
+	json.Unmarshal(line, &msg)
+
+	return msg
+}
+
+func TestDrain(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	clientConn, serverConn := net.Pipe()
+	go rpc.ServeConn(serverConn)
+	defer clientConn.Close()
+	reader := bufio.NewReader(clientConn)
+
+	//The connection is known once it answers
+	clientConn.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}` + "\n"))
+	readTestMessage(t, clientConn, reader)
+
+	drained := make(chan struct{})
+	start := time.Now()
+	go func() {
+		rpc.Drain(50 * time.Millisecond)
+		close(drained)
+	}()
+
+	closing := readTestMessage(t, clientConn, reader)
+	assert.Equal(t, CLOSING_METHOD, closing["method"])
+	assert.Equal(t, map[string]any{"reason": CLOSING_REASON_DRAIN, "gracePeriodMs": float64(50)}, closing["params"])
+
+	//Calls still work during the grace period, but subscriptions do not
+	clientConn.Write([]byte(`{"jsonrpc":"2.0","id":"2","method":"rpc.subscribe","params":[]}` + "\n"))
+	res := readTestMessage(t, clientConn, reader)
+	assert.Equal(t, "Connection is closing", res["error"].(map[string]any)["message"])
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return")
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	_, err := reader.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDrainWithoutConnections(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	done := make(chan struct{})
+	go func() {
+		rpc.Drain(time.Minute)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Drain did not return")
+	}
+}
+
+func TestMaxConnectionAge(t *testing.T) {
+	rpc := NewJsonRpc(WithMaxConnectionAge(50*time.Millisecond, 10*time.Millisecond))
+
+	clientConn, serverConn := net.Pipe()
+	go rpc.ServeConn(serverConn)
+	defer clientConn.Close()
+	reader := bufio.NewReader(clientConn)
+
+	closing := readTestMessage(t, clientConn, reader)
+	assert.Equal(t, CLOSING_METHOD, closing["method"])
+	assert.Equal(t, CLOSING_REASON_MAX_AGE, closing["params"].(map[string]any)["reason"])
+
+	clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := reader.ReadByte()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,85 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// DuplicateIDPolicy decides how batches where several requests share an id are handled
+type DuplicateIDPolicy int
+
+const (
+	ALLOW_DUPLICATE_IDS  DuplicateIDPolicy = iota //Responses keep the shared id. Clients correlate them by position only
+	REJECT_DUPLICATE_IDS                          //The whole batch is rejected with INVALID_REQUEST before any call
+	TAG_DUPLICATE_IDS                             //Responses to shared ids get the id "<id>#<index of the request in the batch>"
+)
+
+// Separates the id of a request from its index in the batch in tagged response ids
+const DUPLICATE_ID_SEPARATOR = "#"
+
+// WithDuplicateIDs sets how batches with duplicate request ids are handled. Defaults to ALLOW_DUPLICATE_IDS
+func WithDuplicateIDs(policy DuplicateIDPolicy) Option {
+	return func(c *config) {
+		c.duplicateIds = policy
+	}
+}
+
+var errDuplicateId = errors.New("Duplicate request id in batch")
+
+// Check the ids of a batch against the policy. Notifications have no id and are never duplicates
+func checkDuplicateIds(policy DuplicateIDPolicy, requests []Request) error {
+	if policy != REJECT_DUPLICATE_IDS {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(requests))
+	for _, req := range requests {
+		if req.Id == nil {
+			continue
+		}
+		if seen[*req.Id] {
+			return &duplicateIdError{id: *req.Id}
+		}
+		seen[*req.Id] = true
+	}
+
+	return nil
+}
+
+// Replace the shared ids of the responses with ids tagged with the index of their request
+func tagDuplicateIds(policy DuplicateIDPolicy, requests []Request, responses []Response) {
+	if policy != TAG_DUPLICATE_IDS {
+		return
+	}
+
+	count := make(map[string]int, len(requests))
+	for _, req := range requests {
+		if req.Id != nil {
+			count[*req.Id]++
+		}
+	}
+
+	for i, req := range requests {
+		if req.Id == nil || count[*req.Id] < 2 {
+			continue
+		}
+
+		//Tagged ids are no longer numbers, even when the request sent one
+		id := *req.Id + DUPLICATE_ID_SEPARATOR + strconv.Itoa(i)
+		responses[i].Id = &id
+		responses[i].numericId = false
+	}
+}
+
+type duplicateIdError struct {
+	id string
+}
+
+func (e *duplicateIdError) Error() string {
+	return fmt.Sprintf("Duplicate request id %s in batch", e.id)
+}
+
+func (e *duplicateIdError) Is(target error) bool {
+	return target == errDuplicateId
+}
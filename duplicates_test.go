@@ -0,0 +1,88 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const duplicateIdsBatch = `[
+	{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]},
+	{"jsonrpc":"2.0","id":"2","method":"Arith.Add","params":[3,4]},
+	{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[5,6]},
+	{"jsonrpc":"2.0","method":"Arith.Add","params":[7,8]}
+]`
+
+func handleTestBatch(t *testing.T, rpc JsonRPC, body string) []Response {
+	var responses []Response
+	assert.Nil(t, json.Unmarshal(rpc.HandleMessage(context.Background(), []byte(body)), &responses))
+
+	return responses
+}
+
+func TestDuplicateIdsAllowedByDefault(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	responses := handleTestBatch(t, rpc, duplicateIdsBatch)
+
+	assert.Len(t, responses, 3)
+	assert.Equal(t, "1", *responses[0].Id)
+	assert.Equal(t, "2", *responses[1].Id)
+	assert.Equal(t, "1", *responses[2].Id)
+}
+
+func TestRejectDuplicateIds(t *testing.T) {
+	rpc := NewJsonRpc(WithDuplicateIDs(REJECT_DUPLICATE_IDS))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	res := decodeTestResponse(t, serveTestBody(rpc, duplicateIdsBatch))
+
+	assert.Nil(t, res.Id)
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, "Duplicate request id 1 in batch", res.Error.Message)
+}
+
+func TestRejectDuplicateIdsIgnoresNotifications(t *testing.T) {
+	rpc := NewJsonRpc(WithDuplicateIDs(REJECT_DUPLICATE_IDS))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	responses := handleTestBatch(t, rpc, `[
+		{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]},
+		{"jsonrpc":"2.0","method":"Arith.Add","params":[3,4]},
+		{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[5,6]}
+	]`)
+
+	assert.Len(t, responses, 1)
+	assert.Equal(t, "1", *responses[0].Id)
+}
+
+func TestTagDuplicateIds(t *testing.T) {
+	rpc := NewJsonRpc(WithDuplicateIDs(TAG_DUPLICATE_IDS))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	responses := handleTestBatch(t, rpc, duplicateIdsBatch)
+
+	assert.Len(t, responses, 3)
+	assert.Equal(t, "1#0", *responses[0].Id)
+	assert.Equal(t, "2", *responses[1].Id)
+	assert.Equal(t, "1#2", *responses[2].Id)
+	assert.Equal(t, any(float64(11)), *responses[2].Result)
+}
+
+func TestTagDuplicateNumericIds(t *testing.T) {
+	rpc := NewJsonRpc(WithStrictNumbers(), WithDuplicateIDs(TAG_DUPLICATE_IDS))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	body := serveTestBody(rpc, `[
+		{"jsonrpc":"2.0","id":1,"method":"Arith.Add","params":[1,2]},
+		{"jsonrpc":"2.0","id":1,"method":"Arith.Add","params":[3,4]},
+		{"jsonrpc":"2.0","id":2,"method":"Arith.Add","params":[5,6]}
+	]`)
+	assert.JSONEq(t, `[
+		{"jsonrpc":"2.0","id":"1#0","result":3},
+		{"jsonrpc":"2.0","id":"1#1","result":7},
+		{"jsonrpc":"2.0","id":2,"result":11}
+	]`, body)
+}
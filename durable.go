@@ -0,0 +1,399 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type (
+	//NotificationStore keeps the events of durable subscribers until they are acknowledged, eg. in a database so
+	//they survive restarts of the server. Implementations must be safe for concurrent use
+	NotificationStore interface {
+		//Append the event to the queue of the subscriber and return its sequence number, which grows with each event
+		Append(subscriber string, event json.RawMessage) (uint64, error)
+		//Pending returns the events of the subscriber not acknowledged yet, in order
+		Pending(subscriber string) ([]StoredNotification, error)
+		//Ack removes the events of the subscriber up to the sequence number
+		Ack(subscriber string, seq uint64) error
+		//Remove the queue of the subscriber, once it unsubscribed
+		Remove(subscriber string) error
+	}
+
+	//StoredNotification is an event waiting in the queue of a durable subscriber
+	StoredNotification struct {
+		Seq   uint64
+		Event json.RawMessage
+	}
+
+	//DurableEvent is the result of an event of a durable subscription. It is delivered again, after reconnecting,
+	//until it is acknowledged
+	DurableEvent struct {
+		Seq    uint64
+		Result json.RawMessage
+		ack    func(seq uint64) error
+	}
+
+	//NotificationStore keeping the queues in memory
+	memoryNotificationStore struct {
+		mu     sync.Mutex
+		limit  int
+		queues map[string]*memoryQueue
+	}
+
+	memoryQueue struct {
+		lastSeq uint64
+		events  []StoredNotification
+	}
+
+	//Durable subscribers of the server, keyed by name. They outlive the connections they are attached to
+	durableSubscribers struct {
+		mu     sync.Mutex
+		byName map[string]*durableSubscriber
+	}
+
+	//Durable subscriber queueing the notifications published on its topics, and delivering them to the
+	//connection it is attached to
+	durableSubscriber struct {
+		mu     sync.Mutex
+		name   string
+		rawId  json.RawMessage
+		store  NotificationStore
+		topics map[string]bool
+		conn   *connSubscriptions //Nil while the subscriber is not connected
+		stop   func()
+	}
+)
+
+// WithDurableNotifications enables durable subscriptions, made with rpc.subscribeDurable. Their events are
+// appended to the store and delivered again, after the subscriber reconnects, until it acknowledges them with
+// rpc.ack. Use it for clients that can not afford to miss events.
+func WithDurableNotifications(store NotificationStore) Option {
+	return func(c *config) {
+		c.notificationStore = store
+	}
+}
+
+// NewMemoryNotificationStore returns a NotificationStore keeping up to limit events per subscriber in memory. The
+// oldest events are dropped beyond that. Zero means no limit. Events are lost when the server restarts.
+func NewMemoryNotificationStore(limit int) NotificationStore {
+	return &memoryNotificationStore{limit: limit, queues: make(map[string]*memoryQueue)}
+}
+
+func (s *memoryNotificationStore) Append(subscriber string, event json.RawMessage) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, ok := s.queues[subscriber]
+	if !ok {
+		queue = &memoryQueue{}
+		s.queues[subscriber] = queue
+	}
+
+	queue.lastSeq++
+	queue.events = append(queue.events, StoredNotification{Seq: queue.lastSeq, Event: event})
+	if s.limit > 0 && len(queue.events) > s.limit {
+		queue.events = append([]StoredNotification(nil), queue.events[len(queue.events)-s.limit:]...)
+	}
+
+	return queue.lastSeq, nil
+}
+
+func (s *memoryNotificationStore) Pending(subscriber string) ([]StoredNotification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, ok := s.queues[subscriber]
+	if !ok {
+		return nil, nil
+	}
+
+	return append([]StoredNotification(nil), queue.events...), nil
+}
+
+func (s *memoryNotificationStore) Ack(subscriber string, seq uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue, ok := s.queues[subscriber]
+	if !ok {
+		return nil
+	}
+
+	i := 0
+	for i < len(queue.events) && queue.events[i].Seq <= seq {
+		i++
+	}
+	queue.events = queue.events[i:]
+
+	return nil
+}
+
+func (s *memoryNotificationStore) Remove(subscriber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.queues, subscriber)
+	return nil
+}
+
+func newDurableSubscribers() *durableSubscribers {
+	return &durableSubscribers{byName: make(map[string]*durableSubscriber)}
+}
+
+func (rpc *jsonRpcImpl) registerDurableMethods(builtins *service) {
+	m := subscriptionMethods{rpc: rpc}
+	builtins.methods["subscribeDurable"] = reflect.ValueOf(m.SubscribeDurable)
+	builtins.methods["unsubscribeDurable"] = reflect.ValueOf(m.UnsubscribeDurable)
+	builtins.methods["ack"] = reflect.ValueOf(m.Ack)
+}
+
+// SubscribeDurable attaches the connection of the caller to the durable subscriber with the name, creating it the
+// first time, and returns the name as the id of the subscription. The events the subscriber did not acknowledge are
+// delivered first. The subscriber keeps queueing the notifications published on the topics, or on every topic when
+// none is given, after the connection ends. Subscribing again with the name replaces its topics, and takes it over
+// from the connection it is attached to.
+func (m subscriptionMethods) SubscribeDurable(ctx context.Context, name string, topics ...string) (string, error, *RpcErrorCode) {
+	store := m.rpc.cfg().notificationStore
+	if store == nil {
+		code := METHOD_NOT_FOUND
+		return "", errors.New("Durable notifications are not enabled"), &code
+	}
+	if name == "" {
+		code := INVALID_PARAMS
+		return "", errors.New("Subscriber name is required"), &code
+	}
+
+	subs, ok := ctx.Value(subscriptionsKey{}).(*connSubscriptions)
+	if !ok {
+		code := INVALID_REQUEST
+		return "", errors.New("Subscriptions need a persistent connection"), &code
+	}
+
+	sub := m.rpc.durable.get(m.rpc, name, store)
+
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+
+	if subs.closed {
+		code := INVALID_REQUEST
+		return "", errors.New("Connection is closing"), &code
+	}
+
+	if err := sub.attach(m.rpc.cfg(), subs, topics); err != nil {
+		return "", errors.New(fmt.Sprintf("Unable to read pending events: %v", err)), nil
+	}
+	subs.stops[durableStopKey(name)] = func() { sub.detach(subs) }
+
+	return name, nil, nil
+}
+
+// UnsubscribeDurable removes the durable subscriber with the name and its queue. It returns false if there is no
+// such subscriber
+func (m subscriptionMethods) UnsubscribeDurable(ctx context.Context, name string) (bool, error, *RpcErrorCode) {
+	sub := m.rpc.durable.remove(name)
+	if sub == nil {
+		return false, nil, nil
+	}
+
+	if subs, ok := ctx.Value(subscriptionsKey{}).(*connSubscriptions); ok {
+		subs.mu.Lock()
+		delete(subs.stops, durableStopKey(name))
+		subs.mu.Unlock()
+	}
+
+	if err := sub.store.Remove(name); err != nil {
+		return true, errors.New(fmt.Sprintf("Unable to remove the queue of %s: %v", name, err)), nil
+	}
+
+	return true, nil, nil
+}
+
+// Ack acknowledges the events of the durable subscriber up to the sequence number, so they are not delivered
+// again. It returns false if there is no such subscriber
+func (m subscriptionMethods) Ack(ctx context.Context, name string, seq uint64) (bool, error, *RpcErrorCode) {
+	sub := m.rpc.durable.lookup(name)
+	if sub == nil {
+		return false, nil, nil
+	}
+
+	if err := sub.store.Ack(name, seq); err != nil {
+		return false, errors.New(fmt.Sprintf("Unable to acknowledge events of %s: %v", name, err)), nil
+	}
+
+	return true, nil, nil
+}
+
+// Key of a durable subscription among the subscriptions of a connection, apart from their numeric ids
+func durableStopKey(name string) string {
+	return "durable:" + name
+}
+
+// The subscriber with the name, created and subscribed to the notifications of the server the first time
+func (d *durableSubscribers) get(rpc *jsonRpcImpl, name string, store NotificationStore) *durableSubscriber {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if sub, ok := d.byName[name]; ok {
+		return sub
+	}
+
+	rawId, _ := json.Marshal(name)
+	sub := &durableSubscriber{name: name, rawId: rawId, store: store}
+	//Subscribed without holding the lock of the subscriber, which publishing takes
+	sub.stop = rpc.Subscribe(func(n Notification) {
+		sub.publish(rpc.cfg(), n)
+	})
+	d.byName[name] = sub
+
+	return sub
+}
+
+func (d *durableSubscribers) lookup(name string) *durableSubscriber {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.byName[name]
+}
+
+// Remove the subscriber with the name and stop queueing its events. Nil when there is none
+func (d *durableSubscribers) remove(name string) *durableSubscriber {
+	d.mu.Lock()
+	sub, ok := d.byName[name]
+	delete(d.byName, name)
+	d.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	sub.stop()
+
+	sub.mu.Lock()
+	sub.conn = nil
+	sub.mu.Unlock()
+
+	return sub
+}
+
+// Deliver the pending events to the connection, then the new ones. Called with the lock of the connection
+func (s *durableSubscriber) attach(cfg *config, conn *connSubscriptions, topics []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.topics = make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		s.topics[topic] = true
+	}
+
+	//Events published meanwhile wait for the lock, so they follow the pending ones
+	pending, err := s.store.Pending(s.name)
+	if err != nil {
+		return err
+	}
+	for _, event := range pending {
+		msg, err := cfg.encodeSubscriptionMessage(s.rawId, event.Event, event.Seq)
+		if err != nil {
+			continue
+		}
+		conn.write(msg)
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// Stop delivering events to the connection, eg. when it ends. Events are queued until the subscriber reconnects
+func (s *durableSubscriber) detach(conn *connSubscriptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == conn {
+		s.conn = nil
+	}
+}
+
+// Queue the notification and deliver it to the connection of the subscriber
+func (s *durableSubscriber) publish(cfg *config, n Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.topics) > 0 && !s.topics[n.Topic] {
+		return
+	}
+
+	result, err := cfg.encodeSubscriptionResult(n)
+	if err != nil {
+		cfg.logger.Printf("Unable to encode notification %s: %v", n.Method, err)
+		return
+	}
+
+	seq, err := s.store.Append(s.name, result)
+	if err != nil {
+		cfg.logger.Printf("Unable to queue notification %s for %s: %v", n.Method, s.name, err)
+		return
+	}
+
+	if s.conn == nil {
+		return
+	}
+	msg, err := cfg.encodeSubscriptionMessage(s.rawId, result, seq)
+	if err != nil {
+		cfg.logger.Printf("Unable to encode notification %s: %v", n.Method, err)
+		return
+	}
+	s.conn.write(msg)
+}
+
+// SubscribeDurable makes a durable subscription with rpc.subscribeDurable and delivers its events on the channel.
+// Unlike Subscribe, events are queued on the server while the client is disconnected, and delivered again after it
+// reconnects until they are acknowledged with Ack, so an event may be received more than once. The name identifies
+// the subscriber across connections and must be unique to it. Unsubscribe removes the subscriber and its queue.
+// Up to SUBSCRIPTION_BUFFER_SIZE events wait on the channel, the oldest are dropped beyond that and delivered
+// again after the next reconnection.
+func (c *Client) SubscribeDurable(ctx context.Context, name string, topics ...string) (<-chan DurableEvent, Unsubscribe, error) {
+	params := make([]any, 0, len(topics)+1)
+	params = append(params, name)
+	for _, topic := range topics {
+		params = append(params, topic)
+	}
+
+	durable := make(chan DurableEvent, SUBSCRIPTION_BUFFER_SIZE)
+	ack := func(seq uint64) error {
+		var acked bool
+		if err := c.Call(context.Background(), "rpc.ack", []any{name, seq}, &acked); err != nil {
+			return err
+		}
+		if !acked {
+			return errors.New(fmt.Sprintf("No durable subscriber %s", name))
+		}
+		return nil
+	}
+
+	//The server uses the name as the id of the subscription
+	id, _ := json.Marshal(name)
+	_, unsubscribe, err := c.subscribeWith(ctx, &clientSubscription{
+		method:  "rpc.subscribeDurable",
+		params:  params,
+		id:      compactId(id),
+		durable: durable,
+		ack:     ack,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return durable, unsubscribe, nil
+}
+
+// Ack acknowledges the event and the ones received before it, so the server stops delivering them
+func (e DurableEvent) Ack() error {
+	if e.ack == nil {
+		return errors.New("Event can not be acknowledged")
+	}
+
+	return e.ack(e.Seq)
+}
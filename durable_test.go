@@ -0,0 +1,169 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Receive the next event of a durable subscription, failing after a second
+func receiveTestDurableEvent(t *testing.T, events <-chan DurableEvent) DurableEvent {
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Subscription closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("No event received")
+		return DurableEvent{}
+	}
+}
+
+func TestMemoryNotificationStore(t *testing.T) {
+	store := NewMemoryNotificationStore(2)
+
+	for _, event := range []string{`1`, `2`, `3`} {
+		_, err := store.Append("reports", json.RawMessage(event))
+		assert.Nil(t, err)
+	}
+
+	pending, _ := store.Pending("reports")
+	assert.Equal(t, []StoredNotification{{Seq: 2, Event: json.RawMessage(`2`)}, {Seq: 3, Event: json.RawMessage(`3`)}}, pending)
+
+	assert.Nil(t, store.Ack("reports", 2))
+	pending, _ = store.Pending("reports")
+	assert.Equal(t, []StoredNotification{{Seq: 3, Event: json.RawMessage(`3`)}}, pending)
+
+	seq, _ := store.Append("reports", json.RawMessage(`4`))
+	assert.Equal(t, uint64(4), seq)
+
+	assert.Nil(t, store.Remove("reports"))
+	pending, _ = store.Pending("reports")
+	assert.Empty(t, pending)
+}
+
+func TestDurableEventsRedeliveredUntilAcknowledged(t *testing.T) {
+	rpc := NewJsonRpc(WithDurableNotifications(NewMemoryNotificationStore(0)))
+
+	client := newTestSubscriptionClient(rpc)
+	events, _, err := client.SubscribeDurable(context.Background(), "billing", "orders")
+	assert.Nil(t, err)
+
+	rpc.Publish("orders", "Orders.Created", []any{1})
+	rpc.Publish("users", "Users.Created", []any{2})
+	rpc.Publish("orders", "Orders.Created", []any{3})
+
+	first := receiveTestDurableEvent(t, events)
+	assert.Equal(t, uint64(1), first.Seq)
+	assert.JSONEq(t, `{"topic":"orders","method":"Orders.Created","params":[1]}`, string(first.Result))
+	assert.Nil(t, first.Ack())
+	assert.Equal(t, uint64(2), receiveTestDurableEvent(t, events).Seq)
+	client.Close()
+
+	//Queued while the subscriber is disconnected
+	rpc.Publish("orders", "Orders.Created", []any{4})
+
+	client = newTestSubscriptionClient(rpc)
+	defer client.Close()
+	events, _, err = client.SubscribeDurable(context.Background(), "billing", "orders")
+	assert.Nil(t, err)
+
+	second := receiveTestDurableEvent(t, events)
+	assert.Equal(t, uint64(2), second.Seq)
+	assert.JSONEq(t, `{"topic":"orders","method":"Orders.Created","params":[3]}`, string(second.Result))
+
+	third := receiveTestDurableEvent(t, events)
+	assert.Equal(t, uint64(3), third.Seq)
+	assert.JSONEq(t, `{"topic":"orders","method":"Orders.Created","params":[4]}`, string(third.Result))
+}
+
+func TestDurableResubscribeAfterReconnect(t *testing.T) {
+	rpc := NewJsonRpc(WithDurableNotifications(NewMemoryNotificationStore(0)))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	defer l.Close()
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	dial := func(ctx context.Context) (net.Conn, error) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+		return conn, err
+	}
+
+	client, err := NewReconnectingClient(context.Background(), dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	events, _, err := client.SubscribeDurable(context.Background(), "billing")
+	assert.Nil(t, err)
+
+	rpc.Publish("orders", "Orders.Created", []any{1})
+	assert.Equal(t, uint64(1), receiveTestDurableEvent(t, events).Seq)
+
+	mu.Lock()
+	conns[0].Close()
+	mu.Unlock()
+	rpc.Publish("orders", "Orders.Created", []any{2})
+
+	//The unacknowledged events are delivered again on the new connection
+	seen := map[uint64]bool{}
+	assert.Eventually(t, func() bool {
+		select {
+		case event := <-events:
+			seen[event.Seq] = true
+		case <-time.After(10 * time.Millisecond):
+		}
+		return seen[1] && seen[2]
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestUnsubscribeDurable(t *testing.T) {
+	store := NewMemoryNotificationStore(0)
+	rpc := NewJsonRpc(WithDurableNotifications(store))
+	client := newTestSubscriptionClient(rpc)
+	defer client.Close()
+
+	events, unsubscribe, err := client.SubscribeDurable(context.Background(), "billing")
+	assert.Nil(t, err)
+	rpc.Publish("orders", "Orders.Created", nil)
+	receiveTestDurableEvent(t, events)
+
+	assert.Nil(t, unsubscribe())
+	_, ok := <-events
+	assert.False(t, ok)
+
+	pending, _ := store.Pending("billing")
+	assert.Empty(t, pending)
+
+	rpc.Publish("orders", "Orders.Created", nil)
+	pending, _ = store.Pending("billing")
+	assert.Empty(t, pending)
+
+	var acked bool
+	assert.Nil(t, client.Call(context.Background(), "rpc.ack", []any{"billing", 1}, &acked))
+	assert.False(t, acked)
+}
+
+func TestDurableNotificationsDisabled(t *testing.T) {
+	client := newTestSubscriptionClient(NewJsonRpc())
+	defer client.Close()
+
+	_, _, err := client.SubscribeDurable(context.Background(), "billing")
+	assert.Equal(t, "Durable notifications are not enabled", err.Error())
+}
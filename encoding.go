@@ -0,0 +1,136 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// A Codec encodes responses and decodes requests in place of encoding/json.
+// Decoded params must use the same Go types as encoding/json: []any, map[string]any, float64, string and bool.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// WithCodec replaces the JSON encoding of the server, eg. with a faster JSON library.
+// WithIndentedResponses, DisableHTMLEscaping and UseNumber have no effect with a codec.
+func WithCodec(codec Codec) Option {
+	return func(c *config) {
+		c.codec = codec
+	}
+}
+
+// WithIndentedResponses pretty prints HTTP responses. Useful while debugging.
+// Responses on raw sockets stay on a single line so they can be framed by new lines.
+func WithIndentedResponses() Option {
+	return func(c *config) {
+		c.indentResponses = true
+	}
+}
+
+// DisableHTMLEscaping stops the encoder from escaping <, > and & in strings of responses
+func DisableHTMLEscaping() Option {
+	return func(c *config) {
+		c.disableHTMLEscaping = true
+	}
+}
+
+// UseNumber decodes numbers in requests as json.Number instead of float64.
+// Params are converted to the type the method declares, so large int64 values keep their precision.
+func UseNumber() Option {
+	return func(c *config) {
+		c.useNumber = true
+	}
+}
+
+// Marshal v with the encoding options of the server
+func (c *config) marshal(v any, indent bool) ([]byte, error) {
+	if c.codec != nil {
+		return c.codec.Marshal(v)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := c.encode(buf, v, indent); err != nil {
+		return nil, err
+	}
+
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// Write v to w with the encoding options of the server, through a pooled buffer. Nothing is written when v can not be encoded
+func (c *config) writeJSON(w io.Writer, v any, indent bool) error {
+	if c.codec != nil {
+		data, err := c.codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		return err
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := c.encode(buf, v, indent); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Encode v into buf with encoding/json
+func (c *config) encode(buf *bytes.Buffer, v any, indent bool) error {
+	return c.encodePrefixed(buf, v, "", indent)
+}
+
+// Encode v into buf with encoding/json. Lines after the first start with the prefix when indented
+func (c *config) encodePrefixed(buf *bytes.Buffer, v any, prefix string, indent bool) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(!c.disableHTMLEscaping)
+	if indent {
+		encoder.SetIndent(prefix, "  ")
+	}
+
+	if c.strictNumbers {
+		v = withNumericIds(v)
+	}
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	//Encoder terminates every value with a new line
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// Unmarshal data into v with the decoding options of the server
+func (c *config) unmarshal(data []byte, v any) error {
+	if c.codec != nil {
+		return c.codec.Unmarshal(data, v)
+	}
+
+	//json.Unmarshal does not copy data into a buffer of its own like the decoder
+	if !c.useNumber {
+		return json.Unmarshal(data, v)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	//Reject trailing data like json.Unmarshal does
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("Invalid data after top-level value")
+	}
+
+	return nil
+}
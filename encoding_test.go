@@ -0,0 +1,83 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type encodingService struct{}
+
+func (encodingService) Echo(ctx context.Context, s string) (string, error, *RpcErrorCode) {
+	return s, nil, nil
+}
+
+func (encodingService) Next(ctx context.Context, n int64) (int64, error, *RpcErrorCode) {
+	return n + 1, nil, nil
+}
+
+func (encodingService) Raw(ctx context.Context) (json.RawMessage, error, *RpcErrorCode) {
+	return json.RawMessage(`{"cached":true}`), nil, nil
+}
+
+func serveTestBody(rpc JsonRPC, body string) string {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	rpc.ServeHTTP(recorder, r)
+
+	return recorder.Body.String()
+}
+
+func TestIndentedResponses(t *testing.T) {
+	rpc := NewJsonRpc(WithIndentedResponses())
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["hi"]}`)
+
+	assert.Equal(t, "{\n  \"jsonrpc\": \"2.0\",\n  \"id\": \"1\",\n  \"result\": \"hi\"\n}", body)
+}
+
+func TestDisableHTMLEscaping(t *testing.T) {
+	params := `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["<b>"]}`
+
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(encodingService{}, "Enc")
+	assert.Contains(t, serveTestBody(rpc, params), `\u003cb\u003e`)
+
+	rpc = NewJsonRpc(DisableHTMLEscaping())
+	rpc.RegisterWithName(encodingService{}, "Enc")
+	assert.Contains(t, serveTestBody(rpc, params), `"<b>"`)
+}
+
+func TestUseNumber(t *testing.T) {
+	params := `{"jsonrpc":"2.0","id":"1","method":"Enc.Next","params":[9007199254740993]}`
+
+	rpc := NewJsonRpc(UseNumber())
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	assert.Contains(t, serveTestBody(rpc, params), `"result":9007199254740994`)
+}
+
+func TestUseNumberInvalidParam(t *testing.T) {
+	rpc := NewJsonRpc(UseNumber())
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	res := &Response{}
+	json.Unmarshal([]byte(serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Enc.Next","params":[1.5]}`)), res)
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+}
+
+func TestRawMessageResult(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Enc.Raw","params":[]}`)
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":"1","result":{"cached":true}}`, body)
+}
@@ -0,0 +1,48 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+)
+
+// Translation of a handler error to an RPC error registered with MapError
+type errorMapping struct {
+	target  error
+	code    RpcErrorCode
+	message string
+}
+
+// MapError translates errors matching target, as reported by errors.Is, to code when a handler returns them
+// without an error code. A non empty message replaces the message of the error so internal details are not leaked.
+// Mappings are checked in the order they are added. It is safe to call while requests are being served.
+func (rpc *jsonRpcImpl) MapError(target error, code RpcErrorCode, message string) {
+	rpc.Reconfigure(func(c *config) {
+		c.errorMappings = append(c.errorMappings, errorMapping{target: target, code: code, message: message})
+	})
+}
+
+// Build the response of an error returned without a code. Unmapped errors are internal errors
+func (c *config) makeMappedErrorResponse(err error, id *string) Response {
+	for _, m := range c.errorMappings {
+		if !errors.Is(err, m.target) {
+			continue
+		}
+
+		res := makeErrorResponse(err, m.code, nil, id)
+		if m.message != "" {
+			res.Error.Message = m.message
+		}
+		return res
+	}
+
+	return makeErrorResponse(err, INTERNAL_ERROR, nil, id)
+}
+
+// Cause of a call context that ended without being cancelled by the client, eg. a deadline of the transport
+func callContextError(ctx context.Context) error {
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,117 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errUserNotFound = errors.New("user not found")
+
+type users struct{}
+
+func (users) Get(ctx context.Context, id string) (string, error, *RpcErrorCode) {
+	return "", fmt.Errorf("query user %s: %w", id, errUserNotFound), nil
+}
+
+func (users) Fail(ctx context.Context) (string, error, *RpcErrorCode) {
+	return "", errors.New("Connection reset"), nil
+}
+
+func (users) Forbidden(ctx context.Context) (string, error, *RpcErrorCode) {
+	code := INVALID_REQUEST
+	return "", errUserNotFound, &code
+}
+
+func (users) Slow(ctx context.Context) (string, error, *RpcErrorCode) {
+	<-ctx.Done()
+	return "", ctx.Err(), nil
+}
+
+func callUsers(t *testing.T, rpc JsonRPC, method string, params []any) *Response {
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Users." + method, Params: params, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestMapError(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(users{}, "Users")
+	rpc.MapError(errUserNotFound, INVALID_PARAMS, "User does not exist")
+
+	res := callUsers(t, rpc, "Get", []any{"42"})
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "User does not exist", res.Error.Message)
+}
+
+func TestMapErrorKeepsMessage(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(users{}, "Users")
+	rpc.MapError(errUserNotFound, INVALID_PARAMS, "")
+
+	res := callUsers(t, rpc, "Get", []any{"42"})
+
+	assert.Equal(t, "query user 42: user not found", res.Error.Message)
+}
+
+func TestUnmappedError(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(users{}, "Users")
+	rpc.MapError(errUserNotFound, INVALID_PARAMS, "User does not exist")
+
+	assert.Equal(t, INTERNAL_ERROR, callUsers(t, rpc, "Fail", []any{}).Error.Code)
+}
+
+func TestMapErrorIgnoresExplicitCode(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(users{}, "Users")
+	rpc.MapError(errUserNotFound, INVALID_PARAMS, "User does not exist")
+
+	assert.Equal(t, INVALID_REQUEST, callUsers(t, rpc, "Forbidden", []any{}).Error.Code)
+}
+
+func TestMapErrorDeadline(t *testing.T) {
+	rpc := NewJsonRpc().(*jsonRpcImpl)
+	rpc.RegisterWithName(users{}, "Users")
+	rpc.MapError(context.DeadlineExceeded, SERVER_BUSY, "Timed out")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	id := "1"
+	res := rpc.handleSingleRequest(ctx, Request{Id: &id, Method: "Users.Slow", Params: []any{}, Jsonrpc: RPC_VERSION})
+
+	assert.Equal(t, SERVER_BUSY, res.Error.Code)
+	assert.Equal(t, "Timed out", res.Error.Message)
+}
+
+func TestMapErrorWhileServing(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(users{}, "Users")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			callUsers(t, rpc, "Get", []any{"42"})
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		rpc.MapError(fmt.Errorf("error %d", i), INVALID_PARAMS, "")
+	}
+	wg.Wait()
+
+	rpc.MapError(errUserNotFound, INVALID_PARAMS, "User does not exist")
+	assert.Equal(t, INVALID_PARAMS, callUsers(t, rpc, "Get", []any{"42"}).Error.Code)
+}
@@ -9,4 +9,25 @@ const (
 	METHOD_NOT_FOUND RpcErrorCode = 32601
 	INVALID_PARAMS   RpcErrorCode = 32602
 	INTERNAL_ERROR   RpcErrorCode = 32603
+
+	SERVER_BUSY        RpcErrorCode = 32001 //Method is at its concurrency limit
+	JOB_PENDING        RpcErrorCode = 32002 //Result of a job that is still running was requested
+	JOB_CANCELLED      RpcErrorCode = 32003 //Result of a cancelled job was requested
+	UNAUTHORIZED       RpcErrorCode = 32004 //Credentials of the request are missing or invalid
+	RESPONSE_TOO_LARGE RpcErrorCode = 32005 //Encoded result exceeds WithMaxResponseBytes
+	QUOTA_EXCEEDED     RpcErrorCode = 32006 //Client made all the calls its quota allows for the period
+	TRANSACTION_FAILED RpcErrorCode = 32007 //Entry of an atomic batch failed, so the batch was compensated
+
+	UPSTREAM_UNAVAILABLE RpcErrorCode = 32010 //No upstream of the proxy could be reached
+
+	REQUEST_CANCELLED RpcErrorCode = 32800 //Request was cancelled by the client. Same code as the Language Server Protocol
 )
+
+func (e *RpcError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the error the RpcError was made from, so interceptors can inspect it with errors.Is and errors.As
+func (e *RpcError) Unwrap() error {
+	return e.err
+}
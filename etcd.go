@@ -0,0 +1,150 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type (
+	//EtcdOptions configure the etcd cluster an EtcdBackend stores endpoints in, through its v3 JSON gateway
+	EtcdOptions struct {
+		Address string //URL of an etcd member. Defaults to http://127.0.0.1:2379
+		Prefix  string //Prefix of the keys. Defaults to /services
+		TTL     int64  //Seconds the lease of an endpoint outlives its last heartbeat. Defaults to 15
+		Token   string //Auth token, sent as the Authorization header when set
+
+		Client *http.Client //Client of the gateway. Defaults to http.DefaultClient
+	}
+
+	//EtcdBackend stores endpoints as JSON under <prefix>/<name>/<id>, attached to a lease the heartbeats keep alive, so
+	//the endpoints of servers that died expire
+	EtcdBackend struct {
+		opts EtcdOptions
+
+		mu     sync.Mutex
+		leases map[string]string //Lease of each registered endpoint, by endpoint id
+	}
+
+	etcdLease struct {
+		ID  json.Number `json:"ID"`
+		TTL json.Number `json:"TTL"`
+	}
+)
+
+// NewEtcdBackend returns a backend storing endpoints in the etcd cluster of the options
+func NewEtcdBackend(opts EtcdOptions) *EtcdBackend {
+	if opts.Address == "" {
+		opts.Address = "http://127.0.0.1:2379"
+	}
+	opts.Address = strings.TrimSuffix(opts.Address, "/")
+	if opts.Prefix == "" {
+		opts.Prefix = "/services"
+	}
+	opts.Prefix = strings.TrimSuffix(opts.Prefix, "/")
+	if opts.TTL <= 0 {
+		opts.TTL = 15
+	}
+
+	return &EtcdBackend{opts: opts, leases: make(map[string]string)}
+}
+
+// Key the endpoint is stored under
+func (b *EtcdBackend) Key(endpoint Endpoint) string {
+	return b.opts.Prefix + "/" + endpoint.Name + "/" + endpoint.ID
+}
+
+// Register grants a lease and stores the endpoint under it
+func (b *EtcdBackend) Register(ctx context.Context, endpoint Endpoint) error {
+	var lease etcdLease
+	if err := b.request(ctx, "/v3/lease/grant", map[string]any{"TTL": b.opts.TTL}, &lease); err != nil {
+		return err
+	}
+	if lease.ID == "" {
+		return errors.New("No lease granted by etcd")
+	}
+
+	if err := b.put(ctx, endpoint, lease.ID.String()); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.leases[endpoint.ID] = lease.ID.String()
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Heartbeat keeps the lease of the endpoint alive and stores its health. An endpoint whose lease expired, eg. after
+// etcd could not be reached for a while, is registered again
+func (b *EtcdBackend) Heartbeat(ctx context.Context, endpoint Endpoint) error {
+	leaseID, ok := b.lease(endpoint)
+	if !ok {
+		return b.Register(ctx, endpoint)
+	}
+
+	var res struct {
+		Result etcdLease `json:"result"`
+	}
+	if err := b.request(ctx, "/v3/lease/keepalive", map[string]string{"ID": leaseID}, &res); err != nil {
+		return err
+	}
+	if ttl, err := res.Result.TTL.Int64(); err != nil || ttl <= 0 {
+		return b.Register(ctx, endpoint)
+	}
+
+	return b.put(ctx, endpoint, leaseID)
+}
+
+// Deregister revokes the lease of the endpoint, which deletes it
+func (b *EtcdBackend) Deregister(ctx context.Context, endpoint Endpoint) error {
+	leaseID, ok := b.lease(endpoint)
+	if !ok {
+		return nil
+	}
+
+	if err := b.request(ctx, "/v3/lease/revoke", map[string]string{"ID": leaseID}, nil); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	delete(b.leases, endpoint.ID)
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *EtcdBackend) lease(endpoint Endpoint) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	leaseID, ok := b.leases[endpoint.ID]
+	return leaseID, ok
+}
+
+func (b *EtcdBackend) put(ctx context.Context, endpoint Endpoint, leaseID string) error {
+	value, err := json.Marshal(endpoint)
+	if err != nil {
+		return err
+	}
+
+	//Keys and values are bytes, which the gateway encodes in base64
+	return b.request(ctx, "/v3/kv/put", map[string]string{
+		"key":   base64.StdEncoding.EncodeToString([]byte(b.Key(endpoint))),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": leaseID,
+	}, nil)
+}
+
+func (b *EtcdBackend) request(ctx context.Context, path string, body, out any) error {
+	header := http.Header{}
+	if b.opts.Token != "" {
+		header.Set("Authorization", b.opts.Token)
+	}
+
+	return discoveryRequest(ctx, b.opts.Client, http.MethodPost, b.opts.Address+path, header, body, out)
+}
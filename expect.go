@@ -0,0 +1,83 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+type (
+	//ErrInvalidResult is returned by Client.Call when the result does not match the expectation of the call
+	ErrInvalidResult struct {
+		Method string          //Method that was called
+		Result json.RawMessage //Result as received from the server
+		Reason error           //Why the result was rejected
+	}
+
+	//Expectation of the result of a call
+	resultExpectation struct {
+		schema *JSONSchema
+		typ    reflect.Type
+	}
+
+	resultExpectationKey struct{}
+)
+
+func (e *ErrInvalidResult) Error() string {
+	return fmt.Sprintf("Invalid result of %s: %s", e.Method, e.Reason)
+}
+
+func (e *ErrInvalidResult) Unwrap() error {
+	return e.Reason
+}
+
+// ContextWithResultSchema makes the calls made with the context check their result against the JSON schema.
+// Results that do not match fail with *ErrInvalidResult. References must be resolved, as no document is attached.
+func ContextWithResultSchema(ctx context.Context, schema JSONSchema) context.Context {
+	return context.WithValue(ctx, resultExpectationKey{}, &resultExpectation{schema: &schema})
+}
+
+// ContextWithResultType makes the calls made with the context check that their result decodes into T and meets the
+// rpc tags of its fields, as params do on the server. Results that do not match fail with *ErrInvalidResult.
+// Unknown fields are accepted so servers can add fields.
+func ContextWithResultType[T any](ctx context.Context) context.Context {
+	return context.WithValue(ctx, resultExpectationKey{}, &resultExpectation{typ: reflect.TypeOf((*T)(nil)).Elem()})
+}
+
+// Check the result of a call against the expectation attached to the context, if any
+func checkExpectedResult(ctx context.Context, method string, raw json.RawMessage) error {
+	expectation, ok := ctx.Value(resultExpectationKey{}).(*resultExpectation)
+	if !ok {
+		return nil
+	}
+
+	if err := expectation.check(raw); err != nil {
+		return &ErrInvalidResult{Method: method, Result: raw, Reason: err}
+	}
+
+	return nil
+}
+
+func (e *resultExpectation) check(raw json.RawMessage) error {
+	if e.schema != nil {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		return (&openRPCSpec{}).validate(value, e.schema, "result")
+	}
+
+	v := reflect.New(e.typ)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return errors.New(fmt.Sprintf("result does not decode into %s: %s", e.typ, err))
+	}
+
+	return validateParam(v)
+}
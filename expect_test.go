@@ -0,0 +1,69 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testExpectedUser struct {
+	Id   int    `json:"id" rpc:"required"`
+	Name string `json:"name" rpc:"required"`
+}
+
+// Client of a server answering every call with the result
+func newTestResultClient(t *testing.T, result string) *Client {
+	url := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":"1","result":` + result + `}`))
+	}))
+
+	return NewHTTPClient(url)
+}
+
+func TestResultType(t *testing.T) {
+	ctx := ContextWithResultType[testExpectedUser](context.Background())
+
+	var user testExpectedUser
+	err := newTestResultClient(t, `{"id":1,"name":"ada","admin":false}`).Call(ctx, "Users.Get", []any{1}, &user)
+	assert.Nil(t, err)
+	assert.Equal(t, "ada", user.Name)
+
+	err = newTestResultClient(t, `{"id":"1","name":"ada"}`).Call(ctx, "Users.Get", []any{1}, &user)
+	var invalid *ErrInvalidResult
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, "Users.Get", invalid.Method)
+	assert.JSONEq(t, `{"id":"1","name":"ada"}`, string(invalid.Result))
+
+	err = newTestResultClient(t, `{"id":1}`).Call(ctx, "Users.Get", []any{1}, nil)
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, "Invalid result of Users.Get: Param name is required", err.Error())
+}
+
+func TestResultSchema(t *testing.T) {
+	min := 0.0
+	ctx := ContextWithResultSchema(context.Background(), JSONSchema{
+		Type: JSONSchemaTypes{"array"},
+		Items: &JSONSchema{
+			Type:    JSONSchemaTypes{"number"},
+			Minimum: &min,
+		},
+	})
+
+	var balances []float64
+	assert.Nil(t, newTestResultClient(t, `[1, 2.5]`).Call(ctx, "Accounts.Balances", nil, &balances))
+
+	err := newTestResultClient(t, `[1, -2]`).Call(ctx, "Accounts.Balances", nil, &balances)
+	var invalid *ErrInvalidResult
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, `[1, -2]`, string(invalid.Result))
+	assert.Equal(t, "Invalid result of Accounts.Balances: result[1] must be at least 0", err.Error())
+}
+
+func TestResultWithoutExpectation(t *testing.T) {
+	var name string
+	assert.Nil(t, newTestResultClient(t, `"ada"`).Call(context.Background(), "Users.Name", nil, &name))
+	assert.Equal(t, "ada", name)
+}
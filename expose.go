@@ -0,0 +1,107 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RegisterInterface registers srv exposing only the methods of the interface T, eg.
+// RegisterInterface[UserAPI](rpc, users, ServiceOptions{}), so exported helpers of the implementation stay private.
+// Registration fails when a method of T is not a valid method. The service is named after the type of srv unless
+// opts names it.
+func RegisterInterface[T any](r Registrar, srv T, opts ServiceOptions) error {
+	iface := reflect.TypeOf((*T)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		return errors.New(fmt.Sprintf("RegisterInterface needs an interface type, got %s", iface))
+	}
+
+	opts.Methods = make([]string, iface.NumMethod())
+	for i := 0; i < iface.NumMethod(); i++ {
+		opts.Methods[i] = iface.Method(i).Name
+	}
+
+	return r.RegisterWithOptions(srv, opts)
+}
+
+// Go names of the methods of srv that are not exposed: those promoted from embedded fields tagged rpc:"-" or
+// exposed under their own namespace, and those missing from the methods of the options when set. A method the
+// service declares itself with the name of a method of such a field is hidden as well.
+func hiddenMethods(srv any, opts ServiceOptions) map[string]bool {
+	hidden := make(map[string]bool)
+
+	if len(opts.Methods) > 0 {
+		exposed := make(map[string]bool, len(opts.Methods))
+		for _, name := range opts.Methods {
+			exposed[name] = true
+		}
+
+		t := reflect.TypeOf(srv)
+		for m := 0; m < t.NumMethod(); m++ {
+			if name := t.Method(m).Name; !exposed[name] {
+				hidden[name] = true
+			}
+		}
+	}
+
+	for _, field := range embeddedFields(srv, opts) {
+		//Methods with a pointer receiver are promoted too when the service is a pointer
+		for _, embedded := range []reflect.Type{field.typ, reflect.PointerTo(field.typ)} {
+			for m := 0; m < embedded.NumMethod(); m++ {
+				hidden[embedded.Method(m).Name] = true
+			}
+		}
+	}
+
+	return hidden
+}
+
+// Check every method the options expose is a method of the service that can be called
+func checkExposedMethods(name string, opts ServiceOptions, registered map[string]bool) error {
+	var missing []string
+	for _, method := range opts.Methods {
+		if !registered[method] {
+			missing = append(missing, method)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+
+	return errors.New(fmt.Sprintf("Service %s has no valid method %v to expose", name, missing))
+}
+
+// WithExposedMethods makes only the methods matching the patterns callable, eg. Arith.Add, or Reports.* for every
+// method of a service and its groups. Other methods fail with METHOD_NOT_FOUND as if they were not registered,
+// whatever registered them. Methods of the built-in service stay callable.
+func WithExposedMethods(patterns ...string) Option {
+	return func(c *config) {
+		c.exposedMethods = append([]string{}, patterns...)
+	}
+}
+
+// Whether the method matches the patterns of WithExposedMethods
+func (c *config) exposes(method string) bool {
+	if c.exposedMethods == nil || isBuiltinMethod(method) {
+		return true
+	}
+
+	return matchesMethod(c.exposedMethods, method)
+}
+
+// Whether the method matches one of the patterns, eg. Arith.Add, Reports.* or *
+func matchesMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == method {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	//Exported helpers that must not be callable
+	lifecycle struct{}
+
+	wallets struct {
+		lifecycle `rpc:"-"`
+	}
+
+	WalletAPI interface {
+		Balance(ctx context.Context, id string) (int, error, *RpcErrorCode)
+	}
+)
+
+func (lifecycle) Shutdown(ctx context.Context) (bool, error, *RpcErrorCode) {
+	return true, nil, nil
+}
+
+func (*lifecycle) Reset(ctx context.Context) (bool, error, *RpcErrorCode) {
+	return true, nil, nil
+}
+
+func (wallets) Balance(ctx context.Context, id string) (int, error, *RpcErrorCode) {
+	return len(id), nil, nil
+}
+
+func (wallets) Close(ctx context.Context) (bool, error, *RpcErrorCode) {
+	return true, nil, nil
+}
+
+func TestHiddenEmbeddedMethods(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithName(&wallets{}, "Wallets"))
+
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "Wallets.Balance", []any{"abc"}).Result)
+	assert.Nil(t, callMethod(t, rpc, "Wallets.Close", nil).Error)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Wallets.Shutdown", nil).Error.Code)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Wallets.Reset", nil).Error.Code)
+}
+
+func TestRegisterInterface(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Nil(t, RegisterInterface[WalletAPI](rpc, wallets{}, ServiceOptions{Name: "Wallets"}))
+
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "Wallets.Balance", []any{"abc"}).Result)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Wallets.Close", nil).Error.Code)
+
+	//Works on groups, and names the service after the implementation by default
+	assert.Nil(t, RegisterInterface[WalletAPI](rpc.Group("v1"), wallets{}, ServiceOptions{}))
+	assert.Nil(t, callMethod(t, rpc, "v1.wallets.Balance", []any{"abc"}).Error)
+}
+
+func TestRegisterInterfaceInvalid(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	err := RegisterInterface[wallets](rpc, wallets{}, ServiceOptions{})
+	assert.Equal(t, "RegisterInterface needs an interface type, got jsonrpc2.wallets", err.Error())
+
+	err = rpc.RegisterWithOptions(wallets{}, ServiceOptions{Methods: []string{"Balance", "Shutdown", "Missing"}})
+	assert.Equal(t, "Service wallets has no valid method [Missing Shutdown] to expose", err.Error())
+}
+
+func TestWithExposedMethods(t *testing.T) {
+	rpc := NewJsonRpc(WithExposedMethods("Wallets.Balance", "Admin.*"))
+	rpc.RegisterWithName(wallets{}, "Wallets")
+	rpc.Group("Admin").RegisterWithName(wallets{}, "Wallets")
+
+	assert.Nil(t, callMethod(t, rpc, "Wallets.Balance", []any{"abc"}).Error)
+	assert.Nil(t, callMethod(t, rpc, "Admin.Wallets.Close", nil).Error)
+	assert.Nil(t, callMethod(t, rpc, "rpc.listMethods", nil).Error)
+
+	res := callMethod(t, rpc, "Wallets.Close", nil)
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+	assert.Equal(t, "Method Wallets.Close does not exist", res.Error.Message)
+}
@@ -0,0 +1,80 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Handler of the calls of methods no registered service has, eg. to proxy them or synthesize methods at runtime. The
+// params are the raw JSON of the request, null when it has none. A nil error answers the call with the result
+type FallbackHandler func(ctx context.Context, method string, params json.RawMessage) (any, *RpcError)
+
+// Call the fallback handler instead of returning METHOD_NOT_FOUND. A nil handler removes it
+func (rpc *jsonRpcImpl) SetFallbackHandler(handler FallbackHandler) {
+	if handler == nil {
+		rpc.fallback.Store(nil)
+		return
+	}
+
+	rpc.fallback.Store(&handler)
+}
+
+// Handler the method falls back to, if any. Methods of the built-in service never fall back
+func (rpc *jsonRpcImpl) fallbackFor(method string) FallbackHandler {
+	handler := rpc.fallback.Load()
+	if handler == nil || isBuiltinMethod(method) {
+		return nil
+	}
+
+	return *handler
+}
+
+// Whether the method is one of the service. Nil services have no method
+func (s *service) declares(methodName string) bool {
+	if s == nil {
+		return false
+	}
+
+	_, ok := s.methods[methodName]
+	return ok
+}
+
+// Answer the request with the fallback handler. It can be cancelled and times out like registered methods
+func (rpc *jsonRpcImpl) callFallback(ctx context.Context, cfg *config, handler FallbackHandler, req *Request) (res Response) {
+	params, err := json.Marshal(req.Params)
+	if err != nil {
+		return makeErrorResponse(err, INVALID_PARAMS, nil, req.Id)
+	}
+
+	if req.Id != nil {
+		var done func()
+		ctx, done = trackRequest(ctx, *req.Id)
+		defer done()
+	}
+	if timeout := cfg.methodTimeout(req.Method); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	call := rpc.handlers.start()
+	defer call.finish()
+	defer func() {
+		if r := recover(); r != nil {
+			cfg.logger.Printf("Recovered from panic in fallback of %s: %v", req.Method, r)
+			res = makeErrorResponse(errors.New(fmt.Sprintf("Internal error: Panic %s", r)), INTERNAL_ERROR, nil, req.Id)
+		}
+	}()
+
+	result, rpcErr := handler(ctx, req.Method, params)
+	if cancelledByClient(ctx) {
+		return makeCancelledResponse(req.Id)
+	}
+	if rpcErr != nil {
+		return Response{Jsonrpc: RPC_VERSION, Id: req.Id, Error: rpcErr}
+	}
+
+	return makeSuccessResponse(&result, req.Id)
+}
@@ -0,0 +1,52 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallbackHandler(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	var calls []string
+	rpc.SetFallbackHandler(func(ctx context.Context, method string, params json.RawMessage) (any, *RpcError) {
+		calls = append(calls, method+" "+string(params))
+		if strings.HasPrefix(method, "Flags.") {
+			return nil, &RpcError{Code: METHOD_NOT_FOUND, Message: "Feature is disabled"}
+		}
+		return "echo " + string(params), nil
+	})
+
+	//Unknown services, unknown methods of known services and root methods fall back
+	assert.Equal(t, any(`echo ["a"]`), *callMethod(t, rpc, "Scripts.Run", []any{"a"}).Result)
+	assert.Equal(t, any("echo null"), *callMethod(t, rpc, "Arith.Pow", nil).Result)
+	assert.Equal(t, any(`echo [{"x":1}]`), *callMethod(t, rpc, "ping", []any{map[string]any{"x": 1}}).Result)
+
+	res := callMethod(t, rpc, "Flags.Beta", nil)
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+	assert.Equal(t, "Feature is disabled", res.Error.Message)
+
+	//Registered methods and built-ins do not
+	assert.Nil(t, callMethod(t, rpc, "Arith.Add", []any{1, 2}).Error)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "rpc.missing", nil).Error.Code)
+	assert.Equal(t, []string{`Scripts.Run ["a"]`, "Arith.Pow null", `ping [{"x":1}]`, "Flags.Beta null"}, calls)
+
+	rpc.SetFallbackHandler(nil)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Scripts.Run", nil).Error.Code)
+}
+
+func TestFallbackHandlerPanics(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.SetFallbackHandler(func(ctx context.Context, method string, params json.RawMessage) (any, *RpcError) {
+		panic("no script")
+	})
+
+	res := callMethod(t, rpc, "Scripts.Run", nil)
+	assert.Equal(t, INTERNAL_ERROR, res.Error.Code)
+	assert.Equal(t, "Internal error: Panic no script", res.Error.Message)
+}
@@ -0,0 +1,149 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Named param listing the fields of the result to return, with FieldSelection. eg. "_fields": ["id", "owner.name"]
+const FIELDS_PARAM = "_fields"
+
+// Fields to keep in a value, keyed by name. A nil selection keeps the whole value
+type fieldSelection map[string]fieldSelection
+
+// FieldSelection returns only the fields of the result a caller lists in the reserved _fields named param, eg.
+// "params": {"id": 7, "_fields": ["id", "name", "owner.email"]}, so large objects shrink without changing handlers.
+// Dots select nested fields and arrays are filtered element by element. The param is removed before the method is
+// called. Results are filtered once encoded, so fields are named as in the JSON of the result.
+func FieldSelection() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			params, ok := req.Params.(map[string]any)
+			if !ok {
+				return next(ctx, req)
+			}
+			raw, ok := params[FIELDS_PARAM]
+			if !ok {
+				return next(ctx, req)
+			}
+
+			selection, err := parseFieldSelection(raw)
+			if err != nil {
+				return makeErrorResponse(err, INVALID_PARAMS, nil, req.Id)
+			}
+
+			stripped := *req
+			rest := make(map[string]any, len(params)-1)
+			for name, value := range params {
+				if name != FIELDS_PARAM {
+					rest[name] = value
+				}
+			}
+			//Methods without params are called by position
+			stripped.Params = rest
+			if len(rest) == 0 {
+				stripped.Params = nil
+			}
+
+			res := next(ctx, &stripped)
+			if res.Result == nil {
+				return res
+			}
+
+			result, err := selection.apply(*res.Result)
+			if err != nil {
+				return makeErrorResponse(err, INTERNAL_ERROR, nil, req.Id)
+			}
+			res.Result = &result
+
+			return res
+		}
+	}
+}
+
+func parseFieldSelection(raw any) (fieldSelection, error) {
+	fields, ok := raw.([]any)
+	if !ok || len(fields) == 0 {
+		return nil, errors.New(FIELDS_PARAM + " must be an array of field names")
+	}
+
+	selection := make(fieldSelection)
+	for _, field := range fields {
+		path, ok := field.(string)
+		if !ok || path == "" {
+			return nil, errors.New(FIELDS_PARAM + " must be an array of field names")
+		}
+		selection.add(strings.Split(path, "."))
+	}
+
+	return selection, nil
+}
+
+func (s fieldSelection) add(path []string) {
+	name := path[0]
+	nested, ok := s[name]
+	if ok && nested == nil {
+		//The whole field is already selected
+		return
+	}
+
+	if len(path) == 1 {
+		s[name] = nil
+		return
+	}
+
+	if nested == nil {
+		nested = make(fieldSelection)
+		s[name] = nested
+	}
+	nested.add(path[1:])
+}
+
+// Encode the result and keep the selected fields
+func (s fieldSelection) apply(result any) (any, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	//Numbers are kept as written so large integers do not lose precision
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	return s.filter(decoded), nil
+}
+
+func (s fieldSelection) filter(value any) any {
+	if s == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		filtered := make(map[string]any, len(s))
+		for name, nested := range s {
+			if field, ok := v[name]; ok {
+				filtered[name] = nested.filter(field)
+			}
+		}
+		return filtered
+
+	case []any:
+		filtered := make([]any, len(v))
+		for i, item := range v {
+			filtered[i] = s.filter(item)
+		}
+		return filtered
+
+	default:
+		return value
+	}
+}
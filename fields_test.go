@@ -0,0 +1,81 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	testFieldsOwner struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	testFieldsRepo struct {
+		Id    int64           `json:"id"`
+		Name  string          `json:"name"`
+		Owner testFieldsOwner `json:"owner"`
+		Tags  []string        `json:"tags"`
+	}
+
+	testRepos struct{}
+)
+
+func (testRepos) Get(ctx context.Context, params struct {
+	Id int64 `json:"id"`
+}) (testFieldsRepo, error, *RpcErrorCode) {
+	return testFieldsRepo{Id: params.Id, Name: "jsonrpc2", Owner: testFieldsOwner{Name: "tom", Email: "tom@example.com"}, Tags: []string{"go"}}, nil, nil
+}
+
+func (testRepos) List(ctx context.Context) ([]testFieldsRepo, error, *RpcErrorCode) {
+	return []testFieldsRepo{{Id: 1, Name: "a"}, {Id: 2, Name: "b"}}, nil, nil
+}
+
+func callTestFields(t *testing.T, method string, params map[string]any) *Response {
+	rpc := NewJsonRpc(WithMiddleware(FieldSelection()))
+	rpc.RegisterWithName(testRepos{}, "Repos")
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: method, Params: params, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestFieldSelection(t *testing.T) {
+	res := callTestFields(t, "Repos.Get", map[string]any{"id": 7, "_fields": []any{"id", "owner.email", "missing"}})
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, map[string]any{"id": float64(7), "owner": map[string]any{"email": "tom@example.com"}}, *res.Result)
+}
+
+func TestFieldSelectionOfArrays(t *testing.T) {
+	res := callTestFields(t, "Repos.List", map[string]any{"_fields": []any{"name"}})
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, []any{map[string]any{"name": "a"}, map[string]any{"name": "b"}}, *res.Result)
+}
+
+func TestFieldSelectionWithoutFields(t *testing.T) {
+	res := callTestFields(t, "Repos.Get", map[string]any{"id": 1})
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "go", (*res.Result).(map[string]any)["tags"].([]any)[0])
+}
+
+func TestInvalidFieldSelection(t *testing.T) {
+	res := callTestFields(t, "Repos.Get", map[string]any{"id": 1, "_fields": "name"})
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "_fields must be an array of field names", res.Error.Message)
+}
+
+func TestFieldSelectionMerge(t *testing.T) {
+	selection, _ := parseFieldSelection([]any{"owner.name", "owner", "id"})
+
+	assert.Equal(t, fieldSelection{"owner": nil, "id": nil}, selection)
+}
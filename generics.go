@@ -0,0 +1,91 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Handle registers a typed function as a method, eg. Handle(rpc, "Arith.Add", add), without declaring a service type.
+// The params are decoded into req: a struct accepts named params, any other type a single positional param.
+// The method is added to the service when it is already registered. Registering the service afterwards replaces it.
+// Errors are sent as INTERNAL_ERROR, or translated with MapError, unless they wrap a *RpcError whose code is used.
+// Works on groups as well, where method is relative to the group.
+func Handle[Req, Resp any](r Registrar, method string, handler func(ctx context.Context, req Req) (Resp, error)) error {
+	fn := func(ctx context.Context, req Req) (Resp, error, *RpcErrorCode) {
+		resp, err := handler(ctx, req)
+
+		var rpcErr *RpcError
+		if errors.As(err, &rpcErr) {
+			return resp, err, &rpcErr.Code
+		}
+
+		return resp, err, nil
+	}
+
+	return registerFunc(r, method, reflect.ValueOf(fn))
+}
+
+// Call calls the method with params and decodes its result into a new Resp. eg. sum, err := Call[int](ctx, c, "Arith.Add", []any{1, 2})
+func Call[Resp any](ctx context.Context, c *Client, method string, params any) (Resp, error) {
+	var resp Resp
+	err := c.Call(ctx, method, params, &resp)
+
+	return resp, err
+}
+
+func registerFunc(r Registrar, method string, fn reflect.Value) error {
+	i := strings.LastIndex(method, ".")
+	if i <= 0 || i == len(method)-1 {
+		return errors.New(fmt.Sprintf("Invalid method name %s. It must be Service.Method", method))
+	}
+	serviceName, methodName := method[:i], method[i+1:]
+
+	switch registrar := r.(type) {
+	case *jsonRpcImpl:
+		return registrar.addMethod(serviceName, methodName, fn, nil)
+	case *OpenRPCServer:
+		return registerFunc(registrar.JsonRPC, method, fn)
+	case *group:
+		middleware := append([]Middleware(nil), registrar.middleware...)
+		return registrar.rpc.addMethod(registrar.prefix+"."+serviceName, methodName, fn, middleware)
+	default:
+		return errors.New(fmt.Sprintf("Handle is not supported on %T", r))
+	}
+}
+
+// Add a single method to a service, creating the service with middleware when it is not registered
+func (rpc *jsonRpcImpl) addMethod(serviceName, methodName string, fn reflect.Value, middleware []Middleware) error {
+	if isReservedServiceName(serviceName) {
+		return errors.New(fmt.Sprintf("Service name %s is reserved", BUILTIN_SERVICE_NAME))
+	}
+
+	if err := checkValidationTags(fn.Type()); err != nil {
+		return err
+	}
+
+	if spec := rpc.cfg().openRPC; spec != nil {
+		if err := spec.checkMethod(serviceName+"."+methodName, fn.Type()); err != nil {
+			return err
+		}
+	}
+
+	rpc.updateServices(func(services serviceMap) {
+		//The published service is copied, since calls may be reading its methods
+		updated := &service{name: serviceName, methods: make(map[string]reflect.Value), middleware: middleware}
+		if s, ok := services[serviceName]; ok {
+			copied := *s
+			copied.methods = make(map[string]reflect.Value, len(s.methods)+1)
+			for name, method := range s.methods {
+				copied.methods[name] = method
+			}
+			updated = &copied
+		}
+		updated.methods[methodName] = fn
+		services[serviceName] = updated
+	})
+
+	return nil
+}
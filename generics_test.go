@@ -0,0 +1,118 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type greetParams struct {
+	Name  string `json:"name" rpc:"required"`
+	Times int    `json:"times"`
+}
+
+func greet(ctx context.Context, p greetParams) (string, error) {
+	greeting := ""
+	for i := 0; i < p.Times; i++ {
+		greeting += "Hello " + p.Name + "! "
+	}
+
+	return greeting, nil
+}
+
+func TestHandle(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Nil(t, Handle(rpc, "Greeter.Greet", greet))
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Greeter.Greet","params":{"name":"Ada","times":2}}`))
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, any("Hello Ada! Hello Ada! "), *res.Result)
+}
+
+func TestHandlePositionalParam(t *testing.T) {
+	rpc := NewJsonRpc()
+	Handle(rpc, "Math.Square", func(ctx context.Context, n int) (int, error) { return n * n, nil })
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Math.Square","params":[7]}`))
+
+	assert.Equal(t, any(float64(49)), *res.Result)
+}
+
+func TestHandleValidatesParams(t *testing.T) {
+	rpc := NewJsonRpc()
+	Handle(rpc, "Greeter.Greet", greet)
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Greeter.Greet","params":{"times":2}}`))
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "Param name is required", res.Error.Message)
+}
+
+func TestHandleErrors(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.MapError(errUserNotFound, INVALID_PARAMS, "User not found")
+	Handle(rpc, "Users.Get", func(ctx context.Context, id string) (string, error) {
+		if id == "forbidden" {
+			return "", &RpcError{Code: RpcErrorCode(32020), Message: "Forbidden"}
+		}
+		return "", errUserNotFound
+	})
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Users.Get","params":["42"]}`))
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "User not found", res.Error.Message)
+
+	res = decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Users.Get","params":["forbidden"]}`))
+	assert.Equal(t, RpcErrorCode(32020), res.Error.Code)
+	assert.Equal(t, "Forbidden", res.Error.Message)
+}
+
+func TestHandleAddsToRegisteredService(t *testing.T) {
+	rpc := newTestArithRpc()
+	Handle(rpc, "Arith.Double", func(ctx context.Context, n float64) (float64, error) { return 2 * n, nil })
+
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "Arith.Add", []any{1, 2}).Result)
+	assert.Equal(t, any(float64(8)), *callMethod(t, rpc, "Arith.Double", []any{4}).Result)
+}
+
+func TestHandleInGroup(t *testing.T) {
+	rpc := NewJsonRpc()
+	calls := 0
+	admin := rpc.Group("admin", func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			calls++
+			return next(ctx, req)
+		}
+	})
+	assert.Nil(t, Handle(admin, "Greeter.Greet", greet))
+
+	res := callMethod(t, rpc, "admin.Greeter.Greet", []any{map[string]any{"name": "Ada", "times": 1}})
+	assert.Equal(t, any("Hello Ada! "), *res.Result)
+	assert.Equal(t, 1, calls)
+}
+
+func TestHandleInvalidNames(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	assert.NotNil(t, Handle(rpc, "Greet", greet))
+	assert.NotNil(t, Handle(rpc, "Greeter.", greet))
+	assert.NotNil(t, Handle(rpc, "rpc.Greet", greet))
+}
+
+func TestCall(t *testing.T) {
+	rpc := NewJsonRpc()
+	Handle(rpc, "Greeter.Greet", greet)
+	client := NewInProcessClient(rpc)
+
+	greeting, err := Call[string](context.Background(), client, "Greeter.Greet", greetParams{Name: "Ada", Times: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello Ada! ", greeting)
+
+	_, err = Call[string](context.Background(), client, "Greeter.Greet", greetParams{Times: 1})
+	var rpcErr *RpcError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, INVALID_PARAMS, rpcErr.Code)
+}
@@ -0,0 +1,74 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"strings"
+)
+
+type (
+	//Registrar registers services. Both the server and its groups are registrars
+	Registrar interface {
+		//Register a service
+		Register(srv any) error
+
+		//Register a service and specify name
+		RegisterWithName(srv any, name string) error
+
+		//Register a service with options such as its name and method documentation
+		RegisterWithOptions(srv any, opts ServiceOptions) error
+
+		//Create a nested group. Its prefix and middleware are added to those of the parent
+		Group(prefix string, middleware ...Middleware) Registrar
+	}
+
+	//Services registered under a common prefix with shared middleware
+	group struct {
+		rpc        *jsonRpcImpl
+		prefix     string
+		middleware []Middleware
+	}
+)
+
+// Group returns a registrar for services sharing the prefix and middleware, like groups of HTTP routers.
+// A service named users in the group admin is called as admin.users.Create.
+// The middleware of the group runs after the server middleware, around the calls of the group's services only.
+func (rpc *jsonRpcImpl) Group(prefix string, middleware ...Middleware) Registrar {
+	return &group{rpc: rpc, prefix: prefix, middleware: middleware}
+}
+
+func (g *group) Register(srv any) error {
+	return g.RegisterWithOptions(srv, ServiceOptions{})
+}
+
+func (g *group) RegisterWithName(srv any, name string) error {
+	return g.RegisterWithOptions(srv, ServiceOptions{Name: name})
+}
+
+func (g *group) RegisterWithOptions(srv any, opts ServiceOptions) error {
+	if g.prefix == "" || strings.HasPrefix(g.prefix, ".") || strings.HasSuffix(g.prefix, ".") {
+		return errors.New("Group prefix must not be empty or start or end with a dot")
+	}
+
+	srv, err := g.rpc.construct(srv)
+	if err != nil {
+		return err
+	}
+
+	if opts.Name == "" {
+		opts.Name = serviceTypeName(srv)
+	}
+	opts.Name = g.prefix + "." + opts.Name
+
+	middleware := make([]Middleware, 0, len(g.middleware)+len(opts.Middleware))
+	middleware = append(middleware, g.middleware...)
+	opts.Middleware = append(middleware, opts.Middleware...)
+
+	return g.rpc.registerWithOptions(srv, opts)
+}
+
+func (g *group) Group(prefix string, middleware ...Middleware) Registrar {
+	nested := make([]Middleware, 0, len(g.middleware)+len(middleware))
+	nested = append(nested, g.middleware...)
+
+	return &group{rpc: g.rpc, prefix: g.prefix + "." + prefix, middleware: append(nested, middleware...)}
+}
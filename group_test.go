@@ -0,0 +1,77 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func denyAll(next Handler) Handler {
+	return func(ctx context.Context, req *Request) Response {
+		return makeErrorResponse(errors.New("Unauthorized"), INVALID_REQUEST, nil, req.Id)
+	}
+}
+
+func callMethod(t *testing.T, rpc JsonRPC, method string, params []any) *Response {
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: method, Params: params, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestGroupPrefix(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.Group("admin").RegisterWithName(arith{}, "math")
+
+	res := callMethod(t, rpc, "admin.math.Add", []any{1, 2})
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, float64(3), *res.Result)
+}
+
+func TestNestedGroups(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.Group("v1").Group("admin").Register(arith{})
+
+	assert.Nil(t, callMethod(t, rpc, "v1.admin.arith.Add", []any{1, 2}).Error)
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+	rpc.Group("admin", denyAll).RegisterWithName(arith{}, "math")
+
+	assert.Equal(t, INVALID_REQUEST, callMethod(t, rpc, "admin.math.Add", []any{1, 2}).Error.Code)
+	assert.Nil(t, callMethod(t, rpc, "Arith.Add", []any{1, 2}).Error)
+}
+
+func TestGroupMiddlewareOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) Response {
+				order = append(order, name)
+				return next(ctx, req)
+			}
+		}
+	}
+
+	rpc := NewJsonRpc(WithMiddleware(trace("server")))
+	rpc.Group("admin", trace("admin")).Group("users", trace("users")).RegisterWithName(arith{}, "math")
+
+	callMethod(t, rpc, "admin.users.math.Add", []any{1, 2})
+
+	assert.Equal(t, []string{"server", "admin", "users"}, order)
+}
+
+func TestGroupReservedPrefix(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	assert.Error(t, rpc.Group(BUILTIN_SERVICE_NAME).Register(arith{}))
+	assert.Error(t, rpc.Group("").Register(arith{}))
+}
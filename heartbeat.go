@@ -0,0 +1,222 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	//Method of the heartbeats. Servers send it as a notification to their clients, and answer it as a request
+	HEARTBEAT_PING_METHOD = "rpc.ping"
+	//Notification answering a heartbeat of the server
+	HEARTBEAT_PONG_METHOD = "rpc.pong"
+)
+
+type (
+	//Liveness checks of the persistent connections served by ServeConn, Serve and ListenAndServe
+	HeartbeatOptions struct {
+		Interval    time.Duration //Silence of the client after which the server pings it. Zero disables the pings
+		Timeout     time.Duration //Time the client has to answer a ping before its connection is closed. Defaults to Interval
+		IdleTimeout time.Duration //Time without requests after which the connection is closed, even when pings are answered. Zero disables it
+
+		//Called once a connection closed for failing a heartbeat or being idle is released, eg. to clean up state
+		//kept for it. Subscriptions made on the connection are already closed.
+		OnDead func(remoteAddr string, reason error)
+	}
+
+	//Tracks the messages of a connection and tells when it is dead
+	liveness struct {
+		opts      HeartbeatOptions
+		seen      chan struct{} //Signaled by every message
+		requested chan struct{} //Signaled by every message but pongs
+		stop      chan struct{}
+		done      chan struct{}
+		err       error //Why the connection is dead. Read once done is closed
+	}
+)
+
+var (
+	pingNotification = []byte(`{"jsonrpc":"2.0","method":"` + HEARTBEAT_PING_METHOD + `"}`)
+	pongNotification = []byte(`{"jsonrpc":"2.0","method":"` + HEARTBEAT_PONG_METHOD + `"}`)
+)
+
+// WithHeartbeat checks that the clients of persistent connections are alive. Clients silent for the interval are
+// sent a rpc.ping notification, and their connection is closed when nothing is received within the timeout.
+// Clients answer with any message, usually a rpc.pong notification as the clients of this package do.
+// Connections without requests for the idle timeout are closed as well. Calls in flight on a closed connection
+// are cancelled and its subscriptions closed. Applies to the connections accepted afterwards.
+func WithHeartbeat(opts HeartbeatOptions) Option {
+	return func(c *config) {
+		if opts.Timeout == 0 {
+			opts.Timeout = opts.Interval
+		}
+		c.heartbeat = &opts
+	}
+}
+
+// WithKeepalive makes clients on a persistent connection call rpc.ping when the interval passes, and close the
+// connection when no answer is received within the timeout. Clients of NewReconnectingClient dial again.
+// Servers answering the ping with an error are alive as well, so it works with servers without rpc.ping.
+func WithKeepalive(interval, timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		if t, ok := c.transport.(*connTransport); ok {
+			go t.keepalive(interval, timeout)
+		}
+	}
+}
+
+func newLiveness(opts HeartbeatOptions) *liveness {
+	return &liveness{
+		opts:      opts,
+		seen:      make(chan struct{}, 1),
+		requested: make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Record a message received on the connection. Returns whether it is a pong, which is not handled further
+func (l *liveness) received(msg []byte) bool {
+	pong := isPong(msg)
+
+	signal(l.seen)
+	if !pong {
+		signal(l.requested)
+	}
+
+	return pong
+}
+
+func isPong(msg []byte) bool {
+	if !bytes.Contains(msg, []byte(HEARTBEAT_PONG_METHOD)) {
+		return false
+	}
+
+	var req struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+
+	return json.Unmarshal(msg, &req) == nil && req.Id == nil && req.Method == HEARTBEAT_PONG_METHOD
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// Ping the connection while it is silent and call dead once it fails the heartbeat or is idle.
+// Returns once the connection is dead or close is called.
+func (l *liveness) watch(ping func(), dead func()) {
+	defer close(l.done)
+
+	var pings, idle <-chan time.Time
+	var pingTimer, idleTimer *time.Timer
+	if l.opts.Interval > 0 {
+		pingTimer = time.NewTimer(l.opts.Interval)
+		defer pingTimer.Stop()
+		pings = pingTimer.C
+	}
+	if l.opts.IdleTimeout > 0 {
+		idleTimer = time.NewTimer(l.opts.IdleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
+	waiting := false
+	for {
+		select {
+		case <-l.stop:
+			return
+
+		case <-l.seen:
+			waiting = false
+			resetTimer(pingTimer, l.opts.Interval)
+
+		case <-l.requested:
+			resetTimer(idleTimer, l.opts.IdleTimeout)
+
+		case <-pings:
+			if waiting {
+				l.err = errors.New(fmt.Sprintf("No answer to the heartbeat within %s", l.opts.Timeout))
+				dead()
+				return
+			}
+			ping()
+			waiting = true
+			pingTimer.Reset(l.opts.Timeout)
+
+		case <-idle:
+			l.err = errors.New(fmt.Sprintf("No request for %s", l.opts.IdleTimeout))
+			dead()
+			return
+		}
+	}
+}
+
+// Stop watching and return why the connection was found dead, if it was
+func (l *liveness) close() error {
+	close(l.stop)
+	<-l.done
+
+	return l.err
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if t == nil {
+		return
+	}
+
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+// Call rpc.ping on the connection when the interval passes and close it when no answer arrives within the timeout
+func (t *connTransport) keepalive(interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pings uint64
+	for {
+		select {
+		case <-t.closed:
+			return
+		case <-ticker.C:
+		}
+
+		t.mu.Lock()
+		conn, done := t.conn, t.done
+		t.mu.Unlock()
+
+		select {
+		case <-done:
+			//Already failed. Reconnecting or closed
+			continue
+		default:
+		}
+
+		//Ids of the client are never prefixed with the method, so they do not collide
+		pings++
+		id := fmt.Sprintf("%s-%d", HEARTBEAT_PING_METHOD, pings)
+		msg, _ := json.Marshal(Request{Jsonrpc: RPC_VERSION, Id: &id, Method: HEARTBEAT_PING_METHOD})
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_, err := t.RoundTrip(ctx, msg, false)
+		cancel()
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Serve a connection with the heartbeat and return the reason it was found dead, once it is
+func serveTestHeartbeat(opts HeartbeatOptions) (net.Conn, <-chan error) {
+	reasons := make(chan error, 1)
+	opts.OnDead = func(remoteAddr string, reason error) {
+		reasons <- reason
+	}
+
+	clientConn, serverConn := net.Pipe()
+	go NewJsonRpc(WithHeartbeat(opts)).ServeConn(serverConn)
+
+	return clientConn, reasons
+}
+
+func TestHeartbeatClosesSilentConnections(t *testing.T) {
+	conn, reasons := serveTestHeartbeat(HeartbeatOptions{Interval: 20 * time.Millisecond})
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	ping, err := reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","method":"rpc.ping"}`, ping)
+
+	_, err = reader.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "No answer to the heartbeat within 20ms", (<-reasons).Error())
+}
+
+func TestClientAnswersHeartbeat(t *testing.T) {
+	conn, reasons := serveTestHeartbeat(HeartbeatOptions{Interval: 10 * time.Millisecond})
+	client := NewConnClient(conn)
+	defer client.Close()
+
+	time.Sleep(100 * time.Millisecond)
+
+	pong, err := client.Ping(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, pong.Time.IsZero())
+	assert.Empty(t, reasons)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	conn, reasons := serveTestHeartbeat(HeartbeatOptions{Interval: 10 * time.Millisecond, IdleTimeout: 100 * time.Millisecond})
+	client := NewConnClient(conn)
+	defer client.Close()
+
+	select {
+	case reason := <-reasons:
+		assert.Equal(t, "No request for 100ms", reason.Error())
+	case <-time.After(time.Second):
+		t.Fatal("Idle connection not closed")
+	}
+
+	assert.Error(t, client.Call(context.Background(), HEARTBEAT_PING_METHOD, nil, nil))
+}
+
+func TestKeepaliveClosesDeadConnections(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	//The server reads requests without answering
+	go io.Copy(io.Discard, serverConn)
+
+	client := NewConnClient(clientConn, WithKeepalive(10*time.Millisecond, 20*time.Millisecond))
+	defer client.Close()
+
+	assert.Eventually(t, func() bool {
+		err := client.Notify(context.Background(), "Arith.Add", nil)
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPingMethod(t *testing.T) {
+	before := time.Now()
+	res := callMethod(t, NewJsonRpc(), HEARTBEAT_PING_METHOD, nil)
+
+	assert.Nil(t, res.Error)
+	var pong PingResult
+	raw, _ := json.Marshal(*res.Result)
+	assert.Nil(t, json.Unmarshal(raw, &pong))
+	assert.False(t, pong.Time.Before(before))
+	assert.NotEmpty(t, pong.GoVersion)
+}
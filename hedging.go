@@ -0,0 +1,92 @@
+package jsonrpc2
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	//Hedging settings of a client
+	hedging struct {
+		secondary ClientTransport
+		delay     time.Duration
+		methods   map[string]bool //Idempotent methods that are hedged. Empty means every method
+	}
+
+	//Outcome of a round trip of a hedged call
+	hedgeResult struct {
+		body []byte
+		err  error
+	}
+)
+
+// WithHedging sends calls that are still pending after delay to the secondary transport as well, eg. another replica,
+// and returns the first answer received. Calls failing on the primary are sent to the secondary without waiting.
+// Only hedge idempotent methods, listed in methods. Every call is hedged when methods is empty. Notifications never are.
+func WithHedging(secondary ClientTransport, delay time.Duration, methods ...string) ClientOption {
+	return func(c *Client) {
+		h := &hedging{secondary: secondary, delay: delay, methods: make(map[string]bool, len(methods))}
+		for _, method := range methods {
+			h.methods[method] = true
+		}
+		c.hedging = h
+	}
+}
+
+func (h *hedging) hedges(req *Request) bool {
+	if h == nil || req.Id == nil {
+		return false
+	}
+
+	return len(h.methods) == 0 || h.methods[req.Method]
+}
+
+// Send the message on primary, then on the secondary transport after the delay or a failure.
+// The first round trip without error wins and the other is cancelled. The first error is returned when both fail.
+func (h *hedging) roundTrip(ctx context.Context, primary ClientTransport, msg []byte) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, 2)
+	send := func(transport ClientTransport) {
+		body, err := transport.RoundTrip(ctx, msg, false)
+		results <- hedgeResult{body: body, err: err}
+	}
+
+	go send(primary)
+	pending := 1
+	hedged := false
+	hedge := func() {
+		hedged = true
+		pending++
+		go send(h.secondary)
+	}
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !hedged {
+				hedge()
+			}
+
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				return res.body, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+
+			if !hedged {
+				hedge()
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
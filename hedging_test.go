@@ -0,0 +1,115 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Transport answering every call with result after delay, or failing with err
+type delayedTransport struct {
+	delay  time.Duration
+	result string
+	err    error
+	calls  atomic.Int32
+	closed atomic.Bool
+}
+
+func (t *delayedTransport) RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error) {
+	t.calls.Add(1)
+
+	select {
+	case <-time.After(t.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if t.err != nil {
+		return nil, t.err
+	}
+	return []byte(`{"jsonrpc":"2.0","id":"1","result":"` + t.result + `"}`), nil
+}
+
+func (t *delayedTransport) Close() error {
+	t.closed.Store(true)
+	return nil
+}
+
+func callHedged(client *Client, method string) (string, error) {
+	var result string
+	err := client.Call(context.Background(), method, nil, &result)
+
+	return result, err
+}
+
+func TestHedgingFastPrimary(t *testing.T) {
+	primary := &delayedTransport{result: "primary"}
+	secondary := &delayedTransport{result: "secondary"}
+	client := NewClient(primary, WithHedging(secondary, 100*time.Millisecond))
+
+	result, err := callHedged(client, "Users.Get")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", result)
+	assert.Equal(t, int32(0), secondary.calls.Load())
+}
+
+func TestHedgingSlowPrimary(t *testing.T) {
+	primary := &delayedTransport{delay: time.Second, result: "primary"}
+	secondary := &delayedTransport{result: "secondary"}
+	client := NewClient(primary, WithHedging(secondary, 10*time.Millisecond))
+
+	start := time.Now()
+	result, err := callHedged(client, "Users.Get")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "secondary", result)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestHedgingFailedPrimary(t *testing.T) {
+	primary := &delayedTransport{err: errors.New("Connection refused")}
+	secondary := &delayedTransport{result: "secondary"}
+	client := NewClient(primary, WithHedging(secondary, time.Minute))
+
+	result, err := callHedged(client, "Users.Get")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "secondary", result)
+}
+
+func TestHedgingBothFail(t *testing.T) {
+	primary := &delayedTransport{err: errors.New("Connection refused")}
+	secondary := &delayedTransport{delay: 10 * time.Millisecond, err: errors.New("Timeout")}
+	client := NewClient(primary, WithHedging(secondary, time.Minute))
+
+	_, err := callHedged(client, "Users.Get")
+
+	assert.Equal(t, "Connection refused", err.Error())
+}
+
+func TestHedgingOnlyListedMethods(t *testing.T) {
+	primary := &delayedTransport{delay: 50 * time.Millisecond, result: "primary"}
+	secondary := &delayedTransport{result: "secondary"}
+	client := NewClient(primary, WithHedging(secondary, time.Millisecond, "Users.Get"))
+
+	result, err := callHedged(client, "Users.Create")
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", result)
+
+	assert.Nil(t, client.Notify(context.Background(), "Users.Get", nil))
+	assert.Equal(t, int32(0), secondary.calls.Load())
+}
+
+func TestHedgingClosesSecondary(t *testing.T) {
+	secondary := &delayedTransport{}
+	client := NewClient(&delayedTransport{}, WithHedging(secondary, time.Second))
+
+	client.Close()
+
+	assert.True(t, secondary.closed.Load())
+}
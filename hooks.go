@@ -0,0 +1,210 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type (
+	//Service with resources to open when the server starts, eg. connections or caches to warm
+	Starter interface {
+		Start(ctx context.Context) error
+	}
+
+	//Service with resources to release when the server shuts down
+	Stopper interface {
+		Stop(ctx context.Context) error
+	}
+
+	//Registered services with lifecycle hooks, in the order they were registered
+	serviceHooks struct {
+		mu       sync.Mutex
+		started  bool
+		services []hookedService
+
+		announcement *announcement //Endpoint published while started. Nil without WithDiscovery
+	}
+
+	hookedService struct {
+		name    string
+		srv     any
+		running bool //Started, or without a Start hook, since the server started
+	}
+)
+
+func newServiceHooks() *serviceHooks {
+	return &serviceHooks{}
+}
+
+// Start calls the Start hook of every registered service, in the order they were registered, eg. before serving
+// with ServeHTTP, then publishes the endpoint of WithDiscovery. Serve, ListenAndServe, ListenAndServeTLS,
+// ServeNATS and ServeAMQP call it themselves. When a hook fails the services already started are stopped and the error is returned. Services registered once the server started are
+// started when they are registered. Starting a started server does nothing.
+func (rpc *jsonRpcImpl) Start(ctx context.Context) error {
+	h := rpc.hooks
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.started {
+		return nil
+	}
+
+	for i := range h.services {
+		if err := h.services[i].start(ctx); err != nil {
+			return h.abortStart(ctx, err)
+		}
+	}
+
+	//Clients are only told about the server once its services run
+	if discovery := rpc.cfg().discovery; discovery != nil {
+		announcement, err := rpc.announce(ctx, discovery)
+		if err != nil {
+			return h.abortStart(ctx, err)
+		}
+		h.announcement = announcement
+	}
+	h.started = true
+
+	return nil
+}
+
+// Shutdown removes the endpoint of WithDiscovery, waits for the handlers to return, as Wait does, then calls the
+// Stop hook of the started services in the reverse order of their registration. Every service is stopped even if a
+// hook fails, and the errors are joined. Call it after the transports stopped accepting requests, eg. after Drain.
+// Workers of WithShardedDispatch stop too. The server can be started again.
+func (rpc *jsonRpcImpl) Shutdown(ctx context.Context) error {
+	h := rpc.hooks
+	h.mu.Lock()
+	announcement := h.announcement
+	h.announcement = nil
+	h.mu.Unlock()
+
+	var withdrawErr error
+	if announcement != nil {
+		withdrawErr = announcement.withdraw(ctx)
+	}
+	waitErr := rpc.Wait(ctx)
+	if shards := rpc.cfg().shards; shards != nil {
+		shards.stop()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.started = false
+	return errors.Join(withdrawErr, waitErr, h.stop(ctx))
+}
+
+// Stop the services started so far and return the error the start failed with
+func (h *serviceHooks) abortStart(ctx context.Context, err error) error {
+	if stopErr := h.stop(ctx); stopErr != nil {
+		return errors.Join(err, stopErr)
+	}
+
+	return err
+}
+
+// Track the hooks of a registered service, starting it at once when the server is started. The service registered
+// before under the name is no longer tracked and is returned, to be stopped once requests no longer reach it
+func (h *serviceHooks) add(ctx context.Context, name string, srv any) (*hookedService, error) {
+	_, starts := srv.(Starter)
+	_, stops := srv.(Stopper)
+	if h == nil {
+		//Servers built without NewJsonRpc track no hooks
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	service := hookedService{name: name, srv: srv}
+	if (starts || stops) && h.started {
+		if err := service.start(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	replaced := h.remove(name)
+	if starts || stops {
+		h.services = append(h.services, service)
+	}
+
+	return replaced, nil
+}
+
+// Stop tracking the hooks of an unregistered service. The service is returned, to be stopped
+func (h *serviceHooks) unregister(name string) *hookedService {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.remove(name)
+}
+
+// Stop tracking the service registered under the name. The service is returned, nil when it has no hooks. Called
+// with the lock held
+func (h *serviceHooks) remove(name string) *hookedService {
+	for i := range h.services {
+		if h.services[i].name == name {
+			removed := h.services[i]
+			h.services = append(h.services[:i], h.services[i+1:]...)
+			return &removed
+		}
+	}
+
+	return nil
+}
+
+// Stop the running services in the reverse order of their registration. Called with the lock held
+func (h *serviceHooks) stop(ctx context.Context) error {
+	var errs []error
+	for i := len(h.services) - 1; i >= 0; i-- {
+		if err := h.services[i].stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stop the service of an unregistered or replaced name, if it runs
+func (rpc *jsonRpcImpl) stopRemovedService(service *hookedService) {
+	if service == nil {
+		return
+	}
+
+	if err := service.stop(context.Background()); err != nil {
+		rpc.cfg().logger.Printf("%v", err)
+	}
+}
+
+func (s *hookedService) start(ctx context.Context) error {
+	if starter, ok := s.srv.(Starter); ok {
+		if err := starter.Start(ctx); err != nil {
+			return errors.New(fmt.Sprintf("Starting service %s failed: %v", s.name, err))
+		}
+	}
+	s.running = true
+
+	return nil
+}
+
+func (s *hookedService) stop(ctx context.Context) error {
+	if !s.running {
+		return nil
+	}
+	s.running = false
+
+	if stopper, ok := s.srv.(Stopper); ok {
+		if err := stopper.Stop(ctx); err != nil {
+			return errors.New(fmt.Sprintf("Stopping service %s failed: %v", s.name, err))
+		}
+	}
+
+	return nil
+}
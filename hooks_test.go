@@ -0,0 +1,131 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	//Service recording its hooks in a shared log
+	warehouse struct {
+		name    string
+		log     *[]string
+		failing bool
+	}
+
+	//Service with a Stop hook only
+	mailer struct {
+		log *[]string
+	}
+)
+
+func (w *warehouse) Start(ctx context.Context) error {
+	if w.failing {
+		return errors.New("no connection")
+	}
+	*w.log = append(*w.log, "start "+w.name)
+	return nil
+}
+
+func (w *warehouse) Stop(ctx context.Context) error {
+	*w.log = append(*w.log, "stop "+w.name)
+	return nil
+}
+
+func (w *warehouse) Stock(ctx context.Context, item string) (int, error, *RpcErrorCode) {
+	return 1, nil, nil
+}
+
+func (m mailer) Stop(ctx context.Context) error {
+	*m.log = append(*m.log, "stop mailer")
+	return errors.New("queue not flushed")
+}
+
+func (m mailer) Send(ctx context.Context, to string) (bool, error, *RpcErrorCode) {
+	return true, nil, nil
+}
+
+func TestServiceHooks(t *testing.T) {
+	var log []string
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "a", log: &log}, "A"))
+	assert.Nil(t, rpc.RegisterWithName(mailer{log: &log}, "Mailer"))
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "b", log: &log}, "B"))
+
+	assert.Nil(t, rpc.Start(context.Background()))
+	assert.Nil(t, rpc.Start(context.Background()))
+
+	//Services registered on a started server start at once
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "c", log: &log}, "C"))
+	assert.Equal(t, []string{"start a", "start b", "start c"}, log)
+
+	//Hooks are not methods
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "A.Start", nil).Error.Code)
+
+	log = nil
+	err := rpc.Shutdown(context.Background())
+	assert.Equal(t, "Stopping service Mailer failed: queue not flushed", err.Error())
+	assert.Equal(t, []string{"stop c", "stop b", "stop mailer", "stop a"}, log)
+
+	//Stopped services are not stopped twice
+	log = nil
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "d", log: &log}, "D"))
+	assert.Nil(t, rpc.Shutdown(context.Background()))
+	assert.Empty(t, log)
+}
+
+func TestServiceHooksStartFailure(t *testing.T) {
+	var log []string
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(&warehouse{name: "a", log: &log}, "A")
+	rpc.RegisterWithName(&warehouse{name: "b", log: &log, failing: true}, "B")
+
+	err := rpc.Start(context.Background())
+	assert.Equal(t, "Starting service B failed: no connection", err.Error())
+	assert.Equal(t, []string{"start a", "stop a"}, log)
+
+	//Serve does not accept connections when the services do not start
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, err.Error(), rpc.Serve(l).Error())
+
+	//Registering on a started server fails when the service does not start
+	rpc = NewJsonRpc()
+	assert.Nil(t, rpc.Start(context.Background()))
+	err = rpc.RegisterWithName(&warehouse{name: "b", log: &log, failing: true}, "B")
+	assert.Equal(t, "Starting service B failed: no connection", err.Error())
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "B.Stock", []any{"pen"}).Error.Code)
+}
+
+func TestServiceHooksUnregister(t *testing.T) {
+	var log []string
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "a", log: &log}, "A"))
+	assert.Nil(t, rpc.Start(context.Background()))
+
+	//Unregistered services are stopped at once
+	assert.True(t, rpc.Unregister("A"))
+	assert.Equal(t, []string{"start a", "stop a"}, log)
+
+	//Registering the name again starts the new service only
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "a2", log: &log}, "A"))
+	assert.Equal(t, []string{"start a", "stop a", "start a2"}, log)
+
+	//Replaced services are stopped once the new one started
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "a3", log: &log}, "A"))
+	assert.Equal(t, []string{"start a", "stop a", "start a2", "start a3", "stop a2"}, log)
+
+	//Services are stopped once
+	assert.Nil(t, rpc.Shutdown(context.Background()))
+	assert.Equal(t, []string{"start a", "stop a", "start a2", "start a3", "stop a2", "stop a3"}, log)
+
+	//Services not started are not stopped
+	log = nil
+	assert.Nil(t, rpc.RegisterWithName(&warehouse{name: "b", log: &log}, "B"))
+	assert.True(t, rpc.Unregister("B"))
+	assert.Nil(t, rpc.Start(context.Background()))
+	assert.Equal(t, []string{"start a3"}, log)
+}
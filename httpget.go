@@ -0,0 +1,162 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type (
+	//CacheHeaders are the HTTP caching headers of the response of a GET request, set with SetCacheHeaders
+	CacheHeaders struct {
+		CacheControl string //eg. public, max-age=60
+		ETag         string //Quoted entity tag, eg. "v42" or W/"v42". Requests with a matching If-None-Match get a 304
+	}
+
+	//Cache headers set by the handler and middleware of a GET request
+	cacheHeaders struct {
+		mu      sync.Mutex
+		headers CacheHeaders
+	}
+
+	cacheHeadersKey struct{}
+
+	//Request object encoded from the query of a GET request
+	getRequest struct {
+		Jsonrpc string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params,omitempty"`
+		Id      *string         `json:"id,omitempty"`
+	}
+)
+
+// WithHTTPGet serves calls of the methods matching the patterns, eg. Catalog.Get or Reports.*, over HTTP GET, so
+// proxies and browsers can cache the answers of read-only methods. The request is in the query: method, params as
+// URL-encoded JSON, and id. Without an id the call is a notification. Calls of other methods are answered with
+// 405. Handlers and middleware set the caching headers of the response with SetCacheHeaders.
+func WithHTTPGet(patterns ...string) Option {
+	return func(c *config) {
+		c.getMethods = append([]string{}, patterns...)
+	}
+}
+
+// SetCacheHeaders sets the Cache-Control and ETag headers of the response, eg. in a handler of a read-only method or
+// in a middleware. Empty fields keep the values set before. The headers are only sent on successful responses of
+// GET requests served by WithHTTPGet, and ignored otherwise.
+func SetCacheHeaders(ctx context.Context, headers CacheHeaders) {
+	c, ok := ctx.Value(cacheHeadersKey{}).(*cacheHeaders)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if headers.CacheControl != "" {
+		c.headers.CacheControl = headers.CacheControl
+	}
+	if headers.ETag != "" {
+		c.headers.ETag = headers.ETag
+	}
+}
+
+// Whether the request is a call made with GET, served by WithHTTPGet
+func (c *config) isGETCall(r *http.Request) bool {
+	return c.getMethods != nil && r.Method == http.MethodGet && r.URL.Query().Has("method")
+}
+
+// Serve a call made with GET. False is returned when the request is not one. Its response goes through the
+// checksums and compressions of POST requests, as it is served from the same handler
+func (s *jsonRpcImpl) serveGET(w http.ResponseWriter, r *http.Request) bool {
+	cfg := s.cfg()
+	if !cfg.isGETCall(r) {
+		return false
+	}
+
+	req, err := s.readGetRequest(r)
+	if err != nil {
+		s.writeErrorResponse(w, err, decodeErrorCode(err), nil, nil)
+		return true
+	}
+	if !matchesMethod(cfg.getMethods, req.Method) {
+		res := makeErrorResponse(errors.New(fmt.Sprintf("Method %s is called with POST", req.Method)), INVALID_REQUEST, nil, req.Id)
+		w.Header().Set("Allow", http.MethodPost)
+		w.Header().Set("Content-Type", JSON_MEDIA_TYPE)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		cfg.writeJSON(w, &res, cfg.indentResponses)
+		return true
+	}
+
+	ctx, cancel, deprecations, timeoutErr := s.httpContext(r)
+	defer cancel()
+	if timeoutErr != nil {
+		s.writeErrorResponse(w, timeoutErr, INVALID_REQUEST, nil, nil)
+		return true
+	}
+
+	cache := &cacheHeaders{}
+	res := s.handleSingleRequest(context.WithValue(ctx, cacheHeadersKey{}, cache), *req)
+	deprecations.writeHeaders(w.Header())
+
+	if res.Error == nil && !req.isNotification() && cache.writeHeaders(w.Header(), r.Header.Get("If-None-Match")) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	s.writeResponse(w, res, req.isNotification())
+	return true
+}
+
+// Decode the request in the query like the body of a POST request
+func (s *jsonRpcImpl) readGetRequest(r *http.Request) (*Request, error) {
+	query := r.URL.Query()
+	encoded := getRequest{Jsonrpc: RPC_VERSION, Method: query.Get("method")}
+	if params := query.Get("params"); params != "" {
+		if !json.Valid([]byte(params)) {
+			return nil, errors.New("Unable to decode params of the query")
+		}
+		encoded.Params = json.RawMessage(params)
+	}
+	if query.Has("id") {
+		id := query.Get("id")
+		encoded.Id = &id
+	}
+
+	body, _ := json.Marshal(encoded)
+	req, _, err := s.decodeRequest(body)
+	return req, err
+}
+
+// Add the headers set by the call. True when the entity tag matches the one the client has cached
+func (c *cacheHeaders) writeHeaders(header http.Header, ifNoneMatch string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.headers.CacheControl != "" {
+		header.Set("Cache-Control", c.headers.CacheControl)
+	}
+	if c.headers.ETag == "" {
+		return false
+	}
+	header.Set("ETag", c.headers.ETag)
+
+	return etagMatches(ifNoneMatch, c.headers.ETag)
+}
+
+// Weak comparison of the entity tag with the ones of an If-None-Match header, as caches revalidate with it
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,141 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Read-only method setting the caching headers of its answers
+type priceList struct{}
+
+func (priceList) Price(ctx context.Context, sku string) (int, error, *RpcErrorCode) {
+	SetCacheHeaders(ctx, CacheHeaders{ETag: `"prices-v1"`})
+	return len(sku) * 100, nil, nil
+}
+
+func serveTestGET(rpc JsonRPC, query url.Values, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	rpc.ServeHTTP(recorder, r)
+
+	return recorder
+}
+
+func TestHTTPGet(t *testing.T) {
+	rpc := NewJsonRpc(WithHTTPGet("Arith.*"))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	res := serveTestGET(rpc, url.Values{"method": {"Arith.Add"}, "params": {"[1,2]"}, "id": {"1"}}, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":3}`, res.Body.String())
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	//Nothing is cached unless the handler says so
+	assert.Empty(t, res.Header().Get("Cache-Control"))
+
+	res = serveTestGET(rpc, url.Values{"method": {"Arith.Add"}, "params": {"[1,2]"}}, nil)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+
+	res = serveTestGET(rpc, url.Values{"method": {"Arith.Add"}, "params": {"[1,"}, "id": {"1"}}, nil)
+	assert.Equal(t, PARSE_ERROR, decodeTestResponse(t, res.Body.String()).Error.Code)
+}
+
+func TestHTTPGetOtherMethods(t *testing.T) {
+	rpc := NewJsonRpc(WithHTTPGet("Arith.Add"))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	res := serveTestGET(rpc, url.Values{"method": {"Arith.ErrorMethod"}, "id": {"1"}}, nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+	assert.Equal(t, http.MethodPost, res.Header().Get("Allow"))
+	assert.Equal(t, "Method Arith.ErrorMethod is called with POST", decodeTestResponse(t, res.Body.String()).Error.Message)
+
+	//Without WithHTTPGet, GET requests are not calls
+	res = serveTestGET(newTestArithRpc(), url.Values{"method": {"Arith.Add"}, "params": {"[1,2]"}, "id": {"1"}}, nil)
+	assert.Equal(t, PARSE_ERROR, decodeTestResponse(t, res.Body.String()).Error.Code)
+}
+
+func TestCacheHeaders(t *testing.T) {
+	rpc := NewJsonRpc(WithHTTPGet("Prices.*"), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			SetCacheHeaders(ctx, CacheHeaders{CacheControl: "public, max-age=60"})
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(priceList{}, "Prices")
+	query := url.Values{"method": {"Prices.Price"}, "params": {`["abc"]`}, "id": {"1"}}
+
+	res := serveTestGET(rpc, query, nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=60", res.Header().Get("Cache-Control"))
+	assert.Equal(t, `"prices-v1"`, res.Header().Get("ETag"))
+
+	res = serveTestGET(rpc, query, http.Header{"If-None-Match": {`"prices-v0", W/"prices-v1"`}})
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Equal(t, `"prices-v1"`, res.Header().Get("ETag"))
+
+	//Errors are not cached
+	res = serveTestGET(rpc, url.Values{"method": {"Prices.Price"}, "params": {`[1]`}, "id": {"1"}}, nil)
+	assert.NotNil(t, decodeTestResponse(t, res.Body.String()).Error)
+	assert.Empty(t, res.Header().Get("Cache-Control"))
+
+	//POST answers have no caching headers
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Prices.Price","params":["abc"]}`)
+	assert.Equal(t, float64(300), *decodeTestResponse(t, body).Result)
+}
+
+func TestHTTPGetChecksumsAndCompression(t *testing.T) {
+	rpc := NewJsonRpc(WithHTTPGet("Prices.*"), WithChecksums(true), WithCodecNegotiation(nil))
+	rpc.RegisterWithName(priceList{}, "Prices")
+	query := url.Values{"method": {"Prices.Price"}, "params": {`["abc"]`}, "id": {"1"}}
+
+	//GET requests have no body, so they need no checksum even when checksums are required
+	res := serveTestGET(rpc, query, http.Header{"Accept-Encoding": {"gzip"}})
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.Equal(t, COMPRESSION_GZIP, res.Header().Get("Content-Encoding"))
+	assert.Equal(t, `"prices-v1"`, res.Header().Get("ETag"))
+	assert.Equal(t, checksumOf(res.Body.Bytes()), res.Header().Get(CHECKSUM_HEADER))
+
+	body, err := decompress(gzipCompression{}, res.Body.Bytes())
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":300}`, string(body))
+
+	res = serveTestGET(rpc, query, http.Header{"Accept-Encoding": {"gzip"}, "If-None-Match": {`"prices-v1"`}})
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+	assert.Empty(t, res.Header().Get(CHECKSUM_HEADER))
+}
+
+func TestWriteResponseHeaders(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`,
+		`[{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}]`,
+		`{"jsonrpc":"2.0","id":"1","method"`,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		rpc.ServeHTTP(w, r)
+		assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"), body)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a"`, `"a"`))
+	assert.True(t, etagMatches(`W/"a"`, `"a"`))
+	assert.True(t, etagMatches(`"b", "a"`, `W/"a"`))
+	assert.True(t, etagMatches("*", `"a"`))
+	assert.False(t, etagMatches(`"b"`, `"a"`))
+	assert.False(t, etagMatches("", `"a"`))
+}
@@ -0,0 +1,81 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+type (
+	//HTTPPoolOptions tune the connections the HTTP transport keeps to servers. Zero values keep the defaults of
+	//http.DefaultTransport
+	HTTPPoolOptions struct {
+		MaxIdleConns        int           //Idle connections kept across every server
+		MaxIdleConnsPerHost int           //Idle connections kept per server. Raise it for high rates of calls to one server
+		MaxConnsPerHost     int           //Connections per server, dialing, active and idle. Unlimited when zero
+		IdleConnTimeout     time.Duration //How long an idle connection is kept
+		KeepAlive           time.Duration //Interval of the TCP keep-alive probes. Negative disables them
+		DisableKeepAlives   bool          //Use a connection for a single call
+
+		DialTimeout           time.Duration //Time to connect to a server
+		ResponseHeaderTimeout time.Duration //Time to wait for the response headers once the call is sent. Unlimited when zero
+	}
+
+	transportKey struct{}
+)
+
+// NewHTTPPool builds an HTTP client whose connections are pooled as the options say
+func NewHTTPPool(opts HTTPPoolOptions) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if opts.DialTimeout > 0 {
+		dialer.Timeout = opts.DialTimeout
+	}
+	if opts.KeepAlive != 0 {
+		dialer.KeepAlive = opts.KeepAlive
+	}
+	transport.DialContext = dialer.DialContext
+
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	transport.MaxConnsPerHost = opts.MaxConnsPerHost
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+
+	return &http.Client{Transport: transport}
+}
+
+// WithHTTPPool pools the connections of a client created by NewHTTPClient as the options say. The idle connections
+// are closed with the client. Other transports are left as they are.
+func WithHTTPPool(opts HTTPPoolOptions) ClientOption {
+	return func(c *Client) {
+		if t, ok := c.transport.(*HTTPTransport); ok {
+			t.Client = NewHTTPPool(opts)
+			t.pooled = true
+		}
+	}
+}
+
+// ContextWithTransport returns a context that sends the call over the transport instead of the transport of the
+// client, eg. a transport with its own pool for bulk calls. Client middleware run as usual.
+func ContextWithTransport(ctx context.Context, transport ClientTransport) context.Context {
+	return context.WithValue(ctx, transportKey{}, transport)
+}
+
+// Transport the call is sent over
+func (c *Client) transportFor(ctx context.Context) ClientTransport {
+	if transport, ok := ctx.Value(transportKey{}).(ClientTransport); ok {
+		return transport
+	}
+
+	return c.transport
+}
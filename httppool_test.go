@@ -0,0 +1,85 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Server counting the connections it accepts
+func newCountingHTTPServer(t *testing.T, handler http.Handler) (string, *atomic.Int64) {
+	var conns atomic.Int64
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return server.URL, &conns
+}
+
+func TestNewHTTPPool(t *testing.T) {
+	client := NewHTTPPool(HTTPPoolOptions{MaxIdleConns: 10, MaxIdleConnsPerHost: 5, IdleConnTimeout: time.Second})
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(t, 10, transport.MaxIdleConns)
+	assert.Equal(t, 5, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Second, transport.IdleConnTimeout)
+	assert.Equal(t, 0, transport.MaxConnsPerHost)
+
+	//The default transport is not changed
+	assert.Equal(t, 0, http.DefaultTransport.(*http.Transport).MaxIdleConnsPerHost)
+}
+
+func TestHTTPPoolConnections(t *testing.T) {
+	url, conns := newCountingHTTPServer(t, newTestArithRpc())
+
+	client := NewHTTPClient(url, WithHTTPPool(HTTPPoolOptions{MaxConnsPerHost: 1}))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var sum int
+			assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), conns.Load())
+	assert.Nil(t, client.Close())
+
+	//Without keep-alives every call connects
+	url, conns = newCountingHTTPServer(t, newTestArithRpc())
+	client = NewHTTPClient(url, WithHTTPPool(HTTPPoolOptions{DisableKeepAlives: true}))
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, nil))
+	}
+	assert.Equal(t, int64(3), conns.Load())
+}
+
+func TestContextWithTransport(t *testing.T) {
+	url, conns := newCountingHTTPServer(t, newTestArithRpc())
+	bulkURL, bulkConns := newCountingHTTPServer(t, newTestArithRpc())
+
+	client := NewHTTPClient(url)
+	bulk := &HTTPTransport{URL: bulkURL, Client: NewHTTPPool(HTTPPoolOptions{MaxIdleConnsPerHost: 16})}
+
+	var sum int
+	ctx := ContextWithTransport(context.Background(), bulk)
+	assert.Nil(t, client.Call(ctx, "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, 3, sum)
+	assert.Equal(t, int64(0), conns.Load())
+	assert.Equal(t, int64(1), bulkConns.Load())
+
+	assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, int64(1), conns.Load())
+}
@@ -0,0 +1,78 @@
+package jsonrpc2
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ID of a request sent by a client
+type ID string
+
+// Crockford's base32 alphabet used by ULIDs
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// WithIDGenerator sets how the client generates the ids of its calls. Defaults to SequentialIDs
+func WithIDGenerator(generate func() ID) ClientOption {
+	return func(c *Client) {
+		c.generateID = generate
+	}
+}
+
+// SequentialIDs generates 1, 2, 3 and so on
+func SequentialIDs() func() ID {
+	var last uint64
+	return func() ID {
+		return ID(strconv.FormatUint(atomic.AddUint64(&last, 1), 10))
+	}
+}
+
+// UUIDs generates random version 4 UUIDs
+func UUIDs() func() ID {
+	return func() ID {
+		var b [16]byte
+		rand.Read(b[:])
+		b[6] = b[6]&0x0f | 0x40 //Version 4
+		b[8] = b[8]&0x3f | 0x80 //RFC 4122 variant
+
+		return ID(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+	}
+}
+
+// ULIDs generates ULIDs, which sort by the time they were generated
+func ULIDs() func() ID {
+	return func() ID {
+		var b [16]byte
+		ms := uint64(time.Now().UnixMilli())
+		binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+		binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+		rand.Read(b[6:])
+
+		return ID(encodeULID(b))
+	}
+}
+
+// PrefixedIDs prepends prefix to the ids of generate, eg. to tell apart the calls of pooled connections
+func PrefixedIDs(prefix string, generate func() ID) func() ID {
+	return func() ID {
+		return ID(prefix) + generate()
+	}
+}
+
+// Encode the 128 bits as 26 base32 characters, most significant first
+func encodeULID(b [16]byte) string {
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = ulidAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out)
+}
@@ -0,0 +1,55 @@
+package jsonrpc2
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequentialIDs(t *testing.T) {
+	next := SequentialIDs()
+
+	assert.Equal(t, ID("1"), next())
+	assert.Equal(t, ID("2"), next())
+}
+
+func TestUUIDs(t *testing.T) {
+	next := UUIDs()
+
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), string(next()))
+	assert.NotEqual(t, next(), next())
+}
+
+func TestULIDs(t *testing.T) {
+	next := ULIDs()
+
+	first := next()
+	assert.Regexp(t, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`), string(first))
+	assert.Equal(t, encodeULID([16]byte{}), "00000000000000000000000000")
+	assert.Equal(t, encodeULID([16]byte{15: 31}), "0000000000000000000000000Z")
+}
+
+func TestPrefixedIDs(t *testing.T) {
+	next := PrefixedIDs("conn-1/", SequentialIDs())
+
+	assert.Equal(t, ID("conn-1/1"), next())
+}
+
+func TestClientIDGenerator(t *testing.T) {
+	var ids []string
+	client := NewInProcessClient(newTestArithRpc(), WithIDGenerator(PrefixedIDs("worker-", SequentialIDs())))
+	client.Use(func(next Invoker) Invoker {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			ids = append(ids, *req.Id)
+			return next(ctx, req)
+		}
+	})
+
+	var sum int
+	assert.NoError(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.NoError(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+
+	assert.Equal(t, []string{"worker-1", "worker-2"}, ids)
+}
@@ -0,0 +1,114 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	//InFlightStats counts the go routines running handlers, eg. to export them as metrics or find leaks
+	InFlightStats struct {
+		Calls     int //Handlers running, including the abandoned ones
+		Abandoned int //Handlers still running after their request was answered, because it was cancelled or timed out
+	}
+
+	//Go routines running handlers
+	handlerTracker struct {
+		mu        sync.Mutex
+		running   int
+		abandoned int
+		idle      chan struct{} //Closed once no handler runs. Nil while none runs
+	}
+
+	//Handler tracked until it returns
+	trackedCall struct {
+		tracker   *handlerTracker
+		abandoned bool
+		finished  bool
+	}
+)
+
+// InFlight returns the number of handlers running. Handlers of cancelled or timed out requests keep running until
+// they return, so a growing number of abandoned handlers points to handlers ignoring their context.
+func (rpc *jsonRpcImpl) InFlight() InFlightStats {
+	return rpc.handlers.stats()
+}
+
+// Wait blocks until no handler runs, including the ones of requests already answered, or the context is done.
+// Call it after the transports stopped accepting requests, eg. after http.Server.Shutdown and Drain, so handlers
+// are not cut short when the process exits.
+func (rpc *jsonRpcImpl) Wait(ctx context.Context) error {
+	return rpc.handlers.wait(ctx)
+}
+
+func newHandlerTracker() *handlerTracker {
+	return &handlerTracker{}
+}
+
+// Track a handler about to start
+func (t *handlerTracker) start() *trackedCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.running == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.running++
+
+	return &trackedCall{tracker: t}
+}
+
+func (t *handlerTracker) stats() InFlightStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return InFlightStats{Calls: t.running, Abandoned: t.abandoned}
+}
+
+func (t *handlerTracker) wait(ctx context.Context) error {
+	t.mu.Lock()
+	idle := t.idle
+	t.mu.Unlock()
+
+	if idle == nil {
+		return nil
+	}
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Record that the request of the handler was answered without waiting for it
+func (c *trackedCall) abandon() {
+	t := c.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c.finished || c.abandoned {
+		return
+	}
+	c.abandoned = true
+	t.abandoned++
+}
+
+// Record that the handler returned
+func (c *trackedCall) finish() {
+	t := c.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c.finished = true
+	if c.abandoned {
+		t.abandoned--
+	}
+
+	t.running--
+	if t.running == 0 {
+		close(t.idle)
+		t.idle = nil
+	}
+}
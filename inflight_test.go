@@ -0,0 +1,60 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Handler that ignores its context until it is released
+type stubborn struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s stubborn) Work(ctx context.Context) (string, error, *RpcErrorCode) {
+	s.started <- struct{}{}
+	<-s.release
+	return "done", nil, nil
+}
+
+func TestInFlight(t *testing.T) {
+	s := stubborn{started: make(chan struct{}, 1), release: make(chan struct{})}
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(s, "Stubborn")
+
+	assert.Nil(t, rpc.Wait(context.Background()))
+
+	done := make(chan *Response)
+	go func() {
+		done <- callMethod(t, rpc, "Stubborn.Work", nil)
+	}()
+	<-s.started
+	assert.Equal(t, InFlightStats{Calls: 1}, rpc.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, rpc.Wait(ctx))
+
+	close(s.release)
+	assert.Equal(t, "done", *(<-done).Result)
+	assert.Nil(t, rpc.Wait(context.Background()))
+	assert.Equal(t, InFlightStats{}, rpc.InFlight())
+}
+
+func TestInFlightAbandoned(t *testing.T) {
+	s := stubborn{started: make(chan struct{}, 1), release: make(chan struct{})}
+	rpc := NewJsonRpc(WithTimeout(10 * time.Millisecond))
+	rpc.RegisterWithName(s, "Stubborn")
+
+	res := callMethod(t, rpc, "Stubborn.Work", nil)
+	assert.NotNil(t, res.Error)
+	<-s.started
+	assert.Equal(t, InFlightStats{Calls: 1, Abandoned: 1}, rpc.InFlight())
+
+	close(s.release)
+	assert.Nil(t, rpc.Wait(context.Background()))
+	assert.Equal(t, InFlightStats{}, rpc.InFlight())
+}
@@ -0,0 +1,91 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// WithDependencies supplies the values constructors passed to Register are called with, eg. a *sql.DB or a logger.
+// A param of a constructor receives the dependency of its type, or else the first dependency assignable to it, so
+// interfaces can be satisfied by concrete values.
+func WithDependencies(deps ...any) Option {
+	return func(c *config) {
+		c.dependencies = append(c.dependencies, deps...)
+	}
+}
+
+// Build the service when srv is a constructor, eg. func(db *sql.DB) *UserService or
+// func(db *sql.DB) (*UserService, error), by calling it with the dependencies of the server.
+// Other values are returned as is.
+func (rpc *jsonRpcImpl) construct(srv any) (any, error) {
+	ctor := reflect.ValueOf(srv)
+	if ctor.Kind() != reflect.Func {
+		return srv, nil
+	}
+
+	ctorType := ctor.Type()
+	if !isConstructor(ctorType) {
+		return nil, errors.New(fmt.Sprintf("Constructor %s must return a service, optionally followed by an error", ctorType))
+	}
+
+	var deps []any
+	if cfg := rpc.cfg(); cfg != nil {
+		deps = cfg.dependencies
+	}
+	args := make([]reflect.Value, ctorType.NumIn())
+	for i := range args {
+		dep, ok := findDependency(deps, ctorType.In(i))
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("No dependency of type %s for constructor %s", ctorType.In(i), ctorType))
+		}
+		args[i] = dep
+	}
+
+	out := ctor.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	if !out[0].IsValid() || (out[0].Kind() == reflect.Pointer && out[0].IsNil()) {
+		return nil, errors.New(fmt.Sprintf("Constructor %s returned no service", ctorType))
+	}
+
+	return out[0].Interface(), nil
+}
+
+func isConstructor(t reflect.Type) bool {
+	if t.IsVariadic() {
+		return false
+	}
+
+	switch t.NumOut() {
+	case 1:
+		return t.Out(0) != errorType
+	case 2:
+		return t.Out(0) != errorType && t.Out(1) == errorType
+	default:
+		return false
+	}
+}
+
+// Dependency of exactly the type, else the first one assignable to it
+func findDependency(deps []any, t reflect.Type) (reflect.Value, bool) {
+	var assignable reflect.Value
+	for _, dep := range deps {
+		if dep == nil {
+			continue
+		}
+
+		v := reflect.ValueOf(dep)
+		if v.Type() == t {
+			return v, true
+		}
+		if !assignable.IsValid() && v.Type().AssignableTo(t) {
+			assignable = v
+		}
+	}
+
+	return assignable, assignable.IsValid()
+}
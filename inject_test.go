@@ -0,0 +1,64 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	//Dependency of the greeter service
+	greeting struct {
+		word string
+	}
+
+	greeter struct {
+		greeting *greeting
+		logger   Logger
+	}
+)
+
+func (g *greeter) Greet(ctx context.Context, name string) (string, error, *RpcErrorCode) {
+	return g.greeting.word + " " + name, nil, nil
+}
+
+func newGreeter(g *greeting, logger Logger) *greeter {
+	return &greeter{greeting: g, logger: logger}
+}
+
+func TestRegisterConstructor(t *testing.T) {
+	logger := &testLogger{}
+	rpc := NewJsonRpc(WithDependencies(&greeting{word: "Hello"}, logger))
+
+	assert.Nil(t, rpc.Register(newGreeter))
+
+	res := callMethod(t, rpc, "greeter.Greet", []any{"ada"})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "Hello ada", *res.Result)
+}
+
+func TestRegisterConstructorInGroup(t *testing.T) {
+	rpc := NewJsonRpc(WithDependencies(&greeting{word: "Hi"}, &testLogger{}))
+
+	assert.Nil(t, rpc.Group("v1").RegisterWithName(newGreeter, "Greeter"))
+
+	res := callMethod(t, rpc, "v1.Greeter.Greet", []any{"ada"})
+	assert.Equal(t, "Hi ada", *res.Result)
+}
+
+func TestConstructorErrors(t *testing.T) {
+	rpc := NewJsonRpc(WithDependencies(&greeting{word: "Hello"}))
+
+	err := rpc.Register(newGreeter)
+	assert.Equal(t, "No dependency of type jsonrpc2.Logger for constructor func(*jsonrpc2.greeting, jsonrpc2.Logger) *jsonrpc2.greeter", err.Error())
+
+	err = rpc.Register(func(g *greeting) (*greeter, error) {
+		return nil, errors.New("Greetings are disabled")
+	})
+	assert.Equal(t, "Greetings are disabled", err.Error())
+
+	err = rpc.Register(func() error { return nil })
+	assert.Equal(t, "Constructor func() error must return a service, optionally followed by an error", err.Error())
+}
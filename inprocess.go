@@ -0,0 +1,32 @@
+package jsonrpc2
+
+import "context"
+
+// Transport handing messages directly to a server in the same process
+type inProcessTransport struct {
+	rpc      JsonRPC
+	inFlight *inFlightRequests
+}
+
+// NewInProcessClient creates a client calling the server directly, without encoding HTTP or opening sockets.
+// Calls go through the full dispatch path of the server, including middleware and interceptors.
+// Use it in unit tests or to embed a server in an application.
+func NewInProcessClient(rpc JsonRPC, opts ...ClientOption) *Client {
+	return NewClient(&inProcessTransport{rpc: rpc, inFlight: newInFlightRequests()}, opts...)
+}
+
+func (t *inProcessTransport) RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error) {
+	//Requests of the client can be cancelled with rpc.cancel like on a connection
+	ctx = withInFlightRequests(ctx, t.inFlight)
+
+	res := t.rpc.HandleMessage(ctx, msg)
+	if notification {
+		return nil, nil
+	}
+
+	return res, nil
+}
+
+func (t *inProcessTransport) Close() error {
+	return nil
+}
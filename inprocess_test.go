@@ -0,0 +1,42 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+type contextService struct{}
+
+func (contextService) Value(ctx context.Context) (string, error, *RpcErrorCode) {
+	value, _ := ctx.Value(ctxKey{}).(string)
+	return value, nil, nil
+}
+
+func TestInProcessClient(t *testing.T) {
+	client := NewInProcessClient(newTestArithRpc())
+	defer client.Close()
+
+	var sum int
+	assert.NoError(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, 3, sum)
+
+	err := client.Call(context.Background(), "Arith.ErrorMethod", nil, nil)
+	assert.Equal(t, "Some error here", err.Error())
+
+	assert.NoError(t, client.Notify(context.Background(), "Arith.Add", []any{1, 2}))
+}
+
+func TestInProcessClientPassesContext(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(contextService{}, "Ctx")
+	client := NewInProcessClient(rpc)
+
+	var value string
+	ctx := context.WithValue(context.Background(), ctxKey{}, "from caller")
+	assert.NoError(t, client.Call(ctx, "Ctx.Value", nil, &value))
+	assert.Equal(t, "from caller", value)
+}
@@ -0,0 +1,40 @@
+package jsonrpc2
+
+import "context"
+
+// A ResponseInterceptor runs on every response before it is serialized. It receives the method that was called
+// and either the result or the error of the call, and returns the result or error to send instead.
+// Returning a non nil error discards the result.
+type ResponseInterceptor func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError)
+
+// WithResponseInterceptor adds interceptors that rewrite results and errors of single and batch requests.
+// Interceptors run in the order they are added.
+func WithResponseInterceptor(interceptors ...ResponseInterceptor) Option {
+	return func(c *config) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+func (rpc *jsonRpcImpl) intercept(ctx context.Context, cfg *config, method string, res Response) Response {
+	interceptors := cfg.interceptors
+	if len(interceptors) == 0 {
+		return res
+	}
+
+	var result any
+	if res.Result != nil {
+		result = *res.Result
+	}
+
+	for _, interceptor := range interceptors {
+		result, res.Error = interceptor(ctx, method, result, res.Error)
+	}
+
+	if res.Error != nil {
+		res.Result = nil
+	} else {
+		res.Result = &result
+	}
+
+	return res
+}
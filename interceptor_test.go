@@ -0,0 +1,85 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseInterceptorRewritesErrors(t *testing.T) {
+	var seenMethod string
+	rpc := NewJsonRpc(WithResponseInterceptor(func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError) {
+		seenMethod = method
+		if err != nil {
+			err.Data = map[string]string{"traceId": "abc"}
+			err.Message = "Something went wrong"
+		}
+		return result, err
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Arith.ErrorMethod", Params: []any{}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "Arith.ErrorMethod", seenMethod)
+	assert.Equal(t, "Something went wrong", res.Error.Message)
+	assert.Equal(t, map[string]any{"traceId": "abc"}, res.Error.Data)
+}
+
+func TestResponseInterceptorUnwrapsCause(t *testing.T) {
+	var cause error
+	rpc := NewJsonRpc(WithResponseInterceptor(func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError) {
+		if err != nil {
+			cause = errors.Unwrap(err)
+		}
+		return result, err
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	id := "1"
+	if _, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Arith.ErrorMethod", Params: []any{}, Jsonrpc: RPC_VERSION}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.EqualError(t, cause, "Some error here")
+}
+
+func TestResponseInterceptorBatch(t *testing.T) {
+	rpc := NewJsonRpc(
+		WithResponseInterceptor(func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError) {
+			if n, ok := result.(int); ok {
+				return n * 10, err
+			}
+			return result, err
+		}),
+		WithResponseInterceptor(func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError) {
+			if method == "Arith.Unknown" {
+				return "fallback", nil
+			}
+			return result, err
+		}),
+	)
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	ids := []string{"1", "2"}
+	responses, err := makeRpcBatchTestRequest(rpc, []Request{
+		{Id: &ids[0], Method: "Arith.Add", Params: []any{1, 2}, Jsonrpc: RPC_VERSION},
+		{Id: &ids[1], Method: "Arith.Unknown", Params: []any{}, Jsonrpc: RPC_VERSION},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := map[string]any{}
+	for _, res := range responses {
+		assert.Nil(t, res.Error)
+		results[*res.Id] = *res.Result
+	}
+
+	assert.Equal(t, map[string]any{"1": float64(30), "2": "fallback"}, results)
+}
@@ -0,0 +1,239 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Name of the service that hosts the built-in methods. eg. rpc.listMethods
+const BUILTIN_SERVICE_NAME = "rpc"
+
+type (
+	//Documentation attached to a method at registration time
+	MethodDoc struct {
+		Description string          //Human readable description of what the method does
+		Params      []ParamDoc      //Documentation of the params in positional order, excluding the context
+		Result      string          //Human readable description of the result
+		Examples    []MethodExample //Calls showing how the method is used
+		Deprecated  bool            //Calls still work but callers are warned
+		Since       string          //Version the method was added in
+		ReplacedBy  string          //Full name of the method to use instead of a deprecated one
+	}
+
+	//Documentation of a param of a method
+	ParamDoc struct {
+		Name        string `json:"name"` //Defaults to the name given in ServiceOptions.ParamNames
+		Description string `json:"description,omitempty"`
+	}
+
+	//Example call of a method, with its params in positional order
+	MethodExample struct {
+		Name   string `json:"name,omitempty"`
+		Params []any  `json:"params"`
+		Result any    `json:"result,omitempty"`
+	}
+
+	//Options used when registering a service with RegisterWithOptions
+	ServiceOptions struct {
+		Name string               //Name the service is registered under. Defaults to the type name of the service
+		Docs map[string]MethodDoc //Documentation of the service methods keyed by Go or exposed method name
+
+		Middleware  []Middleware //Middleware run around the calls of this service only, after the server middleware
+		MethodNamer MethodNamer  //Names the methods of this service. Defaults to the namer of the server
+		Methods     []string     //Go names of the only methods exposed. Every valid method when empty
+
+		NamespaceEmbedded bool //Expose the methods of embedded structs under <service>.<field name> instead of the service
+
+		//Names of the params of methods, excluding the context, keyed by Go or exposed method name. Methods with names
+		//are called with params by position or by name. Methods taking a single struct are called by name without them
+		ParamNames map[string][]string
+	}
+
+	//Description of a registered method returned by rpc.describe
+	MethodDescription struct {
+		Name        string   `json:"name"`                  //Full method name. eg. Arith.Add
+		Params      []string `json:"params"`                //Kinds of the positional params, excluding the context
+		Result      string   `json:"result"`                //Kind of the result
+		Description string   `json:"description,omitempty"` //Description taken from the registration options
+		Deprecated  bool     `json:"deprecated,omitempty"`
+		Since       string   `json:"since,omitempty"`
+		ReplacedBy  string   `json:"replacedBy,omitempty"`
+
+		ParamDocs         []ParamDoc      `json:"paramDocs,omitempty"`         //Names and descriptions of the params when they are documented or named
+		ResultDescription string          `json:"resultDescription,omitempty"` //Description of the result
+		Examples          []MethodExample `json:"examples,omitempty"`
+	}
+
+	//Description of a registered service returned by rpc.describe
+	ServiceDescription struct {
+		Name    string              `json:"name"`
+		Methods []MethodDescription `json:"methods"`
+	}
+
+	//Implements the built-in introspection methods
+	introspection struct {
+		rpc *jsonRpcImpl
+	}
+)
+
+// DisableIntrospection removes the built-in rpc.listMethods, rpc.describe, rpc.stats, rpc.connections and rpc.discover
+// methods from the server
+func DisableIntrospection() Option {
+	return func(c *config) {
+		c.disableIntrospection = true
+	}
+}
+
+// Register the built-in service. It is not exposed through the usual registration since its method names
+// do not follow the exported Go method names.
+func (rpc *jsonRpcImpl) registerBuiltins() {
+	builtins := &service{
+		name:    BUILTIN_SERVICE_NAME,
+		methods: make(map[string]reflect.Value),
+	}
+
+	if !rpc.config.disableIntrospection {
+		i := introspection{rpc: rpc}
+		builtins.methods["listMethods"] = reflect.ValueOf(i.ListMethods)
+		builtins.methods["describe"] = reflect.ValueOf(i.Describe)
+		rpc.registerStatsMethod(builtins)
+		rpc.registerConnectionsMethod(builtins)
+		rpc.registerDiscoverMethod(builtins)
+	}
+	rpc.registerJobMethods(builtins)
+	rpc.registerCancelMethod(builtins)
+	rpc.registerSubscriptionMethods(builtins)
+	rpc.registerPingMethod(builtins)
+	rpc.registerNegotiateMethod(builtins)
+	rpc.registerUsageMethod(builtins)
+
+	rpc.updateServices(func(services serviceMap) {
+		services[BUILTIN_SERVICE_NAME] = builtins
+	})
+}
+
+// ListMethods returns the full names of every method registered on the server
+func (i introspection) ListMethods(ctx context.Context) ([]string, error, *RpcErrorCode) {
+	if err := i.rpc.resolveAllServices(ctx); err != nil {
+		code := INTERNAL_ERROR
+		return nil, err, &code
+	}
+
+	methods := make([]string, 0)
+	for _, srv := range i.rpc.registeredServices() {
+		for name := range srv.methods {
+			methods = append(methods, srv.fullName(name))
+		}
+	}
+	sort.Strings(methods)
+
+	return methods, nil, nil
+}
+
+// Describe returns the description of the named services, or of every registered service when no name is given
+func (i introspection) Describe(ctx context.Context, names ...string) ([]ServiceDescription, error, *RpcErrorCode) {
+	if len(names) == 0 {
+		if err := i.rpc.resolveAllServices(ctx); err != nil {
+			code := INTERNAL_ERROR
+			return nil, err, &code
+		}
+
+		services := i.rpc.registeredServices()
+		descriptions := make([]ServiceDescription, 0, len(services))
+		for _, srv := range services {
+			descriptions = append(descriptions, srv.describe())
+		}
+
+		return descriptions, nil, nil
+	}
+
+	descriptions := make([]ServiceDescription, 0, len(names))
+	for _, name := range names {
+		srv, err := i.rpc.resolveService(ctx, name)
+		if err != nil {
+			code := INTERNAL_ERROR
+			return nil, err, &code
+		}
+		if srv == nil || name == BUILTIN_SERVICE_NAME {
+			code := INVALID_PARAMS
+			return nil, errors.New(fmt.Sprintf("Service %s is not registered", name)), &code
+		}
+		descriptions = append(descriptions, srv.describe())
+	}
+
+	return descriptions, nil, nil
+}
+
+func (s *service) describe() ServiceDescription {
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	desc := ServiceDescription{
+		Name:    s.name,
+		Methods: make([]MethodDescription, 0, len(names)),
+	}
+
+	for _, name := range names {
+		methodType := s.methods[name].Type()
+
+		params := make([]string, 0)
+		//First param is the context
+		for p := 1; p < methodType.NumIn(); p++ {
+			params = append(params, methodType.In(p).Kind().String())
+		}
+
+		desc.Methods = append(desc.Methods, MethodDescription{
+			Name:              s.fullName(name),
+			Params:            params,
+			Result:            methodType.Out(0).Kind().String(),
+			Description:       s.docs[name].Description,
+			Deprecated:        s.docs[name].Deprecated,
+			Since:             s.docs[name].Since,
+			ReplacedBy:        s.docs[name].ReplacedBy,
+			ParamDocs:         s.paramDocs(name, len(params)),
+			ResultDescription: s.docs[name].Result,
+			Examples:          s.docs[name].Examples,
+		})
+	}
+
+	return desc
+}
+
+// Documentation of the n params of the method, named after ServiceOptions.ParamNames or by position when the
+// documentation does not name them. Nil when the params are neither documented nor named
+func (s *service) paramDocs(method string, n int) []ParamDoc {
+	docs, names := s.docs[method].Params, s.paramNames[method]
+	if len(docs) == 0 && len(names) == 0 {
+		return nil
+	}
+
+	params := make([]ParamDoc, n)
+	for i := range params {
+		if i < len(docs) {
+			params[i] = docs[i]
+		}
+		if params[i].Name == "" && i < len(names) {
+			params[i].Name = names[i]
+		}
+		if params[i].Name == "" {
+			params[i].Name = fmt.Sprintf("param%d", i+1)
+		}
+	}
+
+	return params
+}
+
+// Check the documentation does not describe more params than the method takes, excluding the context
+func checkParamDocs(method string, methodType reflect.Type, doc MethodDoc) error {
+	if expected := methodType.NumIn() - 1; len(doc.Params) > expected {
+		return errors.New(fmt.Sprintf("Method %s takes %d params but %d are documented", method, expected, len(doc.Params)))
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMethods(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "rpc.listMethods", Params: []any{}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, []any{"Arith.Add", "Arith.ErrorMethod"}, *res.Result)
+}
+
+func TestDescribe(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithOptions(arith{}, ServiceOptions{
+		Name: "Arith",
+		Docs: map[string]MethodDoc{"Add": {Description: "Adds two numbers"}},
+	})
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "rpc.describe", Params: []any{"Arith"}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := json.Marshal(*res.Result)
+	var services []ServiceDescription
+	if err := json.Unmarshal(raw, &services); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, services, 1)
+	assert.Equal(t, "Arith", services[0].Name)
+	assert.Equal(t, MethodDescription{
+		Name:        "Arith.Add",
+		Params:      []string{"float64", "float64"},
+		Result:      "int",
+		Description: "Adds two numbers",
+	}, services[0].Methods[0])
+}
+
+func TestDescribeUnknownService(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "rpc.describe", Params: []any{"Unknown"}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+}
+
+func TestDisableIntrospection(t *testing.T) {
+	rpc := NewJsonRpc(DisableIntrospection())
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "rpc.listMethods", Params: []any{}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+}
+
+func TestRegisterReservedName(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Error(t, rpc.RegisterWithName(arith{}, BUILTIN_SERVICE_NAME))
+}
+
+func TestDescribeParamDocs(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithOptions(arith{}, ServiceOptions{
+		Name:       "Arith",
+		ParamNames: map[string][]string{"Add": {"a", "b"}},
+		Docs: map[string]MethodDoc{"Add": {
+			Description: "Adds two numbers",
+			Params:      []ParamDoc{{Description: "First term"}, {Name: "addend", Description: "Second term"}},
+			Result:      "Sum of the terms",
+			Examples:    []MethodExample{{Name: "small", Params: []any{1, 2}, Result: 3}},
+		}},
+	})
+
+	res := callMethod(t, rpc, "rpc.describe", []any{"Arith"})
+	raw, _ := json.Marshal(*res.Result)
+	var services []ServiceDescription
+	assert.Nil(t, json.Unmarshal(raw, &services))
+
+	add := services[0].Methods[0]
+	assert.Equal(t, []ParamDoc{{Name: "a", Description: "First term"}, {Name: "addend", Description: "Second term"}}, add.ParamDocs)
+	assert.Equal(t, "Sum of the terms", add.ResultDescription)
+	assert.Equal(t, []MethodExample{{Name: "small", Params: []any{float64(1), float64(2)}, Result: float64(3)}}, add.Examples)
+
+	//Methods without documentation or names have no param docs
+	assert.Nil(t, services[0].Methods[1].ParamDocs)
+}
+
+func TestRegisterTooManyParamDocs(t *testing.T) {
+	rpc := NewJsonRpc()
+	err := rpc.RegisterWithOptions(arith{}, ServiceOptions{
+		Name: "Arith",
+		Docs: map[string]MethodDoc{"Add": {Params: []ParamDoc{{Name: "a"}, {Name: "b"}, {Name: "c"}}}},
+	})
+
+	assert.EqualError(t, err, "Method Arith.Add takes 2 params but 3 are documented")
+}
@@ -0,0 +1,185 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type (
+	//IPFilterOptions list the networks allowed to call the server, as CIDRs such as 10.0.0.0/8 or single addresses
+	IPFilterOptions struct {
+		Allow []string //Networks allowed to call. Every address is allowed when empty
+		Deny  []string //Networks refused even when they are allowed
+
+		//Proxies whose X-Forwarded-For and X-Real-IP headers are trusted to name the client. The headers of other
+		//peers are ignored, so clients can not spoof their address
+		TrustedProxies []string
+	}
+
+	//IPFilter refuses callers by network address before their requests are read
+	IPFilter struct {
+		allow   []netip.Prefix
+		deny    []netip.Prefix
+		proxies []netip.Prefix
+	}
+)
+
+// NewIPFilter parses the networks of the options
+func NewIPFilter(opts IPFilterOptions) (*IPFilter, error) {
+	f := &IPFilter{}
+	var err error
+	if f.allow, err = parsePrefixes(opts.Allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = parsePrefixes(opts.Deny); err != nil {
+		return nil, err
+	}
+	if f.proxies, err = parsePrefixes(opts.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// WithIPFilter refuses the callers the filter does not allow. HTTP requests are answered with 403 Forbidden and an
+// UNAUTHORIZED error before their body is read, and persistent connections are closed as they are served. Behind
+// trusted proxies, the client named by the proxies is filtered and is the address of RemoteAddrFromContext.
+func WithIPFilter(filter *IPFilter) Option {
+	return func(c *config) {
+		c.ipFilter = filter
+	}
+}
+
+// Allowed reports whether the address may call the server
+func (f *IPFilter) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if containsAddr(f.deny, addr) {
+		return false
+	}
+
+	return len(f.allow) == 0 || containsAddr(f.allow, addr)
+}
+
+// ClientAddr returns the address of the client that sent the request. It is the peer of the connection unless the
+// peer is a trusted proxy, in which case it is the last address of X-Forwarded-For that is not a trusted proxy, or
+// X-Real-IP when X-Forwarded-For is missing.
+func (f *IPFilter) ClientAddr(r *http.Request) (netip.Addr, error) {
+	peer, err := parseRemoteAddr(r.RemoteAddr)
+	if err != nil || !containsAddr(f.proxies, peer) {
+		return peer, err
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return realIP.Unmap(), nil
+		}
+		return peer, nil
+	}
+
+	//Walk back from the proxy closest to the server, since only trusted proxies append reliable addresses
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, errors.New(fmt.Sprintf("Invalid X-Forwarded-For address %q", strings.TrimSpace(hops[i])))
+		}
+
+		client = hop.Unmap()
+		if !containsAddr(f.proxies, client) {
+			break
+		}
+	}
+
+	return client, nil
+}
+
+// Refuse the HTTP request when its client is not allowed. It returns whether the request was answered
+func (f *IPFilter) refuseRequest(w http.ResponseWriter, r *http.Request) bool {
+	addr, err := f.ClientAddr(r)
+	if err == nil && f.Allowed(addr) {
+		return false
+	}
+	if err == nil {
+		err = errors.New(fmt.Sprintf("Address %s is not allowed", addr))
+	}
+
+	res := makeErrorResponse(err, UNAUTHORIZED, nil, nil)
+	body, _ := json.Marshal(res)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	w.Write(body)
+
+	return true
+}
+
+// Whether the peer of the connection is allowed. Connections do not carry forwarding headers, and the ones without
+// an IP address, eg. over unix sockets, are refused
+func (f *IPFilter) allowsConn(conn net.Conn) bool {
+	addr, err := parseRemoteAddr(conn.RemoteAddr().String())
+	return err == nil && f.Allowed(addr)
+}
+
+// Address of the client of the HTTP request as seen by handlers
+func (c *config) remoteAddr(r *http.Request) string {
+	if c.ipFilter == nil || len(c.ipFilter.proxies) == 0 {
+		return r.RemoteAddr
+	}
+
+	addr, err := c.ipFilter.ClientAddr(r)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return addr.String()
+}
+
+func parsePrefixes(networks []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(networks))
+	for _, network := range networks {
+		if addr, err := netip.ParseAddr(network); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid network %q", network))
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+func parseRemoteAddr(remoteAddr string) (netip.Addr, error) {
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		//Addresses of some listeners have no port
+		addr, err := netip.ParseAddr(remoteAddr)
+		if err != nil {
+			return netip.Addr{}, errors.New(fmt.Sprintf("Invalid remote address %q", remoteAddr))
+		}
+		return addr.Unmap(), nil
+	}
+
+	return addrPort.Addr().Unmap(), nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,125 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Serve the body as sent from the address with the headers
+func serveFromAddr(rpc JsonRPC, remoteAddr string, header http.Header, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	r.RemoteAddr = remoteAddr
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	rpc.ServeHTTP(recorder, r)
+
+	return recorder
+}
+
+func TestIPFilter(t *testing.T) {
+	filter, err := NewIPFilter(IPFilterOptions{Allow: []string{"10.0.0.0/8", "192.0.2.7"}, Deny: []string{"10.1.0.0/16"}})
+	assert.Nil(t, err)
+
+	assert.True(t, filter.Allowed(netip.MustParseAddr("10.2.3.4")))
+	assert.True(t, filter.Allowed(netip.MustParseAddr("::ffff:192.0.2.7")))
+	assert.False(t, filter.Allowed(netip.MustParseAddr("10.1.3.4")))
+	assert.False(t, filter.Allowed(netip.MustParseAddr("192.0.2.8")))
+
+	//Everyone is allowed unless denied when no network is allowed
+	filter, _ = NewIPFilter(IPFilterOptions{Deny: []string{"2001:db8::/32"}})
+	assert.True(t, filter.Allowed(netip.MustParseAddr("192.0.2.8")))
+	assert.False(t, filter.Allowed(netip.MustParseAddr("2001:db8::1")))
+
+	_, err = NewIPFilter(IPFilterOptions{Allow: []string{"10.0.0.0/33"}})
+	assert.Equal(t, `Invalid network "10.0.0.0/33"`, err.Error())
+}
+
+func TestIPFilterHTTP(t *testing.T) {
+	filter, _ := NewIPFilter(IPFilterOptions{Allow: []string{"10.0.0.0/8"}})
+	rpc := NewJsonRpc(WithIPFilter(filter))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	body := `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`
+	res := serveFromAddr(rpc, "10.0.0.1:5000", nil, body)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = serveFromAddr(rpc, "192.0.2.1:5000", nil, body)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":32004,"message":"Address 192.0.2.1 is not allowed","data":null}}`, res.Body.String())
+
+	//Forwarding headers of untrusted peers are ignored
+	res = serveFromAddr(rpc, "192.0.2.1:5000", http.Header{"X-Forwarded-For": {"10.0.0.1"}}, body)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+}
+
+func TestIPFilterTrustedProxies(t *testing.T) {
+	filter, _ := NewIPFilter(IPFilterOptions{Deny: []string{"198.51.100.0/24"}, TrustedProxies: []string{"10.0.0.0/8"}})
+
+	clientAddr := func(remoteAddr string, header http.Header) string {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header = header
+		addr, err := filter.ClientAddr(r)
+		if err != nil {
+			return err.Error()
+		}
+		return addr.String()
+	}
+
+	//The last address appended by an untrusted hop is the client, whatever the client sent before it
+	assert.Equal(t, "203.0.113.9", clientAddr("10.0.0.1:80", http.Header{"X-Forwarded-For": {"1.2.3.4, 203.0.113.9", "10.0.0.2"}}))
+	assert.Equal(t, "203.0.113.9", clientAddr("10.0.0.1:80", http.Header{"X-Real-Ip": {"203.0.113.9"}}))
+	assert.Equal(t, "10.0.0.1", clientAddr("10.0.0.1:80", http.Header{}))
+	assert.Equal(t, "203.0.113.1", clientAddr("203.0.113.1:80", http.Header{"X-Forwarded-For": {"10.0.0.3"}}))
+	assert.Equal(t, `Invalid X-Forwarded-For address "nope"`, clientAddr("10.0.0.1:80", http.Header{"X-Forwarded-For": {"nope"}}))
+
+	//Handlers see the client behind the proxies
+	var seen string
+	rpc := NewJsonRpc(WithIPFilter(filter), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			seen = RemoteAddrFromContext(ctx)
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	body := `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`
+	res := serveFromAddr(rpc, "10.0.0.1:5000", http.Header{"X-Forwarded-For": {"203.0.113.9"}}, body)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "203.0.113.9", seen)
+
+	res = serveFromAddr(rpc, "10.0.0.1:5000", http.Header{"X-Forwarded-For": {"198.51.100.4"}}, body)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+}
+
+func TestIPFilterConnections(t *testing.T) {
+	filter, _ := NewIPFilter(IPFilterOptions{Deny: []string{"127.0.0.1"}})
+	rpc := NewJsonRpc(WithIPFilter(filter))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	//Closed without reading the request
+	_, err = conn.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,237 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// How long finished jobs are kept for polling when WithJobRetention is not set
+const DEFAULT_JOB_RETENTION = 10 * time.Minute
+
+type JobState string
+
+const (
+	JOB_STATE_RUNNING   JobState = "running"
+	JOB_STATE_DONE      JobState = "done"
+	JOB_STATE_FAILED    JobState = "failed"
+	JOB_STATE_CANCELLED JobState = "cancelled"
+)
+
+type (
+	//Function executed by a job. The context is cancelled when the job is cancelled through rpc.jobCancel
+	JobFunc func(ctx context.Context) (any, error)
+
+	//A JobHandle is returned by a method to run a long computation in the background.
+	//The caller immediately receives a JobAccepted result and polls the job with rpc.jobStatus and rpc.jobResult
+	JobHandle struct {
+		fn JobFunc
+	}
+
+	//Result sent to the caller when a method returns a JobHandle
+	JobAccepted struct {
+		JobId string `json:"jobId"`
+	}
+
+	//Result of rpc.jobStatus
+	JobStatus struct {
+		JobId string   `json:"jobId"`
+		State JobState `json:"state"`
+		Error string   `json:"error,omitempty"` //Error message if the job failed
+	}
+
+	//A job started by the server
+	job struct {
+		id     string
+		state  JobState
+		result any
+		err    error
+		cancel context.CancelFunc
+	}
+
+	//Keeps track of running and recently finished jobs
+	jobStore struct {
+		mu        sync.Mutex
+		jobs      map[string]*job
+		retention time.Duration
+	}
+
+	//Implements the built-in job methods
+	jobMethods struct {
+		store *jobStore
+	}
+)
+
+// NewJob wraps fn in a JobHandle. Return the handle from a method to run fn asynchronously.
+func NewJob(fn JobFunc) *JobHandle {
+	return &JobHandle{fn: fn}
+}
+
+// WithJobRetention sets how long finished jobs are kept before they are discarded
+func WithJobRetention(retention time.Duration) Option {
+	return func(c *config) {
+		c.jobRetention = retention
+	}
+}
+
+func newJobStore(retention time.Duration) *jobStore {
+	if retention <= 0 {
+		retention = DEFAULT_JOB_RETENTION
+	}
+
+	return &jobStore{
+		jobs:      make(map[string]*job),
+		retention: retention,
+	}
+}
+
+func newJobId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// start runs the job in a go routine and returns the result sent to the caller
+func (s *jobStore) start(h *JobHandle) JobAccepted {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:     newJobId(),
+		state:  JOB_STATE_RUNNING,
+		cancel: cancel,
+	}
+
+	s.mu.Lock()
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		result, err := runJob(ctx, h.fn)
+
+		s.mu.Lock()
+		if j.state == JOB_STATE_RUNNING {
+			if err != nil {
+				j.state = JOB_STATE_FAILED
+				j.err = err
+			} else {
+				j.state = JOB_STATE_DONE
+				j.result = result
+			}
+		}
+		s.mu.Unlock()
+
+		time.AfterFunc(s.retention, func() {
+			s.mu.Lock()
+			delete(s.jobs, j.id)
+			s.mu.Unlock()
+		})
+	}()
+
+	return JobAccepted{JobId: j.id}
+}
+
+func runJob(ctx context.Context, fn JobFunc) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("Internal error: Panic %s", r))
+		}
+	}()
+
+	return fn(ctx)
+}
+
+func (s *jobStore) get(id string) (*job, error, *RpcErrorCode) {
+	j, ok := s.jobs[id]
+	if !ok {
+		code := INVALID_PARAMS
+		return nil, errors.New(fmt.Sprintf("Job %s does not exist", id)), &code
+	}
+
+	return j, nil, nil
+}
+
+// Replace a JobHandle result with the job id sent to the caller
+func (rpc *jsonRpcImpl) startJobs(data any) any {
+	if h, ok := data.(*JobHandle); ok && h != nil {
+		return rpc.jobs.start(h)
+	}
+
+	return data
+}
+
+func (rpc *jsonRpcImpl) registerJobMethods(builtins *service) {
+	m := jobMethods{store: rpc.jobs}
+	builtins.methods["jobStatus"] = reflect.ValueOf(m.JobStatus)
+	builtins.methods["jobResult"] = reflect.ValueOf(m.JobResult)
+	builtins.methods["jobCancel"] = reflect.ValueOf(m.JobCancel)
+}
+
+// JobStatus reports the state of a job
+func (m jobMethods) JobStatus(ctx context.Context, id string) (*JobStatus, error, *RpcErrorCode) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	j, err, code := m.store.get(id)
+	if err != nil {
+		return nil, err, code
+	}
+
+	status := &JobStatus{JobId: j.id, State: j.state}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+
+	return status, nil, nil
+}
+
+// JobResult returns the result of a finished job
+func (m jobMethods) JobResult(ctx context.Context, id string) (any, error, *RpcErrorCode) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	j, err, code := m.store.get(id)
+	if err != nil {
+		return nil, err, code
+	}
+
+	switch j.state {
+	case JOB_STATE_DONE:
+		return j.result, nil, nil
+	case JOB_STATE_FAILED:
+		code := INTERNAL_ERROR
+		return nil, j.err, &code
+	case JOB_STATE_CANCELLED:
+		code := JOB_CANCELLED
+		return nil, errors.New(fmt.Sprintf("Job %s was cancelled", id)), &code
+	default:
+		code := JOB_PENDING
+		return nil, errors.New(fmt.Sprintf("Job %s is still running", id)), &code
+	}
+}
+
+// JobCancel cancels a running job. It returns false if the job had already finished
+func (m jobMethods) JobCancel(ctx context.Context, id string) (bool, error, *RpcErrorCode) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	j, err, code := m.store.get(id)
+	if err != nil {
+		return false, err, code
+	}
+
+	if j.state != JOB_STATE_RUNNING {
+		return false, nil, nil
+	}
+
+	j.state = JOB_STATE_CANCELLED
+	j.cancel()
+
+	return true, nil, nil
+}
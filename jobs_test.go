@@ -0,0 +1,110 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reports struct {
+	release chan struct{}
+}
+
+func (r reports) Build(ctx context.Context) (*JobHandle, error, *RpcErrorCode) {
+	return NewJob(func(ctx context.Context) (any, error) {
+		select {
+		case <-r.release:
+			return "report", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}), nil, nil
+}
+
+func (r reports) Broken(ctx context.Context) (*JobHandle, error, *RpcErrorCode) {
+	return NewJob(func(ctx context.Context) (any, error) {
+		return nil, errors.New("Broken report")
+	}), nil, nil
+}
+
+func startTestJob(t *testing.T, rpc JsonRPC, method string) string {
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: method, Params: []any{}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := (*res.Result).(map[string]any)
+	return result["jobId"].(string)
+}
+
+func callJobMethod(t *testing.T, rpc JsonRPC, method string, jobId string) *Response {
+	id := "2"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: method, Params: []any{jobId}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestJobLifecycle(t *testing.T) {
+	rpc := NewJsonRpc()
+	srv := reports{release: make(chan struct{})}
+	rpc.RegisterWithName(srv, "Reports")
+
+	jobId := startTestJob(t, rpc, "Reports.Build")
+
+	res := callJobMethod(t, rpc, "rpc.jobResult", jobId)
+	assert.Equal(t, JOB_PENDING, res.Error.Code)
+
+	close(srv.release)
+
+	assert.Eventually(t, func() bool {
+		res := callJobMethod(t, rpc, "rpc.jobStatus", jobId)
+		return (*res.Result).(map[string]any)["state"] == string(JOB_STATE_DONE)
+	}, time.Second, 5*time.Millisecond)
+
+	res = callJobMethod(t, rpc, "rpc.jobResult", jobId)
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "report", *res.Result)
+}
+
+func TestJobCancel(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(reports{release: make(chan struct{})}, "Reports")
+
+	jobId := startTestJob(t, rpc, "Reports.Build")
+
+	res := callJobMethod(t, rpc, "rpc.jobCancel", jobId)
+	assert.Equal(t, true, *res.Result)
+
+	res = callJobMethod(t, rpc, "rpc.jobResult", jobId)
+	assert.Equal(t, JOB_CANCELLED, res.Error.Code)
+}
+
+func TestJobFailure(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(reports{}, "Reports")
+
+	jobId := startTestJob(t, rpc, "Reports.Broken")
+
+	assert.Eventually(t, func() bool {
+		res := callJobMethod(t, rpc, "rpc.jobStatus", jobId)
+		return (*res.Result).(map[string]any)["state"] == string(JOB_STATE_FAILED)
+	}, time.Second, 5*time.Millisecond)
+
+	res := callJobMethod(t, rpc, "rpc.jobResult", jobId)
+	assert.Equal(t, INTERNAL_ERROR, res.Error.Code)
+	assert.Equal(t, "Broken report", res.Error.Message)
+}
+
+func TestJobUnknown(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	res := callJobMethod(t, rpc, "rpc.jobStatus", "missing")
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+}
@@ -1,123 +1,375 @@
 package jsonrpc2
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type (
 	JsonRPC interface {
-		//Register a service
+		//Register a service, or a constructor of one called with the values of WithDependencies
 		Register(srv any) error
 
 		//Register a service and specify name
 		RegisterWithName(srv any, name string) error
 
+		//Register a service with options such as its name and method documentation
+		RegisterWithOptions(srv any, opts ServiceOptions) error
+
+		//Register a service whose methods are called without the service prefix. eg. ping
+		RegisterRoot(srv any) error
+
+		//Remove a registered service. Calls in flight finish on it, and its Stop hook is called if it was started.
+		//False when no service has the name
+		Unregister(name string) bool
+
+		//Handle the calls of methods that are not registered instead of returning METHOD_NOT_FOUND. Nil removes it
+		SetFallbackHandler(handler FallbackHandler)
+
+		//Translate errors returned by handlers without an error code to a specific code
+		MapError(target error, code RpcErrorCode, message string)
+
+		//Apply options on top of the current settings without restarting the server
+		Reconfigure(opts ...Option)
+
+		//Group services under a common prefix with shared middleware
+		Group(prefix string, middleware ...Middleware) Registrar
+
 		// The `ServeHTTP` function is responsible for handling incoming JSON-RPC requests. It takes in an
 		// `http.ResponseWriter` and an `http.Request` as parameters.
 		ServeHTTP(w http.ResponseWriter, r *http.Request)
-	}
 
-	//Used to service to method name and request object in batch request's go routine
-	batchServiceRequestType struct {
-		methodName string
-		req        request
-		service    *service
+		//Listen on a TCP address and serve JSON-RPC over raw sockets
+		ListenAndServe(addr string) error
+
+		//Listen on a TCP address and serve JSON-RPC over TLS
+		ListenAndServeTLS(addr string, opts ...TLSOption) error
+
+		//Serve JSON-RPC on every connection accepted by the listener
+		Serve(l net.Listener) error
+
+		//Serve JSON-RPC on a single connection until it is closed
+		ServeConn(conn net.Conn)
+
+		//Serve JSON-RPC over NATS request/reply until stop is called
+		ServeNATS(conn NATSConn, opts NATSOptions) (stop func() error, err error)
+
+		//Serve JSON-RPC over AMQP request/reply queues until stop is called
+		ServeAMQP(channel AMQPChannel, opts AMQPOptions) (stop func() error, err error)
+
+		//Handle a raw JSON-RPC message and return the encoded response. Used to build custom transports
+		HandleMessage(ctx context.Context, body []byte) []byte
+
+		//Handle a request without encoding it, eg. from another transport or a test
+		Dispatch(ctx context.Context, req Request) Response
+
+		//Handle requests as a batch without encoding them. Responses are in the order of the requests
+		DispatchBatch(ctx context.Context, requests []Request) []Response
+
+		//Push a notification to the subscribed clients, eg. on the SSE endpoint
+		Publish(topic string, method string, params any)
+
+		//Receive the notifications published on the server, eg. to bridge them to another transport
+		Subscribe(handler func(Notification)) (unsubscribe func())
+
+		//State of the worker pool of WithScheduler, eg. to export queue depth metrics
+		SchedulerStats() SchedulerStats
+
+		//Call counts, error rates and latency percentiles of every method called
+		Stats() []MethodStats
+
+		//Notify the clients of persistent connections and close them after the grace period
+		Drain(grace time.Duration)
+
+		//Persistent connections being served, with their pending calls and subscriptions
+		Connections() []ConnectionInfo
+
+		//Connection counts of every transport persistent connections were served on
+		ListenerStats() []ListenerStats
+
+		//Notify the client of a persistent connection and close it without a grace period
+		Kick(connId string) error
+
+		//Number of handlers running, including the ones of cancelled or timed out requests
+		InFlight() InFlightStats
+
+		//Block until no handler runs or the context is done
+		Wait(ctx context.Context) error
+
+		//Call the Start hook of the registered services. Serve, ServeNATS and ServeAMQP call it themselves
+		Start(ctx context.Context) error
+
+		//Wait for the handlers, then call the Stop hook of the started services
+		Shutdown(ctx context.Context) error
 	}
 
 	//Type for error channel in service.call routine. It maps err to error code and request ID
 	callerError struct {
-		err   error
-		code  RpcErrorCode
-		reqId *string
+		err       error
+		code      RpcErrorCode
+		reqId     *string
+		method    string
+		unhandled bool //Handler returned the error without a code
+		recovered any  //Value of the panic the error was made from
+		data      *any //Data of the error response, eg. the violations of the params
 	}
 
 	//Type for response channel in service.call routine. It maps response data to request ID
 	callerSuccess struct {
-		data  any
-		reqId *string
+		data   any
+		reqId  *string
+		method string
 	}
 
 	//JSON rpc request object type
-	request struct {
-		Id      *string `json:"id,omitempty"` //Id of request. Can be nil if it is a notification
-		Method  string  `json:"method"`       //Method name. Should be  service.method. eg. Arith.Add
-		Params  []any   `json:"params"`       //Argument of method
-		Jsonrpc string  `json:"jsonrpc"`      //RPC version. Should be 2.0
+	Request struct {
+		Id        *string `json:"id,omitempty"` //Id of request. Can be nil if it is a notification
+		Method    string  `json:"method"`       //Method name. Should be  service.method. eg. Arith.Add
+		Params    any     `json:"params"`       //Argument of method. Positional params are decoded as []any
+		Jsonrpc   string  `json:"jsonrpc"`      //RPC version. Should be 2.0
+		invalid   error   //Why the request could not be decoded. Not serialized
+		numericId bool    //Id was received as a number, with WithStrictNumbers. Not serialized
 	}
 
 	//JSON RPC error response object type
-	errorResponse struct {
+	RpcError struct {
 		Code    RpcErrorCode `json:"code"`    //A Number that indicates the error type that occurred.
 		Data    any          `json:"data"`    //A Primitive or Structured value that contains additional information about the error. This may be omitted.
 		Message string       `json:"message"` //A String providing a short description of the error.
+		err     error        //Error the response was made from. Not serialized
 	}
 
 	//json RPC response type
-	response struct {
+	Response struct {
 		Jsonrpc string         `json:"jsonrpc"`          //RPC version. Should be 2.0
-		Id      *string        `json:"id,omitempty"`     //Id of request. Can be nil if it is a notification
+		Id      *string        `json:"id"`               //Id of request. Null when the id of an invalid request is unknown
+		Meta    map[string]any `json:"meta,omitempty"`   //Extension members, eg. timings or cursors. Only sent with WithResponseMeta
 		Result  *any           `json:"result,omitempty"` //Results,Should be empty if error is not
-		Error   *errorResponse `json:"error,omitempty"`  //Results,Should be empty if Result is not
+		Error   *RpcError      `json:"error,omitempty"`  //Results,Should be empty if Result is not
+
+		truncated bool //Cut in half once encoded, by Chaos. Not serialized
+		numericId bool //Id is written as a number, with WithStrictNumbers. Not serialized
 	}
 
+	//Registered services by name. A published map is never modified, nor are the services in it, so calls read it
+	//without locking
+	serviceMap map[string]*service
+
 	//A service is a group of related methods
 	service struct {
-		methods map[string]reflect.Value
-		name    string
+		methods    map[string]reflect.Value
+		name       string
+		docs       map[string]MethodDoc
+		middleware []Middleware        //Run around the calls of the service only
+		paramNames map[string][]string //Names of the params of the methods callable by name, by method name
+		children   []string            //Names of the services of its embedded values, removed along with it
 	}
 
 	//RPC implementation
 	jsonRpcImpl struct {
-		services map[string]*service
+		services   atomic.Pointer[serviceMap] //Snapshot of the registered services, replaced as a whole on every change
+		servicesMu sync.Mutex                 //Serializes the changes of services, never taken to dispatch
+		configMu   sync.RWMutex               //Guards config, which Reconfigure replaces
+		config     *config
+		jobs       *jobStore
+		inFlight   *inFlightRequests //HTTP requests that can be cancelled
+
+		notifications *notificationHub
+		stats         *statsRecorder
+		conns         *servedConns        //Persistent connections being served
+		handlers      *handlerTracker     //Go routines running handlers
+		durable       *durableSubscribers //Subscribers of durable subscriptions, which outlive connections
+		coalesced     *coalescer          //Calls of WithCoalescing methods in flight
+		hooks         *serviceHooks       //Services to start and stop with the server
+
+		fallback atomic.Pointer[FallbackHandler] //Called for the methods no service has
 	}
 )
 
-func NewJsonRpc() JsonRPC {
-	return &jsonRpcImpl{
-		services: make(map[string]*service),
+func NewJsonRpc(opts ...Option) JsonRPC {
+	rpc := &jsonRpcImpl{
+		config: newConfig(opts),
 	}
+	rpc.jobs = newJobStore(rpc.config.jobRetention)
+	rpc.inFlight = newInFlightRequests()
+	rpc.inFlight.caller = rpc.cancellationCaller
+	rpc.notifications = newNotificationHub()
+	rpc.stats = newStatsRecorder()
+	rpc.conns = newServedConns()
+	rpc.handlers = newHandlerTracker()
+	rpc.durable = newDurableSubscribers()
+	rpc.coalesced = newCoalescer()
+	rpc.hooks = newServiceHooks()
+	rpc.registerBuiltins()
+
+	return rpc
 }
 
 func (rpc *jsonRpcImpl) register(srv any, name *string) error {
+	opts := ServiceOptions{}
+	if name != nil {
+		opts.Name = *name
+	}
+
+	return rpc.registerWithOptions(srv, opts)
+}
+
+func (rpc *jsonRpcImpl) registerWithOptions(srv any, opts ServiceOptions) error {
+	srv, err := rpc.construct(srv)
+	if err != nil {
+		return err
+	}
+
 	if reflect.ValueOf(srv).NumMethod() == 0 {
 		return errors.New("No method registered for this service")
 	}
 
-	service := new(service)
-	service.methods = make(map[string]reflect.Value, 0)
+	name := opts.Name
+	if name == "" {
+		name = serviceTypeName(srv)
+	}
+
+	if isReservedServiceName(name) {
+		return errors.New(fmt.Sprintf("Service name %s is reserved", BUILTIN_SERVICE_NAME))
+	}
+
+	return rpc.registerService(srv, name, opts)
+}
+
+// Name of the type of the service. Pointers are named after the type they point to
+func serviceTypeName(srv any) string {
+	t := reflect.TypeOf(srv)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
+
+// Whether the name is the one of the built-in service or nested under it
+func isReservedServiceName(name string) bool {
+	return name == BUILTIN_SERVICE_NAME || strings.HasPrefix(name, BUILTIN_SERVICE_NAME+".")
+}
+
+// Whether the full method name is one of the built-in service
+func isBuiltinMethod(method string) bool {
+	i := strings.LastIndex(method, ".")
+	return i >= 0 && isReservedServiceName(method[:i])
+}
+
+func (rpc *jsonRpcImpl) registerService(srv any, name string, opts ServiceOptions) error {
+	service, err := rpc.newService(srv, name, opts)
+	if err != nil {
+		return err
+	}
+	embedded, err := rpc.embeddedServices(srv, name, opts)
+	if err != nil {
+		return err
+	}
+	replaced, err := rpc.hooks.add(context.Background(), name, srv)
+	if err != nil {
+		return err
+	}
 
-	if name == nil {
-		service.name = reflect.ValueOf(srv).Type().Name()
-	} else {
-		service.name = *name
+	for _, s := range embedded {
+		service.children = append(service.children, s.name)
 	}
 
+	rpc.updateServices(func(services serviceMap) {
+		//Services of the embedded values of a replaced service may no longer exist
+		if replaced, ok := services[service.name]; ok {
+			removeChildren(services, replaced)
+		}
+		services[service.name] = service
+		for _, s := range embedded {
+			services[s.name] = s
+		}
+	})
+	rpc.stopRemovedService(replaced)
+
+	return nil
+}
+
+// Build the service from the exported methods of srv
+func (rpc *jsonRpcImpl) newService(srv any, name string, opts ServiceOptions) (*service, error) {
+	service := new(service)
+	service.methods = make(map[string]reflect.Value, 0)
+	service.docs = make(map[string]MethodDoc, len(opts.Docs))
+	service.middleware = opts.Middleware
+	service.paramNames = make(map[string][]string)
+	service.name = name
+
+	cfg := rpc.cfg()
+	namer := cfg.serviceNamer(opts)
+	var spec *openRPCSpec
+	if cfg != nil {
+		spec = cfg.openRPC
+	}
+	hidden := hiddenMethods(srv, opts)
+	registered := make(map[string]bool)
 	for m := 0; m < reflect.ValueOf(srv).NumMethod(); m++ {
 		methodVal := reflect.ValueOf(srv).Method(m)
 		method := reflect.ValueOf(srv).Type().Method(m)
 
-		if isValidMethod(method) {
-			methodName := method.Name
+		if isValidMethod(method) && !hidden[method.Name] {
+			methodVal = withContextParam(methodVal)
+			if err := checkValidationTags(methodVal.Type()); err != nil {
+				return nil, err
+			}
+
+			methodName := namer(method.Name)
+			if _, ok := service.methods[methodName]; ok {
+				return nil, errors.New(fmt.Sprintf("Methods of service %s are both named %s", name, methodName))
+			}
+			if spec != nil {
+				if err := spec.checkMethod(service.fullName(methodName), methodVal.Type()); err != nil {
+					return nil, err
+				}
+			}
 			service.methods[methodName] = methodVal
+			registered[method.Name] = true
+
+			names, ok := opts.ParamNames[method.Name]
+			if !ok {
+				names, ok = opts.ParamNames[methodName]
+			}
+			if ok {
+				if err := checkParamNames(service.fullName(methodName), methodVal.Type(), names); err != nil {
+					return nil, err
+				}
+				service.paramNames[methodName] = names
+			}
+
+			doc, ok := opts.Docs[method.Name]
+			if !ok {
+				doc, ok = opts.Docs[methodName]
+			}
+			if ok {
+				if err := checkParamDocs(service.fullName(methodName), methodVal.Type(), doc); err != nil {
+					return nil, err
+				}
+				service.docs[methodName] = doc
+			}
 		}
 
 	}
 
-	rpc.services[service.name] = service
-
-	if len(rpc.services) == 0 {
-		return errors.New("No method registered for this service")
+	if err := checkExposedMethods(name, opts, registered); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return service, nil
 }
 
 func (rpc *jsonRpcImpl) Register(srv any) error {
@@ -128,127 +380,356 @@ func (rpc *jsonRpcImpl) RegisterWithName(srv any, name string) error {
 	return rpc.register(srv, &name)
 }
 
+func (rpc *jsonRpcImpl) RegisterWithOptions(srv any, opts ServiceOptions) error {
+	return rpc.registerWithOptions(srv, opts)
+}
+
+func (rpc *jsonRpcImpl) Unregister(name string) bool {
+	if isReservedServiceName(name) {
+		return false
+	}
+
+	var removed bool
+	rpc.updateServices(func(services serviceMap) {
+		var srv *service
+		srv, removed = services[name]
+		if removed {
+			removeChildren(services, srv)
+		}
+		delete(services, name)
+	})
+	if removed {
+		rpc.stopRemovedService(rpc.hooks.unregister(name))
+	}
+
+	return removed
+}
+
+// Remove the services of the embedded values of the service
+func removeChildren(services serviceMap, srv *service) {
+	for _, child := range srv.children {
+		delete(services, child)
+	}
+}
+
+// Error of the calls of a method the service does not have
+func (s service) methodNotFound(methodName string) error {
+	if s.name == ROOT_SERVICE_NAME {
+		return errors.New(fmt.Sprintf("Method %s does not exist", methodName))
+	}
+
+	return errors.New(fmt.Sprintf("Method %s does not exist on service %s", methodName, s.name))
+}
+
 // Call this in a go routine
 func (s service) call(ctx context.Context, methodName string, args []any, id *string, respChan chan callerSuccess, errChan chan callerError) {
+	fullName := s.fullName(methodName)
+
 	method, ok := s.methods[methodName]
 	if !ok {
-		err := errors.New(fmt.Sprintf("Method %s does not exist on service %s", methodName, s.name))
 		errChan <- callerError{
-			err:   err,
-			code:  METHOD_NOT_FOUND,
-			reqId: id,
+			err:    s.methodNotFound(methodName),
+			code:   METHOD_NOT_FOUND,
+			reqId:  id,
+			method: fullName,
+		}
+
+		return
+	}
+
+	shape, streaming := streamShapeOf(method.Type())
+	if err := checkParamCount(method.Type(), len(args)+shape.params()); err != nil {
+		errChan <- callerError{
+			err:    err,
+			code:   INVALID_PARAMS,
+			reqId:  id,
+			method: fullName,
 		}
 
 		return
 	}
 
-	params := []reflect.Value{reflect.ValueOf(ctx)}
-	for _, arg := range args {
-		params = append(params, reflect.ValueOf(arg))
+	var stream *serverStream
+	if streaming {
+		var err error
+		if stream, ctx, err = openStream(ctx, id, shape); err != nil {
+			errChan <- callerError{
+				err:    err,
+				code:   INVALID_REQUEST,
+				reqId:  id,
+				method: fullName,
+			}
+
+			return
+		}
+		defer stream.close()
+	}
+
+	params := getParams(len(args) + 1 + shape.params())
+	defer putParams(params)
+
+	*params = append(*params, reflect.ValueOf(ctx))
+	for i, arg := range args {
+		param, err := paramValue(arg, paramType(method.Type(), i+1))
+		if err == nil {
+			err = validateParam(param)
+		}
+		if err == nil {
+			err = validateStruct(ctx, param)
+		}
+		if err != nil {
+			errChan <- callerError{
+				err:    err,
+				code:   INVALID_PARAMS,
+				reqId:  id,
+				method: fullName,
+				data:   violationsData(err),
+			}
+
+			return
+		}
+		*params = append(*params, param)
+	}
+	if shape.in != nil {
+		*params = append(*params, stream.values(ctx, shape.in))
 	}
 
 	//Handle panics from reflect
 	defer func() {
 		if err := recover(); err != nil {
-			fmt.Println("Recovered from panic:", err)
 			errChan <- callerError{
-				err:   errors.New(fmt.Sprintf("Internal error: Panic %s", err)),
-				code:  INTERNAL_ERROR,
-				reqId: id,
+				err:       errors.New(fmt.Sprintf("Internal error: Panic %s", err)),
+				code:      INTERNAL_ERROR,
+				reqId:     id,
+				method:    fullName,
+				recovered: err,
 			}
 		}
 	}()
 
 	//Call method
-	resp := method.Call(params)
+	resp := method.Call(*params)
 	if resp[1].Interface() != nil {
 
-		errCode := resp[2].Interface()
-		var code RpcErrorCode
-
-		if errCode == nil {
-			code = INTERNAL_ERROR
-		} else {
-			code = *errCode.(*RpcErrorCode)
+		//A nil *RpcErrorCode is not a nil interface
+		unhandled := resp[2].IsNil()
+		code := INTERNAL_ERROR
+		if !unhandled {
+			code = *resp[2].Interface().(*RpcErrorCode)
 		}
 
 		errorResponse := resp[1].Interface().(error)
 
 		errChan <- callerError{
-			err:   errorResponse,
-			code:  code,
-			reqId: id,
+			err:       errorResponse,
+			code:      code,
+			reqId:     id,
+			method:    fullName,
+			unhandled: unhandled,
+		}
+		return
+	}
+
+	//The call of a streaming method returns once its values are sent
+	if streaming {
+		if err := stream.sendValues(ctx, resp[0]); err != nil {
+			errChan <- callerError{
+				err:    err,
+				code:   streamErrorCode(err),
+				reqId:  id,
+				method: fullName,
+			}
+			return
 		}
+
+		respChan <- callerSuccess{reqId: id, method: fullName}
 		return
 	}
 
 	respChan <- callerSuccess{
-		data:  resp[0].Interface(),
-		reqId: id,
+		data:   resp[0].Interface(),
+		reqId:  id,
+		method: fullName,
 	}
 
 	return
 }
 
+var errRequestTooLarge = errors.New("Request too large")
+
 // Decode json request to be either single or batch request type
-func readRequest(r *http.Request) (*request, []request, error) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
+func (s *jsonRpcImpl) readRequest(r *http.Request) (*Request, []Request, error) {
+	var reader io.Reader = r.Body
+	maxSize := s.cfg().maxRequestSize
+	if maxSize > 0 {
+		reader = io.LimitReader(r.Body, maxSize+1)
+	}
+
+	//Decoded requests do not reference the body, so its buffer can be reused
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if _, err := buf.ReadFrom(reader); err != nil {
 		return nil, nil, err
 	}
 
-	singleRequest := &request{}
-	if err := json.Unmarshal(body, singleRequest); err == nil {
-		//single request
-		return singleRequest, nil, nil
+	if maxSize > 0 && int64(buf.Len()) > maxSize {
+		return nil, nil, errRequestTooLarge
+	}
+
+	return s.decodeRequest(buf.Bytes())
+}
+
+var errEmptyBatch = errors.New("Batch must contain at least one request")
+
+// Decode raw json message to be either single or batch request type.
+// The first non whitespace byte decides between the two. Entries that are not valid requests are returned
+// with their error so they get their own error response.
+func (s *jsonRpcImpl) decodeRequest(body []byte) (*Request, []Request, error) {
+	cfg := s.cfg()
+
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) == 0 || !json.Valid(trimmed) {
+		return nil, nil, errors.New("Unable to decode request")
+	}
+
+	switch trimmed[0] {
+	case '[':
+		var entries []json.RawMessage
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, nil, errors.New("Unable to decode request")
+		}
+		if len(entries) == 0 {
+			if cfg.legacyBatchResponses {
+				return nil, []Request{}, nil
+			}
+			return nil, nil, errEmptyBatch
+		}
+
+		batchRequest := make([]Request, len(entries))
+		for i, entry := range entries {
+			batchRequest[i] = decodeRequestObject(cfg, entry)
+		}
+		if err := checkDuplicateIds(cfg.duplicateIds, batchRequest); err != nil {
+			return nil, nil, err
+		}
+		return nil, batchRequest, nil
+
+	case '{':
+		singleRequest := decodeRequestObject(cfg, trimmed)
+		return &singleRequest, nil, nil
+
+	default:
+		return &Request{invalid: errors.New("Request must be an object or an array")}, nil, nil
+	}
+}
+
+func decodeRequestObject(cfg *config, data []byte) Request {
+	if cfg.strictNumbers && cfg.codec == nil {
+		return decodeStrictRequestObject(cfg, data)
 	}
 
-	batchRequest := &[]request{}
-	if err := json.Unmarshal(body, batchRequest); err == nil {
-		//batch request
-		return nil, *batchRequest, nil
+	req := Request{}
+	if err := cfg.unmarshal(data, &req); err != nil {
+		//Keep the id, when it is a string, so the error response can be matched to the request. Other ids are
+		//answered with null, as the id could not be read
+		var probe struct {
+			Id json.RawMessage `json:"id"`
+		}
+		var id *string
+		if json.Unmarshal(data, &probe) == nil && len(probe.Id) > 0 && probe.Id[0] == '"' {
+			id = new(string)
+			if json.Unmarshal(probe.Id, id) != nil {
+				id = nil
+			}
+		}
+
+		return Request{Id: id, invalid: errors.New("Invalid request object")}
+	}
+
+	return req
+}
+
+// Code of the error response sent when a message can not be decoded
+func decodeErrorCode(err error) RpcErrorCode {
+	if errors.Is(err, errEmptyBatch) || errors.Is(err, errRequestTooLarge) || errors.Is(err, errDuplicateId) {
+		return INVALID_REQUEST
 	}
 
-	return nil, nil, errors.New("Unable to decode request")
+	return PARSE_ERROR
 }
 
-func writeResponse(w http.ResponseWriter, res response, id *string) {
-	// Request is notification
-	if id == nil {
+// Check the request is a valid request object
+func (req *Request) validate() error {
+	if req.invalid != nil {
+		return req.invalid
+	}
+
+	if req.Jsonrpc != RPC_VERSION {
+		return errors.New("Invalid RPC version. jsonrpc must be 2.0")
+	}
+
+	if req.Method == "" {
+		return errors.New("Method is required")
+	}
+
+	return nil
+}
+
+// Notifications are valid requests without an id. Invalid requests are answered even without an id
+func (req *Request) isNotification() bool {
+	return req.Id == nil && req.validate() == nil
+}
+
+func (s *jsonRpcImpl) writeResponse(w http.ResponseWriter, res Response, notification bool) {
+	if notification {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
 	// I cannot handle another error here
-	r, _ := json.Marshal(&res)
-
-	w.WriteHeader(http.StatusOK)
+	cfg := s.cfg()
+	//Headers set after WriteHeader are not sent
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(r)
+	w.WriteHeader(http.StatusOK)
+	cfg.streamResponse(truncatingWriterFor(w, res), res, "", cfg.indentResponses)
 }
 
-func writeBatchResponse(w http.ResponseWriter, responses []response) {
+func (s *jsonRpcImpl) writeBatchResponse(w http.ResponseWriter, requests []Request, responses []Response) {
+	validResponses := withoutNotifications(requests, responses)
 
-	//Filter responses for all requests that are not notifications
-	validResponses := make([]response, 0)
-	for _, resp := range responses {
-		if resp.Id != nil {
-			validResponses = append(validResponses, resp)
-		}
+	cfg := s.cfg()
+	//Nothing is returned for a batch of notifications
+	if len(validResponses) == 0 && !cfg.legacyBatchResponses {
+		w.WriteHeader(http.StatusNoContent)
+		return
 	}
 
-	r, _ := json.Marshal(&validResponses)
-
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(r)
+	cfg.streamResponses(truncatingWriterFor(w, validResponses...), validResponses, cfg.indentResponses)
 }
 
-func writeSuccessResponse(w http.ResponseWriter, data any, id *string) {
-	writeResponse(w, makeSuccessResponse(&data, id), id)
+// Filter responses for all requests that are not notifications
+func withoutNotifications(requests []Request, responses []Response) []Response {
+	validResponses := make([]Response, 0)
+	for i, resp := range responses {
+		if !requests[i].isNotification() {
+			validResponses = append(validResponses, resp)
+		}
+	}
+
+	return validResponses
 }
 
-func writeErrorResponse(w http.ResponseWriter, err error, errCode RpcErrorCode, id *string, data any) {
-	writeResponse(w, makeErrorResponse(err, errCode, &data, id), id)
+// Errors detected before the id of the request is known are written as well, unlike responses to notifications
+func (s *jsonRpcImpl) writeErrorResponse(w http.ResponseWriter, err error, errCode RpcErrorCode, id *string, data any) {
+	res := makeErrorResponse(err, errCode, &data, id)
+
+	cfg := s.cfg()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	cfg.writeJSON(truncatingWriterFor(w, res), &res, cfg.indentResponses)
 }
 
 // The function `sanitizeMethodPath` splits a method name into a service name and a method name, and
@@ -259,36 +740,57 @@ func sanitizeMethodPath(method string) (serviceName *string, methodName *string,
 		return
 	}
 
-	n := strings.Split(method, ".")
+	//Service names of groups contain dots. eg. admin.users.Create
+	i := strings.LastIndex(method, ".")
+	service, name := method[:i], method[i+1:]
 
-	serviceName = &n[0]
-	methodName = &n[1]
+	serviceName = &service
+	methodName = &name
 	err = nil
 
 	return
 }
 
 func (s *jsonRpcImpl) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.handle(w, r)
+	cfg := s.cfg()
+	if filter := cfg.ipFilter; filter != nil && filter.refuseRequest(w, r) {
+		return
+	}
+
+	if cors := cfg.cors; cors != nil && cors.handleCORS(w, r) {
+		return
+	}
+
+	if s.servePlayground(w, r) || s.serveSSE(w, r) || s.serveREST(w, r) || s.serveAdapted(w, r) {
+		return
+	}
+
+	s.handleChecksummed(w, r)
+}
+
+// NewErrorResponse builds the response of a request that failed with the code, eg. in a middleware rejecting the request
+func NewErrorResponse(err error, code RpcErrorCode, id *string) Response {
+	return makeErrorResponse(err, code, nil, id)
 }
 
-func makeErrorResponse(err error, errCode RpcErrorCode, data *any, id *string) response {
+func makeErrorResponse(err error, errCode RpcErrorCode, data *any, id *string) Response {
 
-	return response{
+	return Response{
 		Jsonrpc: RPC_VERSION,
 		Id:      id,
 		Result:  nil,
-		Error: &errorResponse{
+		Error: &RpcError{
 			Code:    errCode,
 			Message: err.Error(),
 			Data:    data,
+			err:     err,
 		},
 	}
 }
 
-func makeSuccessResponse(data *any, id *string) response {
+func makeSuccessResponse(data *any, id *string) Response {
 
-	return response{
+	return Response{
 		Jsonrpc: RPC_VERSION,
 		Id:      id,
 		Result:  data,
@@ -296,134 +798,332 @@ func makeSuccessResponse(data *any, id *string) response {
 	}
 }
 
-func (s *jsonRpcImpl) handleBatchRequest(ctx context.Context, w http.ResponseWriter, requests []request) {
-	responses := make([]response, 0)
+// Requests of a batch are handled concurrently by a pool of workers, as many as WithBatchConcurrency allows, unless
+// the batch is atomic. Responses are in the order of the requests. Once the deadline of the batch passes, entries still running or
+// waiting for a worker fail with the timeout error without waiting for them. With WithBatchEntryBudgets each entry
+// gets its share of the time left instead of the whole deadline
+func (s *jsonRpcImpl) handleBatchRequest(ctx context.Context, requests []Request) []Response {
+	cfg := s.cfg()
+	if cfg.batchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.batchTimeout)
+		defer cancel()
+	}
+
+	if isAtomicBatch(requests) {
+		responses := s.handleAtomicBatch(ctx, requests)
+		tagDuplicateIds(cfg.duplicateIds, requests, responses)
+		return responses
+	}
 
-	validServices := make([]batchServiceRequestType, 0)
+	var expired <-chan struct{}
+	if _, ok := ctx.Deadline(); ok {
+		expired = ctx.Done()
+	}
 
-	for _, req := range requests {
-		if req.Jsonrpc != RPC_VERSION {
-			err := errors.New("Invalid RPC version. jsonrpc must be 2.0")
-			responses = append(responses, makeErrorResponse(err, INVALID_REQUEST, nil, req.Id))
+	type entryResponse struct {
+		index int
+		res   Response
+	}
+	//Both buffered so workers never block, even once the batch is answered
+	pending := make(chan int, len(requests))
+	results := make(chan entryResponse, len(requests))
+	for i := range requests {
+		pending <- i
+	}
+	close(pending)
+
+	workers := cfg.batchWorkers(len(requests))
+	var started atomic.Int32
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range pending {
+				select {
+				case <-expired:
+					//Answered by the collector with the timeout error
+					return
+				default:
+				}
+
+				entryCtx, cancel := ctx, context.CancelFunc(func() {})
+				if cfg.batchEntryBudgets {
+					entryCtx, cancel = withEntryBudget(ctx, len(requests)-int(started.Add(1))+1, workers)
+				}
+				res := s.handleSingleRequest(entryCtx, requests[i])
+				cancel()
+				results <- entryResponse{index: i, res: res}
+			}
+		}()
+	}
 
-			continue
+	responses := make([]Response, len(requests))
+	answered := make([]bool, len(requests))
+collect:
+	for received := 0; received < len(requests); received++ {
+		select {
+		case result := <-results:
+			responses[result.index] = result.res
+			answered[result.index] = true
+		case <-expired:
+			err := callContextError(ctx)
+			for i, req := range requests {
+				if !answered[i] {
+					responses[i] = cfg.makeMappedErrorResponse(err, req.Id)
+				}
+			}
+			break collect
 		}
+	}
 
-		serviceName, methodName, err := sanitizeMethodPath(req.Method)
+	tagDuplicateIds(cfg.duplicateIds, requests, responses)
 
-		if err != nil {
-			responses = append(responses, makeErrorResponse(err, PARSE_ERROR, nil, req.Id))
-			continue
-		}
+	return responses
+}
 
-		service, ok := s.services[*serviceName]
+// Context of a batch entry with its share of the time left to the batch, split between the rounds of workers the
+// entries not started yet, this one included, still need
+func withEntryBudget(ctx context.Context, left int, workers int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}
+	}
 
-		if !ok {
-			err = errors.New(fmt.Sprintf("Service %s is not registered", *serviceName))
-			responses = append(responses, makeErrorResponse(err, METHOD_NOT_FOUND, nil, req.Id))
-			continue
-		}
-		validServices = append(validServices, batchServiceRequestType{req: req, service: service, methodName: *methodName})
+	rounds := (left + workers - 1) / workers
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(rounds))
+}
+
+// The settings are read once, so a Reconfigure while the request is handled does not affect it
+func (s *jsonRpcImpl) handleSingleRequest(ctx context.Context, req Request) (res Response) {
+	cfg := s.cfg()
+	req = normalizeCancelRequest(req)
+	ctx = cfg.requestContext(ctx, req)
+	ctx, meta := cfg.withResponseMeta(ctx)
+
+	start := time.Now()
+	defer func() {
+		res = s.intercept(ctx, cfg, req.Method, res)
+		res = cfg.attachResponseMeta(res, meta)
+		res = cfg.limitResponse(res)
+		res.numericId = req.numericId && res.Id != nil
+		s.logAccess(ctx, cfg, start, req, res)
+		s.audit(ctx, cfg, start, req, res)
+		s.recordStats(start, req, res)
+		res = cfg.localize(ctx, res)
+	}()
+
+	if err := req.validate(); err != nil {
+		return makeErrorResponse(err, INVALID_REQUEST, nil, req.Id)
 	}
 
-	var mu sync.Mutex
-	respChan := make(chan callerSuccess)
-	errChan := make(chan callerError)
+	return cfg.chain(func(ctx context.Context, req *Request) Response {
+		return s.dispatch(ctx, cfg, req)
+	})(ctx, &req)
+}
 
-	for _, s := range validServices {
-		go s.service.call(ctx, s.methodName, s.req.Params, s.req.Id, respChan, errChan)
+// Innermost handler. It resolves the method and calls it
+func (s *jsonRpcImpl) dispatch(ctx context.Context, cfg *config, req *Request) (res Response) {
+	if !cfg.exposes(req.Method) {
+		return makeErrorResponse(errors.New(fmt.Sprintf("Method %s does not exist", req.Method)), METHOD_NOT_FOUND, nil, req.Id)
+	}
+	if res, exceeded := cfg.chargeQuota(ctx, req); exceeded {
+		return res
 	}
 
-	for range validServices {
-		select {
-		case e := <-errChan:
-			mu.Lock()
-			responses = append(responses, makeErrorResponse(e.err, e.code, nil, e.reqId))
-			mu.Unlock()
-
-		case r := <-respChan:
-			mu.Lock()
-			responses = append(responses, makeSuccessResponse(&r.data, r.reqId))
-			mu.Unlock()
-
-		case <-ctx.Done():
-			err := errors.New("Request was not able to complete")
-			mu.Unlock()
-			responses = append(responses, makeErrorResponse(err, INTERNAL_ERROR, nil, nil))
-			mu.Unlock()
+	serviceName, methodName, err := sanitizeMethodPath(req.Method)
+
+	if err != nil {
+		if _, ok := s.lookupService(ROOT_SERVICE_NAME); !ok {
+			if fallback := s.fallbackFor(req.Method); fallback != nil {
+				return s.callFallback(ctx, cfg, fallback, req)
+			}
+			return makeErrorResponse(err, PARSE_ERROR, nil, req.Id)
 		}
+		//Methods of the root service have no service prefix
+		root, name := ROOT_SERVICE_NAME, req.Method
+		serviceName, methodName = &root, &name
 	}
 
-	close(respChan)
-	close(errChan)
+	service, err := s.resolveService(ctx, *serviceName)
+	if err != nil {
+		return cfg.makeMappedErrorResponse(err, req.Id)
+	}
 
-	writeBatchResponse(w, responses)
+	if fallback := s.fallbackFor(req.Method); fallback != nil && !service.declares(*methodName) {
+		return s.callFallback(ctx, cfg, fallback, req)
+	}
+	if service == nil {
+		err = errors.New(fmt.Sprintf("Service %s is not registered", *serviceName))
+		return makeErrorResponse(err, METHOD_NOT_FOUND, nil, req.Id)
+	}
 
-}
+	warnDeprecated(ctx, service, *methodName)
 
-func (s *jsonRpcImpl) handleSingleRequest(ctx context.Context, w http.ResponseWriter, req request) {
+	if len(service.middleware) == 0 {
+		return s.invokeCoalesced(ctx, cfg, service, *methodName, req)
+	}
 
-	if req.Jsonrpc != RPC_VERSION {
-		err := errors.New("Invalid RPC version. jsonrpc must be 2.0")
-		writeErrorResponse(w, err, INVALID_REQUEST, req.Id, nil)
-		return
+	return chainMiddleware(service.middleware, func(ctx context.Context, req *Request) Response {
+		return s.invokeCoalesced(ctx, cfg, service, *methodName, req)
+	})(ctx, req)
+}
+
+// Call the method of the service with the params of the request
+func (s *jsonRpcImpl) invoke(ctx context.Context, cfg *config, service *service, methodName string, req *Request) (res Response) {
+	//The method is resolved before its params are decoded, so unknown methods are not reported as invalid params
+	if _, ok := service.methods[methodName]; !ok {
+		return makeErrorResponse(service.methodNotFound(methodName), METHOD_NOT_FOUND, nil, req.Id)
 	}
 
-	serviceName, methodName, err := sanitizeMethodPath(req.Method)
+	if spec := cfg.openRPC; spec != nil {
+		if err := spec.checkParams(req.Method, req.Params); err != nil {
+			return makeErrorResponse(err, INVALID_PARAMS, nil, req.Id)
+		}
+	}
 
+	args, err := service.positionalParams(methodName, req.Params)
 	if err != nil {
-		writeErrorResponse(w, err, PARSE_ERROR, req.Id, nil)
-		return
+		return makeErrorResponse(err, INVALID_PARAMS, nil, req.Id)
 	}
 
-	service, ok := s.services[*serviceName]
-
-	if !ok {
-		err = errors.New(fmt.Sprintf("Service %s is not registered", *serviceName))
-		writeErrorResponse(w, err, METHOD_NOT_FOUND, req.Id, nil)
+	callCtx := ctx
+	if req.Id != nil {
+		var done func()
+		callCtx, done = trackRequest(ctx, *req.Id)
+		defer done()
+	}
 
-		return
+	if timeout := cfg.methodTimeout(req.Method); timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(callCtx, timeout)
+		defer cancel()
 	}
+	callCtx = withStructValidator(callCtx, cfg.structValidator)
 
-	respChan := make(chan callerSuccess)
-	errChan := make(chan callerError)
+	//Buffered so the call can complete after the request was cancelled and nobody is receiving
+	respChan := make(chan callerSuccess, 1)
+	errChan := make(chan callerError, 1)
 
-	//Call method in a go routine
-	go service.call(ctx, *methodName, req.Params, req.Id, respChan, errChan)
+	//Call method in a go routine. It is tracked until it returns, even when the request is answered before
+	call := s.handlers.start()
+	go func() {
+		defer call.finish()
+		s.callLimited(callCtx, cfg, service, methodName, args, req.Id, respChan, errChan)
+	}()
 
 	select {
 	case err := <-errChan:
-		writeErrorResponse(w, err.err, err.code, err.reqId, nil)
+		if err.recovered != nil {
+			cfg.logger.Printf("Recovered from panic in %s: %v", err.method, err.recovered)
+		}
+
+		if err.unhandled {
+			res = cfg.makeMappedErrorResponse(err.err, err.reqId)
+		} else {
+			res = makeErrorResponse(err.err, err.code, err.data, err.reqId)
+		}
+		if cancelledByClient(callCtx) {
+			res = makeCancelledResponse(req.Id)
+		}
 
 	case d := <-respChan:
-		writeSuccessResponse(w, d.data, d.reqId)
+		data := s.startJobs(d.data)
+		res = makeSuccessResponse(&data, d.reqId)
+
+	case <-callCtx.Done():
+		call.abandon()
+		if cancelledByClient(callCtx) {
+			res = makeCancelledResponse(req.Id)
+			break
+		}
+		res = cfg.makeMappedErrorResponse(callContextError(callCtx), req.Id)
+	}
+
+	return res
+}
+
+// Process a raw JSON-RPC message and return the encoded response.
+// Nil is returned when there is nothing to send back, eg. the message only contained notifications
+func (s *jsonRpcImpl) HandleMessage(ctx context.Context, body []byte) []byte {
+	cfg := s.cfg()
+	singleRequest, batchRequest, err := s.decodeRequest(body)
 
-	case <-ctx.Done():
-		err := errors.New("Request canceled")
-		writeErrorResponse(w, err, INTERNAL_ERROR, req.Id, nil)
+	if err != nil {
+		res := makeErrorResponse(err, decodeErrorCode(err), nil, nil)
+		r, _ := cfg.marshal(&res, false)
+		return r
 	}
 
-	close(respChan)
-	close(errChan)
-	return
+	if singleRequest != nil {
+		res := s.handleSingleRequest(ctx, *singleRequest)
+		if singleRequest.isNotification() {
+			return nil
+		}
+
+		r, _ := cfg.marshal(&res, false)
+		return truncateMessage(r, res)
+	}
+
+	responses := withoutNotifications(batchRequest, s.handleBatchRequest(ctx, batchRequest))
+	if len(responses) == 0 {
+		return nil
+	}
+
+	r, _ := cfg.marshal(&responses, false)
+	return truncateMessage(r, responses...)
 }
 
 func (s *jsonRpcImpl) handle(w http.ResponseWriter, r *http.Request) {
-	singleRequest, batchRequest, err := readRequest(r)
+	if s.serveGET(w, r) {
+		return
+	}
 
+	singleRequest, batchRequest, err := s.readRequest(r)
+	ctx, cancel, deprecations, timeoutErr := s.httpContext(r)
+	defer cancel()
+
+	if timeoutErr != nil {
+		s.writeErrorResponse(w, timeoutErr, INVALID_REQUEST, nil, nil)
+		return
+	}
 	if err != nil {
-		writeErrorResponse(w, err, PARSE_ERROR, nil, nil)
+		s.writeErrorResponse(w, err, decodeErrorCode(err), nil, nil)
 		return
 	}
 
 	//Handle request types
 	if singleRequest != nil {
-		s.handleSingleRequest(r.Context(), w, *singleRequest)
+		res := s.handleSingleRequest(ctx, *singleRequest)
+		deprecations.writeHeaders(w.Header())
+
+		if a, ok := attachmentOf(res); ok && !singleRequest.isNotification() {
+			s.writeAttachmentResponse(w, r, res, a)
+			return
+		}
+
+		s.writeResponse(w, res, singleRequest.isNotification())
 		return
 	}
 
-	s.handleBatchRequest(r.Context(), w, batchRequest)
+	responses := s.handleBatchRequest(ctx, batchRequest)
+	deprecations.writeHeaders(w.Header())
+
+	s.writeBatchResponse(w, batchRequest, responses)
+
+}
+
+// Context of the calls of an HTTP request, with the deprecation warnings they record. The error tells the timeout
+// header is invalid
+func (s *jsonRpcImpl) httpContext(r *http.Request) (context.Context, context.CancelFunc, *deprecationWarnings, error) {
+	cfg := s.cfg()
+	ctx := withInFlightRequests(r.Context(), s.inFlight)
+	ctx = withRemoteAddr(ctx, cfg.remoteAddr(r))
+	ctx = withHTTPRequest(ctx, r)
+	ctx = cfg.requestMetadata(ctx, r.Header)
+
+	ctx, cancel, timeoutErr := withTimeoutHeader(ctx, r.Header)
+	ctx, deprecations := withDeprecationWarnings(ctx)
 
+	return ctx, cancel, deprecations, timeoutErr
 }
 
 func isValidMethod(methodType reflect.Method) bool {
@@ -440,3 +1140,31 @@ func isValidMethod(methodType reflect.Method) bool {
 
 	return true
 }
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// Adapt a method that does not take a context, eg. of legacy code, to take one that is ignored, so it is called
+// like the other methods
+func withContextParam(method reflect.Value) reflect.Value {
+	t := method.Type()
+	if t.NumIn() > 0 && t.In(0) == contextType {
+		return method
+	}
+
+	in := make([]reflect.Type, 0, t.NumIn()+1)
+	in = append(in, contextType)
+	for i := 0; i < t.NumIn(); i++ {
+		in = append(in, t.In(i))
+	}
+	out := make([]reflect.Type, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i)
+	}
+
+	return reflect.MakeFunc(reflect.FuncOf(in, out, t.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		if t.IsVariadic() {
+			return method.CallSlice(args[1:])
+		}
+		return method.Call(args[1:])
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -73,12 +74,10 @@ func TestIsValidMethod(t *testing.T) {
 }
 
 func TestRegister(t *testing.T) {
-	rpc := &jsonRpcImpl{
-		services: make(map[string]*service),
-	}
+	rpc := &jsonRpcImpl{}
 
 	rpc.register(arith{}, nil)
-	service, ok := rpc.services["arith"]
+	service, ok := rpc.lookupService("arith")
 
 	assert.True(t, ok)
 
@@ -88,13 +87,11 @@ func TestRegister(t *testing.T) {
 }
 
 func TestRegisterWithName(t *testing.T) {
-	rpc := &jsonRpcImpl{
-		services: make(map[string]*service),
-	}
+	rpc := &jsonRpcImpl{}
 	var name = "Arith"
 
 	rpc.register(arith{}, &name)
-	service, ok := rpc.services[name]
+	service, ok := rpc.lookupService(name)
 
 	assert.True(t, ok)
 
@@ -104,9 +101,7 @@ func TestRegisterWithName(t *testing.T) {
 }
 
 func TestServiceCall(t *testing.T) {
-	rpc := &jsonRpcImpl{
-		services: make(map[string]*service),
-	}
+	rpc := &jsonRpcImpl{}
 
 	var (
 		id             = "1"
@@ -117,7 +112,7 @@ func TestServiceCall(t *testing.T) {
 	)
 
 	rpc.register(arith{}, &serviceName)
-	service, ok := rpc.services[serviceName]
+	service, ok := rpc.lookupService(serviceName)
 
 	assert.True(t, ok)
 
@@ -159,7 +154,7 @@ func (suit *JsonRpc2TestSuite) TestHandleSingle() {
 		expectedOutput = float64(4)
 	)
 
-	req := request{
+	req := Request{
 		Id:      &id,
 		Method:  "Arith.Add",
 		Params:  []any{1, 3},
@@ -184,7 +179,7 @@ func (suit *JsonRpc2TestSuite) TestHandleSingleNoMethod() {
 		expectedErrorMessage = "Method Sub does not exist on service Arith"
 	)
 
-	req := request{
+	req := Request{
 		Id:      &id,
 		Method:  "Arith.Sub",
 		Params:  []any{1, 3},
@@ -211,7 +206,7 @@ func (suit *JsonRpc2TestSuite) TestHandleSingleWrongVersion() {
 		expectedErrorMessage = "Invalid RPC version. jsonrpc must be 2.0"
 	)
 
-	req := request{
+	req := Request{
 		Id:      &id,
 		Method:  "Arith.Add",
 		Params:  []any{1, 3},
@@ -237,7 +232,7 @@ func (suit *JsonRpc2TestSuite) TestHandleSingleWrongWrongMethodNameFormat() {
 		expectedErrorMessage = "Invalid method name"
 	)
 
-	req := request{
+	req := Request{
 		Id:      &id,
 		Method:  "ArithAdd",
 		Params:  []any{1, 3},
@@ -262,7 +257,7 @@ func (suit *JsonRpc2TestSuite) TestHandleSingleErrorHandling() {
 		id = "1"
 	)
 
-	req := request{
+	req := Request{
 		Id:      &id,
 		Method:  "Arith.ErrorMethod",
 		Params:  []any{},
@@ -286,7 +281,7 @@ func (suit *JsonRpc2TestSuite) TestHandleBatch() {
 		ids = []string{"1", "2"}
 	)
 
-	req := []request{{
+	req := []Request{{
 		Id:      &ids[0],
 		Method:  "Arith.Add",
 		Params:  []any{1, 3},
@@ -315,3 +310,39 @@ func TestJsonRpc2(t *testing.T) {
 
 	suite.Run(t, new(JsonRpc2TestSuite))
 }
+
+// Methods of legacy code that do not take a context
+type legacy struct{}
+
+func (legacy) Add(a, b int) (int, error, *RpcErrorCode) {
+	return a + b, nil, nil
+}
+
+func (legacy) Version() (string, error, *RpcErrorCode) {
+	return "1.0", nil, nil
+}
+
+func (legacy) Join(sep string, parts ...string) (string, error, *RpcErrorCode) {
+	return strings.Join(parts, sep), nil, nil
+}
+
+func (legacy) Create(user testExpectedUser) (int, error, *RpcErrorCode) {
+	return user.Id, nil, nil
+}
+
+func TestMethodsWithoutContext(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithName(legacy{}, "Legacy"))
+
+	assert.Equal(t, float64(3), *callMethod(t, rpc, "Legacy.Add", []any{1, 2}).Result)
+	assert.Equal(t, "1.0", *callMethod(t, rpc, "Legacy.Version", nil).Result)
+	assert.Equal(t, "a-b", *callMethod(t, rpc, "Legacy.Join", []any{"-", "a", "b"}).Result)
+
+	res := callMethod(t, rpc, "Legacy.Add", []any{1})
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "Method expects 2 params, got 1", res.Error.Message)
+
+	id := "1"
+	named, _ := makeRpcSingleTestRequest(rpc, Request{Id: &id, Jsonrpc: RPC_VERSION, Method: "Legacy.Create", Params: map[string]any{"id": 7, "name": "ada"}})
+	assert.Equal(t, float64(7), *named.Result)
+}
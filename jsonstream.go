@@ -0,0 +1,154 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Slice results with at least this many elements are written to HTTP responses one element at a time
+const STREAMED_RESULT_LENGTH = 256
+
+// Result encoded in place of a streamed result, whose elements are then written where it stands
+const streamedResultMarker = "\x00jsonrpc2 streamed result\x00"
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	encodedResultMarker []byte
+)
+
+func init() {
+	encodedResultMarker, _ = json.Marshal(streamedResultMarker)
+}
+
+// Write the responses of a batch as a JSON array one response at a time, so a large batch is never held encoded
+// whole. A response that can not be encoded is replaced by an INTERNAL_ERROR response with its id.
+func (c *config) streamResponses(w io.Writer, responses []Response, indent bool) error {
+	if c.codec != nil {
+		return c.writeJSON(w, &responses, indent)
+	}
+
+	return writeArray(w, len(responses), "", indent, func(i int, prefix string) error {
+		err := c.streamResponse(w, responses[i], prefix, indent)
+		var encodeErr *encodeError
+		if !errors.As(err, &encodeErr) {
+			return err
+		}
+
+		res := makeErrorResponse(errors.New(fmt.Sprintf("Response could not be encoded: %v", encodeErr.err)), INTERNAL_ERROR, nil, responses[i].Id)
+		return c.streamResponse(w, res, prefix, indent)
+	})
+}
+
+// Write a response whose lines start with the prefix. Slice results with many elements are written one element at
+// a time. Encoding errors before anything was written are *encodeError
+func (c *config) streamResponse(w io.Writer, res Response, prefix string, indent bool) error {
+	if c.codec != nil {
+		return c.writeJSON(w, &res, indent)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	values, streamed := streamedResult(res)
+	if streamed {
+		var marker any = streamedResultMarker
+		res.Result = &marker
+	}
+	if err := c.encodePrefixed(buf, &res, prefix, indent); err != nil {
+		return &encodeError{err}
+	}
+	if !streamed {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	//The result is the last member, so markers in the id or other members are never mistaken for it
+	at := bytes.LastIndex(buf.Bytes(), encodedResultMarker)
+	before, after := buf.Bytes()[:at], buf.Bytes()[at+len(encodedResultMarker):]
+	line := before[bytes.LastIndexByte(before, '\n')+1:]
+	linePrefix := string(line[:len(line)-len(bytes.TrimLeft(line, " "))])
+
+	if _, err := w.Write(before); err != nil {
+		return err
+	}
+	err := writeArray(w, values.Len(), linePrefix, indent, func(i int, prefix string) error {
+		element := getBuffer()
+		defer putBuffer(element)
+
+		if err := c.encodePrefixed(element, values.Index(i).Interface(), prefix, indent); err != nil {
+			return err
+		}
+		_, err := w.Write(element.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(after)
+	return err
+}
+
+// Write a JSON array of n elements, each written by element. Lines of indented arrays start with the prefix
+func writeArray(w io.Writer, n int, prefix string, indent bool, element func(i int, prefix string) error) error {
+	if n == 0 {
+		_, err := io.WriteString(w, "[]")
+		return err
+	}
+
+	open, separator, end, inner := "[", ",", "]", prefix
+	if indent {
+		inner = prefix + "  "
+		open, separator, end = "[\n"+inner, ",\n"+inner, "\n"+prefix+"]"
+	}
+
+	if _, err := io.WriteString(w, open); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+		}
+		if err := element(i, inner); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, end)
+	return err
+}
+
+// Elements of a result worth streaming: a long slice that encoding/json writes as an array
+func streamedResult(res Response) (reflect.Value, bool) {
+	if res.Result == nil || res.Error != nil {
+		return reflect.Value{}, false
+	}
+
+	v := reflect.ValueOf(*res.Result)
+	if v.Kind() != reflect.Slice || v.Len() < STREAMED_RESULT_LENGTH || v.Type().Elem().Kind() == reflect.Uint8 {
+		return reflect.Value{}, false
+	}
+	for _, t := range []reflect.Type{v.Type(), reflect.PointerTo(v.Type())} {
+		if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+			return reflect.Value{}, false
+		}
+	}
+
+	return v, true
+}
+
+// Error encoding a value before any of it was written
+type encodeError struct {
+	err error
+}
+
+func (e *encodeError) Error() string {
+	return e.err.Error()
+}
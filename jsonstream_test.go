@@ -0,0 +1,109 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	listing struct{}
+
+	row struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	//Writer counting its writes
+	countingWriter struct {
+		bytes.Buffer
+		writes int
+	}
+)
+
+func (listing) Rows(ctx context.Context, n int) ([]row, error, *RpcErrorCode) {
+	rows := make([]row, n)
+	for i := range rows {
+		rows[i] = row{Id: i, Name: "<row>"}
+	}
+	return rows, nil, nil
+}
+
+func (listing) Broken(ctx context.Context) (any, error, *RpcErrorCode) {
+	return make(chan int), nil, nil
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+// Encode the response as a whole with the options of the server
+func encodeWhole(t *testing.T, v any, indent bool) string {
+	var data []byte
+	var err error
+	if indent {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestStreamedResult(t *testing.T) {
+	for _, indent := range []bool{false, true} {
+		rows, _, _ := listing{}.Rows(context.Background(), STREAMED_RESULT_LENGTH)
+		id := "1"
+		var result any = rows
+		res := Response{Jsonrpc: RPC_VERSION, Id: &id, Result: &result}
+
+		var w countingWriter
+		cfg := newConfig(nil)
+		assert.Nil(t, cfg.streamResponse(&w, res, "", indent))
+		assert.Greater(t, w.writes, STREAMED_RESULT_LENGTH)
+
+		//Same bytes as encoding the response whole, with HTML escaping on
+		assert.Equal(t, encodeWhole(t, res, indent), w.String())
+	}
+}
+
+func TestStreamedBatch(t *testing.T) {
+	rpc := NewJsonRpc(WithIndentedResponses())
+	rpc.RegisterWithName(listing{}, "Listing")
+
+	body := serveTestBody(rpc, `[
+		{"jsonrpc":"2.0","id":"1","method":"Listing.Rows","params":[300]},
+		{"jsonrpc":"2.0","id":"2","method":"Listing.Rows","params":[2]},
+		{"jsonrpc":"2.0","id":"3","method":"Listing.Broken","params":[]},
+		{"jsonrpc":"2.0","method":"Listing.Rows","params":[1]}
+	]`)
+
+	var responses []struct {
+		Id     string `json:"id"`
+		Result []row  `json:"result"`
+		Error  *RpcError
+	}
+	assert.Nil(t, json.Unmarshal([]byte(body), &responses))
+	assert.Len(t, responses, 3)
+	assert.Len(t, responses[0].Result, 300)
+	assert.Equal(t, row{Id: 299, Name: "<row>"}, responses[0].Result[299])
+	assert.Len(t, responses[1].Result, 2)
+
+	//Entries that can not be encoded fail alone
+	assert.Equal(t, "3", responses[2].Id)
+	assert.Equal(t, INTERNAL_ERROR, responses[2].Error.Code)
+	assert.Equal(t, "Response could not be encoded: json: unsupported type: chan int", responses[2].Error.Message)
+
+	//Indented like a batch encoded whole
+	var compact, indented bytes.Buffer
+	assert.Nil(t, json.Compact(&compact, []byte(body)))
+	assert.Nil(t, json.Indent(&indented, compact.Bytes(), "", "  "))
+	assert.Equal(t, indented.String(), body)
+}
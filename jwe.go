@@ -0,0 +1,274 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Named param holding the encrypted params of a request, and field holding the encrypted result of its response
+const JWE_PARAM = "jwe"
+
+type (
+	//JWEKeyResolver returns the key of the client identified by the kid header of a JWE, eg. from a database of
+	//client keys. Keys are 16, 24 or 32 bytes long for A128GCM, A192GCM and A256GCM
+	JWEKeyResolver func(ctx context.Context, kid string) ([]byte, error)
+
+	//Protected header of a JWE
+	jweHeader struct {
+		Alg string `json:"alg"`
+		Enc string `json:"enc"`
+		Kid string `json:"kid,omitempty"`
+	}
+)
+
+var errNotEncrypted = errors.New("Params must be encrypted")
+
+// EncryptedPayloads decrypts the params of requests encrypted as a JWE, eg. by a client using EncryptPayloads, and
+// encrypts their results with the same key, so payloads stay confidential when TLS terminates at a proxy that is
+// not trusted. Encrypted params are sent as {"jwe": "<compact JWE>"}, and results returned the same way. The JWE uses
+// direct encryption with the key keys returns for its kid header, so every client has its own key.
+// Requests with plaintext params or that can not be decrypted fail with INVALID_REQUEST. Error responses and
+// notifications pushed by the server are not encrypted.
+func EncryptedPayloads(keys JWEKeyResolver) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			token, ok := encryptedPayload(req.Params)
+			if !ok {
+				return makeErrorResponse(errNotEncrypted, INVALID_REQUEST, nil, req.Id)
+			}
+
+			header, err := parseJWEHeader(token)
+			if err != nil {
+				return makeErrorResponse(err, INVALID_REQUEST, nil, req.Id)
+			}
+			key, err := keys(ctx, header.Kid)
+			if err != nil {
+				return makeErrorResponse(errors.New(fmt.Sprintf("Unable to decrypt params: %v", err)), INVALID_REQUEST, nil, req.Id)
+			}
+			plaintext, err := decryptJWE(token, key)
+			if err != nil {
+				return makeErrorResponse(err, INVALID_REQUEST, nil, req.Id)
+			}
+
+			var params any
+			if err := json.Unmarshal(plaintext, &params); err != nil {
+				return makeErrorResponse(errors.New("Unable to decode decrypted params"), INVALID_REQUEST, nil, req.Id)
+			}
+			switch params.(type) {
+			case nil, []any, map[string]any:
+			default:
+				return makeErrorResponse(errors.New("Params must be an array or an object"), INVALID_PARAMS, nil, req.Id)
+			}
+
+			decrypted := *req
+			decrypted.Params = params
+
+			res := next(ctx, &decrypted)
+			if res.Error != nil || res.Result == nil {
+				return res
+			}
+
+			encrypted, err := encryptResult(*res.Result, header.Kid, key)
+			if err != nil {
+				return makeErrorResponse(err, INTERNAL_ERROR, nil, req.Id)
+			}
+			res.Result = &encrypted
+
+			return res
+		}
+	}
+}
+
+// EncryptPayloads encrypts the params of every call and notification of a client with the key, as a JWE whose kid
+// header identifies the client, and decrypts the results. The server decrypts them with EncryptedPayloads.
+// The key is 16, 24 or 32 bytes long for A128GCM, A192GCM and A256GCM.
+func EncryptPayloads(kid string, key []byte) ClientMiddleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			params, err := json.Marshal(clientParams(req.Params))
+			if err != nil {
+				return nil, err
+			}
+			token, err := encryptJWE(params, kid, key)
+			if err != nil {
+				return nil, err
+			}
+
+			encrypted := *req
+			encrypted.Params = map[string]any{JWE_PARAM: token}
+
+			res, err := next(ctx, &encrypted)
+			if err != nil || res == nil || res.Result == nil {
+				return res, err
+			}
+
+			raw, ok := (*res.Result).(json.RawMessage)
+			if !ok {
+				if raw, err = json.Marshal(*res.Result); err != nil {
+					return nil, err
+				}
+			}
+
+			var wrapped map[string]string
+			if err := json.Unmarshal(raw, &wrapped); err != nil || wrapped[JWE_PARAM] == "" {
+				return nil, errors.New("Result is not encrypted")
+			}
+			plaintext, err := decryptJWE(wrapped[JWE_PARAM], key)
+			if err != nil {
+				return nil, err
+			}
+
+			decrypted := *res
+			var result any = json.RawMessage(plaintext)
+			decrypted.Result = &result
+
+			return &decrypted, nil
+		}
+	}
+}
+
+// The compact JWE of encrypted params. False when the params are not encrypted
+func encryptedPayload(params any) (string, bool) {
+	named, ok := params.(map[string]any)
+	if !ok || len(named) != 1 {
+		return "", false
+	}
+
+	token, ok := named[JWE_PARAM].(string)
+	return token, ok && token != ""
+}
+
+// Encrypt the encoded result as the value of the jwe field
+func encryptResult(result any, kid string, key []byte) (any, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := encryptJWE(data, kid, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{JWE_PARAM: token}, nil
+}
+
+// Content encryption of each key size
+func jweEncryption(key []byte) (string, error) {
+	switch len(key) {
+	case 16:
+		return "A128GCM", nil
+	case 24:
+		return "A192GCM", nil
+	case 32:
+		return "A256GCM", nil
+	default:
+		return "", errors.New("Key must be 16, 24 or 32 bytes long")
+	}
+}
+
+// Encrypt the plaintext as a compact JWE with direct encryption. The protected header is the additional data
+func encryptJWE(plaintext []byte, kid string, key []byte) (string, error) {
+	enc, err := jweEncryption(key)
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := json.Marshal(jweHeader{Alg: "dir", Enc: enc, Kid: kid})
+	protected := base64.RawURLEncoding.EncodeToString(header)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		protected,
+		"", //No encrypted key with direct encryption
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// Parse the protected header of a compact JWE, without decrypting it
+func parseJWEHeader(token string) (jweHeader, error) {
+	var header jweHeader
+
+	protected, _, _ := strings.Cut(token, ".")
+	data, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil || json.Unmarshal(data, &header) != nil {
+		return header, errors.New("Malformed JWE")
+	}
+	if header.Alg != "dir" {
+		return header, errors.New(fmt.Sprintf("Unsupported JWE algorithm %s", header.Alg))
+	}
+
+	return header, nil
+}
+
+// Decrypt a compact JWE made with direct encryption
+func decryptJWE(token string, key []byte) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return nil, errors.New("Malformed JWE")
+	}
+
+	header, err := parseJWEHeader(token)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := jweEncryption(key)
+	if err != nil {
+		return nil, err
+	}
+	if header.Enc != enc {
+		return nil, errors.New(fmt.Sprintf("Key does not match JWE encryption %s", header.Enc))
+	}
+
+	var decoded [3][]byte
+	for i, part := range parts[2:] {
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			return nil, errors.New("Malformed JWE")
+		}
+	}
+	iv, ciphertext, tag := decoded[0], decoded[1], decoded[2]
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return nil, errors.New("Malformed JWE")
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, errors.New("Unable to decrypt JWE")
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
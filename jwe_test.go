@@ -0,0 +1,118 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testClientKeys = map[string][]byte{
+	"billing": []byte("0123456789abcdef0123456789abcdef"),
+	"reports": []byte("fedcba9876543210"),
+}
+
+func resolveTestClientKey(ctx context.Context, kid string) ([]byte, error) {
+	key, ok := testClientKeys[kid]
+	if !ok {
+		return nil, errors.New("Unknown client " + kid)
+	}
+
+	return key, nil
+}
+
+func TestEncryptedPayloads(t *testing.T) {
+	var received []any
+	rpc := NewJsonRpc(WithMiddleware(
+		func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) Response {
+				received = append(received, req.Params)
+				return next(ctx, req)
+			}
+		},
+		EncryptedPayloads(resolveTestClientKey),
+	))
+	rpc.RegisterWithName(arith{}, "Arith")
+	url := newTestHTTPServer(t, rpc)
+
+	for kid, key := range testClientKeys {
+		received = nil
+		client := NewHTTPClient(url)
+		client.Use(EncryptPayloads(kid, key))
+
+		var sum int
+		assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+		assert.Equal(t, 3, sum)
+
+		//The params travel encrypted
+		token, ok := encryptedPayload(received[0])
+		assert.True(t, ok)
+		assert.NotContains(t, token, "[1,2]")
+	}
+}
+
+func TestEncryptedPayloadsRejected(t *testing.T) {
+	rpc := NewJsonRpc(WithMiddleware(EncryptedPayloads(resolveTestClientKey)))
+	rpc.RegisterWithName(arith{}, "Arith")
+	url := newTestHTTPServer(t, rpc)
+
+	var rpcErr *RpcError
+	err := NewHTTPClient(url).Call(context.Background(), "Arith.Add", []any{1, 2}, nil)
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, INVALID_REQUEST, rpcErr.Code)
+	assert.Equal(t, "Params must be encrypted", rpcErr.Message)
+
+	unknown := NewHTTPClient(url)
+	unknown.Use(EncryptPayloads("payroll", testClientKeys["billing"]))
+	err = unknown.Call(context.Background(), "Arith.Add", []any{1, 2}, nil)
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, "Unable to decrypt params: Unknown client payroll", rpcErr.Message)
+
+	//Encrypted with the key of another client
+	impostor := NewHTTPClient(url)
+	impostor.Use(EncryptPayloads("billing", []byte("0123456789abcdef0123456789abcdeX")))
+	err = impostor.Call(context.Background(), "Arith.Add", []any{1, 2}, nil)
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, "Unable to decrypt JWE", rpcErr.Message)
+}
+
+func TestDecryptTamperedJWE(t *testing.T) {
+	key := testClientKeys["billing"]
+	token, err := encryptJWE([]byte(`[1,2]`), "billing", key)
+	assert.Nil(t, err)
+
+	plaintext, err := decryptJWE(token, key)
+	assert.Nil(t, err)
+	assert.Equal(t, `[1,2]`, string(plaintext))
+
+	parts := strings.Split(token, ".")
+	header, _ := parseJWEHeader(token)
+	assert.Equal(t, jweHeader{Alg: "dir", Enc: "A256GCM", Kid: "billing"}, header)
+
+	//The header is authenticated too
+	forged, _ := encryptJWE([]byte(`[1,2]`), "reports", key)
+	parts[0] = strings.Split(forged, ".")[0]
+	_, err = decryptJWE(strings.Join(parts, "."), key)
+	assert.Equal(t, "Unable to decrypt JWE", err.Error())
+
+	_, err = decryptJWE("a.b.c", key)
+	assert.Equal(t, "Malformed JWE", err.Error())
+
+	_, err = encryptJWE([]byte(`[]`), "billing", []byte("short"))
+	assert.Equal(t, "Key must be 16, 24 or 32 bytes long", err.Error())
+}
+
+func TestClientRejectsPlaintextResult(t *testing.T) {
+	client := NewHTTPClient(newTestHTTPServer(t, NewJsonRpc(WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			var result any = 3
+			return makeSuccessResponse(&result, req.Id)
+		}
+	}))))
+	client.Use(EncryptPayloads("billing", testClientKeys["billing"]))
+
+	err := client.Call(context.Background(), "Arith.Add", []any{1, 2}, nil)
+	assert.Equal(t, "Result is not encrypted", err.Error())
+}
@@ -0,0 +1,114 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Bounds the number of concurrent calls of a method
+type methodLimiter struct {
+	slots     chan struct{}
+	mu        sync.Mutex
+	queued    int
+	maxQueued int
+}
+
+// WithMethodConcurrency limits how many calls of a method run at the same time.
+// method is the full method name. eg. Reports.Build.
+// Calls over the limit are rejected with SERVER_BUSY unless a queue is configured with WithMethodQueue.
+func WithMethodConcurrency(method string, limit int) Option {
+	return func(c *config) {
+		c.limiter(method).slots = make(chan struct{}, limit)
+	}
+}
+
+// WithMethodQueue lets up to maxQueued calls wait for a free slot when the method is at its concurrency limit.
+// Calls beyond the queue are rejected with SERVER_BUSY.
+func WithMethodQueue(method string, maxQueued int) Option {
+	return func(c *config) {
+		c.limiter(method).maxQueued = maxQueued
+	}
+}
+
+func (c *config) limiter(method string) *methodLimiter {
+	if c.methodLimits == nil {
+		c.methodLimits = make(map[string]*methodLimiter)
+	}
+
+	l, ok := c.methodLimits[method]
+	if !ok {
+		l = &methodLimiter{}
+		c.methodLimits[method] = l
+	}
+
+	return l
+}
+
+var errServerBusy = errors.New("Server busy")
+
+// acquire takes a slot, waiting in the queue if allowed
+func (l *methodLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	l.mu.Lock()
+	if l.queued >= l.maxQueued {
+		l.mu.Unlock()
+		return errServerBusy
+	}
+	l.queued++
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+	}()
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *methodLimiter) release() {
+	<-l.slots
+}
+
+// Call the method once the concurrency limit of the method and the scheduler allow it. Call this in a go routine
+func (rpc *jsonRpcImpl) callLimited(ctx context.Context, cfg *config, srv *service, methodName string, args []any, id *string, respChan chan callerSuccess, errChan chan callerError) {
+	fullName := srv.fullName(methodName)
+
+	if l, ok := cfg.methodLimits[fullName]; ok && l.slots != nil {
+		if err := l.acquire(ctx); err != nil {
+			if errors.Is(err, errServerBusy) {
+				errChan <- callerError{
+					err:    errors.New(fmt.Sprintf("Server busy. Too many concurrent calls of %s", fullName)),
+					code:   SERVER_BUSY,
+					reqId:  id,
+					method: fullName,
+				}
+			}
+			//Otherwise the request was cancelled while queued and the caller has already given up on it
+			return
+		}
+		defer l.release()
+	}
+
+	//Waiting for a method slot does not hold a worker of the scheduler
+	if s := cfg.scheduler; s != nil {
+		if err := s.acquire(ctx, cfg.methodPriority(fullName)); err != nil {
+			return
+		}
+		defer s.release()
+	}
+
+	srv.call(ctx, methodName, args, id, respChan, errChan)
+}
@@ -0,0 +1,84 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type slowService struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s slowService) Wait(ctx context.Context) (string, error, *RpcErrorCode) {
+	s.started <- struct{}{}
+	<-s.release
+	return "done", nil, nil
+}
+
+func newSlowService() slowService {
+	return slowService{started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func callSlow(t *testing.T, rpc JsonRPC, id string) *Response {
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Slow.Wait", Params: []any{}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Error(err)
+	}
+
+	return res
+}
+
+func TestMethodConcurrencyRejects(t *testing.T) {
+	srv := newSlowService()
+	rpc := NewJsonRpc(WithMethodConcurrency("Slow.Wait", 1))
+	rpc.RegisterWithName(srv, "Slow")
+
+	var first *Response
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = callSlow(t, rpc, "1")
+	}()
+	<-srv.started
+
+	second := callSlow(t, rpc, "2")
+	assert.Equal(t, SERVER_BUSY, second.Error.Code)
+
+	close(srv.release)
+	wg.Wait()
+	assert.Equal(t, "done", *first.Result)
+}
+
+func TestMethodConcurrencyQueues(t *testing.T) {
+	srv := newSlowService()
+	rpc := NewJsonRpc(WithMethodConcurrency("Slow.Wait", 1), WithMethodQueue("Slow.Wait", 1))
+	rpc.RegisterWithName(srv, "Slow")
+
+	results := make(chan *Response, 2)
+	go func() { results <- callSlow(t, rpc, "1") }()
+	<-srv.started
+	go func() { results <- callSlow(t, rpc, "2") }()
+
+	//Wait for the second call to be queued, the third one is rejected
+	assert.Eventually(t, func() bool {
+		l := rpc.(*jsonRpcImpl).config.methodLimits["Slow.Wait"]
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.queued == 1
+	}, time.Second, time.Millisecond)
+
+	third := callSlow(t, rpc, "3")
+	assert.Equal(t, SERVER_BUSY, third.Error.Code)
+
+	close(srv.release)
+	for i := 0; i < 2; i++ {
+		res := <-results
+		assert.Nil(t, res.Error)
+	}
+}
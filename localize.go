@@ -0,0 +1,94 @@
+package jsonrpc2
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Localizer translates the message of an error to the locale of the caller, eg. "fr" or "pt-BR". Returning an empty
+// string keeps the original message
+type Localizer func(locale string, code RpcErrorCode, message string) string
+
+type localeKey struct{}
+
+// WithLocalizer translates the messages of errors before responses are serialized, so handlers return errors in a
+// single language. The locale is the one set with WithLocale, or the preferred language of the Accept-Language
+// header of HTTP requests. Errors are not translated when the locale is unknown.
+// The access log and the audit trail keep the original messages.
+func WithLocalizer(localizer Localizer) Option {
+	return func(c *config) {
+		c.localizer = localizer
+	}
+}
+
+// WithLocale sets the locale errors of the request are translated to, eg. from a ContextHook reading the profile
+// of the caller. It takes precedence over the Accept-Language header
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale of the request being handled: the one set with WithLocale, or the preferred
+// language of the Accept-Language header. Empty when it is unknown
+func LocaleFromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok {
+		return locale
+	}
+
+	if r := HTTPRequestFromContext(ctx); r != nil {
+		return preferredLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	return ""
+}
+
+// Translate the error of the response, if any
+func (c *config) localize(ctx context.Context, res Response) Response {
+	if c.localizer == nil || res.Error == nil {
+		return res
+	}
+
+	locale := LocaleFromContext(ctx)
+	if locale == "" {
+		return res
+	}
+
+	message := c.localizer(locale, res.Error.Code, res.Error.Message)
+	if message == "" || message == res.Error.Message {
+		return res
+	}
+
+	//Interceptors may return shared errors
+	translated := *res.Error
+	translated.Message = message
+	res.Error = &translated
+
+	return res
+}
+
+// Language with the highest quality in an Accept-Language header. eg. "fr-CH, fr;q=0.9, en;q=0.8" is fr-CH
+func preferredLanguage(header string) string {
+	preferred, best := "", 0.0
+	for _, entry := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		if quality > best {
+			preferred, best = tag, quality
+		}
+	}
+
+	return preferred
+}
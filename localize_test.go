@@ -0,0 +1,80 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLocalizer(locale string, code RpcErrorCode, message string) string {
+	if strings.HasPrefix(locale, "fr") && message == "Some error here" {
+		return "Une erreur ici"
+	}
+
+	return ""
+}
+
+func localizedErrorMessage(t *testing.T, rpc JsonRPC, acceptLanguage string) string {
+	body := `{"jsonrpc":"2.0","id":"1","method":"Arith.ErrorMethod"}`
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if acceptLanguage != "" {
+		r.Header.Set("Accept-Language", acceptLanguage)
+	}
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+
+	var res Response
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	return res.Error.Message
+}
+
+func TestLocalizeFromAcceptLanguage(t *testing.T) {
+	rpc := NewJsonRpc(WithLocalizer(testLocalizer))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	assert.Equal(t, "Une erreur ici", localizedErrorMessage(t, rpc, "en;q=0.5, fr-CA"))
+	assert.Equal(t, "Some error here", localizedErrorMessage(t, rpc, "de"))
+	assert.Equal(t, "Some error here", localizedErrorMessage(t, rpc, ""))
+}
+
+func TestLocalizeFromContext(t *testing.T) {
+	rpc := NewJsonRpc(
+		WithLocalizer(testLocalizer),
+		WithContextHook(func(ctx context.Context, r *http.Request, req Request) context.Context {
+			return WithLocale(ctx, "fr")
+		}),
+	)
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	assert.Equal(t, "Une erreur ici", localizedErrorMessage(t, rpc, "en"))
+}
+
+func TestLocalizeAfterInterceptors(t *testing.T) {
+	var intercepted string
+	rpc := NewJsonRpc(
+		WithLocalizer(testLocalizer),
+		WithResponseInterceptor(func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError) {
+			intercepted = err.Message
+			return result, err
+		}),
+	)
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	assert.Equal(t, "Une erreur ici", localizedErrorMessage(t, rpc, "fr"))
+	assert.Equal(t, "Some error here", intercepted)
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	assert.Equal(t, "fr-CH", preferredLanguage("fr-CH, fr;q=0.9, en;q=0.8"))
+	assert.Equal(t, "en", preferredLanguage("de;q=0.2, *, en;q=0.7"))
+	assert.Equal(t, "", preferredLanguage("de;q=0"))
+	assert.Equal(t, "", preferredLanguage(""))
+}
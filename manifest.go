@@ -0,0 +1,203 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	//ServerConfig is the declarative configuration of a server, usually loaded from a YAML or JSON file by LoadConfig,
+	//so servers are tuned without code changes. Durations are strings such as 500ms or 1m
+	ServerConfig struct {
+		Timeouts   TimeoutsConfig   `yaml:"timeouts"`
+		Limits     LimitsConfig     `yaml:"limits"`
+		Auth       AuthConfig       `yaml:"auth"`
+		Methods    MethodsConfig    `yaml:"methods"`
+		Transports TransportsConfig `yaml:"transports"`
+
+		ipFilter *IPFilter //Parsed from Auth. Nil when no network is listed
+	}
+
+	//Deadlines of calls and batches. Zero means no deadline
+	TimeoutsConfig struct {
+		Call    time.Duration            `yaml:"call"`
+		Batch   time.Duration            `yaml:"batch"`
+		Methods map[string]time.Duration `yaml:"methods"` //Deadlines overriding call, by full method name
+	}
+
+	//Sizes and concurrency of requests. Zero means no limit
+	LimitsConfig struct {
+		MaxRequestSize    int64          `yaml:"maxRequestSize"` //Bytes of an HTTP body
+		BatchConcurrency  int            `yaml:"batchConcurrency"`
+		MethodConcurrency map[string]int `yaml:"methodConcurrency"` //Calls of a method at once
+		MethodQueue       map[string]int `yaml:"methodQueue"`       //Calls waiting for a slot
+	}
+
+	//Networks allowed to call the server, as for NewIPFilter
+	AuthConfig struct {
+		Allow          []string `yaml:"allow"`
+		Deny           []string `yaml:"deny"`
+		TrustedProxies []string `yaml:"trustedProxies"`
+	}
+
+	//Methods clients can call, as for WithExposedMethods. Every method when empty
+	MethodsConfig struct {
+		Expose []string `yaml:"expose"`
+	}
+
+	//Addresses the server listens on. Transports without an address are not served
+	TransportsConfig struct {
+		HTTP string    `yaml:"http"`
+		TCP  string    `yaml:"tcp"`
+		TLS  TLSConfig `yaml:"tls"`
+	}
+
+	//Raw sockets over TLS
+	TLSConfig struct {
+		Addr      string `yaml:"addr"`
+		Cert      string `yaml:"cert"`      //Certificate file of the server
+		Key       string `yaml:"key"`       //Key file of the certificate
+		ClientCAs string `yaml:"clientCAs"` //CA file verifying client certificates. Not verified when empty
+	}
+)
+
+// LoadConfig reads the configuration of a server from a YAML or JSON file. Unknown keys are errors, so a misspelled
+// setting is not silently ignored.
+//
+//	rpc := jsonrpc2.NewJsonRpc(cfg.Options()...)
+//	rpc.Register(new(Arith))
+//	err = cfg.ListenAndServe(rpc)
+func LoadConfig(path string) (*ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid config %s: %v", path, err))
+	}
+
+	return cfg, nil
+}
+
+// ParseConfig reads the configuration of a server from YAML or JSON, which is valid YAML
+func ParseConfig(data []byte) (*ServerConfig, error) {
+	cfg := &ServerConfig{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	//An empty document keeps the defaults
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(cfg.Auth.Allow)+len(cfg.Auth.Deny)+len(cfg.Auth.TrustedProxies) > 0 {
+		filter, err := NewIPFilter(IPFilterOptions{Allow: cfg.Auth.Allow, Deny: cfg.Auth.Deny, TrustedProxies: cfg.Auth.TrustedProxies})
+		if err != nil {
+			return nil, err
+		}
+		cfg.ipFilter = filter
+	}
+
+	tls := cfg.Transports.TLS
+	if tls.Addr != "" && (tls.Cert == "" || tls.Key == "") {
+		return nil, errors.New("A certificate and its key are required to serve TLS")
+	}
+
+	return cfg, nil
+}
+
+// Options configure a server as the configuration says. Options passed after them to NewJsonRpc override them
+func (c *ServerConfig) Options() []Option {
+	var opts []Option
+
+	if c.Timeouts.Call > 0 {
+		opts = append(opts, WithTimeout(c.Timeouts.Call))
+	}
+	if c.Timeouts.Batch > 0 {
+		opts = append(opts, WithBatchTimeout(c.Timeouts.Batch))
+	}
+	for method, timeout := range c.Timeouts.Methods {
+		opts = append(opts, WithMethodTimeout(method, timeout))
+	}
+
+	if c.Limits.MaxRequestSize > 0 {
+		opts = append(opts, WithMaxRequestSize(c.Limits.MaxRequestSize))
+	}
+	if c.Limits.BatchConcurrency > 0 {
+		opts = append(opts, WithBatchConcurrency(c.Limits.BatchConcurrency))
+	}
+	for method, limit := range c.Limits.MethodConcurrency {
+		opts = append(opts, WithMethodConcurrency(method, limit))
+	}
+	for method, maxQueued := range c.Limits.MethodQueue {
+		opts = append(opts, WithMethodQueue(method, maxQueued))
+	}
+
+	if c.ipFilter != nil {
+		opts = append(opts, WithIPFilter(c.ipFilter))
+	}
+	if len(c.Methods.Expose) > 0 {
+		opts = append(opts, WithExposedMethods(c.Methods.Expose...))
+	}
+
+	return opts
+}
+
+// ListenAndServe starts the services of the server, then serves it on every transport of the configuration. It
+// returns the first error of one of them, once it stopped serving. Without any transport it fails at once.
+func (c *ServerConfig) ListenAndServe(rpc JsonRPC) error {
+	var serve []func() error
+
+	if c.Transports.HTTP != "" {
+		addr := c.Transports.HTTP
+		serve = append(serve, func() error {
+			return http.ListenAndServe(addr, rpc)
+		})
+	}
+	if c.Transports.TCP != "" {
+		addr := c.Transports.TCP
+		serve = append(serve, func() error {
+			l, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			return rpc.Serve(l)
+		})
+	}
+	if tls := c.Transports.TLS; tls.Addr != "" {
+		opts := []TLSOption{WithCertificate(tls.Cert, tls.Key)}
+		if tls.ClientCAs != "" {
+			opts = append(opts, WithClientCAs(tls.ClientCAs))
+		}
+		serve = append(serve, func() error {
+			return rpc.ListenAndServeTLS(tls.Addr, opts...)
+		})
+	}
+
+	if len(serve) == 0 {
+		return errors.New("No transport to serve in the config")
+	}
+	if err := rpc.Start(context.Background()); err != nil {
+		return err
+	}
+
+	errs := make(chan error, len(serve))
+	for _, s := range serve {
+		go func(s func() error) {
+			errs <- s()
+		}(s)
+	}
+
+	return <-errs
+}
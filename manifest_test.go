@@ -0,0 +1,95 @@
+package jsonrpc2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testServerConfig = `
+timeouts:
+  call: 2s
+  batch: 5s
+  methods:
+    Arith.Add: 50ms
+limits:
+  maxRequestSize: 1024
+  batchConcurrency: 4
+  methodConcurrency:
+    Arith.Add: 2
+  methodQueue:
+    Arith.Add: 8
+auth:
+  allow: [10.0.0.0/8]
+methods:
+  expose: [Arith.Add]
+transports:
+  http: ":8080"
+`
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(testServerConfig), 0o600))
+
+	cfg, err := LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*time.Second, cfg.Timeouts.Call)
+	assert.Equal(t, 50*time.Millisecond, cfg.Timeouts.Methods["Arith.Add"])
+	assert.Equal(t, ":8080", cfg.Transports.HTTP)
+
+	c := newConfig(cfg.Options())
+	assert.Equal(t, 2*time.Second, c.timeout)
+	assert.Equal(t, 5*time.Second, c.batchTimeout)
+	assert.Equal(t, 50*time.Millisecond, c.methodTimeout("Arith.Add"))
+	assert.Equal(t, int64(1024), c.maxRequestSize)
+	assert.Equal(t, 4, c.batchConcurrency)
+	assert.Equal(t, 2, cap(c.methodLimits["Arith.Add"].slots))
+	assert.Equal(t, 8, c.methodLimits["Arith.Add"].maxQueued)
+	assert.NotNil(t, c.ipFilter)
+	assert.False(t, c.exposes("Arith.ErrorMethod"))
+
+	rpc := NewJsonRpc(cfg.Options()...)
+	rpc.RegisterWithName(arith{}, "Arith")
+	res := serveFromAddr(rpc, "10.1.2.3:4000", nil, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":3}`, res.Body.String())
+}
+
+func TestParseConfig(t *testing.T) {
+	//JSON is valid YAML, and empty documents keep the defaults
+	cfg, err := ParseConfig([]byte(`{"timeouts":{"call":"1m"},"transports":{"tcp":":9000"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Minute, cfg.Timeouts.Call)
+	assert.Equal(t, ":9000", cfg.Transports.TCP)
+
+	cfg, err = ParseConfig([]byte("# nothing yet\n"))
+	assert.Nil(t, err)
+	assert.Empty(t, cfg.Options())
+
+	_, err = ParseConfig([]byte("timeout:\n  call: 1s\n"))
+	assert.Contains(t, err.Error(), "field timeout not found")
+
+	_, err = ParseConfig([]byte("auth:\n  deny: [10.0.0.0/40]\n"))
+	assert.Equal(t, `Invalid network "10.0.0.0/40"`, err.Error())
+
+	_, err = ParseConfig([]byte("transports:\n  tls:\n    addr: :9443\n"))
+	assert.Equal(t, "A certificate and its key are required to serve TLS", err.Error())
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestConfigListenAndServe(t *testing.T) {
+	cfg, _ := ParseConfig(nil)
+	assert.Equal(t, "No transport to serve in the config", cfg.ListenAndServe(NewJsonRpc()).Error())
+
+	//Services that do not start are not served
+	var log []string
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(&warehouse{name: "a", log: &log, failing: true}, "A")
+	cfg, _ = ParseConfig([]byte("transports:\n  tcp: 127.0.0.1:0\n"))
+	assert.Equal(t, "Starting service A failed: no connection", cfg.ListenAndServe(rpc).Error())
+
+}
@@ -0,0 +1,78 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net/http"
+)
+
+type (
+	//Metadata are key/value pairs carried by the context of a call, eg. a correlation id or an auth token.
+	//Keys are canonical HTTP header names, eg. X-Request-Id
+	Metadata map[string]string
+
+	metadataKey struct{}
+)
+
+// WithMetadataHeaders makes the server read the headers of HTTP requests into the metadata of their context.
+// Clients calling other services with that context send the metadata along as headers, so correlation ids and
+// tokens propagate across services. Only the listed headers are read, since any of them is forwarded downstream.
+func WithMetadataHeaders(headers ...string) Option {
+	return func(c *config) {
+		for _, header := range headers {
+			c.metadataHeaders = append(c.metadataHeaders, http.CanonicalHeaderKey(header))
+		}
+	}
+}
+
+// ContextWithMetadata returns a context carrying the metadata of ctx and the pair. Clients send it as a header
+// with the calls made with the context
+func ContextWithMetadata(ctx context.Context, key, value string) context.Context {
+	parent := MetadataFromContext(ctx)
+	metadata := make(Metadata, len(parent)+1)
+	for k, v := range parent {
+		metadata[k] = v
+	}
+	metadata[http.CanonicalHeaderKey(key)] = value
+
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// MetadataFromContext returns the metadata carried by the context, eg. the one of the request being handled.
+// It must not be modified. Nil when there is none
+func MetadataFromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+	return metadata
+}
+
+// Get the value of the key. Empty when it is not set
+func (m Metadata) Get(key string) string {
+	return m[http.CanonicalHeaderKey(key)]
+}
+
+// Read the metadata headers of the request into the context
+func (c *config) requestMetadata(ctx context.Context, header http.Header) context.Context {
+	if len(c.metadataHeaders) == 0 {
+		return ctx
+	}
+
+	metadata := make(Metadata, len(c.metadataHeaders))
+	for _, key := range c.metadataHeaders {
+		if value := header.Get(key); value != "" {
+			metadata[key] = value
+		}
+	}
+	if len(metadata) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// Set the metadata of the context as headers, unless the call sets them explicitly
+func setMetadataHeaders(ctx context.Context, header http.Header, explicit http.Header) {
+	for key, value := range MetadataFromContext(ctx) {
+		if _, ok := explicit[key]; !ok {
+			header.Set(key, value)
+		}
+	}
+}
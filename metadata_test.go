@@ -0,0 +1,70 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Calls the downstream service with the context of its requests
+type testGateway struct {
+	downstream *Client
+}
+
+func (g testGateway) Forward(ctx context.Context) (string, error, *RpcErrorCode) {
+	var res string
+	if err := g.downstream.Call(ctx, "Echo.RequestId", nil, &res); err != nil {
+		return "", err, nil
+	}
+
+	return res, nil, nil
+}
+
+type testEcho struct{}
+
+func (testEcho) RequestId(ctx context.Context) (string, error, *RpcErrorCode) {
+	metadata := MetadataFromContext(ctx)
+	return metadata.Get("x-request-id") + " " + metadata.Get("Authorization"), nil, nil
+}
+
+func TestMetadataPropagation(t *testing.T) {
+	downstream := NewJsonRpc(WithMetadataHeaders("X-Request-Id", "Authorization"))
+	downstream.RegisterWithName(testEcho{}, "Echo")
+
+	gateway := NewJsonRpc(WithMetadataHeaders("x-request-id", "authorization"))
+	gateway.RegisterWithName(testGateway{downstream: NewHTTPClient(newTestHTTPServer(t, downstream))}, "Gateway")
+
+	client := NewHTTPClient(newTestHTTPServer(t, gateway))
+	ctx := ContextWithMetadata(context.Background(), "X-Request-Id", "req-1")
+	ctx = ContextWithHeader(ctx, "Authorization", "Bearer token")
+
+	var res string
+	assert.Nil(t, client.Call(ctx, "Gateway.Forward", nil, &res))
+	assert.Equal(t, "req-1 Bearer token", res)
+}
+
+func TestMetadataHeadersNotListed(t *testing.T) {
+	rpc := NewJsonRpc(WithMetadataHeaders("X-Request-Id"))
+	rpc.RegisterWithName(testEcho{}, "Echo")
+
+	client := NewHTTPClient(newTestHTTPServer(t, rpc))
+	ctx := ContextWithHeader(context.Background(), "Authorization", "Bearer token")
+
+	var res string
+	assert.Nil(t, client.Call(ctx, "Echo.RequestId", nil, &res))
+	assert.Equal(t, " ", res)
+}
+
+func TestContextWithMetadata(t *testing.T) {
+	ctx := ContextWithMetadata(context.Background(), "x-tenant", "acme")
+	child := ContextWithMetadata(ctx, "X-Request-Id", "req-1")
+
+	assert.Equal(t, Metadata{"X-Tenant": "acme"}, MetadataFromContext(ctx))
+	assert.Equal(t, Metadata{"X-Tenant": "acme", "X-Request-Id": "req-1"}, MetadataFromContext(child))
+
+	header := http.Header{}
+	setMetadataHeaders(child, header, http.Header{"X-Tenant": {"other"}})
+	assert.Equal(t, http.Header{"X-Request-Id": {"req-1"}}, header)
+}
@@ -0,0 +1,34 @@
+package jsonrpc2
+
+import "context"
+
+type (
+	//Handler produces the response of a single request, including each entry of a batch
+	Handler func(ctx context.Context, req *Request) Response
+
+	//Middleware wraps the handling of every request. Use it for auth, rate limiting or tracing.
+	//A middleware can answer a request itself by returning a response without calling next.
+	Middleware func(next Handler) Handler
+)
+
+// WithMiddleware adds middleware around every request. The first middleware added is the outermost.
+// Middleware run after the version of the request is checked and before the method is resolved.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *config) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// Wrap the handler with the middleware of the settings
+func (c *config) chain(handler Handler) Handler {
+	return chainMiddleware(c.middleware, handler)
+}
+
+// Wrap the handler so the first middleware is the outermost
+func chainMiddleware(middleware []Middleware, handler Handler) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
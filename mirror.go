@@ -0,0 +1,76 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"time"
+)
+
+const (
+	//Deadline of a mirrored request, unless the options set another
+	MIRROR_TIMEOUT = 5 * time.Second
+	//Mirrored requests in flight, unless the options set another. Requests beyond that are not mirrored
+	MIRROR_MAX_IN_FLIGHT = 64
+)
+
+// Options of Mirror
+type MirrorOptions struct {
+	Rate        float64       //Fraction of the requests copied, between 0 and 1
+	Methods     []string      //Methods whose requests are copied. Every method when empty
+	Timeout     time.Duration //Deadline of a mirrored request. Defaults to MIRROR_TIMEOUT
+	MaxInFlight int           //Mirrored requests in flight. Defaults to MIRROR_MAX_IN_FLIGHT
+}
+
+// Mirror copies a fraction of the incoming requests to the target in the background and ignores its responses,
+// eg. to validate a new implementation of a service against production traffic. Callers are answered by the
+// server as usual and never wait for the target. Requests are dropped instead of mirrored while the target is
+// slower than the traffic and MaxInFlight requests are pending.
+func Mirror(target ClientTransport, opts MirrorOptions) Middleware {
+	if opts.Timeout == 0 {
+		opts.Timeout = MIRROR_TIMEOUT
+	}
+	if opts.MaxInFlight == 0 {
+		opts.MaxInFlight = MIRROR_MAX_IN_FLIGHT
+	}
+
+	methods := make(map[string]bool, len(opts.Methods))
+	for _, method := range opts.Methods {
+		methods[method] = true
+	}
+	slots := make(chan struct{}, opts.MaxInFlight)
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			if len(methods) > 0 && !methods[req.Method] {
+				return next(ctx, req)
+			}
+			if opts.Rate <= 0 || (opts.Rate < 1 && rand.Float64() >= opts.Rate) {
+				return next(ctx, req)
+			}
+
+			//Encoded now since the request can change once next returns
+			msg, err := json.Marshal(req)
+			if err != nil {
+				return next(ctx, req)
+			}
+			notification := req.Id == nil
+
+			select {
+			case slots <- struct{}{}:
+				go func() {
+					defer func() { <-slots }()
+
+					//The request context ends with the response, so the copy gets its own
+					mirrorCtx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+					defer cancel()
+
+					target.RoundTrip(mirrorCtx, msg, notification)
+				}()
+			default:
+			}
+
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Transport recording the messages it receives. Round trips block until release is closed
+type recordingTransport struct {
+	messages chan string
+	release  chan struct{}
+}
+
+func newRecordingTransport() *recordingTransport {
+	release := make(chan struct{})
+	close(release)
+
+	return &recordingTransport{messages: make(chan string, 16), release: release}
+}
+
+func (t *recordingTransport) RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error) {
+	t.messages <- string(msg)
+	select {
+	case <-t.release:
+	case <-ctx.Done():
+	}
+
+	return []byte(`{"jsonrpc":"2.0","id":"1","result":0}`), nil
+}
+
+func (t *recordingTransport) Close() error {
+	return nil
+}
+
+func TestMirror(t *testing.T) {
+	target := newRecordingTransport()
+	rpc := NewJsonRpc(WithMiddleware(Mirror(target, MirrorOptions{Rate: 1})))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+
+	assert.Equal(t, any(float64(3)), *res.Result)
+	select {
+	case msg := <-target.messages:
+		assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`, msg)
+	case <-time.After(time.Second):
+		t.Fatal("Request not mirrored")
+	}
+}
+
+func TestMirrorMethodsAndRate(t *testing.T) {
+	target := newRecordingTransport()
+	rpc := NewJsonRpc(
+		WithMiddleware(Mirror(target, MirrorOptions{Rate: 1, Methods: []string{"Arith.ErrorMethod"}})),
+		WithMiddleware(Mirror(target, MirrorOptions{Rate: 0})),
+	)
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	callMethod(t, rpc, "Arith.Add", []any{1, 2})
+
+	select {
+	case msg := <-target.messages:
+		t.Fatalf("Unexpected mirrored request %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMirrorDoesNotWaitForTarget(t *testing.T) {
+	target := newRecordingTransport()
+	target.release = make(chan struct{})
+	defer close(target.release)
+
+	rpc := NewJsonRpc(WithMiddleware(Mirror(target, MirrorOptions{Rate: 1, MaxInFlight: 1})))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	start := time.Now()
+	callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	callMethod(t, rpc, "Arith.Add", []any{3, 4})
+
+	assert.Less(t, time.Since(start), time.Second)
+
+	//The second request is dropped while the first is pending
+	<-target.messages
+	select {
+	case msg := <-target.messages:
+		t.Fatalf("Unexpected mirrored request %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
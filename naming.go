@@ -0,0 +1,82 @@
+package jsonrpc2
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MethodNamer maps the name of an exported Go method to the name it is called by, eg. GetUserByID to get_user_by_id
+type MethodNamer func(goName string) string
+
+// WithMethodNamer sets how the methods of the services registered afterwards are named.
+// ServiceOptions.MethodNamer overrides it for a service. Methods keep their Go name by default.
+func WithMethodNamer(namer MethodNamer) Option {
+	return func(c *config) {
+		c.methodNamer = namer
+	}
+}
+
+// ExactNames keeps the Go name of methods. eg. GetUserByID
+func ExactNames(goName string) string {
+	return goName
+}
+
+// SnakeCase names methods in snake case, keeping acronyms together. eg. GetUserByID is get_user_by_id
+func SnakeCase(goName string) string {
+	runes := []rune(goName)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && startsWord(runes, i) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// CamelCase names methods in lower camel case. eg. GetUserByID is getUserByID and HTTPStatus is httpStatus
+func CamelCase(goName string) string {
+	runes := []rune(goName)
+
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	//The last upper case letter of an acronym starts the next word. eg. the S of HTTPStatus
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	if upper == 0 && len(runes) > 0 {
+		runes[0] = unicode.ToLower(runes[0])
+	}
+
+	return string(runes)
+}
+
+// Whether the upper case letter at i starts a word: after a lower case letter or digit, or ending an acronym
+func startsWord(runes []rune, i int) bool {
+	prev := runes[i-1]
+	if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+		return true
+	}
+
+	return unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+}
+
+// Namer of a service: its own, else the one of the server. The config can be nil
+func (c *config) serviceNamer(opts ServiceOptions) MethodNamer {
+	if opts.MethodNamer != nil {
+		return opts.MethodNamer
+	}
+	if c != nil && c.methodNamer != nil {
+		return c.methodNamer
+	}
+
+	return ExactNames
+}
@@ -0,0 +1,89 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type accountsAPI struct{}
+
+func (accountsAPI) GetUserByID(ctx context.Context, id int) (int, error, *RpcErrorCode) {
+	return id, nil, nil
+}
+
+func (accountsAPI) HTTPStatus(ctx context.Context) (string, error, *RpcErrorCode) {
+	return "ok", nil, nil
+}
+
+type clashingAPI struct{}
+
+func (clashingAPI) GetID(ctx context.Context) (string, error, *RpcErrorCode) {
+	return "", nil, nil
+}
+
+func (clashingAPI) GetId(ctx context.Context) (string, error, *RpcErrorCode) {
+	return "", nil, nil
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"GetUserByID": "get_user_by_id",
+		"HTTPStatus":  "http_status",
+		"ID":          "id",
+		"Add":         "add",
+		"UserID2":     "user_id2",
+		"V2Request":   "v2_request",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, SnakeCase(name), name)
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"GetUserByID": "getUserByID",
+		"HTTPStatus":  "httpStatus",
+		"ID":          "id",
+		"Add":         "add",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, CamelCase(name), name)
+	}
+}
+
+func TestMethodNamer(t *testing.T) {
+	rpc := NewJsonRpc(WithMethodNamer(SnakeCase))
+	rpc.RegisterWithName(accountsAPI{}, "accounts")
+
+	assert.Equal(t, any(float64(7)), *callMethod(t, rpc, "accounts.get_user_by_id", []any{7}).Result)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "accounts.GetUserByID", []any{7}).Error.Code)
+}
+
+func TestMethodNamerPerService(t *testing.T) {
+	rpc := NewJsonRpc(WithMethodNamer(SnakeCase))
+	rpc.RegisterWithOptions(accountsAPI{}, ServiceOptions{
+		Name:        "accounts",
+		MethodNamer: CamelCase,
+		Docs:        map[string]MethodDoc{"HTTPStatus": {Description: "Status of the service"}},
+	})
+	rpc.RegisterRoot(node{})
+
+	assert.Equal(t, any("ok"), *callMethod(t, rpc, "accounts.httpStatus", nil).Result)
+	assert.Equal(t, any("pong"), *callMethod(t, rpc, "ping", nil).Result)
+
+	services, _, _ := introspection{rpc: rpc.(*jsonRpcImpl)}.Describe(context.Background(), "accounts")
+	assert.Equal(t, "accounts.httpStatus", services[0].Methods[1].Name)
+	assert.Equal(t, "Status of the service", services[0].Methods[1].Description)
+}
+
+func TestMethodNamerCollision(t *testing.T) {
+	rpc := NewJsonRpc(WithMethodNamer(SnakeCase))
+
+	err := rpc.Register(clashingAPI{})
+
+	assert.Equal(t, "Methods of service clashingAPI are both named get_id", err.Error())
+}
@@ -0,0 +1,125 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type (
+	//NATSConn is the part of a NATS connection used by the NATS transport. Adapt *nats.Conn to it, see the README.
+	//The package does not depend on a NATS client so applications pick its version.
+	NATSConn interface {
+		//Call handler with the data and reply subject of the messages on subject. Subscribers sharing queue split the messages
+		QueueSubscribe(subject, queue string, handler func(data []byte, reply string)) (unsubscribe func() error, err error)
+
+		//Publish data on subject without waiting for a reply
+		Publish(subject string, data []byte) error
+
+		//Publish data on subject and wait for the reply
+		Request(ctx context.Context, subject string, data []byte) ([]byte, error)
+	}
+
+	//NATSOptions configure how a server is exposed over NATS
+	NATSOptions struct {
+		Subject            string //Subject the requests are received on
+		Queue              string //Queue group of the servers splitting the requests. Defaults to Subject
+		NotificationPrefix string //Published notifications go to NotificationPrefix.<topic>. Not forwarded when empty
+	}
+
+	//NATSTransport sends each message as a NATS request. Notifications are published without waiting for a reply
+	NATSTransport struct {
+		Conn    NATSConn
+		Subject string //Subject the server receives requests on
+	}
+)
+
+// ServeNATS handles the requests received on the subject of opts and publishes the responses to their reply subject.
+// Requests are handled concurrently. Stop unsubscribes and cancels the calls in flight.
+func (rpc *jsonRpcImpl) ServeNATS(conn NATSConn, opts NATSOptions) (stop func() error, err error) {
+	if opts.Subject == "" {
+		return nil, errors.New("NATS subject is required")
+	}
+	if opts.Queue == "" {
+		opts.Queue = opts.Subject
+	}
+	if err := rpc.Start(context.Background()); err != nil {
+		return nil, err
+	}
+
+	ctx := withInFlightRequests(context.Background(), newInFlightRequests())
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	unsubscribe, err := conn.QueueSubscribe(opts.Subject, opts.Queue, func(data []byte, reply string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res := rpc.HandleMessage(ctx, data)
+			if res == nil || reply == "" {
+				return
+			}
+			if err := conn.Publish(reply, res); err != nil {
+				rpc.cfg().logger.Printf("Unable to publish NATS response to %s: %v", reply, err)
+			}
+		}()
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	stopNotifications := func() {}
+	if opts.NotificationPrefix != "" {
+		stopNotifications = rpc.Subscribe(func(n Notification) {
+			rpc.publishNATS(conn, opts.NotificationPrefix, n)
+		})
+	}
+
+	return func() error {
+		stopNotifications()
+		err := unsubscribe()
+		cancel()
+		wg.Wait()
+
+		return err
+	}, nil
+}
+
+// Publish a notification on the subject of its topic
+func (rpc *jsonRpcImpl) publishNATS(conn NATSConn, prefix string, n Notification) {
+	cfg := rpc.cfg()
+
+	data, err := cfg.encodeNotification(n)
+	if err != nil {
+		cfg.logger.Printf("Unable to encode notification %s: %v", n.Method, err)
+		return
+	}
+
+	subject := prefix
+	if n.Topic != "" {
+		subject += "." + n.Topic
+	}
+	if err := conn.Publish(subject, data); err != nil {
+		cfg.logger.Printf("Unable to publish notification %s to %s: %v", n.Method, subject, err)
+	}
+}
+
+// NewNATSClient creates a client calling the server listening on subject over NATS
+func NewNATSClient(conn NATSConn, subject string, opts ...ClientOption) *Client {
+	return NewClient(&NATSTransport{Conn: conn, Subject: subject}, opts...)
+}
+
+func (t *NATSTransport) RoundTrip(ctx context.Context, msg []byte, notification bool) ([]byte, error) {
+	if notification {
+		return nil, t.Conn.Publish(t.Subject, msg)
+	}
+
+	return t.Conn.Request(ctx, t.Subject, msg)
+}
+
+// Close does not close the NATS connection, which is owned by the application
+func (t *NATSTransport) Close() error {
+	return nil
+}
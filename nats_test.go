@@ -0,0 +1,114 @@
+package jsonrpc2
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// In memory NATS server. Queue groups are ignored as tests run a single subscriber
+type memoryNATS struct {
+	mu          sync.Mutex
+	subscribers map[string]func(data []byte, reply string)
+	lastInbox   int
+}
+
+func newMemoryNATS() *memoryNATS {
+	return &memoryNATS{subscribers: make(map[string]func(data []byte, reply string))}
+}
+
+func (n *memoryNATS) QueueSubscribe(subject, queue string, handler func(data []byte, reply string)) (func() error, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.subscribers[subject] = handler
+	return func() error {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		delete(n.subscribers, subject)
+		return nil
+	}, nil
+}
+
+func (n *memoryNATS) subscribe(subject string) chan []byte {
+	messages := make(chan []byte, 10)
+	n.QueueSubscribe(subject, "", func(data []byte, reply string) { messages <- data })
+
+	return messages
+}
+
+func (n *memoryNATS) Publish(subject string, data []byte) error {
+	n.mu.Lock()
+	handler, ok := n.subscribers[subject]
+	n.mu.Unlock()
+
+	if ok {
+		handler(data, "")
+	}
+	return nil
+}
+
+func (n *memoryNATS) Request(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	n.mu.Lock()
+	n.lastInbox++
+	inbox := "_INBOX." + strconv.Itoa(n.lastInbox)
+	handler := n.subscribers[subject]
+	n.mu.Unlock()
+
+	replies := n.subscribe(inbox)
+	handler(data, inbox)
+
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestNATS(t *testing.T) {
+	conn := newMemoryNATS()
+	stop, err := newTestArithRpc().ServeNATS(conn, NATSOptions{Subject: "rpc.arith"})
+	assert.Nil(t, err)
+	defer stop()
+
+	client := NewNATSClient(conn, "rpc.arith")
+
+	sum, err := Call[int](context.Background(), client, "Arith.Add", []any{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, sum)
+
+	err = client.Call(context.Background(), "Arith.ErrorMethod", nil, nil)
+	assert.Equal(t, "Some error here", err.Error())
+}
+
+func TestNATSNotifications(t *testing.T) {
+	conn := newMemoryNATS()
+	rpc := NewJsonRpc()
+	stop, _ := rpc.ServeNATS(conn, NATSOptions{Subject: "rpc", NotificationPrefix: "events"})
+	orders := conn.subscribe("events.orders")
+
+	rpc.Publish("orders", "Orders.Created", []any{"order-1"})
+
+	select {
+	case msg := <-orders:
+		assert.JSONEq(t, `{"jsonrpc":"2.0","method":"Orders.Created","params":["order-1"]}`, string(msg))
+	case <-time.After(time.Second):
+		t.Fatal("Notification not published")
+	}
+
+	stop()
+	rpc.Publish("orders", "Orders.Created", nil)
+	assert.Len(t, orders, 0)
+}
+
+func TestNATSRequiresSubject(t *testing.T) {
+	_, err := NewJsonRpc().ServeNATS(newMemoryNATS(), NATSOptions{})
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,479 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	//Method of the first message of a connection switching its codec. Its params are a Negotiation
+	NEGOTIATE_METHOD = "rpc.negotiate"
+	//Media type of the JSON codec, always offered with negotiation
+	JSON_MEDIA_TYPE = "application/json"
+	//Compression of the messages with gzip. Always offered
+	COMPRESSION_GZIP = "gzip"
+	//Content coding of Zstandard, offered with WithCompression
+	COMPRESSION_ZSTD = "zstd"
+	//Content coding of Brotli, offered with WithCompression
+	COMPRESSION_BROTLI = "br"
+	//Largest frame accepted on a connection that switched codec, in bytes
+	MAX_FRAME_SIZE = 64 << 20
+)
+
+type (
+	//Codec and compression of a connection, negotiated by its first message
+	Negotiation struct {
+		Codec       string `json:"codec"`                 //Media type of the codec. eg. application/msgpack. Defaults to JSON_MEDIA_TYPE
+		Compression string `json:"compression,omitempty"` //Content coding, eg. COMPRESSION_GZIP, or empty to leave messages uncompressed
+	}
+
+	//Connection translating the messages of a negotiated codec to the JSON lines read and written by the server
+	//and the client. Messages pass unchanged until a codec is negotiated
+	codecConn struct {
+		net.Conn
+		reader     *bufio.Reader                //Reads of the connection, keeping what was read ahead of the negotiation
+		decoded    bytes.Buffer                 //JSON lines of the frames not read yet
+		negotiated atomic.Pointer[codecSession] //Nil until the codec is negotiated
+		writeMu    sync.Mutex
+	}
+
+	codecSession struct {
+		codec       Codec
+		encoding    string      //Content coding of the messages. Empty when they are not compressed
+		compression Compression //Nil when messages are not compressed
+	}
+
+	//Encoding/json as a Codec, so connections can negotiate compression alone
+	jsonCodec struct{}
+
+	//Buffers the JSON response of the server to write it with the codec of the request
+	transcodingResponseWriter struct {
+		http.ResponseWriter
+		status int
+		body   bytes.Buffer
+	}
+)
+
+// WithCodecNegotiation lets clients choose the codec and the compression of their messages among the codecs,
+// keyed by media type, eg. "application/msgpack", so clients of different codecs share one endpoint.
+// JSON is always offered. On persistent connections the first message is a rpc.negotiate request with a
+// Negotiation, answered in JSON. Messages are then sent as frames made of their length in 4 bytes, big endian,
+// and the message encoded by the codec then compressed. Clients that do not negotiate keep using JSON lines.
+// Over HTTP the Content-Type of the request selects the codec and its Content-Encoding the compression. The
+// response is encoded with the codec of the request and compressed with the Accept-Encoding header, see WithCompression.
+// Codecs are applied around the JSON handling of the server, so their decoded values must be the ones of encoding/json.
+func WithCodecNegotiation(codecs map[string]Codec) Option {
+	return func(c *config) {
+		c.codecs = make(map[string]Codec, len(codecs)+1)
+		for mediaType, codec := range codecs {
+			c.codecs[mediaType] = codec
+		}
+		c.codecs[JSON_MEDIA_TYPE] = jsonCodec{}
+	}
+}
+
+// NegotiateCodec asks the server on the other end of conn to switch to the codec and compression of the negotiation,
+// and returns the connection to make the client with, eg. with NewConnClient or in the dial function of
+// NewReconnectingClient. The codec encodes the messages the client writes and decodes the ones it reads.
+// It must be the first message sent on the connection.
+func NegotiateCodec(ctx context.Context, conn net.Conn, negotiation Negotiation, codec Codec) (net.Conn, error) {
+	return NegotiateCompressedCodec(ctx, conn, negotiation, codec, nil)
+}
+
+// NegotiateCompressedCodec is NegotiateCodec with the compression of the negotiation, eg. zstd offered by the server
+// with WithCompression. It can be nil for gzip and no compression.
+func NegotiateCompressedCodec(ctx context.Context, conn net.Conn, negotiation Negotiation, codec Codec, compression Compression) (net.Conn, error) {
+	if compression == nil {
+		switch negotiation.Compression {
+		case "":
+		case COMPRESSION_GZIP:
+			compression = gzipCompression{}
+		default:
+			return nil, errors.New(fmt.Sprintf("No compression given for %s", negotiation.Compression))
+		}
+	}
+	if negotiation.Codec == "" {
+		negotiation.Codec = JSON_MEDIA_TYPE
+	}
+	if codec == nil {
+		if negotiation.Codec != JSON_MEDIA_TYPE {
+			return nil, errors.New(fmt.Sprintf("No codec given for %s", negotiation.Codec))
+		}
+		codec = jsonCodec{}
+	}
+
+	id := NEGOTIATE_METHOD
+	msg, err := json.Marshal(Request{Jsonrpc: RPC_VERSION, Id: &id, Method: NEGOTIATE_METHOD, Params: negotiation})
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if _, err := conn.Write(append(msg, '\n')); err != nil {
+		return nil, err
+	}
+
+	c := newCodecConn(conn)
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		var res clientResponse
+		if err := json.Unmarshal(line, &res); err != nil {
+			return nil, errors.New("Invalid answer to the codec negotiation")
+		}
+		//Notifications, eg. heartbeats, may come first
+		if res.Id == nil || *res.Id != id {
+			continue
+		}
+		if res.Error != nil {
+			return nil, res.Error
+		}
+
+		c.negotiated.Store(&codecSession{codec: codec, encoding: negotiation.Compression, compression: compression})
+		return c, nil
+	}
+}
+
+func newCodecConn(conn net.Conn) *codecConn {
+	return &codecConn{Conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *codecConn) Read(p []byte) (int, error) {
+	session := c.negotiated.Load()
+	if session == nil {
+		return c.reader.Read(p)
+	}
+
+	if c.decoded.Len() == 0 {
+		if err := c.readFrame(session); err != nil {
+			return 0, err
+		}
+	}
+
+	return c.decoded.Read(p)
+}
+
+// Read a frame and add the JSON line of its message to the decoded messages
+func (c *codecConn) readFrame(session *codecSession) error {
+	//The new line ending the negotiation may be left. Lengths up to MAX_FRAME_SIZE never start with white space
+	for {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != '\n' && b != '\r' && b != ' ' && b != '\t' {
+			c.reader.UnreadByte()
+			break
+		}
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MAX_FRAME_SIZE {
+		return errors.New(fmt.Sprintf("Frame of %d bytes is larger than %d bytes", size, MAX_FRAME_SIZE))
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(c.reader, frame); err != nil {
+		return err
+	}
+
+	msg, err := session.decode(frame)
+	if err != nil {
+		return err
+	}
+	c.decoded.Write(msg)
+	c.decoded.WriteByte('\n')
+
+	return nil
+}
+
+// Write a JSON message, followed by a new line, in the negotiated codec
+func (c *codecConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	session := c.negotiated.Load()
+	if session == nil {
+		return c.Conn.Write(p)
+	}
+
+	frame, err := session.encode(bytes.TrimSuffix(p, []byte("\n")))
+	if err != nil {
+		return 0, err
+	}
+
+	msg := make([]byte, 4, 4+len(frame))
+	binary.BigEndian.PutUint32(msg, uint32(len(frame)))
+	if _, err := c.Conn.Write(append(msg, frame...)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Answer the negotiation in JSON and switch to the codec for the following messages
+func (c *codecConn) switchTo(session *codecSession, res []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if _, err := c.Conn.Write(append(res, '\n')); err != nil {
+		return err
+	}
+	c.negotiated.Store(session)
+
+	return nil
+}
+
+// JSON of a message received in the codec
+func (s *codecSession) decode(data []byte) ([]byte, error) {
+	if s.compression != nil {
+		var err error
+		if data, err = decompress(s.compression, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var v any
+	if err := s.codec.Unmarshal(data, &v); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decode message: %s", err))
+	}
+
+	return json.Marshal(v)
+}
+
+// Message to send in the codec from its JSON
+func (s *codecSession) encode(msg []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(msg, &v); err != nil {
+		return nil, err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.compression != nil {
+		return compress(s.compression, data)
+	}
+
+	return data, nil
+}
+
+// Whether the message is a negotiation request
+func isNegotiation(msg []byte) bool {
+	if !bytes.Contains(msg, []byte(NEGOTIATE_METHOD)) {
+		return false
+	}
+
+	var req struct {
+		Method string `json:"method"`
+	}
+
+	return json.Unmarshal(msg, &req) == nil && req.Method == NEGOTIATE_METHOD
+}
+
+// Answer the negotiation of the connection. The answer of a successful negotiation is written before the switch,
+// so it is read in JSON. Failures are returned to write as usual, and the connection stays in JSON.
+func (rpc *jsonRpcImpl) negotiate(conn *codecConn, msg []byte) []byte {
+	cfg := rpc.cfg()
+
+	var req struct {
+		Id     *string     `json:"id"`
+		Params Negotiation `json:"params"`
+	}
+	if err := json.Unmarshal(msg, &req); err != nil {
+		res := makeErrorResponse(errors.New("Params must be a negotiation"), INVALID_PARAMS, nil, nil)
+		r, _ := cfg.marshal(&res, false)
+		return r
+	}
+
+	session, err := cfg.newCodecSession(req.Params)
+	if err != nil {
+		res := makeErrorResponse(err, INVALID_PARAMS, nil, req.Id)
+		r, _ := cfg.marshal(&res, false)
+		return r
+	}
+
+	result := any(req.Params)
+	res, _ := cfg.marshal(&Response{Jsonrpc: RPC_VERSION, Id: req.Id, Result: &result}, false)
+	if err := conn.switchTo(session, res); err != nil {
+		conn.Close()
+	}
+
+	return nil
+}
+
+func (c *config) newCodecSession(negotiation Negotiation) (*codecSession, error) {
+	if negotiation.Codec == "" {
+		negotiation.Codec = JSON_MEDIA_TYPE
+	}
+
+	codec, ok := c.codecs[negotiation.Codec]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Codec %s is not supported", negotiation.Codec))
+	}
+
+	compression, err := c.compression(negotiation.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &codecSession{codec: codec, encoding: negotiation.Compression, compression: compression}, nil
+}
+
+// The negotiation is only valid as the first message of a connection, which is handled before the server
+func (rpc *jsonRpcImpl) registerNegotiateMethod(builtins *service) {
+	if rpc.config.codecs == nil {
+		return
+	}
+
+	builtins.methods["negotiate"] = reflect.ValueOf(func(ctx context.Context, negotiation Negotiation) (Negotiation, error, *RpcErrorCode) {
+		code := INVALID_REQUEST
+		return negotiation, errors.New("The codec is negotiated by the first message of a connection"), &code
+	})
+}
+
+// Serve the HTTP request in the codec and compression its headers ask for. Requests in plain JSON are served as usual
+func (s *jsonRpcImpl) handleNegotiated(w http.ResponseWriter, r *http.Request) {
+	cfg := s.cfg()
+	if cfg.codecs == nil {
+		s.handle(w, r)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	codec, ok := cfg.codecs[mediaType]
+	if !ok {
+		mediaType, codec = JSON_MEDIA_TYPE, jsonCodec{}
+	}
+	requestEncoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if requestEncoding == "identity" {
+		requestEncoding = ""
+	}
+	responseEncoding := cfg.acceptedCompression(r.Header.Get("Accept-Encoding"))
+
+	if mediaType == JSON_MEDIA_TYPE && requestEncoding == "" && responseEncoding == "" {
+		s.handle(w, r)
+		return
+	}
+
+	tw := &transcodingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	responseCompression, _ := cfg.compression(responseEncoding)
+	defer tw.finish(&codecSession{codec: codec, encoding: responseEncoding, compression: responseCompression}, mediaType)
+
+	//Calls made with GET have no body to decode
+	if cfg.isGETCall(r) {
+		s.handle(tw, r)
+		return
+	}
+
+	requestCompression, err := cfg.compression(requestEncoding)
+	if err != nil {
+		s.writeErrorResponse(tw, err, INVALID_REQUEST, nil, nil)
+		return
+	}
+	session := &codecSession{codec: codec, encoding: requestEncoding, compression: requestCompression}
+
+	var body bytes.Buffer
+	var reader io.Reader = r.Body
+	maxSize := s.cfg().maxRequestSize
+	if maxSize > 0 {
+		reader = io.LimitReader(r.Body, maxSize+1)
+	}
+
+	msg := []byte(nil)
+	_, err = body.ReadFrom(reader)
+	if err == nil && maxSize > 0 && int64(body.Len()) > maxSize {
+		err = errRequestTooLarge
+	}
+	if err == nil {
+		msg, err = session.decode(body.Bytes())
+	}
+
+	if errors.Is(err, errRequestTooLarge) {
+		s.writeErrorResponse(tw, err, INVALID_REQUEST, nil, nil)
+	} else if err != nil {
+		s.writeErrorResponse(tw, errors.New("Unable to decode request"), PARSE_ERROR, nil, nil)
+	} else {
+		r.Body = io.NopCloser(bytes.NewReader(msg))
+		r.ContentLength = int64(len(msg))
+		s.handle(tw, r)
+	}
+}
+
+func (w *transcodingResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *transcodingResponseWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+// Write the buffered response in the codec. Responses that are not JSON, eg. with attachments, are only compressed
+func (w *transcodingResponseWriter) finish(session *codecSession, mediaType string) {
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+
+	var body []byte
+	var err error
+	contentType := w.Header().Get("Content-Type")
+	if contentType == "" || strings.HasPrefix(contentType, JSON_MEDIA_TYPE) {
+		w.Header().Set("Content-Type", mediaType)
+		body, err = session.encode(w.body.Bytes())
+	} else if session.compression != nil {
+		body, err = compress(session.compression, w.body.Bytes())
+	} else {
+		body = w.body.Bytes()
+	}
+
+	if err != nil {
+		w.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if session.compression != nil {
+		w.Header().Set("Content-Encoding", session.encoding)
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,146 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testHexMediaType = "application/x-hex-json"
+
+// JSON written in hexadecimal, so messages that were not translated are easy to tell apart
+type hexCodec struct{}
+
+func (hexCodec) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(hex.EncodeToString(data)), nil
+}
+
+func (hexCodec) Unmarshal(data []byte, v any) error {
+	raw, err := hex.DecodeString(string(data))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+func newTestNegotiationRpc() JsonRPC {
+	rpc := NewJsonRpc(WithCodecNegotiation(map[string]Codec{testHexMediaType: hexCodec{}}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	return rpc
+}
+
+func TestNegotiateCodec(t *testing.T) {
+	rpc := newTestNegotiationRpc()
+
+	for _, compression := range []string{"", COMPRESSION_GZIP} {
+		clientConn, serverConn := net.Pipe()
+		go rpc.ServeConn(serverConn)
+
+		conn, err := NegotiateCodec(context.Background(), clientConn, Negotiation{Codec: testHexMediaType, Compression: compression}, hexCodec{})
+		assert.Nil(t, err)
+
+		client := NewConnClient(conn)
+		var sum int
+		assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+		assert.Equal(t, 3, sum)
+		client.Close()
+	}
+}
+
+func TestNegotiatedFrames(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	go newTestNegotiationRpc().ServeConn(serverConn)
+	defer clientConn.Close()
+
+	conn, err := NegotiateCodec(context.Background(), clientConn, Negotiation{Codec: testHexMediaType}, hexCodec{})
+	assert.Nil(t, err)
+	conn.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}` + "\n"))
+
+	//Length of the message then the message in hexadecimal
+	header := make([]byte, 4)
+	io.ReadFull(clientConn, header)
+	frame := make([]byte, int(header[0])<<24|int(header[1])<<16|int(header[2])<<8|int(header[3]))
+	io.ReadFull(clientConn, frame)
+
+	var res map[string]any
+	assert.Nil(t, hexCodec{}.Unmarshal(frame, &res))
+	assert.Equal(t, float64(3), res["result"])
+}
+
+func TestJSONClientsWithNegotiation(t *testing.T) {
+	client := newTestSubscriptionClient(newTestNegotiationRpc())
+	defer client.Close()
+
+	var sum int
+	assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, 3, sum)
+}
+
+func TestNegotiateUnsupportedCodec(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	go newTestNegotiationRpc().ServeConn(serverConn)
+	defer clientConn.Close()
+
+	_, err := NegotiateCodec(context.Background(), clientConn, Negotiation{Codec: "application/msgpack"}, hexCodec{})
+	assert.Equal(t, "Codec application/msgpack is not supported", err.Error())
+
+	//The connection stays in JSON
+	client := NewConnClient(clientConn)
+	var sum int
+	assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+
+	err = client.Call(context.Background(), NEGOTIATE_METHOD, map[string]any{"codec": testHexMediaType}, nil)
+	assert.Equal(t, "The codec is negotiated by the first message of a connection", err.Error())
+}
+
+func TestHTTPCodecNegotiation(t *testing.T) {
+	rpc := newTestNegotiationRpc()
+
+	body, _ := hexCodec{}.Marshal(map[string]any{"jsonrpc": "2.0", "id": "1", "method": "Arith.Add", "params": []any{1, 2}})
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", testHexMediaType)
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+
+	assert.Equal(t, testHexMediaType, w.Header().Get("Content-Type"))
+	var res map[string]any
+	assert.Nil(t, hexCodec{}.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, float64(3), res["result"])
+}
+
+func TestHTTPCompression(t *testing.T) {
+	rpc := newTestNegotiationRpc()
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`))
+	gz.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Encoding", "gzip")
+	r.Header.Set("Accept-Encoding", "br, gzip")
+	w := httptest.NewRecorder()
+	rpc.ServeHTTP(w, r)
+
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(w.Body)
+	assert.Nil(t, err)
+	decoded, _ := io.ReadAll(reader)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":3}`, string(decoded))
+}
@@ -0,0 +1,82 @@
+package jsonrpc2
+
+import "sync"
+
+type (
+	//Notification is a message the server pushes to its clients, eg. a subscription event
+	Notification struct {
+		Topic  string //Topic the notification is published on. Clients filter by topic
+		Method string //Method of the JSON-RPC notification sent to clients
+		Params any
+		remote bool //Received from another server over a bridge, so it is not forwarded again
+	}
+
+	//Subscribers of the notifications published on a server
+	notificationHub struct {
+		mu          sync.RWMutex
+		subscribers map[int]func(Notification)
+		lastId      int
+	}
+)
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{subscribers: make(map[int]func(Notification))}
+}
+
+// Publish pushes a notification to every subscriber, eg. clients connected to the SSE endpoint
+func (rpc *jsonRpcImpl) Publish(topic string, method string, params any) {
+	rpc.notifications.publish(Notification{Topic: topic, Method: method, Params: params})
+}
+
+// Subscribe calls handler with every notification published on the server until unsubscribe is called.
+// The handler runs in the go routine of Publish, so it must not block.
+func (rpc *jsonRpcImpl) Subscribe(handler func(Notification)) (unsubscribe func()) {
+	return rpc.notifications.subscribe(handler)
+}
+
+// Call the subscribers outside of the lock, so a subscriber waiting on a slow client holds up neither subscriptions
+// nor the publishers of other clients
+func (h *notificationHub) publish(n Notification) {
+	h.mu.RLock()
+	subscribers := make([]func(Notification), 0, len(h.subscribers))
+	for _, subscriber := range h.subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+	h.mu.RUnlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(n)
+	}
+}
+
+func (h *notificationHub) subscribe(handler func(Notification)) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastId++
+	id := h.lastId
+	h.subscribers[id] = handler
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.subscribers, id)
+	}
+}
+
+// Encode the notification as a JSON-RPC request without id
+func (c *config) encodeNotification(n Notification) ([]byte, error) {
+	req := n.request()
+	return c.marshal(&req, false)
+}
+
+// The notification as a JSON-RPC request without id
+func (n Notification) request() Request {
+	params := n.Params
+	if params == nil {
+		params = []any{}
+	}
+
+	return Request{Jsonrpc: RPC_VERSION, Method: n.Method, Params: params}
+}
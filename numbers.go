@@ -0,0 +1,116 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// WithStrictNumbers keeps integers exact from the request to the response, so APIs of block numbers, timestamps and
+// other large integers do not lose precision to float64. Numbers in params are decoded as json.Number, as with
+// UseNumber, and numeric request ids are answered with the same number instead of being rejected. On the server the
+// Id of the request holds the digits of the number. Results are encoded from their Go types, so int64 and
+// json.Number results stay exact. It has no effect with a codec.
+func WithStrictNumbers() Option {
+	return func(c *config) {
+		c.useNumber = true
+		c.strictNumbers = true
+	}
+}
+
+// WithStrictNumberResults decodes the numbers of results as json.Number instead of float64 where the result, or a
+// part of it, is decoded into an interface, eg. *any or map[string]any. Typed results are decoded as before.
+func WithStrictNumberResults() ClientOption {
+	return func(c *Client) {
+		c.strictNumbers = true
+	}
+}
+
+// Decode a request object whose id may be a number, with WithStrictNumbers
+func decodeStrictRequestObject(cfg *config, data []byte) Request {
+	var envelope struct {
+		Id      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  any             `json:"params"`
+		Jsonrpc string          `json:"jsonrpc"`
+	}
+	if err := cfg.unmarshal(data, &envelope); err != nil {
+		return Request{Id: strictRequestId(envelope.Id), invalid: errors.New("Invalid request object"), numericId: isJSONNumber(envelope.Id)}
+	}
+
+	req := Request{Method: envelope.Method, Params: envelope.Params, Jsonrpc: envelope.Jsonrpc}
+	switch {
+	case len(envelope.Id) == 0 || bytes.Equal(envelope.Id, []byte("null")):
+	case isJSONNumber(envelope.Id):
+		req.Id, req.numericId = strictRequestId(envelope.Id), true
+	default:
+		var id string
+		if err := json.Unmarshal(envelope.Id, &id); err != nil {
+			return Request{invalid: errors.New("Invalid request object")}
+		}
+		req.Id = &id
+	}
+
+	return req
+}
+
+// Id of a request as text. Numbers keep their digits. Nil when the id is missing or neither a string nor a number
+func strictRequestId(raw json.RawMessage) *string {
+	if isJSONNumber(raw) {
+		id := string(raw)
+		return &id
+	}
+
+	var id *string
+	json.Unmarshal(raw, &id)
+	return id
+}
+
+func isJSONNumber(raw json.RawMessage) bool {
+	if len(raw) == 0 || (raw[0] != '-' && (raw[0] < '0' || raw[0] > '9')) {
+		return false
+	}
+
+	var n json.Number
+	return json.Unmarshal(raw, &n) == nil
+}
+
+// Value to encode in place of v, so responses to numeric ids are written with the id as a number
+func withNumericIds(v any) any {
+	switch value := v.(type) {
+	case *Response:
+		if value.numericId {
+			return numericIdResponse(value)
+		}
+	case Response:
+		if value.numericId {
+			return numericIdResponse(&value)
+		}
+	case *[]Response:
+		return withNumericIds(*value)
+	case []Response:
+		for i := range value {
+			if value[i].numericId {
+				converted := make([]any, len(value))
+				for j := range value {
+					converted[j] = withNumericIds(&value[j])
+				}
+				return converted
+			}
+		}
+	}
+
+	return v
+}
+
+func numericIdResponse(res *Response) *handledResponse {
+	return &handledResponse{Jsonrpc: res.Jsonrpc, Id: json.RawMessage(*res.Id), Meta: res.Meta, Result: res.Result, Error: res.Error}
+}
+
+// Unmarshal data into v, decoding numbers into interfaces as json.Number
+func decodeStrictNumbers(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
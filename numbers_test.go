@@ -0,0 +1,109 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Echoes its params, whatever their type
+type ledger struct{}
+
+func (ledger) Echo(ctx context.Context, v any) (any, error, *RpcErrorCode) {
+	return v, nil, nil
+}
+
+func newTestStrictRpc() JsonRPC {
+	rpc := NewJsonRpc(WithStrictNumbers())
+	rpc.RegisterWithName(encodingService{}, "Enc")
+	rpc.RegisterWithName(ledger{}, "Ledger")
+
+	return rpc
+}
+
+func TestStrictNumbersKeepNumericIds(t *testing.T) {
+	rpc := newTestStrictRpc()
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":9007199254740993,"method":"Enc.Next","params":[9007199254740993]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":9007199254740993,"result":9007199254740994}`, body)
+
+	//String ids stay strings
+	body = serveTestBody(rpc, `{"jsonrpc":"2.0","id":"7","method":"Enc.Next","params":[1]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"7","result":2}`, body)
+
+	//Errors answer with the numeric id too
+	body = serveTestBody(rpc, `{"jsonrpc":"2.0","id":-2,"method":"Enc.Missing"}`)
+	assert.Contains(t, body, `"id":-2`)
+
+	body = serveTestBody(rpc, `{"jsonrpc":"2.0","id":3,"method":1}`)
+	assert.Contains(t, body, `"id":3`)
+	assert.Contains(t, body, "Invalid request object")
+}
+
+func TestStrictNumbersBatch(t *testing.T) {
+	rpc := newTestStrictRpc()
+
+	body := serveTestBody(rpc, `[
+		{"jsonrpc":"2.0","id":1,"method":"Enc.Next","params":[1]},
+		{"jsonrpc":"2.0","id":"2","method":"Enc.Next","params":[2]}
+	]`)
+	assert.JSONEq(t, `[{"jsonrpc":"2.0","id":1,"result":2},{"jsonrpc":"2.0","id":"2","result":3}]`, body)
+
+	//Messages of connections are encoded the same way
+	msg := rpc.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":10,"method":"Enc.Next","params":[1]}`))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":10,"result":2}`, string(msg))
+}
+
+func TestStrictNumbersUntypedParams(t *testing.T) {
+	rpc := newTestStrictRpc()
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":1,"method":"Ledger.Echo","params":[{"block":18446744073709551615,"at":1700000000123456789}]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{"block":18446744073709551615,"at":1700000000123456789}}`, body)
+}
+
+func TestNumericIdsRejectedByDefault(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":1,"method":"Enc.Next","params":[1]}`))
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	//The id could not be read, so it is null rather than an id the client never sent
+	assert.Nil(t, res.Id)
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":1,"method":"Enc.Next","params":[1]}`), `"id":null`)
+
+	//String ids of invalid requests are kept
+	res = decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"7","method":1}`))
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, "7", *res.Id)
+}
+
+func TestIsJSONNumber(t *testing.T) {
+	assert.True(t, isJSONNumber(json.RawMessage(`12`)))
+	assert.True(t, isJSONNumber(json.RawMessage(`-1.5e3`)))
+	assert.False(t, isJSONNumber(json.RawMessage(`"12"`)))
+	assert.False(t, isJSONNumber(json.RawMessage(`null`)))
+	assert.False(t, isJSONNumber(json.RawMessage(`-`)))
+	assert.False(t, isJSONNumber(nil))
+}
+
+func TestWithStrictNumberResults(t *testing.T) {
+	url := newTestHTTPServer(t, newTestStrictRpc())
+
+	var loose any
+	err := NewHTTPClient(url).Call(context.Background(), "Ledger.Echo", []any{json.Number("9007199254740993")}, &loose)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(9007199254740992), loose)
+
+	var strict any
+	err = NewHTTPClient(url, WithStrictNumberResults()).Call(context.Background(), "Ledger.Echo", []any{json.Number("9007199254740993")}, &strict)
+	assert.Nil(t, err)
+	assert.Equal(t, json.Number("9007199254740993"), strict)
+
+	//Typed results are decoded as before
+	var next int64
+	err = NewHTTPClient(url, WithStrictNumberResults()).Call(context.Background(), "Enc.Next", []any{json.Number("9007199254740993")}, &next)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(9007199254740994), next)
+}
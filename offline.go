@@ -0,0 +1,195 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Calls and notifications a client holds while disconnected when OfflineQueueOptions has no Size
+const OFFLINE_QUEUE_SIZE = 256
+
+type (
+	//OfflineQueueOptions bound the calls and notifications a reconnecting client holds while it is disconnected
+	OfflineQueueOptions struct {
+		Size int           //Messages held at most. Messages beyond fail. Defaults to OFFLINE_QUEUE_SIZE
+		TTL  time.Duration //How long a message is held before it is dropped. Zero holds messages until the client is closed
+	}
+
+	//Messages held until the transport reconnects, in the order they were sent
+	offlineQueue struct {
+		opts     OfflineQueueOptions
+		messages []*queuedMessage
+		flushing bool //Held messages are being written, so new messages wait behind them
+	}
+
+	queuedMessage struct {
+		msg     []byte
+		expires time.Time         //Zero when the message does not expire
+		sent    chan queuedResult //Receives the outcome of the message once written or dropped. Buffered
+	}
+
+	queuedResult struct {
+		done chan struct{} //Closed when the connection the message was written on fails
+		err  error
+	}
+)
+
+var (
+	errOfflineQueueFull = errors.New("Offline queue is full")
+	errQueuedExpired    = errors.New("Message expired in the offline queue")
+)
+
+// WithOfflineQueue makes clients of NewReconnectingClient hold the calls and notifications made while disconnected,
+// instead of failing them, and send them in order once reconnected, eg. for devices on flaky networks. Held calls
+// wait for their response until their context is done. Messages held longer than the TTL are dropped and their
+// calls fail. Calls in flight when the connection fails still return an error, since they may have been handled.
+func WithOfflineQueue(opts OfflineQueueOptions) ClientOption {
+	if opts.Size <= 0 {
+		opts.Size = OFFLINE_QUEUE_SIZE
+	}
+
+	return func(c *Client) {
+		if t, ok := c.transport.(*connTransport); ok && t.dial != nil {
+			t.mu.Lock()
+			t.offline = &offlineQueue{opts: opts}
+			t.mu.Unlock()
+		}
+	}
+}
+
+// Write the message, or hold it while the transport is disconnected. Returns the done channel of the connection the
+// message was written on. Notifications held return at once
+func (t *connTransport) send(ctx context.Context, msg []byte, notification bool) (chan struct{}, error) {
+	t.mu.Lock()
+	done := t.done
+	if t.offline == nil || !t.offline.holds(done) {
+		t.mu.Unlock()
+		return done, t.write(msg)
+	}
+
+	queued, err := t.offline.push(msg)
+	t.mu.Unlock()
+	if err != nil || notification {
+		return nil, err
+	}
+
+	var expired <-chan time.Time
+	if !queued.expires.IsZero() {
+		timer := time.NewTimer(time.Until(queued.expires))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case res := <-queued.sent:
+		return res.done, res.err
+	case <-ctx.Done():
+		t.dropQueued(queued)
+		return nil, ctx.Err()
+	case <-expired:
+		t.dropQueued(queued)
+		return nil, errQueuedExpired
+	case <-t.closed:
+		return nil, errors.New("Connection closed")
+	}
+}
+
+// Write the held messages on the connection, in order, until none are left or a write fails
+func (t *connTransport) flushOffline(done chan struct{}) {
+	t.mu.Lock()
+	if t.offline == nil {
+		t.mu.Unlock()
+		return
+	}
+	t.offline.flushing = true
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		t.offline.flushing = false
+		t.mu.Unlock()
+	}()
+
+	for {
+		t.mu.Lock()
+		queued := t.offline.pop(time.Now())
+		t.mu.Unlock()
+		if queued == nil {
+			return
+		}
+
+		err := t.write(queued.msg)
+		queued.sent <- queuedResult{done: done, err: err}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Remove a message that is no longer waited for
+func (t *connTransport) dropQueued(queued *queuedMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, held := range t.offline.messages {
+		if held == queued {
+			t.offline.messages = append(t.offline.messages[:i], t.offline.messages[i+1:]...)
+			return
+		}
+	}
+}
+
+// Whether messages are held: the connection failed, or held messages are still to be written
+func (q *offlineQueue) holds(done chan struct{}) bool {
+	if q.flushing || len(q.messages) > 0 {
+		return true
+	}
+
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *offlineQueue) push(msg []byte) (*queuedMessage, error) {
+	q.dropExpired(time.Now())
+	if len(q.messages) >= q.opts.Size {
+		return nil, errOfflineQueueFull
+	}
+
+	queued := &queuedMessage{msg: msg, sent: make(chan queuedResult, 1)}
+	if q.opts.TTL > 0 {
+		queued.expires = time.Now().Add(q.opts.TTL)
+	}
+	q.messages = append(q.messages, queued)
+
+	return queued, nil
+}
+
+// Oldest message that has not expired. Nil when none are left
+func (q *offlineQueue) pop(now time.Time) *queuedMessage {
+	q.dropExpired(now)
+	if len(q.messages) == 0 {
+		return nil
+	}
+
+	queued := q.messages[0]
+	q.messages = q.messages[1:]
+	return queued
+}
+
+// Drop the messages held longer than the TTL. Their calls fail
+func (q *offlineQueue) dropExpired(now time.Time) {
+	kept := q.messages[:0]
+	for _, queued := range q.messages {
+		if !queued.expires.IsZero() && !now.Before(queued.expires) {
+			queued.sent <- queuedResult{err: errQueuedExpired}
+			continue
+		}
+		kept = append(kept, queued)
+	}
+	q.messages = kept
+}
@@ -0,0 +1,151 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Records the readings it is sent
+type tally struct {
+	readings chan float64
+}
+
+func (s tally) Record(ctx context.Context, reading float64) (bool, error, *RpcErrorCode) {
+	s.readings <- reading
+	return true, nil, nil
+}
+
+// Server whose connections the test breaks, and a dial failing while the network is down
+type flakyNetwork struct {
+	mu    sync.Mutex
+	addr  string
+	down  bool
+	conns []net.Conn
+}
+
+func newFlakyNetwork(t *testing.T, rpc JsonRPC) *flakyNetwork {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	t.Cleanup(func() { l.Close() })
+
+	return &flakyNetwork{addr: l.Addr().String()}
+}
+
+func (n *flakyNetwork) dial(ctx context.Context) (net.Conn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.down {
+		return nil, errors.New("Network is down")
+	}
+	conn, err := net.Dial("tcp", n.addr)
+	if err == nil {
+		n.conns = append(n.conns, conn)
+	}
+	return conn, err
+}
+
+// Break the connections and fail dialing until the network is up again
+func (n *flakyNetwork) setDown(down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.down = down
+	if down {
+		for _, conn := range n.conns {
+			conn.Close()
+		}
+	}
+}
+
+func newOfflineTestClient(t *testing.T, opts OfflineQueueOptions) (*Client, *flakyNetwork, chan float64) {
+	readings := make(chan float64, 8)
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(tally{readings: readings}, "Tally")
+	network := newFlakyNetwork(t, rpc)
+
+	client, err := NewReconnectingClient(context.Background(), network.dial, WithOfflineQueue(opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	network.setDown(true)
+	transport := client.transport.(*connTransport)
+	assert.Eventually(t, func() bool {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		return transport.offline.holds(transport.done)
+	}, 5*time.Second, 5*time.Millisecond)
+
+	return client, network, readings
+}
+
+func heldMessages(client *Client) int {
+	transport := client.transport.(*connTransport)
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	return len(transport.offline.messages)
+}
+
+func TestOfflineQueueFlushesOnReconnect(t *testing.T) {
+	client, network, readings := newOfflineTestClient(t, OfflineQueueOptions{})
+
+	assert.Nil(t, client.Notify(context.Background(), "Tally.Record", []any{1}))
+	called := make(chan error, 1)
+	go func() {
+		var ok bool
+		called <- client.Call(context.Background(), "Tally.Record", []any{2}, &ok)
+	}()
+	assert.Eventually(t, func() bool { return heldMessages(client) == 2 }, 5*time.Second, 5*time.Millisecond)
+
+	network.setDown(false)
+	select {
+	case err := <-called:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Held call was not answered")
+	}
+	//The server handles the messages of a connection concurrently, so only their writes are in order
+	assert.ElementsMatch(t, []float64{1, 2}, []float64{<-readings, <-readings})
+}
+
+func TestOfflineQueueBounds(t *testing.T) {
+	client, _, _ := newOfflineTestClient(t, OfflineQueueOptions{Size: 1, TTL: 50 * time.Millisecond})
+
+	assert.Nil(t, client.Notify(context.Background(), "Tally.Record", []any{1}))
+	assert.Equal(t, errOfflineQueueFull, client.Notify(context.Background(), "Tally.Record", []any{2}))
+
+	//The notification expired, so the call is held in its place until it expires too
+	time.Sleep(60 * time.Millisecond)
+	var ok bool
+	err := client.Call(context.Background(), "Tally.Record", []any{3}, &ok)
+	assert.Equal(t, errQueuedExpired, err)
+	assert.Equal(t, 0, heldMessages(client))
+
+	//Calls given up on are no longer held
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, client.Call(ctx, "Tally.Record", []any{4}, &ok))
+	assert.Equal(t, 0, heldMessages(client))
+}
+
+func TestNoOfflineQueueWithoutDial(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	client := NewConnClient(conn, WithOfflineQueue(OfflineQueueOptions{}))
+	defer client.Close()
+
+	assert.Nil(t, client.transport.(*connTransport).offline)
+}
@@ -0,0 +1,546 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Param structures of an OpenRPC method. Methods accept both when the document does not specify one
+const (
+	OPENRPC_BY_NAME     = "by-name"
+	OPENRPC_BY_POSITION = "by-position"
+)
+
+type (
+	//OpenRPC document a server is built from, eg. with NewFromOpenRPC. Only the fields the server checks are decoded
+	OpenRPCDocument struct {
+		OpenRPC    string            `json:"openrpc"`
+		Methods    []OpenRPCMethod   `json:"methods"`
+		Components OpenRPCComponents `json:"components"`
+	}
+
+	//Method of an OpenRPC document
+	OpenRPCMethod struct {
+		Name           string                     `json:"name"`           //Full method name. eg. Arith.Add
+		Params         []OpenRPCContentDescriptor `json:"params"`         //Params in positional order
+		Result         *OpenRPCContentDescriptor  `json:"result"`         //Nil for methods whose result is not specified
+		ParamStructure string                     `json:"paramStructure"` //OPENRPC_BY_NAME, OPENRPC_BY_POSITION or empty for either
+	}
+
+	//Named schema of a param or a result
+	OpenRPCContentDescriptor struct {
+		Name     string      `json:"name"`
+		Required bool        `json:"required"`
+		Schema   *JSONSchema `json:"schema"`
+	}
+
+	//Reusable schemas, referenced with "$ref": "#/components/schemas/Name"
+	OpenRPCComponents struct {
+		Schemas map[string]*JSONSchema `json:"schemas"`
+	}
+
+	//Subset of JSON schema checked against registered methods and incoming params
+	JSONSchema struct {
+		Ref        string                 `json:"$ref"`
+		Type       JSONSchemaTypes        `json:"type"`
+		Properties map[string]*JSONSchema `json:"properties"`
+		Required   []string               `json:"required"`
+		Items      *JSONSchema            `json:"items"`
+		Enum       []any                  `json:"enum"`
+		Minimum    *float64               `json:"minimum"`
+		Maximum    *float64               `json:"maximum"`
+		MinLength  *int                   `json:"minLength"`
+		MaxLength  *int                   `json:"maxLength"`
+	}
+
+	//Types a value may have, written as a single type or an array of types in the document. eg. ["string", "null"]
+	JSONSchemaTypes []string
+
+	//Server whose methods are specified by an OpenRPC document
+	OpenRPCServer struct {
+		JsonRPC
+		spec *openRPCSpec
+	}
+
+	//Methods of an OpenRPC document keyed by name, checked on registration and on every call
+	openRPCSpec struct {
+		raw     json.RawMessage //Document as written, returned by rpc.discover
+		doc     OpenRPCDocument
+		methods map[string]*OpenRPCMethod
+	}
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	rawMessageType      = reflect.TypeOf(json.RawMessage(nil))
+)
+
+func (t *JSONSchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = JSONSchemaTypes{single}
+		return nil
+	}
+
+	var types []string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return errors.New("Schema type must be a string or an array of strings")
+	}
+	*t = types
+
+	return nil
+}
+
+// NewFromOpenRPC creates a server implementing the methods of an OpenRPC document, so the document is the
+// source of truth of the API instead of the Go types. Registering a method that is not in the document, or whose
+// params or result do not match it, fails. Check reports the methods of the document that are not registered and
+// should be called once every service is registered, before serving. Params of every call are validated against
+// the schemas of the document and rejected with INVALID_PARAMS when they do not match.
+// The document is returned by the built-in rpc.discover method unless introspection is disabled.
+func NewFromOpenRPC(doc []byte, opts ...Option) (*OpenRPCServer, error) {
+	spec, err := parseOpenRPC(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append(append([]Option(nil), opts...), func(c *config) {
+		c.openRPC = spec
+	})
+
+	return &OpenRPCServer{JsonRPC: NewJsonRpc(opts...), spec: spec}, nil
+}
+
+// Check returns an error naming the methods of the document that are not registered on the server.
+// Services of registries are resolved to find their methods.
+func (s *OpenRPCServer) Check() error {
+	rpc, ok := s.JsonRPC.(*jsonRpcImpl)
+	if !ok {
+		return errors.New("Server does not support OpenRPC checks")
+	}
+
+	missing := make([]string, 0)
+	for name := range s.spec.methods {
+		if !rpc.hasMethod(context.Background(), name) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	if len(missing) > 0 {
+		return errors.New(fmt.Sprintf("Methods of the OpenRPC document are not registered: %s", strings.Join(missing, ", ")))
+	}
+
+	return nil
+}
+
+// Document returns the OpenRPC document the server was created from
+func (s *OpenRPCServer) Document() OpenRPCDocument {
+	return s.spec.doc
+}
+
+// Whether the method is registered, or provided by a registry
+func (rpc *jsonRpcImpl) hasMethod(ctx context.Context, method string) bool {
+	serviceName, methodName, err := sanitizeMethodPath(method)
+	if err != nil {
+		root, name := ROOT_SERVICE_NAME, method
+		serviceName, methodName = &root, &name
+	}
+
+	srv, err := rpc.resolveService(ctx, *serviceName)
+	if err != nil || srv == nil {
+		return false
+	}
+
+	_, ok := srv.methods[*methodName]
+
+	return ok
+}
+
+func parseOpenRPC(raw []byte) (*openRPCSpec, error) {
+	spec := &openRPCSpec{raw: append(json.RawMessage(nil), raw...)}
+	if err := json.Unmarshal(raw, &spec.doc); err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid OpenRPC document: %s", err))
+	}
+
+	spec.methods = make(map[string]*OpenRPCMethod, len(spec.doc.Methods))
+	for i := range spec.doc.Methods {
+		method := &spec.doc.Methods[i]
+		if method.Name == "" {
+			return nil, errors.New("Invalid OpenRPC document: Method without a name")
+		}
+		if _, ok := spec.methods[method.Name]; ok {
+			return nil, errors.New(fmt.Sprintf("Invalid OpenRPC document: Method %s is declared twice", method.Name))
+		}
+		switch method.ParamStructure {
+		case "", OPENRPC_BY_NAME, OPENRPC_BY_POSITION, "either":
+		default:
+			return nil, errors.New(fmt.Sprintf("Invalid OpenRPC document: Unknown param structure %s", method.ParamStructure))
+		}
+
+		for _, param := range method.Params {
+			if _, err := spec.resolve(param.Schema); err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid OpenRPC document: %s", err))
+			}
+		}
+		if method.Result != nil {
+			if _, err := spec.resolve(method.Result.Schema); err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid OpenRPC document: %s", err))
+			}
+		}
+
+		spec.methods[method.Name] = method
+	}
+
+	return spec, nil
+}
+
+// Follow the references of the schema to the components. Nil schemas accept any value
+func (spec *openRPCSpec) resolve(schema *JSONSchema) (*JSONSchema, error) {
+	//Bounded so references to each other do not loop
+	for i := 0; schema != nil && schema.Ref != ""; i++ {
+		name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		if !ok || i >= 32 {
+			return nil, errors.New(fmt.Sprintf("Unsupported schema reference %s", schema.Ref))
+		}
+		if schema, ok = spec.doc.Components.Schemas[name]; !ok {
+			return nil, errors.New(fmt.Sprintf("Schema %s is not defined", name))
+		}
+	}
+
+	return schema, nil
+}
+
+// Check a method being registered against the document. methodType is the type of the Go method, context first
+func (spec *openRPCSpec) checkMethod(name string, methodType reflect.Type) error {
+	method, ok := spec.methods[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("Method %s is not in the OpenRPC document", name))
+	}
+
+	if method.ParamStructure == OPENRPC_BY_NAME && takesParamsStruct(methodType) {
+		paramsType := methodType.In(1)
+		if paramsType.Kind() == reflect.Pointer {
+			paramsType = paramsType.Elem()
+		}
+
+		fields := make(map[string]reflect.Type, paramsType.NumField())
+		for i := 0; i < paramsType.NumField(); i++ {
+			if f := paramsType.Field(i); f.IsExported() {
+				fields[jsonFieldName(f)] = f.Type
+			}
+		}
+
+		for _, param := range method.Params {
+			t, ok := fields[param.Name]
+			if !ok {
+				return errors.New(fmt.Sprintf("Param %s of method %s has no field in %s", param.Name, name, paramsType))
+			}
+			if err := spec.checkType(name, "Param "+param.Name, param.Schema, t); err != nil {
+				return err
+			}
+		}
+	} else {
+		expected := methodType.NumIn() - 1
+		if methodType.IsVariadic() {
+			if len(method.Params) < expected-1 {
+				return errors.New(fmt.Sprintf("Method %s takes at least %d params but the OpenRPC document declares %d", name, expected-1, len(method.Params)))
+			}
+		} else if len(method.Params) != expected {
+			return errors.New(fmt.Sprintf("Method %s takes %d params but the OpenRPC document declares %d", name, expected, len(method.Params)))
+		}
+
+		for i, param := range method.Params {
+			if err := spec.checkType(name, "Param "+param.Name, param.Schema, paramType(methodType, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if method.Result != nil {
+		return spec.checkType(name, "Result", method.Result.Schema, methodType.Out(0))
+	}
+
+	return nil
+}
+
+func (spec *openRPCSpec) checkType(method, what string, schema *JSONSchema, t reflect.Type) error {
+	schema, err := spec.resolve(schema)
+	if err != nil {
+		return err
+	}
+
+	if schema == nil || schemaAcceptsType(schema.Type, t) {
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("%s of method %s is %s but the OpenRPC document declares %s", what, method, t, strings.Join(schema.Type, " or ")))
+}
+
+// Whether values of the schema types can be decoded into the Go type
+func schemaAcceptsType(types JSONSchemaTypes, t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	//Types decoding themselves can accept anything
+	custom := reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType)
+	if len(types) == 0 || t.Kind() == reflect.Interface || t == rawMessageType || custom {
+		return true
+	}
+
+	for _, schemaType := range types {
+		switch schemaType {
+		case "null":
+			continue
+		case "string":
+			if t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8) {
+				return true
+			}
+		case "integer":
+			if isNumberKind(t.Kind()) {
+				return true
+			}
+		case "number":
+			if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+				return true
+			}
+		case "boolean":
+			if t.Kind() == reflect.Bool {
+				return true
+			}
+		case "array":
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				return true
+			}
+		case "object":
+			if t.Kind() == reflect.Struct || t.Kind() == reflect.Map {
+				return true
+			}
+		}
+	}
+
+	//A schema that only allows null fits any type
+	return len(types) == 1 && types[0] == "null"
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// Validate the params of a call against the document. Methods that are not in the document are not checked
+func (spec *openRPCSpec) checkParams(name string, params any) error {
+	method, ok := spec.methods[name]
+	if !ok {
+		return nil
+	}
+
+	switch p := params.(type) {
+	case nil:
+		return spec.checkPositionalParams(method, nil)
+	case []any:
+		if method.ParamStructure == OPENRPC_BY_NAME {
+			return errors.New("Params must be passed by name")
+		}
+		return spec.checkPositionalParams(method, p)
+	case map[string]any:
+		if method.ParamStructure == OPENRPC_BY_POSITION {
+			return errors.New("Params must be passed by position")
+		}
+		return spec.checkNamedParams(method, p)
+	default:
+		return errors.New("Params must be an array or an object")
+	}
+}
+
+func (spec *openRPCSpec) checkPositionalParams(method *OpenRPCMethod, params []any) error {
+	if len(params) > len(method.Params) {
+		return errors.New(fmt.Sprintf("Method expects at most %d params, got %d", len(method.Params), len(params)))
+	}
+
+	for i, param := range method.Params {
+		if i >= len(params) {
+			if param.Required {
+				return errors.New(fmt.Sprintf("Param %s is required", param.Name))
+			}
+			continue
+		}
+		if err := spec.validate(params[i], param.Schema, param.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (spec *openRPCSpec) checkNamedParams(method *OpenRPCMethod, params map[string]any) error {
+	known := make(map[string]bool, len(method.Params))
+	for _, param := range method.Params {
+		known[param.Name] = true
+
+		value, ok := params[param.Name]
+		if !ok {
+			if param.Required {
+				return errors.New(fmt.Sprintf("Param %s is required", param.Name))
+			}
+			continue
+		}
+		if err := spec.validate(value, param.Schema, param.Name); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			return errors.New(fmt.Sprintf("Unknown param %s", name))
+		}
+	}
+
+	return nil
+}
+
+// Validate a decoded value against the schema. path names the value in errors. eg. user.emails[0]
+func (spec *openRPCSpec) validate(value any, schema *JSONSchema, path string) error {
+	schema, err := spec.resolve(schema)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return errors.New(fmt.Sprintf("%s is not a valid number", path))
+		}
+		value = f
+	}
+
+	if len(schema.Type) > 0 && !schemaAcceptsValue(schema.Type, value) {
+		return errors.New(fmt.Sprintf("%s must be %s", path, strings.Join(schema.Type, " or ")))
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New(fmt.Sprintf("%s is not one of the allowed values", path))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			return errors.New(fmt.Sprintf("%s must be at least %d characters", path, *schema.MinLength))
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			return errors.New(fmt.Sprintf("%s must be at most %d characters", path, *schema.MaxLength))
+		}
+
+	case float64:
+		if schema.Minimum != nil && v < *schema.Minimum {
+			return errors.New(fmt.Sprintf("%s must be at least %v", path, *schema.Minimum))
+		}
+		if schema.Maximum != nil && v > *schema.Maximum {
+			return errors.New(fmt.Sprintf("%s must be at most %v", path, *schema.Maximum))
+		}
+
+	case []any:
+		for i, item := range v {
+			if err := spec.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				return errors.New(fmt.Sprintf("%s is required", joinPath(path, name)))
+			}
+		}
+
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := v[name]; ok {
+				if err := spec.validate(property, schema.Properties[name], joinPath(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Whether a decoded JSON value has one of the schema types
+func schemaAcceptsValue(types JSONSchemaTypes, value any) bool {
+	for _, schemaType := range types {
+		switch v := value.(type) {
+		case nil:
+			if schemaType == "null" {
+				return true
+			}
+		case string:
+			if schemaType == "string" {
+				return true
+			}
+		case bool:
+			if schemaType == "boolean" {
+				return true
+			}
+		case float64:
+			if schemaType == "number" || (schemaType == "integer" && v == float64(int64(v))) {
+				return true
+			}
+		case []any:
+			if schemaType == "array" {
+				return true
+			}
+		case map[string]any:
+			if schemaType == "object" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (rpc *jsonRpcImpl) registerDiscoverMethod(builtins *service) {
+	spec := rpc.config.openRPC
+	if spec == nil {
+		rpc.registerGeneratedDiscoverMethod(builtins)
+		return
+	}
+
+	builtins.methods["discover"] = reflect.ValueOf(func(ctx context.Context) (json.RawMessage, error, *RpcErrorCode) {
+		return spec.raw, nil, nil
+	})
+}
@@ -0,0 +1,174 @@
+package jsonrpc2
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testOpenRPCDocument = `{
+	"openrpc": "1.2.6",
+	"methods": [
+		{
+			"name": "Arith.Add",
+			"params": [
+				{"name": "a", "required": true, "schema": {"type": "number", "minimum": 0}},
+				{"name": "b", "required": true, "schema": {"type": "number"}}
+			],
+			"result": {"name": "sum", "schema": {"type": "integer"}}
+		},
+		{
+			"name": "Arith.ErrorMethod",
+			"params": []
+		},
+		{
+			"name": "Users.Create",
+			"paramStructure": "by-name",
+			"params": [
+				{"name": "user", "required": true, "schema": {"$ref": "#/components/schemas/User"}}
+			]
+		}
+	],
+	"components": {
+		"schemas": {
+			"User": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {
+					"name": {"type": "string", "minLength": 1},
+					"role": {"enum": ["admin", "member"]}
+				}
+			}
+		}
+	}
+}`
+
+type (
+	testOpenRPCUser struct {
+		Name string `json:"name"`
+		Role string `json:"role"`
+	}
+
+	testOpenRPCUsers struct{}
+)
+
+func (testOpenRPCUsers) Create(ctx context.Context, params struct {
+	User testOpenRPCUser `json:"user"`
+}) (string, error, *RpcErrorCode) {
+	return params.User.Name, nil, nil
+}
+
+func newTestOpenRPCServer(t *testing.T) *OpenRPCServer {
+	rpc, err := NewFromOpenRPC([]byte(testOpenRPCDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rpc
+}
+
+func TestOpenRPCServer(t *testing.T) {
+	rpc := newTestOpenRPCServer(t)
+	assert.Nil(t, rpc.RegisterWithName(arith{}, "Arith"))
+	assert.Nil(t, rpc.RegisterWithName(testOpenRPCUsers{}, "Users"))
+	assert.Nil(t, rpc.Check())
+
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, float64(3), *res.Result)
+}
+
+func TestOpenRPCCheckReportsMissingMethods(t *testing.T) {
+	rpc := newTestOpenRPCServer(t)
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	assert.Equal(t, "Methods of the OpenRPC document are not registered: Users.Create", rpc.Check().Error())
+}
+
+func TestOpenRPCRejectsMismatchedMethods(t *testing.T) {
+	rpc := newTestOpenRPCServer(t)
+
+	err := rpc.RegisterWithName(node{}, "Arith")
+	assert.Equal(t, "Method Arith.GetBlockCount is not in the OpenRPC document", err.Error())
+
+	err = Handle(rpc, "Arith.Add", func(ctx context.Context, a string) (int, error) { return 0, nil })
+	assert.Equal(t, "Method Arith.Add takes 1 params but the OpenRPC document declares 2", err.Error())
+
+	spec, _ := parseOpenRPC([]byte(`{"methods": [{"name": "Arith.Add", "params": [
+		{"name": "a", "schema": {"type": "string"}}, {"name": "b", "schema": {"type": "number"}}
+	]}]}`))
+	err = spec.checkMethod("Arith.Add", reflect.TypeOf(arith{}.Add))
+	assert.Equal(t, "Param a of method Arith.Add is float64 but the OpenRPC document declares string", err.Error())
+
+	spec, _ = parseOpenRPC([]byte(`{"methods": [{"name": "Arith.Add", "params": [
+		{"name": "a", "schema": {"type": "number"}}, {"name": "b", "schema": {"type": "number"}}
+	], "result": {"name": "sum", "schema": {"type": "object"}}}]}`))
+	err = spec.checkMethod("Arith.Add", reflect.TypeOf(arith{}.Add))
+	assert.Equal(t, "Result of method Arith.Add is int but the OpenRPC document declares object", err.Error())
+}
+
+func TestOpenRPCValidatesParams(t *testing.T) {
+	rpc := newTestOpenRPCServer(t)
+	rpc.RegisterWithName(arith{}, "Arith")
+	rpc.RegisterWithName(testOpenRPCUsers{}, "Users")
+
+	res := callMethod(t, rpc, "Arith.Add", []any{-1, 2})
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "a must be at least 0", res.Error.Message)
+
+	res = callMethod(t, rpc, "Arith.Add", []any{"1", 2})
+	assert.Equal(t, "a must be number", res.Error.Message)
+
+	res = callMethod(t, rpc, "Arith.Add", []any{1})
+	assert.Equal(t, "Param b is required", res.Error.Message)
+
+	res = callMethod(t, rpc, "Users.Create", []any{map[string]any{"name": "ada"}})
+	assert.Equal(t, "Params must be passed by name", res.Error.Message)
+
+	id := "1"
+	res, _ = makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Users.Create", Params: map[string]any{
+		"user": map[string]any{"name": "ada", "role": "owner"},
+	}, Jsonrpc: RPC_VERSION})
+	assert.Equal(t, "user.role is not one of the allowed values", res.Error.Message)
+
+	res, _ = makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Users.Create", Params: map[string]any{
+		"user": map[string]any{"role": "admin"},
+	}, Jsonrpc: RPC_VERSION})
+	assert.Equal(t, "user.name is required", res.Error.Message)
+
+	res, _ = makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Users.Create", Params: map[string]any{
+		"user": map[string]any{"name": "ada", "role": "admin"},
+	}, Jsonrpc: RPC_VERSION})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "ada", *res.Result)
+}
+
+func TestOpenRPCDiscover(t *testing.T) {
+	rpc := newTestOpenRPCServer(t)
+
+	res := callMethod(t, rpc, "rpc.discover", nil)
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "1.2.6", (*res.Result).(map[string]any)["openrpc"])
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, NewJsonRpc(), "rpc.discover", nil).Error.Code)
+}
+
+func TestInvalidOpenRPCDocument(t *testing.T) {
+	_, err := NewFromOpenRPC([]byte(`{"methods": [{"name": "Arith.Add"}, {"name": "Arith.Add"}]}`))
+	assert.Equal(t, "Invalid OpenRPC document: Method Arith.Add is declared twice", err.Error())
+
+	_, err = NewFromOpenRPC([]byte(`{"methods": [{"name": "Arith.Add", "params": [{"name": "a", "schema": {"$ref": "#/components/schemas/Missing"}}]}]}`))
+	assert.Equal(t, "Invalid OpenRPC document: Schema Missing is not defined", err.Error())
+}
+
+func TestSchemaTypes(t *testing.T) {
+	spec, err := parseOpenRPC([]byte(`{"methods": [{"name": "Notes.Get", "params": [{"name": "id", "schema": {"type": ["integer", "null"]}}]}]}`))
+	assert.Nil(t, err)
+
+	method := spec.methods["Notes.Get"]
+	assert.Nil(t, spec.checkPositionalParams(method, []any{float64(1)}))
+	assert.Nil(t, spec.checkPositionalParams(method, []any{nil}))
+	assert.Equal(t, "id must be integer or null", spec.checkPositionalParams(method, []any{1.5}).Error())
+}
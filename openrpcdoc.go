@@ -0,0 +1,147 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Version of the OpenRPC specification of the documents generated by rpc.discover
+const OPENRPC_VERSION = "1.2.6"
+
+type (
+	//OpenRPCInfo describes the API in the OpenRPC document generated by rpc.discover
+	OpenRPCInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	//OpenRPC document generated from the registered methods and their MethodDoc
+	generatedOpenRPC struct {
+		OpenRPC string            `json:"openrpc"`
+		Info    OpenRPCInfo       `json:"info"`
+		Methods []generatedMethod `json:"methods"`
+	}
+
+	generatedMethod struct {
+		Name        string                `json:"name"`
+		Description string                `json:"description,omitempty"`
+		Params      []generatedDescriptor `json:"params"`
+		Result      generatedDescriptor   `json:"result"`
+		Deprecated  bool                  `json:"deprecated,omitempty"`
+		Examples    []generatedExample    `json:"examples,omitempty"`
+	}
+
+	generatedDescriptor struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description,omitempty"`
+		Required    bool           `json:"required,omitempty"`
+		Schema      map[string]any `json:"schema"`
+	}
+
+	generatedExample struct {
+		Name   string         `json:"name"`
+		Params []exampleValue `json:"params"`
+		Result *exampleValue  `json:"result,omitempty"`
+	}
+
+	exampleValue struct {
+		Name  string `json:"name"`
+		Value any    `json:"value"`
+	}
+)
+
+// WithOpenRPCInfo makes rpc.discover return an OpenRPC document generated from the registered methods, with the
+// descriptions, params and examples of their MethodDoc. Schemas are derived from the Go types of the params and
+// results, as in the OpenAPI document of WithREST. Servers built with NewFromOpenRPC return their document instead.
+func WithOpenRPCInfo(info OpenRPCInfo) Option {
+	return func(c *config) {
+		c.openRPCInfo = &info
+	}
+}
+
+// Generate the OpenRPC document of the registered methods. Services of registries are resolved first
+func (rpc *jsonRpcImpl) generateOpenRPC(ctx context.Context, info OpenRPCInfo) (json.RawMessage, error) {
+	if err := rpc.resolveAllServices(ctx); err != nil {
+		return nil, err
+	}
+
+	doc := generatedOpenRPC{OpenRPC: OPENRPC_VERSION, Info: info, Methods: make([]generatedMethod, 0)}
+	for _, srv := range rpc.registeredServices() {
+		names := make([]string, 0, len(srv.methods))
+		for name := range srv.methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			doc.Methods = append(doc.Methods, srv.openRPCMethod(name))
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func (s *service) openRPCMethod(name string) generatedMethod {
+	methodType := s.methods[name].Type()
+	doc := s.docs[name]
+	paramDocs := s.paramDocs(name, methodType.NumIn()-1)
+
+	method := generatedMethod{
+		Name:        s.fullName(name),
+		Description: doc.Description,
+		Params:      make([]generatedDescriptor, 0, methodType.NumIn()-1),
+		Result:      generatedDescriptor{Name: "result", Description: doc.Result, Schema: jsonSchemaOf(methodType.Out(0), make(map[reflect.Type]bool))},
+		Deprecated:  doc.Deprecated,
+	}
+
+	//First param is the context
+	for p := 1; p < methodType.NumIn(); p++ {
+		param := generatedDescriptor{Name: fmt.Sprintf("param%d", p), Schema: jsonSchemaOf(methodType.In(p), make(map[reflect.Type]bool))}
+		if paramDocs != nil {
+			param.Name, param.Description = paramDocs[p-1].Name, paramDocs[p-1].Description
+		}
+		param.Required = !nilable(methodType.In(p))
+		method.Params = append(method.Params, param)
+	}
+
+	for i, example := range doc.Examples {
+		generated := generatedExample{Name: example.Name, Params: make([]exampleValue, 0, len(example.Params))}
+		if generated.Name == "" {
+			generated.Name = fmt.Sprintf("example%d", i+1)
+		}
+		for p, value := range example.Params {
+			name := fmt.Sprintf("param%d", p+1)
+			if p < len(method.Params) {
+				name = method.Params[p].Name
+			}
+			generated.Params = append(generated.Params, exampleValue{Name: name, Value: value})
+		}
+		if example.Result != nil {
+			generated.Result = &exampleValue{Name: "result", Value: example.Result}
+		}
+		method.Examples = append(method.Examples, generated)
+	}
+
+	return method
+}
+
+func (rpc *jsonRpcImpl) registerGeneratedDiscoverMethod(builtins *service) {
+	info := rpc.config.openRPCInfo
+	if info == nil {
+		return
+	}
+
+	builtins.methods["discover"] = reflect.ValueOf(func(ctx context.Context) (json.RawMessage, error, *RpcErrorCode) {
+		doc, err := rpc.generateOpenRPC(ctx, *info)
+		if err != nil {
+			code := INTERNAL_ERROR
+			return nil, err, &code
+		}
+
+		return doc, nil, nil
+	})
+}
@@ -0,0 +1,51 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratedOpenRPC(t *testing.T) {
+	rpc := NewJsonRpc(WithOpenRPCInfo(OpenRPCInfo{Title: "Calculator", Version: "1.0.0"}))
+	rpc.RegisterWithOptions(arith{}, ServiceOptions{
+		Name: "Arith",
+		Docs: map[string]MethodDoc{"Add": {
+			Description: "Adds two numbers",
+			Params:      []ParamDoc{{Name: "a", Description: "First term"}, {Name: "b"}},
+			Result:      "Sum of the terms",
+			Examples:    []MethodExample{{Params: []any{1, 2}, Result: 3}},
+		}},
+	})
+
+	res := callMethod(t, rpc, "rpc.discover", nil)
+	assert.Nil(t, res.Error)
+
+	raw, _ := json.Marshal(*res.Result)
+	var doc struct {
+		OpenRPC string          `json:"openrpc"`
+		Info    OpenRPCInfo     `json:"info"`
+		Methods json.RawMessage `json:"methods"`
+	}
+	assert.Nil(t, json.Unmarshal(raw, &doc))
+	assert.Equal(t, OPENRPC_VERSION, doc.OpenRPC)
+	assert.Equal(t, OpenRPCInfo{Title: "Calculator", Version: "1.0.0"}, doc.Info)
+	assert.JSONEq(t, `[
+		{
+			"name": "Arith.Add",
+			"description": "Adds two numbers",
+			"params": [
+				{"name": "a", "description": "First term", "required": true, "schema": {"type": "number"}},
+				{"name": "b", "required": true, "schema": {"type": "number"}}
+			],
+			"result": {"name": "result", "description": "Sum of the terms", "schema": {"type": "integer"}},
+			"examples": [{"name": "example1", "params": [{"name": "a", "value": 1}, {"name": "b", "value": 2}], "result": {"name": "result", "value": 3}}]
+		},
+		{
+			"name": "Arith.ErrorMethod",
+			"params": [],
+			"result": {"name": "result", "schema": {"type": "integer", "nullable": true}}
+		}
+	]`, string(doc.Methods))
+}
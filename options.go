@@ -0,0 +1,271 @@
+package jsonrpc2
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+type (
+	//Option configures optional behaviour of the RPC server. Options are passed to NewJsonRpc and Reconfigure
+	Option func(*config)
+
+	//Logger receives the internal errors of the server, eg. panics recovered from handlers. *log.Logger implements it
+	Logger interface {
+		Printf(format string, v ...any)
+	}
+
+	//Server settings assembled from the options passed to NewJsonRpc
+	config struct {
+		cors                 *corsConfig   //CORS settings. Nil when CORS is disabled
+		ipFilter             *IPFilter     //Networks allowed to call. Nil when every caller is
+		disableIntrospection bool          //Remove rpc.listMethods and rpc.describe
+		jobRetention         time.Duration //How long finished jobs are kept for polling
+		interceptors         []ResponseInterceptor
+		indentResponses      bool
+		disableHTMLEscaping  bool
+		useNumber            bool //Decode numbers in requests as json.Number
+		methodLimits         map[string]*methodLimiter
+		accessLog            *accessLogConfig
+		timeout              time.Duration            //Deadline of every call. Zero means no deadline
+		methodTimeouts       map[string]time.Duration //Deadlines overriding timeout, keyed by full method name
+		batchTimeout         time.Duration            //Deadline of every batch. Zero means no deadline
+		batchConcurrency     int                      //Entries of a batch handled at once. Zero means every entry
+		batchEntryBudgets    bool                     //Split the time left to a batch between the entries still to run
+		logger               Logger
+		codec                Codec //Replaces the JSON encoding options when set
+		middleware           []Middleware
+		maxRequestSize       int64 //Maximum size of an HTTP request body in bytes. Zero means no limit
+		legacyBatchResponses bool  //Answer batches without responses with an empty array, and accept empty batches
+		playgroundPath       string
+		ssePath              string
+		duplicateIds         DuplicateIDPolicy
+		contextHook          ContextHook
+		writeBuffer          *writeBufferConfig //Nil uses the default of each transport
+		scheduler            *scheduler
+		methodPriorities     map[string]int
+		methodNamer          MethodNamer
+		registries           []mountedRegistry //Resolve the services that are not registered, in order
+		audit                *auditConfig
+		openRPC              *openRPCSpec      //Methods and params are checked against it. Set by NewFromOpenRPC
+		heartbeat            *HeartbeatOptions //Nil when the liveness of connections is not checked
+		codecs               map[string]Codec  //Codecs clients can negotiate, keyed by media type. Nil disables negotiation
+		localizer            Localizer
+		dependencies         []any             //Values constructors registered as services are called with
+		maxConnectionAge     *connectionAge    //Nil keeps persistent connections open until they are closed
+		metadataHeaders      []string          //Canonical names of the HTTP headers read into the metadata of requests
+		notificationStore    NotificationStore //Queues the events of durable subscriptions. Nil disables them
+		restPrefix           string            //Path the REST bridge serves methods under. Empty disables it
+		coalescedMethods     map[string]bool   //Methods whose identical concurrent calls share a response
+		exposedMethods       []string          //Patterns of the only methods callable. Nil exposes every method
+		discovery            *discoveryConfig  //Endpoint published while the server is started. Nil when not published
+
+		adapters        map[string]ProtocolAdapter       //Adapters of other protocols, by the path they are served on
+		responseLimit   *responseLimit                   //Limit of the encoded results. Nil when results are not limited
+		quotas          *QuotaOptions                    //Calls allowed per client. Nil when calls are not counted
+		compressions    []namedCompression               //Compressions offered besides gzip, in the order they are preferred
+		openRPCInfo     *OpenRPCInfo                     //Info of the OpenRPC document generated by rpc.discover. Nil to not generate one
+		strictNumbers   bool                             //Keep numeric request ids as numbers and decode numbers as json.Number
+		responseMeta    bool                             //Send the meta object of responses. Dropped when false, per the specification
+		checksums       *checksumConfig                  //Checksums of HTTP bodies. Nil when they are neither verified nor sent
+		structValidator StructValidator                  //Validator of the struct params. Nil when only rpc tags are checked
+		shards          *shardedDispatcher               //Shards requests are dispatched to. Nil handles each request on its own go routine
+		getMethods      []string                         //Patterns of the methods callable with HTTP GET. Nil when GET is not served
+		cancelIdentity  func(ctx context.Context) string //Caller of HTTP requests that can cancel them. Nil for the client IP
+		errorMappings   []errorMapping                   //Translations of handler errors registered with MapError, in order
+	}
+)
+
+func newConfig(opts []Option) *config {
+	cfg := &config{logger: log.Default()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return cfg
+}
+
+// WithTimeout sets a deadline on the context of every call. Calls that exceed it fail with
+// context.DeadlineExceeded, which can be translated with MapError.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+// WithBatchTimeout sets the time a batch has to complete. Entries still running when it is exhausted fail with
+// context.DeadlineExceeded, like calls exceeding WithTimeout, and the batch is answered without waiting for them.
+// Every entry sees the deadline of the batch on its context, unless its own timeout ends earlier. A batch sent with
+// the X-RPC-Timeout header gets the earliest of both deadlines.
+func WithBatchTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.batchTimeout = timeout
+	}
+}
+
+// WithBatchConcurrency bounds the entries of a batch handled at once, so a large batch does not start a handler for
+// every entry together. The other entries wait for one to finish. Entries still waiting when the batch deadline
+// passes fail without being handled. Every entry is handled at once by default.
+func WithBatchConcurrency(n int) Option {
+	return func(c *config) {
+		c.batchConcurrency = n
+	}
+}
+
+// WithBatchEntryBudgets splits the deadline of a batch, see WithBatchTimeout, between its entries, so one slow entry
+// does not use up the time of the others. Each entry starting gets a share of the time left to the batch: the time
+// left divided by the rounds of WithBatchConcurrency workers still needed for the entries not started. An entry
+// running past its share fails with context.DeadlineExceeded, mapped like other timeouts, and the next entry starts.
+// Without bounded concurrency every entry starts at once and gets the whole deadline.
+func WithBatchEntryBudgets() Option {
+	return func(c *config) {
+		c.batchEntryBudgets = true
+	}
+}
+
+// Workers handling a batch of n entries
+func (c *config) batchWorkers(n int) int {
+	if c.batchConcurrency > 0 && c.batchConcurrency < n {
+		return c.batchConcurrency
+	}
+
+	return n
+}
+
+// WithMethodTimeout overrides the timeout of a single method. method is the full method name. eg. Reports.Build
+func WithMethodTimeout(method string, timeout time.Duration) Option {
+	return func(c *config) {
+		if c.methodTimeouts == nil {
+			c.methodTimeouts = make(map[string]time.Duration)
+		}
+		c.methodTimeouts[method] = timeout
+	}
+}
+
+// WithLogger sets the logger of internal errors. Defaults to the standard logger of the log package
+func WithLogger(logger Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithMaxRequestSize rejects HTTP requests with a body larger than size bytes
+func WithMaxRequestSize(size int64) Option {
+	return func(c *config) {
+		c.maxRequestSize = size
+	}
+}
+
+// WithLegacyBatchResponses answers HTTP batches made only of notifications with an empty array and status 200
+// instead of status 204 without a body, and runs empty batches instead of rejecting them with INVALID_REQUEST.
+// This is how earlier versions behaved, for clients that depend on it.
+func WithLegacyBatchResponses() Option {
+	return func(c *config) {
+		c.legacyBatchResponses = true
+	}
+}
+
+// Reconfigure applies the options on top of the current settings while the server is running.
+// Calls in flight keep the settings they started with.
+// DisableIntrospection and WithJobRetention only take effect when passed to NewJsonRpc.
+func (rpc *jsonRpcImpl) Reconfigure(opts ...Option) {
+	rpc.configMu.Lock()
+	defer rpc.configMu.Unlock()
+
+	cfg := rpc.config.clone()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	rpc.config = cfg
+}
+
+// Current settings of the server. Read them once per request so a request sees consistent settings
+func (rpc *jsonRpcImpl) cfg() *config {
+	rpc.configMu.RLock()
+	defer rpc.configMu.RUnlock()
+
+	return rpc.config
+}
+
+// Copy the settings so options can modify the copy without affecting requests using the original
+func (c *config) clone() *config {
+	cfg := *c
+
+	cfg.interceptors = append([]ResponseInterceptor(nil), c.interceptors...)
+	cfg.middleware = append([]Middleware(nil), c.middleware...)
+	cfg.registries = append([]mountedRegistry(nil), c.registries...)
+	cfg.dependencies = append([]any(nil), c.dependencies...)
+	cfg.metadataHeaders = append([]string(nil), c.metadataHeaders...)
+	cfg.compressions = append([]namedCompression(nil), c.compressions...)
+	cfg.errorMappings = append([]errorMapping(nil), c.errorMappings...)
+	if c.exposedMethods != nil {
+		cfg.exposedMethods = append([]string{}, c.exposedMethods...)
+	}
+
+	if c.codecs != nil {
+		cfg.codecs = make(map[string]Codec, len(c.codecs))
+		for mediaType, codec := range c.codecs {
+			cfg.codecs[mediaType] = codec
+		}
+	}
+
+	if c.adapters != nil {
+		cfg.adapters = make(map[string]ProtocolAdapter, len(c.adapters))
+		for path, adapter := range c.adapters {
+			cfg.adapters[path] = adapter
+		}
+	}
+
+	if c.methodTimeouts != nil {
+		cfg.methodTimeouts = make(map[string]time.Duration, len(c.methodTimeouts))
+		for method, timeout := range c.methodTimeouts {
+			cfg.methodTimeouts[method] = timeout
+		}
+	}
+
+	if c.methodLimits != nil {
+		cfg.methodLimits = make(map[string]*methodLimiter, len(c.methodLimits))
+		for method, l := range c.methodLimits {
+			//Calls in flight keep their slots in the shared channel
+			cfg.methodLimits[method] = &methodLimiter{slots: l.slots, maxQueued: l.maxQueued}
+		}
+	}
+
+	if c.coalescedMethods != nil {
+		cfg.coalescedMethods = make(map[string]bool, len(c.coalescedMethods))
+		for method := range c.coalescedMethods {
+			cfg.coalescedMethods[method] = true
+		}
+	}
+
+	if c.methodPriorities != nil {
+		cfg.methodPriorities = make(map[string]int, len(c.methodPriorities))
+		for method, priority := range c.methodPriorities {
+			cfg.methodPriorities[method] = priority
+		}
+	}
+
+	if c.audit != nil {
+		cfg.audit = c.audit.clone()
+	}
+
+	if c.accessLog != nil {
+		accessLog := *c.accessLog
+		accessLog.methodRates = make(map[string]float64, len(c.accessLog.methodRates))
+		for method, rate := range c.accessLog.methodRates {
+			accessLog.methodRates[method] = rate
+		}
+		cfg.accessLog = &accessLog
+	}
+
+	return &cfg
+}
+
+// Timeout of a call of the method. Zero means no deadline
+func (c *config) methodTimeout(method string) time.Duration {
+	if timeout, ok := c.methodTimeouts[method]; ok {
+		return timeout
+	}
+
+	return c.timeout
+}
@@ -0,0 +1,340 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type panicService struct{}
+
+func (panicService) Panic(ctx context.Context) (string, error, *RpcErrorCode) {
+	panic("boom")
+}
+
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return []byte(strings.ToUpper(string(b))), err
+}
+
+func (upperCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func TestReconfigure(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	body := `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["hi"]}`
+	assert.Equal(t, `{"jsonrpc":"2.0","id":"1","result":"hi"}`, serveTestBody(rpc, body))
+
+	rpc.Reconfigure(WithIndentedResponses())
+	assert.Contains(t, serveTestBody(rpc, body), "\n  \"result\": \"hi\"")
+}
+
+func TestReconfigureKeepsOptions(t *testing.T) {
+	rpc := NewJsonRpc(WithResponseInterceptor(func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError) {
+		return "intercepted", err
+	}))
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	rpc.Reconfigure(WithIndentedResponses())
+
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["hi"]}`), `"intercepted"`)
+}
+
+func TestReconfigureDuringRequest(t *testing.T) {
+	var rpc JsonRPC
+	rpc = NewJsonRpc(WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			rpc.Reconfigure(WithResponseInterceptor(func(ctx context.Context, method string, result any, err *RpcError) (any, *RpcError) {
+				return "intercepted", err
+			}))
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	//The request keeps the settings it started with, the next one sees the interceptor
+	body := `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["hi"]}`
+	assert.Equal(t, `{"jsonrpc":"2.0","id":"1","result":"hi"}`, serveTestBody(rpc, body))
+	assert.Contains(t, serveTestBody(rpc, body), `"intercepted"`)
+}
+
+func TestWithTimeout(t *testing.T) {
+	rpc := NewJsonRpc(WithMethodTimeout("Users.Slow", 10*time.Millisecond))
+	rpc.RegisterWithName(users{}, "Users")
+	rpc.MapError(context.DeadlineExceeded, SERVER_BUSY, "Timed out")
+
+	res := callUsers(t, rpc, "Slow", []any{})
+
+	assert.Equal(t, SERVER_BUSY, res.Error.Code)
+}
+
+func TestWithBatchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	//Blocks regardless of the context, so only the batch deadline ends the wait
+	rpc := NewJsonRpc(WithBatchTimeout(20*time.Millisecond), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			if req.Method == "Arith.ErrorMethod" {
+				<-release
+			}
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+	rpc.MapError(context.DeadlineExceeded, SERVER_BUSY, "Timed out")
+
+	first, second := "1", "2"
+	start := time.Now()
+	responses, err := makeRpcBatchTestRequest(rpc, []Request{
+		{Jsonrpc: RPC_VERSION, Id: &first, Method: "Arith.Add", Params: []any{1, 2}},
+		{Jsonrpc: RPC_VERSION, Id: &second, Method: "Arith.ErrorMethod", Params: []any{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, responses, 2)
+	assert.Nil(t, responses[0].Error)
+	assert.Equal(t, &second, responses[1].Id)
+	assert.Equal(t, SERVER_BUSY, responses[1].Error.Code)
+	assert.Equal(t, "Timed out", responses[1].Error.Message)
+}
+
+func TestWithBatchConcurrency(t *testing.T) {
+	var running, peak int
+	var mu sync.Mutex
+	rpc := NewJsonRpc(WithBatchConcurrency(2), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			mu.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+			defer func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}()
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	requests := make([]Request, 10)
+	for i := range requests {
+		id := fmt.Sprint(i)
+		requests[i] = Request{Jsonrpc: RPC_VERSION, Id: &id, Method: "Arith.Add", Params: []any{i, 1}}
+	}
+	responses, err := makeRpcBatchTestRequest(rpc, requests)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, peak)
+	for i, res := range responses {
+		assert.Equal(t, fmt.Sprint(i), *res.Id)
+		assert.Equal(t, any(float64(i+1)), *res.Result)
+	}
+}
+
+func TestBatchEntriesWaitingPastDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	var handled []string
+	var mu sync.Mutex
+	rpc := NewJsonRpc(WithBatchConcurrency(1), WithBatchTimeout(20*time.Millisecond), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			mu.Lock()
+			handled = append(handled, *req.Id)
+			mu.Unlock()
+			<-release
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+	rpc.MapError(context.DeadlineExceeded, SERVER_BUSY, "Timed out")
+
+	first, second := "1", "2"
+	responses, err := makeRpcBatchTestRequest(rpc, []Request{
+		{Jsonrpc: RPC_VERSION, Id: &first, Method: "Arith.Add", Params: []any{1, 2}},
+		{Jsonrpc: RPC_VERSION, Id: &second, Method: "Arith.Add", Params: []any{3, 4}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Every entry fails with its own timeout error, and the waiting one is never handled
+	for i, id := range []string{first, second} {
+		assert.Equal(t, id, *responses[i].Id)
+		assert.Equal(t, SERVER_BUSY, responses[i].Error.Code)
+	}
+	mu.Lock()
+	assert.Equal(t, []string{first}, handled)
+	mu.Unlock()
+}
+
+func TestBatchDeadlineSeenByEntries(t *testing.T) {
+	var deadlines []bool
+	var mu sync.Mutex
+	rpc := NewJsonRpc(WithBatchTimeout(time.Second), WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			_, ok := ctx.Deadline()
+			mu.Lock()
+			deadlines = append(deadlines, ok)
+			mu.Unlock()
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	first, second := "1", "2"
+	makeRpcBatchTestRequest(rpc, []Request{
+		{Jsonrpc: RPC_VERSION, Id: &first, Method: "Arith.Add", Params: []any{1, 2}},
+		{Jsonrpc: RPC_VERSION, Id: &second, Method: "Arith.Add", Params: []any{3, 4}},
+	})
+	assert.Equal(t, []bool{true, true}, deadlines)
+
+	//Single requests are not bound by the batch deadline
+	deadlines = nil
+	makeRpcSingleTestRequest(rpc, Request{Jsonrpc: RPC_VERSION, Id: &first, Method: "Arith.Add", Params: []any{1, 2}})
+	assert.Equal(t, []bool{false}, deadlines)
+}
+
+func TestWithBatchEntryBudgets(t *testing.T) {
+	batch := func() []Request {
+		ids := []string{"slow", "1", "2", "3"}
+		requests := []Request{{Jsonrpc: RPC_VERSION, Id: &ids[0], Method: "Blocking.Block", Params: []any{}}}
+		for i := range ids[1:] {
+			requests = append(requests, Request{Jsonrpc: RPC_VERSION, Id: &ids[i+1], Method: "Arith.Add", Params: []any{i, 1}})
+		}
+		return requests
+	}
+	newRpc := func(opts ...Option) JsonRPC {
+		rpc := NewJsonRpc(append([]Option{WithBatchConcurrency(1), WithBatchTimeout(200 * time.Millisecond)}, opts...)...)
+		rpc.RegisterWithName(blockingService{started: make(chan struct{}, 1)}, "Blocking")
+		rpc.RegisterWithName(arith{}, "Arith")
+		rpc.MapError(context.DeadlineExceeded, SERVER_BUSY, "Timed out")
+		return rpc
+	}
+
+	//The slow entry only gets its share of the deadline, so the fast ones still run
+	start := time.Now()
+	responses, err := makeRpcBatchTestRequest(newRpc(WithBatchEntryBudgets()), batch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+	assert.Equal(t, SERVER_BUSY, responses[0].Error.Code)
+	for i, res := range responses[1:] {
+		assert.Nil(t, res.Error)
+		assert.Equal(t, any(float64(i+1)), *res.Result)
+	}
+
+	//Without budgets the slow entry uses up the deadline of the batch
+	responses, err = makeRpcBatchTestRequest(newRpc(), batch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range responses {
+		assert.Equal(t, SERVER_BUSY, res.Error.Code)
+	}
+}
+
+func TestWithEntryBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	//Four entries left on two workers need two rounds
+	entryCtx, entryCancel := withEntryBudget(ctx, 4, 2)
+	defer entryCancel()
+	deadline, _ := entryCtx.Deadline()
+	assert.InDelta(t, 30*time.Second, time.Until(deadline), float64(time.Second))
+
+	//Batches without a deadline give none to their entries
+	entryCtx, _ = withEntryBudget(context.Background(), 4, 2)
+	_, ok := entryCtx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestWithMiddleware(t *testing.T) {
+	var seen []string
+	rpc := NewJsonRpc(WithMiddleware(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			seen = append(seen, req.Method)
+			if req.Method == "Arith.ErrorMethod" {
+				return makeErrorResponse(errors.New("Forbidden"), INVALID_REQUEST, nil, req.Id)
+			}
+			return next(ctx, req)
+		}
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	id := "1"
+	res, err := makeRpcSingleTestRequest(rpc, Request{Id: &id, Method: "Arith.ErrorMethod", Params: []any{}, Jsonrpc: RPC_VERSION})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, []string{"Arith.ErrorMethod"}, seen)
+}
+
+func TestWithLogger(t *testing.T) {
+	logger := &testLogger{}
+	rpc := NewJsonRpc(WithLogger(logger))
+	rpc.RegisterWithName(panicService{}, "Panic")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Panic.Panic","params":[]}`)
+
+	assert.Contains(t, body, `"code":32603`)
+	assert.Equal(t, []string{"Recovered from panic in Panic.Panic: boom"}, logger.lines)
+}
+
+func TestWithCodec(t *testing.T) {
+	rpc := NewJsonRpc(WithCodec(upperCodec{}))
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["hi"]}`)
+
+	assert.Equal(t, `{"JSONRPC":"2.0","ID":"1","RESULT":"HI"}`, body)
+}
+
+func TestWithMaxRequestSize(t *testing.T) {
+	rpc := NewJsonRpc(WithMaxRequestSize(32))
+	rpc.RegisterWithName(encodingService{}, "Enc")
+
+	res := &Response{}
+	json.Unmarshal([]byte(serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Enc.Echo","params":["hi"]}`)), res)
+
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+}
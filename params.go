@@ -0,0 +1,268 @@
+package jsonrpc2
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	bigIntType   = reflect.TypeOf(big.Int{})
+)
+
+// Positional params of a request. Named params are put in the order of the param names of the method, or passed as
+// the only param of methods taking a single struct
+func (s *service) positionalParams(methodName string, params any) ([]any, error) {
+	switch p := params.(type) {
+	case nil:
+		return []any{}, nil
+	case []any:
+		return p, nil
+	case map[string]any:
+		if names, ok := s.paramNames[methodName]; ok {
+			return namedParams(s.methods[methodName].Type(), names, p)
+		}
+		if method, ok := s.methods[methodName]; ok && takesParamsStruct(method.Type()) {
+			return []any{p}, nil
+		}
+		return nil, errors.New("Params must be passed by position")
+	default:
+		return nil, errors.New("Params must be an array")
+	}
+}
+
+// Whether the method takes a single struct, or pointer to struct, after the context
+func takesParamsStruct(methodType reflect.Type) bool {
+	if methodType.NumIn() != 2 || methodType.IsVariadic() {
+		return false
+	}
+
+	t := methodType.In(1)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct
+}
+
+// Check the method takes a param for every name, excluding the context and the input stream
+func checkParamNames(method string, methodType reflect.Type, names []string) error {
+	shape, _ := streamShapeOf(methodType)
+	if expected := methodType.NumIn() - 1 - shape.params(); len(names) != expected {
+		return errors.New(fmt.Sprintf("Method %s takes %d params but %d names are given", method, expected, len(names)))
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" || seen[name] {
+			return errors.New(fmt.Sprintf("Param names of method %s must be unique and not empty", method))
+		}
+		seen[name] = true
+	}
+
+	return nil
+}
+
+// Put params passed by name in the order of the names. Missing params are nil when their type can be, and the
+// elements of an array passed to the last param of a variadic method are its values
+func namedParams(methodType reflect.Type, names []string, params map[string]any) ([]any, error) {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	for name := range params {
+		if !known[name] {
+			return nil, errors.New(fmt.Sprintf("Unknown param %s", name))
+		}
+	}
+
+	args := make([]any, 0, len(names))
+	for i, name := range names {
+		value, ok := params[name]
+		last := i == len(names)-1
+		switch {
+		case last && methodType.IsVariadic():
+			if values, isArray := value.([]any); isArray {
+				return append(args, values...), nil
+			}
+			if ok {
+				args = append(args, value)
+			}
+			return args, nil
+		case !ok && !nilable(methodType.In(i+1)):
+			return nil, errors.New(fmt.Sprintf("Param %s is required", name))
+		}
+		args = append(args, value)
+	}
+
+	return args, nil
+}
+
+func nilable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+
+	return false
+}
+
+// Type of the positional param at index i of a method, accounting for variadic methods.
+// Nil is returned when the method does not accept that many params.
+func paramType(methodType reflect.Type, i int) reflect.Type {
+	if methodType.IsVariadic() && i >= methodType.NumIn()-1 {
+		return methodType.In(methodType.NumIn() - 1).Elem()
+	}
+
+	if i >= methodType.NumIn() {
+		return nil
+	}
+
+	return methodType.In(i)
+}
+
+// Check the number of positional params against the method, excluding the context
+func checkParamCount(methodType reflect.Type, n int) error {
+	expected := methodType.NumIn() - 1
+	if methodType.IsVariadic() {
+		if n < expected-1 {
+			return errors.New(fmt.Sprintf("Method expects at least %d params, got %d", expected-1, n))
+		}
+		return nil
+	}
+
+	if n != expected {
+		return errors.New(fmt.Sprintf("Method expects %d params, got %d", expected, n))
+	}
+
+	return nil
+}
+
+// Convert a decoded param to the value passed to the method.
+// Values that do not match the declared type, eg. objects passed to struct params, are re-decoded into it.
+func paramValue(arg any, t reflect.Type) (reflect.Value, error) {
+	if t == nil {
+		return reflect.ValueOf(arg), nil
+	}
+
+	if arg == nil {
+		return reflect.Zero(t), nil
+	}
+
+	if reflect.TypeOf(arg).AssignableTo(t) {
+		return reflect.ValueOf(arg), nil
+	}
+
+	if v, ok, err := unmarshalParam(arg, t); ok {
+		return v, err
+	}
+
+	raw, err := json.Marshal(arg)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return reflect.Value{}, errors.New(fmt.Sprintf("Param %s is not a valid %s", raw, t))
+	}
+
+	return v.Elem(), nil
+}
+
+// Decode params of types with a natural JSON form the default conversion misses, eg. durations such as "1m30s",
+// big integers sent as strings, and types implementing json.Unmarshaler or encoding.TextUnmarshaler such as
+// time.Time. False is returned for other types.
+func unmarshalParam(arg any, t reflect.Type) (reflect.Value, bool, error) {
+	target := t
+	if target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	if target.Kind() == reflect.Interface {
+		return reflect.Value{}, false, nil
+	}
+
+	ptr := reflect.New(target)
+	switch {
+	case target == durationType:
+		s, ok := arg.(string)
+		if !ok {
+			//Numbers are nanoseconds, which the default conversion handles
+			return reflect.Value{}, false, nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, true, invalidParam(arg, t, err)
+		}
+		ptr.Elem().SetInt(int64(d))
+
+	case target == bigIntType:
+		if err := setBigInt(ptr.Interface().(*big.Int), arg); err != nil {
+			return reflect.Value{}, true, invalidParam(arg, t, err)
+		}
+
+	case ptr.Type().Implements(jsonUnmarshalerType):
+		raw, err := json.Marshal(arg)
+		if err != nil {
+			return reflect.Value{}, true, err
+		}
+		if err := ptr.Interface().(json.Unmarshaler).UnmarshalJSON(raw); err != nil {
+			return reflect.Value{}, true, invalidParam(arg, t, err)
+		}
+
+	case ptr.Type().Implements(textUnmarshalerType):
+		s, ok := arg.(string)
+		if !ok {
+			return reflect.Value{}, true, invalidParam(arg, t, errors.New("Expected a string"))
+		}
+		if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return reflect.Value{}, true, invalidParam(arg, t, err)
+		}
+
+	default:
+		return reflect.Value{}, false, nil
+	}
+
+	if t.Kind() == reflect.Pointer {
+		return ptr, true, nil
+	}
+
+	return ptr.Elem(), true, nil
+}
+
+// Set z to an integer param sent as a string, in any base strconv accepts such as 0x, or as a number. Numbers beyond
+// 2^53 keep their precision only when decoded with UseNumber
+func setBigInt(z *big.Int, arg any) error {
+	switch a := arg.(type) {
+	case string:
+		if _, ok := z.SetString(a, 0); !ok {
+			return errors.New("Not an integer")
+		}
+	case json.Number:
+		if _, ok := z.SetString(a.String(), 10); !ok {
+			return errors.New("Not an integer")
+		}
+	case float64:
+		f := big.NewFloat(a)
+		if !f.IsInt() {
+			return errors.New("Not an integer")
+		}
+		f.Int(z)
+	default:
+		return errors.New("Expected a string or a number")
+	}
+
+	return nil
+}
+
+// Error of a param that could not be decoded into its type
+func invalidParam(arg any, t reflect.Type, err error) error {
+	raw, _ := json.Marshal(arg)
+
+	return errors.New(fmt.Sprintf("Param %s is not a valid %s: %v", raw, t, err))
+}
@@ -0,0 +1,264 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	address struct {
+		City string `json:"city"`
+	}
+
+	person struct {
+		Name    string   `json:"name"`
+		Address *address `json:"address"`
+	}
+
+	directory struct{}
+
+	//Decodes itself from its name
+	level int
+
+	//Decodes itself from a "lat,lng" string or a [lat, lng] array
+	point struct {
+		Lat, Lng float64
+	}
+
+	calendar struct{}
+
+	//Joins names, with params by position or by name
+	roster struct{}
+)
+
+func (l *level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "error":
+		*l = 2
+	default:
+		return errors.New("Unknown level " + string(text))
+	}
+
+	return nil
+}
+
+func (p *point) UnmarshalJSON(data []byte) error {
+	var coords []float64
+	if err := json.Unmarshal(data, &coords); err == nil && len(coords) == 2 {
+		p.Lat, p.Lng = coords[0], coords[1]
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	lat, lng, _ := strings.Cut(s, ",")
+	if err := json.Unmarshal([]byte(lat), &p.Lat); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(lng), &p.Lng)
+}
+
+func (calendar) Delay(ctx context.Context, at time.Time, after time.Duration) (string, error, *RpcErrorCode) {
+	return at.Add(after).UTC().Format(time.RFC3339), nil, nil
+}
+
+func (calendar) Double(ctx context.Context, n *big.Int) (string, error, *RpcErrorCode) {
+	return new(big.Int).Lsh(n, 1).String(), nil, nil
+}
+
+func (calendar) Log(ctx context.Context, l level, at point) (float64, error, *RpcErrorCode) {
+	return float64(l) + at.Lat + at.Lng, nil, nil
+}
+
+func (directory) City(ctx context.Context, p person) (string, error, *RpcErrorCode) {
+	return p.Address.City, nil, nil
+}
+
+func (directory) Rename(ctx context.Context, p *person, name string) (*person, error, *RpcErrorCode) {
+	p.Name = name
+	return p, nil, nil
+}
+
+func (directory) Count(ctx context.Context, people []person, tags map[string]int) (int, error, *RpcErrorCode) {
+	return len(people) + tags["extra"], nil, nil
+}
+
+func (roster) Join(ctx context.Context, sep string, names ...string) (string, error, *RpcErrorCode) {
+	return strings.Join(names, sep), nil, nil
+}
+
+func decodeTestResponse(t *testing.T, body string) *Response {
+	res := &Response{}
+	if err := json.Unmarshal([]byte(body), res); err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestStructParam(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(directory{}, "Dir")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Dir.City","params":[{"name":"Ada","address":{"city":"London"}}]}`)
+
+	assert.Contains(t, body, `"result":"London"`)
+}
+
+func TestPointerParam(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(directory{}, "Dir")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Dir.Rename","params":[{"name":"Ada"},"Grace"]}`)
+
+	assert.Contains(t, body, `"result":{"name":"Grace","address":null}`)
+}
+
+func TestSliceAndMapParams(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(directory{}, "Dir")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Dir.Count","params":[[{"name":"Ada"},{"name":"Grace"}],{"extra":3}]}`)
+
+	assert.Contains(t, body, `"result":5`)
+}
+
+func TestInvalidStructParam(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(directory{}, "Dir")
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Dir.City","params":["London"]}`))
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+}
+
+func TestWrongParamCount(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1]}`))
+
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+}
+
+func TestTimeParams(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(calendar{}, "Calendar")
+
+	res := callMethod(t, rpc, "Calendar.Delay", []any{"2024-01-02T10:00:00+02:00", "1h30m"})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, any("2024-01-02T09:30:00Z"), *res.Result)
+
+	//Durations can still be sent as nanoseconds
+	res = callMethod(t, rpc, "Calendar.Delay", []any{"2024-01-02T10:00:00Z", float64(time.Second)})
+	assert.Equal(t, any("2024-01-02T10:00:01Z"), *res.Result)
+
+	res = callMethod(t, rpc, "Calendar.Delay", []any{"2024-01-02T10:00:00Z", "soon"})
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, `Param "soon" is not a valid time.Duration: time: invalid duration "soon"`, res.Error.Message)
+
+	res = callMethod(t, rpc, "Calendar.Delay", []any{"yesterday", "1h"})
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Contains(t, res.Error.Message, `Param "yesterday" is not a valid time.Time`)
+}
+
+func TestBigIntParams(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(calendar{}, "Calendar")
+
+	res := callMethod(t, rpc, "Calendar.Double", []any{"123456789012345678901234567890"})
+	assert.Equal(t, any("246913578024691357802469135780"), *res.Result)
+
+	res = callMethod(t, rpc, "Calendar.Double", []any{"0xff"})
+	assert.Equal(t, any("510"), *res.Result)
+
+	res = callMethod(t, rpc, "Calendar.Double", []any{21})
+	assert.Equal(t, any("42"), *res.Result)
+
+	res = callMethod(t, rpc, "Calendar.Double", []any{1.5})
+	assert.Equal(t, `Param 1.5 is not a valid *big.Int: Not an integer`, res.Error.Message)
+
+	//Numbers keep their precision with UseNumber
+	precise := NewJsonRpc(UseNumber())
+	precise.RegisterWithName(calendar{}, "Calendar")
+	body := serveTestBody(precise, `{"jsonrpc":"2.0","id":"1","method":"Calendar.Double","params":[123456789012345678901]}`)
+	assert.Contains(t, body, `"result":"246913578024691357802"`)
+}
+
+func TestUnmarshalerParams(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(calendar{}, "Calendar")
+
+	res := callMethod(t, rpc, "Calendar.Log", []any{"error", "1.5,2"})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, any(5.5), *res.Result)
+
+	res = callMethod(t, rpc, "Calendar.Log", []any{"debug", []any{1, 2}})
+	assert.Equal(t, any(float64(3)), *res.Result)
+
+	res = callMethod(t, rpc, "Calendar.Log", []any{"trace", "1,2"})
+	assert.Equal(t, `Param "trace" is not a valid jsonrpc2.level: Unknown level trace`, res.Error.Message)
+
+	res = callMethod(t, rpc, "Calendar.Log", []any{2, "1,2"})
+	assert.Equal(t, `Param 2 is not a valid jsonrpc2.level: Expected a string`, res.Error.Message)
+}
+
+func TestParamsByName(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithOptions(arith{}, ServiceOptions{Name: "Arith", ParamNames: map[string][]string{"Add": {"a", "b"}}}))
+	assert.Nil(t, rpc.RegisterWithOptions(directory{}, ServiceOptions{Name: "Dir", ParamNames: map[string][]string{"Rename": {"person", "name"}}}))
+	assert.Nil(t, rpc.RegisterWithOptions(roster{}, ServiceOptions{Name: "Roster", ParamNames: map[string][]string{"Join": {"sep", "names"}}}))
+
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":{"b":2,"a":1}}`), `"result":3`)
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`), `"result":3`)
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Roster.Join","params":{"sep":"+","names":["a","b"]}}`), `"result":"a+b"`)
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Roster.Join","params":{"sep":"+"}}`), `"result":""`)
+
+	//Missing params whose type can be nil are nil
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Dir.Rename","params":{"person":{"name":"Ada"},"name":"Grace"}}`), `"result":{"name":"Grace","address":null}`)
+
+	//Structs are still bound by name without names
+	assert.Contains(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Dir.City","params":{"name":"Ada","address":{"city":"London"}}}`), `"result":"London"`)
+
+	res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":{"a":1}}`))
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "Param b is required", res.Error.Message)
+
+	res = decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":{"a":1,"b":2,"c":3}}`))
+	assert.Equal(t, "Unknown param c", res.Error.Message)
+
+	res = decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Roster.Join","params":{"names":["a"]}}`))
+	assert.Equal(t, "Param sep is required", res.Error.Message)
+}
+
+func TestUnknownMethodWithNamedParams(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	for _, params := range []string{`{"a":1}`, `"a"`} {
+		res := decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Missing","params":`+params+`}`))
+		assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+		assert.Equal(t, "Method Missing does not exist on service Arith", res.Error.Message)
+	}
+}
+
+func TestInvalidParamNames(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	err := rpc.RegisterWithOptions(arith{}, ServiceOptions{Name: "Arith", ParamNames: map[string][]string{"Add": {"a"}}})
+	assert.EqualError(t, err, "Method Arith.Add takes 2 params but 1 names are given")
+
+	err = rpc.RegisterWithOptions(arith{}, ServiceOptions{Name: "Arith", ParamNames: map[string][]string{"Add": {"a", "a"}}})
+	assert.EqualError(t, err, "Param names of method Arith.Add must be unique and not empty")
+}
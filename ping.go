@@ -0,0 +1,145 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	//PingResult is the answer of rpc.ping. Build info is empty when the server binary was built without module support
+	PingResult struct {
+		Time      time.Time `json:"time"`                //Time of the server when it answered
+		Module    string    `json:"module,omitempty"`    //Path of the main module of the server binary
+		Version   string    `json:"version,omitempty"`   //Version of the main module, eg. v1.2.3 or (devel)
+		Revision  string    `json:"revision,omitempty"`  //Revision of the version control system the binary was built from
+		GoVersion string    `json:"goVersion,omitempty"` //Version of Go the binary was built with
+	}
+
+	//LatencyStats are the round trip times of the pings of MeasureLatency
+	LatencyStats struct {
+		Samples int           `json:"samples"`
+		Min     time.Duration `json:"min"`
+		Max     time.Duration `json:"max"`
+		Mean    time.Duration `json:"mean"`
+		Median  time.Duration `json:"median"`
+		Jitter  time.Duration `json:"jitter"` //Mean difference between consecutive round trips
+
+		//Estimated difference between the clocks of the server and the client, from the fastest ping. Positive when the
+		//server is ahead. Zero when the server does not tell its time
+		ClockOffset time.Duration `json:"clockOffset"`
+	}
+)
+
+var (
+	buildInfoOnce sync.Once
+	buildInfo     PingResult //Build info of the binary, without a time
+)
+
+func (rpc *jsonRpcImpl) registerPingMethod(builtins *service) {
+	builtins.methods["ping"] = reflect.ValueOf(func(ctx context.Context) (PingResult, error, *RpcErrorCode) {
+		buildInfoOnce.Do(readBuildInfo)
+
+		result := buildInfo
+		result.Time = time.Now()
+		return result, nil, nil
+	})
+}
+
+func readBuildInfo() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	buildInfo = PingResult{Module: info.Main.Path, Version: info.Main.Version, GoVersion: info.GoVersion}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			buildInfo.Revision = setting.Value
+		}
+	}
+}
+
+// Ping calls rpc.ping to check the server answers. Servers answering it with something else than a PingResult, eg.
+// "pong", return an empty result
+func (c *Client) Ping(ctx context.Context) (PingResult, error) {
+	var raw json.RawMessage
+	if err := c.Call(ctx, HEARTBEAT_PING_METHOD, nil, &raw); err != nil {
+		return PingResult{}, err
+	}
+
+	var result PingResult
+	if json.Unmarshal(raw, &result) != nil {
+		return PingResult{}, nil
+	}
+
+	return result, nil
+}
+
+// MeasureLatency pings the server n times, one after the other, and returns the round trip times. It stops at the
+// first failed ping
+func (c *Client) MeasureLatency(ctx context.Context, n int) (LatencyStats, error) {
+	if n <= 0 {
+		return LatencyStats{}, errors.New("Latency is measured over at least one ping")
+	}
+
+	samples := make([]time.Duration, 0, n)
+	stats := LatencyStats{}
+	for i := 0; i < n; i++ {
+		sent := time.Now()
+		result, err := c.Ping(ctx)
+		if err != nil {
+			return LatencyStats{}, err
+		}
+		rtt := time.Since(sent)
+
+		if len(samples) == 0 || rtt < stats.Min {
+			stats.Min = rtt
+			if !result.Time.IsZero() {
+				//The server answered about half way through the round trip
+				stats.ClockOffset = result.Time.Sub(sent.Add(rtt / 2))
+			}
+		}
+		samples = append(samples, rtt)
+	}
+
+	return summarizeLatency(samples, stats), nil
+}
+
+// Complete the statistics of the round trips, in the order they were measured
+func summarizeLatency(samples []time.Duration, stats LatencyStats) LatencyStats {
+	stats.Samples = len(samples)
+
+	var total, jitter time.Duration
+	for i, rtt := range samples {
+		total += rtt
+		if rtt > stats.Max {
+			stats.Max = rtt
+		}
+		if i > 0 {
+			diff := rtt - samples[i-1]
+			if diff < 0 {
+				diff = -diff
+			}
+			jitter += diff
+		}
+	}
+	stats.Mean = total / time.Duration(len(samples))
+	if len(samples) > 1 {
+		stats.Jitter = jitter / time.Duration(len(samples)-1)
+	}
+
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.Median = sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		stats.Median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	return stats
+}
@@ -0,0 +1,62 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientPing(t *testing.T) {
+	client := NewHTTPClient(newTestHTTPServer(t, NewJsonRpc()))
+
+	before := time.Now()
+	pong, err := client.Ping(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, pong.Time.Before(before.Add(-time.Second)))
+	assert.NotEmpty(t, pong.GoVersion)
+}
+
+func TestPingLegacyServer(t *testing.T) {
+	//Servers answering "pong" are alive too
+	url := newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":"1","result":"pong"}`))
+	}))
+
+	pong, err := NewHTTPClient(url).Ping(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, PingResult{}, pong)
+
+	stats, err := NewHTTPClient(url).MeasureLatency(context.Background(), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, stats.Samples)
+	assert.Zero(t, stats.ClockOffset)
+}
+
+func TestMeasureLatency(t *testing.T) {
+	client := NewHTTPClient(newTestHTTPServer(t, NewJsonRpc()))
+
+	stats, err := client.MeasureLatency(context.Background(), 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, stats.Samples)
+	assert.True(t, stats.Min > 0)
+	assert.True(t, stats.Min <= stats.Median && stats.Median <= stats.Max)
+	assert.True(t, stats.Min <= stats.Mean && stats.Mean <= stats.Max)
+	//Both clocks are the same
+	assert.True(t, stats.ClockOffset.Abs() < stats.Max)
+
+	_, err = client.MeasureLatency(context.Background(), 0)
+	assert.EqualError(t, err, "Latency is measured over at least one ping")
+
+	_, err = NewHTTPClient("http://127.0.0.1:1").MeasureLatency(context.Background(), 3)
+	assert.NotNil(t, err)
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	ms := time.Millisecond
+	stats := summarizeLatency([]time.Duration{4 * ms, 2 * ms, 6 * ms, 8 * ms}, LatencyStats{Min: 2 * ms})
+
+	assert.Equal(t, LatencyStats{Samples: 4, Min: 2 * ms, Max: 8 * ms, Mean: 5 * ms, Median: 5 * ms, Jitter: 8 * ms / 3}, stats)
+}
@@ -0,0 +1,141 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+)
+
+// Path the playground is usually served on
+const DEFAULT_PLAYGROUND_PATH = "/playground"
+
+// WithPlayground serves an interactive playground on GET requests to path, eg. DEFAULT_PLAYGROUND_PATH.
+// It lists the registered methods with their documentation, a form for their params, the examples of their
+// MethodDoc and a console sending requests to the server.
+// Only enable it where every user of the server may see the registered methods.
+func WithPlayground(path string) Option {
+	return func(c *config) {
+		c.playgroundPath = path
+	}
+}
+
+// Serve the playground. False is returned when the request is not for the playground
+func (rpc *jsonRpcImpl) servePlayground(w http.ResponseWriter, r *http.Request) bool {
+	path := rpc.cfg().playgroundPath
+	if path == "" || r.Method != http.MethodGet || r.URL.Path != path {
+		return false
+	}
+
+	services, _, _ := introspection{rpc: rpc}.Describe(context.Background())
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := playgroundTemplate.Execute(w, services); err != nil {
+		rpc.cfg().logger.Printf("Unable to render playground: %v", err)
+	}
+
+	return true
+}
+
+var playgroundTemplate = template.Must(template.New("playground").Funcs(template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>JSON-RPC playground</title>
+<style>
+body { font-family: sans-serif; margin: 0; display: flex; height: 100vh; }
+nav { width: 280px; overflow: auto; border-right: 1px solid #ddd; padding: 1em; }
+nav h3 { margin: 1em 0 .25em; }
+nav a { display: block; padding: .2em 0; cursor: pointer; color: #0645ad; }
+nav .deprecated { text-decoration: line-through; }
+main { flex: 1; padding: 1em; display: flex; flex-direction: column; gap: .5em; }
+textarea, pre { font-family: monospace; width: 100%; box-sizing: border-box; }
+textarea { height: 30%; }
+pre { flex: 1; overflow: auto; background: #f6f6f6; margin: 0; padding: .5em; }
+label { display: block; }
+label small { color: #666; }
+</style>
+</head>
+<body>
+<nav>
+{{range .}}<h3>{{.Name}}</h3>
+{{range .Methods}}<a title="{{.Description}}" class="{{if .Deprecated}}deprecated{{end}}" data-method="{{.Name}}" data-params="{{range $i, $p := .Params}}{{if $i}},{{end}}{{$p}}{{end}}" data-docs="{{json .ParamDocs}}" data-examples="{{json .Examples}}">{{.Name}}</a>
+{{end}}{{else}}<p>No service registered</p>{{end}}
+</nav>
+<main>
+<h2 id="method">Pick a method</h2>
+<p id="description"></p>
+<form id="params"></form>
+<div id="examples"></div>
+<textarea id="request">{"jsonrpc": "2.0", "id": "1", "method": "", "params": []}</textarea>
+<div><button id="send">Send</button></div>
+<pre id="response"></pre>
+</main>
+<script>
+const form = document.getElementById("params");
+const request = document.getElementById("request");
+
+function paramValue(kind, value) {
+  if (kind === "string") return value;
+  try { return JSON.parse(value); } catch (e) { return value; }
+}
+
+function updateRequest(method) {
+  const params = Array.from(form.querySelectorAll("input")).map(i => paramValue(i.dataset.kind, i.value));
+  request.value = JSON.stringify({jsonrpc: "2.0", id: "1", method: method, params: params}, null, 2);
+}
+
+document.querySelectorAll("nav a").forEach(a => a.addEventListener("click", () => {
+  const method = a.dataset.method;
+  const docs = JSON.parse(a.dataset.docs) || [];
+  document.getElementById("method").textContent = method;
+  document.getElementById("description").textContent = a.title;
+  form.innerHTML = "";
+  a.dataset.params.split(",").filter(p => p).forEach((kind, i) => {
+    const doc = docs[i] || {name: "Param " + (i + 1)};
+    const label = document.createElement("label");
+    label.textContent = doc.name + " (" + kind + ") ";
+    const input = document.createElement("input");
+    input.dataset.kind = kind;
+    input.addEventListener("input", () => updateRequest(method));
+    label.appendChild(input);
+    if (doc.description) {
+      const description = document.createElement("small");
+      description.textContent = doc.description;
+      label.appendChild(description);
+    }
+    form.appendChild(label);
+  });
+
+  const examples = document.getElementById("examples");
+  examples.innerHTML = "";
+  (JSON.parse(a.dataset.examples) || []).forEach((example, i) => {
+    const button = document.createElement("button");
+    button.textContent = example.name || "Example " + (i + 1);
+    button.addEventListener("click", () => {
+      request.value = JSON.stringify({jsonrpc: "2.0", id: "1", method: method, params: example.params}, null, 2);
+    });
+    examples.appendChild(button);
+  });
+  updateRequest(method);
+}));
+
+document.getElementById("send").addEventListener("click", async () => {
+  const res = await fetch(location.pathname, {method: "POST", headers: {"Content-Type": "application/json"}, body: request.value});
+  const text = await res.text();
+  try {
+    document.getElementById("response").textContent = JSON.stringify(JSON.parse(text), null, 2);
+  } catch (e) {
+    document.getElementById("response").textContent = res.status + " " + text;
+  }
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,60 @@
+package jsonrpc2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlayground(t *testing.T) {
+	rpc := NewJsonRpc(WithPlayground(DEFAULT_PLAYGROUND_PATH))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DEFAULT_PLAYGROUND_PATH, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `data-method="Arith.Add" data-params="float64,float64"`)
+}
+
+func TestPlaygroundDisabledByDefault(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DEFAULT_PLAYGROUND_PATH, nil))
+
+	assert.NotContains(t, recorder.Body.String(), "<html>")
+}
+
+func TestPlaygroundConsolePostsToServer(t *testing.T) {
+	rpc := NewJsonRpc(WithPlayground(DEFAULT_PLAYGROUND_PATH))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)
+
+	assert.Contains(t, body, `"result":3`)
+}
+
+func TestPlaygroundDocs(t *testing.T) {
+	rpc := NewJsonRpc(WithPlayground(DEFAULT_PLAYGROUND_PATH))
+	rpc.RegisterWithOptions(arith{}, ServiceOptions{
+		Name: "Arith",
+		Docs: map[string]MethodDoc{"Add": {
+			Description: "Adds <two> numbers",
+			Params:      []ParamDoc{{Name: "a"}, {Name: "b", Description: "Second term"}},
+			Examples:    []MethodExample{{Params: []any{1, 2}}},
+		}},
+	})
+
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, DEFAULT_PLAYGROUND_PATH, nil))
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `title="Adds &lt;two&gt; numbers"`)
+	assert.Contains(t, body, `data-docs="[{&#34;name&#34;:&#34;a&#34;},{&#34;name&#34;:&#34;b&#34;,&#34;description&#34;:&#34;Second term&#34;}]"`)
+	assert.Contains(t, body, `data-examples="[{&#34;params&#34;:[1,2]}]"`)
+}
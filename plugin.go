@@ -0,0 +1,362 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	//Environment variable telling a plugin the unix domain socket to listen on
+	PLUGIN_SOCKET_ENV = "JSONRPC2_PLUGIN_SOCKET"
+	//Wait before restarting a plugin that exited, unless its config sets another
+	PLUGIN_RESTART_DELAY = time.Second
+	//How long a plugin has to create its socket once started
+	PLUGIN_START_TIMEOUT = 10 * time.Second
+	//How long a plugin has to exit once the host disconnects before it is killed
+	PLUGIN_STOP_TIMEOUT = 5 * time.Second
+)
+
+type (
+	//PluginConfig describes a plugin process and how the host talks to it
+	PluginConfig struct {
+		Name         string        //Namespace of the plugin. eg. the method Greeter.Hello of the plugin greeter is called as greeter.Greeter.Hello
+		Command      string        //Executable of the plugin
+		Args         []string      //Arguments passed to the executable
+		Env          []string      //Environment of the process, added to the one of the host
+		Socket       string        //Unix domain socket the plugin listens on. Empty to talk over the standard input and output of the process
+		RestartDelay time.Duration //Wait before restarting the plugin once it exited. Defaults to PLUGIN_RESTART_DELAY
+	}
+
+	//PluginHost runs services implemented in external processes. The host starts the plugins, forwards the calls
+	//of their namespace to them and restarts them when they exit.
+	PluginHost struct {
+		mu      sync.RWMutex
+		plugins map[string]*plugin
+		logger  Logger
+	}
+
+	//Plugin process supervised by a host
+	plugin struct {
+		config    PluginConfig
+		logger    Logger
+		mu        sync.RWMutex
+		cmd       *exec.Cmd
+		transport ClientTransport //Nil while the plugin is not running
+		stop      chan struct{}
+		stopped   chan struct{}
+	}
+
+	//Connection over a pair of pipes, eg. the standard input and output of a process
+	pipeConn struct {
+		io.ReadCloser
+		io.WriteCloser
+	}
+
+	pipeAddr struct{}
+)
+
+// NewPluginHost creates a host without plugins. Plugins exiting and restarting are logged to logger,
+// the standard logger of the log package when nil.
+func NewPluginHost(logger Logger) *PluginHost {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	return &PluginHost{plugins: make(map[string]*plugin), logger: logger}
+}
+
+// Attach starts the plugin and supervises it until the host is closed. It fails when the plugin does not start.
+// Plugins implement their services with a JsonRPC server passed to ServePlugin, and log to their standard error.
+func (h *PluginHost) Attach(config PluginConfig) error {
+	if config.Name == "" || strings.Contains(config.Name, ".") {
+		return errors.New("Plugin name must not be empty or contain a dot")
+	}
+	if config.RestartDelay == 0 {
+		config.RestartDelay = PLUGIN_RESTART_DELAY
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.plugins[config.Name]; ok {
+		return errors.New(fmt.Sprintf("Plugin %s is already attached", config.Name))
+	}
+
+	p := &plugin{
+		config:  config,
+		logger:  h.logger,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+	h.plugins[config.Name] = p
+	go p.supervise()
+
+	return nil
+}
+
+// Running reports whether the named plugin is attached and running
+func (h *PluginHost) Running(name string) bool {
+	p := h.plugin(name)
+	return p != nil && p.current() != nil
+}
+
+// Middleware forwards the calls of the attached plugins, stripped of their namespace. Calls fail with
+// UPSTREAM_UNAVAILABLE while their plugin is restarting. Other calls are passed on.
+func (h *PluginHost) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			name, method, ok := strings.Cut(req.Method, ".")
+			p := h.plugin(name)
+			if !ok || p == nil {
+				return next(ctx, req)
+			}
+
+			return p.forward(ctx, req, method)
+		}
+	}
+}
+
+// Close stops the plugins. They are killed when they do not exit within PLUGIN_STOP_TIMEOUT
+func (h *PluginHost) Close() error {
+	h.mu.Lock()
+	plugins := h.plugins
+	h.plugins = make(map[string]*plugin)
+	h.mu.Unlock()
+
+	for _, p := range plugins {
+		p.close()
+	}
+
+	return nil
+}
+
+func (h *PluginHost) plugin(name string) *plugin {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.plugins[name]
+}
+
+// ServePlugin serves rpc as a plugin of a PluginHost: on the unix domain socket the host asks for, else on the
+// standard input and output of the process. It returns once the host disconnects.
+func ServePlugin(rpc JsonRPC) error {
+	path := os.Getenv(PLUGIN_SOCKET_ENV)
+	if path == "" {
+		rpc.ServeConn(pipeConn{ReadCloser: os.Stdin, WriteCloser: os.Stdout})
+		return nil
+	}
+
+	//Left over by a previous run of the plugin
+	os.Remove(path)
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		return err
+	}
+	rpc.ServeConn(conn)
+
+	return nil
+}
+
+// Start the process and connect to it
+func (p *plugin) start() error {
+	cmd := exec.Command(p.config.Command, p.config.Args...)
+	cmd.Env = append(os.Environ(), p.config.Env...)
+	cmd.Stderr = os.Stderr
+
+	var conn net.Conn
+	if p.config.Socket == "" {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		conn = pipeConn{ReadCloser: stdout, WriteCloser: stdin}
+	} else {
+		//Do not connect to the socket of a previous run
+		os.Remove(p.config.Socket)
+		cmd.Env = append(cmd.Env, PLUGIN_SOCKET_ENV+"="+p.config.Socket)
+		cmd.Stdout = os.Stdout
+	}
+
+	if err := cmd.Start(); err != nil {
+		return errors.New(fmt.Sprintf("Plugin %s failed to start: %s", p.config.Name, err))
+	}
+
+	if conn == nil {
+		var err error
+		if conn, err = dialPlugin(p.config.Socket); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return errors.New(fmt.Sprintf("Plugin %s failed to start: %s", p.config.Name, err))
+		}
+	}
+
+	p.mu.Lock()
+	p.cmd = cmd
+	p.transport = newConnTransport(conn)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Dial the socket of a plugin once the plugin listens on it
+func dialPlugin(path string) (net.Conn, error) {
+	deadline := time.Now().Add(PLUGIN_START_TIMEOUT)
+	for {
+		conn, err := net.Dial("unix", path)
+		if err == nil || time.Now().After(deadline) {
+			return conn, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Restart the plugin every time it exits, until it is closed
+func (p *plugin) supervise() {
+	defer close(p.stopped)
+
+	for {
+		p.mu.RLock()
+		cmd := p.cmd
+		p.mu.RUnlock()
+
+		err := cmd.Wait()
+
+		p.mu.Lock()
+		p.transport.Close()
+		p.transport = nil
+		p.mu.Unlock()
+
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+		p.logger.Printf("Plugin %s exited: %v. Restarting in %s", p.config.Name, err, p.config.RestartDelay)
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(p.config.RestartDelay):
+			}
+
+			err := p.start()
+			if err == nil {
+				break
+			}
+			p.logger.Printf("%s. Restarting in %s", err, p.config.RestartDelay)
+		}
+
+		//Closed while restarting. Disconnecting makes the new process exit
+		select {
+		case <-p.stop:
+			p.current().Close()
+		default:
+		}
+	}
+}
+
+// Transport of the running process. Nil while the plugin is not running
+func (p *plugin) current() ClientTransport {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.transport
+}
+
+// Forward the request to the plugin as method
+func (p *plugin) forward(ctx context.Context, req *Request, method string) Response {
+	transport := p.current()
+	if transport == nil {
+		return makeErrorResponse(errors.New(fmt.Sprintf("Plugin %s is not running", p.config.Name)), UPSTREAM_UNAVAILABLE, nil, req.Id)
+	}
+
+	forwarded := *req
+	forwarded.Method = method
+	msg, err := json.Marshal(&forwarded)
+	if err != nil {
+		return makeErrorResponse(err, INTERNAL_ERROR, nil, req.Id)
+	}
+
+	body, err := transport.RoundTrip(ctx, msg, req.Id == nil)
+	if err != nil {
+		return makeErrorResponse(errors.New(fmt.Sprintf("Plugin %s: %s", p.config.Name, err)), UPSTREAM_UNAVAILABLE, nil, req.Id)
+	}
+	if body == nil {
+		return Response{Jsonrpc: RPC_VERSION}
+	}
+
+	var res clientResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return makeErrorResponse(errors.New(fmt.Sprintf("Plugin %s sent an invalid response", p.config.Name)), INTERNAL_ERROR, nil, req.Id)
+	}
+	if res.Error != nil {
+		return Response{Jsonrpc: RPC_VERSION, Id: req.Id, Error: res.Error}
+	}
+
+	//Written as is, without decoding it
+	result := any(res.Result)
+	return Response{Jsonrpc: RPC_VERSION, Id: req.Id, Result: &result}
+}
+
+// Disconnect from the plugin and wait for it to exit, killing it after PLUGIN_STOP_TIMEOUT
+func (p *plugin) close() {
+	close(p.stop)
+
+	p.mu.RLock()
+	if p.transport != nil {
+		p.transport.Close()
+	}
+	p.mu.RUnlock()
+
+	select {
+	case <-p.stopped:
+	case <-time.After(PLUGIN_STOP_TIMEOUT):
+		p.mu.RLock()
+		p.cmd.Process.Kill()
+		p.mu.RUnlock()
+		<-p.stopped
+	}
+}
+
+func (c pipeConn) Close() error {
+	err := c.WriteCloser.Close()
+	if rerr := c.ReadCloser.Close(); err == nil {
+		err = rerr
+	}
+
+	return err
+}
+
+func (pipeConn) LocalAddr() net.Addr                { return pipeAddr{} }
+func (pipeConn) RemoteAddr() net.Addr               { return pipeAddr{} }
+func (pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (pipeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "stdio" }
@@ -0,0 +1,99 @@
+package jsonrpc2
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPluginEnv = "JSONRPC2_TEST_PLUGIN"
+
+type processService struct{}
+
+func (processService) Pid(ctx context.Context) (int, error, *RpcErrorCode) {
+	return os.Getpid(), nil, nil
+}
+
+func (processService) Exit(ctx context.Context) (int, error, *RpcErrorCode) {
+	os.Exit(1)
+	return 0, nil, nil
+}
+
+// Runs as the plugin process of the tests when started by newTestPluginHost
+func TestPluginProcess(t *testing.T) {
+	if os.Getenv(testPluginEnv) == "" {
+		return
+	}
+
+	rpc := newTestArithRpc()
+	rpc.RegisterWithName(processService{}, "Proc")
+	ServePlugin(rpc)
+	os.Exit(0)
+}
+
+// Host running the test binary as the plugin calc, over a socket when one is given
+func newTestPluginHost(t *testing.T, socket string) (*PluginHost, JsonRPC) {
+	host := NewPluginHost(log.New(io.Discard, "", 0))
+	t.Cleanup(func() { host.Close() })
+
+	err := host.Attach(PluginConfig{
+		Name:         "calc",
+		Command:      os.Args[0],
+		Args:         []string{"-test.run=^TestPluginProcess$"},
+		Env:          []string{testPluginEnv + "=1"},
+		Socket:       socket,
+		RestartDelay: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpc := NewJsonRpc(WithMiddleware(host.Middleware()))
+	rpc.RegisterWithName(arith{}, "Local")
+
+	return host, rpc
+}
+
+func TestPluginStdio(t *testing.T) {
+	_, rpc := newTestPluginHost(t, "")
+
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "calc.Arith.Add", []any{1, 2}).Result)
+	assert.Equal(t, any(float64(7)), *callMethod(t, rpc, "Local.Add", []any{3, 4}).Result)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "calc.Arith.Sub", []any{1, 2}).Error.Code)
+}
+
+func TestPluginSocket(t *testing.T) {
+	_, rpc := newTestPluginHost(t, filepath.Join(t.TempDir(), "calc.sock"))
+
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "calc.Arith.Add", []any{1, 2}).Result)
+}
+
+func TestPluginRestart(t *testing.T) {
+	host, rpc := newTestPluginHost(t, "")
+
+	pid := *callMethod(t, rpc, "calc.Proc.Pid", nil).Result
+
+	res := callMethod(t, rpc, "calc.Proc.Exit", nil)
+	assert.Equal(t, UPSTREAM_UNAVAILABLE, res.Error.Code)
+
+	assert.Eventually(t, func() bool {
+		return host.Running("calc")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.NotEqual(t, pid, *callMethod(t, rpc, "calc.Proc.Pid", nil).Result)
+}
+
+func TestPluginAttachErrors(t *testing.T) {
+	host := NewPluginHost(log.New(io.Discard, "", 0))
+	defer host.Close()
+
+	assert.Equal(t, "Plugin name must not be empty or contain a dot", host.Attach(PluginConfig{Name: "a.b"}).Error())
+	assert.Error(t, host.Attach(PluginConfig{Name: "missing", Command: filepath.Join(t.TempDir(), "missing")}))
+	assert.False(t, host.Running("missing"))
+}
@@ -0,0 +1,61 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+)
+
+// Buffers grown past this size are dropped instead of pooled, so one large request does not pin its memory
+const MAX_POOLED_BUFFER_SIZE = 1 << 20
+
+var (
+	//Buffers for reading requests and encoding responses
+	bufferPool = sync.Pool{
+		New: func() any {
+			return new(bytes.Buffer)
+		},
+	}
+
+	//Param slices of method calls
+	paramsPool = sync.Pool{
+		New: func() any {
+			params := make([]reflect.Value, 0, 4)
+			return &params
+		},
+	}
+)
+
+// Get an empty buffer. Return it with putBuffer once its bytes are no longer referenced
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > MAX_POOLED_BUFFER_SIZE {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// Get an empty slice holding at least n params. Return it with putParams once the method returned
+func getParams(n int) *[]reflect.Value {
+	params := paramsPool.Get().(*[]reflect.Value)
+	if cap(*params) < n {
+		*params = make([]reflect.Value, 0, n)
+	}
+
+	return params
+}
+
+func putParams(params *[]reflect.Value) {
+	//Drop the references to the params so they can be collected
+	for i := range *params {
+		(*params)[i] = reflect.Value{}
+	}
+	*params = (*params)[:0]
+
+	paramsPool.Put(params)
+}
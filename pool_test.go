@@ -0,0 +1,46 @@
+package jsonrpc2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutBufferResets(t *testing.T) {
+	buf := getBuffer()
+	buf.WriteString("request")
+	putBuffer(buf)
+
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestPutParamsClearsReferences(t *testing.T) {
+	params := getParams(2)
+	*params = append(*params, reflect.ValueOf("a"), reflect.ValueOf(1))
+	values := (*params)[:2]
+
+	putParams(params)
+
+	assert.Equal(t, 0, len(*params))
+	assert.False(t, values[0].IsValid())
+	assert.False(t, values[1].IsValid())
+}
+
+func TestGetParamsGrows(t *testing.T) {
+	params := getParams(16)
+	defer putParams(params)
+
+	assert.GreaterOrEqual(t, cap(*params), 16)
+	assert.Equal(t, 0, len(*params))
+}
+
+func TestPooledResponsesAreNotShared(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	first := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)
+	second := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"2","method":"Arith.Add","params":[3,4]}`)
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":"1","result":3}`, first)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":"2","result":7}`, second)
+}
@@ -0,0 +1,336 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	//Upstream is a JSON-RPC server the proxy forwards requests to
+	Upstream struct {
+		Name      string          //Name used in error messages
+		Transport ClientTransport //Transport the requests are forwarded over
+		Breaker   *CircuitBreaker //Skips the upstream for the methods whose breaker is open. Nil to always try it
+		unhealthy atomic.Bool
+	}
+
+	//Proxy forwards JSON-RPC requests to upstream servers picked by the prefix of the method name.
+	//eg. requests for eth.* go to one set of nodes and requests for btc.* to another.
+	Proxy struct {
+		mu       sync.RWMutex
+		routes   []proxyRoute
+		canaries []proxyCanary //Checked before the routes
+		stop     chan struct{}
+		closed   sync.Once
+	}
+
+	//Upstreams serving the methods matching a pattern. Upstreams are tried in order
+	proxyRoute struct {
+		prefix    string
+		exact     bool
+		upstreams []*Upstream
+	}
+
+	//Fields of a request the proxy routes on. The id is kept raw so any id type is passed through
+	proxyRequest struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"` //Only decoded by canaries with conditions on params
+	}
+
+	//Error response made by the proxy itself
+	proxyErrorResponse struct {
+		Jsonrpc string          `json:"jsonrpc"`
+		Id      json.RawMessage `json:"id"`
+		Error   *RpcError       `json:"error"`
+	}
+)
+
+// NewUpstream creates an upstream forwarding requests over the transport
+func NewUpstream(name string, transport ClientTransport) *Upstream {
+	return &Upstream{Name: name, Transport: transport}
+}
+
+// NewHTTPUpstream creates an upstream forwarding requests to the server at url over HTTP
+func NewHTTPUpstream(url string) *Upstream {
+	return NewUpstream(url, &HTTPTransport{URL: url})
+}
+
+// Healthy reports whether the last request or health check of the upstream succeeded
+func (u *Upstream) Healthy() bool {
+	return !u.unhealthy.Load()
+}
+
+// NewProxy creates a proxy without routes. Add them with Route
+func NewProxy() *Proxy {
+	return &Proxy{stop: make(chan struct{})}
+}
+
+// Route forwards the methods matching pattern to the upstreams. A pattern ending with * matches every method
+// starting with the rest of the pattern, eg. eth.*, and * alone matches every method. Other patterns match
+// a single method. The longest matching pattern wins.
+// Healthy upstreams are tried in order. The next upstream is tried when one can not be reached.
+func (p *Proxy) Route(pattern string, upstreams ...*Upstream) {
+	route := proxyRoute{prefix: strings.TrimSuffix(pattern, "*"), upstreams: upstreams}
+	route.exact = route.prefix == pattern
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.routes = append(p.routes, route)
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		return len(p.routes[i].prefix) > len(p.routes[j].prefix)
+	})
+}
+
+// StartHealthChecks calls method on every upstream at each interval. Upstreams that can not be reached are
+// skipped until a later check succeeds. Error responses count as healthy since the upstream answered.
+func (p *Proxy) StartHealthChecks(interval time.Duration, method string) {
+	msg, _ := json.Marshal(Request{Jsonrpc: RPC_VERSION, Id: new(string), Method: method, Params: []any{}})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+
+			for _, u := range p.upstreams() {
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				_, err := u.Transport.RoundTrip(ctx, msg, false)
+				cancel()
+
+				u.unhealthy.Store(err != nil)
+			}
+		}
+	}()
+}
+
+// Close stops the health checks and closes the transports of the upstreams
+func (p *Proxy) Close() error {
+	p.closed.Do(func() { close(p.stop) })
+
+	var errs []error
+	for _, u := range p.upstreams() {
+		if err := u.Transport.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Every upstream of every route, once
+func (p *Proxy) upstreams() []*Upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	seen := make(map[*Upstream]bool)
+	upstreams := make([]*Upstream, 0)
+	add := func(routed []*Upstream) {
+		for _, u := range routed {
+			if !seen[u] {
+				seen[u] = true
+				upstreams = append(upstreams, u)
+			}
+		}
+	}
+	for _, route := range p.routes {
+		add(route.upstreams)
+	}
+	for _, canary := range p.canaries {
+		add(canary.upstreams)
+	}
+
+	return upstreams
+}
+
+func (p *Proxy) match(method string) []*Upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, route := range p.routes {
+		if route.exact && method == route.prefix {
+			return route.upstreams
+		}
+		if !route.exact && strings.HasPrefix(method, route.prefix) {
+			return route.upstreams
+		}
+	}
+
+	return nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	//Forwarded requests carry the deadline on to the upstream
+	ctx, cancel, err := withTimeoutHeader(withHTTPRequest(r.Context(), r), r.Header)
+	defer cancel()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(makeProxyError(nil, err, INVALID_REQUEST))
+		return
+	}
+
+	res := p.HandleMessage(ctx, body)
+	if res == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// HandleMessage forwards a raw JSON-RPC message and returns the encoded response, or nil when there is
+// nothing to send back. Entries of a batch are forwarded concurrently, each to the upstream of its method,
+// and their responses are merged into a single batch response.
+func (p *Proxy) HandleMessage(ctx context.Context, body []byte) []byte {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return p.forward(ctx, body)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return makeProxyError(nil, errors.New("Unable to decode request"), PARSE_ERROR)
+	}
+	if len(batch) == 0 {
+		return makeProxyError(nil, errEmptyBatch, INVALID_REQUEST)
+	}
+
+	responses := make([]json.RawMessage, len(batch))
+	var wg sync.WaitGroup
+	for i, msg := range batch {
+		wg.Add(1)
+		go func(i int, msg json.RawMessage) {
+			defer wg.Done()
+			responses[i] = p.forward(ctx, msg)
+		}(i, msg)
+	}
+	wg.Wait()
+
+	merged := make([]json.RawMessage, 0, len(responses))
+	for _, res := range responses {
+		if res != nil {
+			merged = append(merged, res)
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+
+	r, _ := json.Marshal(merged)
+	return r
+}
+
+// Forward a single request to the first upstream of its route that can be reached
+func (p *Proxy) forward(ctx context.Context, msg []byte) []byte {
+	var req proxyRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return makeProxyError(nil, errors.New("Unable to decode request"), PARSE_ERROR)
+	}
+
+	notification := len(req.Id) == 0
+
+	upstreams := p.matchCanary(ctx, req)
+	if upstreams == nil {
+		upstreams = p.match(req.Method)
+	}
+	if len(upstreams) == 0 {
+		if notification {
+			return nil
+		}
+		return makeProxyError(req.Id, errors.New(fmt.Sprintf("No upstream serves method %s", req.Method)), METHOD_NOT_FOUND)
+	}
+
+	var errs []string
+	for _, u := range failoverOrder(upstreams) {
+		done, ok := u.Breaker.allow(req.Method)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: circuit breaker open", u.Name))
+			continue
+		}
+
+		start := time.Now()
+		res, err := u.Transport.RoundTrip(ctx, msg, notification)
+		done(ctx, start, err != nil || !notification && failedResponse(res))
+		if err == nil {
+			u.unhealthy.Store(false)
+			if !notification && len(res) == 0 {
+				//Eg. a 204 to a call. Answered so the call is not missing from the batch response
+				return makeProxyError(req.Id, errors.New("Upstream returned no response"), INTERNAL_ERROR)
+			}
+			return res
+		}
+		if ctx.Err() != nil {
+			//The caller went away or its deadline passed, the upstream is not to blame
+			if notification {
+				return nil
+			}
+			return makeProxyError(req.Id, ctx.Err(), UPSTREAM_UNAVAILABLE)
+		}
+
+		u.unhealthy.Store(true)
+		errs = append(errs, fmt.Sprintf("%s: %s", u.Name, err))
+	}
+
+	if notification {
+		return nil
+	}
+
+	err := errors.New(fmt.Sprintf("Upstream unavailable. %s", strings.Join(errs, "; ")))
+	return makeProxyError(req.Id, err, UPSTREAM_UNAVAILABLE)
+}
+
+// Healthy upstreams first, keeping the configured order. Unhealthy upstreams are tried last
+// in case they recovered since they were last checked.
+func failoverOrder(upstreams []*Upstream) []*Upstream {
+	ordered := make([]*Upstream, 0, len(upstreams))
+	for _, u := range upstreams {
+		if u.Healthy() {
+			ordered = append(ordered, u)
+		}
+	}
+	for _, u := range upstreams {
+		if !u.Healthy() {
+			ordered = append(ordered, u)
+		}
+	}
+
+	return ordered
+}
+
+func makeProxyError(id json.RawMessage, err error, code RpcErrorCode) []byte {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	r, _ := json.Marshal(proxyErrorResponse{
+		Jsonrpc: RPC_VERSION,
+		Id:      id,
+		Error:   &RpcError{Code: code, Message: err.Error()},
+	})
+
+	return r
+}
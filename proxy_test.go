@@ -0,0 +1,115 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestProxy(t *testing.T) (*Proxy, *Upstream) {
+	dead := NewHTTPUpstream("http://127.0.0.1:1")
+
+	arithRpc := newTestArithRpc()
+	encRpc := NewJsonRpc()
+	encRpc.RegisterWithName(encodingService{}, "Enc")
+
+	proxy := NewProxy()
+	proxy.Route("Arith.*", dead, NewHTTPUpstream(newTestHTTPServer(t, arithRpc)))
+	proxy.Route("Enc.Echo", NewHTTPUpstream(newTestHTTPServer(t, encRpc)))
+	t.Cleanup(func() { proxy.Close() })
+
+	return proxy, dead
+}
+
+func TestProxyRoutesByPrefix(t *testing.T) {
+	proxy, _ := newTestProxy(t)
+	client := NewHTTPClient(newTestHTTPServer(t, proxy))
+
+	var sum int
+	assert.NoError(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &sum))
+	assert.Equal(t, 3, sum)
+
+	var echo string
+	assert.NoError(t, client.Call(context.Background(), "Enc.Echo", []any{"hi"}, &echo))
+	assert.Equal(t, "hi", echo)
+
+	err := client.Call(context.Background(), "Enc.Next", []any{1}, nil)
+	assert.Equal(t, METHOD_NOT_FOUND, err.(*RpcError).Code)
+}
+
+func TestProxyFailover(t *testing.T) {
+	proxy, dead := newTestProxy(t)
+
+	res := proxy.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`))
+
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":3}`, string(res))
+	assert.False(t, dead.Healthy())
+}
+
+func TestProxyMergesBatches(t *testing.T) {
+	proxy, _ := newTestProxy(t)
+
+	res := proxy.HandleMessage(context.Background(), []byte(`[
+		{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]},
+		{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]},
+		{"jsonrpc":"2.0","id":"2","method":"Enc.Echo","params":["hi"]}
+	]`))
+
+	var responses []map[string]any
+	if err := json.Unmarshal(res, &responses); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, responses, 2)
+	assert.Equal(t, float64(3), responses[0]["result"])
+	assert.Equal(t, "hi", responses[1]["result"])
+}
+
+func TestProxyUpstreamUnavailable(t *testing.T) {
+	proxy := NewProxy()
+	proxy.Route("*", NewHTTPUpstream("http://127.0.0.1:1"))
+
+	res := &Response{}
+	json.Unmarshal(proxy.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`)), res)
+
+	assert.Equal(t, UPSTREAM_UNAVAILABLE, res.Error.Code)
+}
+
+func TestProxyUpstreamNoContent(t *testing.T) {
+	proxy := NewProxy()
+	proxy.Route("*", NewHTTPUpstream(newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))))
+
+	res := proxy.HandleMessage(context.Background(), []byte(`[
+		{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]},
+		{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]}
+	]`))
+
+	var responses []Response
+	if err := json.Unmarshal(res, &responses); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, responses, 1)
+	assert.Equal(t, "1", *responses[0].Id)
+	assert.Equal(t, INTERNAL_ERROR, responses[0].Error.Code)
+	assert.Equal(t, "Upstream returned no response", responses[0].Error.Message)
+}
+
+func TestProxyHealthChecks(t *testing.T) {
+	upstream := NewHTTPUpstream(newTestHTTPServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+
+	proxy := NewProxy()
+	proxy.Route("*", upstream)
+	defer proxy.Close()
+
+	proxy.StartHealthChecks(10*time.Millisecond, "rpc.listMethods")
+	assert.Eventually(t, func() bool { return !upstream.Healthy() }, time.Second, 10*time.Millisecond)
+}
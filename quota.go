@@ -0,0 +1,284 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+)
+
+type (
+	//QuotaStore keeps the usage counters of quotas, eg. in Redis so servers share them. Counters are named after the
+	//client and the period they count
+	QuotaStore interface {
+		//Add delta to the counter and return its new value. The counter can be dropped once it expires
+		Increment(ctx context.Context, counter string, delta int64, expires time.Time) (int64, error)
+
+		//Value of the counter. Zero when it does not exist or expired
+		Get(ctx context.Context, counter string) (int64, error)
+	}
+
+	//QuotaOptions set how many calls each client makes per UTC day and month
+	QuotaOptions struct {
+		//Client the call is counted for, eg. auth.QuotaKey for the authenticated principal. Calls with an empty key
+		//are not counted. Defaults to the host of the remote address
+		Key func(ctx context.Context, req *Request) string
+
+		Daily   int64 //Calls per key and day. Zero means no daily limit, calls are still counted
+		Monthly int64 //Calls per key and month. Zero means no monthly limit, calls are still counted
+
+		//Limits of the key overriding Daily and Monthly, eg. by the plan of the client. Optional
+		Limits func(ctx context.Context, key string) (daily, monthly int64)
+
+		//Defaults to NewMemoryQuotaStore
+		Store QuotaStore
+	}
+
+	//QuotaUsage is the usage of a client, returned by rpc.usage and in the data of QUOTA_EXCEEDED errors
+	QuotaUsage struct {
+		Key     string       `json:"key"`
+		Daily   QuotaCounter `json:"daily"`
+		Monthly QuotaCounter `json:"monthly"`
+	}
+
+	//QuotaCounter is the usage of a client over a period
+	QuotaCounter struct {
+		Used   int64     `json:"used"`
+		Limit  int64     `json:"limit"` //Zero when unlimited
+		Resets time.Time `json:"resets"`
+	}
+
+	//Period calls are counted over
+	quotaPeriod struct {
+		name  string
+		start func(now time.Time) time.Time
+		next  func(start time.Time) time.Time
+	}
+
+	memoryQuotaStore struct {
+		mu        sync.Mutex
+		counters  map[string]memoryCounter
+		lastSweep int //Counters after the last sweep of expired counters
+	}
+
+	memoryCounter struct {
+		value   int64
+		expires time.Time
+	}
+)
+
+var (
+	quotaDay = quotaPeriod{
+		name: "Daily",
+		start: func(now time.Time) time.Time {
+			return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		},
+		next: func(start time.Time) time.Time { return start.AddDate(0, 0, 1) },
+	}
+	quotaMonth = quotaPeriod{
+		name:  "Monthly",
+		start: func(now time.Time) time.Time { return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC) },
+		next:  func(start time.Time) time.Time { return start.AddDate(0, 1, 0) },
+	}
+
+	//Periods of QuotaUsage counters, in order
+	quotaPeriods = []quotaPeriod{quotaDay, quotaMonth}
+)
+
+// WithQuotas counts the calls of each client per UTC day and month and fails the calls beyond its limits with a
+// QUOTA_EXCEEDED error, whose data is the QuotaUsage of the client. Calls are counted after the middleware, so
+// authentication middleware sets the principal first, and rejected calls are not counted. Built-in methods are
+// neither counted nor limited. Clients read their usage with rpc.usage. When the store fails the call is let
+// through and the error logged.
+func WithQuotas(opts QuotaOptions) Option {
+	if opts.Key == nil {
+		opts.Key = remoteHostKey
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryQuotaStore()
+	}
+
+	return func(c *config) {
+		c.quotas = &opts
+	}
+}
+
+// NewMemoryQuotaStore returns a QuotaStore keeping the counters in memory. Usage is lost when the server restarts
+// and is not shared between servers.
+func NewMemoryQuotaStore() QuotaStore {
+	return &memoryQuotaStore{counters: make(map[string]memoryCounter)}
+}
+
+// Count the call against the quotas of its client. A response is returned when a quota is exceeded
+func (c *config) chargeQuota(ctx context.Context, req *Request) (Response, bool) {
+	q := c.quotas
+	if q == nil || isBuiltinMethod(req.Method) {
+		return Response{}, false
+	}
+	key := q.Key(ctx, req)
+	if key == "" {
+		return Response{}, false
+	}
+
+	now := time.Now()
+	limits := q.limits(ctx, key)
+	usage := QuotaUsage{Key: key}
+	counters := usage.counters()
+
+	var exceeded error
+	for i, period := range quotaPeriods {
+		start := period.start(now)
+		used, err := q.Store.Increment(ctx, quotaCounterName(key, period, start), 1, period.next(start))
+		if err != nil {
+			c.logger.Printf("Counting the calls of %s failed: %v", key, err)
+			q.refund(ctx, key, quotaPeriods[:i], now)
+			return Response{}, false
+		}
+
+		*counters[i] = QuotaCounter{Used: used, Limit: limits[i], Resets: period.next(start)}
+		if limits[i] > 0 && used > limits[i] && exceeded == nil {
+			exceeded = errors.New(fmt.Sprintf("%s quota of %d calls exceeded", period.name, limits[i]))
+		}
+	}
+	if exceeded == nil {
+		return Response{}, false
+	}
+
+	q.refund(ctx, key, quotaPeriods, now)
+	for _, counter := range counters {
+		counter.Used--
+	}
+
+	var data any = usage
+	return makeErrorResponse(exceeded, QUOTA_EXCEEDED, &data, req.Id), true
+}
+
+// Usage of the key in the current periods
+func (q *QuotaOptions) usage(ctx context.Context, key string) (QuotaUsage, error) {
+	now := time.Now()
+	limits := q.limits(ctx, key)
+	usage := QuotaUsage{Key: key}
+
+	for i, counter := range usage.counters() {
+		period := quotaPeriods[i]
+		start := period.start(now)
+		used, err := q.Store.Get(ctx, quotaCounterName(key, period, start))
+		if err != nil {
+			return QuotaUsage{}, err
+		}
+		*counter = QuotaCounter{Used: used, Limit: limits[i], Resets: period.next(start)}
+	}
+
+	return usage, nil
+}
+
+// Daily and monthly limits of the key
+func (q *QuotaOptions) limits(ctx context.Context, key string) [2]int64 {
+	if q.Limits != nil {
+		daily, monthly := q.Limits(ctx, key)
+		return [2]int64{daily, monthly}
+	}
+
+	return [2]int64{q.Daily, q.Monthly}
+}
+
+// Take back a call counted in the periods
+func (q *QuotaOptions) refund(ctx context.Context, key string, periods []quotaPeriod, now time.Time) {
+	for _, period := range periods {
+		start := period.start(now)
+		q.Store.Increment(ctx, quotaCounterName(key, period, start), -1, period.next(start))
+	}
+}
+
+// Counters of the usage, in the order of quotaPeriods
+func (u *QuotaUsage) counters() []*QuotaCounter {
+	return []*QuotaCounter{&u.Daily, &u.Monthly}
+}
+
+func quotaCounterName(key string, period quotaPeriod, start time.Time) string {
+	if period.name == quotaMonth.name {
+		return key + "/month/" + start.Format("2006-01")
+	}
+
+	return key + "/day/" + start.Format("2006-01-02")
+}
+
+// Host of the remote address of the request, the default key of quotas
+func remoteHostKey(ctx context.Context, req *Request) string {
+	addr := RemoteAddrFromContext(ctx)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
+
+// Register rpc.usage, returning the usage of the caller, when quotas are enabled
+func (rpc *jsonRpcImpl) registerUsageMethod(builtins *service) {
+	if rpc.config.quotas == nil {
+		return
+	}
+
+	builtins.methods["usage"] = reflect.ValueOf(func(ctx context.Context) (*QuotaUsage, error, *RpcErrorCode) {
+		q := rpc.cfg().quotas
+		if q == nil {
+			code := METHOD_NOT_FOUND
+			return nil, errors.New("Quotas are disabled"), &code
+		}
+
+		key := q.Key(ctx, &Request{Jsonrpc: RPC_VERSION, Method: BUILTIN_SERVICE_NAME + ".usage"})
+		if key == "" {
+			code := UNAUTHORIZED
+			return nil, errors.New("The caller is not identified"), &code
+		}
+
+		usage, err := q.usage(ctx, key)
+		if err != nil {
+			code := INTERNAL_ERROR
+			return nil, err, &code
+		}
+
+		return &usage, nil, nil
+	})
+}
+
+func (s *memoryQuotaStore) Increment(ctx context.Context, counter string, delta int64, expires time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	c, ok := s.counters[counter]
+	if !ok || !now.Before(c.expires) {
+		c = memoryCounter{}
+	}
+	c.value += delta
+	c.expires = expires
+	s.counters[counter] = c
+
+	//Drop the counters of past periods once they may make up half of the map
+	if len(s.counters) > 2*s.lastSweep {
+		for name, c := range s.counters {
+			if !now.Before(c.expires) {
+				delete(s.counters, name)
+			}
+		}
+		s.lastSweep = len(s.counters)
+	}
+
+	return c.value, nil
+}
+
+func (s *memoryQuotaStore) Get(ctx context.Context, counter string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[counter]
+	if !ok || !time.Now().Before(c.expires) {
+		return 0, nil
+	}
+
+	return c.value, nil
+}
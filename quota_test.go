@@ -0,0 +1,123 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type brokenQuotaStore struct{}
+
+func (brokenQuotaStore) Increment(ctx context.Context, counter string, delta int64, expires time.Time) (int64, error) {
+	return 0, errors.New("Store is down")
+}
+
+func (brokenQuotaStore) Get(ctx context.Context, counter string) (int64, error) {
+	return 0, errors.New("Store is down")
+}
+
+func quotaUsageOf(t *testing.T, v any) QuotaUsage {
+	var usage QuotaUsage
+	raw, _ := json.Marshal(v)
+	assert.Nil(t, json.Unmarshal(raw, &usage))
+
+	return usage
+}
+
+func TestWithQuotas(t *testing.T) {
+	caller := "client-a"
+	rpc := NewJsonRpc(WithQuotas(QuotaOptions{
+		Key:   func(ctx context.Context, req *Request) string { return caller },
+		Daily: 2,
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	for i := 0; i < 2; i++ {
+		res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+		assert.Nil(t, res.Error)
+	}
+
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Equal(t, QUOTA_EXCEEDED, res.Error.Code)
+	assert.Equal(t, "Daily quota of 2 calls exceeded", res.Error.Message)
+	usage := quotaUsageOf(t, res.Error.Data)
+	assert.Equal(t, "client-a", usage.Key)
+	assert.Equal(t, int64(2), usage.Daily.Used)
+	assert.Equal(t, int64(2), usage.Daily.Limit)
+	assert.Equal(t, int64(2), usage.Monthly.Used)
+	assert.Equal(t, int64(0), usage.Monthly.Limit)
+
+	//Rejected calls and built-in methods are not counted
+	res = callMethod(t, rpc, "rpc.usage", nil)
+	assert.Nil(t, res.Error)
+	usage = quotaUsageOf(t, *res.Result)
+	assert.Equal(t, int64(2), usage.Daily.Used)
+	now := time.Now().UTC()
+	assert.Equal(t, time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC), usage.Daily.Resets)
+
+	//Other clients have their own counters
+	caller = "client-b"
+	res = callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Nil(t, res.Error)
+
+	//Calls without a key are not counted
+	caller = ""
+	res = callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Nil(t, res.Error)
+	res = callMethod(t, rpc, "rpc.usage", nil)
+	assert.Equal(t, UNAUTHORIZED, res.Error.Code)
+}
+
+func TestQuotaLimits(t *testing.T) {
+	rpc := NewJsonRpc(WithQuotas(QuotaOptions{
+		Key:   func(ctx context.Context, req *Request) string { return "client-a" },
+		Daily: 100,
+		Limits: func(ctx context.Context, key string) (int64, int64) {
+			return 0, 1
+		},
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	assert.Nil(t, callMethod(t, rpc, "Arith.Add", []any{1, 2}).Error)
+
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Equal(t, QUOTA_EXCEEDED, res.Error.Code)
+	assert.Equal(t, "Monthly quota of 1 calls exceeded", res.Error.Message)
+}
+
+func TestQuotaStoreFailure(t *testing.T) {
+	rpc := NewJsonRpc(WithQuotas(QuotaOptions{
+		Key:   func(ctx context.Context, req *Request) string { return "client-a" },
+		Daily: 1,
+		Store: brokenQuotaStore{},
+	}))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, callMethod(t, rpc, "Arith.Add", []any{1, 2}).Error)
+	}
+
+	res := callMethod(t, rpc, "rpc.usage", nil)
+	assert.Equal(t, INTERNAL_ERROR, res.Error.Code)
+}
+
+func TestMemoryQuotaStore(t *testing.T) {
+	store := NewMemoryQuotaStore()
+	ctx := context.Background()
+
+	value, _ := store.Increment(ctx, "a", 1, time.Now().Add(time.Hour))
+	assert.Equal(t, int64(1), value)
+	value, _ = store.Increment(ctx, "a", 2, time.Now().Add(time.Hour))
+	assert.Equal(t, int64(3), value)
+
+	//Expired counters start over
+	store.Increment(ctx, "b", 5, time.Now().Add(-time.Second))
+	value, _ = store.Get(ctx, "b")
+	assert.Equal(t, int64(0), value)
+	value, _ = store.Increment(ctx, "b", 1, time.Now().Add(time.Hour))
+	assert.Equal(t, int64(1), value)
+}
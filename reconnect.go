@@ -0,0 +1,73 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	//Wait before the first attempt to reconnect. It doubles after each failed attempt
+	CLIENT_RECONNECT_MIN_DELAY = 100 * time.Millisecond
+	//Longest wait between two attempts to reconnect
+	CLIENT_RECONNECT_MAX_DELAY = 10 * time.Second
+)
+
+// NewReconnectingClient creates a client calling the server over a persistent connection made by dial, eg. with
+// net.Dial or DialTLS. Once the connection fails, dial is called again with a growing delay until it succeeds.
+// Calls in flight when the connection fails return an error. Subscriptions are made again after reconnecting.
+// Calls made while disconnected fail too, unless the client holds them WithOfflineQueue.
+func NewReconnectingClient(ctx context.Context, dial func(ctx context.Context) (net.Conn, error), opts ...ClientOption) (*Client, error) {
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := newConnTransport(conn)
+	transport.dial = dial
+
+	return NewClient(transport, opts...), nil
+}
+
+// Dial until a connection is made or the transport is closed
+func (t *connTransport) reconnect() {
+	delay := CLIENT_RECONNECT_MIN_DELAY
+	for {
+		select {
+		case <-t.closed:
+			return
+		case <-time.After(delay):
+		}
+
+		conn, err := t.dial(context.Background())
+		if err != nil {
+			delay *= 2
+			if delay > CLIENT_RECONNECT_MAX_DELAY {
+				delay = CLIENT_RECONNECT_MAX_DELAY
+			}
+			continue
+		}
+
+		t.mu.Lock()
+		select {
+		case <-t.closed:
+			t.mu.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		t.conn = conn
+		t.done = make(chan struct{})
+		done := t.done
+		onReconnect := t.onReconnect
+		t.mu.Unlock()
+
+		go t.readLoop(conn, done)
+		t.flushOffline(done)
+		if onReconnect != nil {
+			onReconnect()
+		}
+
+		return
+	}
+}
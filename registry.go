@@ -0,0 +1,166 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+type (
+	//ServiceRegistry resolves services that are not registered on the server when they are first called, eg. from
+	//a plugin system, a scripting engine or a remote catalog
+	ServiceRegistry interface {
+		//Resolve returns the service named name, a value whose methods follow the procedure signature like those
+		//passed to Register, and the options to register it with. The name of the options is ignored.
+		//A nil service means the registry has no service by that name
+		Resolve(ctx context.Context, name string) (srv any, opts ServiceOptions, err error)
+
+		//Services returns the names of the services the registry resolves, for introspection
+		Services(ctx context.Context) ([]string, error)
+	}
+
+	//Registry whose services are called under a namespace
+	mountedRegistry struct {
+		namespace string
+		registry  ServiceRegistry
+	}
+)
+
+// WithServiceRegistry resolves the services that are not registered on the server with registry. Its services are
+// called under the namespace, eg. plugins.Greeter.Hello for the Greeter service in the plugins namespace, or by their
+// own name when the namespace is empty. Registries are asked in the order they were added.
+// A resolved service is registered on the server, so the registry is asked once per service.
+func WithServiceRegistry(namespace string, registry ServiceRegistry) Option {
+	return func(c *config) {
+		c.registries = append(c.registries, mountedRegistry{namespace: namespace, registry: registry})
+	}
+}
+
+// Name of the service in the registry, when the registry serves it
+func (m mountedRegistry) localName(name string) (string, bool) {
+	if m.namespace == "" {
+		return name, name != ROOT_SERVICE_NAME
+	}
+
+	local, ok := strings.CutPrefix(name, m.namespace+".")
+	return local, ok && local != ""
+}
+
+// Name the service of the registry is called by
+func (m mountedRegistry) fullName(local string) string {
+	if m.namespace == "" {
+		return local
+	}
+
+	return m.namespace + "." + local
+}
+
+// Current snapshot of the registered services. It must not be modified
+func (rpc *jsonRpcImpl) serviceSnapshot() serviceMap {
+	if services := rpc.services.Load(); services != nil {
+		return *services
+	}
+
+	return nil
+}
+
+// Publish a copy of the registered services changed by update. Calls keep dispatching on the previous snapshot
+// meanwhile, so changes never wait for calls nor calls for changes
+func (rpc *jsonRpcImpl) updateServices(update func(services serviceMap)) {
+	rpc.servicesMu.Lock()
+	defer rpc.servicesMu.Unlock()
+
+	current := rpc.serviceSnapshot()
+	next := make(serviceMap, len(current)+1)
+	for name, srv := range current {
+		next[name] = srv
+	}
+	update(next)
+
+	rpc.services.Store(&next)
+}
+
+// Service registered on the server under name
+func (rpc *jsonRpcImpl) lookupService(name string) (*service, bool) {
+	srv, ok := rpc.serviceSnapshot()[name]
+	return srv, ok
+}
+
+// Registered services sorted by name, excluding the built-in one
+func (rpc *jsonRpcImpl) registeredServices() []*service {
+	snapshot := rpc.serviceSnapshot()
+
+	services := make([]*service, 0, len(snapshot))
+	for name, srv := range snapshot {
+		if name != BUILTIN_SERVICE_NAME {
+			services = append(services, srv)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].name < services[j].name
+	})
+
+	return services
+}
+
+// Service registered under name, else resolved by the registries and registered. Nil when none has it
+func (rpc *jsonRpcImpl) resolveService(ctx context.Context, name string) (*service, error) {
+	if srv, ok := rpc.lookupService(name); ok {
+		return srv, nil
+	}
+
+	if isReservedServiceName(name) {
+		return nil, nil
+	}
+
+	for _, mounted := range rpc.cfg().registries {
+		local, ok := mounted.localName(name)
+		if !ok {
+			continue
+		}
+
+		srv, opts, err := mounted.registry.Resolve(ctx, local)
+		if err != nil {
+			return nil, err
+		}
+		if srv == nil {
+			continue
+		}
+
+		resolved, err := rpc.newService(srv, name, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		rpc.updateServices(func(services serviceMap) {
+			//Another call may have resolved it meanwhile
+			if existing, ok := services[name]; ok {
+				resolved = existing
+				return
+			}
+			services[name] = resolved
+		})
+
+		return resolved, nil
+	}
+
+	return nil, nil
+}
+
+// Resolve every service of the registries, so they are listed by introspection
+func (rpc *jsonRpcImpl) resolveAllServices(ctx context.Context) error {
+	for _, mounted := range rpc.cfg().registries {
+		names, err := mounted.registry.Services(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if _, err := rpc.resolveService(ctx, mounted.fullName(name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
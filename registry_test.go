@@ -0,0 +1,180 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Registry resolving services from a map, counting how often each is resolved
+type memoryRegistry struct {
+	mu       sync.Mutex
+	services map[string]any
+	resolved map[string]int
+	err      error
+}
+
+func newMemoryRegistry(services map[string]any) *memoryRegistry {
+	return &memoryRegistry{services: services, resolved: make(map[string]int)}
+}
+
+func (r *memoryRegistry) Resolve(ctx context.Context, name string) (any, ServiceOptions, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, ServiceOptions{}, r.err
+	}
+
+	srv, ok := r.services[name]
+	if !ok {
+		return nil, ServiceOptions{}, nil
+	}
+	r.resolved[name]++
+
+	return srv, ServiceOptions{Docs: map[string]MethodDoc{"Add": {Description: "Adds two numbers"}}}, nil
+}
+
+func (r *memoryRegistry) Services(ctx context.Context) ([]string, error) {
+	names := make([]string, 0, len(r.services))
+	for name := range r.services {
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func TestServiceRegistry(t *testing.T) {
+	registry := newMemoryRegistry(map[string]any{"Arith": arith{}})
+	rpc := NewJsonRpc(WithServiceRegistry("", registry))
+
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "Arith.Add", []any{1, 2}).Result)
+	assert.Equal(t, any(float64(7)), *callMethod(t, rpc, "Arith.Add", []any{3, 4}).Result)
+
+	assert.Equal(t, 1, registry.resolved["Arith"])
+}
+
+func TestServiceRegistryNotFound(t *testing.T) {
+	rpc := NewJsonRpc(WithServiceRegistry("", newMemoryRegistry(map[string]any{})))
+
+	res := callMethod(t, rpc, "Missing.Add", []any{1, 2})
+
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+	assert.Equal(t, "Service Missing is not registered", res.Error.Message)
+}
+
+func TestServiceRegistryNamespace(t *testing.T) {
+	plugins := newMemoryRegistry(map[string]any{"Arith": arith{}})
+	rpc := NewJsonRpc(WithServiceRegistry("plugins", plugins))
+
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "plugins.Arith.Add", []any{1, 2}).Result)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Arith.Add", []any{1, 2}).Error.Code)
+}
+
+func TestServiceRegistryRegisteredFirst(t *testing.T) {
+	registry := newMemoryRegistry(map[string]any{"Arith": arith{}})
+	rpc := NewJsonRpc(WithServiceRegistry("", registry))
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	callMethod(t, rpc, "Arith.Add", []any{1, 2})
+
+	assert.Equal(t, 0, registry.resolved["Arith"])
+}
+
+func TestServiceRegistryError(t *testing.T) {
+	registry := newMemoryRegistry(map[string]any{})
+	registry.err = errors.New("Catalog unavailable")
+	rpc := NewJsonRpc(WithServiceRegistry("", registry))
+
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+
+	assert.Equal(t, INTERNAL_ERROR, res.Error.Code)
+	assert.Equal(t, "Catalog unavailable", res.Error.Message)
+}
+
+func TestServiceRegistryIntrospection(t *testing.T) {
+	rpc := NewJsonRpc(WithServiceRegistry("plugins", newMemoryRegistry(map[string]any{"Arith": arith{}})))
+
+	res := callMethod(t, rpc, "rpc.listMethods", nil)
+
+	assert.Equal(t, any([]any{"plugins.Arith.Add", "plugins.Arith.ErrorMethod"}), *res.Result)
+
+	services, _, _ := introspection{rpc: rpc.(*jsonRpcImpl)}.Describe(context.Background(), "plugins.Arith")
+	assert.Equal(t, "Adds two numbers", services[0].Methods[0].Description)
+}
+
+func TestServiceRegistryBuiltinReserved(t *testing.T) {
+	rpc := NewJsonRpc(WithServiceRegistry("", newMemoryRegistry(map[string]any{"rpc.x": arith{}})))
+
+	res := callMethod(t, rpc, "rpc.x.Add", []any{1, 2})
+
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+}
+
+func TestUnregister(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	assert.True(t, rpc.Unregister("Arith"))
+	assert.False(t, rpc.Unregister("Arith"))
+	assert.False(t, rpc.Unregister(BUILTIN_SERVICE_NAME))
+
+	res := callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+}
+
+func TestUnregisterEmbeddedServices(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Nil(t, rpc.RegisterWithOptions(&storefront{Catalog: &Catalog{}}, ServiceOptions{Name: "Store", NamespaceEmbedded: true}))
+	assert.Nil(t, callMethod(t, rpc, "Store.Orders.Place", []any{"pen"}).Error)
+
+	assert.True(t, rpc.Unregister("Store"))
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Store.Orders.Place", []any{"pen"}).Error.Code)
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Store.Catalog.Add", []any{"pen"}).Error.Code)
+
+	//Services of embedded values dropped by a replacement go away too
+	assert.Nil(t, rpc.RegisterWithOptions(&storefront{Catalog: &Catalog{}}, ServiceOptions{Name: "Store", NamespaceEmbedded: true}))
+	assert.Nil(t, rpc.RegisterWithName(&storefront{Catalog: &Catalog{}}, "Store"))
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "Store.Orders.Place", []any{"pen"}).Error.Code)
+	assert.Nil(t, callMethod(t, rpc, "Store.Place", []any{"pen"}).Error)
+}
+
+func TestRegisterWhileDispatching(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			rpc.RegisterWithName(arith{}, "Other")
+			Handle(rpc, fmt.Sprintf("Funcs.Echo%d", i%8), func(ctx context.Context, value string) (string, error) {
+				return value, nil
+			})
+			rpc.Unregister("Other")
+
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	id := "1"
+	for i := 0; i < 200; i++ {
+		res := rpc.Dispatch(context.Background(), Request{Id: &id, Method: "Arith.Add", Params: []any{1, 2}, Jsonrpc: RPC_VERSION})
+		assert.Nil(t, res.Error)
+		assert.Equal(t, any(3), *res.Result)
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, any("hi"), *rpc.Dispatch(context.Background(), Request{Id: &id, Method: "Funcs.Echo0", Params: []any{"hi"}, Jsonrpc: RPC_VERSION}).Result)
+}
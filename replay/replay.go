@@ -0,0 +1,281 @@
+// Package replay sends recorded requests, eg. the audit trail of a jsonrpc2 server, to a server again and reports
+// the responses that differ from the recorded ones or from a baseline server. Use it to check that a new version of
+// a service answers production traffic like the previous one.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+)
+
+// Size of the largest line read from records, in bytes
+const MAX_RECORD_SIZE = 16 << 20
+
+type (
+	//Record is a request to replay with the outcome it had when it was recorded
+	Record struct {
+		Time         time.Time             `json:"time"` //Zero when unknown. Used to keep the pace of the original traffic
+		Method       string                `json:"method"`
+		Params       any                   `json:"params"`
+		Notification bool                  `json:"-"`
+		Code         jsonrpc2.RpcErrorCode `json:"code,omitempty"`   //Code of the recorded error. Zero for successful calls
+		Result       json.RawMessage       `json:"result,omitempty"` //Recorded result. Nil when only the outcome is known
+	}
+
+	//Options of Replay
+	Options struct {
+		//Pace relative to the recorded times. 1 keeps the original pace and 2 is twice as fast.
+		//Zero sends the requests one after the other as fast as possible
+		Speed float64
+
+		//Server the requests are sent to as well, whose responses are expected instead of the recorded ones.
+		//Nil compares with the records
+		Baseline *jsonrpc2.Client
+
+		//Dotted paths of the result fields left out of the comparison, eg. timestamps or generated ids
+		Ignore []string
+	}
+
+	//Diff is a request whose response differs from the expected one
+	Diff struct {
+		Record   Record
+		Expected string //Expected outcome, as JSON or as the error code
+		Actual   string
+	}
+
+	//Report is the outcome of a replay
+	Report struct {
+		Sent    int    //Requests sent, including notifications
+		Matched int    //Responses equal to the expected ones
+		Diffs   []Diff //Responses that differ, in the order of the records
+	}
+
+	//Outcome of a call: its result or the code of its error
+	outcome struct {
+		code   jsonrpc2.RpcErrorCode
+		result any
+	}
+
+	//Line of a record file. Audit records and JSON-RPC requests are both accepted
+	recordLine struct {
+		Time      time.Time             `json:"time"`
+		Method    string                `json:"method"`
+		Params    any                   `json:"params"`
+		Id        json.RawMessage       `json:"id"`
+		RequestId *string               `json:"requestId"`
+		Code      jsonrpc2.RpcErrorCode `json:"code"`
+		Result    json.RawMessage       `json:"result"`
+		Error     *struct {
+			Code jsonrpc2.RpcErrorCode `json:"code"`
+		} `json:"error"`
+	}
+)
+
+// ReadRecords reads records written as JSON lines. Lines are audit records written by jsonrpc2.NewWriterAuditSink,
+// or JSON-RPC requests, optionally with the "result" or "error" they were answered with. Requests without id and
+// audit records without requestId are notifications. Empty lines are skipped.
+// Params redacted in audit records are sent as jsonrpc2.REDACTED.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_RECORD_SIZE)
+
+	records := make([]Record, 0)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var l recordLine
+		if err := json.Unmarshal(line, &l); err != nil || l.Method == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid record on line %d", n))
+		}
+
+		record := Record{Time: l.Time, Method: l.Method, Params: l.Params, Code: l.Code, Result: l.Result}
+		record.Notification = (l.RequestId == nil || *l.RequestId == "") && (len(l.Id) == 0 || string(l.Id) == "null")
+		if l.Error != nil {
+			record.Code = l.Error.Code
+		}
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// Replay sends the records to the target in order and compares its responses with the baseline server, when set, or
+// with the recorded outcomes. Records only knowing whether the call failed, like audit records, match when the target
+// answers with the same error code, or successfully. Notifications are sent without comparison.
+// Replay stops when the context is done, or a request can not be sent, and returns the report so far.
+func Replay(ctx context.Context, records []Record, target *jsonrpc2.Client, opts Options) (Report, error) {
+	report := Report{Diffs: make([]Diff, 0)}
+
+	ignore := make([][]string, 0, len(opts.Ignore))
+	for _, path := range opts.Ignore {
+		ignore = append(ignore, strings.Split(path, "."))
+	}
+
+	start := time.Now()
+	var first time.Time
+	for _, record := range records {
+		if err := wait(ctx, record, start, &first, opts.Speed); err != nil {
+			return report, err
+		}
+
+		if record.Notification {
+			if err := target.Notify(ctx, record.Method, record.Params); err != nil {
+				return report, err
+			}
+			report.Sent++
+			continue
+		}
+
+		actual, err := call(ctx, target, record)
+		if err != nil {
+			return report, err
+		}
+		report.Sent++
+
+		expected, known := recordedOutcome(record)
+		if opts.Baseline != nil {
+			if expected, err = call(ctx, opts.Baseline, record); err != nil {
+				return report, err
+			}
+			known = true
+		}
+
+		if matches(expected, actual, known, ignore) {
+			report.Matched++
+			continue
+		}
+		report.Diffs = append(report.Diffs, Diff{Record: record, Expected: expected.String(known), Actual: actual.String(true)})
+	}
+
+	return report, nil
+}
+
+// Wait until the record is due, at the pace of the options
+func wait(ctx context.Context, record Record, start time.Time, first *time.Time, speed float64) error {
+	if speed <= 0 || record.Time.IsZero() {
+		return ctx.Err()
+	}
+	if first.IsZero() {
+		*first = record.Time
+	}
+
+	due := start.Add(time.Duration(float64(record.Time.Sub(*first)) / speed))
+	select {
+	case <-time.After(time.Until(due)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Call the method of the record. Errors of the server are outcomes, only transport errors are returned
+func call(ctx context.Context, client *jsonrpc2.Client, record Record) (outcome, error) {
+	var raw json.RawMessage
+	err := client.Call(ctx, record.Method, record.Params, &raw)
+
+	var rpcErr *jsonrpc2.RpcError
+	if errors.As(err, &rpcErr) {
+		return outcome{code: rpcErr.Code}, nil
+	}
+	if err != nil {
+		return outcome{}, err
+	}
+
+	var result any
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return outcome{}, err
+		}
+	}
+
+	return outcome{result: result}, nil
+}
+
+// Outcome of the record, and whether its result is known
+func recordedOutcome(record Record) (outcome, bool) {
+	if record.Code != 0 || len(record.Result) == 0 {
+		return outcome{code: record.Code}, record.Code != 0
+	}
+
+	var result any
+	if err := json.Unmarshal(record.Result, &result); err != nil {
+		return outcome{}, false
+	}
+
+	return outcome{result: result}, true
+}
+
+// Whether the actual outcome is the expected one. Unknown results match any successful result
+func matches(expected, actual outcome, known bool, ignore [][]string) bool {
+	if expected.code != actual.code {
+		return false
+	}
+	if !known || expected.code != 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(without(expected.result, ignore), without(actual.result, ignore))
+}
+
+// Copy of the value without the fields at the paths
+func without(value any, paths [][]string) any {
+	for _, path := range paths {
+		value = remove(value, path)
+	}
+
+	return value
+}
+
+func remove(value any, path []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for name, field := range v {
+			if name != path[0] {
+				copied[name] = field
+			} else if len(path) > 1 {
+				copied[name] = remove(field, path[1:])
+			}
+		}
+		return copied
+
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = remove(item, path)
+		}
+		return copied
+
+	default:
+		return value
+	}
+}
+
+func (o outcome) String(known bool) string {
+	if o.code != 0 {
+		return fmt.Sprintf("error %d", o.code)
+	}
+	if !known {
+		return "success"
+	}
+
+	data, _ := json.Marshal(o.result)
+	return string(data)
+}
@@ -0,0 +1,126 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsonrpc2 "github.com/developertom01/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	//Version of the users service recorded
+	usersV1 struct{}
+	//Version of the users service replayed, which changed the greeting and lost a user
+	usersV2 struct{}
+
+	user struct {
+		Id        int    `json:"id"`
+		Name      string `json:"name"`
+		UpdatedAt string `json:"updatedAt"`
+	}
+)
+
+func (usersV1) Get(ctx context.Context, id int) (user, error, *jsonrpc2.RpcErrorCode) {
+	return user{Id: id, Name: "ada", UpdatedAt: "monday"}, nil, nil
+}
+
+func (usersV1) Greet(ctx context.Context, name string) (string, error, *jsonrpc2.RpcErrorCode) {
+	return "Hello " + name, nil, nil
+}
+
+func (usersV2) Get(ctx context.Context, id int) (user, error, *jsonrpc2.RpcErrorCode) {
+	if id == 2 {
+		code := jsonrpc2.INVALID_PARAMS
+		return user{}, errors.New("No such user"), &code
+	}
+	return user{Id: id, Name: "ada", UpdatedAt: "tuesday"}, nil, nil
+}
+
+func (usersV2) Greet(ctx context.Context, name string) (string, error, *jsonrpc2.RpcErrorCode) {
+	return "Hi " + name, nil, nil
+}
+
+func newTestClient(t *testing.T, srv any, opts ...jsonrpc2.Option) *jsonrpc2.Client {
+	rpc := jsonrpc2.NewJsonRpc(opts...)
+	rpc.RegisterWithName(srv, "Users")
+
+	server := httptest.NewServer(rpc)
+	t.Cleanup(server.Close)
+
+	return jsonrpc2.NewHTTPClient(server.URL)
+}
+
+func TestReplayAuditTrail(t *testing.T) {
+	var trail bytes.Buffer
+	v1 := newTestClient(t, usersV1{}, jsonrpc2.WithAudit(jsonrpc2.NewWriterAuditSink(&trail)))
+
+	var u user
+	v1.Call(context.Background(), "Users.Get", []any{1}, &u)
+	v1.Call(context.Background(), "Users.Get", []any{2}, &u)
+	v1.Notify(context.Background(), "Users.Greet", []any{"ada"})
+
+	records, err := ReadRecords(&trail)
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+	assert.True(t, records[2].Notification)
+
+	report, err := Replay(context.Background(), records, newTestClient(t, usersV2{}), Options{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, report.Sent)
+	assert.Equal(t, 1, report.Matched)
+	assert.Len(t, report.Diffs, 1)
+	assert.Equal(t, "success", report.Diffs[0].Expected)
+	assert.Equal(t, "error 32602", report.Diffs[0].Actual)
+}
+
+func TestReplayAgainstBaseline(t *testing.T) {
+	records, _ := ReadRecords(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"Users.Get","params":[1]}
+{"jsonrpc":"2.0","id":2,"method":"Users.Greet","params":["ada"]}`))
+
+	report, err := Replay(context.Background(), records, newTestClient(t, usersV2{}), Options{
+		Baseline: newTestClient(t, usersV1{}),
+		Ignore:   []string{"updatedAt"},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Matched)
+	assert.Equal(t, "Users.Greet", report.Diffs[0].Record.Method)
+	assert.Equal(t, `"Hello ada"`, report.Diffs[0].Expected)
+	assert.Equal(t, `"Hi ada"`, report.Diffs[0].Actual)
+}
+
+func TestReplayRecordedResults(t *testing.T) {
+	records, _ := ReadRecords(strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"Users.Greet","params":["ada"],"result":"Hi ada"}
+
+{"jsonrpc":"2.0","id":2,"method":"Users.Missing","error":{"code":32601,"message":"Method not found"}}`))
+
+	report, err := Replay(context.Background(), records, newTestClient(t, usersV2{}), Options{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Matched)
+}
+
+func TestReplaySpeed(t *testing.T) {
+	start := time.Now()
+	records := []Record{
+		{Time: start, Method: "Users.Greet", Params: []any{"a"}, Notification: true},
+		{Time: start.Add(200 * time.Millisecond), Method: "Users.Greet", Params: []any{"b"}, Notification: true},
+	}
+
+	Replay(context.Background(), records, newTestClient(t, usersV2{}), Options{Speed: 2})
+
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestInvalidRecord(t *testing.T) {
+	_, err := ReadRecords(strings.NewReader("{\"method\":\"Users.Get\"}\nnot json"))
+
+	assert.Equal(t, "Invalid record on line 2", err.Error())
+}
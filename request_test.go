@@ -0,0 +1,108 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictParserRejectsNonObjects(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	for _, body := range []string{`1`, `"Arith.Add"`, `{}`, `{"jsonrpc":"2.0","method":1,"params":"bar"}`} {
+		res := &Response{}
+		assert.NoError(t, json.Unmarshal([]byte(serveTestBody(rpc, body)), res), body)
+		assert.Equal(t, INVALID_REQUEST, res.Error.Code, body)
+		assert.Nil(t, res.Id, body)
+	}
+}
+
+func TestStrictParserInvalidJson(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	body := serveTestBody(rpc, `[{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]`)
+
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":32700,"data":null,"message":"Unable to decode request"}}`, body)
+}
+
+func TestStrictParserEmptyBatch(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	res := &Response{}
+	json.Unmarshal([]byte(serveTestBody(rpc, ` [] `)), res)
+
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+}
+
+func TestStrictParserInvalidBatchEntries(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	body := serveTestBody(rpc, `[
+		{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]},
+		1,
+		{"jsonrpc":"2.0","id":"3","method":2},
+		{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]}
+	]`)
+
+	var responses []Response
+	if err := json.Unmarshal([]byte(body), &responses); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, responses, 3)
+	assert.Nil(t, responses[0].Error)
+	assert.Equal(t, INVALID_REQUEST, responses[1].Error.Code)
+	assert.Nil(t, responses[1].Id)
+	assert.Equal(t, INVALID_REQUEST, responses[2].Error.Code)
+	assert.Equal(t, "3", *responses[2].Id)
+}
+
+func FuzzHandleMessage(f *testing.F) {
+	for _, seed := range []string{`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}`, `[]`, `[1,{}]`, `{}`, `null`, `"x"`} {
+		f.Add([]byte(seed))
+	}
+
+	rpc := newTestArithRpc()
+	f.Fuzz(func(t *testing.T, body []byte) {
+		res := rpc.HandleMessage(context.Background(), body)
+		if res != nil && !json.Valid(res) {
+			t.Fatalf("Invalid response %s", res)
+		}
+	})
+}
+
+func TestNotificationBatchNoContent(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[
+		{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]},
+		{"jsonrpc":"2.0","method":"Arith.Add","params":[3,4]}
+	]`))
+	newTestArithRpc().ServeHTTP(recorder, r)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestEmptyBatchSingleError(t *testing.T) {
+	body := serveTestBody(newTestArithRpc(), `[]`)
+
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":32600,"data":null,"message":"Batch must contain at least one request"}}`, body)
+}
+
+func TestLegacyBatchResponses(t *testing.T) {
+	rpc := NewJsonRpc(WithLegacyBatchResponses())
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	for _, body := range []string{`[]`, `[{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]}]`} {
+		recorder := httptest.NewRecorder()
+		rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusOK, recorder.Code, body)
+		assert.Equal(t, `[]`, recorder.Body.String(), body)
+	}
+}
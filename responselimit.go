@@ -0,0 +1,86 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+type (
+	//Limit of the encoded results of responses
+	responseLimit struct {
+		maxBytes int64
+		truncate bool //Send the start of the result in the error data instead of rejecting it only
+	}
+
+	//TruncatedResult is the data of RESPONSE_TOO_LARGE errors when WithResponseTruncation is used
+	TruncatedResult struct {
+		Truncated bool   `json:"truncated"` //Always true, so clients tell a truncated result from other error data
+		Size      int    `json:"size"`      //Bytes of the whole encoded result
+		Limit     int64  `json:"limit"`     //Bytes allowed by the server
+		Partial   string `json:"partial"`   //First bytes of the encoded result, which is not valid JSON on its own
+	}
+)
+
+// WithMaxResponseBytes rejects results whose encoding exceeds n bytes with a RESPONSE_TOO_LARGE error, protecting the
+// server and its clients from handlers that serialize huge object graphs by accident. Each result is encoded once
+// more to be measured, in every transport. Streamed attachments are not limited. Entries of a batch are limited one
+// by one.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *config) {
+		limit := c.copyResponseLimit()
+		limit.maxBytes = n
+		c.responseLimit = limit
+	}
+}
+
+// WithResponseTruncation sends the first WithMaxResponseBytes bytes of results that exceed it in the data of the
+// RESPONSE_TOO_LARGE error, as a TruncatedResult, instead of dropping them
+func WithResponseTruncation() Option {
+	return func(c *config) {
+		limit := c.copyResponseLimit()
+		limit.truncate = true
+		c.responseLimit = limit
+	}
+}
+
+// Copy of the limit to change, since configs cloned by Reconfigure share it
+func (c *config) copyResponseLimit() *responseLimit {
+	limit := &responseLimit{}
+	if c.responseLimit != nil {
+		*limit = *c.responseLimit
+	}
+
+	return limit
+}
+
+// Replace the result of the response with an error when it is larger than the limit
+func (c *config) limitResponse(res Response) Response {
+	l := c.responseLimit
+	if l == nil || l.maxBytes <= 0 || res.Result == nil || res.Error != nil {
+		return res
+	}
+	if _, ok := attachmentOf(res); ok {
+		return res
+	}
+
+	encoded, err := c.marshal(*res.Result, false)
+	if err != nil || int64(len(encoded)) <= l.maxBytes {
+		//Results that can not be encoded fail when they are written
+		return res
+	}
+
+	err = errors.New(fmt.Sprintf("Response of %d bytes exceeds the limit of %d bytes", len(encoded), l.maxBytes))
+	if !l.truncate {
+		return makeErrorResponse(err, RESPONSE_TOO_LARGE, nil, res.Id)
+	}
+
+	//Cut on a rune boundary so the partial result is valid text
+	cut := int(l.maxBytes)
+	for cut > 0 && !utf8.RuneStart(encoded[cut]) {
+		cut--
+	}
+	var data any = TruncatedResult{Truncated: true, Size: len(encoded), Limit: l.maxBytes, Partial: string(encoded[:cut])}
+
+	return makeErrorResponse(err, RESPONSE_TOO_LARGE, &data, res.Id)
+}
@@ -0,0 +1,56 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns text of the requested length
+type archive struct{}
+
+func (archive) Read(ctx context.Context, n int, fill string) (string, error, *RpcErrorCode) {
+	return strings.Repeat(fill, n), nil, nil
+}
+
+func TestWithMaxResponseBytes(t *testing.T) {
+	rpc := NewJsonRpc(WithMaxResponseBytes(16))
+	rpc.RegisterWithName(archive{}, "Archive")
+
+	res := callMethod(t, rpc, "Archive.Read", []any{14, "a"})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, strings.Repeat("a", 14), *res.Result)
+
+	res = callMethod(t, rpc, "Archive.Read", []any{15, "a"})
+	assert.Nil(t, res.Result)
+	assert.Equal(t, RESPONSE_TOO_LARGE, res.Error.Code)
+	assert.Equal(t, "Response of 17 bytes exceeds the limit of 16 bytes", res.Error.Message)
+	assert.Nil(t, res.Error.Data)
+
+	//Entries of a batch are limited one by one
+	small, large := "1", "2"
+	responses, err := makeRpcBatchTestRequest(rpc, []Request{
+		{Jsonrpc: RPC_VERSION, Id: &small, Method: "Archive.Read", Params: []any{1, "a"}},
+		{Jsonrpc: RPC_VERSION, Id: &large, Method: "Archive.Read", Params: []any{100, "a"}},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, responses[0].Error)
+	assert.Equal(t, RESPONSE_TOO_LARGE, responses[1].Error.Code)
+}
+
+func TestWithResponseTruncation(t *testing.T) {
+	rpc := NewJsonRpc(WithMaxResponseBytes(8), WithResponseTruncation())
+	rpc.RegisterWithName(archive{}, "Archive")
+
+	res := callMethod(t, rpc, "Archive.Read", []any{4, "é"})
+	assert.Equal(t, RESPONSE_TOO_LARGE, res.Error.Code)
+
+	var data TruncatedResult
+	raw, _ := json.Marshal(res.Error.Data)
+	assert.Nil(t, json.Unmarshal(raw, &data))
+	//The cut does not split the last two bytes of é
+	assert.Equal(t, TruncatedResult{Truncated: true, Size: 10, Limit: 8, Partial: `"ééé`}, data)
+}
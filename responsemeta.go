@@ -0,0 +1,82 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	//Members of the meta object of the response to the request being handled
+	responseMeta struct {
+		mu     sync.Mutex
+		values map[string]any
+	}
+
+	responseMetaKey struct{}
+)
+
+// WithResponseMeta sends the meta object of responses alongside their result or error, eg. timings, trace ids,
+// pagination cursors or warnings. Handlers add members with SetResponseMeta, while middleware and interceptors may
+// set the Meta of the response they return. Meta is not part of the JSON-RPC 2.0 specification, so servers are strict
+// by default and drop it from responses, for clients that reject unknown members.
+func WithResponseMeta() Option {
+	return func(c *config) {
+		c.responseMeta = true
+	}
+}
+
+// SetResponseMeta sets a member of the meta object of the response to the request being handled. It does nothing
+// unless the server was built WithResponseMeta. Members set by middleware on the response take precedence.
+func SetResponseMeta(ctx context.Context, key string, value any) {
+	meta, ok := ctx.Value(responseMetaKey{}).(*responseMeta)
+	if !ok {
+		return
+	}
+
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+
+	if meta.values == nil {
+		meta.values = make(map[string]any)
+	}
+	meta.values[key] = value
+}
+
+// Collect the meta set by the handler of the request, when responses carry meta
+func (c *config) withResponseMeta(ctx context.Context) (context.Context, *responseMeta) {
+	if !c.responseMeta {
+		return ctx, nil
+	}
+
+	meta := &responseMeta{}
+	return context.WithValue(ctx, responseMetaKey{}, meta), meta
+}
+
+// Add the collected meta to the response. Meta is dropped from the responses of strict servers
+func (c *config) attachResponseMeta(res Response, meta *responseMeta) Response {
+	if !c.responseMeta {
+		res.Meta = nil
+		return res
+	}
+	if meta == nil {
+		return res
+	}
+
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+
+	if len(meta.values) == 0 {
+		return res
+	}
+
+	merged := make(map[string]any, len(meta.values)+len(res.Meta))
+	for key, value := range meta.values {
+		merged[key] = value
+	}
+	for key, value := range res.Meta {
+		merged[key] = value
+	}
+	res.Meta = merged
+
+	return res
+}
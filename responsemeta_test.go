@@ -0,0 +1,71 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Lists pages of items with a cursor to the next page in the meta of the response
+type catalog struct{}
+
+func (catalog) List(ctx context.Context, page int) ([]string, error, *RpcErrorCode) {
+	SetResponseMeta(ctx, "next", page+1)
+	SetResponseMeta(ctx, "warning", "Catalog is stale")
+	return []string{"a", "b"}, nil, nil
+}
+
+// Middleware adding a trace id to the meta of the response
+func traceMeta(next Handler) Handler {
+	return func(ctx context.Context, req *Request) Response {
+		res := next(ctx, req)
+		res.Meta = map[string]any{"traceId": "abc", "warning": "Overridden"}
+		return res
+	}
+}
+
+func TestWithResponseMeta(t *testing.T) {
+	rpc := NewJsonRpc(WithResponseMeta(), WithMiddleware(traceMeta))
+	rpc.RegisterWithName(catalog{}, "Catalog")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Catalog.List","params":[1]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","meta":{"next":2,"warning":"Overridden","traceId":"abc"},"result":["a","b"]}`, body)
+
+	//Errors carry meta too
+	body = serveTestBody(rpc, `{"jsonrpc":"2.0","id":"2","method":"Catalog.Missing","params":[]}`)
+	assert.Contains(t, body, `"meta":{"traceId":"abc","warning":"Overridden"}`)
+}
+
+func TestResponseMetaDroppedByDefault(t *testing.T) {
+	rpc := NewJsonRpc(WithMiddleware(traceMeta))
+	rpc.RegisterWithName(catalog{}, "Catalog")
+
+	body := serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Catalog.List","params":[1]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","result":["a","b"]}`, body)
+
+	//Setting meta outside a request does nothing
+	SetResponseMeta(context.Background(), "next", 1)
+}
+
+func TestClientResponseMeta(t *testing.T) {
+	rpc := NewJsonRpc(WithResponseMeta())
+	rpc.RegisterWithName(catalog{}, "Catalog")
+
+	var meta map[string]any
+	client := NewHTTPClient(newTestHTTPServer(t, rpc))
+	client.Use(func(next Invoker) Invoker {
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			res, err := next(ctx, req)
+			if res != nil {
+				meta = res.Meta
+			}
+			return res, err
+		}
+	})
+
+	var items []string
+	assert.Nil(t, client.Call(context.Background(), "Catalog.List", []any{4}, &items))
+	assert.Equal(t, []string{"a", "b"}, items)
+	assert.Equal(t, map[string]any{"next": float64(5), "warning": "Catalog is stale"}, meta)
+}
@@ -0,0 +1,434 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Path of the OpenAPI document under the prefix of the REST bridge
+const OPENAPI_DOCUMENT_PATH = "/openapi.json"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// WithREST exposes the registered methods as REST endpoints under prefix, eg. /api. Arith.Add is called with
+// POST /api/arith/add, whose JSON body holds the params by position or by name. The result is returned as the body,
+// and errors as the error object of JSON-RPC with an HTTP status matching its code.
+// An OpenAPI 3 document describing the endpoints is served on GET {prefix}/openapi.json, so API gateways and
+// clients generated from it use the same services.
+func WithREST(prefix string) Option {
+	return func(c *config) {
+		c.restPrefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// Serve the REST bridge. False is returned when the request is not for it
+func (rpc *jsonRpcImpl) serveREST(w http.ResponseWriter, r *http.Request) bool {
+	cfg := rpc.cfg()
+	if cfg.restPrefix == "" {
+		return false
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, cfg.restPrefix)
+	if !ok || !strings.HasPrefix(path, "/") {
+		return false
+	}
+
+	if path == OPENAPI_DOCUMENT_PATH && r.Method == http.MethodGet {
+		rpc.serveOpenAPI(w, r)
+		return true
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		rpc.writeRESTError(w, &RpcError{Code: INVALID_REQUEST, Message: "Methods are called with POST"}, http.StatusMethodNotAllowed)
+		return true
+	}
+
+	method, err := rpc.restMethod(r.Context(), path)
+	if err != nil {
+		rpc.writeRESTError(w, &RpcError{Code: INTERNAL_ERROR, Message: err.Error()}, http.StatusInternalServerError)
+		return true
+	}
+	if method == "" {
+		rpc.writeRESTError(w, &RpcError{Code: METHOD_NOT_FOUND, Message: "Method not found"}, http.StatusNotFound)
+		return true
+	}
+
+	params, paramsErr := rpc.readRESTParams(r)
+	if paramsErr != nil {
+		rpc.writeRESTError(w, paramsErr, http.StatusBadRequest)
+		return true
+	}
+
+	ctx := withInFlightRequests(r.Context(), rpc.inFlight)
+	ctx = withRemoteAddr(ctx, rpc.cfg().remoteAddr(r))
+	ctx = withHTTPRequest(ctx, r)
+	ctx = cfg.requestMetadata(ctx, r.Header)
+
+	ctx, cancel, timeoutErr := withTimeoutHeader(ctx, r.Header)
+	defer cancel()
+	if timeoutErr != nil {
+		rpc.writeRESTError(w, &RpcError{Code: INVALID_REQUEST, Message: timeoutErr.Error()}, http.StatusBadRequest)
+		return true
+	}
+
+	ctx, deprecations := withDeprecationWarnings(ctx)
+
+	id := "rest"
+	res := rpc.handleSingleRequest(ctx, Request{Jsonrpc: RPC_VERSION, Method: method, Params: params, Id: &id})
+	deprecations.writeHeaders(w.Header())
+
+	if res.Error != nil {
+		rpc.writeRESTError(w, res.Error, restStatus(res.Error.Code))
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	cfg.writeJSON(truncatingWriterFor(w, res), res.Result, cfg.indentResponses)
+
+	return true
+}
+
+// Full name of the method served on the path, matched without case. Empty when there is none
+func (rpc *jsonRpcImpl) restMethod(ctx context.Context, path string) (string, error) {
+	if method := rpc.lookupRESTMethod(path); method != "" {
+		return method, nil
+	}
+
+	//The service may be resolved by a registry
+	if err := rpc.resolveAllServices(ctx); err != nil {
+		return "", err
+	}
+
+	return rpc.lookupRESTMethod(path), nil
+}
+
+func (rpc *jsonRpcImpl) lookupRESTMethod(path string) string {
+	for _, srv := range rpc.registeredServices() {
+		for name := range srv.methods {
+			method := srv.fullName(name)
+			if strings.EqualFold(restPath(method), path) {
+				return method
+			}
+		}
+	}
+
+	return ""
+}
+
+// Path of the method under the prefix. Dots of the names of groups separate segments too. eg. /admin/users/create
+func restPath(method string) string {
+	return "/" + strings.ToLower(strings.ReplaceAll(method, ".", "/"))
+}
+
+// Params of the call in the body. An empty body calls the method without params
+func (rpc *jsonRpcImpl) readRESTParams(r *http.Request) (any, *RpcError) {
+	cfg := rpc.cfg()
+
+	var reader io.Reader = r.Body
+	if cfg.maxRequestSize > 0 {
+		reader = io.LimitReader(r.Body, cfg.maxRequestSize+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, &RpcError{Code: PARSE_ERROR, Message: err.Error()}
+	}
+	if cfg.maxRequestSize > 0 && int64(len(body)) > cfg.maxRequestSize {
+		return nil, &RpcError{Code: INVALID_REQUEST, Message: errRequestTooLarge.Error()}
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	var params any
+	if err := cfg.unmarshal(body, &params); err != nil {
+		return nil, &RpcError{Code: PARSE_ERROR, Message: "Unable to decode params"}
+	}
+
+	switch params.(type) {
+	case []any, map[string]any:
+		return params, nil
+	default:
+		return nil, &RpcError{Code: INVALID_PARAMS, Message: "Params must be an array or an object"}
+	}
+}
+
+func (rpc *jsonRpcImpl) writeRESTError(w http.ResponseWriter, rpcErr *RpcError, status int) {
+	cfg := rpc.cfg()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	cfg.writeJSON(w, rpcErr, cfg.indentResponses)
+}
+
+// HTTP status of the responses failing with the code
+func restStatus(code RpcErrorCode) int {
+	switch code {
+	case PARSE_ERROR, INVALID_REQUEST, INVALID_PARAMS:
+		return http.StatusBadRequest
+	case METHOD_NOT_FOUND:
+		return http.StatusNotFound
+	case UNAUTHORIZED:
+		return http.StatusUnauthorized
+	case SERVER_BUSY:
+		return http.StatusServiceUnavailable
+	case UPSTREAM_UNAVAILABLE:
+		return http.StatusBadGateway
+	case REQUEST_CANCELLED:
+		return http.StatusRequestTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (rpc *jsonRpcImpl) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if err := rpc.resolveAllServices(r.Context()); err != nil {
+		rpc.writeRESTError(w, &RpcError{Code: INTERNAL_ERROR, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	cfg := rpc.cfg()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	cfg.writeJSON(w, rpc.openAPIDocument(cfg.restPrefix), cfg.indentResponses)
+}
+
+// OpenAPI 3 document of the endpoints of the registered methods
+func (rpc *jsonRpcImpl) openAPIDocument(prefix string) map[string]any {
+	paths := make(map[string]any)
+	for _, srv := range rpc.registeredServices() {
+		//Descriptions are sorted by method name
+		names := make([]string, 0, len(srv.methods))
+		for name := range srv.methods {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		desc := srv.describe()
+		for i, name := range names {
+			method := desc.Methods[i]
+			paths[prefix+restPath(method.Name)] = map[string]any{
+				"post": openAPIOperation(srv.methods[name].Type(), method),
+			}
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "JSON-RPC services", "version": "1.0.0"},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"Error": map[string]any{
+					"type":     "object",
+					"required": []string{"code", "message"},
+					"properties": map[string]any{
+						"code":    map[string]any{"type": "integer"},
+						"message": map[string]any{"type": "string"},
+						"data":    map[string]any{},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Operation calling the method. Params are described by position, or by name for methods taking a single struct
+func openAPIOperation(methodType reflect.Type, desc MethodDescription) map[string]any {
+	var body map[string]any
+	if takesParamsStruct(methodType) {
+		body = jsonSchemaOf(methodType.In(1), make(map[reflect.Type]bool))
+	} else {
+		items := make([]any, 0, methodType.NumIn()-1)
+		for p := 1; p < methodType.NumIn(); p++ {
+			t := methodType.In(p)
+			if methodType.IsVariadic() && p == methodType.NumIn()-1 {
+				t = t.Elem()
+			}
+			schema := jsonSchemaOf(t, make(map[reflect.Type]bool))
+
+			duplicate := false
+			for _, item := range items {
+				duplicate = duplicate || reflect.DeepEqual(item, schema)
+			}
+			if !duplicate {
+				items = append(items, schema)
+			}
+		}
+
+		body = map[string]any{"type": "array", "items": map[string]any{}}
+		if len(items) == 1 {
+			body["items"] = items[0]
+		} else if len(items) > 1 {
+			body["items"] = map[string]any{"anyOf": items}
+		}
+		if methodType.IsVariadic() {
+			body["minItems"] = methodType.NumIn() - 2
+		} else {
+			body["minItems"] = methodType.NumIn() - 1
+			body["maxItems"] = methodType.NumIn() - 1
+		}
+	}
+
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+			},
+		}
+	}
+
+	operation := map[string]any{
+		"operationId": desc.Name,
+		"requestBody": map[string]any{
+			"required": methodType.NumIn() > 1 && !methodType.IsVariadic(),
+			"content":  map[string]any{"application/json": map[string]any{"schema": body}},
+		},
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "Result of the method",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": jsonSchemaOf(methodType.Out(0), make(map[reflect.Type]bool))},
+				},
+			},
+			"400":     errorResponse("Invalid params"),
+			"404":     errorResponse("Method not found"),
+			"default": errorResponse("Error of the method"),
+		},
+	}
+	if desc.Description != "" {
+		operation["description"] = desc.Description
+	}
+	if desc.Deprecated {
+		operation["deprecated"] = true
+	}
+
+	return operation
+}
+
+// JSON schema of the values of the type as encoded by encoding/json. Recursive types are left open
+func jsonSchemaOf(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType, t.Implements(jsonMarshalerType), reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := jsonSchemaOf(t.Elem(), seen)
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": jsonSchemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]any{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		return structSchema(t, seen)
+	default:
+		return map[string]any{}
+	}
+}
+
+// Schema of a struct, with the constraints of the rpc tags of its fields
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	rules, _ := structRules(t)
+	byIndex := make(map[int]fieldRule, len(rules))
+	for _, rule := range rules {
+		byIndex[rule.index] = rule
+	}
+
+	properties := make(map[string]any)
+	required := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+
+		//Fields of embedded structs are encoded as fields of the struct
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			embedded := jsonSchemaOf(f.Type, seen)
+			if props, ok := embedded["properties"].(map[string]any); ok {
+				for name, schema := range props {
+					properties[name] = schema
+				}
+			}
+			if names, ok := embedded["required"].([]string); ok {
+				required = append(required, names...)
+			}
+			continue
+		}
+
+		schema := jsonSchemaOf(f.Type, seen)
+		rule := byIndex[i]
+		if rule.min != nil {
+			schema[constraintKeyword(f.Type, "min")] = *rule.min
+		}
+		if rule.max != nil {
+			schema[constraintKeyword(f.Type, "max")] = *rule.max
+		}
+		if rule.required {
+			required = append(required, rule.name)
+		}
+		properties[jsonFieldName(f)] = schema
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// Keyword of a min or max constraint, which applies to the value of numbers and the length of other types
+func constraintKeyword(t reflect.Type, bound string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	suffix := "imum"
+	switch t.Kind() {
+	case reflect.String:
+		suffix = "Length"
+	case reflect.Slice, reflect.Array:
+		suffix = "Items"
+	case reflect.Map:
+		suffix = "Properties"
+	}
+
+	return bound + suffix
+}
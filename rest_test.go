@@ -0,0 +1,153 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	restOrder struct {
+		Id      int       `json:"id" rpc:"required,min=1"`
+		Items   []string  `json:"items" rpc:"max=10"`
+		Created time.Time `json:"created"`
+		Note    *string   `json:"note,omitempty"`
+	}
+
+	restOrders struct{}
+)
+
+func (restOrders) Place(ctx context.Context, order restOrder) (restOrder, error, *RpcErrorCode) {
+	return order, nil, nil
+}
+
+// Call the REST bridge of the server
+func callTestREST(rpc JsonRPC, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+	return recorder
+}
+
+func TestRESTCall(t *testing.T) {
+	rpc := NewJsonRpc(WithREST("/api/"))
+	rpc.RegisterWithName(arith{}, "Arith")
+	rpc.RegisterWithName(restOrders{}, "Orders")
+
+	res := callTestREST(rpc, http.MethodPost, "/api/arith/add", `[1, 2]`)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.JSONEq(t, `3`, res.Body.String())
+
+	res = callTestREST(rpc, http.MethodPost, "/api/Orders/Place", `{"id": 7, "items": ["book"]}`)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.JSONEq(t, `{"id":7,"items":["book"],"created":"0001-01-01T00:00:00Z"}`, res.Body.String())
+
+	//The JSON-RPC endpoint keeps working
+	res = callTestREST(rpc, http.MethodPost, "/", `{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2],"id":"1"}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","result":3,"id":"1"}`, res.Body.String())
+}
+
+func TestRESTErrors(t *testing.T) {
+	rpc := NewJsonRpc(WithREST("/api"))
+	rpc.RegisterWithName(arith{}, "Arith")
+	rpc.RegisterWithName(restOrders{}, "Orders")
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               RpcErrorCode
+	}{
+		{http.MethodPost, "/api/arith/errormethod", ``, http.StatusInternalServerError, INTERNAL_ERROR},
+		{http.MethodPost, "/api/arith/sub", `[1, 2]`, http.StatusNotFound, METHOD_NOT_FOUND},
+		{http.MethodPost, "/api/arith/add", `[1`, http.StatusBadRequest, PARSE_ERROR},
+		{http.MethodPost, "/api/arith/add", `1`, http.StatusBadRequest, INVALID_PARAMS},
+		{http.MethodPost, "/api/arith/add", `[1]`, http.StatusBadRequest, INVALID_PARAMS},
+		{http.MethodPost, "/api/orders/place", `{"id": 0}`, http.StatusBadRequest, INVALID_PARAMS},
+		{http.MethodGet, "/api/arith/add", ``, http.StatusMethodNotAllowed, INVALID_REQUEST},
+	}
+
+	for _, test := range tests {
+		res := callTestREST(rpc, test.method, test.path, test.body)
+		assert.Equal(t, test.status, res.Code, test.path)
+
+		var rpcErr RpcError
+		assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &rpcErr))
+		assert.Equal(t, test.code, rpcErr.Code, test.path)
+	}
+}
+
+func TestRESTDisabled(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	res := callTestREST(rpc, http.MethodPost, "/api/arith/add", `[1, 2]`)
+	assert.NotEqual(t, `3`, strings.TrimSpace(res.Body.String()))
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	rpc := NewJsonRpc(WithREST("/api"))
+	rpc.RegisterWithOptions(arith{}, ServiceOptions{
+		Name: "Arith",
+		Docs: map[string]MethodDoc{"Add": {Description: "Adds two numbers"}},
+	})
+	rpc.RegisterWithName(restOrders{}, "Orders")
+
+	res := callTestREST(rpc, http.MethodGet, "/api"+OPENAPI_DOCUMENT_PATH, ``)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.ElementsMatch(t, []string{"/api/arith/add", "/api/arith/errormethod", "/api/orders/place"}, keysOf(doc.Paths))
+
+	var add struct {
+		OperationId string `json:"operationId"`
+		Description string `json:"description"`
+		RequestBody struct {
+			Content map[string]struct {
+				Schema json.RawMessage `json:"schema"`
+			} `json:"content"`
+		} `json:"requestBody"`
+	}
+	assert.Nil(t, json.Unmarshal(doc.Paths["/api/arith/add"]["post"], &add))
+	assert.Equal(t, "Arith.Add", add.OperationId)
+	assert.Equal(t, "Adds two numbers", add.Description)
+	assert.JSONEq(t, `{"type":"array","items":{"type":"number"},"minItems":2,"maxItems":2}`, string(add.RequestBody.Content["application/json"].Schema))
+
+	var place struct {
+		RequestBody struct {
+			Content map[string]struct {
+				Schema json.RawMessage `json:"schema"`
+			} `json:"content"`
+		} `json:"requestBody"`
+	}
+	assert.Nil(t, json.Unmarshal(doc.Paths["/api/orders/place"]["post"], &place))
+	assert.JSONEq(t, `{
+		"type": "object",
+		"required": ["id"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"items": {"type": "array", "items": {"type": "string"}, "maxItems": 10},
+			"created": {"type": "string", "format": "date-time"},
+			"note": {"type": "string", "nullable": true}
+		}
+	}`, string(place.RequestBody.Content["application/json"].Schema))
+}
+
+func keysOf[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
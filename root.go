@@ -0,0 +1,34 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Name of the root service, whose methods are called without a service prefix
+const ROOT_SERVICE_NAME = ""
+
+// RegisterRoot registers a service whose methods are called by their name alone, eg. "method": "ping", like the
+// APIs of Ethereum or Bitcoin nodes. Registering another root service replaces it.
+// Method names without a dot are invalid when no root service is registered.
+func (rpc *jsonRpcImpl) RegisterRoot(srv any) error {
+	srv, err := rpc.construct(srv)
+	if err != nil {
+		return err
+	}
+
+	if reflect.ValueOf(srv).NumMethod() == 0 {
+		return errors.New("No method registered for this service")
+	}
+
+	return rpc.registerService(srv, ROOT_SERVICE_NAME, ServiceOptions{})
+}
+
+// Name a method of the service is called by. eg. Arith.Add
+func (s *service) fullName(methodName string) string {
+	if s.name == ROOT_SERVICE_NAME {
+		return methodName
+	}
+
+	return s.name + "." + methodName
+}
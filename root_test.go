@@ -0,0 +1,46 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type node struct{}
+
+func (node) Ping(ctx context.Context) (string, error, *RpcErrorCode) {
+	return "pong", nil, nil
+}
+
+func (node) GetBlockCount(ctx context.Context) (int, error, *RpcErrorCode) {
+	return 42, nil, nil
+}
+
+func TestRegisterRoot(t *testing.T) {
+	rpc := newTestArithRpc()
+	assert.Nil(t, rpc.RegisterRoot(node{}))
+
+	assert.Equal(t, any("pong"), *callMethod(t, rpc, "Ping", nil).Result)
+	assert.Equal(t, any(float64(42)), *callMethod(t, rpc, "GetBlockCount", nil).Result)
+	assert.Equal(t, any(float64(3)), *callMethod(t, rpc, "Arith.Add", []any{1, 2}).Result)
+}
+
+func TestRootMethodNotFound(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterRoot(node{})
+
+	res := callMethod(t, rpc, "Pong", nil)
+
+	assert.Equal(t, METHOD_NOT_FOUND, res.Error.Code)
+	assert.Equal(t, "Method Pong does not exist", res.Error.Message)
+}
+
+func TestRootMethodsListed(t *testing.T) {
+	rpc := newTestArithRpc()
+	rpc.RegisterRoot(node{})
+
+	res := callMethod(t, rpc, "rpc.listMethods", nil)
+
+	assert.Equal(t, any([]any{"Arith.Add", "Arith.ErrorMethod", "GetBlockCount", "Ping"}), *res.Result)
+}
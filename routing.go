@@ -0,0 +1,163 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+type (
+	//RoutingRule picks the calls sent to a canary, eg. a rewrite of a service. A call is routed when it matches
+	//every condition of the rule, then only for the share of calls set by Percent
+	RoutingRule struct {
+		Methods []string          //Methods routed. Patterns ending with * match prefixes, eg. Users.*. Every method when empty
+		Headers map[string]string //HTTP headers the call must carry, eg. X-Canary: 1
+		Params  map[string]any    //Named params the call must have, eg. "tenant": "acme"
+		Percent float64           //Share of the matching calls routed, between 0 and 100
+	}
+
+	//Canary route of a proxy
+	proxyCanary struct {
+		rule      routingRule
+		upstreams []*Upstream
+	}
+
+	//RoutingRule with its params as decoded from JSON, so they compare with the params of requests
+	routingRule struct {
+		RoutingRule
+		params map[string]any
+	}
+)
+
+// Canary routes a share of the calls of a service to another registered service, eg. Users to UsersV2, to roll out
+// a rewrite of its handlers progressively. The method keeps its name on the other service, which must implement
+// every routed method. Rules are checked in order and the first one matching routes the call. Calls matching none
+// stay on the service.
+func Canary(from, to string, rules ...RoutingRule) Middleware {
+	compiled := compileRoutingRules(rules)
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) Response {
+			method, ok := strings.CutPrefix(req.Method, from+".")
+			if !ok {
+				return next(ctx, req)
+			}
+
+			for _, rule := range compiled {
+				if rule.routes(ctx, req.Method, func() any { return req.Params }) {
+					routed := *req
+					routed.Method = to + "." + method
+					return next(ctx, &routed)
+				}
+			}
+
+			return next(ctx, req)
+		}
+	}
+}
+
+// Canary forwards the calls matching the rule to other upstreams than the ones of their route, eg. servers
+// running a new version. Canaries are checked in the order they are added, before the routes.
+func (p *Proxy) Canary(rule RoutingRule, upstreams ...*Upstream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.canaries = append(p.canaries, proxyCanary{rule: compileRoutingRules([]RoutingRule{rule})[0], upstreams: upstreams})
+}
+
+// Upstreams of the first canary the request is routed to. Nil when it is routed to none
+func (p *Proxy) matchCanary(ctx context.Context, req proxyRequest) []*Upstream {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var params any
+	decoded := false
+	decodeParams := func() any {
+		if !decoded {
+			json.Unmarshal(req.Params, &params)
+			decoded = true
+		}
+		return params
+	}
+
+	for _, canary := range p.canaries {
+		if canary.rule.routes(ctx, req.Method, decodeParams) {
+			return canary.upstreams
+		}
+	}
+
+	return nil
+}
+
+func compileRoutingRules(rules []RoutingRule) []routingRule {
+	compiled := make([]routingRule, len(rules))
+	for i, rule := range rules {
+		compiled[i] = routingRule{RoutingRule: rule}
+		if len(rule.Params) == 0 {
+			continue
+		}
+
+		//Numbers become float64 like the params of requests
+		data, _ := json.Marshal(rule.Params)
+		json.Unmarshal(data, &compiled[i].params)
+	}
+
+	return compiled
+}
+
+// Whether the call is routed. Params are only read when the rule has conditions on them
+func (r routingRule) routes(ctx context.Context, method string, params func() any) bool {
+	if len(r.Methods) > 0 {
+		matched := false
+		for _, pattern := range r.Methods {
+			if matchMethodPattern(pattern, method) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(r.Headers) > 0 {
+		req := HTTPRequestFromContext(ctx)
+		if req == nil {
+			return false
+		}
+		for key, value := range r.Headers {
+			if req.Header.Get(key) != value {
+				return false
+			}
+		}
+	}
+
+	if len(r.params) > 0 {
+		named, ok := params().(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range r.params {
+			param := named[key]
+			if n, ok := param.(json.Number); ok {
+				param, _ = n.Float64()
+			}
+			if !reflect.DeepEqual(param, value) {
+				return false
+			}
+		}
+	}
+
+	return r.Percent >= 100 || (r.Percent > 0 && rand.Float64()*100 < r.Percent)
+}
+
+// Whether the method matches the pattern. Patterns ending with * match every method starting with the rest
+func matchMethodPattern(pattern, method string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(method, prefix)
+	}
+
+	return pattern == method
+}
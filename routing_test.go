@@ -0,0 +1,91 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	testUsersV1 struct{}
+	testUsersV2 struct{}
+
+	testTenantParams struct {
+		Tenant string `json:"tenant"`
+	}
+)
+
+func (testUsersV1) Version(ctx context.Context, p testTenantParams) (string, error, *RpcErrorCode) {
+	return "v1", nil, nil
+}
+
+func (testUsersV2) Version(ctx context.Context, p testTenantParams) (string, error, *RpcErrorCode) {
+	return "v2", nil, nil
+}
+
+func newTestCanaryRpc(rules ...RoutingRule) JsonRPC {
+	rpc := NewJsonRpc(WithMiddleware(Canary("Users", "UsersV2", rules...)))
+	rpc.RegisterWithName(testUsersV1{}, "Users")
+	rpc.RegisterWithName(testUsersV2{}, "UsersV2")
+
+	return rpc
+}
+
+func callTestVersion(t *testing.T, client *Client, tenant string) string {
+	var version string
+	if err := client.Call(context.Background(), "Users.Version", map[string]any{"tenant": tenant}, &version); err != nil {
+		t.Fatal(err)
+	}
+
+	return version
+}
+
+func TestCanaryByParams(t *testing.T) {
+	rpc := newTestCanaryRpc(RoutingRule{Params: map[string]any{"tenant": "acme"}, Percent: 100})
+	client := NewInProcessClient(rpc)
+
+	assert.Equal(t, "v2", callTestVersion(t, client, "acme"))
+	assert.Equal(t, "v1", callTestVersion(t, client, "globex"))
+}
+
+func TestCanaryByHeader(t *testing.T) {
+	rpc := newTestCanaryRpc(RoutingRule{Methods: []string{"Users.*"}, Headers: map[string]string{"X-Canary": "1"}, Percent: 100})
+	client := NewHTTPClient(newTestHTTPServer(t, rpc))
+
+	assert.Equal(t, "v1", callTestVersion(t, client, "acme"))
+
+	var version string
+	ctx := ContextWithHeader(context.Background(), "X-Canary", "1")
+	assert.Nil(t, client.Call(ctx, "Users.Version", map[string]any{"tenant": "acme"}, &version))
+	assert.Equal(t, "v2", version)
+}
+
+func TestCanaryPercent(t *testing.T) {
+	client := NewInProcessClient(newTestCanaryRpc(RoutingRule{Percent: 0}))
+	assert.Equal(t, "v1", callTestVersion(t, client, "acme"))
+
+	client = NewInProcessClient(newTestCanaryRpc(RoutingRule{Percent: 50}))
+	versions := map[string]int{}
+	for i := 0; i < 200; i++ {
+		versions[callTestVersion(t, client, "acme")]++
+	}
+	assert.Greater(t, versions["v1"], 50)
+	assert.Greater(t, versions["v2"], 50)
+}
+
+func TestProxyCanary(t *testing.T) {
+	v1 := NewJsonRpc()
+	v1.RegisterWithName(testUsersV1{}, "Users")
+	v2 := NewJsonRpc()
+	v2.RegisterWithName(testUsersV2{}, "Users")
+
+	proxy := NewProxy()
+	proxy.Route("*", NewHTTPUpstream(newTestHTTPServer(t, v1)))
+	proxy.Canary(RoutingRule{Params: map[string]any{"tenant": "acme"}, Percent: 100}, NewHTTPUpstream(newTestHTTPServer(t, v2)))
+	t.Cleanup(func() { proxy.Close() })
+
+	client := NewHTTPClient(newTestHTTPServer(t, proxy))
+	assert.Equal(t, "v2", callTestVersion(t, client, "acme"))
+	assert.Equal(t, "v1", callTestVersion(t, client, "globex"))
+}
@@ -0,0 +1,189 @@
+package jsonrpc2
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// Priorities of methods. Any int works, higher priorities are dispatched first
+const (
+	PRIORITY_LOW    = -10
+	PRIORITY_NORMAL = 0
+	PRIORITY_HIGH   = 10
+)
+
+type (
+	//SchedulerStats is a snapshot of the scheduler, eg. to export queue depth metrics
+	SchedulerStats struct {
+		Workers          int
+		Running          int         //Calls holding a worker
+		Queued           int         //Calls waiting for a worker
+		QueuedByPriority map[int]int //Queued calls by priority
+	}
+
+	//Bounded pool of workers. Waiting calls are dispatched by priority, then in arrival order
+	scheduler struct {
+		mu      sync.Mutex
+		workers int
+		running int
+		queue   scheduledCalls
+		lastSeq uint64
+	}
+
+	//A call waiting for a worker
+	scheduledCall struct {
+		priority int
+		seq      uint64
+		index    int //Position in the heap. -1 once dispatched
+		ready    chan struct{}
+	}
+
+	//Heap of waiting calls
+	scheduledCalls []*scheduledCall
+)
+
+// Builtin methods dispatched first unless WithMethodPriority says otherwise, so clients can cancel calls under load
+var defaultPriorities = map[string]int{
+	BUILTIN_SERVICE_NAME + ".cancel":    PRIORITY_HIGH,
+	BUILTIN_SERVICE_NAME + ".jobCancel": PRIORITY_HIGH,
+}
+
+// WithScheduler runs at most workers calls at the same time. Calls beyond that wait for a worker and are
+// dispatched by the priority of their method, see WithMethodPriority, so health checks and cancellations are
+// not stuck behind bulk queries under load.
+func WithScheduler(workers int) Option {
+	return func(c *config) {
+		if workers < 1 {
+			workers = 1
+		}
+		c.scheduler = &scheduler{workers: workers}
+	}
+}
+
+// WithMethodPriority sets the priority of a method, eg. PRIORITY_HIGH. method is the full method name. eg. Health.Check.
+// Methods default to PRIORITY_NORMAL. Priorities only matter with WithScheduler.
+func WithMethodPriority(method string, priority int) Option {
+	return func(c *config) {
+		if c.methodPriorities == nil {
+			c.methodPriorities = make(map[string]int)
+		}
+		c.methodPriorities[method] = priority
+	}
+}
+
+func (c *config) methodPriority(method string) int {
+	if priority, ok := c.methodPriorities[method]; ok {
+		return priority
+	}
+
+	return defaultPriorities[method]
+}
+
+// SchedulerStats returns the state of the scheduler. It is zero when WithScheduler is not used
+func (rpc *jsonRpcImpl) SchedulerStats() SchedulerStats {
+	s := rpc.cfg().scheduler
+	if s == nil {
+		return SchedulerStats{}
+	}
+
+	return s.stats()
+}
+
+// Wait for a worker. The worker must be released unless an error is returned
+func (s *scheduler) acquire(ctx context.Context, priority int) error {
+	s.mu.Lock()
+	if s.running < s.workers && len(s.queue) == 0 {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.lastSeq++
+	call := &scheduledCall{priority: priority, seq: s.lastSeq, ready: make(chan struct{})}
+	heap.Push(&s.queue, call)
+	s.mu.Unlock()
+
+	select {
+	case <-call.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	if call.index >= 0 {
+		heap.Remove(&s.queue, call.index)
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+	s.mu.Unlock()
+
+	//The worker was handed over while the context ended
+	s.release()
+	return ctx.Err()
+}
+
+// Hand the worker to the next waiting call, if any
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		s.running--
+		return
+	}
+
+	call := heap.Pop(&s.queue).(*scheduledCall)
+	close(call.ready)
+}
+
+func (s *scheduler) stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{
+		Workers:          s.workers,
+		Running:          s.running,
+		Queued:           len(s.queue),
+		QueuedByPriority: make(map[int]int),
+	}
+	for _, call := range s.queue {
+		stats.QueuedByPriority[call.priority]++
+	}
+
+	return stats
+}
+
+func (q scheduledCalls) Len() int {
+	return len(q)
+}
+
+func (q scheduledCalls) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+
+	return q[i].seq < q[j].seq
+}
+
+func (q scheduledCalls) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduledCalls) Push(x any) {
+	call := x.(*scheduledCall)
+	call.index = len(*q)
+	*q = append(*q, call)
+}
+
+func (q *scheduledCalls) Pop() any {
+	old := *q
+	call := old[len(old)-1]
+	old[len(old)-1] = nil
+	call.index = -1
+	*q = old[:len(old)-1]
+
+	return call
+}
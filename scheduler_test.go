@@ -0,0 +1,102 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Methods record when they start and wait to be released
+type workService struct {
+	started chan string
+	release chan struct{}
+}
+
+func (s workService) Bulk(ctx context.Context) (string, error, *RpcErrorCode) {
+	s.started <- "Bulk"
+	<-s.release
+	return "done", nil, nil
+}
+
+func (s workService) Health(ctx context.Context) (string, error, *RpcErrorCode) {
+	s.started <- "Health"
+	<-s.release
+	return "ok", nil, nil
+}
+
+func newWorkService() workService {
+	return workService{started: make(chan string, 10), release: make(chan struct{})}
+}
+
+func callWork(rpc JsonRPC, method string) {
+	rpc.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":"1","method":"`+method+`"}`))
+}
+
+func waitQueued(t *testing.T, rpc JsonRPC, n int) {
+	assert.Eventually(t, func() bool { return rpc.SchedulerStats().Queued == n }, time.Second, time.Millisecond)
+}
+
+func TestSchedulerDispatchesByPriority(t *testing.T) {
+	work := newWorkService()
+	rpc := NewJsonRpc(WithScheduler(1), WithMethodPriority("Work.Health", PRIORITY_HIGH))
+	rpc.RegisterWithName(work, "Work")
+
+	go callWork(rpc, "Work.Bulk")
+	assert.Equal(t, "Bulk", <-work.started)
+
+	go callWork(rpc, "Work.Bulk")
+	waitQueued(t, rpc, 1)
+	go callWork(rpc, "Work.Health")
+	waitQueued(t, rpc, 2)
+
+	assert.Equal(t, SchedulerStats{Workers: 1, Running: 1, Queued: 2, QueuedByPriority: map[int]int{PRIORITY_NORMAL: 1, PRIORITY_HIGH: 1}}, rpc.SchedulerStats())
+
+	work.release <- struct{}{}
+	assert.Equal(t, "Health", <-work.started)
+	work.release <- struct{}{}
+	assert.Equal(t, "Bulk", <-work.started)
+	work.release <- struct{}{}
+
+	assert.Eventually(t, func() bool { return rpc.SchedulerStats().Running == 0 }, time.Second, time.Millisecond)
+}
+
+func TestSchedulerCancelledWhileQueued(t *testing.T) {
+	work := newWorkService()
+	rpc := NewJsonRpc(WithScheduler(1))
+	rpc.RegisterWithName(work, "Work")
+
+	go callWork(rpc, "Work.Bulk")
+	<-work.started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rpc.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":"2","method":"Work.Bulk"}`))
+	}()
+	waitQueued(t, rpc, 1)
+
+	cancel()
+	<-done
+	waitQueued(t, rpc, 0)
+
+	//The worker is handed to later calls once the running call finishes
+	work.release <- struct{}{}
+	go callWork(rpc, "Work.Health")
+	assert.Equal(t, "Health", <-work.started)
+	work.release <- struct{}{}
+}
+
+func TestCancelHasHighPriority(t *testing.T) {
+	cfg := newConfig([]Option{WithMethodPriority("Work.Health", PRIORITY_LOW)})
+
+	assert.Equal(t, PRIORITY_HIGH, cfg.methodPriority("rpc.cancel"))
+	assert.Equal(t, PRIORITY_LOW, cfg.methodPriority("Work.Health"))
+	assert.Equal(t, PRIORITY_NORMAL, cfg.methodPriority("Work.Bulk"))
+}
+
+func TestSchedulerStatsWithoutScheduler(t *testing.T) {
+	assert.Equal(t, SchedulerStats{}, NewJsonRpc().SchedulerStats())
+}
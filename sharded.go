@@ -0,0 +1,211 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"runtime"
+	"sync"
+)
+
+const (
+	SHARD_WORKERS    = 4   //Workers of a shard when ShardOptions has no Workers
+	SHARD_QUEUE_SIZE = 256 //Requests waiting in a shard when ShardOptions has no QueueSize
+)
+
+var errShardBusy = errors.New("Server busy. Too many requests waiting on the connection's shard")
+
+type (
+	//ShardOptions size the shards of WithShardedDispatch
+	ShardOptions struct {
+		Shards    int //Independent shards. Defaults to GOMAXPROCS
+		Workers   int //Requests a shard handles at once. Defaults to SHARD_WORKERS
+		QueueSize int //Requests waiting for a worker of a shard. Requests beyond get SERVER_BUSY. Defaults to SHARD_QUEUE_SIZE
+	}
+
+	//Shards the requests of connections are dispatched to. Shards share nothing, so requests of different
+	//connections rarely contend for a queue or a pool
+	shardedDispatcher struct {
+		shards []*dispatchShard
+	}
+
+	//Queue of requests handled by a fixed set of workers, with its own pool of buffers. Workers are started with the
+	//first request and stopped with the server
+	dispatchShard struct {
+		mu        sync.Mutex
+		tasks     chan func() //Nil while the workers are stopped
+		retired   bool        //The settings of the server replaced the shard, so requests get a go routine each
+		workers   int
+		queueSize int
+		buffers   sync.Pool
+	}
+)
+
+// WithShardedDispatch hands the requests of raw sockets to a fixed set of shards instead of a go routine per request,
+// for servers taking very high rates of requests on many cores. Each shard has its own queue, workers and buffers.
+// The requests of a connection all go to the same shard, chosen by the address of the peer, so a connection flooding
+// its shard does not hold up the connections of other shards. Requests beyond the queue of a shard are answered with
+// SERVER_BUSY. Cancellations, pings and stream frames skip the shards, so they get through to busy shards. Long
+// running calls hold a worker of their shard, so methods blocking for long are better served without sharding.
+// Workers stop on Shutdown and when Reconfigure replaces the shards.
+func WithShardedDispatch(opts ShardOptions) Option {
+	if opts.Shards <= 0 {
+		opts.Shards = runtime.GOMAXPROCS(0)
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = SHARD_WORKERS
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = SHARD_QUEUE_SIZE
+	}
+
+	return func(c *config) {
+		if c.shards != nil {
+			c.shards.retire()
+		}
+		c.shards = newShardedDispatcher(opts)
+	}
+}
+
+func newShardedDispatcher(opts ShardOptions) *shardedDispatcher {
+	d := &shardedDispatcher{shards: make([]*dispatchShard, opts.Shards)}
+	for i := range d.shards {
+		shard := &dispatchShard{workers: opts.Workers, queueSize: opts.QueueSize}
+		shard.buffers.New = func() any {
+			return new(bytes.Buffer)
+		}
+		d.shards[i] = shard
+	}
+
+	return d
+}
+
+// Shard of the address of a peer
+func (d *shardedDispatcher) shardFor(addr string) *dispatchShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(addr))
+	return d.shards[hash.Sum32()%uint32(len(d.shards))]
+}
+
+// Stop the workers once they handled the queued requests. They start again with the next request
+func (d *shardedDispatcher) stop() {
+	for _, shard := range d.shards {
+		shard.stop(false)
+	}
+}
+
+// Stop the workers for good, once the shards are replaced
+func (d *shardedDispatcher) retire() {
+	for _, shard := range d.shards {
+		shard.stop(true)
+	}
+}
+
+// Queue the task without waiting. False when the queue is full
+func (sh *dispatchShard) dispatch(task func()) bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.retired {
+		go task()
+		return true
+	}
+	if sh.tasks == nil {
+		sh.tasks = make(chan func(), sh.queueSize)
+		for i := 0; i < sh.workers; i++ {
+			go work(sh.tasks)
+		}
+	}
+
+	select {
+	case sh.tasks <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+func work(tasks chan func()) {
+	for task := range tasks {
+		task()
+	}
+}
+
+func (sh *dispatchShard) stop(retire bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.tasks != nil {
+		close(sh.tasks)
+		sh.tasks = nil
+	}
+	sh.retired = sh.retired || retire
+}
+
+// Get an empty buffer of the shard. Return it with putBuffer once its bytes are no longer referenced
+func (sh *dispatchShard) getBuffer() *bytes.Buffer {
+	return sh.buffers.Get().(*bytes.Buffer)
+}
+
+func (sh *dispatchShard) putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > MAX_POOLED_BUFFER_SIZE {
+		return
+	}
+
+	buf.Reset()
+	sh.buffers.Put(buf)
+}
+
+// Whether the message cancels a request or pings the server. They are handled as they are read, so they are not
+// held up behind the requests of a busy shard
+func isControlMessage(msg []byte) bool {
+	//Cheap check first, as most messages call other methods
+	if !bytes.Contains(msg, []byte(`"`+BUILTIN_SERVICE_NAME+`.`)) && !bytes.Contains(msg, []byte(`"$/`)) {
+		return false
+	}
+
+	var req struct {
+		Method string `json:"method"`
+	}
+	if json.Unmarshal(msg, &req) != nil {
+		return false
+	}
+
+	switch req.Method {
+	case BUILTIN_SERVICE_NAME + ".cancel", CANCEL_REQUEST_METHOD, HEARTBEAT_PING_METHOD:
+		return true
+	}
+	return false
+}
+
+// Encoded SERVER_BUSY responses to the requests of the message. Nil when they are all notifications
+func (rpc *jsonRpcImpl) busyResponse(msg []byte) []byte {
+	singleRequest, batchRequest, err := rpc.decodeRequest(msg)
+	if err != nil {
+		return nil
+	}
+	if singleRequest != nil {
+		batchRequest = []Request{*singleRequest}
+	}
+
+	responses := make([]Response, 0, len(batchRequest))
+	for _, req := range batchRequest {
+		if !req.isNotification() {
+			res := makeErrorResponse(errShardBusy, SERVER_BUSY, nil, req.Id)
+			res.numericId = req.numericId
+			responses = append(responses, res)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+
+	var r []byte
+	if singleRequest != nil {
+		r, _ = rpc.cfg().marshal(&responses[0], false)
+	} else {
+		r, _ = rpc.cfg().marshal(&responses, false)
+	}
+	return r
+}
@@ -0,0 +1,186 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listenTestRpc(t *testing.T, rpc JsonRPC) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	t.Cleanup(func() { l.Close() })
+
+	return l.Addr().String()
+}
+
+func dialTestRpc(t *testing.T, addr string) net.Conn {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestShardedDispatchConn(t *testing.T) {
+	rpc := NewJsonRpc(WithShardedDispatch(ShardOptions{Shards: 2, Workers: 2, QueueSize: 64}))
+	rpc.RegisterWithName(arith{}, "Arith")
+	conn := dialTestRpc(t, listenTestRpc(t, rpc))
+
+	//Messages are decoded into reused buffers, so each response must match its own request
+	const calls = 50
+	go func() {
+		for i := 0; i < calls; i++ {
+			fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":"%d","method":"Arith.Add","params":[%d,1]}`+"\n", i, i)
+		}
+	}()
+
+	reader := bufio.NewReader(conn)
+	seen := make(map[string]bool)
+	for i := 0; i < calls; i++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := &Response{}
+		assert.Nil(t, json.Unmarshal(line, res))
+		id, _ := strconv.Atoi(*res.Id)
+		assert.Equal(t, float64(id+1), *res.Result)
+		seen[*res.Id] = true
+	}
+	assert.Len(t, seen, calls)
+}
+
+func TestShardIsolation(t *testing.T) {
+	srv := blockingService{started: make(chan struct{}, 1)}
+	rpc := NewJsonRpc(WithShardedDispatch(ShardOptions{Shards: 2, Workers: 1, QueueSize: 1}))
+	rpc.RegisterWithName(srv, "Blocking")
+	rpc.RegisterWithName(arith{}, "Arith")
+	shards := rpc.(*jsonRpcImpl).cfg().shards
+	addr := listenTestRpc(t, rpc)
+
+	blocked := dialTestRpc(t, addr)
+	other := dialTestRpc(t, addr)
+	for shards.shardFor(other.LocalAddr().String()) == shards.shardFor(blocked.LocalAddr().String()) {
+		other = dialTestRpc(t, addr)
+	}
+	blockedReader, otherReader := bufio.NewReader(blocked), bufio.NewReader(other)
+
+	//The worker of the shard is held by the first call and its queue by the second
+	blocked.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Blocking.Block","params":[]}` + "\n"))
+	<-srv.started
+	blocked.Write([]byte(`{"jsonrpc":"2.0","id":"2","method":"Blocking.Block","params":[]}` + "\n"))
+	blocked.Write([]byte(`{"jsonrpc":"2.0","id":"3","method":"Arith.Add","params":[1,2]}` + "\n"))
+
+	busy := readTestMessage(t, blocked, blockedReader)
+	assert.Equal(t, "3", busy["id"])
+	assert.Equal(t, float64(SERVER_BUSY), busy["error"].(map[string]any)["code"])
+
+	//Connections of other shards go on
+	other.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}` + "\n"))
+	assert.Equal(t, float64(3), readTestMessage(t, other, otherReader)["result"])
+
+	//Cancellations and pings skip the busy shard
+	blocked.Write([]byte(`{"jsonrpc":"2.0","id":"4","method":"rpc.ping"}` + "\n"))
+	assert.Equal(t, "4", readTestMessage(t, blocked, blockedReader)["id"])
+	blocked.Write([]byte(`{"jsonrpc":"2.0","id":"5","method":"rpc.cancel","params":["1"]}` + "\n"))
+	responses := map[any]map[string]any{}
+	for i := 0; i < 2; i++ {
+		msg := readTestMessage(t, blocked, blockedReader)
+		responses[msg["id"]] = msg
+	}
+	assert.Equal(t, true, responses["5"]["result"])
+	assert.Equal(t, float64(REQUEST_CANCELLED), responses["1"]["error"].(map[string]any)["code"])
+
+	//The queued call got the worker
+	select {
+	case <-srv.started:
+	case <-time.After(time.Second):
+		t.Fatal("Queued call did not start")
+	}
+}
+
+func TestShardedDispatchStops(t *testing.T) {
+	rpc := NewJsonRpc(WithShardedDispatch(ShardOptions{Shards: 1, Workers: 1}))
+	shard := rpc.(*jsonRpcImpl).cfg().shards.shards[0]
+
+	done := make(chan struct{})
+	assert.True(t, shard.dispatch(func() { close(done) }))
+	<-done
+	assert.NotNil(t, shard.tasks)
+
+	//Workers stop with the server and start again with the next request
+	assert.Nil(t, rpc.Shutdown(context.Background()))
+	assert.Nil(t, shard.tasks)
+	done = make(chan struct{})
+	assert.True(t, shard.dispatch(func() { close(done) }))
+	<-done
+
+	//Replaced shards stop for good, requests still reaching them get a go routine each
+	rpc.Reconfigure(WithShardedDispatch(ShardOptions{Shards: 1}))
+	assert.Nil(t, shard.tasks)
+	assert.True(t, shard.retired)
+	assert.NotSame(t, shard, rpc.(*jsonRpcImpl).cfg().shards.shards[0])
+	done = make(chan struct{})
+	assert.True(t, shard.dispatch(func() { close(done) }))
+	<-done
+	assert.Nil(t, shard.tasks)
+}
+
+func TestShardDispatchBusy(t *testing.T) {
+	shard := newShardedDispatcher(ShardOptions{Shards: 1, Workers: 1, QueueSize: 1}).shards[0]
+	defer shard.stop(true)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	assert.True(t, shard.dispatch(func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	assert.True(t, shard.dispatch(func() {}))
+	assert.False(t, shard.dispatch(func() {}))
+	close(release)
+}
+
+func TestShardFor(t *testing.T) {
+	d := newShardedDispatcher(ShardOptions{Shards: 4, Workers: 1, QueueSize: 1})
+
+	//Peers keep their shard
+	assert.Same(t, d.shardFor("10.0.0.1:5000"), d.shardFor("10.0.0.1:5000"))
+
+	used := make(map[*dispatchShard]bool)
+	for i := 0; i < 64; i++ {
+		used[d.shardFor(fmt.Sprintf("10.0.0.%d:5000", i))] = true
+	}
+	assert.Len(t, used, 4)
+}
+
+func TestIsControlMessage(t *testing.T) {
+	assert.True(t, isControlMessage([]byte(`{"jsonrpc":"2.0","id":"1","method":"rpc.cancel","params":["2"]}`)))
+	assert.True(t, isControlMessage([]byte(`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":"2"}}`)))
+	assert.True(t, isControlMessage([]byte(`{"jsonrpc":"2.0","id":"1","method":"rpc.ping"}`)))
+	assert.False(t, isControlMessage([]byte(`{"jsonrpc":"2.0","id":"1","method":"rpc.subscribe"}`)))
+	assert.False(t, isControlMessage([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":["rpc.cancel"]}`)))
+}
+
+func TestWithShardedDispatchDefaults(t *testing.T) {
+	cfg := newConfig([]Option{WithShardedDispatch(ShardOptions{})})
+
+	assert.NotEmpty(t, cfg.shards.shards)
+	assert.Equal(t, SHARD_WORKERS, cfg.shards.shards[0].workers)
+	assert.Equal(t, SHARD_QUEUE_SIZE, cfg.shards.shards[0].queueSize)
+}
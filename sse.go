@@ -0,0 +1,135 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Interval of the comments sent to keep idle SSE connections open through proxies
+const SSE_KEEPALIVE_INTERVAL = 30 * time.Second
+
+// WithSSE serves Server-Sent Events on GET requests to path. Each event carries a notification published with
+// Publish as a JSON-RPC notification. Clients filter the notifications they receive with the method and topic
+// query params, which can be repeated. eg. /events?topic=orders&method=Orders.Created
+func WithSSE(path string) Option {
+	return func(c *config) {
+		c.ssePath = path
+	}
+}
+
+// Serve the SSE endpoint. False is returned when the request is not for the endpoint
+func (rpc *jsonRpcImpl) serveSSE(w http.ResponseWriter, r *http.Request) bool {
+	path := rpc.cfg().ssePath
+	if path == "" || r.Method != http.MethodGet || r.URL.Path != path {
+		return false
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return true
+	}
+
+	filter := newSSEFilter(r)
+	queue := newWriteQueue(
+		rpc.cfg().writeBufferFor(BACKPRESSURE_DROP_OLDEST),
+		func() { rpc.cfg().logger.Printf("SSE client %s is too slow. Notification dropped", r.RemoteAddr) },
+		nil,
+	)
+	unsubscribe := rpc.Subscribe(func(n Notification) {
+		if !filter.matches(n) {
+			return
+		}
+
+		event, err := rpc.cfg().encodeEvent(n)
+		if err != nil {
+			rpc.cfg().logger.Printf("Unable to encode notification %s: %v", n.Method, err)
+			return
+		}
+		queue.push(event)
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(SSE_KEEPALIVE_INTERVAL)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return true
+
+		case <-queue.done:
+			//Disconnected by the backpressure policy
+			return true
+
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+
+		case event := <-queue.messages:
+			w.Write(event)
+		}
+
+		flusher.Flush()
+	}
+}
+
+// Encode the notification as a SSE event named after its topic
+func (c *config) encodeEvent(n Notification) ([]byte, error) {
+	data, err := c.encodeNotification(n)
+	if err != nil {
+		return nil, err
+	}
+
+	var event bytes.Buffer
+	if n.Topic != "" {
+		fmt.Fprintf(&event, "event: %s\n", n.Topic)
+	}
+	//Encoded JSON has no new lines, so the data fits on a single line
+	fmt.Fprintf(&event, "data: %s\n\n", bytes.TrimRight(data, "\n"))
+
+	return event.Bytes(), nil
+}
+
+// Methods and topics a SSE client subscribed to. Empty sets match everything
+type sseFilter struct {
+	methods map[string]bool
+	topics  map[string]bool
+}
+
+func newSSEFilter(r *http.Request) sseFilter {
+	filter := sseFilter{methods: make(map[string]bool), topics: make(map[string]bool)}
+
+	query := r.URL.Query()
+	for _, values := range query["method"] {
+		for _, method := range strings.Split(values, ",") {
+			filter.methods[method] = true
+		}
+	}
+	for _, values := range query["topic"] {
+		for _, topic := range strings.Split(values, ",") {
+			filter.topics[topic] = true
+		}
+	}
+
+	return filter
+}
+
+func (f sseFilter) matches(n Notification) bool {
+	if len(f.methods) > 0 && !f.methods[n.Method] {
+		return false
+	}
+	if len(f.topics) > 0 && !f.topics[n.Topic] {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Connect to the SSE endpoint. The server has subscribed once the headers are received
+func openTestSSE(t *testing.T, url string) *bufio.Reader {
+	res, err := http.Get(url)
+	assert.Nil(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	return bufio.NewReader(res.Body)
+}
+
+// Read the next event up to the blank line ending it
+func readTestEvent(t *testing.T, r *bufio.Reader) string {
+	lines := make([]string, 0)
+	for {
+		line, err := r.ReadString('\n')
+		assert.Nil(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSSENotification(t *testing.T) {
+	rpc := NewJsonRpc(WithSSE("/events"))
+	events := openTestSSE(t, newTestHTTPServer(t, rpc)+"/events")
+
+	rpc.Publish("", "Orders.Created", []any{"order-1"})
+
+	assert.Equal(t, `data: {"method":"Orders.Created","params":["order-1"],"jsonrpc":"2.0"}`, readTestEvent(t, events))
+}
+
+func TestSSEFiltersByTopicAndMethod(t *testing.T) {
+	rpc := NewJsonRpc(WithSSE("/events"))
+	url := newTestHTTPServer(t, rpc)
+	byTopic := openTestSSE(t, url+"/events?topic=orders")
+	byMethod := openTestSSE(t, url+"/events?method=Users.Deleted,Orders.Cancelled")
+
+	rpc.Publish("users", "Users.Created", nil)
+	rpc.Publish("orders", "Orders.Created", nil)
+	rpc.Publish("users", "Users.Deleted", nil)
+
+	assert.Equal(t, "event: orders\n"+`data: {"method":"Orders.Created","params":[],"jsonrpc":"2.0"}`, readTestEvent(t, byTopic))
+	assert.Equal(t, "event: users\n"+`data: {"method":"Users.Deleted","params":[],"jsonrpc":"2.0"}`, readTestEvent(t, byMethod))
+}
+
+func TestSSEUnsubscribesOnDisconnect(t *testing.T) {
+	rpc := NewJsonRpc(WithSSE("/events"))
+	url := newTestHTTPServer(t, rpc)
+
+	hub := rpc.(*jsonRpcImpl).notifications
+	subscribers := func() int {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+
+		return len(hub.subscribers)
+	}
+
+	res, err := http.Get(url + "/events")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, subscribers())
+	res.Body.Close()
+
+	assert.Eventually(t, func() bool { return subscribers() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSubscribe(t *testing.T) {
+	rpc := NewJsonRpc()
+	received := make([]Notification, 0)
+	unsubscribe := rpc.Subscribe(func(n Notification) { received = append(received, n) })
+
+	rpc.Publish("orders", "Orders.Created", 1)
+	unsubscribe()
+	rpc.Publish("orders", "Orders.Created", 2)
+
+	assert.Equal(t, []Notification{{Topic: "orders", Method: "Orders.Created", Params: 1}}, received)
+}
+
+func TestSSEDisabledByDefault(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	assert.NotEqual(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+}
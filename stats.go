@@ -0,0 +1,143 @@
+package jsonrpc2
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Number of latest call durations of each method the latency percentiles are computed from
+const STATS_WINDOW = 1024
+
+type (
+	//Statistics of the calls of a method since the server started, returned by Stats and rpc.stats
+	MethodStats struct {
+		Method    string        `json:"method"`
+		Calls     uint64        `json:"calls"`
+		Errors    uint64        `json:"errors"`    //Calls answered with an error
+		ErrorRate float64       `json:"errorRate"` //Errors divided by calls
+		P50       time.Duration `json:"p50"`       //Latency percentiles of the latest STATS_WINDOW calls, in nanoseconds
+		P95       time.Duration `json:"p95"`
+		P99       time.Duration `json:"p99"`
+	}
+
+	//Statistics of every method called, keyed by method name
+	statsRecorder struct {
+		mu      sync.RWMutex
+		methods map[string]*methodStats
+	}
+
+	methodStats struct {
+		mu        sync.Mutex
+		calls     uint64
+		errors    uint64
+		latencies []time.Duration //Ring of the latest durations
+		next      int
+	}
+)
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{methods: make(map[string]*methodStats)}
+}
+
+// Stats returns the statistics of every method called on the server, sorted by method name
+func (rpc *jsonRpcImpl) Stats() []MethodStats {
+	return rpc.stats.snapshot()
+}
+
+// Record the call in the statistics of its method. Invalid requests and unknown methods are not recorded,
+// so callers can not grow the statistics without bounds
+func (rpc *jsonRpcImpl) recordStats(start time.Time, req Request, res Response) {
+	if req.validate() != nil || (res.Error != nil && res.Error.Code == METHOD_NOT_FOUND) {
+		return
+	}
+
+	rpc.stats.record(req.Method, time.Since(start), res.Error != nil)
+}
+
+func (r *statsRecorder) record(method string, duration time.Duration, failed bool) {
+	r.mu.RLock()
+	m, ok := r.methods[method]
+	r.mu.RUnlock()
+
+	if !ok {
+		r.mu.Lock()
+		if m, ok = r.methods[method]; !ok {
+			m = &methodStats{latencies: make([]time.Duration, 0, STATS_WINDOW)}
+			r.methods[method] = m
+		}
+		r.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if failed {
+		m.errors++
+	}
+
+	if len(m.latencies) < STATS_WINDOW {
+		m.latencies = append(m.latencies, duration)
+	} else {
+		m.latencies[m.next] = duration
+	}
+	m.next = (m.next + 1) % STATS_WINDOW
+}
+
+func (r *statsRecorder) snapshot() []MethodStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make([]MethodStats, 0, len(r.methods))
+	for method, m := range r.methods {
+		stats = append(stats, m.snapshot(method))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Method < stats[j].Method
+	})
+
+	return stats
+}
+
+func (m *methodStats) snapshot(method string) MethodStats {
+	m.mu.Lock()
+	latencies := append([]time.Duration(nil), m.latencies...)
+	stats := MethodStats{Method: method, Calls: m.calls, Errors: m.errors}
+	m.mu.Unlock()
+
+	if stats.Calls > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Calls)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	stats.P50 = percentile(latencies, 50)
+	stats.P95 = percentile(latencies, 95)
+	stats.P99 = percentile(latencies, 99)
+
+	return stats
+}
+
+// Nearest rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+func (rpc *jsonRpcImpl) registerStatsMethod(builtins *service) {
+	builtins.methods["stats"] = reflect.ValueOf(func(ctx context.Context) ([]MethodStats, error, *RpcErrorCode) {
+		return rpc.Stats(), nil, nil
+	})
+}
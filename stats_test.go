@@ -0,0 +1,75 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	rpc := newTestArithRpc()
+
+	callMethod(t, rpc, "Arith.Add", []any{1, 2})
+	callMethod(t, rpc, "Arith.Add", []any{3, 4})
+	callMethod(t, rpc, "Arith.Add", []any{"a", 4})
+	callMethod(t, rpc, "Arith.Missing", nil)
+	callMethod(t, rpc, "Missing.Add", nil)
+
+	stats := rpc.Stats()
+
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "Arith.Add", stats[0].Method)
+	assert.Equal(t, uint64(3), stats[0].Calls)
+	assert.Equal(t, uint64(1), stats[0].Errors)
+	assert.InDelta(t, 1.0/3, stats[0].ErrorRate, 0.001)
+	assert.Greater(t, stats[0].P99, time.Duration(0))
+}
+
+func TestStatsMethod(t *testing.T) {
+	rpc := newTestArithRpc()
+	callMethod(t, rpc, "Arith.Add", []any{1, 2})
+
+	res := callMethod(t, rpc, "rpc.stats", nil)
+
+	raw, _ := json.Marshal(*res.Result)
+	var stats []MethodStats
+	assert.Nil(t, json.Unmarshal(raw, &stats))
+	assert.Equal(t, "Arith.Add", stats[0].Method)
+	assert.Equal(t, uint64(1), stats[0].Calls)
+}
+
+func TestStatsPercentiles(t *testing.T) {
+	recorder := newStatsRecorder()
+	for i := 1; i <= 100; i++ {
+		recorder.record("Arith.Add", time.Duration(i)*time.Millisecond, false)
+	}
+
+	stats := recorder.snapshot()[0]
+
+	assert.Equal(t, 50*time.Millisecond, stats.P50)
+	assert.Equal(t, 95*time.Millisecond, stats.P95)
+	assert.Equal(t, 99*time.Millisecond, stats.P99)
+}
+
+func TestStatsWindow(t *testing.T) {
+	recorder := newStatsRecorder()
+	for i := 0; i < STATS_WINDOW; i++ {
+		recorder.record("Arith.Add", time.Second, false)
+	}
+	for i := 0; i < STATS_WINDOW; i++ {
+		recorder.record("Arith.Add", time.Millisecond, false)
+	}
+
+	stats := recorder.snapshot()[0]
+
+	assert.Equal(t, uint64(2*STATS_WINDOW), stats.Calls)
+	assert.Equal(t, time.Millisecond, stats.P99)
+}
+
+func TestStatsDisabledWithIntrospection(t *testing.T) {
+	rpc := NewJsonRpc(DisableIntrospection())
+
+	assert.Equal(t, METHOD_NOT_FOUND, callMethod(t, rpc, "rpc.stats", nil).Error.Code)
+}
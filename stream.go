@@ -0,0 +1,279 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ListenAndServe listens on the TCP address and serves JSON-RPC over raw sockets.
+// Messages on the connection are JSON values, usually separated by new lines.
+func (rpc *jsonRpcImpl) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return rpc.Serve(l)
+}
+
+// Serve accepts connections on the listener and serves each of them in a go routine.
+// Any listener works, so unix domain sockets and TLS listeners are supported as well.
+// The services are started first.
+func (rpc *jsonRpcImpl) Serve(l net.Listener) error {
+	defer l.Close()
+
+	if err := rpc.Start(context.Background()); err != nil {
+		return err
+	}
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go rpc.ServeConn(conn)
+	}
+}
+
+// ServeConn serves JSON-RPC messages on a single connection until the peer disconnects.
+// Messages are handled concurrently and responses are written in the order they complete.
+func (rpc *jsonRpcImpl) ServeConn(conn net.Conn) {
+	if filter := rpc.cfg().ipFilter; filter != nil && !filter.allowsConn(conn) {
+		conn.Close()
+		return
+	}
+
+	//Clients may switch codec with the first message
+	var negotiable *codecConn
+	if rpc.cfg().codecs != nil {
+		negotiable = newCodecConn(conn)
+		conn = negotiable
+	}
+
+	transport := transportName(conn)
+	inFlight := newInFlightRequests()
+	ctx := withInFlightRequests(context.Background(), inFlight)
+	ctx = withRemoteAddr(ctx, conn.RemoteAddr().String())
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer conn.Close()
+
+	var wg sync.WaitGroup
+
+	//Responses are written from a single go routine so a slow client only fills its own buffer
+	addr := conn.RemoteAddr().String()
+	queue := newWriteQueue(
+		rpc.cfg().writeBufferFor(BACKPRESSURE_BLOCK),
+		func() { rpc.cfg().logger.Printf("Client %s is too slow. Message dropped", addr) },
+		func() {
+			cancel()
+			conn.Close()
+		},
+	)
+	stopWriting := make(chan struct{})
+	written := make(chan struct{})
+	go rpc.writeConn(conn, queue, stopWriting, written)
+
+	write := func(msg []byte) {
+		queue.pushResponse(msg)
+	}
+
+	//Events of the subscriptions made on the connection share its queue, and only they are dropped by its policy
+	subs := newConnSubscriptions(func(msg []byte) {
+		queue.push(msg)
+	})
+	ctx = withSubscriptions(ctx, subs)
+
+	//Frames of streaming calls are handed to their stream in the order they are read
+	streams := newConnStreams(write, func(frame streamFrame) ([]byte, error) {
+		return rpc.cfg().encodeStreamFrame(frame)
+	})
+	ctx = withStreams(ctx, streams)
+
+	//Closing the connection ends the decoding below, as when the peer disconnects
+	served := &servedConn{notify: write, refuse: subs.refuse, close: func() {
+		cancel()
+		conn.Close()
+	}, stopReading: func() {
+		//Failing the read ends the decoding below without dropping the queued messages
+		conn.SetReadDeadline(time.Now())
+	}}
+	served.transport, served.remoteAddr, served.connectedAt = transport, addr, time.Now()
+	served.inFlight, served.subs = inFlight, subs
+	if age := rpc.cfg().maxConnectionAge; age != nil {
+		served.expireAfter(*age)
+	}
+	rpc.conns.add(served)
+
+	var live *liveness
+	if heartbeat := rpc.cfg().heartbeat; heartbeat != nil {
+		live = newLiveness(*heartbeat)
+		go live.watch(func() { write(pingNotification) }, func() {
+			cancel()
+			conn.Close()
+		})
+	}
+
+	//Requests of the connection share a shard, whose buffers its messages are decoded into
+	var shard *dispatchShard
+	if shards := rpc.cfg().shards; shards != nil {
+		shard = shards.shardFor(addr)
+	}
+
+	decoder := json.NewDecoder(conn)
+	for {
+		var msg json.RawMessage
+		if shard != nil {
+			msg = shard.getBuffer().Bytes()
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				//The stream can not be recovered after invalid json
+				res := makeErrorResponse(errors.New("Unable to decode request"), PARSE_ERROR, nil, nil)
+				r, _ := json.Marshal(&res)
+				write(r)
+			} else if !errors.Is(err, io.EOF) {
+				//Connection is broken. Abort in flight calls
+				cancel()
+			}
+			break
+		}
+
+		if live != nil && live.received(msg) {
+			continue
+		}
+		if streams.receive(msg) {
+			continue
+		}
+
+		if negotiable != nil {
+			first := negotiable
+			negotiable = nil
+			if isNegotiation(msg) {
+				//Answered before reading on, since the following messages are in the negotiated codec
+				if res := rpc.negotiate(first, msg); res != nil {
+					write(res)
+				}
+				continue
+			}
+		}
+
+		if shard != nil {
+			//Pongs and stream frames were handled above, cancellations and pings are answered right away
+			if isControlMessage(msg) {
+				if res := rpc.HandleMessage(ctx, msg); res != nil {
+					write(res)
+				}
+				continue
+			}
+
+			wg.Add(1)
+			if !shard.dispatch(func() {
+				defer wg.Done()
+				defer shard.putBuffer(bytes.NewBuffer(msg[:0]))
+
+				if res := rpc.HandleMessage(ctx, msg); res != nil {
+					write(res)
+				}
+			}) {
+				wg.Done()
+				//Readers never wait for a shard, so its queue does not hold up the control messages behind
+				if res := rpc.busyResponse(msg); res != nil {
+					write(res)
+				}
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(msg json.RawMessage) {
+			defer wg.Done()
+
+			if res := rpc.HandleMessage(ctx, msg); res != nil {
+				write(res)
+			}
+		}(msg)
+	}
+
+	rpc.conns.remove(served)
+	subs.close()
+	wg.Wait()
+	close(stopWriting)
+	<-written
+
+	if live != nil {
+		if err := live.close(); err != nil && live.opts.OnDead != nil {
+			live.opts.OnDead(addr, err)
+		}
+	}
+}
+
+// Write the queued messages to the connection. Once stop is closed the remaining messages are written
+func (rpc *jsonRpcImpl) writeConn(conn net.Conn, queue *writeQueue, stop chan struct{}, written chan struct{}) {
+	defer close(written)
+
+	write := func(msg []byte) bool {
+		if _, err := conn.Write(append(msg, '\n')); err != nil {
+			queue.close()
+			return false
+		}
+		return true
+	}
+
+	for {
+		//Responses go first, so the response of a subscription is written before its events
+		select {
+		case msg := <-queue.responses:
+			if !write(msg) {
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case msg := <-queue.responses:
+			if !write(msg) {
+				return
+			}
+
+		case msg := <-queue.messages:
+			if !write(msg) {
+				return
+			}
+
+		case <-queue.done:
+			return
+
+		case <-stop:
+			for _, buffer := range []chan []byte{queue.responses, queue.messages} {
+				if !drainTo(buffer, write) {
+					return
+				}
+			}
+			return
+		}
+	}
+}
+
+// Write the buffered messages. False when a write failed
+func drainTo(buffer chan []byte, write func(msg []byte) bool) bool {
+	for {
+		select {
+		case msg := <-buffer:
+			if !write(msg) {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
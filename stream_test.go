@@ -0,0 +1,88 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeConn(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	client, server := net.Pipe()
+	go rpc.ServeConn(server)
+	defer client.Close()
+
+	go client.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}` + "\n"))
+
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &Response{}
+	if err := json.Unmarshal(line, res); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "1", *res.Id)
+	assert.Equal(t, float64(3), *res.Result)
+}
+
+func TestServeConnBatchAndNotification(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	//The notification has no response, so the batch response is the first line read
+	conn.Write([]byte(`{"jsonrpc":"2.0","method":"Arith.Add","params":[1,2]}` + "\n"))
+	conn.Write([]byte(`[{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]},{"jsonrpc":"2.0","id":"2","method":"Arith.Add","params":[2,2]}]` + "\n"))
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := []Response{}
+	if err := json.Unmarshal(line, &responses); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, responses, 2)
+}
+
+func TestServeConnInvalidJson(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	client, server := net.Pipe()
+	go rpc.ServeConn(server)
+	defer client.Close()
+
+	go client.Write([]byte(`{"jsonrpc":` + "}\n"))
+
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &Response{}
+	if err := json.Unmarshal(line, res); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, PARSE_ERROR, res.Error.Code)
+}
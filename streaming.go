@@ -0,0 +1,610 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+const (
+	//Method of the notifications carrying the frames of streaming calls, in both directions
+	STREAM_METHOD = "rpc.stream"
+	//Values either side may send before the receiver grants more credit
+	STREAM_WINDOW_SIZE = 16
+)
+
+var (
+	errStreamOverflow = &RpcError{Code: INVALID_REQUEST, Message: "Stream values were sent beyond the granted credit"}
+	errStreamEnded    = errors.New("Stream ended")
+	errStreamRefused  = errors.New("Method does not receive a stream")
+	errSendClosed     = errors.New("Stream is closed for sending")
+)
+
+type (
+	//Frame of a stream, sent as the params of an rpc.stream notification. A frame carries a value, grants credit
+	//for more values, or ends the values of its sender
+	streamFrame struct {
+		Stream string          `json:"stream"`           //Id of the call that opened the stream
+		Data   json.RawMessage `json:"data,omitempty"`   //Value sent on the stream
+		Credit int             `json:"credit,omitempty"` //Number of values the sender of the frame accepts on top
+		End    bool            `json:"end,omitempty"`    //The sender of the frame sends no more values
+	}
+
+	//Values a side may send, as granted by the receiver
+	streamCredit struct {
+		mu    sync.Mutex
+		n     int
+		added chan struct{}
+	}
+
+	//Streams of the calls made on a persistent connection, keyed by call id
+	connStreams struct {
+		mu     sync.Mutex
+		write  func(msg []byte)
+		encode func(frame streamFrame) ([]byte, error)
+		byId   map[string]*serverStream
+	}
+
+	connStreamsKey struct{}
+
+	//Stream of a call to a streaming method on the server
+	serverStream struct {
+		id      string
+		streams *connStreams
+		frames  chan streamFrame //Values of the client, up to the credit granted, and its end
+		credit  *streamCredit    //Values the client accepts
+		fail    context.CancelCauseFunc
+	}
+
+	//Call to a streaming method made by a client
+	clientStreams struct {
+		mu   sync.Mutex
+		byId map[string]*ClientStream
+	}
+
+	//ClientStream is a call to a streaming method. Values are sent to the method and received from it while it runs
+	ClientStream struct {
+		client *Client
+		id     string
+		values chan json.RawMessage //Values of the server, up to the credit granted
+		credit *streamCredit        //Values the server accepts
+
+		mu        sync.Mutex
+		opened    chan struct{} //Closed once the server granted credit or refused values
+		refused   bool
+		sendEnded bool
+		consumed  int //Values received since credit was last granted
+
+		done chan struct{} //Closed once the call returned
+		err  error
+	}
+
+	//Channels of a streaming method: the values it sends, and the values it receives when it takes a channel last
+	streamShape struct {
+		out reflect.Type
+		in  reflect.Type
+	}
+)
+
+func newStreamCredit(n int) *streamCredit {
+	return &streamCredit{n: n, added: make(chan struct{}, 1)}
+}
+
+// Grant n more values
+func (c *streamCredit) add(n int) {
+	c.mu.Lock()
+	c.n += n
+	c.mu.Unlock()
+
+	select {
+	case c.added <- struct{}{}:
+	default:
+	}
+}
+
+// Wait for the credit of one value and take it
+func (c *streamCredit) take(ctx context.Context, done <-chan struct{}) error {
+	for {
+		c.mu.Lock()
+		if c.n > 0 {
+			c.n--
+			c.mu.Unlock()
+			return nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.added:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-done:
+			return errStreamEnded
+		}
+	}
+}
+
+// Shape of the method when it streams, ie. its result is a receive channel. It may take a receive channel last
+func streamShapeOf(methodType reflect.Type) (streamShape, bool) {
+	isRecvChan := func(t reflect.Type) bool {
+		return t.Kind() == reflect.Chan && t.ChanDir() == reflect.RecvDir
+	}
+
+	if methodType.NumOut() == 0 || !isRecvChan(methodType.Out(0)) {
+		return streamShape{}, false
+	}
+
+	shape := streamShape{out: methodType.Out(0).Elem()}
+	if n := methodType.NumIn(); n > 1 && !methodType.IsVariadic() && isRecvChan(methodType.In(n-1)) {
+		shape.in = methodType.In(n - 1).Elem()
+	}
+
+	return shape, true
+}
+
+// Number of params taken by the stream rather than the request
+func (s streamShape) params() int {
+	if s.in == nil {
+		return 0
+	}
+
+	return 1
+}
+
+func newConnStreams(write func(msg []byte), encode func(frame streamFrame) ([]byte, error)) *connStreams {
+	return &connStreams{write: write, encode: encode, byId: make(map[string]*serverStream)}
+}
+
+func withStreams(ctx context.Context, streams *connStreams) context.Context {
+	return context.WithValue(ctx, connStreamsKey{}, streams)
+}
+
+// Encode the rpc.stream notification carrying the frame
+func (c *config) encodeStreamFrame(frame streamFrame) ([]byte, error) {
+	req := Request{Jsonrpc: RPC_VERSION, Method: STREAM_METHOD, Params: frame}
+	return c.marshal(&req, false)
+}
+
+// Decode msg when it is an rpc.stream notification
+func decodeStreamFrame(msg []byte) (streamFrame, bool) {
+	if !bytes.Contains(msg, []byte(STREAM_METHOD)) {
+		return streamFrame{}, false
+	}
+
+	var notification struct {
+		Id     *string     `json:"id"`
+		Method string      `json:"method"`
+		Params streamFrame `json:"params"`
+	}
+	if err := json.Unmarshal(msg, &notification); err != nil || notification.Id != nil || notification.Method != STREAM_METHOD {
+		return streamFrame{}, false
+	}
+
+	return notification.Params, true
+}
+
+// Hand the frame of the client to its stream. False when msg is not a frame. Frames of streams that ended are dropped
+func (s *connStreams) receive(msg []byte) bool {
+	frame, ok := decodeStreamFrame(msg)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	stream, ok := s.byId[frame.Stream]
+	s.mu.Unlock()
+
+	if ok {
+		stream.receive(frame)
+	}
+
+	return true
+}
+
+// Open the stream of the call with the id on the connection of the context. The returned context is cancelled
+// when the client breaks the protocol of the stream
+func openStream(ctx context.Context, id *string, shape streamShape) (*serverStream, context.Context, error) {
+	streams, ok := ctx.Value(connStreamsKey{}).(*connStreams)
+	if !ok {
+		return nil, ctx, errors.New("Streams need a persistent connection")
+	}
+	if id == nil {
+		return nil, ctx, errors.New("Streams need a request id")
+	}
+
+	ctx, fail := context.WithCancelCause(ctx)
+	stream := &serverStream{
+		id:      *id,
+		streams: streams,
+		frames:  make(chan streamFrame, STREAM_WINDOW_SIZE+1),
+		credit:  newStreamCredit(STREAM_WINDOW_SIZE),
+		fail:    fail,
+	}
+
+	streams.mu.Lock()
+	if _, ok := streams.byId[*id]; ok {
+		streams.mu.Unlock()
+		fail(nil)
+		return nil, ctx, errors.New(fmt.Sprintf("Stream %s is already open", *id))
+	}
+	streams.byId[*id] = stream
+	streams.mu.Unlock()
+
+	//The client sends values once they are granted, so it never sends them before the stream is open
+	if shape.in != nil {
+		stream.send(streamFrame{Credit: STREAM_WINDOW_SIZE})
+	} else {
+		stream.send(streamFrame{End: true})
+	}
+
+	return stream, ctx, nil
+}
+
+func (s *serverStream) send(frame streamFrame) {
+	frame.Stream = s.id
+	msg, err := s.streams.encode(frame)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	s.streams.write(msg)
+}
+
+func (s *serverStream) receive(frame streamFrame) {
+	if frame.Credit > 0 {
+		s.credit.add(frame.Credit)
+		return
+	}
+
+	select {
+	case s.frames <- frame:
+	default:
+		s.fail(errStreamOverflow)
+	}
+}
+
+// Stop receiving frames once the call returned
+func (s *serverStream) close() {
+	s.streams.mu.Lock()
+	if s.streams.byId[s.id] == s {
+		delete(s.streams.byId, s.id)
+	}
+	s.streams.mu.Unlock()
+
+	s.fail(nil)
+}
+
+// Channel passed to the method, receiving the values of the client decoded like params. It is closed once the
+// client ends its values. Credit is granted as the method receives them
+func (s *serverStream) values(ctx context.Context, elem reflect.Type) reflect.Value {
+	ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, elem), 0)
+	done := reflect.ValueOf(ctx.Done())
+
+	go func() {
+		defer ch.Close()
+
+		received := 0
+		for {
+			var frame streamFrame
+			select {
+			case frame = <-s.frames:
+			case <-ctx.Done():
+				return
+			}
+			if frame.End {
+				return
+			}
+
+			var arg any
+			err := json.Unmarshal(frame.Data, &arg)
+			value := reflect.Value{}
+			if err == nil {
+				value, err = paramValue(arg, elem)
+			}
+			if err != nil {
+				s.fail(&RpcError{Code: INVALID_PARAMS, Message: err.Error()})
+				return
+			}
+
+			chosen, _, _ := reflect.Select([]reflect.SelectCase{
+				{Dir: reflect.SelectSend, Chan: ch, Send: value},
+				{Dir: reflect.SelectRecv, Chan: done},
+			})
+			if chosen == 1 {
+				return
+			}
+
+			//Granted in halves of the window so the client rarely waits, without a frame per value
+			received++
+			if received >= STREAM_WINDOW_SIZE/2 {
+				s.send(streamFrame{Credit: received})
+				received = 0
+			}
+		}
+	}()
+
+	return ch
+}
+
+// Send the values of the method to the client as they are granted, until the method closes its channel
+func (s *serverStream) sendValues(ctx context.Context, out reflect.Value) error {
+	if out.IsNil() {
+		return nil
+	}
+
+	done := reflect.ValueOf(ctx.Done())
+	for {
+		chosen, value, ok := reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: out},
+			{Dir: reflect.SelectRecv, Chan: done},
+		})
+		if chosen == 1 {
+			return context.Cause(ctx)
+		}
+		if !ok {
+			//The method may close its channel because the stream failed
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			return nil
+		}
+
+		if err := s.credit.take(ctx, nil); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value.Interface())
+		if err != nil {
+			return err
+		}
+		s.send(streamFrame{Data: data})
+	}
+}
+
+// Code of the error ending a stream. Values of the client that break the protocol or can not be decoded fail the
+// stream with an *RpcError
+func streamErrorCode(err error) RpcErrorCode {
+	var rpcErr *RpcError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code
+	}
+
+	return INTERNAL_ERROR
+}
+
+// Stream calls method with params, a method whose result is a receive channel, and streams values both ways while it
+// runs: Send delivers values to the channel the method takes last, and Recv receives the values the method sends.
+// Each side sends up to STREAM_WINDOW_SIZE values before the receiver grants more, so a slow side holds the other
+// back rather than buffering without bound. The call ends with the context, or once the method closed its channel.
+// Streams need a persistent connection, eg. NewConnClient or NewReconnectingClient.
+func (c *Client) Stream(ctx context.Context, method string, params any) (*ClientStream, error) {
+	streams, err := c.streamsOf()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ClientStream{
+		client: c,
+		id:     string(c.generateID()),
+		values: make(chan json.RawMessage, STREAM_WINDOW_SIZE),
+		credit: newStreamCredit(0),
+		opened: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	//Added before the call so no frame of the server is missed
+	streams.mu.Lock()
+	streams.byId[s.id] = s
+	streams.mu.Unlock()
+
+	go func() {
+		err := c.call(ctx, s.id, method, params, nil)
+
+		streams.mu.Lock()
+		delete(streams.byId, s.id)
+		streams.mu.Unlock()
+
+		s.err = err
+		close(s.done)
+	}()
+
+	return s, nil
+}
+
+// Streams of the client, watching the transport the first time
+func (c *Client) streamsOf() (*clientStreams, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.watchTransport() {
+		return nil, errors.New("Streams need a persistent connection")
+	}
+	if c.streams == nil {
+		c.streams = &clientStreams{byId: make(map[string]*ClientStream)}
+	}
+
+	return c.streams, nil
+}
+
+// Hand the frame of the server to its stream. False when msg is not a frame
+func (s *clientStreams) receive(msg []byte) bool {
+	frame, ok := decodeStreamFrame(msg)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	stream, ok := s.byId[frame.Stream]
+	s.mu.Unlock()
+
+	if ok {
+		stream.receive(frame)
+	}
+
+	return true
+}
+
+func (s *ClientStream) receive(frame streamFrame) {
+	switch {
+	case frame.Credit > 0:
+		s.open(false)
+		s.credit.add(frame.Credit)
+
+	case frame.End:
+		s.open(true)
+
+	default:
+		//Values beyond the credit granted are dropped
+		select {
+		case s.values <- frame.Data:
+		default:
+		}
+	}
+}
+
+// Mark the stream as open, refusing values when the method does not receive a stream
+func (s *ClientStream) open(refused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.opened:
+	default:
+		s.refused = refused
+		close(s.opened)
+	}
+}
+
+// Wait for the server to open the stream, or refuse values
+func (s *ClientStream) waitOpen(ctx context.Context) error {
+	select {
+	case <-s.opened:
+		return nil
+	case <-s.done:
+		return errStreamEnded
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Send a value to the method. It waits while the method has not granted credit for more values
+func (s *ClientStream) Send(ctx context.Context, value any) error {
+	if err := s.waitOpen(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	refused, sendEnded := s.refused, s.sendEnded
+	s.mu.Unlock()
+	if refused {
+		return errStreamRefused
+	}
+	if sendEnded {
+		return errSendClosed
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := s.credit.take(ctx, s.done); err != nil {
+		return err
+	}
+
+	return s.sendFrame(ctx, streamFrame{Data: data})
+}
+
+// CloseSend ends the values sent to the method, closing the channel it receives them on
+func (s *ClientStream) CloseSend() error {
+	ctx := context.Background()
+	if err := s.waitOpen(ctx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	refused, sendEnded := s.refused, s.sendEnded
+	s.sendEnded = true
+	s.mu.Unlock()
+	if refused || sendEnded {
+		return nil
+	}
+
+	return s.sendFrame(ctx, streamFrame{End: true})
+}
+
+// Recv decodes the next value of the method into result. It returns io.EOF once the method closed its channel, or
+// the error the call ended with
+func (s *ClientStream) Recv(ctx context.Context, result any) error {
+	select {
+	case data := <-s.values:
+		return s.decode(data, result)
+	case <-s.done:
+		//Values are received before the response ending the call
+		select {
+		case data := <-s.values:
+			return s.decode(data, result)
+		default:
+		}
+		if s.err != nil {
+			return s.err
+		}
+		return io.EOF
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Decode a received value, granting credit once half of the window was received
+func (s *ClientStream) decode(data json.RawMessage, result any) error {
+	s.mu.Lock()
+	s.consumed++
+	grant := 0
+	if s.consumed >= STREAM_WINDOW_SIZE/2 {
+		grant, s.consumed = s.consumed, 0
+	}
+	s.mu.Unlock()
+
+	if grant > 0 {
+		select {
+		case <-s.done:
+		default:
+			if err := s.sendFrame(context.Background(), streamFrame{Credit: grant}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, result)
+}
+
+// Close cancels the call on the server unless it already returned
+func (s *ClientStream) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+
+	var cancelled bool
+	return s.client.Call(context.Background(), BUILTIN_SERVICE_NAME+".cancel", []any{s.id}, &cancelled)
+}
+
+// Write the frame on the transport of the client. Frames are part of the stream protocol, so they skip middleware
+func (s *ClientStream) sendFrame(ctx context.Context, frame streamFrame) error {
+	frame.Stream = s.id
+	msg, err := json.Marshal(Request{Jsonrpc: RPC_VERSION, Method: STREAM_METHOD, Params: frame})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.transport.RoundTrip(ctx, msg, true)
+	return err
+}
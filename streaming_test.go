@@ -0,0 +1,206 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type streamer struct {
+	sent *atomic.Int64
+}
+
+func (streamer) Upper(ctx context.Context, prefix string, in <-chan string) (<-chan string, error, *RpcErrorCode) {
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for value := range in {
+			select {
+			case out <- prefix + strings.ToUpper(value):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil, nil
+}
+
+func (s streamer) Tail(ctx context.Context, n int) (<-chan int, error, *RpcErrorCode) {
+	if n < 0 {
+		code := INVALID_PARAMS
+		return nil, errors.New("Count must be positive"), &code
+	}
+
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for i := 0; n == 0 || i < n; i++ {
+			select {
+			case out <- i:
+				if s.sent != nil {
+					s.sent.Add(1)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil, nil
+}
+
+func (streamer) Sum(ctx context.Context, in <-chan float64) (<-chan float64, error, *RpcErrorCode) {
+	out := make(chan float64, 1)
+	go func() {
+		defer close(out)
+		var sum float64
+		for value := range in {
+			sum += value
+		}
+		out <- sum
+	}()
+
+	return out, nil, nil
+}
+
+func newTestStreamClient(sent *atomic.Int64) *Client {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(streamer{sent: sent}, "Streamer")
+
+	return newTestSubscriptionClient(rpc)
+}
+
+func TestBidirectionalStream(t *testing.T) {
+	client := newTestStreamClient(nil)
+	defer client.Close()
+
+	stream, err := client.Stream(context.Background(), "Streamer.Upper", []any{"> "})
+	assert.Nil(t, err)
+
+	//More values than the window, so both sides wait for credit
+	sendErr := make(chan error, 1)
+	go func() {
+		for i := 0; i < 3*STREAM_WINDOW_SIZE; i++ {
+			if err := stream.Send(context.Background(), "line"); err != nil {
+				sendErr <- err
+				return
+			}
+		}
+		sendErr <- stream.CloseSend()
+	}()
+
+	received := 0
+	for {
+		var line string
+		err := stream.Recv(context.Background(), &line)
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, "> LINE", line)
+		received++
+	}
+
+	assert.Nil(t, <-sendErr)
+	assert.Equal(t, 3*STREAM_WINDOW_SIZE, received)
+}
+
+func TestServerStream(t *testing.T) {
+	client := newTestStreamClient(nil)
+	defer client.Close()
+
+	stream, err := client.Stream(context.Background(), "Streamer.Tail", []any{50})
+	assert.Nil(t, err)
+	assert.Equal(t, errStreamRefused, stream.Send(context.Background(), 1))
+	assert.Nil(t, stream.CloseSend())
+
+	for i := 0; i < 50; i++ {
+		var value int
+		assert.Nil(t, stream.Recv(context.Background(), &value))
+		assert.Equal(t, i, value)
+	}
+	assert.Equal(t, io.EOF, stream.Recv(context.Background(), nil))
+}
+
+func TestClientStream(t *testing.T) {
+	client := newTestStreamClient(nil)
+	defer client.Close()
+
+	stream, err := client.Stream(context.Background(), "Streamer.Sum", nil)
+	assert.Nil(t, err)
+	for _, value := range []float64{1, 2, 3.5} {
+		assert.Nil(t, stream.Send(context.Background(), value))
+	}
+	assert.Nil(t, stream.CloseSend())
+	assert.Equal(t, errSendClosed, stream.Send(context.Background(), 4))
+
+	var sum float64
+	assert.Nil(t, stream.Recv(context.Background(), &sum))
+	assert.Equal(t, 6.5, sum)
+	assert.Equal(t, io.EOF, stream.Recv(context.Background(), nil))
+}
+
+func TestStreamFlowControl(t *testing.T) {
+	var sent atomic.Int64
+	client := newTestStreamClient(&sent)
+	defer client.Close()
+
+	stream, err := client.Stream(context.Background(), "Streamer.Tail", []any{0})
+	assert.Nil(t, err)
+
+	//Without receiving, the server sends a window of values and the method waits
+	time.Sleep(100 * time.Millisecond)
+	assert.LessOrEqual(t, sent.Load(), int64(STREAM_WINDOW_SIZE+1))
+
+	for i := 0; i < 2*STREAM_WINDOW_SIZE; i++ {
+		assert.Nil(t, stream.Recv(context.Background(), nil))
+	}
+	assert.Greater(t, sent.Load(), int64(STREAM_WINDOW_SIZE+1))
+
+	//Closing cancels the endless call
+	assert.Nil(t, stream.Close())
+	var err2 error
+	for err2 == nil {
+		err2 = stream.Recv(context.Background(), nil)
+	}
+	var rpcErr *RpcError
+	assert.True(t, errors.As(err2, &rpcErr))
+	assert.Equal(t, REQUEST_CANCELLED, rpcErr.Code)
+}
+
+func TestStreamErrors(t *testing.T) {
+	client := newTestStreamClient(nil)
+	defer client.Close()
+
+	//Errors returned by the method end the call before it streams
+	stream, _ := client.Stream(context.Background(), "Streamer.Tail", []any{-1})
+	var rpcErr *RpcError
+	assert.True(t, errors.As(stream.Recv(context.Background(), nil), &rpcErr))
+	assert.Equal(t, "Count must be positive", rpcErr.Message)
+
+	//Values that do not decode fail the stream
+	stream, _ = client.Stream(context.Background(), "Streamer.Sum", nil)
+	assert.Nil(t, stream.Send(context.Background(), "ten"))
+	assert.True(t, errors.As(stream.Recv(context.Background(), nil), &rpcErr))
+	assert.Equal(t, INVALID_PARAMS, rpcErr.Code)
+}
+
+func TestStreamNeedsPersistentConnection(t *testing.T) {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(streamer{}, "Streamer")
+
+	client := NewHTTPClient(newTestHTTPServer(t, rpc))
+	_, err := client.Stream(context.Background(), "Streamer.Tail", []any{1})
+	assert.Equal(t, "Streams need a persistent connection", err.Error())
+
+	res := callMethod(t, rpc, "Streamer.Tail", []any{1})
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+	assert.Equal(t, "Streams need a persistent connection", res.Error.Message)
+}
@@ -0,0 +1,109 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NewStub returns a T whose func fields call the methods of the service, so callers depend on a Go type instead of
+// method names. Go can not implement an interface at runtime, so T is a struct of func fields, eg.
+//
+//	type ArithClient struct {
+//		Add    func(ctx context.Context, a, b int) (int, error)
+//		Notify func(ctx context.Context, msg string) error `rpc:"Log,notify"`
+//	}
+//
+// Each field calls the method of its name on the service, or the method named by its rpc tag. Fields tagged
+// rpc:"-", unexported fields and fields that are not funcs are left alone. Funcs take a context.Context first,
+// then the positional params, and return the decoded result and an error, or an error only when the result is
+// dropped. The notify option sends a notification instead of a call. Errors returned by the server are *RpcError.
+func NewStub[T any](c *Client, service string) (T, error) {
+	var stub T
+	v := reflect.ValueOf(&stub).Elem()
+	if v.Kind() != reflect.Struct {
+		return stub, errors.New(fmt.Sprintf("NewStub needs a struct of funcs, got %s", v.Type()))
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || field.Type.Kind() != reflect.Func {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("rpc"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		method := name
+		if service != "" {
+			method = service + "." + name
+		}
+
+		if err := checkStubFunc(field.Name, field.Type); err != nil {
+			return stub, err
+		}
+		v.Field(i).Set(reflect.MakeFunc(field.Type, stubCall(c, method, field.Type, opts == "notify")))
+	}
+
+	return stub, nil
+}
+
+// Check the func of the field takes a context first and returns an error last, with at most a result before it
+func checkStubFunc(name string, t reflect.Type) error {
+	contextType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+
+	switch {
+	case t.IsVariadic():
+		return errors.New(fmt.Sprintf("Stub func %s can not be variadic", name))
+	case t.NumIn() == 0 || t.In(0) != contextType:
+		return errors.New(fmt.Sprintf("Stub func %s must take a context.Context first", name))
+	case t.NumOut() == 0 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType:
+		return errors.New(fmt.Sprintf("Stub func %s must return an error, optionally after a result", name))
+	}
+
+	return nil
+}
+
+// Body of a stub func. The params are sent in order and the result decoded into the first result of the func
+func stubCall(c *Client, method string, t reflect.Type, notify bool) func(args []reflect.Value) []reflect.Value {
+	return func(args []reflect.Value) []reflect.Value {
+		ctx, _ := args[0].Interface().(context.Context)
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		params := make([]any, len(args)-1)
+		for i, arg := range args[1:] {
+			params[i] = arg.Interface()
+		}
+
+		var result reflect.Value
+		var err error
+		if t.NumOut() == 2 {
+			result = reflect.New(t.Out(0))
+		}
+		switch {
+		case notify:
+			err = c.Notify(ctx, method, params)
+		case result.IsValid():
+			err = c.Call(ctx, method, params, result.Interface())
+		default:
+			err = c.Call(ctx, method, params, nil)
+		}
+
+		//Typed as error, which the func returns, rather than as the error value
+		errValue := reflect.ValueOf(&err).Elem()
+		if !result.IsValid() {
+			return []reflect.Value{errValue}
+		}
+
+		return []reflect.Value{result.Elem(), errValue}
+	}
+}
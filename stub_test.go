@@ -0,0 +1,72 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	arithStub struct {
+		Add      func(ctx context.Context, a, b float64) (int, error)
+		Fail     func(ctx context.Context) (*int, error)       `rpc:"ErrorMethod"`
+		Check    func(ctx context.Context, a, b float64) error `rpc:"Add"`
+		Log      func(ctx context.Context, a, b float64) error `rpc:"Add,notify"`
+		Internal func(ctx context.Context) error               `rpc:"-"`
+		Version  string
+	}
+
+	//Interface callers depend on, implemented on top of the stub
+	adder interface {
+		Add(ctx context.Context, a, b float64) (int, error)
+	}
+
+	arithAdder struct {
+		stub arithStub
+	}
+)
+
+func (a arithAdder) Add(ctx context.Context, x, y float64) (int, error) {
+	return a.stub.Add(ctx, x, y)
+}
+
+func TestNewStub(t *testing.T) {
+	client := NewInProcessClient(newTestArithRpc())
+	stub, err := NewStub[arithStub](client, "Arith")
+	assert.Nil(t, err)
+
+	var calc adder = arithAdder{stub: stub}
+	sum, err := calc.Add(context.Background(), 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, sum)
+
+	assert.Nil(t, stub.Check(context.Background(), 1, 2))
+	assert.Nil(t, stub.Log(context.Background(), 1, 2))
+	assert.Nil(t, stub.Internal)
+
+	result, err := stub.Fail(context.Background())
+	assert.Nil(t, result)
+	var rpcErr *RpcError
+	assert.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, INTERNAL_ERROR, rpcErr.Code)
+	assert.Equal(t, "Some error here", rpcErr.Message)
+}
+
+func TestNewStubInvalid(t *testing.T) {
+	client := NewInProcessClient(newTestArithRpc())
+
+	_, err := NewStub[adder](client, "Arith")
+	assert.Equal(t, "NewStub needs a struct of funcs, got jsonrpc2.adder", err.Error())
+
+	_, err = NewStub[struct {
+		Add func(a, b float64) (int, error)
+	}](client, "Arith")
+	assert.Equal(t, "Stub func Add must take a context.Context first", err.Error())
+
+	_, err = NewStub[struct {
+		Add func(ctx context.Context, a, b float64) int
+	}](client, "Arith")
+	assert.Equal(t, "Stub func Add must return an error, optionally after a result", err.Error())
+}
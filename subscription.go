@@ -0,0 +1,515 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	//Method of the notifications delivering the events of a subscription
+	SUBSCRIPTION_METHOD = "rpc.subscription"
+	//Events a client keeps for each subscription until they are received. The oldest are dropped beyond that
+	SUBSCRIPTION_BUFFER_SIZE = 64
+)
+
+type (
+	//Unsubscribe ends a subscription and closes its channel
+	Unsubscribe func() error
+
+	//Subscriptions made on a persistent connection. They end with the connection
+	connSubscriptions struct {
+		mu     sync.Mutex
+		write  func(msg []byte)
+		stops  map[string]func()
+		lastId int
+		closed bool
+	}
+
+	subscriptionsKey struct{}
+
+	//Implements the built-in subscription methods
+	subscriptionMethods struct {
+		rpc *jsonRpcImpl
+	}
+
+	//Params of the notifications delivering the events of a subscription
+	subscriptionParams struct {
+		Subscription json.RawMessage `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+		Seq          uint64          `json:"seq,omitempty"` //Sequence number of the events of durable subscriptions
+	}
+
+	//Published notification delivered as the result of a subscription event
+	subscriptionEvent struct {
+		Topic  string `json:"topic,omitempty"`
+		Method string `json:"method"`
+		Params any    `json:"params"`
+	}
+
+	//Subscription of a client. The id changes when it is made again after reconnecting, unless it is durable
+	clientSubscription struct {
+		method  string
+		params  any
+		id      string
+		events  chan json.RawMessage
+		durable chan DurableEvent //Receives the events instead of events for durable subscriptions
+		ack     func(seq uint64) error
+	}
+
+	//Subscriptions of a client keyed by the id the server gave them
+	clientSubscriptions struct {
+		mu      sync.Mutex
+		byId    map[string]*clientSubscription
+		pending int                             //Subscribe calls waiting for their id
+		early   map[string][]subscriptionParams //Events received before the id of their subscription, while calls are pending
+	}
+
+	//Transports receiving the notifications of the server, such as persistent connections
+	notifyingTransport interface {
+		watch(notification func(msg []byte), reconnected func())
+	}
+)
+
+func newConnSubscriptions(write func(msg []byte)) *connSubscriptions {
+	return &connSubscriptions{write: write, stops: make(map[string]func())}
+}
+
+func withSubscriptions(ctx context.Context, subs *connSubscriptions) context.Context {
+	return context.WithValue(ctx, subscriptionsKey{}, subs)
+}
+
+// Stop every subscription of the connection
+func (s *connSubscriptions) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for id, stop := range s.stops {
+		stop()
+		delete(s.stops, id)
+	}
+}
+
+// Refuse new subscriptions, eg. while the connection is drained. Current subscriptions go on
+func (s *connSubscriptions) refuse() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+}
+
+// Number of subscriptions of the connection
+func (s *connSubscriptions) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.stops)
+}
+
+func (rpc *jsonRpcImpl) registerSubscriptionMethods(builtins *service) {
+	m := subscriptionMethods{rpc: rpc}
+	builtins.methods["subscribe"] = reflect.ValueOf(m.Subscribe)
+	builtins.methods["unsubscribe"] = reflect.ValueOf(m.Unsubscribe)
+	rpc.registerDurableMethods(builtins)
+}
+
+// Subscribe delivers the notifications published on the topics, or on every topic when none is given, to the
+// connection of the caller as rpc.subscription notifications. It returns the id of the subscription.
+// Subscriptions need a persistent connection and end with it.
+func (m subscriptionMethods) Subscribe(ctx context.Context, topics ...string) (string, error, *RpcErrorCode) {
+	subs, ok := ctx.Value(subscriptionsKey{}).(*connSubscriptions)
+	if !ok {
+		code := INVALID_REQUEST
+		return "", errors.New("Subscriptions need a persistent connection"), &code
+	}
+
+	filter := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		filter[topic] = true
+	}
+
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+
+	if subs.closed {
+		code := INVALID_REQUEST
+		return "", errors.New("Connection is closing"), &code
+	}
+
+	subs.lastId++
+	id := strconv.Itoa(subs.lastId)
+	rawId, _ := json.Marshal(id)
+
+	subs.stops[id] = m.rpc.Subscribe(func(n Notification) {
+		if len(filter) > 0 && !filter[n.Topic] {
+			return
+		}
+
+		msg, err := m.rpc.cfg().encodeSubscriptionEvent(rawId, n)
+		if err != nil {
+			m.rpc.cfg().logger.Printf("Unable to encode notification %s: %v", n.Method, err)
+			return
+		}
+		subs.write(msg)
+	})
+
+	return id, nil, nil
+}
+
+// Unsubscribe stops the subscription with the id. It returns false if the connection has no such subscription
+func (m subscriptionMethods) Unsubscribe(ctx context.Context, id string) (bool, error, *RpcErrorCode) {
+	subs, ok := ctx.Value(subscriptionsKey{}).(*connSubscriptions)
+	if !ok {
+		return false, nil, nil
+	}
+
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+
+	stop, ok := subs.stops[id]
+	if ok {
+		stop()
+		delete(subs.stops, id)
+	}
+
+	return ok, nil, nil
+}
+
+// Encode the notification as an event of the subscription
+func (c *config) encodeSubscriptionEvent(id json.RawMessage, n Notification) ([]byte, error) {
+	result, err := c.encodeSubscriptionResult(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.encodeSubscriptionMessage(id, result, 0)
+}
+
+// Encode the notification as the result of a subscription event
+func (c *config) encodeSubscriptionResult(n Notification) (json.RawMessage, error) {
+	params := n.Params
+	if params == nil {
+		params = []any{}
+	}
+
+	return c.marshal(subscriptionEvent{Topic: n.Topic, Method: n.Method, Params: params}, false)
+}
+
+// Encode the rpc.subscription notification delivering the result. seq is zero for events that are not durable
+func (c *config) encodeSubscriptionMessage(id json.RawMessage, result json.RawMessage, seq uint64) ([]byte, error) {
+	req := Request{
+		Jsonrpc: RPC_VERSION,
+		Method:  SUBSCRIPTION_METHOD,
+		Params:  subscriptionParams{Subscription: id, Result: result, Seq: seq},
+	}
+	return c.marshal(&req, false)
+}
+
+// Subscribe calls method with params to subscribe, and delivers the result of each event of the subscription on
+// the channel. Events are notifications whose params hold the subscription id returned by method and the result,
+// like rpc.subscription and eth_subscription. Unsubscribe calls the method named after method with subscribe
+// replaced by unsubscribe, eg. rpc.unsubscribe or eth_unsubscribe, with the id.
+// The subscription is made again when the client reconnects. Its channel is closed if that fails.
+// Up to SUBSCRIPTION_BUFFER_SIZE events wait on the channel, the oldest are dropped beyond that.
+// Subscriptions need a persistent connection, eg. NewConnClient or NewReconnectingClient.
+func (c *Client) Subscribe(ctx context.Context, method string, params any) (<-chan json.RawMessage, Unsubscribe, error) {
+	sub := &clientSubscription{method: method, params: params, events: make(chan json.RawMessage, SUBSCRIPTION_BUFFER_SIZE)}
+	return c.subscribeWith(ctx, sub)
+}
+
+// Make the subscription and return its channel. Subscriptions whose id is known beforehand receive their events
+// as soon as the subscribe call is sent
+func (c *Client) subscribeWith(ctx context.Context, sub *clientSubscription) (<-chan json.RawMessage, Unsubscribe, error) {
+	subs, err := c.subscriptionsOf()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subs.mu.Lock()
+	subs.pending++
+	if sub.id != "" {
+		subs.byId[sub.id] = sub
+	}
+	subs.mu.Unlock()
+
+	id, err := c.subscribe(ctx, sub)
+
+	subs.mu.Lock()
+	defer subs.mu.Unlock()
+
+	subs.pending--
+	if err == nil {
+		sub.id = id
+		subs.byId[id] = sub
+		for _, event := range subs.early[id] {
+			sub.deliver(event)
+		}
+	} else if sub.id != "" {
+		delete(subs.byId, sub.id)
+	}
+	if subs.pending == 0 {
+		subs.early = make(map[string][]subscriptionParams)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var once sync.Once
+	unsubscribe := func() error {
+		err := errors.New("Already unsubscribed")
+		once.Do(func() {
+			err = c.unsubscribe(sub)
+		})
+		return err
+	}
+
+	return sub.events, unsubscribe, nil
+}
+
+// Subscriptions of the client, watching the transport the first time
+func (c *Client) subscriptionsOf() (*clientSubscriptions, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.watchTransport() {
+		return nil, errors.New("Subscriptions need a persistent connection")
+	}
+	if c.subscriptions == nil {
+		c.subscriptions = &clientSubscriptions{
+			byId:  make(map[string]*clientSubscription),
+			early: make(map[string][]subscriptionParams),
+		}
+	}
+
+	return c.subscriptions, nil
+}
+
+// Receive the notifications of the transport, the first time. False when the transport does not receive
+// notifications. Called with the lock of the client
+func (c *Client) watchTransport() bool {
+	transport, ok := c.transport.(notifyingTransport)
+	if !ok {
+		return false
+	}
+
+	if !c.watching {
+		c.watching = true
+		transport.watch(c.receiveNotification, c.reconnected)
+	}
+
+	return true
+}
+
+// Hand a notification of the server to the stream or subscription it belongs to, and requests of the server
+// to their handler
+func (c *Client) receiveNotification(msg []byte) {
+	c.mu.RLock()
+	subs, streams, handlers := c.subscriptions, c.streams, c.handlers
+	c.mu.RUnlock()
+
+	var received serverMessage
+	if err := json.Unmarshal(msg, &received); err != nil {
+		return
+	}
+	if received.Id != nil && !bytes.Equal(received.Id, []byte("null")) {
+		if handlers != nil {
+			handlers.receive(received, c.reply)
+		}
+		return
+	}
+	received.Id = nil
+
+	if streams != nil && streams.receive(msg) {
+		return
+	}
+	if handlers != nil && handlers.receive(received, c.reply) {
+		return
+	}
+	if subs != nil {
+		subs.receive(msg)
+	}
+}
+
+// Write the answer to a request of the server
+func (c *Client) reply(ctx context.Context, msg []byte) error {
+	_, err := c.transport.RoundTrip(ctx, msg, true)
+	return err
+}
+
+// Make the subscriptions again once the transport reconnected. Streams end with the connection instead
+func (c *Client) reconnected() {
+	c.mu.RLock()
+	subs := c.subscriptions
+	c.mu.RUnlock()
+
+	if subs != nil {
+		c.resubscribe()
+	}
+}
+
+// Call the subscribe method and return the id of the subscription, compacted so it matches the id of the events
+func (c *Client) subscribe(ctx context.Context, sub *clientSubscription) (string, error) {
+	var id json.RawMessage
+	if err := c.Call(ctx, sub.method, sub.params, &id); err != nil {
+		return "", err
+	}
+
+	return compactId(id), nil
+}
+
+// Make the subscriptions again once the transport reconnected. They are closed when that fails. Durable
+// subscriptions keep their id, so they receive the events delivered again before the call returns
+func (c *Client) resubscribe() {
+	subs := c.subscriptions
+
+	subs.mu.Lock()
+	current := make([]*clientSubscription, 0, len(subs.byId))
+	for id, sub := range subs.byId {
+		current = append(current, sub)
+		if sub.durable == nil {
+			delete(subs.byId, id)
+		}
+	}
+	subs.pending += len(current)
+	subs.mu.Unlock()
+
+	for _, sub := range current {
+		id, err := c.subscribe(context.Background(), sub)
+
+		subs.mu.Lock()
+		subs.pending--
+		if err != nil {
+			delete(subs.byId, sub.id)
+			sub.close()
+		} else {
+			sub.id = id
+			subs.byId[id] = sub
+			for _, event := range subs.early[id] {
+				sub.deliver(event)
+			}
+		}
+		if subs.pending == 0 {
+			subs.early = make(map[string][]subscriptionParams)
+		}
+		subs.mu.Unlock()
+	}
+}
+
+// Stop the subscription locally, then on the server
+func (c *Client) unsubscribe(sub *clientSubscription) error {
+	subs := c.subscriptions
+
+	subs.mu.Lock()
+	id := sub.id
+	_, active := subs.byId[id]
+	if active {
+		delete(subs.byId, id)
+		sub.close()
+	}
+	subs.mu.Unlock()
+
+	method := unsubscribeMethod(sub.method)
+	if !active || method == "" {
+		return nil
+	}
+
+	var stopped bool
+	return c.Call(context.Background(), method, []any{json.RawMessage(id)}, &stopped)
+}
+
+// Deliver an event to its subscription
+func (s *clientSubscriptions) receive(msg []byte) {
+	var notification struct {
+		Params subscriptionParams `json:"params"`
+	}
+	if err := json.Unmarshal(msg, &notification); err != nil || len(notification.Params.Subscription) == 0 {
+		return
+	}
+	id := compactId(notification.Params.Subscription)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub, ok := s.byId[id]; ok {
+		sub.deliver(notification.Params)
+		return
+	}
+
+	//The event may belong to a subscription whose id is not received yet
+	if s.pending > 0 && len(s.early[id]) < SUBSCRIPTION_BUFFER_SIZE {
+		s.early[id] = append(s.early[id], notification.Params)
+	}
+}
+
+// Close the subscriptions, eg. when the client is closed
+func (s *clientSubscriptions) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, sub := range s.byId {
+		sub.close()
+		delete(s.byId, id)
+	}
+}
+
+// Queue the event, dropping the oldest one when the buffer is full. Called with the lock of the subscriptions
+func (s *clientSubscription) deliver(event subscriptionParams) {
+	if s.durable != nil {
+		deliverDroppingOldest(s.durable, DurableEvent{Seq: event.Seq, Result: event.Result, ack: s.ack})
+		return
+	}
+
+	deliverDroppingOldest(s.events, event.Result)
+}
+
+// Close the channel of the subscription
+func (s *clientSubscription) close() {
+	if s.durable != nil {
+		close(s.durable)
+		return
+	}
+
+	close(s.events)
+}
+
+// Send the value on the channel, dropping the oldest one when it is full
+func deliverDroppingOldest[T any](ch chan T, value T) {
+	for {
+		select {
+		case ch <- value:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+// Name of the method ending subscriptions made with method. Empty when it can not be told
+func unsubscribeMethod(method string) string {
+	i := strings.LastIndex(method, "subscribe")
+	if i < 0 {
+		return ""
+	}
+
+	return method[:i] + "un" + method[i:]
+}
+
+// The id without insignificant white space, so ids of responses and events compare equal
+func compactId(id json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, id); err != nil {
+		return string(id)
+	}
+
+	return buf.String()
+}
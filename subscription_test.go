@@ -0,0 +1,143 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Client connected to the server over a pipe
+func newTestSubscriptionClient(rpc JsonRPC) *Client {
+	clientConn, serverConn := net.Pipe()
+	go rpc.ServeConn(serverConn)
+
+	return NewConnClient(clientConn)
+}
+
+// Receive the next event of a subscription, failing after a second
+func receiveTestEvent(t *testing.T, events <-chan json.RawMessage) string {
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Subscription closed")
+		}
+		return string(event)
+	case <-time.After(time.Second):
+		t.Fatal("No event received")
+		return ""
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	rpc := NewJsonRpc()
+	client := newTestSubscriptionClient(rpc)
+	defer client.Close()
+
+	events, _, err := client.Subscribe(context.Background(), "rpc.subscribe", []any{"orders"})
+	assert.Nil(t, err)
+
+	rpc.Publish("users", "Users.Created", []any{1})
+	rpc.Publish("orders", "Orders.Created", []any{2})
+
+	assert.JSONEq(t, `{"topic":"orders","method":"Orders.Created","params":[2]}`, receiveTestEvent(t, events))
+}
+
+func TestUnsubscribe(t *testing.T) {
+	rpc := NewJsonRpc()
+	client := newTestSubscriptionClient(rpc)
+	defer client.Close()
+
+	events, unsubscribe, _ := client.Subscribe(context.Background(), "rpc.subscribe", nil)
+
+	assert.Nil(t, unsubscribe())
+	assert.Error(t, unsubscribe())
+
+	_, ok := <-events
+	assert.False(t, ok)
+
+	var stopped bool
+	client.Call(context.Background(), "rpc.unsubscribe", []any{"1"}, &stopped)
+	assert.False(t, stopped)
+}
+
+func TestSubscribeNeedsConnection(t *testing.T) {
+	rpc := NewJsonRpc()
+
+	res := callMethod(t, rpc, "rpc.subscribe", nil)
+	assert.Equal(t, INVALID_REQUEST, res.Error.Code)
+
+	client := NewHTTPClient(newTestHTTPServer(t, rpc))
+	_, _, err := client.Subscribe(context.Background(), "rpc.subscribe", nil)
+	assert.Equal(t, "Subscriptions need a persistent connection", err.Error())
+}
+
+func TestResubscribeAfterReconnect(t *testing.T) {
+	rpc := NewJsonRpc()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	defer l.Close()
+
+	var mu sync.Mutex
+	var conns []net.Conn
+	dial := func(ctx context.Context) (net.Conn, error) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+		return conn, err
+	}
+
+	client, err := NewReconnectingClient(context.Background(), dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	events, _, err := client.Subscribe(context.Background(), "rpc.subscribe", []any{"orders"})
+	assert.Nil(t, err)
+
+	mu.Lock()
+	conns[0].Close()
+	mu.Unlock()
+
+	//Publish until the subscription is made again on the new connection
+	assert.Eventually(t, func() bool {
+		rpc.Publish("orders", "Orders.Created", nil)
+		select {
+		case event := <-events:
+			return string(event) == `{"topic":"orders","method":"Orders.Created","params":[]}`
+		case <-time.After(10 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var methods []string
+	assert.Nil(t, client.Call(context.Background(), "rpc.listMethods", nil, &methods))
+}
+
+func TestCloseClientClosesSubscriptions(t *testing.T) {
+	client := newTestSubscriptionClient(NewJsonRpc())
+
+	events, unsubscribe, _ := client.Subscribe(context.Background(), "rpc.subscribe", nil)
+	client.Close()
+
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Nil(t, unsubscribe())
+}
+
+func TestUnsubscribeMethod(t *testing.T) {
+	assert.Equal(t, "rpc.unsubscribe", unsubscribeMethod("rpc.subscribe"))
+	assert.Equal(t, "eth_unsubscribe", unsubscribeMethod("eth_subscribe"))
+	assert.Equal(t, "", unsubscribeMethod("Orders.Watch"))
+}
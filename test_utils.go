@@ -9,7 +9,7 @@ import (
 	"net/http/httptest"
 )
 
-func makeRpcSingleTestRequest(rpc JsonRPC, req request) (*response, error) {
+func makeRpcSingleTestRequest(rpc JsonRPC, req Request) (*Response, error) {
 	recorder := httptest.NewRecorder()
 
 	reqBody, err := json.Marshal(req)
@@ -31,7 +31,7 @@ func makeRpcSingleTestRequest(rpc JsonRPC, req request) (*response, error) {
 
 	var statusCode = recorder.Result().StatusCode
 	if statusCode == int(http.StatusOK) || statusCode == int(http.StatusNoContent) {
-		res := &response{}
+		res := &Response{}
 		if err := json.Unmarshal(body, res); err != nil {
 			return nil, err
 		}
@@ -43,7 +43,7 @@ func makeRpcSingleTestRequest(rpc JsonRPC, req request) (*response, error) {
 
 }
 
-func makeRpcBatchTestRequest(rpc JsonRPC, reqs []request) ([]response, error) {
+func makeRpcBatchTestRequest(rpc JsonRPC, reqs []Request) ([]Response, error) {
 	recorder := httptest.NewRecorder()
 
 	reqBody, err := json.Marshal(reqs)
@@ -65,7 +65,7 @@ func makeRpcBatchTestRequest(rpc JsonRPC, reqs []request) ([]response, error) {
 
 	var statusCode = recorder.Result().StatusCode
 	if statusCode == int(http.StatusOK) || statusCode == int(http.StatusNoContent) {
-		res := &[]response{}
+		res := &[]Response{}
 		if err := json.Unmarshal(body, res); err != nil {
 			return nil, err
 		}
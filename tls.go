@@ -0,0 +1,147 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+)
+
+// A TLSOption configures the TLS settings used by ListenAndServeTLS and DialTLS
+type TLSOption func(*tls.Config) error
+
+// NewTLSConfig builds a tls.Config from the options. TLS 1.2 is the minimum version accepted.
+func NewTLSConfig(opts ...TLSOption) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// WithCertificate loads the certificate presented to the peer.
+// Servers use it as their server certificate and clients as their client certificate for mTLS.
+func WithCertificate(certFile, keyFile string) TLSOption {
+	return func(cfg *tls.Config) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+
+		return nil
+	}
+}
+
+// WithClientCAs enables mTLS on a server. Clients must present a certificate signed by one of the CAs in caFile.
+func WithClientCAs(caFile string) TLSOption {
+	return func(cfg *tls.Config) error {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+		return nil
+	}
+}
+
+// WithRootCAs sets the CAs a client uses to verify the server certificate instead of the system pool
+func WithRootCAs(caFile string) TLSOption {
+	return func(cfg *tls.Config) error {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return err
+		}
+		cfg.RootCAs = pool
+
+		return nil
+	}
+}
+
+// WithServerName sets the name a client expects in the server certificate. Defaults to the host dialed.
+func WithServerName(name string) TLSOption {
+	return func(cfg *tls.Config) error {
+		cfg.ServerName = name
+		return nil
+	}
+}
+
+// WithPeerVerifier adds a callback run on the peer's leaf certificate after the standard chain verification.
+// Returning an error aborts the handshake. Use it to pin certificates or check the subject of client certificates.
+func WithPeerVerifier(verify func(cert *x509.Certificate) error) TLSOption {
+	return func(cfg *tls.Config) error {
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if len(verifiedChains) > 0 && len(verifiedChains[0]) > 0 {
+				return verify(verifiedChains[0][0])
+			}
+
+			if len(rawCerts) == 0 {
+				//Peer was not asked for a certificate
+				return nil
+			}
+
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+
+			return verify(cert)
+		}
+
+		return nil
+	}
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New(fmt.Sprintf("No certificate found in %s", caFile))
+	}
+
+	return pool, nil
+}
+
+// ListenAndServeTLS listens on the TCP address and serves JSON-RPC over TLS.
+// The options must at least provide the server certificate with WithCertificate.
+func (rpc *jsonRpcImpl) ListenAndServeTLS(addr string, opts ...TLSOption) error {
+	cfg, err := NewTLSConfig(opts...)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil {
+		return errors.New("A server certificate is required to serve TLS")
+	}
+
+	l, err := tls.Listen("tcp", addr, cfg)
+	if err != nil {
+		return err
+	}
+
+	return rpc.Serve(l)
+}
+
+// DialTLS connects to a JSON-RPC server serving TLS on addr and completes the handshake
+func DialTLS(ctx context.Context, addr string, opts ...TLSOption) (net.Conn, error) {
+	cfg, err := NewTLSConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &tls.Dialer{Config: cfg}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
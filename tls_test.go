@@ -0,0 +1,205 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCertificates struct {
+	caFile         string
+	serverCertFile string
+	serverKeyFile  string
+	clientCertFile string
+	clientKeyFile  string
+}
+
+func writeTestPem(t *testing.T, path string, blockType string, der []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func issueTestCertificate(t *testing.T, dir, name string, template *x509.Certificate, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	writeTestPem(t, certFile, "CERTIFICATE", der)
+	writeTestPem(t, keyFile, "EC PRIVATE KEY", keyDer)
+
+	return certFile, keyFile
+}
+
+func makeTestCertificates(t *testing.T) testCertificates {
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDer)
+
+	certs := testCertificates{caFile: filepath.Join(dir, "ca.pem")}
+	writeTestPem(t, certs.caFile, "CERTIFICATE", caDer)
+
+	certs.serverCertFile, certs.serverKeyFile = issueTestCertificate(t, dir, "server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+
+	certs.clientCertFile, certs.clientKeyFile = issueTestCertificate(t, dir, "client", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "billing"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	return certs
+}
+
+func serveTestTLS(t *testing.T, rpc JsonRPC, opts ...TLSOption) string {
+	cfg, err := NewTLSConfig(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rpc.Serve(l)
+	t.Cleanup(func() { l.Close() })
+
+	return l.Addr().String()
+}
+
+func TestMutualTLS(t *testing.T) {
+	certs := makeTestCertificates(t)
+
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(arith{}, "Arith")
+
+	var clientName string
+	addr := serveTestTLS(t, rpc,
+		WithCertificate(certs.serverCertFile, certs.serverKeyFile),
+		WithClientCAs(certs.caFile),
+		WithPeerVerifier(func(cert *x509.Certificate) error {
+			clientName = cert.Subject.CommonName
+			return nil
+		}),
+	)
+
+	conn, err := DialTLS(context.Background(), addr,
+		WithCertificate(certs.clientCertFile, certs.clientKeyFile),
+		WithRootCAs(certs.caFile),
+		WithServerName("localhost"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte(`{"jsonrpc":"2.0","id":"1","method":"Arith.Add","params":[1,2]}` + "\n"))
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &Response{}
+	if err := json.Unmarshal(line, res); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, float64(3), *res.Result)
+	assert.Equal(t, "billing", clientName)
+}
+
+func TestMutualTLSRejectsClientWithoutCertificate(t *testing.T) {
+	certs := makeTestCertificates(t)
+
+	addr := serveTestTLS(t, NewJsonRpc(),
+		WithCertificate(certs.serverCertFile, certs.serverKeyFile),
+		WithClientCAs(certs.caFile),
+	)
+
+	conn, err := DialTLS(context.Background(), addr, WithRootCAs(certs.caFile), WithServerName("localhost"))
+	if err == nil {
+		//TLS 1.3 reports the rejected client certificate on the first read
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+
+	assert.Error(t, err)
+}
+
+func TestPeerVerifierRejects(t *testing.T) {
+	certs := makeTestCertificates(t)
+
+	addr := serveTestTLS(t, NewJsonRpc(), WithCertificate(certs.serverCertFile, certs.serverKeyFile))
+
+	_, err := DialTLS(context.Background(), addr,
+		WithRootCAs(certs.caFile),
+		WithServerName("localhost"),
+		WithPeerVerifier(func(cert *x509.Certificate) error {
+			return errors.New("Pinned certificate mismatch")
+		}),
+	)
+
+	assert.Error(t, err)
+}
+
+func TestListenAndServeTLSRequiresCertificate(t *testing.T) {
+	err := NewJsonRpc().ListenAndServeTLS("127.0.0.1:0")
+	assert.Error(t, err)
+
+	var opErr *net.OpError
+	assert.False(t, errors.As(err, &opErr))
+}
@@ -0,0 +1,234 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Struct tag holding the constraints of a param field. eg. rpc:"required,min=1,max=100"
+const VALIDATION_TAG = "rpc"
+
+// Constraints of a struct field read from its rpc tag
+type fieldRule struct {
+	index    int
+	name     string //JSON name of the field
+	required bool
+	min      *float64 //Minimum value of numbers, or minimum length of strings, slices and maps
+	max      *float64
+}
+
+// Rules of struct types by reflect.Type
+var structRulesCache sync.Map
+
+// Check the constraints of the rpc tags of a param, including nested structs
+func validateParam(v reflect.Value) error {
+	return validateValue(v, "")
+}
+
+func validateValue(v reflect.Value, path string) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		rules, err := structRules(v.Type())
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range rules {
+			field := v.Field(rule.index)
+			name := joinPath(path, rule.name)
+
+			if err := rule.check(field, name); err != nil {
+				return err
+			}
+			if err := validateValue(field, name); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if !mayHoldStructs(v.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rule fieldRule) check(field reflect.Value, name string) error {
+	if rule.required && field.IsZero() {
+		return errors.New(fmt.Sprintf("Param %s is required", name))
+	}
+
+	field = indirect(field)
+	if !field.IsValid() || (rule.min == nil && rule.max == nil) {
+		return nil
+	}
+
+	var size float64
+	unit := ""
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = field.Float()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		size = float64(field.Len())
+		unit = " in length"
+	default:
+		return nil
+	}
+
+	if rule.min != nil && size < *rule.min {
+		return errors.New(fmt.Sprintf("Param %s must be at least %v%s", name, *rule.min, unit))
+	}
+	if rule.max != nil && size > *rule.max {
+		return errors.New(fmt.Sprintf("Param %s must be at most %v%s", name, *rule.max, unit))
+	}
+
+	return nil
+}
+
+// Rules of the exported fields of a struct. Fields without an rpc tag have no constraints
+func structRules(t reflect.Type) ([]fieldRule, error) {
+	if rules, ok := structRulesCache.Load(t); ok {
+		return rules.([]fieldRule), nil
+	}
+
+	rules := make([]fieldRule, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		rule := fieldRule{index: i, name: jsonFieldName(f)}
+		tag, ok := f.Tag.Lookup(VALIDATION_TAG)
+		if ok {
+			if err := rule.parse(tag); err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid %s tag on %s.%s: %s", VALIDATION_TAG, t.Name(), f.Name, err))
+			}
+		}
+
+		//Untagged fields are kept to validate the structs nested in them
+		rules = append(rules, rule)
+	}
+
+	structRulesCache.Store(t, rules)
+	return rules, nil
+}
+
+func (rule *fieldRule) parse(tag string) error {
+	for _, constraint := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(constraint), "=")
+
+		switch key {
+		case "":
+		case "required":
+			rule.required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return errors.New(fmt.Sprintf("%s must be a number", key))
+			}
+			if key == "min" {
+				rule.min = &n
+			} else {
+				rule.max = &n
+			}
+		default:
+			return errors.New(fmt.Sprintf("Unknown constraint %s", key))
+		}
+	}
+
+	return nil
+}
+
+// Check the rpc tags of the struct params of a method, including nested structs, when it is registered
+func checkValidationTags(methodType reflect.Type) error {
+	seen := make(map[reflect.Type]bool)
+
+	var check func(t reflect.Type) error
+	check = func(t reflect.Type) error {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || seen[t] {
+			return nil
+		}
+		seen[t] = true
+
+		if _, err := structRules(t); err != nil {
+			return err
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if err := check(t.Field(i).Type); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for i := 1; i < methodType.NumIn(); i++ {
+		if err := check(methodType.In(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Name of the field in JSON, following the json tag
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+
+	return name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// Whether values of the type can contain structs to validate
+func mayHoldStructs(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct || t.Kind() == reflect.Interface
+}
+
+// Follow pointers and interfaces. The zero Value is returned for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
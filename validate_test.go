@@ -0,0 +1,75 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	createUserParams struct {
+		UserId int      `json:"user_id" rpc:"required,min=1"`
+		Name   string   `json:"name" rpc:"required,max=8"`
+		Tags   []string `json:"tags" rpc:"max=2"`
+		Owner  *owner   `json:"owner"`
+	}
+
+	owner struct {
+		Email string `json:"email" rpc:"required"`
+	}
+
+	accounts struct{}
+
+	badTags struct {
+		Name string `rpc:"requird"`
+	}
+
+	badService struct{}
+)
+
+func (accounts) Create(ctx context.Context, p createUserParams) (string, error, *RpcErrorCode) {
+	return p.Name, nil, nil
+}
+
+func (badService) Create(ctx context.Context, p badTags) (string, error, *RpcErrorCode) {
+	return p.Name, nil, nil
+}
+
+func callAccounts(t *testing.T, params string) *Response {
+	rpc := NewJsonRpc()
+	rpc.RegisterWithName(accounts{}, "Accounts")
+
+	return decodeTestResponse(t, serveTestBody(rpc, `{"jsonrpc":"2.0","id":"1","method":"Accounts.Create","params":`+params+`}`))
+}
+
+func TestNamedParamsStruct(t *testing.T) {
+	res := callAccounts(t, `{"user_id":1,"name":"ada"}`)
+
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "ada", *res.Result)
+}
+
+func TestValidationTags(t *testing.T) {
+	cases := map[string]string{
+		`{"name":"ada"}`:                                    "Param user_id is required",
+		`{"user_id":-1,"name":"ada"}`:                       "Param user_id must be at least 1",
+		`{"user_id":1,"name":"ada lovelace"}`:               "Param name must be at most 8 in length",
+		`{"user_id":1,"name":"ada","tags":["a","b","c"]}`:   "Param tags must be at most 2 in length",
+		`{"user_id":1,"name":"ada","owner":{}}`:             "Param owner.email is required",
+		`[{"user_id":1,"name":"ada","owner":{"email":""}}]`: "Param owner.email is required",
+	}
+
+	for params, message := range cases {
+		res := callAccounts(t, params)
+		if assert.NotNil(t, res.Error, params) {
+			assert.Equal(t, INVALID_PARAMS, res.Error.Code, params)
+			assert.Equal(t, message, res.Error.Message, params)
+		}
+	}
+}
+
+func TestInvalidValidationTag(t *testing.T) {
+	rpc := NewJsonRpc()
+	assert.Error(t, rpc.RegisterWithName(badService{}, "Bad"))
+}
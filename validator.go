@@ -0,0 +1,144 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type (
+	//StructValidator validates structs by the tags of their fields, eg. the *validator.Validate of
+	//github.com/go-playground/validator, which implements it as is. The package does not depend on a validator so
+	//applications pick its version.
+	StructValidator interface {
+		//Error of the violated rules. Nil when the struct is valid
+		Struct(s any) error
+	}
+
+	//FieldViolation is a rule a field of the params violates. INVALID_PARAMS errors of WithStructValidator list them
+	//in their data
+	FieldViolation struct {
+		Field   string `json:"field"`           //Path of the field, eg. address.zip. Empty when the error names no field
+		Rule    string `json:"rule,omitempty"`  //Violated rule, eg. required or min
+		Param   string `json:"param,omitempty"` //Parameter of the rule, eg. 3 for min=3
+		Message string `json:"message"`
+	}
+
+	//Field error of go-playground/validator, whose ValidationErrors is a slice of them
+	validatorFieldError interface {
+		Namespace() string
+		Tag() string
+		Param() string
+		Error() string
+	}
+
+	//Params rejected by the struct validator
+	violationsError struct {
+		violations []FieldViolation
+	}
+
+	structValidatorKey struct{}
+)
+
+// WithStructValidator validates the struct params of methods, and the structs nested in them, with the validator, eg.
+// validator.New() of github.com/go-playground/validator. Params violating the rules of their validate tags are
+// answered with INVALID_PARAMS, whose data lists the FieldViolation of each field. Register a tag name function on
+// the validator to name fields after their json tags. It runs after the checks of rpc tags.
+func WithStructValidator(validator StructValidator) Option {
+	return func(c *config) {
+		c.structValidator = validator
+	}
+}
+
+func withStructValidator(ctx context.Context, validator StructValidator) context.Context {
+	if validator == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, structValidatorKey{}, validator)
+}
+
+// Validate a param with the struct validator of the context. Params that are not structs are not validated
+func validateStruct(ctx context.Context, param reflect.Value) error {
+	validator, ok := ctx.Value(structValidatorKey{}).(StructValidator)
+	if !ok {
+		return nil
+	}
+
+	v := indirect(param)
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if !v.CanAddr() {
+		//Validators take pointers to structs, as their rules may be on methods of the pointer
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		v = copied
+	}
+	err := validator.Struct(v.Addr().Interface())
+	if err == nil {
+		return nil
+	}
+
+	return &violationsError{violations: fieldViolations(err)}
+}
+
+// Violations of the error of a validator. A ValidationErrors of go-playground/validator gives one per field
+func fieldViolations(err error) []FieldViolation {
+	v := reflect.ValueOf(err)
+	if v.Kind() != reflect.Slice {
+		return []FieldViolation{{Message: err.Error()}}
+	}
+
+	violations := make([]FieldViolation, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		fieldErr, ok := v.Index(i).Interface().(validatorFieldError)
+		if !ok {
+			return []FieldViolation{{Message: err.Error()}}
+		}
+
+		violations = append(violations, FieldViolation{
+			Field:   violationPath(fieldErr.Namespace()),
+			Rule:    fieldErr.Tag(),
+			Param:   fieldErr.Param(),
+			Message: fieldErr.Error(),
+		})
+	}
+
+	return violations
+}
+
+// Path of the field without the name of the struct the namespace starts with, eg. address.zip for User.address.zip
+func violationPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+
+	return path
+}
+
+func (e *violationsError) Error() string {
+	if len(e.violations) == 1 && e.violations[0].Field != "" {
+		return fmt.Sprintf("Param %s is invalid", e.violations[0].Field)
+	}
+	if len(e.violations) == 1 {
+		return e.violations[0].Message
+	}
+
+	return fmt.Sprintf("%d fields of the params are invalid", len(e.violations))
+}
+
+// Data of the error response of the violations. Nil for other errors
+func violationsData(err error) *any {
+	var violations *violationsError
+	if !errors.As(err, &violations) {
+		return nil
+	}
+
+	var data any = violations.violations
+	return &data
+}
@@ -0,0 +1,128 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	signup struct {
+		Email   string        `json:"email" validate:"required,email"`
+		Age     int           `json:"age" validate:"min=18"`
+		Address signupAddress `json:"address"`
+	}
+
+	signupAddress struct {
+		Zip string `json:"zip" validate:"len=5"`
+	}
+
+	signups struct{}
+
+	//Field error shaped like the ones of go-playground/validator
+	playgroundFieldError struct {
+		namespace, tag, param string
+	}
+
+	playgroundValidationErrors []playgroundFieldError
+
+	//Checks the rules the tests need, naming fields after their json tags like a registered tag name function
+	playgroundValidator struct{}
+
+	//Validator failing with a plain error
+	plainValidator struct{}
+)
+
+func (signups) Create(ctx context.Context, s signup) (string, error, *RpcErrorCode) {
+	return s.Email, nil, nil
+}
+
+func (signups) Count(ctx context.Context, n int) (int, error, *RpcErrorCode) {
+	return n, nil, nil
+}
+
+func (e playgroundFieldError) Namespace() string { return e.namespace }
+func (e playgroundFieldError) Tag() string       { return e.tag }
+func (e playgroundFieldError) Param() string     { return e.param }
+func (e playgroundFieldError) Error() string {
+	return fmt.Sprintf("Key: '%s' Error:Field validation for '%s' failed on the '%s' tag", e.namespace, e.namespace, e.tag)
+}
+
+func (e playgroundValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (playgroundValidator) Struct(s any) error {
+	user := s.(*signup)
+	var errs playgroundValidationErrors
+	if !strings.Contains(user.Email, "@") {
+		errs = append(errs, playgroundFieldError{namespace: "signup.email", tag: "email"})
+	}
+	if user.Age < 18 {
+		errs = append(errs, playgroundFieldError{namespace: "signup.age", tag: "min", param: "18"})
+	}
+	if len(user.Address.Zip) != 5 {
+		errs = append(errs, playgroundFieldError{namespace: "signup.address.zip", tag: "len", param: "5"})
+	}
+	if errs == nil {
+		return nil
+	}
+	return errs
+}
+
+func (plainValidator) Struct(s any) error {
+	return errors.New("Validation is down")
+}
+
+func TestWithStructValidator(t *testing.T) {
+	rpc := NewJsonRpc(WithStructValidator(playgroundValidator{}))
+	rpc.RegisterWithName(signups{}, "Signups")
+
+	res := callMethod(t, rpc, "Signups.Create", []any{map[string]any{"email": "ada@example.com", "age": 36, "address": map[string]any{"zip": "10115"}}})
+	assert.Nil(t, res.Error)
+	assert.Equal(t, "ada@example.com", *res.Result)
+
+	res = callMethod(t, rpc, "Signups.Create", []any{map[string]any{"email": "ada", "age": 12, "address": map[string]any{"zip": "1"}}})
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "3 fields of the params are invalid", res.Error.Message)
+
+	var violations []FieldViolation
+	raw, _ := json.Marshal(res.Error.Data)
+	assert.Nil(t, json.Unmarshal(raw, &violations))
+	assert.Equal(t, []FieldViolation{
+		{Field: "email", Rule: "email", Message: "Key: 'signup.email' Error:Field validation for 'signup.email' failed on the 'email' tag"},
+		{Field: "age", Rule: "min", Param: "18", Message: "Key: 'signup.age' Error:Field validation for 'signup.age' failed on the 'min' tag"},
+		{Field: "address.zip", Rule: "len", Param: "5", Message: "Key: 'signup.address.zip' Error:Field validation for 'signup.address.zip' failed on the 'len' tag"},
+	}, violations)
+
+	res = callMethod(t, rpc, "Signups.Create", []any{map[string]any{"email": "ada@example.com", "age": 12, "address": map[string]any{"zip": "10115"}}})
+	assert.Equal(t, "Param age is invalid", res.Error.Message)
+
+	//Params that are not structs are not validated
+	res = callMethod(t, rpc, "Signups.Count", []any{3})
+	assert.Nil(t, res.Error)
+}
+
+func TestStructValidatorPlainError(t *testing.T) {
+	rpc := NewJsonRpc(WithStructValidator(plainValidator{}))
+	rpc.RegisterWithName(signups{}, "Signups")
+
+	res := callMethod(t, rpc, "Signups.Create", []any{map[string]any{"email": "ada@example.com"}})
+	assert.Equal(t, INVALID_PARAMS, res.Error.Code)
+	assert.Equal(t, "Validation is down", res.Error.Message)
+	assert.Equal(t, []any{map[string]any{"field": "", "message": "Validation is down"}}, res.Error.Data)
+}
+
+func TestViolationPath(t *testing.T) {
+	assert.Equal(t, "address.zip", violationPath("User.address.zip"))
+	assert.Equal(t, "zip", violationPath("zip"))
+}
@@ -0,0 +1,15 @@
+//go:build !(js && wasm)
+
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// DialWebSocket connects to a JSON-RPC server over the WebSocket API of the browser. It is only supported when
+// built with GOOS=js GOARCH=wasm. Other platforms get an error, so code shared with the browser still builds.
+func DialWebSocket(ctx context.Context, url string) (net.Conn, error) {
+	return nil, errors.New("WebSocket connections are only supported in browsers")
+}
@@ -0,0 +1,173 @@
+//go:build js && wasm
+
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+type (
+	//Browser WebSocket used as a connection. Every message is a JSON value, read as if separated by new lines
+	webSocketConn struct {
+		ws       js.Value
+		url      string
+		handlers []js.Func
+
+		mu        sync.Mutex
+		received  bytes.Buffer  //Messages not read yet
+		readable  chan struct{} //Signaled when a message is received
+		closed    chan struct{}
+		closeOnce sync.Once
+	}
+
+	webSocketAddr string
+)
+
+// DialWebSocket connects to a JSON-RPC server over the WebSocket API of the browser. The connection is used with
+// NewConnClient, or returned by the dial function of NewReconnectingClient, like any other connection.
+func DialWebSocket(ctx context.Context, url string) (net.Conn, error) {
+	constructor := js.Global().Get("WebSocket")
+	if constructor.IsUndefined() {
+		return nil, errors.New("WebSocket is not supported by the runtime")
+	}
+
+	c := &webSocketConn{
+		ws:       constructor.New(url),
+		url:      url,
+		readable: make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+	c.ws.Set("binaryType", "arraybuffer")
+
+	opened := make(chan struct{})
+	failed := make(chan struct{})
+	var openOnce sync.Once
+
+	c.handle("onopen", func(event js.Value) {
+		openOnce.Do(func() { close(opened) })
+	})
+	c.handle("onerror", func(event js.Value) {
+		openOnce.Do(func() { close(failed) })
+	})
+	c.handle("onclose", func(event js.Value) {
+		openOnce.Do(func() { close(failed) })
+		c.closeOnce.Do(func() { close(c.closed) })
+	})
+	c.handle("onmessage", func(event js.Value) {
+		data := event.Get("data")
+
+		c.mu.Lock()
+		if data.Type() == js.TypeString {
+			c.received.WriteString(data.String())
+		} else {
+			msg := make([]byte, data.Get("byteLength").Int())
+			js.CopyBytesToGo(msg, js.Global().Get("Uint8Array").New(data))
+			c.received.Write(msg)
+		}
+		c.received.WriteByte('\n')
+		c.mu.Unlock()
+
+		select {
+		case c.readable <- struct{}{}:
+		default:
+		}
+	})
+
+	select {
+	case <-opened:
+		return c, nil
+	case <-failed:
+		c.Close()
+		return nil, errors.New(fmt.Sprintf("Unable to connect to %s", url))
+	case <-ctx.Done():
+		c.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// Set an event handler of the WebSocket. Handlers run on the event loop, so they must not block
+func (c *webSocketConn) handle(event string, handler func(event js.Value)) {
+	fn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		handler(args[0])
+		return nil
+	})
+	c.handlers = append(c.handlers, fn)
+	c.ws.Set(event, fn)
+}
+
+func (c *webSocketConn) Read(p []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if c.received.Len() > 0 {
+			n, _ := c.received.Read(p)
+			c.mu.Unlock()
+			return n, nil
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.readable:
+		case <-c.closed:
+			//Messages received before the close are read first
+			c.mu.Lock()
+			empty := c.received.Len() == 0
+			c.mu.Unlock()
+			if empty {
+				return 0, io.EOF
+			}
+		}
+	}
+}
+
+// Write sends p as a single message. Writes of the client are whole messages ending with a new line
+func (c *webSocketConn) Write(p []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+
+	c.ws.Call("send", string(bytes.TrimSuffix(p, []byte("\n"))))
+
+	return len(p), nil
+}
+
+func (c *webSocketConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.handlers == nil {
+		return nil
+	}
+
+	//Events of the closing socket must not call the released functions
+	for _, event := range []string{"onopen", "onerror", "onclose", "onmessage"} {
+		c.ws.Set(event, js.Null())
+	}
+	c.ws.Call("close")
+	for _, fn := range c.handlers {
+		fn.Release()
+	}
+	c.handlers = nil
+
+	return nil
+}
+
+func (c *webSocketConn) LocalAddr() net.Addr                { return webSocketAddr("") }
+func (c *webSocketConn) RemoteAddr() net.Addr               { return webSocketAddr(c.url) }
+func (c *webSocketConn) SetDeadline(t time.Time) error      { return nil }
+func (c *webSocketConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *webSocketConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (webSocketAddr) Network() string  { return "websocket" }
+func (a webSocketAddr) String() string { return string(a) }
@@ -0,0 +1,47 @@
+//go:build js && wasm
+
+package jsonrpc2
+
+import (
+	"context"
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Replace the WebSocket API with a server answering every request with its method
+func mockWebSocket() {
+	js.Global().Call("eval", `globalThis.WebSocket = class {
+		constructor(url) {
+			this.url = url;
+			setTimeout(() => url.startsWith("ws://down") ? this.onerror({}) : this.onopen({}));
+		}
+		send(msg) {
+			const req = JSON.parse(msg);
+			setTimeout(() => this.onmessage && this.onmessage({data: JSON.stringify({jsonrpc: "2.0", id: req.id, result: req.method})}));
+		}
+		close() {}
+	}`)
+}
+
+func TestWebSocketClient(t *testing.T) {
+	mockWebSocket()
+
+	conn, err := DialWebSocket(context.Background(), "ws://localhost/rpc")
+	assert.Nil(t, err)
+
+	client := NewConnClient(conn)
+	defer client.Close()
+
+	var method string
+	assert.Nil(t, client.Call(context.Background(), "Arith.Add", []any{1, 2}, &method))
+	assert.Equal(t, "Arith.Add", method)
+}
+
+func TestWebSocketDialFails(t *testing.T) {
+	mockWebSocket()
+
+	_, err := DialWebSocket(context.Background(), "ws://down/rpc")
+	assert.Equal(t, "Unable to connect to ws://down/rpc", err.Error())
+}
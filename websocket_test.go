@@ -0,0 +1,16 @@
+//go:build !(js && wasm)
+
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocketNeedsBrowser(t *testing.T) {
+	_, err := DialWebSocket(context.Background(), "ws://localhost/rpc")
+
+	assert.Equal(t, "WebSocket connections are only supported in browsers", err.Error())
+}
@@ -0,0 +1,229 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout of the dateTime.iso8601 values of XML-RPC
+const XMLRPC_TIME_LAYOUT = "20060102T15:04:05"
+
+type (
+	//XML-RPC, translated to JSON-RPC 2.0 calls with positional params. Every method call is answered
+	xmlRPCAdapter struct{}
+
+	xmlMethodCall struct {
+		MethodName string     `xml:"methodName"`
+		Params     []xmlValue `xml:"params>param>value"`
+	}
+
+	//Value of XML-RPC. A value without a type element is a string
+	xmlValue struct {
+		Int      *string    `xml:"int"`
+		I4       *string    `xml:"i4"`
+		I8       *string    `xml:"i8"`
+		Double   *string    `xml:"double"`
+		Boolean  *string    `xml:"boolean"`
+		String   *string    `xml:"string"`
+		Base64   *string    `xml:"base64"`
+		DateTime *string    `xml:"dateTime.iso8601"`
+		Nil      *struct{}  `xml:"nil"`
+		Array    *xmlArray  `xml:"array"`
+		Struct   *xmlStruct `xml:"struct"`
+		Text     string     `xml:",chardata"`
+	}
+
+	xmlArray struct {
+		Values []xmlValue `xml:"data>value"`
+	}
+
+	xmlStruct struct {
+		Members []xmlMember `xml:"member"`
+	}
+
+	xmlMember struct {
+		Name  string   `xml:"name"`
+		Value xmlValue `xml:"value"`
+	}
+)
+
+func (xmlRPCAdapter) DecodeRequest(body []byte) ([]byte, error) {
+	var call xmlMethodCall
+	if err := xml.Unmarshal(body, &call); err != nil {
+		return nil, err
+	}
+
+	params := make([]any, len(call.Params))
+	for i, value := range call.Params {
+		param, err := value.decode()
+		if err != nil {
+			return nil, err
+		}
+		params[i] = param
+	}
+
+	//XML-RPC has no notifications nor ids. The response matches the only call of the request
+	id := "1"
+	return json.Marshal(Request{Jsonrpc: RPC_VERSION, Id: &id, Method: strings.TrimSpace(call.MethodName), Params: params})
+}
+
+func (xmlRPCAdapter) EncodeResponse(request, response []byte) ([]byte, error) {
+	var res struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RpcError       `json:"error"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(response))
+	decoder.UseNumber()
+	if err := decoder.Decode(&res); err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBufferString(xml.Header + "<methodResponse>")
+	if res.Error != nil {
+		fault := map[string]any{"faultCode": json.Number(strconv.Itoa(int(res.Error.Code))), "faultString": res.Error.Message}
+		buf.WriteString("<fault>")
+		writeXMLValue(buf, fault)
+		buf.WriteString("</fault>")
+	} else {
+		var result any
+		if len(res.Result) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(res.Result))
+			decoder.UseNumber()
+			if err := decoder.Decode(&result); err != nil {
+				return nil, err
+			}
+		}
+		buf.WriteString("<params><param>")
+		writeXMLValue(buf, result)
+		buf.WriteString("</param></params>")
+	}
+	buf.WriteString("</methodResponse>")
+
+	return buf.Bytes(), nil
+}
+
+func (xmlRPCAdapter) ContentType() string {
+	return "text/xml"
+}
+
+// Value as decoded from JSON. Numbers are json.Number, binary values base64 strings and times RFC 3339 strings
+func (v xmlValue) decode() (any, error) {
+	number := func(text string) (any, error) {
+		text = strings.TrimSpace(text)
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid number %q", text))
+		}
+		return json.Number(text), nil
+	}
+
+	switch {
+	case v.Int != nil:
+		return number(*v.Int)
+	case v.I4 != nil:
+		return number(*v.I4)
+	case v.I8 != nil:
+		return number(*v.I8)
+	case v.Double != nil:
+		return number(*v.Double)
+	case v.Boolean != nil:
+		switch strings.TrimSpace(*v.Boolean) {
+		case "1":
+			return true, nil
+		case "0":
+			return false, nil
+		}
+		return nil, errors.New(fmt.Sprintf("Invalid boolean %q", *v.Boolean))
+	case v.String != nil:
+		return *v.String, nil
+	case v.Base64 != nil:
+		return strings.Join(strings.Fields(*v.Base64), ""), nil
+	case v.DateTime != nil:
+		t, err := time.Parse(XMLRPC_TIME_LAYOUT, strings.TrimSpace(*v.DateTime))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid dateTime.iso8601 %q", *v.DateTime))
+		}
+		return t.Format(time.RFC3339), nil
+	case v.Nil != nil:
+		return nil, nil
+	case v.Array != nil:
+		values := make([]any, len(v.Array.Values))
+		for i, value := range v.Array.Values {
+			decoded, err := value.decode()
+			if err != nil {
+				return nil, err
+			}
+			values[i] = decoded
+		}
+		return values, nil
+	case v.Struct != nil:
+		members := make(map[string]any, len(v.Struct.Members))
+		for _, member := range v.Struct.Members {
+			decoded, err := member.Value.decode()
+			if err != nil {
+				return nil, err
+			}
+			members[member.Name] = decoded
+		}
+		return members, nil
+	}
+
+	return v.Text, nil
+}
+
+// Write a value decoded from JSON with UseNumber as an XML-RPC value. Whole numbers fitting 32 bits are ints and
+// other numbers doubles. Members of structs are sorted by name
+func writeXMLValue(buf *bytes.Buffer, value any) {
+	buf.WriteString("<value>")
+	defer buf.WriteString("</value>")
+
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("<nil/>")
+	case bool:
+		if v {
+			buf.WriteString("<boolean>1</boolean>")
+		} else {
+			buf.WriteString("<boolean>0</boolean>")
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil && i >= math.MinInt32 && i <= math.MaxInt32 {
+			fmt.Fprintf(buf, "<int>%d</int>", i)
+		} else {
+			fmt.Fprintf(buf, "<double>%s</double>", v)
+		}
+	case string:
+		buf.WriteString("<string>")
+		xml.EscapeText(buf, []byte(v))
+		buf.WriteString("</string>")
+	case []any:
+		buf.WriteString("<array><data>")
+		for _, element := range v {
+			writeXMLValue(buf, element)
+		}
+		buf.WriteString("</data></array>")
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		buf.WriteString("<struct>")
+		for _, name := range names {
+			buf.WriteString("<member><name>")
+			xml.EscapeText(buf, []byte(name))
+			buf.WriteString("</name>")
+			writeXMLValue(buf, v[name])
+			buf.WriteString("</member>")
+		}
+		buf.WriteString("</struct>")
+	}
+}
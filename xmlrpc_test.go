@@ -0,0 +1,62 @@
+package jsonrpc2
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXMLRPCAdapter(t *testing.T) {
+	rpc := NewJsonRpc(WithProtocolAdapter("/xmlrpc", XMLRPC))
+	rpc.RegisterWithName(arith{}, "Arith")
+	url := newTestHTTPServer(t, rpc)
+
+	status, body := postAdapted(t, url+"/xmlrpc", "text/xml", `<?xml version="1.0"?>
+<methodCall>
+  <methodName>Arith.Add</methodName>
+  <params>
+    <param><value><int>1</int></value></param>
+    <param><value><double>2.5</double></value></param>
+  </params>
+</methodCall>`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<methodResponse><params><param><value><int>3</int></value></param></params></methodResponse>`, body)
+
+	_, body = postAdapted(t, url+"/xmlrpc", "text/xml", `<methodCall><methodName>Arith.ErrorMethod</methodName></methodCall>`)
+	assert.Contains(t, body, `<fault><value><struct><member><name>faultCode</name><value><int>32603</int></value></member><member><name>faultString</name><value><string>Some error here</string></value></member></struct></value></fault>`)
+
+	_, body = postAdapted(t, url+"/xmlrpc", "text/xml", `<methodCall>`)
+	assert.Contains(t, body, `<name>faultCode</name><value><int>32700</int></value>`)
+}
+
+func TestXMLRPCValues(t *testing.T) {
+	req, err := XMLRPC.DecodeRequest([]byte(`<methodCall><methodName>Users.Create</methodName><params>
+<param><value>plain</value></param>
+<param><value><struct>
+  <member><name>admin</name><value><boolean>1</boolean></value></member>
+  <member><name>born</name><value><dateTime.iso8601>19980717T14:08:55</dateTime.iso8601></value></member>
+  <member><name>tags</name><value><array><data><value><string>a</string></value><value><i4>2</i4></value><value><nil/></value></data></array></value></member>
+</struct></value></param>
+<param><value><base64>aGVs
+bG8=</base64></value></param>
+</params></methodCall>`))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","method":"Users.Create","params":[
+		"plain",
+		{"admin":true,"born":"1998-07-17T14:08:55Z","tags":["a",2,null]},
+		"aGVsbG8="
+	]}`, string(req))
+
+	_, err = XMLRPC.DecodeRequest([]byte(`<methodCall><methodName>A.B</methodName><params><param><value><boolean>yes</boolean></value></param></params></methodCall>`))
+	assert.EqualError(t, err, `Invalid boolean "yes"`)
+
+	res, err := XMLRPC.EncodeResponse(nil, []byte(`{"jsonrpc":"2.0","id":"1","result":{"name":"<Ann>","ids":[1,4294967296,0.5],"ok":false,"none":null}}`))
+	assert.Nil(t, err)
+	assert.Contains(t, string(res), `<params><param><value><struct>`+
+		`<member><name>ids</name><value><array><data><value><int>1</int></value><value><double>4294967296</double></value><value><double>0.5</double></value></data></array></value></member>`+
+		`<member><name>name</name><value><string>&lt;Ann&gt;</string></value></member>`+
+		`<member><name>none</name><value><nil/></value></member>`+
+		`<member><name>ok</name><value><boolean>0</boolean></value></member>`+
+		`</struct></value></param></params>`)
+}